@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/osa911/giraffecloud/internal/api/handlers"
+	"github.com/osa911/giraffecloud/internal/tunnel"
+
+	"github.com/spf13/cobra"
+)
+
+var certCmd = &cobra.Command{
+	Use:   "cert",
+	Short: "Manage client certificates",
+	Long:  `Inspect and renew the client certificates used to authenticate tunnel connections.`,
+}
+
+var certRotateCmd = &cobra.Command{
+	Use:   "rotate",
+	Short: "Fetch a new client certificate without logging in again",
+	Long: `Fetch a new client certificate and key using the stored API token and replace
+the current ones. The new pair is checked against the CA before anything is written,
+and each file is swapped atomically, so a running tunnel picks up the new certificate
+the next time it reconnects.
+
+The CA certificate is left untouched unless --rotate-ca is given.
+
+Examples:
+  giraffecloud cert rotate
+  giraffecloud cert rotate --rotate-ca`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, err := tunnel.LoadConfig()
+		if err != nil {
+			logger.Error("Error loading config: %v", err)
+			os.Exit(1)
+		}
+		if cfg.Token == "" {
+			logger.Error("No API token configured - run 'giraffecloud login --token YOUR_API_TOKEN' first")
+			os.Exit(1)
+		}
+
+		insecure, _ := cmd.Flags().GetBool("insecure")
+		if insecure {
+			logger.Warn("⚠️  --insecure: TLS certificate verification of %s is DISABLED. Never use this in production.", cfg.API.Host)
+		}
+		rotateCA, _ := cmd.Flags().GetBool("rotate-ca")
+
+		certResp, err := handlers.FetchCertificates(cfg.API.Host, cfg.API.Port, cfg.Token, insecure)
+		if err != nil {
+			logger.Error("Failed to fetch certificates: %v", err)
+			os.Exit(1)
+		}
+		if err := certResp.Verify(); err != nil {
+			logger.Error("Downloaded certificates are invalid: %v", err)
+			os.Exit(1)
+		}
+
+		var caPEM []byte
+		if rotateCA {
+			caPEM = []byte(certResp.CACert)
+		}
+		if err := tunnel.RotateClientCert(cfg.Security, []byte(certResp.ClientCert), []byte(certResp.ClientKey), caPEM); err != nil {
+			logger.Error("Failed to rotate client certificate: %v", err)
+			if !rotateCA {
+				logger.Info("If the server's CA changed, run 'giraffecloud cert rotate --rotate-ca'")
+			}
+			os.Exit(1)
+		}
+
+		expiries, _ := tunnel.CheckCertExpiry(cfg.Security)
+		for _, expiry := range expiries {
+			if expiry.Name == "client" || rotateCA {
+				logCertExpiry(expiry, certExpiryWindow())
+			}
+		}
+		if rotateCA {
+			logger.Info("✅ Client and CA certificates rotated")
+		} else {
+			logger.Info("✅ Client certificate rotated")
+		}
+		logger.Info("A running tunnel uses the new certificate on its next reconnect ('giraffecloud service restart', or SIGHUP if it runs with --reconnect-on-sighup, to apply now)")
+	},
+}
+
+// initCertCommands sets up the cert command and its subcommands
+func initCertCommands() {
+	certCmd.AddCommand(certRotateCmd)
+	certRotateCmd.Flags().Bool("rotate-ca", false, "Also replace the CA certificate")
+	certRotateCmd.Flags().Bool("insecure", false, "Skip TLS certificate verification of the API server (testing only)")
+}
+
+// warnCertExpiry logs a warning for every configured certificate that expires within the
+// configured window, and returns the expiry of each certificate for the tunnel's stats
+func warnCertExpiry(cfg *tunnel.Config) []tunnel.CertExpiry {
+	expiries, err := tunnel.CheckCertExpiry(cfg.Security)
+	if err != nil {
+		logger.Warn("Could not check certificate expiry: %v", err)
+	}
+	for _, expiry := range expiries {
+		if !expiry.ExpiresWithin(cfg.Security.CertExpiryWarning) {
+			continue
+		}
+		if expiry.Remaining() <= 0 {
+			logger.Error("❌ The %s - run 'giraffecloud login' to get new certificates", expiry)
+		} else {
+			logger.Warn("⚠️  The %s - run 'giraffecloud login' to refresh it", expiry)
+		}
+	}
+	return expiries
+}
+
+// logCertExpiry prints one certificate's expiry for 'giraffecloud status'
+func logCertExpiry(expiry tunnel.CertExpiry, window time.Duration) {
+	date := expiry.NotAfter.Local().Format("2006-01-02 15:04")
+	switch {
+	case expiry.Remaining() <= 0:
+		logger.Info("  %s Certificate Expired: %s ❌ run 'giraffecloud login'", certLabel(expiry.Name), date)
+	case expiry.ExpiresWithin(window):
+		logger.Info("  %s Certificate Expires: %s ⚠️  (in %d days) run 'giraffecloud login' to refresh", certLabel(expiry.Name), date, int(expiry.Remaining().Hours()/24))
+	default:
+		logger.Info("  %s Certificate Expires: %s", certLabel(expiry.Name), date)
+	}
+}
+
+// certLabel capitalizes a CertExpiry name for status output
+func certLabel(name string) string {
+	if name == "client" {
+		return "Client"
+	}
+	return name
+}
+
+// certExpiryWindow returns the warning window from the config file, or the default
+func certExpiryWindow() time.Duration {
+	if cfg, err := tunnel.LoadConfig(); err == nil && cfg.Security.CertExpiryWarning > 0 {
+		return cfg.Security.CertExpiryWarning
+	}
+	return tunnel.DefaultCertExpiryWarning
+}
+
+// logLiveCertExpiry prints the certificate expiry reported by a running tunnel's stats
+func logLiveCertExpiry(stats map[string]interface{}) {
+	window := certExpiryWindow()
+	for _, c := range []struct{ key, name string }{
+		{"client_cert_expires", "client"},
+		{"ca_cert_expires", "CA"},
+	} {
+		value, _ := stats[c.key].(string)
+		notAfter, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			continue
+		}
+		logCertExpiry(tunnel.CertExpiry{Name: c.name, NotAfter: notAfter}, window)
+	}
+}
+
+// reloadCertificatesOn reloads the certificates and reconnects at each request until ctx ends
+// (nil requests = never)
+func reloadCertificatesOn(ctx context.Context, requests <-chan struct{}, reload func() error) {
+	if requests == nil {
+		return
+	}
+	for {
+		select {
+		case <-requests:
+			logger.Info("Certificate reload requested")
+			if err := reload(); err != nil {
+				logger.Error("Certificate reload failed, keeping the current connection: %v", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
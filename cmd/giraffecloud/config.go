@@ -31,12 +31,16 @@ var configPathCmd = &cobra.Command{
 		fmt.Printf("Configuration directory: %s\n", baseDir)
 
 		// Only show files/directories that actually exist
-		configPath := filepath.Join(baseDir, "config.json")
+		configPath, err := tunnel.GetConfigPath()
+		if err != nil {
+			logger.Error("Failed to determine config path: %v", err)
+			os.Exit(1)
+		}
 		if _, err := os.Stat(configPath); err == nil {
 			fmt.Printf("Config file:            %s\n", configPath)
 		}
 
-		certsDir := filepath.Join(baseDir, "certs")
+		certsDir := filepath.Join(filepath.Dir(configPath), "certs")
 		if _, err := os.Stat(certsDir); err == nil {
 			fmt.Printf("Certificates directory: %s\n", certsDir)
 			// List certificate files if they exist
@@ -89,9 +93,119 @@ var configShowCmd = &cobra.Command{
 	},
 }
 
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check the configuration for problems",
+	Long: `Load the configuration and check everything 'giraffecloud connect' needs:
+token and domain are set, ports are in range, server and API hosts resolve,
+and the certificate files exist and parse.
+
+Prints a pass/fail checklist and exits non-zero if any check fails, so it can
+be used to verify a machine before enabling the service at boot.
+
+Example:
+  giraffecloud config validate`,
+	Run: func(cmd *cobra.Command, args []string) {
+		path, _ := tunnel.GetConfigPath()
+		fmt.Printf("Config file: %s\n\n", path)
+
+		cfg, err := tunnel.LoadConfig()
+		if err != nil {
+			fmt.Printf("❌ config: %v\n", err)
+			os.Exit(1)
+		}
+
+		failed := 0
+		for _, check := range tunnel.ValidateConfig(cfg) {
+			if check.OK {
+				fmt.Printf("✅ %s: %s\n", check.Name, check.Detail)
+			} else {
+				fmt.Printf("❌ %s: %s\n", check.Name, check.Detail)
+				failed++
+			}
+		}
+
+		if failed > 0 {
+			fmt.Printf("\n❌ %d check(s) failed\n", failed)
+			os.Exit(1)
+		}
+		fmt.Println("\n✅ Configuration is valid")
+	},
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get KEY",
+	Short: "Print a configuration value",
+	Long: `Print the value of a single configuration key. Nested settings use dotted
+keys named as in the config file.
+
+Examples:
+  giraffecloud config get local_port
+  giraffecloud config get server.host
+  giraffecloud config get auto_update.check_interval`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, err := tunnel.LoadConfig()
+		if err != nil {
+			logger.Error("Error loading config: %v", err)
+			os.Exit(1)
+		}
+
+		value, err := tunnel.GetConfigValue(cfg, args[0])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Println(value)
+	},
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set KEY VALUE",
+	Short: "Change a configuration value",
+	Long: `Set a single configuration key and save the config file, instead of editing it
+by hand. Nested settings use dotted keys named as in the config file; durations
+take values such as 30s, 5m or 24h, and booleans true or false.
+
+The configuration is validated before it is saved. A running tunnel picks up
+the change on its next start.
+
+Examples:
+  giraffecloud config set server.host tunnel.example.com
+  giraffecloud config set local_port 3000
+  giraffecloud config set auto_update.enabled false`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		key, value := args[0], args[1]
+
+		loaded, err := tunnel.LoadConfig()
+		if err != nil {
+			logger.Error("Error loading config: %v", err)
+			os.Exit(1)
+		}
+		// LoadConfig may return the shared defaults, so change a copy
+		cfg := *loaded
+
+		if err := tunnel.SetConfigValue(&cfg, key, value); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if err := tunnel.SaveConfig(&cfg); err != nil {
+			logger.Error("Failed to save config: %v", err)
+			os.Exit(1)
+		}
+
+		saved, _ := tunnel.GetConfigValue(&cfg, key)
+		fmt.Printf("✅ %s = %s\n", key, saved)
+	},
+}
+
 // initConfigCommands sets up all config-related commands
 func initConfigCommands() {
 	// Add subcommands to config
 	configCmd.AddCommand(configPathCmd)
 	configCmd.AddCommand(configShowCmd)
+	configCmd.AddCommand(configValidateCmd)
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
 }
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/osa911/giraffecloud/internal/service"
+	"github.com/osa911/giraffecloud/internal/tunnel"
+)
+
+// connectMultiple runs every tunnel listed in tunnelConfigPath from this process until ctx is cancelled.
+// usageRecorder may be nil when usage recording is disabled, certReloads when certificates aren't reloaded.
+func connectMultiple(ctx context.Context, cfg *tunnel.Config, serverAddr string, tlsConfig *tls.Config, insecure bool, localTimeout, waitForLocal time.Duration, tunnelConfigPath string, usageRecorder *tunnel.FileUsageRecorder, certExpiries []tunnel.CertExpiry, certReloads <-chan struct{}) {
+	entries, err := tunnel.LoadTunnelEntries(tunnelConfigPath)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(1)
+	}
+
+	logger.Info("Starting %d tunnel connections to %s", len(entries), serverAddr)
+	for _, entry := range entries {
+		logger.Info("  %s -> port %d", entry.Domain, entry.LocalPort)
+	}
+
+	mt := tunnel.NewMultiTunnel(entries)
+	endpoints := tunnel.NewServerEndpoints(cfg.Server)
+	for _, t := range mt.Tunnels() {
+		if cfg.LocalHost != "" {
+			t.SetLocalHost(cfg.LocalHost)
+		}
+		t.SetLocalProtocol(cfg.LocalScheme, cfg.LocalUseHTTP2)
+		t.SetPathRewrite(cfg.PublicPathStrip, cfg.LocalPathPrefix)
+		t.SetSignedURL(cfg.RequireSignedURL, cfg.SignedURLSecret)
+		t.SetDisableMediaOptimization(cfg.DisableMediaOptimization)
+		t.SetGRPCPort(cfg.Server.GRPCPort)
+		t.SetInsecureSkipVerify(insecure)
+		t.SetServerCertFingerprint(cfg.Security.ServerCertFingerprint)
+		t.SetLocalRequestTimeout(localTimeout)
+		t.SetWaitForLocal(waitForLocal)
+		t.SetCertExpiries(certExpiries)
+		t.SetServerDialer(tunnel.NewServerDialer(cfg))
+		t.SetServerEndpoints(endpoints)
+		if err := t.SetStateChangeWebhook(cfg.StateChangeWebhook); err != nil {
+			logger.Warn("%v - state change notifications disabled", err)
+		}
+		if usageRecorder != nil {
+			t.SetUsageRecorder(usageRecorder)
+		}
+	}
+	mt.ApplyReloadableConfig(cfg)
+
+	apiServerURL := fmt.Sprintf("https://%s:%d", cfg.API.Host, cfg.API.Port)
+	autoUpdateSvc, _ := service.NewAutoUpdateService(&cfg.AutoUpdate, mt, service.NewDefaultServiceManager())
+	if autoUpdateSvc != nil {
+		autoUpdateSvc.VerifyPendingUpdate(ctx)
+	}
+
+	checkVersionCompatibility(apiServerURL)
+
+	if err := mt.Connect(ctx, serverAddr, cfg.Token, tlsConfig); err != nil {
+		fmt.Printf("❌ Failed to connect to GiraffeCloud: %v\n", err)
+		os.Exit(1)
+	}
+
+	controlServer, err := tunnel.NewControlServer()
+	if err == nil {
+		controlServer.Handle("reload", func() (interface{}, error) {
+			reloaded, err := tunnel.LoadConfig()
+			if err != nil {
+				return nil, fmt.Errorf("failed to load config: %w", err)
+			}
+			applied := mt.ApplyReloadableConfig(reloaded)
+			if applied == nil {
+				applied = []string{}
+			}
+			return applied, nil
+		})
+		controlServer.Handle("status", func() (interface{}, error) {
+			return mt.GetStats(), nil
+		})
+		if err := controlServer.Start(); err != nil {
+			logger.Warn("Failed to start control socket: %v", err)
+			controlServer = nil
+		}
+	} else {
+		logger.Warn("Failed to create control socket: %v", err)
+		controlServer = nil
+	}
+
+	go reloadCertificatesOn(ctx, certReloads, mt.ReloadCertificates)
+
+	logger.Info("%d tunnels are running. Press Ctrl+C to stop.", len(entries))
+
+	if autoUpdateSvc != nil {
+		if startErr := autoUpdateSvc.Start(ctx, apiServerURL); startErr != nil {
+			logger.Warn("Failed to start auto-update service: %v", startErr)
+		}
+	}
+
+	<-ctx.Done()
+	logger.Info("Shutting down tunnels...")
+	if controlServer != nil {
+		controlServer.Stop()
+	}
+	mt.Disconnect()
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/osa911/giraffecloud/internal/tunnel"
+
+	"github.com/spf13/cobra"
+)
+
+var stopCmd = &cobra.Command{
+	Use:   "stop",
+	Short: "Stop the running tunnel",
+	Long: `Find the running tunnel through its PID file and ask it to shut down gracefully
+(SIGTERM), then wait for it to exit. Works for tunnels started with 'giraffecloud
+connect', in the foreground or with --daemon.
+
+A tunnel run by the system service is better stopped with 'giraffecloud service stop',
+otherwise the service manager may start it again.
+
+Examples:
+  giraffecloud stop
+  giraffecloud stop --timeout 1m`,
+	Run: func(cmd *cobra.Command, args []string) {
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+
+		pid, err := tunnel.RunningTunnelPID()
+		if errors.Is(err, tunnel.ErrNoRunningInstance) {
+			fmt.Println("❌ No running tunnel found.")
+			os.Exit(1)
+		}
+		if err != nil {
+			logger.Error("Failed to locate the running tunnel: %v", err)
+			os.Exit(1)
+		}
+		if serviceManager, err := tunnel.NewServiceManager(); err == nil {
+			if running, err := serviceManager.IsRunning(); err == nil && running {
+				logger.Warn("The GiraffeCloud service is running - use 'giraffecloud service stop' to keep it from restarting the tunnel")
+			}
+		}
+
+		fmt.Printf("Stopping tunnel (PID %d)...\n", pid)
+		if _, err := tunnel.StopRunningTunnel(timeout); err != nil {
+			if errors.Is(err, tunnel.ErrNoRunningInstance) {
+				fmt.Println("✅ Tunnel stopped")
+				return
+			}
+			logger.Error("Failed to stop tunnel: %v", err)
+			os.Exit(1)
+		}
+		fmt.Println("✅ Tunnel stopped")
+	},
+}
+
+// initStopCommand sets up the stop command's flags
+func initStopCommand() {
+	stopCmd.Flags().Duration("timeout", 30*time.Second, "How long to wait for the tunnel to exit")
+}
+
+// startDaemon re-runs 'connect' in the background and waits until it holds the
+// singleton lock, so startup errors (bad config, tunnel already running) are reported
+func startDaemon() {
+	if pid, err := tunnel.RunningTunnelPID(); err == nil {
+		logger.Error("Tunnel is already running (PID: %d) - stop it with 'giraffecloud stop'", pid)
+		os.Exit(1)
+	}
+
+	// Same command line, minus --daemon
+	var args []string
+	for _, arg := range os.Args[1:] {
+		if arg == "--daemon" || strings.HasPrefix(arg, "--daemon=") {
+			continue
+		}
+		args = append(args, arg)
+	}
+	args = append(args, "--foreground")
+
+	child, err := tunnel.StartDetached(args)
+	if err != nil {
+		logger.Error("Failed to start tunnel in the background: %v", err)
+		os.Exit(1)
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- child.Wait() }()
+
+	deadline := time.After(daemonStartTimeout)
+	ticker := time.NewTicker(200 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case err := <-exited:
+			logger.Error("Background tunnel exited during startup (%v) - see 'giraffecloud logs' or run without --daemon", err)
+			os.Exit(1)
+		case <-deadline:
+			logger.Warn("Background tunnel (PID %d) is still starting - check 'giraffecloud status'", child.Process.Pid)
+			return
+		case <-ticker.C:
+			if pid, err := tunnel.RunningTunnelPID(); err == nil && pid == child.Process.Pid {
+				fmt.Printf("✅ Tunnel running in the background (PID %d)\n", pid)
+				fmt.Println("   Stop it with 'giraffecloud stop'")
+				return
+			}
+		}
+	}
+}
+
+// daemonStartTimeout is how long 'connect --daemon' waits for the background tunnel to start
+const daemonStartTimeout = 15 * time.Second
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/osa911/giraffecloud/internal/tunnel"
+
+	"github.com/spf13/cobra"
+)
+
+var logsCmd = &cobra.Command{
+	Use:   "logs",
+	Short: "Show the tunnel client log",
+	Long: `Show the tunnel client log (client.log in the config directory), written by both
+'giraffecloud connect' and the system service.
+
+If there is no client log yet and the service is installed, the service logs
+(journal, launchd log or Windows event log) are shown instead.
+
+Examples:
+  giraffecloud logs            # Last 100 lines
+  giraffecloud logs -n 500     # Last 500 lines
+  giraffecloud logs -f         # Follow new lines until Ctrl+C`,
+	Run: func(cmd *cobra.Command, args []string) {
+		follow, _ := cmd.Flags().GetBool("follow")
+		lines, _ := cmd.Flags().GetInt("lines")
+
+		cfgDir, err := tunnel.GetConfigDir()
+		if err != nil {
+			logger.Error("Failed to determine config directory: %v", err)
+			os.Exit(1)
+		}
+		logPath := filepath.Join(cfgDir, "client.log")
+
+		if _, err := os.Stat(logPath); err == nil {
+			ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+			defer stop()
+			if err := tunnel.TailFile(ctx, logPath, lines, follow, os.Stdout); err != nil {
+				logger.Error("Failed to read %s: %v", logPath, err)
+				os.Exit(1)
+			}
+			return
+		}
+
+		// No client log: fall back to the service's own logs
+		sm, err := tunnel.NewServiceManager()
+		if err != nil {
+			logger.Error("Failed to create service manager: %v", err)
+			os.Exit(1)
+		}
+		if installed, _ := sm.IsInstalled(); !installed {
+			fmt.Printf("❌ No logs found at %s\n", logPath)
+			fmt.Println("💡 Run 'giraffecloud connect' or 'giraffecloud service install' first")
+			os.Exit(1)
+		}
+
+		if follow {
+			if err := sm.FollowLogsWithLines(lines); err != nil {
+				logger.Error("Failed to follow service logs: %v", err)
+				os.Exit(1)
+			}
+			return
+		}
+		logs, err := sm.GetLogsWithLines(lines)
+		if err != nil {
+			logger.Error("Failed to get service logs: %v", err)
+			os.Exit(1)
+		}
+		fmt.Print(logs)
+	},
+}
+
+func initLogsCommand() {
+	logsCmd.Flags().BoolP("follow", "f", false, "Follow the log as new lines are written")
+	logsCmd.Flags().IntP("lines", "n", 100, "Number of lines to show")
+}
@@ -4,11 +4,13 @@ import (
 	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/json"
 	"fmt"
-	"net"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"runtime"
+	"sort"
 	"strings"
 	"syscall"
 	"time"
@@ -25,6 +27,7 @@ import (
 
 var logger *logging.Logger
 var rootVersionFlag bool
+var rootConfigFlag string
 
 func initLogger() {
 	// Resolve log file under config dir to avoid reliance on $HOME expansion
@@ -42,7 +45,9 @@ func initLogger() {
 		MaxSize:    100,
 		MaxBackups: 3,
 		MaxAge:     7,
-		Level:      os.Getenv("LOG_LEVEL"), // Default to empty string, will use INFO level
+		Level:      os.Getenv("LOG_LEVEL"),  // Default to empty string, will use INFO level
+		Format:     os.Getenv("LOG_FORMAT"), // text (default) or json for Loki/ELK ingestion
+		Stderr:     wantsPrintURL(),         // stdout only carries the public URL
 	}
 
 	// Initialize the global logger
@@ -55,6 +60,43 @@ func initLogger() {
 	logger = logging.GetGlobalLogger()
 }
 
+// envFileArg returns the --env-file value from the command line (flags aren't parsed yet in init)
+func envFileArg() string {
+	args := os.Args[1:]
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if value, ok := strings.CutPrefix(arg, "--env-file="); ok {
+			return value
+		}
+		if arg == "--env-file" && i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return ""
+}
+
+// wantsJSONOutput reports whether the command line asks for JSON output (flags aren't parsed yet in init)
+func wantsJSONOutput() bool {
+	for _, arg := range os.Args[1:] {
+		if arg == "--json" || arg == "--json=true" {
+			return true
+		}
+	}
+	return false
+}
+
+// wantsPrintURL reports whether 'connect --print-url' reserves stdout for the public URL
+func wantsPrintURL() bool {
+	for _, arg := range os.Args[1:] {
+		if arg == "--print-url" || arg == "--print-url=true" {
+			return true
+		}
+	}
+	return false
+}
+
 var rootCmd = &cobra.Command{
 	Use:   "giraffecloud",
 	Short: "GiraffeCloud CLI - Secure reverse tunnel client",
@@ -62,6 +104,9 @@ var rootCmd = &cobra.Command{
 local services through GiraffeCloud's infrastructure.`,
 }
 
+// onceMaxRetries is how many attempts 'connect --once' gives the first connection
+const onceMaxRetries = 3
+
 var connectCmd = &cobra.Command{
 	Use:   "connect",
 	Short: "Connect to GiraffeCloud and establish a tunnel",
@@ -70,13 +115,36 @@ The tunnel will forward requests from your assigned domain to your local service
 
 Domain Selection:
   - If you have only one tunnel, it will be used automatically
-  - If you have multiple tunnels, specify which one with --domain flag
+  - If you have multiple tunnels, specify which one with --domain or --tunnel-id
+    (see 'giraffecloud tunnels list' for IDs)
   - The last connected domain is saved for quick reconnect
 
 Examples:
   giraffecloud connect                         # Connect to last used or first active tunnel
-  giraffecloud connect --domain example.com    # Connect to specific tunnel`,
+  giraffecloud connect --domain example.com    # Connect to specific tunnel
+  giraffecloud connect --tunnel-id 42          # Connect to specific tunnel by ID
+  giraffecloud connect --local-host 192.168.1.50  # Forward to a service on another machine
+  giraffecloud connect --daemon                # Run in the background, 'giraffecloud stop' to end it
+  giraffecloud connect --once                  # Exit non-zero if the tunnel can't connect or drops (CI)
+  giraffecloud connect --print-url             # Print only the public URL on stdout (logs on stderr)
+  giraffecloud connect --reconnect-on-sighup   # 'kill -HUP <pid>' reloads rotated certificates
+  giraffecloud connect --tunnel-config tunnels.yaml  # Run several tunnels from one process
+
+Multiple tunnels (--tunnel-config):
+  tunnels:
+    - domain: app.example.com
+      localPort: 3000
+    - domain: api.example.com
+      localPort: 8080
+      localHost: 192.168.1.50   # optional`,
 	Run: func(cmd *cobra.Command, args []string) {
+		// With --print-url stdout only carries the public URL; everything else goes to stderr
+		printURL, _ := cmd.Flags().GetBool("print-url")
+		urlOut := os.Stdout
+		if printURL {
+			os.Stdout = os.Stderr
+		}
+
 		// Check if user has logged in (config.json exists)
 		configPath, err := tunnel.GetConfigPath()
 		if err != nil {
@@ -101,25 +169,123 @@ Examples:
 		// Get tunnel host and port from flags if provided
 		tunnelHost, _ := cmd.Flags().GetString("tunnel-host")
 		tunnelPort, _ := cmd.Flags().GetInt("tunnel-port")
+		grpcPort, _ := cmd.Flags().GetInt("grpc-port")
 		domainFlag, _ := cmd.Flags().GetString("domain")
+		localHostFlag, _ := cmd.Flags().GetString("local-host")
+		tunnelIDFlag, _ := cmd.Flags().GetUint32("tunnel-id")
+		tunnelConfigFlag, _ := cmd.Flags().GetString("tunnel-config")
+		localTimeout, _ := cmd.Flags().GetDuration("local-timeout")
+		waitForLocal, _ := cmd.Flags().GetDuration("wait-for-local")
 
 		if tunnelHost != "" {
 			cfg.Server.Host = tunnelHost
+			cfg.Server.Hosts = nil // Connect to just the server asked for
 		}
-		if tunnelPort != 0 {
+		if cmd.Flags().Changed("tunnel-port") {
 			cfg.Server.Port = tunnelPort
+			cfg.Server.TCPPort = tunnelPort
+		}
+		if grpcPort != 0 {
+			cfg.Server.GRPCPort = grpcPort
 		}
 		if domainFlag != "" {
 			cfg.Domain = domainFlag
 		}
+		if localHostFlag != "" {
+			cfg.LocalHost = localHostFlag
+		}
+		if localScheme, _ := cmd.Flags().GetString("local-scheme"); localScheme != "" {
+			cfg.LocalScheme = localScheme
+		}
+		if localHTTP2, _ := cmd.Flags().GetBool("local-http2"); localHTTP2 {
+			cfg.LocalUseHTTP2 = true
+		}
+		if prefix, _ := cmd.Flags().GetString("local-path-prefix"); prefix != "" {
+			cfg.LocalPathPrefix = prefix
+		}
+		if strip, _ := cmd.Flags().GetString("public-path-strip"); strip != "" {
+			cfg.PublicPathStrip = strip
+		}
+		if fallbackPort, _ := cmd.Flags().GetInt("fallback-port"); fallbackPort != 0 {
+			cfg.FallbackLocalPort = fallbackPort
+		}
+		if udpPort, _ := cmd.Flags().GetInt("udp-port"); udpPort != 0 {
+			cfg.UDPPort = udpPort
+		}
+		if udpPublicPort, _ := cmd.Flags().GetInt("udp-public-port"); udpPublicPort != 0 {
+			cfg.UDPPublicPort = udpPublicPort
+		}
+		if cmd.Flags().Changed("local-health-interval") {
+			interval, _ := cmd.Flags().GetDuration("local-health-interval")
+			if cfg.Retry == nil {
+				cfg.Retry = tunnel.DefaultRetryConfig()
+			}
+			cfg.Retry.LocalHealthInterval = interval
+		}
+		once, _ := cmd.Flags().GetBool("once")
+		if once {
+			if cfg.Retry == nil {
+				cfg.Retry = tunnel.DefaultRetryConfig()
+			}
+			cfg.Retry.MaxRetries = onceMaxRetries
+		}
+		if err := tunnel.ValidateLocalScheme(cfg.LocalScheme); err != nil {
+			logger.Error("Invalid local scheme: %v", err)
+			os.Exit(1)
+		}
+		for flag, prefix := range map[string]string{"local path prefix": cfg.LocalPathPrefix, "public path strip": cfg.PublicPathStrip} {
+			if err := tunnel.ValidatePathPrefix(prefix); err != nil {
+				logger.Error("Invalid %s: %v", flag, err)
+				os.Exit(1)
+			}
+		}
+		if cfg.RequireSignedURL {
+			if err := tunnel.ValidateSignedURLSecret(cfg.SignedURLSecret); err != nil {
+				logger.Error("Invalid signed_url_secret: %v", err)
+				os.Exit(1)
+			}
+		}
+
+		// Pin the tunnel server IP or resolve it through a specific DNS server
+		resolveFlags, _ := cmd.Flags().GetStringArray("resolve")
+		overrides, err := tunnel.ParseDNSOverrides(resolveFlags)
+		if err != nil {
+			logger.Error("Invalid --resolve: %v", err)
+			os.Exit(1)
+		}
+		if len(overrides) > 0 && cfg.DNSOverride == nil {
+			cfg.DNSOverride = make(map[string]string, len(overrides))
+		}
+		for host, ip := range overrides {
+			cfg.DNSOverride[host] = ip
+		}
+		if dnsServer, _ := cmd.Flags().GetString("dns-server"); dnsServer != "" {
+			cfg.DNSServer = dnsServer
+		}
+		if proxyFlag, _ := cmd.Flags().GetString("proxy"); proxyFlag != "" {
+			cfg.Proxy = proxyFlag
+		}
+		if cfg.Proxy != "" && !strings.EqualFold(cfg.Proxy, tunnel.ProxyDirect) {
+			if _, err := tunnel.ParseProxyURL(cfg.Proxy); err != nil {
+				logger.Error("Invalid --proxy: %v", err)
+				os.Exit(1)
+			}
+		}
 
-		serverAddr := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port)
+		serverAddr := cfg.Server.TCPAddr()
 
-		// Create TLS config
-		tlsConfig := &tls.Config{
-			InsecureSkipVerify: cfg.Security.InsecureSkipVerify,
+		// Skipping certificate verification takes the explicit flag; the config value alone is ignored
+		insecure, _ := cmd.Flags().GetBool("insecure")
+		if cfg.Security.InsecureSkipVerify && !insecure {
+			logger.Warn("Ignoring security.insecure_skip_verify in the config file; pass --insecure to skip certificate verification")
+		}
+		if insecure {
+			logger.Warn("⚠️  --insecure: TLS certificate verification is DISABLED. Never use this in production.")
 		}
 
+		// Create TLS config
+		tlsConfig := &tls.Config{}
+
 		// Load CA certificate if provided
 		if cfg.Security.CACert != "" {
 			caCert, err := os.ReadFile(cfg.Security.CACert)
@@ -148,6 +314,32 @@ Examples:
 			tlsConfig.Certificates = []tls.Certificate{cert}
 			logger.Info("Using client certificate: %s", cfg.Security.ClientCert)
 		}
+		certExpiries := warnCertExpiry(cfg)
+
+		// --daemon re-runs this command in the background once the config checks out
+		daemon, _ := cmd.Flags().GetBool("daemon")
+		foreground, _ := cmd.Flags().GetBool("foreground")
+		if daemon && foreground {
+			logger.Error("--daemon and --foreground can't be used together")
+			os.Exit(1)
+		}
+		if once && (daemon || tunnelConfigFlag != "") {
+			logger.Error("--once can't be used with --daemon or --tunnel-config")
+			os.Exit(1)
+		}
+		if printURL && (daemon || tunnelConfigFlag != "") {
+			logger.Error("--print-url can't be used with --daemon or --tunnel-config")
+			os.Exit(1)
+		}
+		reconnectOnSighup, _ := cmd.Flags().GetBool("reconnect-on-sighup")
+		if once && reconnectOnSighup {
+			logger.Error("--once can't be used with --reconnect-on-sighup")
+			os.Exit(1)
+		}
+		if daemon {
+			startDaemon()
+			return
+		}
 
 		// Set up context and signal handling
 		ctx, cancel := context.WithCancel(context.Background())
@@ -159,18 +351,67 @@ Examples:
 			cancel()
 		}()
 
+		// Rotated certificates are picked up on SIGHUP (a named event on Windows) once connected
+		var certReloads <-chan struct{}
+		if reconnectOnSighup {
+			if certReloads, err = tunnel.CertReloadRequests(ctx); err != nil {
+				logger.Warn("%v - certificate reloads disabled", err)
+			}
+		}
+
+		// Record bytes per domain for 'giraffecloud usage'
+		usageRecorder := startUsageRecorder(cmd)
+		if usageRecorder != nil {
+			usageRecorder.Start(ctx)
+			defer usageRecorder.Close()
+		}
+
+		if tunnelConfigFlag != "" {
+			connectMultiple(ctx, cfg, serverAddr, tlsConfig, insecure, localTimeout, waitForLocal, tunnelConfigFlag, usageRecorder, certExpiries, certReloads)
+			return
+		}
+
 		logger.Info("Starting tunnel connection to %s", serverAddr)
-		if cfg.Domain != "" {
+		if tunnelIDFlag != 0 {
+			logger.Info("Connecting to tunnel ID: %d", tunnelIDFlag)
+		} else if cfg.Domain != "" {
 			logger.Info("Connecting to tunnel: %s", cfg.Domain)
 		} else {
 			logger.Info("No domain specified - server will select the first active tunnel")
 		}
 
 		t := tunnel.NewTunnel()
+		t.SetLocalHost(cfg.LocalHost)
+		t.SetFallbackLocalPort(cfg.FallbackLocalPort)
+		t.SetLocalProtocol(cfg.LocalScheme, cfg.LocalUseHTTP2)
+		t.SetPathRewrite(cfg.PublicPathStrip, cfg.LocalPathPrefix)
+		t.SetSignedURL(cfg.RequireSignedURL, cfg.SignedURLSecret)
+		t.SetUDPForward(cfg.UDPPort, cfg.UDPPublicPort, cfg.UDPMaxSessions)
+		t.SetDisableMediaOptimization(cfg.DisableMediaOptimization)
+		t.SetOnce(once)
+		t.SetTunnelID(tunnelIDFlag)
+		t.SetGRPCPort(cfg.Server.GRPCPort)
+		t.SetInsecureSkipVerify(insecure)
+		t.SetServerCertFingerprint(cfg.Security.ServerCertFingerprint)
+		t.SetLocalRequestTimeout(localTimeout)
+		t.SetWaitForLocal(waitForLocal)
+		t.SetCertExpiries(certExpiries)
+		t.SetServerDialer(tunnel.NewServerDialer(cfg))
+		t.SetServerEndpoints(tunnel.NewServerEndpoints(cfg.Server))
+		if err := t.SetStateChangeWebhook(cfg.StateChangeWebhook); err != nil {
+			logger.Warn("%v - state change notifications disabled", err)
+		}
+		if usageRecorder != nil {
+			t.SetUsageRecorder(usageRecorder)
+		}
+		t.ApplyReloadableConfig(cfg)
 
 		// Prepare auto-update service and on-connect hook before connecting
 		apiServerURL := fmt.Sprintf("https://%s:%d", cfg.API.Host, cfg.API.Port)
 		autoUpdateSvc, _ := service.NewAutoUpdateService(&cfg.AutoUpdate, t, service.NewDefaultServiceManager())
+		if autoUpdateSvc != nil {
+			autoUpdateSvc.VerifyPendingUpdate(ctx)
+		}
 		t.SetOnConnectHook(func() {
 			if cfg.AutoUpdate.Enabled && autoUpdateSvc != nil {
 				autoUpdateSvc.CheckNow(apiServerURL)
@@ -180,6 +421,9 @@ Examples:
 		// Spinner while connecting
 		s := spinner.New(spinner.CharSets[14], 120*time.Millisecond)
 		s.Suffix = " Connecting to GiraffeCloud..."
+		if printURL {
+			s.Writer = os.Stderr
+		}
 		s.Start()
 
 		// Check version compatibility (respect test/beta channel if enabled)
@@ -234,6 +478,10 @@ Examples:
 			os.Exit(1)
 		}
 
+		if printURL {
+			fmt.Fprintf(urlOut, "https://%s\n", t.GetDomain())
+		}
+
 		// Save the domain to config for future connections (if domain was specified)
 		if cfg.Domain != "" {
 			if err := tunnel.SaveConfig(cfg); err != nil {
@@ -243,6 +491,26 @@ Examples:
 			}
 		}
 
+		// Expose control socket so 'giraffecloud reload' can reach this process
+		controlServer, err := tunnel.NewControlServer()
+		if err == nil {
+			controlServer.Handle("reload", func() (interface{}, error) {
+				return reloadTunnelConfig(t)
+			})
+			controlServer.Handle("status", func() (interface{}, error) {
+				return t.GetStats(), nil
+			})
+			if err := controlServer.Start(); err != nil {
+				logger.Warn("Failed to start control socket: %v", err)
+				controlServer = nil
+			}
+		} else {
+			logger.Warn("Failed to create control socket: %v", err)
+			controlServer = nil
+		}
+
+		go reloadCertificatesOn(ctx, certReloads, t.ReloadCertificates)
+
 		logger.Info("Tunnel is running. Press Ctrl+C to stop.")
 
 		// Start auto-update background service
@@ -252,20 +520,148 @@ Examples:
 			}
 		}
 
-		<-ctx.Done()
+		exitCode := 0
+		select {
+		case <-ctx.Done():
+		case err := <-t.Lost():
+			fmt.Printf("❌ Tunnel connection lost: %v\n", err)
+			exitCode = 1
+		}
 		logger.Info("Shutting down tunnel...")
+		if controlServer != nil {
+			controlServer.Stop()
+		}
 		t.Disconnect()
+		if exitCode != 0 {
+			os.Exit(exitCode)
+		}
 	},
 }
 
 var versionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Print version information",
+	Long: `Print version information.
+
+Use --json for machine-readable output:
+  {"version": "v1.2.3", "commit": "...", "buildDate": "...", "goVersion": "go1.24.1", "os": "linux", "arch": "amd64"}
+
+--check also asks the API server whether this version is still supported (on the
+release channel from the config) and adds its answer under "compatibility":
+  {"minimumVersion": "...", "latestVersion": "...", "updateAvailable": false, "updateRequired": false, "channel": "stable"}
+
+With --check the command exits non-zero when an update is required or the server
+can't be reached, so CI scripts can gate on it.`,
 	Run: func(cmd *cobra.Command, args []string) {
+		asJSON, _ := cmd.Flags().GetBool("json")
+		check, _ := cmd.Flags().GetBool("check")
+
+		buildInfo := version.GetBuildInfo()
+		output := versionOutput{
+			Version:   buildInfo.Version,
+			Commit:    buildInfo.GitCommit,
+			BuildDate: buildInfo.BuildTime,
+			GoVersion: buildInfo.GoVersion,
+			OS:        runtime.GOOS,
+			Arch:      runtime.GOARCH,
+		}
+
+		var checkErr error
+		if check {
+			output.Compatibility, checkErr = checkServerCompatibility()
+			if checkErr != nil {
+				output.Error = checkErr.Error()
+			}
+		}
+		failed := checkErr != nil || (output.Compatibility != nil && output.Compatibility.UpdateRequired)
+
+		if asJSON {
+			data, err := json.MarshalIndent(output, "", "  ")
+			if err != nil {
+				logger.Error("Failed to marshal version: %v", err)
+				os.Exit(1)
+			}
+			fmt.Println(string(data))
+			if failed {
+				os.Exit(1)
+			}
+			return
+		}
+
 		logger.Info("GiraffeCloud version: %s", version.Info())
+		if !check {
+			return
+		}
+		if checkErr != nil {
+			logger.Error("Could not check server version: %v", checkErr)
+			os.Exit(1)
+		}
+		compat := output.Compatibility
+		logger.Info("Channel: %s", compat.Channel)
+		logger.Info("Latest version: %s", compat.LatestVersion)
+		logger.Info("Minimum version: %s", compat.MinimumVersion)
+		if compat.UpdateRequired {
+			logger.Error("❌ Update required: run 'giraffecloud update'")
+			os.Exit(1)
+		}
+		if compat.UpdateAvailable {
+			logger.Info("📢 Update available: %s -> %s", output.Version, compat.LatestVersion)
+		} else {
+			logger.Info("✅ Client version %s is compatible", output.Version)
+		}
 	},
 }
 
+// versionOutput is what 'giraffecloud version --json' prints
+type versionOutput struct {
+	Version       string                `json:"version"`
+	Commit        string                `json:"commit"`
+	BuildDate     string                `json:"buildDate"`
+	GoVersion     string                `json:"goVersion"`
+	OS            string                `json:"os"`
+	Arch          string                `json:"arch"`
+	Compatibility *versionCompatibility `json:"compatibility,omitempty"` // With --check
+	Error         string                `json:"error,omitempty"`         // Why --check failed
+}
+
+// versionCompatibility is the API server's verdict on this client version
+type versionCompatibility struct {
+	MinimumVersion  string `json:"minimumVersion"`
+	LatestVersion   string `json:"latestVersion"`
+	UpdateAvailable bool   `json:"updateAvailable"`
+	UpdateRequired  bool   `json:"updateRequired"`
+	Channel         string `json:"channel"`
+}
+
+// checkServerCompatibility asks the configured API server about this client version
+func checkServerCompatibility() (*versionCompatibility, error) {
+	cfg, err := tunnel.LoadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	apiServerURL := fmt.Sprintf("https://%s:%d", cfg.API.Host, cfg.API.Port)
+	channel := tunnel.ResolveReleaseChannel()
+
+	versionInfo, err := version.CheckServerVersionWithChannel(apiServerURL, channel)
+	if err != nil {
+		return nil, err
+	}
+	compat := &versionCompatibility{
+		MinimumVersion:  versionInfo.MinimumVersion,
+		LatestVersion:   versionInfo.LatestVersion,
+		UpdateAvailable: versionInfo.UpdateAvailable,
+		UpdateRequired:  versionInfo.UpdateRequired,
+		Channel:         versionInfo.Channel,
+	}
+	if compat.Channel == "" {
+		compat.Channel = channel
+	}
+	if compat.Channel == "" {
+		compat.Channel = "stable"
+	}
+	return compat, nil
+}
+
 var loginCmd = &cobra.Command{
 	Use:   "login",
 	Short: "Login to GiraffeCloud and obtain certificates",
@@ -280,7 +676,7 @@ After successful login, use 'giraffecloud connect' to establish a tunnel connect
 Example:
   giraffecloud login --token your-api-token`,
 	Run: func(cmd *cobra.Command, args []string) {
-		cfg, err := tunnel.LoadConfig()
+		cfg, err := tunnel.LoadOrCreateConfig()
 		if err != nil {
 			logger.Error("Error loading config: %v", err)
 			os.Exit(1)
@@ -303,26 +699,40 @@ Example:
 		token, _ := cmd.Flags().GetString("token")
 		cfg.Token = token
 
-		// Create certificates directory
-		homeDir, err := os.UserHomeDir()
+		// Create certificates directory next to the config file in use
+		configPath, err := tunnel.GetConfigPath()
 		if err != nil {
-			logger.Error("Failed to get home directory: %v", err)
+			logger.Error("Failed to determine config path: %v", err)
 			os.Exit(1)
 		}
-		certsDir := filepath.Join(homeDir, ".giraffecloud", "certs")
+		certsDir := filepath.Join(filepath.Dir(configPath), "certs")
 		if err := os.MkdirAll(certsDir, 0700); err != nil {
 			logger.Error("Failed to create certificates directory: %v", err)
 			os.Exit(1)
 		}
 
+		insecure, _ := cmd.Flags().GetBool("insecure")
+		if insecure {
+			logger.Warn("⚠️  --insecure: TLS certificate verification of %s is DISABLED. Never use this in production.", cfg.API.Host)
+		}
+
 		// Fetch certificates from API server
-		certResp, err := handlers.FetchCertificates(cfg.API.Host, cfg.API.Port, cfg.Token)
+		certResp, err := handlers.FetchCertificates(cfg.API.Host, cfg.API.Port, cfg.Token, insecure)
 		if err != nil {
 			logger.Error("Failed to fetch certificates: %v", err)
 			os.Exit(1)
 		}
 		logger.Info("Successfully downloaded certificates")
 
+		// Verify before writing so a bad download doesn't leave broken files behind
+		if len(certResp.Checksums) == 0 {
+			logger.Warn("Server did not send certificate checksums, skipping integrity check")
+		}
+		if err := certResp.Verify(); err != nil {
+			logger.Error("Downloaded certificates are invalid: %v", err)
+			os.Exit(1)
+		}
+
 		// Save certificates to files
 		files := map[string]string{
 			"ca.crt":     certResp.CACert,
@@ -351,7 +761,7 @@ Example:
 
 		logger.Info("Successfully logged in to GiraffeCloud")
 		logger.Info("API server: %s:%d", cfg.API.Host, cfg.API.Port)
-		logger.Info("Tunnel server: %s:%d", cfg.Server.Host, cfg.Server.Port)
+		logger.Info("Tunnel server: %s (gRPC: %s)", cfg.Server.TCPAddr(), cfg.Server.GRPCAddr())
 		logger.Info("Certificates stored in: %s", certsDir)
 		logger.Info("Run 'giraffecloud connect' to establish a tunnel connection")
 		logger.Info("Run 'giraffecloud service install' to install the tunnel as a service and not have to run it manually")
@@ -361,11 +771,15 @@ Example:
 var statusCmd = &cobra.Command{
 	Use:   "status",
 	Short: "Show tunnel connection status and statistics",
-	Long:  `Display the current status of the tunnel connection, including connection state, retry count, and other statistics.`,
+	Long: `Display the current status of the tunnel connection, including connection state, retry count, and other statistics.
+
+If a tunnel is running ('giraffecloud connect' or the system service), its live state is
+read over the local control socket. Otherwise the server reachability is checked instead.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		// Note: This is a simplified status check
-		// In a real implementation, you'd want to connect to a running tunnel process
-		// or read status from a shared file/socket
+		// Prefer live state from a running tunnel process
+		if showLiveStatus() {
+			return
+		}
 
 		cfg, err := tunnel.LoadConfig()
 		if err != nil {
@@ -377,7 +791,13 @@ var statusCmd = &cobra.Command{
 		logger.Info("Configuration:")
 		logger.Info("  Domain: %s", cfg.Domain)
 		logger.Info("  Local Port: %d", cfg.LocalPort)
-		logger.Info("  Server: %s:%d", cfg.Server.Host, cfg.Server.Port)
+		if cfg.FallbackLocalPort != 0 {
+			logger.Info("  Fallback Local Port: %d", cfg.FallbackLocalPort)
+		}
+		logger.Info("  Server: %s (gRPC: %s)", cfg.Server.TCPAddr(), cfg.Server.GRPCAddr())
+		if hosts := cfg.Server.AllHosts(); len(hosts) > 1 {
+			logger.Info("  Failover Servers: %s", strings.Join(hosts[1:], ", "))
+		}
 
 		if cfg.Security.CACert != "" {
 			logger.Info("  CA Certificate: %s", cfg.Security.CACert)
@@ -401,13 +821,20 @@ var statusCmd = &cobra.Command{
 			}
 		}
 
+		expiries, err := tunnel.CheckCertExpiry(cfg.Security)
+		if err != nil {
+			logger.Info("  Status: ❌ %v", err)
+			return
+		}
+		for _, expiry := range expiries {
+			logCertExpiry(expiry, cfg.Security.CertExpiryWarning)
+		}
+
 		// Try to connect briefly to check server availability
 		logger.Info("Checking server connectivity...")
 
 		// Create TLS config
-		tlsConfig := &tls.Config{
-			InsecureSkipVerify: cfg.Security.InsecureSkipVerify,
-		}
+		tlsConfig := &tls.Config{}
 
 		// Load CA certificate if provided
 		if cfg.Security.CACert != "" {
@@ -436,12 +863,14 @@ var statusCmd = &cobra.Command{
 			tlsConfig.Certificates = []tls.Certificate{cert}
 		}
 
-		serverAddr := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port)
-		dialer := &net.Dialer{
-			Timeout: 5 * time.Second,
+		serverAddr := cfg.Server.TCPAddr()
+		dialer := tunnel.NewServerDialer(cfg)
+		if dialer == nil {
+			dialer = &tunnel.ServerDialer{}
 		}
+		dialer.Timeout = 5 * time.Second
 
-		conn, err := tls.DialWithDialer(dialer, "tcp", serverAddr, tlsConfig)
+		conn, err := dialer.DialTLS("tcp", serverAddr, tlsConfig)
 		if err != nil {
 			logger.Info("  Status: ❌ Cannot connect to server: %v", err)
 			logger.Info("  Suggestion: Check if server is running or try 'giraffecloud connect'")
@@ -454,6 +883,111 @@ var statusCmd = &cobra.Command{
 	},
 }
 
+// showLiveStatus prints the stats of a running tunnel via the control socket.
+// Returns false if no running tunnel answered, so the caller can fall back.
+func showLiveStatus() bool {
+	pid, err := tunnel.RunningTunnelPID()
+	if err != nil {
+		return false
+	}
+	resp, err := tunnel.SendControlCommand("status")
+	if err != nil {
+		logger.Warn("Tunnel is running (PID %d) but could not be queried: %v", pid, err)
+		return false
+	}
+	if !resp.OK {
+		logger.Warn("Running tunnel returned an error: %s", resp.Message)
+		return false
+	}
+
+	var stats map[string]interface{}
+	if err := json.Unmarshal(resp.Data, &stats); err != nil {
+		logger.Warn("Failed to decode tunnel status: %v", err)
+		return false
+	}
+
+	if tunnels, ok := stats["tunnels"].(map[string]interface{}); ok {
+		showMultiTunnelStatus(pid, stats, tunnels)
+		return true
+	}
+
+	logger.Info("=== GiraffeCloud Tunnel Status (live) ===")
+	logger.Info("  PID: %d", pid)
+	logger.Info("  Domain: %v", stats["domain"])
+	logger.Info("  Local Port: %v", stats["local_port"])
+	if localService, ok := stats["local_service"]; ok {
+		logger.Info("  Local Service: %v", localService)
+	}
+	logger.Info("  State: %v", stats["state"])
+	if stats["state"] == tunnel.StateQuotaExceeded.String() {
+		logger.Info("  ⚠️  Monthly bandwidth quota reached - the tunnel resumes when the quota resets or the plan is upgraded")
+	}
+	logger.Info("  Retry Count: %v", stats["retry_count"])
+	if lastError, ok := stats["last_error"]; ok {
+		logger.Info("  Last Error: %v", lastError)
+	}
+	logLiveCertExpiry(stats)
+
+	if grpcEnabled, _ := stats["grpc_enabled"].(bool); grpcEnabled {
+		logger.Info("gRPC:")
+		logger.Info("  Connected: %v", stats["grpc_connected"])
+		logger.Info("  Requests: %v, Responses: %v", stats["grpc_requests"], stats["grpc_responses"])
+		logger.Info("  Errors: %v (timeouts: %v)", stats["grpc_errors"], stats["grpc_timeout_errors"])
+		logger.Info("  Reconnects: %v (timeout-triggered: %v)", stats["grpc_reconnects"], stats["grpc_timeout_reconnects"])
+		logger.Info("Traffic:")
+		logger.Info("  Received: %s", formatBytes(stats["bytes_in"]))
+		logger.Info("  Sent: %s", formatBytes(stats["bytes_out"]))
+	}
+	return true
+}
+
+// showMultiTunnelStatus prints live status for a 'connect --tunnel-config' process
+func showMultiTunnelStatus(pid int, stats map[string]interface{}, tunnels map[string]interface{}) {
+	logger.Info("=== GiraffeCloud Tunnel Status (live, %v/%v connected) ===", stats["connected"], stats["tunnel_count"])
+	logger.Info("  PID: %d", pid)
+
+	domains := make([]string, 0, len(tunnels))
+	for domain := range tunnels {
+		domains = append(domains, domain)
+	}
+	sort.Strings(domains)
+
+	for _, domain := range domains {
+		t, _ := tunnels[domain].(map[string]interface{})
+		logger.Info("  %s -> port %v: %v (received %s, sent %s)",
+			domain, t["local_port"], t["state"], formatBytes(t["bytes_in"]), formatBytes(t["bytes_out"]))
+		if lastError, ok := t["last_error"]; ok {
+			logger.Info("    Last Error: %v", lastError)
+		}
+	}
+	// Every tunnel uses the same certificates
+	if len(domains) > 0 {
+		t, _ := tunnels[domains[0]].(map[string]interface{})
+		logLiveCertExpiry(t)
+	}
+	logger.Info("Traffic (all tunnels):")
+	logger.Info("  Received: %s", formatBytes(stats["bytes_in"]))
+	logger.Info("  Sent: %s", formatBytes(stats["bytes_out"]))
+}
+
+// formatBytes renders a JSON-decoded byte count in human-readable units
+func formatBytes(v interface{}) string {
+	n, ok := v.(float64)
+	if !ok {
+		return "n/a"
+	}
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%.0f B", n)
+	}
+	div, exp := float64(unit), 0
+	for n/div >= unit && exp < 4 {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", n/div, "KMGTP"[exp])
+}
+
 func checkVersionCompatibility(apiServerURL string) {
 	logger.Info("🔍 Checking client version compatibility...")
 
@@ -489,9 +1023,21 @@ func checkVersionCompatibility(apiServerURL string) {
 func init() {
 	// Normalize config home BEFORE any file paths/loggers expand ~ or read HOME
 	tunnel.EnsureConsistentConfigHome()
+	// Load env files before the logger and config read LOG_LEVEL and friends
+	envFiles, err := tunnel.LoadEnvFiles(envFileArg())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
 	// Initialize logger after home normalization so file paths are correct
 	initLogger()
-	logger.Info("🦒 Initializing GiraffeCloud CLI %s 🦒", version.Info())
+	for _, file := range envFiles {
+		logger.Debug("Loaded environment from %s", file)
+	}
+	// Keep stdout clean for machine-readable output
+	if !wantsJSONOutput() && !wantsPrintURL() {
+		logger.Info("🦒 Initializing GiraffeCloud CLI %s 🦒", version.Info())
+	}
 
 	// Setup core commands
 	rootCmd.AddCommand(connectCmd)
@@ -500,6 +1046,14 @@ func init() {
 	rootCmd.AddCommand(versionCmd)
 	rootCmd.AddCommand(serviceCmd)
 	rootCmd.AddCommand(configCmd)
+	rootCmd.AddCommand(reloadCmd)
+	rootCmd.AddCommand(tunnelsCmd)
+	rootCmd.AddCommand(logsCmd)
+	rootCmd.AddCommand(usageCmd)
+	rootCmd.AddCommand(certCmd)
+	rootCmd.AddCommand(signCmd)
+	rootCmd.AddCommand(stopCmd)
+	rootCmd.AddCommand(testConnectionCmd)
 
 	// Setup update commands (from update.go)
 	initUpdateCommands()
@@ -513,14 +1067,66 @@ func init() {
 	// Setup config commands (from config.go)
 	initConfigCommands()
 
+	// Setup tunnels commands (from tunnels.go)
+	initTunnelsCommands()
+
+	// Setup cert commands (from certs.go)
+	initCertCommands()
+
+	// Setup logs command (from logs.go)
+	initLogsCommand()
+	initUsageCommand()
+	initSignCommand()
+	initStopCommand()
+	initTestConnectionCommand()
+
 	// Add host flags to connect command
 	connectCmd.Flags().String("tunnel-host", "", "Tunnel host to connect to (default: tunnel.giraffecloud.xyz)")
-	connectCmd.Flags().Int("tunnel-port", 4443, "Tunnel port to connect to (default: 4443)")
+	connectCmd.Flags().Int("tunnel-port", tunnel.DefaultTCPTunnelPort, "TCP tunnel port to connect to (default: server.tcp_port from config, or 4443)")
+	connectCmd.Flags().Int("grpc-port", 0, "gRPC tunnel port to connect to (default: server.grpc_port from config, or 4444)")
 	connectCmd.Flags().String("domain", "", "Domain of the tunnel to connect to (required if you have multiple tunnels)")
+	connectCmd.Flags().Uint32("tunnel-id", 0, "ID of the tunnel to connect to (see 'giraffecloud tunnels list')")
+	connectCmd.Flags().String("local-host", "", "Host of the local service to forward traffic to (default: localhost)")
+	connectCmd.Flags().Int("fallback-port", 0, "Forward to this local port when nothing accepts connections on the main one (default: fallback_local_port from config, or off)")
+	connectCmd.Flags().String("local-scheme", "", "Scheme of the local service: http or https (default: local_scheme from config, or http)")
+	connectCmd.Flags().String("local-path-prefix", "", "Prepend this path to every request before forwarding it, e.g. /app serves https://domain/foo from localhost:PORT/app/foo (default: local_path_prefix from config)")
+	connectCmd.Flags().String("public-path-strip", "", "Remove this public path prefix from requests under it before forwarding, e.g. /api serves https://domain/api/foo from localhost:PORT/foo (default: public_path_strip from config)")
+	connectCmd.Flags().Bool("local-http2", false, "Speak HTTP/2 to the local service (h2c for http), e.g. for gRPC services")
+	connectCmd.Flags().Int("udp-port", 0, "Also forward UDP datagrams (game servers, DNS) to this local port; the server assigns a public UDP port (default: udp_port from config, or off)")
+	connectCmd.Flags().Int("udp-public-port", 0, "Public UDP port to ask the server for, within its UDP port range (default: any free port)")
+	connectCmd.Flags().Duration("local-health-interval", 0, "Poll the local service this often to recover quickly when it restarts, e.g. 2s (default: retry.local_health_interval from config, or off)")
+	connectCmd.Flags().Duration("local-timeout", 0, "Timeout for regular requests to the local service, e.g. 30s or 5m (default: 2m; large downloads get 10m, streamed responses are only timed until headers arrive)")
+	connectCmd.Flags().Bool("insecure", false, "Skip TLS certificate verification of the tunnel server (testing only)")
+	connectCmd.Flags().StringArray("resolve", nil, "Connect to host at the given IP instead of resolving it, as host=ip (repeatable; overrides dns_override in the config)")
+	connectCmd.Flags().String("dns-server", "", "DNS server (host[:port]) to resolve the tunnel server through instead of the system resolver")
+	connectCmd.Flags().String("proxy", "", "Reach the tunnel server through a proxy: http://, https://, socks5:// or socks5h:// URL, with optional user:password@ (default: HTTPS_PROXY/ALL_PROXY/HTTP_PROXY, honouring NO_PROXY; \"direct\" to ignore them)")
+	connectCmd.Flags().String("usage-file", "", "File to record per-domain traffic totals to (default: usage.json in the config directory)")
+	connectCmd.Flags().Bool("no-usage", false, "Don't record traffic totals for 'giraffecloud usage'")
+	connectCmd.Flags().String("tunnel-config", "", "YAML file listing several tunnels ({domain, localPort}) to run from one process")
+	connectCmd.Flags().Bool("daemon", false, "Run the tunnel in the background (stop it with 'giraffecloud stop')")
+	connectCmd.Flags().Bool("foreground", false, "Run the tunnel in this terminal (the default)")
+	connectCmd.Flags().Bool("print-url", false, "Print just the public URL (https://<domain>) to stdout once connected, with logs on stderr, for scripts and test harnesses")
+	connectCmd.Flags().Duration("wait-for-local", 0, "Wait for the local service to listen before connecting instead of failing at once, for at most the given time, e.g. --wait-for-local=30s")
+	connectCmd.Flags().Lookup("wait-for-local").NoOptDefVal = tunnel.DefaultLocalWaitTimeout.String()
+	connectCmd.Flags().Bool("reconnect-on-sighup", false, "Reload the certificates from disk and reconnect on SIGHUP, for cert-management tooling that rotates them (Windows: set the Global\\GiraffeCloudReloadCertificates event)")
+	connectCmd.Flags().Bool("once", false, "Don't reconnect: exit non-zero if the tunnel can't connect or when it drops (for CI and ephemeral environments)")
+
+	versionCmd.Flags().Bool("json", false, "Print version information as JSON")
+	versionCmd.Flags().Bool("check", false, "Also check compatibility with the API server (exits non-zero if an update is required)")
 
 	// Global version flags on root: giraffecloud -v / --version
 	rootCmd.PersistentFlags().BoolVarP(&rootVersionFlag, "version", "v", false, "Print version information and exit")
+	// Global config file override: giraffecloud --config /path/to/config.json
+	rootCmd.PersistentFlags().StringVar(&rootConfigFlag, "config", "", "Path to the JSON config file (default: ~/.giraffecloud/config.json); login creates it if missing")
+	// Env file loaded before anything else (see envFileArg); registered so cobra accepts it
+	rootCmd.PersistentFlags().String("env-file", "", "Load environment variables (e.g. LOG_LEVEL) from this file; ~/.giraffecloud/.env is also loaded when present")
 	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		if rootConfigFlag != "" {
+			if err := tunnel.SetConfigPathOverride(rootConfigFlag); err != nil {
+				return err
+			}
+			logger.Debug("Using config file override: %s", rootConfigFlag)
+		}
 		// Only intercept when root is the executing command (no subcommand specified)
 		if cmd == rootCmd && rootVersionFlag {
 			logger.Info("GiraffeCloud version: %s", version.Info())
@@ -534,6 +1140,7 @@ func init() {
 	loginCmd.Flags().Int("api-port", 0, "API port for login/certificates")
 	loginCmd.Flags().String("token", "", "API token for authentication")
 	loginCmd.MarkFlagRequired("token")
+	loginCmd.Flags().Bool("insecure", false, "Skip TLS certificate verification of the API server (testing only)")
 
 	logger.Debug("CLI commands and flags initialized")
 }
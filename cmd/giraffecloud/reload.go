@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/osa911/giraffecloud/internal/tunnel"
+
+	"github.com/spf13/cobra"
+)
+
+var reloadCmd = &cobra.Command{
+	Use:   "reload",
+	Short: "Reload configuration of the running tunnel without reconnecting",
+	Long: `Ask the running tunnel process to re-read its config file and apply the
+hot-reloadable settings without dropping the gRPC tunnel.
+
+Hot-reloadable (applied immediately):
+  - streaming   Streaming buffer, timeout and media detection settings
+  - retry       Reconnect retry/backoff settings and local service polling
+
+Require a reconnect ('giraffecloud service restart' or re-running 'connect'):
+  - token, domain, local_host, local_port
+  - server, api, security (certificates)
+
+Example:
+  giraffecloud reload`,
+	Run: func(cmd *cobra.Command, args []string) {
+		pid, err := tunnel.RunningTunnelPID()
+		if errors.Is(err, tunnel.ErrNoRunningInstance) {
+			fmt.Println("❌ No running tunnel found. Start one with 'giraffecloud connect' or 'giraffecloud service start'.")
+			os.Exit(1)
+		}
+		if err != nil {
+			logger.Error("Failed to locate the running tunnel: %v", err)
+			os.Exit(1)
+		}
+
+		resp, err := tunnel.SendControlCommand("reload")
+		if err != nil {
+			if errors.Is(err, tunnel.ErrNoRunningInstance) {
+				fmt.Printf("❌ Tunnel is running (PID %d) but not answering on its control socket. Restart it to apply the config.\n", pid)
+				os.Exit(1)
+			}
+			logger.Error("Failed to reload: %v", err)
+			os.Exit(1)
+		}
+
+		if !resp.OK {
+			fmt.Printf("❌ Reload failed: %s\n", resp.Message)
+			os.Exit(1)
+		}
+
+		var applied []string
+		if len(resp.Data) > 0 {
+			_ = json.Unmarshal(resp.Data, &applied)
+		}
+		if len(applied) == 0 {
+			fmt.Println("✅ Configuration reloaded (no hot-reloadable settings found in config)")
+			return
+		}
+		fmt.Printf("✅ Configuration reloaded: %s\n", strings.Join(applied, ", "))
+	},
+}
+
+// reloadTunnelConfig reloads the config file and applies hot-reloadable settings to t
+func reloadTunnelConfig(t *tunnel.Tunnel) (interface{}, error) {
+	cfg, err := tunnel.LoadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	applied := t.ApplyReloadableConfig(cfg)
+	if applied == nil {
+		applied = []string{}
+	}
+	return applied, nil
+}
@@ -1,9 +1,13 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"maps"
 	"net"
 	"os"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/osa911/giraffecloud/internal/tunnel"
@@ -21,18 +25,28 @@ var serviceCmd = &cobra.Command{
 var installCmd = &cobra.Command{
 	Use:   "install",
 	Short: "Install GiraffeCloud as a system service",
+	Long: `Install GiraffeCloud as a system service (systemd, launchd or Windows SC).
+
+Use --dry-run to print the unit file contents and the commands that would be run,
+without touching the filesystem or invoking sudo.`,
 	Run: func(cmd *cobra.Command, args []string) {
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
 		sm, err := tunnel.NewServiceManager()
 		if err != nil {
 			logger.Error("Failed to create service manager: %v", err)
 			os.Exit(1)
 		}
 
-		if err := sm.Install(); err != nil {
+		if err := sm.Install(dryRun); err != nil {
 			logger.Error("Failed to install service: %v", err)
 			os.Exit(1)
 		}
 
+		if dryRun {
+			fmt.Println("# Dry run complete - no changes were made")
+			return
+		}
 		logger.Info("Successfully installed GiraffeCloud service")
 	},
 }
@@ -152,7 +166,7 @@ This helps diagnose issues with the system service.`,
 
 			// Check tunnel server connectivity
 			logger.Info("🔍 Testing tunnel server connectivity...")
-			serverAddr := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port)
+			serverAddr := cfg.Server.TCPAddr()
 
 			conn, err := net.DialTimeout("tcp", serverAddr, 10*time.Second)
 			if err != nil {
@@ -185,7 +199,7 @@ This helps diagnose issues with the system service.`,
 		if cfg != nil {
 			logger.Info("Domain: %s", cfg.Domain)
 			logger.Info("Local Port: %d", cfg.LocalPort)
-			logger.Info("Tunnel Server: %s:%d", cfg.Server.Host, cfg.Server.Port)
+			logger.Info("Tunnel Server: %s (gRPC: %s)", cfg.Server.TCPAddr(), cfg.Server.GRPCAddr())
 		}
 
 		if isInstalled && isRunning {
@@ -207,6 +221,7 @@ This helps diagnose issues with the system service.`,
 // initServiceCommands sets up all service-related commands and their flags
 func initServiceCommands() {
 	// Add subcommands to service
+	installCmd.Flags().Bool("dry-run", false, "Print the unit file and commands without making any changes")
 	serviceCmd.AddCommand(installCmd)
 	serviceCmd.AddCommand(uninstallCmd)
 	serviceCmd.AddCommand(healthCheckCmd)
@@ -274,29 +289,55 @@ func initServiceCommands() {
 	statusCmd := &cobra.Command{
 		Use:   "status",
 		Short: "Show service status",
+		Long: `Show whether the tunnel service is installed and running, with its unit path,
+PID and uptime.
+
+Use --json for machine-readable output, e.g. for monitoring scripts:
+  {"installed": true, "running": true, "unitPath": "...", "pid": 1234, "uptime": 3600}
+
+uptime is in seconds (0 when not running or unknown).`,
 		Run: func(cmd *cobra.Command, args []string) {
+			asJSON, _ := cmd.Flags().GetBool("json")
 			sm, err := tunnel.NewServiceManager()
 			if err != nil {
 				logger.Error("Failed to create service manager: %v", err)
 				os.Exit(1)
 			}
-			installed, ierr := sm.IsInstalled()
-			running, rerr := sm.IsRunning()
-			if ierr != nil {
-				logger.Error("Failed to check installation: %v", ierr)
+			status, err := sm.Status()
+			if err != nil {
+				logger.Error("Failed to get service status: %v", err)
+				os.Exit(1)
+			}
+
+			if asJSON {
+				data, err := json.MarshalIndent(status, "", "  ")
+				if err != nil {
+					logger.Error("Failed to marshal status: %v", err)
+					os.Exit(1)
+				}
+				fmt.Println(string(data))
+				return
+			}
+
+			logger.Info("Installed: %v", status.Installed)
+			logger.Info("Running: %v", status.Running)
+			if status.Installed {
+				logger.Info("Unit: %s", status.UnitPath)
+			}
+			if status.Running && status.PID > 0 {
+				logger.Info("PID: %d", status.PID)
 			}
-			if rerr != nil {
-				logger.Error("Failed to check running state: %v", rerr)
+			if status.Uptime > 0 {
+				logger.Info("Uptime: %s", time.Duration(status.Uptime)*time.Second)
 			}
-			logger.Info("Installed: %v", installed)
-			logger.Info("Running: %v", running)
-			if !installed {
+			if !status.Installed {
 				logger.Info("Tip: Run 'giraffecloud service install'")
-			} else if !running {
+			} else if !status.Running {
 				logger.Info("Tip: Run 'giraffecloud service start'")
 			}
 		},
 	}
+	statusCmd.Flags().Bool("json", false, "Print status as JSON")
 	serviceCmd.AddCommand(statusCmd)
 
 	// Logs
@@ -332,6 +373,62 @@ func initServiceCommands() {
 	}
 	serviceCmd.AddCommand(logsCmd)
 
+	// Edit service environment
+	editEnvCmd := &cobra.Command{
+		Use:   "edit-env [KEY=VALUE...]",
+		Short: "Set or unset environment variables of the service",
+		Long: `Set or unset environment variables of the installed service, then restart it so
+they take effect. This updates the Environment= lines of the systemd unit, the
+EnvironmentVariables of the launchd plist, or the registry Environment value on Windows.
+
+Without arguments, prints the variables currently set for the service.
+
+Examples:
+  giraffecloud service edit-env LOG_LEVEL=debug
+  giraffecloud service edit-env HTTPS_PROXY=http://proxy.local:3128 --unset HTTP_PROXY
+  giraffecloud service edit-env --unset LOG_LEVEL --no-restart`,
+		Run: func(cmd *cobra.Command, args []string) {
+			unset, _ := cmd.Flags().GetStringSlice("unset")
+			noRestart, _ := cmd.Flags().GetBool("no-restart")
+			sm, err := tunnel.NewServiceManager()
+			if err != nil {
+				logger.Error("Failed to create service manager: %v", err)
+				os.Exit(1)
+			}
+
+			if len(args) == 0 && len(unset) == 0 {
+				env, err := sm.ServiceEnv()
+				if err != nil {
+					logger.Error("Failed to read service environment: %v", err)
+					os.Exit(1)
+				}
+				for _, key := range slices.Sorted(maps.Keys(env)) {
+					fmt.Printf("%s=%s\n", key, env[key])
+				}
+				return
+			}
+
+			set := make(map[string]string, len(args))
+			for _, arg := range args {
+				key, value, ok := strings.Cut(arg, "=")
+				if !ok {
+					logger.Error("Invalid assignment %q: expected KEY=VALUE", arg)
+					os.Exit(1)
+				}
+				set[key] = value
+			}
+			if err := sm.EditEnv(set, unset, !noRestart); err != nil {
+				logger.Error("Failed to update service environment: %v", err)
+				logger.Info("Tip: You may need elevated privileges (try with sudo)")
+				os.Exit(1)
+			}
+			logger.Info("Service environment updated")
+		},
+	}
+	editEnvCmd.Flags().StringSlice("unset", nil, "Remove a variable from the service environment (repeatable)")
+	editEnvCmd.Flags().Bool("no-restart", false, "Update the service definition without restarting the service")
+	serviceCmd.AddCommand(editEnvCmd)
+
 	// Singleton status command
 	singletonCmd := &cobra.Command{
 		Use:   "singleton",
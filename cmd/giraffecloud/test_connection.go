@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/osa911/giraffecloud/internal/tunnel"
+
+	"github.com/spf13/cobra"
+)
+
+var testConnectionCmd = &cobra.Command{
+	Use:   "test-connection [path]",
+	Short: "Send a request through the tunnel and check it reaches the local service",
+	Long: `Send a GET to https://<domain>/<path> through the public internet, the server and the
+running tunnel, and report the status, latency and the transport (gRPC or TCP) that carried it.
+
+Unlike 'giraffecloud status', which only checks that the server is reachable, this
+verifies the whole path to your local service. Any status your service returns (even a
+404) counts as reaching it; errors the server answers itself, like "tunnel not connected",
+don't. The exit code is 1 when the request didn't reach the local service.
+
+Examples:
+  giraffecloud test-connection
+  giraffecloud test-connection /health
+  giraffecloud test-connection /api/ping --domain api.example.com`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		domain, _ := cmd.Flags().GetString("domain")
+		if domain == "" {
+			cfg, err := tunnel.LoadConfig()
+			if err != nil {
+				logger.Error("Error loading config: %v", err)
+				os.Exit(1)
+			}
+			domain = cfg.Domain
+		}
+		if domain == "" {
+			logger.Error("No domain configured - pass --domain or run 'giraffecloud connect' first")
+			os.Exit(1)
+		}
+
+		path := "/"
+		if len(args) > 0 {
+			path = args[0]
+			if !strings.HasPrefix(path, "/") {
+				path = "/" + path
+			}
+		}
+
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+		insecure, _ := cmd.Flags().GetBool("insecure")
+		client := &http.Client{
+			Timeout: timeout,
+			// Report redirects from the local service instead of following them off the tunnel
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+			Transport: &http.Transport{
+				Proxy:             http.ProxyFromEnvironment,
+				TLSClientConfig:   &tls.Config{InsecureSkipVerify: insecure},
+				DisableKeepAlives: true, // Latency includes connecting, like a new visitor's
+			},
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		result, err := tunnel.ProbeTunnel(ctx, client, "https://"+domain+path)
+		if err != nil && result == nil {
+			fmt.Printf("❌ Request to https://%s%s failed: %v\n", domain, path, err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("URL:        %s\n", result.URL)
+		fmt.Printf("Status:     %d %s\n", result.StatusCode, http.StatusText(result.StatusCode))
+		fmt.Printf("Latency:    %v (headers), %v total, %d bytes\n", result.Latency.Round(time.Millisecond), result.Total.Round(time.Millisecond), result.BodyBytes)
+		switch {
+		case result.Transport != "":
+			fmt.Printf("Served via: %s\n", strings.ToUpper(result.Transport))
+		case result.RouterError:
+			fmt.Println("Served via: the server itself (tunnel error)")
+		default:
+			fmt.Println("Served via: unknown (no X-Tunnel-Transport header)")
+		}
+		if result.CacheStatus != "" {
+			fmt.Printf("Cache:      %s\n", result.CacheStatus)
+		}
+		if err != nil {
+			fmt.Printf("⚠️  %v\n", err)
+		}
+
+		if !result.Reached() {
+			switch {
+			case result.RouterError:
+				fmt.Println("❌ The server answered without reaching your local service - check the 'giraffecloud connect' logs")
+			case result.StatusCode >= 500:
+				fmt.Println("❌ The tunnel does not look connected - is 'giraffecloud connect' running?")
+			default:
+				fmt.Println("❌ The response did not come through a tunnel (the server may be too old to report it)")
+			}
+			os.Exit(1)
+		}
+		fmt.Println("✅ The request reached your local service through the tunnel")
+	},
+}
+
+// initTestConnectionCommand sets up the test-connection command's flags
+func initTestConnectionCommand() {
+	testConnectionCmd.Flags().String("domain", "", "Domain to test (default: domain from config)")
+	testConnectionCmd.Flags().Duration("timeout", 30*time.Second, "Give up on the request after this long")
+	testConnectionCmd.Flags().Bool("insecure", false, "Skip verifying the domain's TLS certificate")
+}
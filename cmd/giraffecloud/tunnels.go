@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/osa911/giraffecloud/internal/api/handlers"
+	"github.com/osa911/giraffecloud/internal/tunnel"
+
+	"github.com/spf13/cobra"
+)
+
+var tunnelsCmd = &cobra.Command{
+	Use:   "tunnels",
+	Short: "Manage your GiraffeCloud tunnels",
+	Long:  `View the tunnels configured for your GiraffeCloud account.`,
+}
+
+var tunnelsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List all tunnels configured for your account",
+	Long: `List all tunnels configured for your account, including their domain,
+target port, status and the address of the currently connected client (if any).
+
+The tunnel marked with '*' is the one 'giraffecloud connect' will use by default.
+
+Example:
+  giraffecloud tunnels list`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, err := tunnel.LoadConfig()
+		if err != nil {
+			logger.Error("Error loading config: %v", err)
+			os.Exit(1)
+		}
+		if cfg.Token == "" {
+			fmt.Println("❌ You must login first: giraffecloud login --token YOUR_API_TOKEN")
+			os.Exit(1)
+		}
+
+		tunnels, err := handlers.FetchTunnels(cfg.API.Host, cfg.API.Port, cfg.Token)
+		if err != nil {
+			logger.Error("Failed to list tunnels: %v", err)
+			os.Exit(1)
+		}
+
+		if len(tunnels) == 0 {
+			fmt.Println("No tunnels configured. Create one at: https://giraffecloud.xyz/dashboard/tunnels")
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "\tID\tDOMAIN\tTARGET PORT\tSTATUS\tCLIENT")
+		for _, t := range tunnels {
+			marker := ""
+			if t.Domain == cfg.Domain {
+				marker = "*"
+			}
+			status := "enabled"
+			if !t.IsEnabled {
+				status = "disabled"
+			}
+			client := t.ClientIP
+			if client == "" {
+				client = "-"
+			}
+			fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%s\n", marker, t.ID, t.Domain, strconv.Itoa(t.TargetPort), status, client)
+		}
+		w.Flush()
+	},
+}
+
+// initTunnelsCommands sets up all tunnels-related commands
+func initTunnelsCommands() {
+	tunnelsCmd.AddCommand(tunnelsListCmd)
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/osa911/giraffecloud/internal/tunnel"
+
+	"github.com/spf13/cobra"
+)
+
+var usageCmd = &cobra.Command{
+	Use:   "usage",
+	Short: "Show bandwidth used by your tunnels",
+	Long: `Show the traffic recorded by 'giraffecloud connect' per domain: requests, bytes
+received from visitors and bytes sent back to them. Totals accumulate across runs in
+usage.json in the config directory until it is deleted or --reset is passed.
+
+Examples:
+  giraffecloud usage                        # Totals from the default usage file
+  giraffecloud usage --file ./usage.json    # Totals from another file (e.g. a server's TUNNEL_USAGE_FILE)
+  giraffecloud usage --reset                # Print the totals, then start counting from zero`,
+	Run: func(cmd *cobra.Command, args []string) {
+		path, _ := cmd.Flags().GetString("file")
+		reset, _ := cmd.Flags().GetBool("reset")
+		if path == "" {
+			defaultPath, err := tunnel.DefaultUsageFilePath()
+			if err != nil {
+				logger.Error("Failed to determine config directory: %v", err)
+				os.Exit(1)
+			}
+			path = defaultPath
+		}
+
+		usage, err := tunnel.ReadUsageFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			fmt.Printf("No usage recorded yet (%s)\n", path)
+			fmt.Println("💡 Usage is recorded while 'giraffecloud connect' runs")
+			return
+		}
+		if err != nil {
+			logger.Error("Failed to read usage: %v", err)
+			os.Exit(1)
+		}
+
+		domains := make([]string, 0, len(usage.Domains))
+		for domain := range usage.Domains {
+			domains = append(domains, domain)
+		}
+		sort.Strings(domains)
+
+		var total tunnel.DomainUsage
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "DOMAIN\tREQUESTS\tRECEIVED\tSENT")
+		for _, domain := range domains {
+			d := usage.Domains[domain]
+			total.Requests += d.Requests
+			total.BytesIn += d.BytesIn
+			total.BytesOut += d.BytesOut
+			fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", domain, d.Requests, formatBytes(float64(d.BytesIn)), formatBytes(float64(d.BytesOut)))
+		}
+		if len(domains) > 1 {
+			fmt.Fprintf(w, "TOTAL\t%d\t%s\t%s\n", total.Requests, formatBytes(float64(total.BytesIn)), formatBytes(float64(total.BytesOut)))
+		}
+		w.Flush()
+		fmt.Printf("\nSince %s (updated %s)\n", usage.Since.Local().Format("2006-01-02 15:04"), usage.UpdatedAt.Local().Format("2006-01-02 15:04"))
+
+		if reset {
+			if err := os.Remove(path); err != nil {
+				logger.Error("Failed to reset usage: %v", err)
+				os.Exit(1)
+			}
+			fmt.Println("✅ Usage reset")
+		}
+	},
+}
+
+// startUsageRecorder records tunnel traffic to the usage file unless --no-usage was
+// given. Returns nil when recording is disabled or the file can't be opened.
+func startUsageRecorder(cmd *cobra.Command) *tunnel.FileUsageRecorder {
+	if noUsage, _ := cmd.Flags().GetBool("no-usage"); noUsage {
+		return nil
+	}
+	path, _ := cmd.Flags().GetString("usage-file")
+	if path == "" {
+		defaultPath, err := tunnel.DefaultUsageFilePath()
+		if err != nil {
+			logger.Warn("Usage recording disabled: %v", err)
+			return nil
+		}
+		path = defaultPath
+	}
+
+	recorder, err := tunnel.NewFileUsageRecorder(path, tunnel.DefaultUsageFlushInterval)
+	if err != nil {
+		logger.Warn("Usage recording disabled: %v", err)
+		return nil
+	}
+	logger.Info("Recording tunnel usage to %s", path)
+	return recorder
+}
+
+// initUsageCommand sets up the usage command
+func initUsageCommand() {
+	usageCmd.Flags().String("file", "", "Usage file to read (default: usage.json in the config directory)")
+	usageCmd.Flags().Bool("reset", false, "Delete the recorded totals after printing them")
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"os"
 	"runtime/debug"
@@ -23,6 +24,15 @@ func main() {
 		}
 	}()
 
+	envFile := flag.String("env-file", "", "Load environment variables (SUBDOMAIN_SECRET, DATABASE_URL, ...) from this file; they take precedence over the default .env files")
+	flag.Parse()
+	if *envFile != "" {
+		if err := config.LoadEnvFile(*envFile); err != nil {
+			fmt.Printf("%v\n", err)
+			os.Exit(1)
+		}
+	}
+
 	// Load configuration
 	cfg, err := config.Load()
 	if err != nil {
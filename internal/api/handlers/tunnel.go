@@ -1,8 +1,13 @@
 package handlers
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/osa911/giraffecloud/internal/api/constants"
 	"github.com/osa911/giraffecloud/internal/api/dto/common"
@@ -257,3 +262,43 @@ func (h *TunnelHandler) UpdateTunnel(c *gin.Context) {
 	response := mapper.TunnelToResponse(tunnel)
 	utils.HandleSuccess(c, response)
 }
+
+// FetchTunnels fetches the authenticated user's tunnels from the API server (used by the CLI)
+func FetchTunnels(apiHost string, apiPort int, token string) ([]tunneldto.Response, error) {
+	url := fmt.Sprintf("https://%s:%d/api/v1/tunnels", apiHost, apiPort)
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch tunnels: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("failed to fetch tunnels (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var apiResp struct {
+		Success bool                  `json:"success"`
+		Data    []tunneldto.Response  `json:"data"`
+		Error   *common.ErrorResponse `json:"error,omitempty"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if !apiResp.Success {
+		if apiResp.Error != nil {
+			return nil, fmt.Errorf("failed to fetch tunnels: %s", apiResp.Error.Message)
+		}
+		return nil, fmt.Errorf("failed to fetch tunnels: unknown error")
+	}
+
+	return apiResp.Data, nil
+}
@@ -3,8 +3,11 @@ package handlers
 import (
 	"crypto/rand"
 	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
+	"encoding/hex"
 	"encoding/json"
 	"encoding/pem"
 	"fmt"
@@ -12,6 +15,7 @@ import (
 	"math/big"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/osa911/giraffecloud/internal/api/constants"
@@ -29,6 +33,47 @@ type CertificateResponse struct {
 	CACert     string `json:"ca_cert"`
 	ClientCert string `json:"client_cert"`
 	ClientKey  string `json:"client_key"`
+
+	// Hex SHA256 of each PEM field above, keyed by its JSON name
+	Checksums map[string]string `json:"checksums,omitempty"`
+}
+
+// certificateChecksums returns the hex SHA256 of each PEM field, keyed by JSON name
+func (r *CertificateResponse) certificateChecksums() map[string]string {
+	sum := func(s string) string {
+		h := sha256.Sum256([]byte(s))
+		return hex.EncodeToString(h[:])
+	}
+	return map[string]string{
+		"ca_cert":     sum(r.CACert),
+		"client_cert": sum(r.ClientCert),
+		"client_key":  sum(r.ClientKey),
+	}
+}
+
+// Verify checks the bundle before it is written to disk: each PEM must match the
+// server's checksum, the client cert and key must form a keypair, and the CA cert
+// must parse. Responses from servers that don't send checksums skip that step.
+func (r *CertificateResponse) Verify() error {
+	if len(r.Checksums) > 0 {
+		for name, actual := range r.certificateChecksums() {
+			expected, ok := r.Checksums[name]
+			if !ok {
+				return fmt.Errorf("server did not send a checksum for %s", name)
+			}
+			if !strings.EqualFold(expected, actual) {
+				return fmt.Errorf("%s checksum mismatch (download corrupted or truncated?) - run 'giraffecloud login' again", name)
+			}
+		}
+	}
+
+	if _, err := tls.X509KeyPair([]byte(r.ClientCert), []byte(r.ClientKey)); err != nil {
+		return fmt.Errorf("client certificate and key do not form a valid keypair: %v - run 'giraffecloud login' again", err)
+	}
+	if !x509.NewCertPool().AppendCertsFromPEM([]byte(r.CACert)) {
+		return fmt.Errorf("CA certificate contains no valid PEM certificates - run 'giraffecloud login' again")
+	}
+	return nil
 }
 
 // IssueClientCertificate issues a new client certificate for the authenticated user
@@ -131,12 +176,14 @@ func (h *TunnelCertificateHandler) IssueClientCertificate(c *gin.Context) {
 		ClientCert: string(clientCertPEM),
 		ClientKey:  string(clientKeyPEM),
 	}
+	resp.Checksums = resp.certificateChecksums()
 	c.Header("Content-Type", "application/json")
 	json.NewEncoder(c.Writer).Encode(resp)
 }
 
-// FetchCertificates fetches client certificates from the API server
-func FetchCertificates(apiHost string, apiPort int, token string) (*CertificateResponse, error) {
+// FetchCertificates fetches client certificates from the API server. insecure skips
+// verification of the API server certificate (--insecure).
+func FetchCertificates(apiHost string, apiPort int, token string, insecure bool) (*CertificateResponse, error) {
 	logger := logging.GetGlobalLogger()
 	logger.Info("Fetching certificates from API server: %s:%d", apiHost, apiPort)
 
@@ -155,6 +202,9 @@ func FetchCertificates(apiHost string, apiPort int, token string) (*CertificateR
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
 
 	client := &http.Client{}
+	if insecure {
+		client.Transport = &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
+	}
 	resp, err := client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch certificates: %w", err)
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestCertificateResponse(t *testing.T) *CertificateResponse {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certPEM := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	resp := &CertificateResponse{
+		CACert:     certPEM,
+		ClientCert: certPEM,
+		ClientKey:  string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})),
+	}
+	resp.Checksums = resp.certificateChecksums()
+	return resp
+}
+
+func TestCertificateResponseVerify(t *testing.T) {
+	if err := newTestCertificateResponse(t).Verify(); err != nil {
+		t.Fatalf("valid bundle failed verification: %v", err)
+	}
+
+	// Older servers send no checksums; the PEM checks still apply
+	resp := newTestCertificateResponse(t)
+	resp.Checksums = nil
+	if err := resp.Verify(); err != nil {
+		t.Fatalf("bundle without checksums failed verification: %v", err)
+	}
+}
+
+func TestCertificateResponseVerifyRejectsTruncated(t *testing.T) {
+	resp := newTestCertificateResponse(t)
+	resp.ClientKey = resp.ClientKey[:len(resp.ClientKey)/2]
+
+	err := resp.Verify()
+	if err == nil || !strings.Contains(err.Error(), "client_key checksum mismatch") {
+		t.Fatalf("Verify() = %v, want client_key checksum mismatch", err)
+	}
+
+	// Without a checksum the keypair check still catches it
+	resp.Checksums = nil
+	if err := resp.Verify(); err == nil || !strings.Contains(err.Error(), "keypair") {
+		t.Fatalf("Verify() = %v, want keypair error", err)
+	}
+}
+
+func TestCertificateResponseVerifyRejectsBadCA(t *testing.T) {
+	resp := newTestCertificateResponse(t)
+	resp.CACert = "not a certificate"
+	resp.Checksums = resp.certificateChecksums()
+
+	if err := resp.Verify(); err == nil || !strings.Contains(err.Error(), "CA certificate") {
+		t.Fatalf("Verify() = %v, want CA certificate error", err)
+	}
+}
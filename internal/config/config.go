@@ -32,6 +32,11 @@ type Config struct {
 	// Caddy Configuration
 	CaddyAdminAPI string `env:"CADDY_ADMIN_API" envDefault:"http://localhost:2019"`
 
+	// Subdomain Configuration (empty values use the built-in word lists and format)
+	SubdomainFormat     string   `env:"SUBDOMAIN_FORMAT"`
+	SubdomainAdjectives []string `env:"SUBDOMAIN_ADJECTIVES"`
+	SubdomainNouns      []string `env:"SUBDOMAIN_NOUNS"`
+
 	// Client Configuration
 	ClientURL string `env:"CLIENT_URL"`
 
@@ -42,6 +47,15 @@ type Config struct {
 	CloudflareToken string `env:"CLOUDFLARE_API_TOKEN"`
 }
 
+// LoadEnvFile loads environment variables from path. Call it before Load: variables
+// already set (by the shell or an earlier file) are kept.
+func LoadEnvFile(path string) error {
+	if err := godotenv.Load(path); err != nil {
+		return fmt.Errorf("failed to load env file %s: %w", path, err)
+	}
+	return nil
+}
+
 // Load loads the configuration from environment variables and .env files
 func Load() (*Config, error) {
 	// Load .env file if it exists
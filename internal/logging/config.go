@@ -6,24 +6,25 @@ import (
 
 // Config holds logging-related configuration
 type Config struct {
-	Level      string `json:"level"`       // debug, info, warn, error
+	Level      string `json:"level"`       // debug, info, warn, error; overrides as in "info,grpc_client=debug"
 	File       string `json:"file"`        // Path to log file
 	MaxSize    int    `json:"max_size"`    // Max size in MB
 	MaxBackups int    `json:"max_backups"` // Number of backups to keep
 	MaxAge     int    `json:"max_age"`     // Max age in days
+	Format     string `json:"format"`      // text (default) or json
 }
 
 // Validate checks if the configuration is valid (used for CLI)
 func (l *Config) Validate() error {
-	validLevels := map[string]bool{
-		"debug": true,
-		"info":  true,
-		"warn":  true,
-		"error": true,
+	if l.Level == "" {
+		return fmt.Errorf("invalid log level: %s", l.Level)
+	}
+	if _, _, err := ParseLevelSpec(l.Level); err != nil {
+		return err
 	}
 
-	if !validLevels[l.Level] {
-		return fmt.Errorf("invalid log level: %s", l.Level)
+	if l.Format != "" && l.Format != "text" && l.Format != "json" {
+		return fmt.Errorf("invalid log format: %s", l.Format)
 	}
 
 	if l.MaxSize <= 0 {
@@ -71,14 +71,75 @@ func ParseLogLevel(level string) (LogLevel, error) {
 	}
 }
 
+// ParseLevelSpec parses a level with optional per-component overrides, comma-separated:
+// "info,grpc_client=debug,media=warn". A component is matched against the [TAG] starting
+// a log line, case-insensitively and with spaces and punctuation read as "_", so
+// grpc_client matches [gRPC CLIENT]. It also covers longer tags it is a prefix of (media
+// matches [MEDIA PROXY]); the longest matching component wins. An empty default is info.
+func ParseLevelSpec(spec string) (LogLevel, map[string]LogLevel, error) {
+	level := LogLevelInfo
+	var components map[string]LogLevel
+	seenDefault := false
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, value, isOverride := strings.Cut(part, "=")
+		if !isOverride {
+			if seenDefault {
+				return LogLevelInfo, nil, fmt.Errorf("invalid log level %q: more than one default level", spec)
+			}
+			var err error
+			if level, err = ParseLogLevel(part); err != nil {
+				return LogLevelInfo, nil, err
+			}
+			seenDefault = true
+			continue
+		}
+		component := componentName(name)
+		if component == "" {
+			return LogLevelInfo, nil, fmt.Errorf("invalid log level %q: empty component name", part)
+		}
+		componentLevel, err := ParseLogLevel(strings.TrimSpace(value))
+		if err != nil {
+			return LogLevelInfo, nil, fmt.Errorf("component %s: %w", component, err)
+		}
+		if components == nil {
+			components = make(map[string]LogLevel)
+		}
+		components[component] = componentLevel
+	}
+	return level, components, nil
+}
+
+// componentName lower-cases a component or tag and turns runs of other characters into "_"
+func componentName(s string) string {
+	var b strings.Builder
+	pendingSep := false
+	for _, r := range strings.ToLower(s) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if pendingSep && b.Len() > 0 {
+				b.WriteByte('_')
+			}
+			pendingSep = false
+			b.WriteRune(r)
+		} else {
+			pendingSep = true
+		}
+	}
+	return b.String()
+}
+
 // LogConfig holds configuration for the logger
 type LogConfig struct {
 	File       string // Log file path
 	MaxSize    int    // Maximum size in megabytes before log rotation
 	MaxBackups int    // Maximum number of old log files to retain
 	MaxAge     int    // Maximum number of days to retain old log files
-	Level      string // Log level (debug, info, warn, error)
+	Level      string // Log level (debug, info, warn, error), optionally with component overrides (see ParseLevelSpec)
 	Format     string // Log format (text, json)
+	Stderr     bool   // Write terminal output to stderr, keeping stdout for machine-readable output
 }
 
 // colorStripper is a custom writer that strips ANSI color codes
@@ -108,6 +169,10 @@ type Logger struct {
 	useColors    bool
 	level        LogLevel
 	slogLogger   *slog.Logger
+
+	// Per-component overrides of level (see ParseLevelSpec), and the lowest of them all
+	components map[string]LogLevel
+	minLevel   LogLevel
 }
 
 // Singleton pattern variables
@@ -139,14 +204,14 @@ func GetGlobalLogger() *Logger {
 
 // newLogger creates a new logger instance (internal function)
 func newLogger(config *LogConfig) (*Logger, error) {
-	// Parse log level
-	level := LogLevelInfo // default
-	if config.Level != "" {
-		var err error
-		level, err = ParseLogLevel(config.Level)
-		if err != nil {
-			return nil, fmt.Errorf("invalid log level: %w", err)
-		}
+	// Parse log level and component overrides
+	level, components, err := ParseLevelSpec(config.Level)
+	if err != nil {
+		return nil, fmt.Errorf("invalid log level: %w", err)
+	}
+	minLevel := level
+	for _, componentLevel := range components {
+		minLevel = min(minLevel, componentLevel)
 	}
 
 	// Expand home directory in log file path
@@ -184,8 +249,11 @@ func newLogger(config *LogConfig) (*Logger, error) {
 		fileDest = newColorStripper(fileWriter)
 	}
 
-	// Always use stdout for terminal output
-	stdoutWriter := os.Stdout
+	// Use stdout for terminal output unless it is reserved for machine-readable output
+	var stdoutWriter io.Writer = os.Stdout
+	if config.Stderr {
+		stdoutWriter = os.Stderr
+	}
 
 	// Create a multi-writer that writes to both file and stdout
 	multiWriter := io.MultiWriter(fileDest, stdoutWriter)
@@ -194,12 +262,15 @@ func newLogger(config *LogConfig) (*Logger, error) {
 	logger := log.New(multiWriter, "", log.LstdFlags|log.Lmicroseconds)
 
 	var slogLogger *slog.Logger
-	if config.Format == "json" {
+	switch config.Format {
+	case "", "text":
+	case "json":
 		opts := &slog.HandlerOptions{
-			Level: slog.LevelInfo, // Default, will be filtered by wrapper methods anyway or we can map it
+			Level:       slog.LevelInfo, // Default, will be filtered by wrapper methods anyway or we can map it
+			ReplaceAttr: jsonReplaceAttr,
 		}
-		// Map LogLevel to slog.Level
-		switch level {
+		// Map LogLevel to slog.Level; component levels are filtered by the wrapper methods
+		switch minLevel {
 		case LogLevelDebug:
 			opts.Level = slog.LevelDebug
 		case LogLevelInfo:
@@ -211,6 +282,8 @@ func newLogger(config *LogConfig) (*Logger, error) {
 		}
 
 		slogLogger = slog.New(slog.NewJSONHandler(multiWriter, opts))
+	default:
+		return nil, fmt.Errorf("invalid log format: %s (expected text or json)", config.Format)
 	}
 
 	return &Logger{
@@ -221,9 +294,26 @@ func newLogger(config *LogConfig) (*Logger, error) {
 		useColors:    true, // Always enable colors since we strip them for file output
 		level:        level,
 		slogLogger:   slogLogger,
+		components:   components,
+		minLevel:     minLevel,
 	}, nil
 }
 
+// jsonReplaceAttr normalizes top-level keys for JSON output: {"ts": ..., "level": "info", "msg": ...}
+func jsonReplaceAttr(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) > 0 {
+		return a
+	}
+	switch a.Key {
+	case slog.TimeKey:
+		a.Key = "ts"
+		a.Value = slog.StringValue(a.Value.Time().UTC().Format(time.RFC3339Nano))
+	case slog.LevelKey:
+		a.Value = slog.StringValue(strings.ToLower(a.Value.String()))
+	}
+	return a
+}
+
 func (l *Logger) Close() error {
 	return l.fileWriter.Close()
 }
@@ -238,9 +328,54 @@ func (l *Logger) shouldLog(msgLevel LogLevel) bool {
 	return msgLevel >= l.level
 }
 
+// shouldLogf checks a message against its component's level (see ParseLevelSpec)
+func (l *Logger) shouldLogf(msgLevel LogLevel, format string, v []interface{}) bool {
+	if len(l.components) == 0 {
+		return msgLevel >= l.level
+	}
+	if msgLevel < l.minLevel {
+		return false
+	}
+	tag := leadingTag(format)
+	if strings.Contains(tag, "%") {
+		// Tags like "[%s]" carry the client ID
+		tag = leadingTag(fmt.Sprintf(format, v...))
+	}
+	return msgLevel >= l.componentLevel(componentName(tag))
+}
+
+// componentLevel returns the level of the longest component matching tag
+func (l *Logger) componentLevel(tag string) LogLevel {
+	level, matched := l.level, -1
+	if tag == "" {
+		return level
+	}
+	for component, componentLevel := range l.components {
+		if len(component) > matched && (tag == component || strings.HasPrefix(tag, component+"_")) {
+			level, matched = componentLevel, len(component)
+		}
+	}
+	return level
+}
+
+// leadingTag returns the text inside a [TAG] at the start of a log line
+func leadingTag(s string) string {
+	if !strings.HasPrefix(s, "[") {
+		return ""
+	}
+	if end := strings.IndexByte(s, ']'); end > 0 {
+		return s[1:end]
+	}
+	return ""
+}
+
 // SetLevel updates the log level
 func (l *Logger) SetLevel(level LogLevel) {
 	l.level = level
+	l.minLevel = level
+	for _, componentLevel := range l.components {
+		l.minLevel = min(l.minLevel, componentLevel)
+	}
 }
 
 // GetLevel returns the current log level
@@ -250,7 +385,7 @@ func (l *Logger) GetLevel() LogLevel {
 
 // Log methods with optional colors and level filtering
 func (l *Logger) Debug(format string, v ...interface{}) {
-	if !l.shouldLog(LogLevelDebug) {
+	if !l.shouldLogf(LogLevelDebug, format, v) {
 		return
 	}
 	if l.slogLogger != nil {
@@ -265,7 +400,7 @@ func (l *Logger) Debug(format string, v ...interface{}) {
 }
 
 func (l *Logger) Info(format string, v ...interface{}) {
-	if !l.shouldLog(LogLevelInfo) {
+	if !l.shouldLogf(LogLevelInfo, format, v) {
 		return
 	}
 	if l.slogLogger != nil {
@@ -280,7 +415,7 @@ func (l *Logger) Info(format string, v ...interface{}) {
 }
 
 func (l *Logger) Warn(format string, v ...interface{}) {
-	if !l.shouldLog(LogLevelWarn) {
+	if !l.shouldLogf(LogLevelWarn, format, v) {
 		return
 	}
 	if l.slogLogger != nil {
@@ -295,7 +430,7 @@ func (l *Logger) Warn(format string, v ...interface{}) {
 }
 
 func (l *Logger) Error(format string, v ...interface{}) {
-	if !l.shouldLog(LogLevelError) {
+	if !l.shouldLogf(LogLevelError, format, v) {
 		return
 	}
 	if l.slogLogger != nil {
@@ -309,6 +444,47 @@ func (l *Logger) Error(format string, v ...interface{}) {
 	l.Printf(prefix+" "+format, v...)
 }
 
+// WithFields logs msg at the given level with structured key/value fields.
+// In JSON mode the fields become top-level keys; in text mode they are appended as key=value.
+func (l *Logger) WithFields(level LogLevel, msg string, fields map[string]interface{}) {
+	if !l.shouldLog(level) {
+		return
+	}
+	if l.slogLogger != nil {
+		attrs := make([]any, 0, len(fields))
+		for k, v := range fields {
+			attrs = append(attrs, slog.Any(k, v))
+		}
+		switch level {
+		case LogLevelDebug:
+			l.slogLogger.Debug(msg, attrs...)
+		case LogLevelWarn:
+			l.slogLogger.Warn(msg, attrs...)
+		case LogLevelError:
+			l.slogLogger.Error(msg, attrs...)
+		default:
+			l.slogLogger.Info(msg, attrs...)
+		}
+		return
+	}
+
+	var b strings.Builder
+	b.WriteString(msg)
+	for k, v := range fields {
+		fmt.Fprintf(&b, " %s=%v", k, v)
+	}
+	switch level {
+	case LogLevelDebug:
+		l.Debug("%s", b.String())
+	case LogLevelWarn:
+		l.Warn("%s", b.String())
+	case LogLevelError:
+		l.Error("%s", b.String())
+	default:
+		l.Info("%s", b.String())
+	}
+}
+
 // Error handling utilities
 type ErrorWithContext struct {
 	Err     error
@@ -380,6 +556,11 @@ func (l *Logger) FormatHTTPStatus(status int) string {
 
 // LogHTTPRequest logs an HTTP request with colored output
 func (l *Logger) LogHTTPRequest(method, path, clientIP string, status, bytes int, latency string) {
+	if l.slogLogger != nil {
+		l.slogLogger.Info("HTTP Request", slog.Int("status", status), slog.String("method", method),
+			slog.String("path", path), slog.String("ip", clientIP), slog.Int("bytes", bytes), slog.String("latency", latency))
+		return
+	}
 	methodFormatted := l.FormatHTTPMethod(method)
 	statusFormatted := l.FormatHTTPStatus(status)
 
@@ -395,6 +576,11 @@ func (l *Logger) LogHTTPRequest(method, path, clientIP string, status, bytes int
 
 // LogHTTPError logs an HTTP error with colored output
 func (l *Logger) LogHTTPError(method, path, clientIP string, status int, message string, err error) {
+	if l.slogLogger != nil {
+		l.slogLogger.Error(message, slog.Int("status", status), slog.String("method", method),
+			slog.String("path", path), slog.String("ip", clientIP), slog.Any("error", err))
+		return
+	}
 	methodFormatted := l.FormatHTTPMethod(method)
 	statusFormatted := l.FormatHTTPStatus(status)
 
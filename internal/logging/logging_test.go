@@ -0,0 +1,60 @@
+package logging
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseLevelSpec(t *testing.T) {
+	level, components, err := ParseLevelSpec("INFO, gRPC-Client=debug,media=warn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if level != LogLevelInfo || components["grpc_client"] != LogLevelDebug || components["media"] != LogLevelWarn || len(components) != 2 {
+		t.Errorf("got %v, %v", level, components)
+	}
+
+	if level, components, err = ParseLevelSpec(""); err != nil || level != LogLevelInfo || components != nil {
+		t.Errorf("empty spec: got %v, %v, %v", level, components, err)
+	}
+
+	for _, bad := range []string{"loud", "info,debug", "media=loud", "=debug"} {
+		if _, _, err := ParseLevelSpec(bad); err == nil {
+			t.Errorf("ParseLevelSpec(%q) accepted", bad)
+		}
+	}
+}
+
+func TestComponentLevels(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "test.log")
+	logger, err := newLogger(&LogConfig{File: logFile, MaxSize: 1, Level: "warn,grpc_client=debug,hybrid=error,hybrid_media=info"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger.SetOutput(logger.fileWriter) // Keep test output quiet
+
+	logger.Debug("[gRPC CLIENT] debug kept")
+	logger.Debug("[%s] client id tag kept", "grpc-client-1")
+	logger.Info("[HYBRID MEDIA] longest component wins")
+	logger.Warn("[HYBRID→gRPC] hybrid warning dropped")
+	logger.Error("[HYBRID] hybrid error kept")
+	logger.Info("plain info dropped")
+	logger.Warn("plain warning kept")
+	logger.Close()
+
+	data, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(data)
+	for _, want := range []string{"debug kept", "client id tag kept", "longest component wins", "hybrid error kept", "plain warning kept"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in log:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "dropped") {
+		t.Errorf("filtered lines were logged:\n%s", out)
+	}
+}
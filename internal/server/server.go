@@ -2,11 +2,15 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
+	"encoding/json"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/exec"
 	"os/signal"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -31,6 +35,7 @@ type serverManager struct {
 	httpServer   *http.Server
 	tunnelRouter *tunnel.HybridTunnelRouter // Changed from TunnelServer to HybridTunnelRouter
 	usageService service.UsageService
+	usageFile    *tunnel.FileUsageRecorder
 	dnsMonitor   *tasks.DNSMonitor
 }
 
@@ -86,6 +91,9 @@ func (sm *serverManager) start() error {
 	return sm.shutdown()
 }
 
+// tunnelDrainTimeout bounds how long shutdown waits for in-flight tunnel requests
+const tunnelDrainTimeout = 30 * time.Second
+
 // shutdown gracefully shuts down both servers
 func (sm *serverManager) shutdown() error {
 	logger := logging.GetGlobalLogger()
@@ -101,8 +109,13 @@ func (sm *serverManager) shutdown() error {
 		logger.Error("HTTP server shutdown error: %v", err)
 	}
 
-	// Shutdown tunnel router
+	// Shutdown tunnel router, letting active downloads finish first
 	if sm.tunnelRouter != nil {
+		drainCtx, drainCancel := context.WithTimeout(context.Background(), tunnelDrainTimeout)
+		if err := sm.tunnelRouter.Drain(drainCtx); err != nil {
+			logger.Warn("Tunnel router drain incomplete: %v", err)
+		}
+		drainCancel()
 		if err := sm.tunnelRouter.Stop(); err != nil {
 			logger.Error("Tunnel router shutdown error: %v", err)
 		}
@@ -115,6 +128,13 @@ func (sm *serverManager) shutdown() error {
 		}
 	}
 
+	// Write the final local usage totals
+	if sm.usageFile != nil {
+		if err := sm.usageFile.Close(); err != nil {
+			logger.Error("Usage file shutdown error: %v", err)
+		}
+	}
+
 	// Shutdown DNS monitor
 	if sm.dnsMonitor != nil {
 		sm.dnsMonitor.Stop()
@@ -253,10 +273,230 @@ func (s *Server) Init() error {
 		routerConfig.TCPAddress = ":4443" // Default TCP port
 	}
 
+	// Optional Prometheus metrics endpoint (e.g. "127.0.0.1:9100")
+	if metricsAddr := os.Getenv("TUNNEL_METRICS_ADDR"); metricsAddr != "" {
+		routerConfig.MetricsListenAddr = metricsAddr
+	}
+
+	// Optional cap on streamed upload size (0/unset = unlimited)
+	if maxUpload := os.Getenv("TUNNEL_MAX_UPLOAD_BYTES"); maxUpload != "" {
+		if limit, err := strconv.ParseInt(maxUpload, 10, 64); err == nil && limit >= 0 {
+			routerConfig.MaxUploadBytes = limit
+		} else {
+			logger.Warn("Invalid TUNNEL_MAX_UPLOAD_BYTES %q, uploads will not be limited", maxUpload)
+		}
+	}
+
+	// Cap on request and tunnel response heads (unset = default, 0 = unlimited)
+	if maxHeader := os.Getenv("TUNNEL_MAX_HEADER_BYTES"); maxHeader != "" {
+		if limit, err := strconv.Atoi(maxHeader); err == nil && limit >= 0 {
+			routerConfig.MaxHeaderBytes = limit
+		} else {
+			logger.Warn("Invalid TUNNEL_MAX_HEADER_BYTES %q, using default %d", maxHeader, tunnel.DefaultMaxHeaderBytes)
+		}
+	}
+
+	// Per-domain concurrency cap with a bounded wait queue and per-user tunnel connection cap
+	// (unset = unlimited), kernel
+	// socket buffer sizes for TCP tunnel connections (unset = OS default), and the public
+	// port range of UDP tunnels (unset = UDP tunnels disabled) and their session cap
+	for name, target := range map[string]*int{
+		"TUNNEL_MAX_CONCURRENT_PER_DOMAIN": &routerConfig.MaxConcurrentPerDomain,
+		"TUNNEL_MAX_CONNECTIONS_PER_USER":  &routerConfig.MaxConnectionsPerUser,
+		"TUNNEL_QUEUE_DEPTH":               &routerConfig.QueueDepth,
+		"TUNNEL_TCP_READ_BUFFER":           &routerConfig.TCPReadBufferSize,
+		"TUNNEL_TCP_WRITE_BUFFER":          &routerConfig.TCPWriteBufferSize,
+		"TUNNEL_UDP_PORT_MIN":              &routerConfig.UDPPortMin,
+		"TUNNEL_UDP_PORT_MAX":              &routerConfig.UDPPortMax,
+		"TUNNEL_UDP_MAX_SESSIONS":          &routerConfig.UDPMaxSessions,
+	} {
+		if value := os.Getenv(name); value != "" {
+			if n, err := strconv.Atoi(value); err == nil && n >= 0 {
+				*target = n
+			} else {
+				logger.Warn("Invalid %s %q, keeping default %d", name, value, *target)
+			}
+		}
+	}
+	if value := os.Getenv("TUNNEL_QUEUE_TIMEOUT"); value != "" {
+		if d, err := time.ParseDuration(value); err == nil && d > 0 {
+			routerConfig.QueueTimeout = d
+		} else {
+			logger.Warn("Invalid TUNNEL_QUEUE_TIMEOUT %q, keeping default %v", value, routerConfig.QueueTimeout)
+		}
+	}
+
+	// WebSocket keepalive: close proxied WebSockets after this much silence, optionally pinging first.
+	// TUNNEL_MAX_ORIGIN_TIMEOUT caps the X-Tunnel-Timeout an origin may set (0 = ignore the header).
+	// TUNNEL_RESPONSE_HEADER_TIMEOUT / TUNNEL_RESPONSE_TIMEOUT bound chunked responses (0 = defaults).
+	// TUNNEL_PENDING_REQUEST_MAX_AGE drops gRPC requests whose handlers never cleaned up (0 = default).
+	// TUNNEL_CRL_CHECK_INTERVAL re-checks connected clients against TUNNEL_CLIENT_CRL_FILE (0 = default).
+	// TUNNEL_CLIENT_WRITE_TIMEOUT abandons responses to visitors that stop reading (0 = never).
+	// TUNNEL_FRESH_CONNECTION_TIMEOUT waits for clients to dial in on-demand connections (0 = default).
+	for name, target := range map[string]*time.Duration{
+		"TUNNEL_WEBSOCKET_IDLE_TIMEOUT":   &routerConfig.WebSocketIdleTimeout,
+		"TUNNEL_WEBSOCKET_PING_INTERVAL":  &routerConfig.WebSocketPingInterval,
+		"TUNNEL_MAX_ORIGIN_TIMEOUT":       &routerConfig.MaxOriginTimeout,
+		"TUNNEL_RESPONSE_HEADER_TIMEOUT":  &routerConfig.ChunkMetadataTimeout,
+		"TUNNEL_RESPONSE_TIMEOUT":         &routerConfig.ChunkCollectionTimeout,
+		"TUNNEL_PENDING_REQUEST_MAX_AGE":  &routerConfig.PendingRequestMaxAge,
+		"TUNNEL_CRL_CHECK_INTERVAL":       &routerConfig.CertRevocationCheckInterval,
+		"TUNNEL_CLIENT_WRITE_TIMEOUT":     &routerConfig.ClientWriteTimeout,
+		"TUNNEL_FRESH_CONNECTION_TIMEOUT": &routerConfig.FreshConnectionTimeout,
+	} {
+		if value := os.Getenv(name); value != "" {
+			if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+				*target = d
+			} else {
+				logger.Warn("Invalid %s %q, keeping default %v", name, value, *target)
+			}
+		}
+	}
+
+	// Token-bucket rate limits, per domain and optionally per client IP within a domain
+	if enabled := os.Getenv("TUNNEL_RATE_LIMIT"); enabled != "" {
+		routerConfig.EnableRateLimit = enabled == "true"
+	}
+	for name, target := range map[string]*int{
+		"TUNNEL_RATE_LIMIT_RPM":          &routerConfig.MaxRequestsPerMin,
+		"TUNNEL_RATE_LIMIT_BURST":        &routerConfig.RateLimitBurst,
+		"TUNNEL_RATE_LIMIT_PER_IP_RPM":   &routerConfig.MaxRequestsPerMinIP,
+		"TUNNEL_RATE_LIMIT_PER_IP_BURST": &routerConfig.RateLimitBurstPerIP,
+	} {
+		if value := os.Getenv(name); value != "" {
+			if n, err := strconv.Atoi(value); err == nil && n >= 0 {
+				*target = n
+			} else {
+				logger.Warn("Invalid %s %q, keeping default %d", name, value, *target)
+			}
+		}
+	}
+
+	// Optional headers added to every tunneled response, as a JSON object
+	if headers := os.Getenv("TUNNEL_RESPONSE_HEADERS"); headers != "" {
+		if err := json.Unmarshal([]byte(headers), &routerConfig.ResponseHeaders); err != nil {
+			logger.Warn("Invalid TUNNEL_RESPONSE_HEADERS (expected a JSON object), no headers will be added: %v", err)
+			routerConfig.ResponseHeaders = nil
+		}
+	}
+	routerConfig.OverrideResponseHeaders = os.Getenv("TUNNEL_OVERRIDE_RESPONSE_HEADERS") == "true"
+
+	// Routing rules replacing the defaults, as JSON arrays of substrings or "glob:"/"regex:" patterns
+	for name, rules := range map[string]*[]string{
+		"TUNNEL_FORCE_TCP_PATHS":  &routerConfig.ForceTCPPaths,
+		"TUNNEL_FORCE_GRPC_PATHS": &routerConfig.ForceGRPCPaths,
+	} {
+		if value := os.Getenv(name); value != "" {
+			var parsed []string
+			if err := json.Unmarshal([]byte(value), &parsed); err != nil {
+				logger.Warn("Invalid %s (expected a JSON array), keeping the defaults: %v", name, err)
+				continue
+			}
+			*rules = parsed
+		}
+	}
+
+	// Extra request headers to remove before forwarding to the origin, comma-separated
+	if strip := os.Getenv("TUNNEL_STRIP_REQUEST_HEADERS"); strip != "" {
+		for _, name := range strings.Split(strip, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				routerConfig.StripRequestHeaders = append(routerConfig.StripRequestHeaders, name)
+			}
+		}
+	}
+
+	// X-Forwarded-For / X-Real-IP / X-Forwarded-Proto on requests to the origin (on by default)
+	if forwarded := os.Getenv("TUNNEL_FORWARDED_HEADERS"); forwarded != "" {
+		routerConfig.ForwardedHeaders = forwarded == "true"
+	}
+
+	// Rewrite redirects to localhost:PORT so they point at the tunnel domain (off by default)
+	routerConfig.RewriteRedirects = os.Getenv("TUNNEL_REWRITE_REDIRECTS") == "true"
+
+	// Tunnel clients connect through a load balancer speaking the PROXY protocol (off by default)
+	routerConfig.ProxyProtocol = os.Getenv("TUNNEL_PROXY_PROTOCOL") == "true"
+
+	// Keep each visitor session on one TCP hot-pool connection, by cookie or visitor IP (off by default)
+	routerConfig.EnableStickySessions = os.Getenv("TUNNEL_STICKY_SESSIONS") == "true"
+	routerConfig.StickySessionCookie = os.Getenv("TUNNEL_STICKY_SESSION_COOKIE")
+
+	// Route every request alike, without the media/large-file guesses by extension and path
+	routerConfig.DisableMediaOptimization = os.Getenv("TUNNEL_DISABLE_MEDIA_OPTIMIZATION") == "true"
+
+	// Bridge WebSockets over the gRPC stream for clients that support it (off by default)
+	routerConfig.WebSocketOverGRPC = os.Getenv("TUNNEL_WEBSOCKET_OVER_GRPC") == "true"
+
+	// gRPC health checks for load balancers/orchestrators (grpc.health.v1 is always on the tunnel port)
+	routerConfig.GRPCReflection = os.Getenv("TUNNEL_GRPC_REFLECTION") == "true"
+	routerConfig.GRPCHealthAddr = os.Getenv("TUNNEL_GRPC_HEALTH_ADDR")
+
+	// Apache/nginx-style access log of tunneled requests (empty = off)
+	routerConfig.AccessLogFile = os.Getenv("TUNNEL_ACCESS_LOG")
+	routerConfig.AccessLogFormat = os.Getenv("TUNNEL_ACCESS_LOG_FORMAT")
+
+	// Edge cache of GET responses the origin marks cacheable (unset = disabled)
+	if cacheSize := os.Getenv("TUNNEL_RESPONSE_CACHE_BYTES"); cacheSize != "" {
+		if size, err := strconv.ParseInt(cacheSize, 10, 64); err == nil && size >= 0 {
+			routerConfig.ResponseCacheSize = size
+		} else {
+			logger.Warn("Invalid TUNNEL_RESPONSE_CACHE_BYTES %q, response caching disabled", cacheSize)
+		}
+	}
+	if cacheTTL := os.Getenv("TUNNEL_RESPONSE_CACHE_TTL"); cacheTTL != "" {
+		if d, err := time.ParseDuration(cacheTTL); err == nil && d > 0 {
+			routerConfig.ResponseCacheTTL = d
+		} else {
+			logger.Warn("Invalid TUNNEL_RESPONSE_CACHE_TTL %q, using default %v", cacheTTL, tunnel.DefaultResponseCacheTTL)
+		}
+	}
+
+	// A second client for a domain replaces the connected one (default) or is refused
+	if policy, err := tunnel.ParseDuplicateStreamPolicy(os.Getenv("TUNNEL_DUPLICATE_CLIENT_POLICY")); err == nil {
+		routerConfig.DuplicateStreamPolicy = policy
+	} else {
+		logger.Warn("%v, using replace", err)
+	}
+
+	// Refuse client certificates listed in a CRL, and close connected streams using them
+	if crlFile := os.Getenv("TUNNEL_CLIENT_CRL_FILE"); crlFile != "" {
+		if check, err := tunnel.CRLRevocationCheck(crlFile); err == nil {
+			tunnel.SetCertRevocationCheck(check)
+			logger.Info("Checking client certificates against CRL %s", crlFile)
+		} else {
+			logger.Error("Client certificate revocation disabled: %v", err)
+		}
+	}
+
+	// Custom HTML page served with a 503 while a domain's tunnel is down
+	routerConfig.MaintenancePage = os.Getenv("TUNNEL_MAINTENANCE_PAGE")
+	routerConfig.MaintenancePageDir = os.Getenv("TUNNEL_MAINTENANCE_PAGE_DIR")
+	if retryAfter := os.Getenv("TUNNEL_MAINTENANCE_RETRY_AFTER"); retryAfter != "" {
+		if d, err := time.ParseDuration(retryAfter); err == nil && d > 0 {
+			routerConfig.MaintenanceRetryAfter = d
+		} else {
+			logger.Warn("Invalid TUNNEL_MAINTENANCE_RETRY_AFTER %q, keeping default %v", retryAfter, routerConfig.MaintenanceRetryAfter)
+		}
+	}
+
 	// Create the hybrid tunnel router
 	s.tunnelRouter = tunnel.NewHybridTunnelRouter(repos.Token, repos.Tunnel, tunnelService, routerConfig)
-	// Wire usage recorder into tunnel router and underlying servers
-	s.tunnelRouter.SetUsageRecorder(usageService)
+	// Wire usage recorder into tunnel router and underlying servers, optionally also
+	// keeping per-domain totals in a local file (readable with 'giraffecloud usage --file')
+	if usageFilePath := os.Getenv("TUNNEL_USAGE_FILE"); usageFilePath != "" {
+		usageFile, err := tunnel.NewFileUsageRecorder(usageFilePath, tunnel.DefaultUsageFlushInterval)
+		if err != nil {
+			logger.Error("Failed to open usage file %s: %v", usageFilePath, err)
+		} else {
+			s.usageFile = usageFile
+			s.usageFile.Start(context.Background())
+			logger.Info("Recording tunnel usage to %s", usageFilePath)
+		}
+	}
+	if s.usageFile != nil {
+		s.tunnelRouter.SetUsageRecorder(tunnel.MultiUsageRecorder(usageService, s.usageFile))
+	} else {
+		s.tunnelRouter.SetUsageRecorder(usageService)
+	}
 	// Adapt service.QuotaService to tunnel.QuotaChecker
 	s.tunnelRouter.SetQuotaChecker(quotaAdapter{q: quotaService})
 
@@ -369,7 +609,8 @@ func (s *Server) Start() error {
 
 	// Custom handler for tunnel domains only
 	httpHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		domain := r.Host
+		// Route by Host, or by the TLS server name when the hijack server terminates TLS itself
+		domain := s.tunnelRouter.RoutingDomain(r.Host, tunnel.ServerNameFromContext(r.Context()))
 		isTunnel := s.tunnelRouter.IsTunnelDomain(domain)
 
 		if isTunnel {
@@ -403,8 +644,12 @@ func (s *Server) Start() error {
 			// Add request line
 			requestData.WriteString(fmt.Sprintf("%s %s HTTP/1.1\r\n", r.Method, r.URL.RequestURI()))
 
-			// Add Host header first
-			requestData.WriteString(fmt.Sprintf("Host: %s\r\n", r.Host))
+			// Add Host header first (the routed domain when the client sent none)
+			host := r.Host
+			if host == "" {
+				host = domain
+			}
+			requestData.WriteString(fmt.Sprintf("Host: %s\r\n", host))
 
 			// Add Content-Length if body exists
 			if r.ContentLength > 0 {
@@ -430,7 +675,7 @@ func (s *Server) Start() error {
 			// It will automatically detect WebSocket upgrades and route to TCP tunnel
 			// Regular HTTP requests will be routed to gRPC tunnel for unlimited concurrency
 			s.tunnelRouter.ProxyConnection(domain, conn, requestBytes, r.Body)
-		} else {
+		} else if !s.tunnelRouter.ServeMaintenancePage(w, domain) {
 			http.Error(w, "Not Found", http.StatusNotFound)
 		}
 	})
@@ -446,15 +691,38 @@ func (s *Server) Start() error {
 
 		logger.Info("Starting HTTP hijack server on :%s for tunnel domains", hijackPort)
 
+		listener, err := net.Listen("tcp", ":"+hijackPort)
+		if err != nil {
+			logger.Error("HTTP hijack server error: %v", err)
+			return
+		}
+		// Behind a load balancer speaking the PROXY protocol, take visitor IPs from its header
+		if os.Getenv("HIJACK_PROXY_PROTOCOL") == "true" {
+			logger.Info("HTTP hijack server expects PROXY protocol headers")
+			listener = tunnel.NewProxyProtocolListener(listener, tunnel.DefaultProxyHeaderTimeout)
+		}
+		// Terminate TLS here instead of in Caddy (e.g. TLS passthrough for wildcard or custom
+		// domains), so requests can be routed by SNI when the Host header doesn't match
+		if certFile, keyFile := os.Getenv("HIJACK_TLS_CERT"), os.Getenv("HIJACK_TLS_KEY"); certFile != "" && keyFile != "" {
+			tlsConfig, err := tunnel.CreateSecureServerTLSConfig(certFile, keyFile, "")
+			if err != nil {
+				logger.Error("HTTP hijack server TLS error: %v", err)
+				listener.Close()
+				return
+			}
+			logger.Info("HTTP hijack server terminates TLS and routes by SNI")
+			listener = tls.NewListener(tunnel.NewSNIListener(listener), tlsConfig)
+		}
+
 		server := &http.Server{
-			Addr:    ":" + hijackPort,
-			Handler: httpHandler,
+			Handler:     httpHandler,
+			ConnContext: tunnel.ConnContext,
 			// Add timeouts to prevent hanging connections
 			ReadTimeout:  30 * time.Second,
 			WriteTimeout: 30 * time.Second,
 			IdleTimeout:  60 * time.Second,
 		}
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
 			logger.Error("HTTP hijack server error: %v", err)
 		}
 		logger.Info("HTTP hijack server stopped")
@@ -462,6 +730,7 @@ func (s *Server) Start() error {
 
 	// Create server manager for the main Gin HTTP API (if needed)
 	manager := newServerManager(s.router, s.tunnelRouter, s.usageService, s.dnsMonitor, cfg.Port)
+	manager.usageFile = s.usageFile
 
 	// Start servers
 	return manager.start()
@@ -20,6 +20,7 @@ type Server struct {
 	tunnelRouter *tunnel.HybridTunnelRouter // Changed from TunnelServer to HybridTunnelRouter
 	config       *config.Config
 	usageService service.UsageService
+	usageFile    *tunnel.FileUsageRecorder // Optional local usage totals (TUNNEL_USAGE_FILE)
 	dnsMonitor   *tasks.DNSMonitor
 }
 
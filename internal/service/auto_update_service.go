@@ -2,12 +2,17 @@ package service
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 	"sync"
 	"time"
 
 	"github.com/osa911/giraffecloud/internal/logging"
 	"github.com/osa911/giraffecloud/internal/tunnel"
+	"github.com/osa911/giraffecloud/internal/version"
 )
 
 // AutoUpdateService handles automatic client updates in the background
@@ -213,6 +218,20 @@ func (s *AutoUpdateService) performUpdate(updateInfo *UpdateInfo) error {
 	// Restart service if configured and running as a service
 	if s.config.RestartService && s.serviceManager != nil {
 		if isRunning, err := s.serviceManager.IsRunning(); err == nil && isRunning {
+			// The restarted service checks that the tunnel comes back, and rolls back if it doesn't
+			verifyTimeout := s.config.VerifyTimeout
+			if verifyTimeout <= 0 {
+				verifyTimeout = tunnel.DefaultConfig.AutoUpdate.VerifyTimeout
+			}
+			if err := savePendingUpdate(&pendingUpdate{
+				BackupPath:      s.updater.LastBackupPath(),
+				PreviousVersion: version.Version,
+				Version:         updateInfo.Version,
+				Deadline:        time.Now().Add(verifyTimeout),
+			}); err != nil {
+				s.logger.Warn("Failed to record update for post-restart verification: %v", err)
+			}
+
 			s.logger.Info("Restarting service after update...")
 			if err := s.restartServiceGracefully(); err != nil {
 				s.logger.Error("Failed to restart service: %v", err)
@@ -266,6 +285,141 @@ func (s *AutoUpdateService) restartServiceGracefully() error {
 	return nil
 }
 
+// pendingUpdateFile records an update whose tunnel has not yet connected after the restart
+const pendingUpdateFile = "pending_update.json"
+
+// pendingUpdate is an installed update awaiting post-restart verification
+type pendingUpdate struct {
+	BackupPath      string    `json:"backup_path"`
+	PreviousVersion string    `json:"previous_version"`
+	Version         string    `json:"version"`
+	Deadline        time.Time `json:"deadline"` // The tunnel must be connected by then
+}
+
+func pendingUpdatePath() (string, error) {
+	dir, err := tunnel.GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, pendingUpdateFile), nil
+}
+
+func savePendingUpdate(p *pendingUpdate) error {
+	path, err := pendingUpdatePath()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// loadPendingUpdate returns the update awaiting verification, or nil if there is none
+func loadPendingUpdate() (*pendingUpdate, error) {
+	path, err := pendingUpdatePath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var p pendingUpdate
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func clearPendingUpdate() {
+	if path, err := pendingUpdatePath(); err == nil {
+		os.Remove(path)
+	}
+}
+
+// VerifyPendingUpdate checks that the tunnel connects after an automatic update restarted
+// the service, and restores the previous binary and restarts again if it doesn't connect
+// within the configured verify timeout. Call it once at startup, before connecting; it
+// returns immediately and waits for the connection in the background.
+func (s *AutoUpdateService) VerifyPendingUpdate(ctx context.Context) {
+	pending, err := loadPendingUpdate()
+	if err != nil {
+		s.logger.Warn("Failed to read pending update verification: %v", err)
+		clearPendingUpdate()
+		return
+	}
+	if pending == nil {
+		return
+	}
+	if version.Version == pending.PreviousVersion {
+		// Still the binary from before the update (rolled back, or never restarted into the new one)
+		clearPendingUpdate()
+		return
+	}
+
+	if time.Now().After(pending.Deadline) {
+		// An earlier start of this version already ran out of time (e.g. it kept exiting)
+		s.rollbackUpdate(pending)
+		return
+	}
+
+	go s.waitForUpdatedTunnel(ctx, pending)
+}
+
+// waitForUpdatedTunnel rolls the update back unless the tunnel connects before the deadline.
+// If ctx ends first the verification is left for the next start.
+func (s *AutoUpdateService) waitForUpdatedTunnel(ctx context.Context, pending *pendingUpdate) {
+	s.logger.Info("Verifying update to %s: waiting up to %v for the tunnel to connect",
+		pending.Version, time.Until(pending.Deadline).Round(time.Second))
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		if s.connectionState != nil && s.connectionState.IsConnected() {
+			s.logger.Info("✅ Tunnel connected after update to %s", pending.Version)
+			clearPendingUpdate()
+			return
+		}
+		if time.Now().After(pending.Deadline) {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+
+	s.rollbackUpdate(pending)
+}
+
+// rollbackUpdate restores the binary from before the update and restarts the service
+func (s *AutoUpdateService) rollbackUpdate(pending *pendingUpdate) {
+	s.logger.Error("❌ Tunnel did not connect after update to %s, rolling back to %s",
+		pending.Version, pending.PreviousVersion)
+
+	// Clear first so a failing rollback can't loop on every start
+	clearPendingUpdate()
+
+	if err := s.updater.Rollback(pending.BackupPath); err != nil {
+		s.logger.Error("❌ Failed to restore backup %s: %v", pending.BackupPath, err)
+		return
+	}
+	s.logger.Info("✅ Restored %s from backup", pending.PreviousVersion)
+
+	if s.serviceManager == nil {
+		return
+	}
+	if err := s.restartServiceGracefully(); err != nil {
+		s.logger.Error("Failed to restart service after rollback: %v", err)
+	}
+}
+
 // isInUpdateWindow checks if current time is within the configured update window
 func (s *AutoUpdateService) isInUpdateWindow() bool {
 	if s.config.UpdateWindow == nil {
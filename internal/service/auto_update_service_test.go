@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/osa911/giraffecloud/internal/logging"
+)
+
+type fakeConnectionState struct {
+	ConnectionStateProvider
+	connected bool
+}
+
+func (f *fakeConnectionState) IsConnected() bool { return f.connected }
+
+func newTestAutoUpdateService(t *testing.T, connected bool) (*AutoUpdateService, string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("GIRAFFECLOUD_HOME", dir)
+	if err := logging.InitLogger(&logging.LogConfig{File: filepath.Join(dir, "test.log"), MaxSize: 1}); err != nil {
+		t.Fatalf("Failed to init logger: %v", err)
+	}
+
+	exePath := filepath.Join(dir, "giraffecloud")
+	backupPath := filepath.Join(dir, "giraffecloud.backup")
+	os.WriteFile(exePath, []byte("new"), 0755)
+	os.WriteFile(backupPath, []byte("old"), 0755)
+
+	logger := logging.GetGlobalLogger()
+	return &AutoUpdateService{
+		logger:          logger,
+		updater:         &UpdaterService{logger: logger, currentExePath: exePath},
+		connectionState: &fakeConnectionState{connected: connected},
+	}, exePath, backupPath
+}
+
+func TestVerifyPendingUpdate_RollsBackAfterDeadline(t *testing.T) {
+	s, exePath, backupPath := newTestAutoUpdateService(t, false)
+	if err := savePendingUpdate(&pendingUpdate{
+		BackupPath:      backupPath,
+		PreviousVersion: "v0.0.1",
+		Version:         "v0.0.2",
+		Deadline:        time.Now().Add(-time.Second),
+	}); err != nil {
+		t.Fatalf("savePendingUpdate: %v", err)
+	}
+
+	s.VerifyPendingUpdate(context.Background())
+
+	if data, _ := os.ReadFile(exePath); string(data) != "old" {
+		t.Errorf("executable = %q, want the backup restored", data)
+	}
+	if pending, _ := loadPendingUpdate(); pending != nil {
+		t.Error("pending update not cleared after rollback")
+	}
+}
+
+func TestVerifyPendingUpdate_KeepsUpdateWhenConnected(t *testing.T) {
+	s, exePath, backupPath := newTestAutoUpdateService(t, true)
+	if err := savePendingUpdate(&pendingUpdate{
+		BackupPath:      backupPath,
+		PreviousVersion: "v0.0.1",
+		Version:         "v0.0.2",
+		Deadline:        time.Now().Add(time.Minute),
+	}); err != nil {
+		t.Fatalf("savePendingUpdate: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	s.waitForUpdatedTunnel(ctx, &pendingUpdate{BackupPath: backupPath, Deadline: time.Now().Add(time.Minute)})
+
+	if data, _ := os.ReadFile(exePath); string(data) != "new" {
+		t.Errorf("executable = %q, want the update kept", data)
+	}
+	if pending, _ := loadPendingUpdate(); pending != nil {
+		t.Error("pending update not cleared after the tunnel connected")
+	}
+}
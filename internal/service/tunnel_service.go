@@ -23,6 +23,7 @@ type tunnelService struct {
 	repo         repository.TunnelRepository
 	caddyService CaddyService
 	config       *config.Config
+	subdomains   *utils.SubdomainConfig
 }
 
 // NewTunnelService creates a new tunnel service instance
@@ -31,9 +32,25 @@ func NewTunnelService(repo repository.TunnelRepository, caddyService CaddyServic
 		repo:         repo,
 		caddyService: caddyService,
 		config:       cfg,
+		subdomains:   subdomainConfig(cfg),
 	}
 }
 
+// subdomainConfig builds the subdomain generation settings from the server config,
+// falling back to the built-in format if the configured one is invalid
+func subdomainConfig(cfg *config.Config) *utils.SubdomainConfig {
+	subdomains := &utils.SubdomainConfig{
+		Adjectives: cfg.SubdomainAdjectives,
+		Nouns:      cfg.SubdomainNouns,
+		Format:     cfg.SubdomainFormat,
+	}
+	if err := subdomains.Validate(); err != nil {
+		logging.GetGlobalLogger().Error("Invalid SUBDOMAIN_FORMAT, using %s: %v", utils.DefaultSubdomainFormat, err)
+		subdomains.Format = ""
+	}
+	return subdomains
+}
+
 // generateToken generates a random token for tunnel authentication
 func generateToken() (string, error) {
 	bytes := make([]byte, 32)
@@ -61,13 +78,39 @@ func (s *tunnelService) GetFreeSubdomain(ctx context.Context, userID uint32) (do
 		}
 	}
 
-	// Generate deterministic subdomain for this user
-	subdomain := utils.GenerateSubdomainForUser(userID)
+	// Generate this user's subdomain, skipping any that another user's tunnel already has
+	subdomain, err := s.subdomainForUser(ctx, userID)
+	if err != nil {
+		return "", false, err
+	}
 	logger.Info("Generated free subdomain for user %d: %s", userID, subdomain)
 
 	return subdomain, true, nil
 }
 
+// subdomainForUser returns the auto-generated subdomain for a user that no other user's
+// tunnel has taken. It is stable as long as the set of taken domains doesn't change.
+func (s *tunnelService) subdomainForUser(ctx context.Context, userID uint32) (string, error) {
+	var lookupErr error
+	subdomain, err := utils.GenerateUniqueSubdomainForUser(userID, s.subdomains, func(domain string) bool {
+		existing, err := s.repo.GetByDomain(ctx, domain)
+		if err != nil {
+			if !errors.Is(err, repository.ErrNotFound) {
+				lookupErr = err
+			}
+			return false
+		}
+		return existing.UserID != userID
+	})
+	if lookupErr != nil {
+		return "", fmt.Errorf("failed to check subdomain availability: %w", lookupErr)
+	}
+	if err != nil {
+		return "", err
+	}
+	return subdomain, nil
+}
+
 // isReservedDomain checks if the domain matches reserved system domains
 func (s *tunnelService) isReservedDomain(domain string) bool {
 	baseDomain := s.config.BaseDomain
@@ -132,7 +175,10 @@ func (s *tunnelService) CreateTunnel(ctx context.Context, userID uint32, domain
 		}
 
 		// Verify this is the correct auto-generated domain for this user
-		expectedDomain := utils.GenerateSubdomainForUser(userID)
+		expectedDomain, err := s.subdomainForUser(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
 		if domain != expectedDomain {
 			logger.Warn("User %d attempted to create tunnel with incorrect auto-generated domain: %s (expected: %s)",
 				userID, domain, expectedDomain)
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/osa911/giraffecloud/internal/config"
@@ -20,6 +21,7 @@ type mockTunnelRepository struct {
 	createFunc      func(ctx context.Context, tunnel *ent.Tunnel) (*ent.Tunnel, error)
 	getByUserIDFunc func(ctx context.Context, userID uint32) ([]*ent.Tunnel, error)
 	getByIDFunc     func(ctx context.Context, id uint32) (*ent.Tunnel, error)
+	getByDomainFunc func(ctx context.Context, domain string) (*ent.Tunnel, error)
 	updateFunc      func(ctx context.Context, id uint32, updates interface{}) (*ent.Tunnel, error)
 }
 
@@ -44,6 +46,13 @@ func (m *mockTunnelRepository) GetByID(ctx context.Context, id uint32) (*ent.Tun
 	return nil, fmt.Errorf("tunnel not found")
 }
 
+func (m *mockTunnelRepository) GetByDomain(ctx context.Context, domain string) (*ent.Tunnel, error) {
+	if m.getByDomainFunc != nil {
+		return m.getByDomainFunc(ctx, domain)
+	}
+	return nil, fmt.Errorf("%w: tunnel not found", repository.ErrNotFound)
+}
+
 func (m *mockTunnelRepository) Update(ctx context.Context, id uint32, updates interface{}) (*ent.Tunnel, error) {
 	if m.updateFunc != nil {
 		return m.updateFunc(ctx, id, updates)
@@ -61,7 +70,7 @@ func TestCreateTunnel_DNSVerification(t *testing.T) {
 	logging.InitLogger(&logging.LogConfig{
 		Level:  "info",
 		Format: "text",
-		File:   "test.log", // Dummy file
+		File:   filepath.Join(t.TempDir(), "test.log"),
 	})
 
 	// Set CLIENT_URL for domain generation logic
@@ -111,7 +120,7 @@ func TestCreateTunnel_DNSVerification(t *testing.T) {
 		{
 			name:              "Auto-generated Domain (Skip Check)",
 			userID:            123,
-			domain:            utils.GenerateSubdomainForUser(123),
+			domain:            utils.GenerateSubdomainForUser(123, nil),
 			mockDNS:           []string{"9.9.9.9"}, // Should be ignored
 			expectedError:     false,
 			expectedEnabled:   true,
@@ -255,7 +264,7 @@ func TestUpdateTunnel_DNSVerification(t *testing.T) {
 		},
 		{
 			name:       "Enable Auto-generated Domain (Skip Check)",
-			domain:     utils.GenerateSubdomainForUser(123),
+			domain:     utils.GenerateSubdomainForUser(123, nil),
 			serverIP:   "1.2.3.4",
 			isEnabled:  false,
 			newEnabled: true,
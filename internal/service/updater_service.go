@@ -28,6 +28,7 @@ type UpdaterService struct {
 	currentExePath  string
 	backupDir       string
 	tempDir         string
+	lastBackupPath  string // Backup made by the last InstallUpdate
 	// OnPrivilegeEscalation is called right before attempting sudo escalation (if any)
 	OnPrivilegeEscalation func()
 }
@@ -218,6 +219,7 @@ func (u *UpdaterService) InstallUpdate(downloadPath string) error {
 		return fmt.Errorf("failed to create backup: %w", err)
 	}
 	u.logger.Info("Created backup: %s", backupPath)
+	u.lastBackupPath = backupPath
 
 	// Extract the update
 	extractPath := filepath.Join(u.tempDir, "extract")
@@ -646,6 +648,19 @@ func (u *UpdaterService) copyFile(src, dst string) error {
 	return nil
 }
 
+// LastBackupPath returns the backup of the executable made by the last InstallUpdate
+func (u *UpdaterService) LastBackupPath() string {
+	return u.lastBackupPath
+}
+
+// Rollback replaces the current executable with a backup made by InstallUpdate
+func (u *UpdaterService) Rollback(backupPath string) error {
+	if _, err := os.Stat(backupPath); err != nil {
+		return fmt.Errorf("backup not found: %w", err)
+	}
+	return u.restoreBackup(backupPath)
+}
+
 // restoreBackup restores a backup file
 func (u *UpdaterService) restoreBackup(backupPath string) error {
 	u.logger.Info("Restoring backup from: %s", backupPath)
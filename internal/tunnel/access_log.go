@@ -0,0 +1,251 @@
+package tunnel
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// Access log formats (HybridRouterConfig.AccessLogFormat)
+const (
+	AccessLogCombined = "combined" // Apache/nginx combined: common + "referer" "user-agent"
+	AccessLogCommon   = "common"   // Apache common log format
+)
+
+// Access logs rotate like the server log
+const (
+	accessLogMaxSize    = 100 // MB
+	accessLogMaxBackups = 10
+	accessLogMaxAge     = 30 // days
+)
+
+// accessLogDomainPlaceholder in HybridRouterConfig.AccessLogFile selects one file per tunnel
+const accessLogDomainPlaceholder = "{domain}"
+
+// statusClientClosed is logged when the visitor went away before any response was written
+// (nginx's 499)
+const statusClientClosed = 499
+
+// accessLog writes one line per request in Common/Combined Log Format, followed by the
+// request duration in seconds
+type accessLog struct {
+	path     string
+	combined bool
+
+	mu      sync.Mutex
+	writers map[string]*lumberjack.Logger // By file path
+}
+
+// newAccessLog returns an access log writing to path ("{domain}" is replaced by the tunnel
+// domain), or nil if path is empty
+func newAccessLog(path, format string) (*accessLog, error) {
+	if path == "" {
+		return nil, nil
+	}
+	switch format {
+	case "", AccessLogCombined, AccessLogCommon:
+	default:
+		return nil, fmt.Errorf("invalid access log format %q (expected %s or %s)", format, AccessLogCombined, AccessLogCommon)
+	}
+	return &accessLog{
+		path:     path,
+		combined: format != AccessLogCommon,
+		writers:  make(map[string]*lumberjack.Logger),
+	}, nil
+}
+
+// writer returns the rotating file for domain's requests
+func (l *accessLog) writer(domain string) io.Writer {
+	path := l.path
+	if strings.Contains(path, accessLogDomainPlaceholder) {
+		path = strings.ReplaceAll(path, accessLogDomainPlaceholder, accessLogFileName(domain))
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	w, ok := l.writers[path]
+	if !ok {
+		w = &lumberjack.Logger{
+			Filename:   path,
+			MaxSize:    accessLogMaxSize,
+			MaxBackups: accessLogMaxBackups,
+			MaxAge:     accessLogMaxAge,
+			Compress:   true,
+		}
+		l.writers[path] = w
+	}
+	return w
+}
+
+// accessLogFileName keeps a domain safe to use as a file name
+func accessLogFileName(domain string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-', r == '.':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + ('a' - 'A')
+		}
+		return '_'
+	}, domain)
+	if strings.Trim(name, ".") == "" {
+		return "_"
+	}
+	return name
+}
+
+// Close closes the open log files
+func (l *accessLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var firstErr error
+	for path, w := range l.writers {
+		if err := w.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		delete(l.writers, path)
+	}
+	return firstErr
+}
+
+// record logs a finished request: requestData is the raw request head, conn the visitor
+// connection the response went out on
+func (l *accessLog) record(domain, clientIP string, requestData []byte, conn *accessLogConn, start time.Time) {
+	status, bytesSent := conn.result()
+	if status == 0 {
+		status = statusClientClosed
+	}
+	requestLine, headers, _ := bytes.Cut(requestData, []byte("\r\n"))
+
+	line := make([]byte, 0, 256)
+	line = append(line, clientIP...)
+	line = append(line, " - - ["...)
+	line = start.AppendFormat(line, "02/Jan/2006:15:04:05 -0700")
+	line = append(line, "] "...)
+	line = strconv.AppendQuote(line, string(requestLine))
+	line = append(line, ' ')
+	line = strconv.AppendInt(line, int64(status), 10)
+	line = append(line, ' ')
+	if bytesSent > 0 {
+		line = strconv.AppendInt(line, bytesSent, 10)
+	} else {
+		line = append(line, '-')
+	}
+	if l.combined {
+		line = append(line, ' ')
+		line = strconv.AppendQuote(line, accessLogHeader(headers, "Referer"))
+		line = append(line, ' ')
+		line = strconv.AppendQuote(line, accessLogHeader(headers, "User-Agent"))
+	}
+	line = append(line, ' ')
+	line = strconv.AppendFloat(line, time.Since(start).Seconds(), 'f', 3, 64)
+	line = append(line, '\n')
+
+	l.writer(domain).Write(line)
+}
+
+// accessLogHeader returns a header from a raw request head, or "-"
+func accessLogHeader(headers []byte, name string) string {
+	for len(headers) > 0 {
+		var line []byte
+		line, headers, _ = bytes.Cut(headers, []byte("\r\n"))
+		key, value, ok := bytes.Cut(line, []byte(":"))
+		if ok && strings.EqualFold(string(key), name) {
+			if value = bytes.TrimSpace(value); len(value) > 0 {
+				return string(value)
+			}
+		}
+	}
+	return "-"
+}
+
+// accessLogConn notes the status and body size of the response written to a visitor
+type accessLogConn struct {
+	net.Conn
+
+	mu        sync.Mutex
+	head      []byte // Response head written so far, until its end is found
+	status    int
+	headerLen int64 // Bytes of response heads (interim ones included), -1 until the final one is written
+	skipped   int64 // Bytes of interim (1xx) responses written before the final head
+	written   int64
+}
+
+// maxAccessLogHead bounds how much of a response head is kept looking for its end
+const maxAccessLogHead = 16 << 10
+
+func newAccessLogConn(conn net.Conn) *accessLogConn {
+	return &accessLogConn{Conn: conn, headerLen: -1}
+}
+
+func (c *accessLogConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+
+	c.mu.Lock()
+	if c.headerLen < 0 && len(c.head) < maxAccessLogHead {
+		c.head = append(c.head, p[:n]...)
+		c.scanHead()
+	}
+	c.written += int64(n)
+	c.mu.Unlock()
+	return n, err
+}
+
+// scanHead looks for the end of the final response head, skipping interim responses such
+// as 100 Continue. Callers hold c.mu.
+func (c *accessLogConn) scanHead() {
+	for {
+		status := parseStatusLine(c.head)
+		end := bytes.Index(c.head, []byte("\r\n\r\n"))
+		if end < 0 {
+			if status >= 200 || status == http.StatusSwitchingProtocols {
+				c.status = status
+			}
+			return
+		}
+		c.skipped += int64(end + 4)
+		if status >= 100 && status < 200 && status != http.StatusSwitchingProtocols {
+			c.head = c.head[end+4:]
+			continue
+		}
+		c.status = status
+		c.headerLen = c.skipped
+		c.head = nil
+		return
+	}
+}
+
+// result returns the response status (0 if none was written) and the body bytes sent
+func (c *accessLogConn) result() (int, int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.headerLen < 0 {
+		return c.status, 0
+	}
+	return c.status, c.written - c.headerLen
+}
+
+// parseStatusLine returns the status code of an "HTTP/1.1 200 OK" line, or 0
+func parseStatusLine(head []byte) int {
+	line, _, complete := bytes.Cut(head, []byte("\r\n"))
+	if !complete || !bytes.HasPrefix(line, []byte("HTTP/")) {
+		return 0
+	}
+	fields := bytes.Fields(line)
+	if len(fields) < 2 {
+		return 0
+	}
+	status, err := strconv.Atoi(string(fields[1]))
+	if err != nil {
+		return 0
+	}
+	return status
+}
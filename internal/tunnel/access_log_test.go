@@ -0,0 +1,56 @@
+package tunnel
+
+import (
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestAccessLog(t *testing.T) {
+	dir := t.TempDir()
+	log, err := newAccessLog(filepath.Join(dir, "{domain}.log"), AccessLogCombined)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server, client := net.Pipe()
+	defer client.Close()
+	go io.Copy(io.Discard, client)
+
+	conn := newAccessLogConn(server)
+	conn.Write([]byte("HTTP/1.1 100 Continue\r\n\r\n"))
+	conn.Write([]byte("HTTP/1.1 201 Created\r\nContent-Length: 5\r\n"))
+	conn.Write([]byte("\r\nhello"))
+	server.Close()
+
+	requestData := []byte("POST /items?id=7 HTTP/1.1\r\nHost: App.example.com\r\nuser-agent: curl/8.5.0\r\n\r\n")
+	log.record("App.example.com", "203.0.113.7", requestData, conn, time.Now())
+
+	// Nothing written at all: the visitor left first
+	gone := newAccessLogConn(server)
+	log.record("App.example.com", "203.0.113.8", []byte("GET / HTTP/1.1\r\n\r\n"), gone, time.Now())
+	if err := log.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "app.example.com.log"))
+	if err != nil {
+		t.Fatalf("per-domain access log not written: %v", err)
+	}
+	want := regexp.MustCompile(`^203\.0\.113\.7 - - \[\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}\] "POST /items\?id=7 HTTP/1\.1" 201 5 "-" "curl/8\.5\.0" \d+\.\d{3}\n` +
+		`203\.0\.113\.8 - - \[[^]]+\] "GET / HTTP/1\.1" 499 - "-" "-" \d+\.\d{3}\n$`)
+	if !want.Match(data) {
+		t.Errorf("unexpected access log:\n%s", data)
+	}
+
+	if _, err := newAccessLog("access.log", "json"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+	if accessLogFileName("../etc/passwd") != ".._etc_passwd" {
+		t.Errorf("domain not sanitized: %q", accessLogFileName("../etc/passwd"))
+	}
+}
@@ -0,0 +1,71 @@
+package tunnel
+
+import "time"
+
+const (
+	// DefaultAdaptiveChunkBase is the chunk size a response stream starts with
+	DefaultAdaptiveChunkBase = 2 * 1024 * 1024
+	// DefaultAdaptiveChunkMin is the smallest chunk size used on slow links
+	DefaultAdaptiveChunkMin = 64 * 1024
+	// adaptiveChunkTargetSendTime is the per-chunk send time the sizer aims for.
+	// Faster sends grow the chunk, slower sends shrink it.
+	adaptiveChunkTargetSendTime = 250 * time.Millisecond
+)
+
+// adaptiveChunkSizer picks the next chunk size from how long the previous stream.Send took.
+// Slow links get smaller chunks (lower latency per chunk), fast links get bigger ones (less overhead).
+type adaptiveChunkSizer struct {
+	size int
+	min  int
+	max  int
+}
+
+// newAdaptiveChunkSizer creates a sizer starting at base, bounded by [min, max] and MaxChunkSize.
+// Zero values fall back to the defaults.
+func newAdaptiveChunkSizer(base, min, max int) *adaptiveChunkSizer {
+	if max <= 0 || max > MaxChunkSize {
+		max = MaxChunkSize
+	}
+	if min <= 0 {
+		min = DefaultAdaptiveChunkMin
+	}
+	if min > max {
+		min = max
+	}
+	if base <= 0 {
+		base = DefaultAdaptiveChunkBase
+	}
+	if base < min {
+		base = min
+	}
+	if base > max {
+		base = max
+	}
+	return &adaptiveChunkSizer{size: base, min: min, max: max}
+}
+
+// Size returns the chunk size to use for the next read
+func (a *adaptiveChunkSizer) Size() int {
+	return a.size
+}
+
+// Observe records how long sending n bytes took and adjusts the next chunk size
+func (a *adaptiveChunkSizer) Observe(n int, elapsed time.Duration) {
+	// A short final chunk says nothing about throughput
+	if n < a.size {
+		return
+	}
+
+	switch {
+	case elapsed < adaptiveChunkTargetSendTime/2:
+		a.size *= 2
+		if a.size > a.max {
+			a.size = a.max
+		}
+	case elapsed > adaptiveChunkTargetSendTime*2:
+		a.size /= 2
+		if a.size < a.min {
+			a.size = a.min
+		}
+	}
+}
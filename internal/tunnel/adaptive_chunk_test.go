@@ -0,0 +1,44 @@
+package tunnel
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAdaptiveChunkSizer(t *testing.T) {
+	sizer := newAdaptiveChunkSizer(256*1024, 64*1024, 1024*1024)
+
+	// Fast sends grow the chunk up to the max
+	for i := 0; i < 5; i++ {
+		sizer.Observe(sizer.Size(), time.Millisecond)
+	}
+	if sizer.Size() != 1024*1024 {
+		t.Errorf("Expected chunk size to grow to 1MB, got %d", sizer.Size())
+	}
+
+	// Slow sends shrink it down to the min
+	for i := 0; i < 10; i++ {
+		sizer.Observe(sizer.Size(), 2*time.Second)
+	}
+	if sizer.Size() != 64*1024 {
+		t.Errorf("Expected chunk size to shrink to 64KB, got %d", sizer.Size())
+	}
+
+	// Short (final) chunks don't affect the size
+	sizer.Observe(100, time.Millisecond)
+	if sizer.Size() != 64*1024 {
+		t.Errorf("Expected short chunk to be ignored, got %d", sizer.Size())
+	}
+}
+
+func TestNewAdaptiveChunkSizer_Bounds(t *testing.T) {
+	sizer := newAdaptiveChunkSizer(0, 0, 0)
+	if sizer.Size() != DefaultAdaptiveChunkBase || sizer.min != DefaultAdaptiveChunkMin || sizer.max != MaxChunkSize {
+		t.Errorf("Unexpected defaults: size=%d min=%d max=%d", sizer.Size(), sizer.min, sizer.max)
+	}
+
+	sizer = newAdaptiveChunkSizer(64*1024*1024, 0, 64*1024*1024)
+	if sizer.max != MaxChunkSize || sizer.Size() != MaxChunkSize {
+		t.Errorf("Expected size to be capped by MaxChunkSize, got size=%d max=%d", sizer.Size(), sizer.max)
+	}
+}
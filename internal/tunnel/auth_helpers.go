@@ -2,6 +2,7 @@ package tunnel
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/osa911/giraffecloud/internal/db/ent"
@@ -9,18 +10,24 @@ import (
 )
 
 // AuthenticateTunnelByToken is a shared authentication helper for both TCP and gRPC tunnel servers.
-// It validates the API token, filters for active tunnels, and matches by domain if provided.
+// It validates the API token, filters for active tunnels, and matches by tunnel ID and/or domain if provided.
+// Refusals are HandshakeErrors (INVALID_TOKEN or NO_TUNNEL); other errors are server-side failures.
+// A tunnelID of 0 means "not specified".
 func AuthenticateTunnelByToken(
 	ctx context.Context,
 	token string,
 	domain string,
+	tunnelID uint32,
 	tokenRepo repository.TokenRepository,
 	tunnelRepo repository.TunnelRepository,
 ) (*ent.Tunnel, error) {
 	// Find user by API token
 	apiToken, err := tokenRepo.GetByToken(ctx, token)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, handshakeErrorf(HandshakeInvalidToken, "invalid token: %v", err)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("invalid token: %w", err)
+		return nil, fmt.Errorf("failed to look up token: %w", err)
 	}
 
 	// Get user's tunnels
@@ -30,7 +37,7 @@ func AuthenticateTunnelByToken(
 	}
 
 	if len(tunnels) == 0 {
-		return nil, fmt.Errorf("no tunnels configured - please create a tunnel in the web UI first")
+		return nil, handshakeErrorf(HandshakeNoTunnel, "no tunnels configured - please create a tunnel in the web UI first")
 	}
 
 	// Filter for enabled tunnels only
@@ -42,7 +49,24 @@ func AuthenticateTunnelByToken(
 	}
 
 	if len(enabledTunnels) == 0 {
-		return nil, fmt.Errorf("no enabled tunnels found - please enable a tunnel in the web UI first")
+		return nil, handshakeErrorf(HandshakeNoTunnel, "no enabled tunnels found - please enable a tunnel in the web UI first")
+	}
+
+	// If client provided a tunnel ID, it must belong to this user and be enabled
+	if tunnelID != 0 {
+		for _, t := range tunnels {
+			if uint32(t.ID) != tunnelID {
+				continue
+			}
+			if domain != "" && t.Domain != domain {
+				return nil, handshakeErrorf(HandshakeNoTunnel, "tunnel %d does not match domain '%s'", tunnelID, domain)
+			}
+			if !t.IsEnabled {
+				return nil, handshakeErrorf(HandshakeNoTunnel, "tunnel %d (%s) exists but is disabled - please enable it in the web UI first", tunnelID, t.Domain)
+			}
+			return t, nil
+		}
+		return nil, handshakeErrorf(HandshakeNoTunnel, "tunnel %d not found for this account - run 'giraffecloud tunnels list' to see available tunnels", tunnelID)
 	}
 
 	// If client provided a domain, try to match it (must be enabled)
@@ -56,11 +80,11 @@ func AuthenticateTunnelByToken(
 		// Check if domain exists but is disabled
 		for _, t := range tunnels {
 			if t.Domain == domain && !t.IsEnabled {
-				return nil, fmt.Errorf("tunnel for domain '%s' exists but is disabled - please enable it in the web UI first", domain)
+				return nil, handshakeErrorf(HandshakeNoTunnel, "tunnel for domain '%s' exists but is disabled - please enable it in the web UI first", domain)
 			}
 		}
 
-		return nil, fmt.Errorf("no enabled tunnel found for domain: %s", domain)
+		return nil, handshakeErrorf(HandshakeNoTunnel, "no enabled tunnel found for domain: %s", domain)
 	}
 
 	// If no domain specified, check if user has multiple enabled tunnels
@@ -70,7 +94,7 @@ func AuthenticateTunnelByToken(
 		for i, t := range enabledTunnels {
 			domains[i] = t.Domain
 		}
-		return nil, fmt.Errorf("multiple enabled tunnels found - please specify which domain to connect to using --domain flag. Available: %v", domains)
+		return nil, handshakeErrorf(HandshakeNoTunnel, "multiple enabled tunnels found - please specify which domain to connect to using --domain flag. Available: %v", domains)
 	}
 
 	// Single enabled tunnel case - use it automatically
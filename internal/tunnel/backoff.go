@@ -0,0 +1,56 @@
+package tunnel
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// BackoffStrategy selects how the delay between reconnect attempts grows
+type BackoffStrategy string
+
+const (
+	// BackoffExponential multiplies the delay by the backoff factor after each failure (default)
+	BackoffExponential BackoffStrategy = "exponential"
+	// BackoffLinear adds the initial delay after each failure
+	BackoffLinear BackoffStrategy = "linear"
+	// BackoffConstant always waits the initial delay
+	BackoffConstant BackoffStrategy = "constant"
+)
+
+// ParseBackoffStrategy validates s ("" = BackoffExponential)
+func ParseBackoffStrategy(s string) (BackoffStrategy, error) {
+	switch strategy := BackoffStrategy(strings.ToLower(strings.TrimSpace(s))); strategy {
+	case "":
+		return BackoffExponential, nil
+	case BackoffExponential, BackoffLinear, BackoffConstant:
+		return strategy, nil
+	default:
+		return "", fmt.Errorf("unsupported backoff strategy %q (use exponential, linear or constant)", s)
+	}
+}
+
+// nextBackoffDelay returns the delay after current for strategy, capped at maxDelay and, with
+// jitter, randomized by up to ±10%. Unknown strategies fall back to exponential.
+func nextBackoffDelay(strategy BackoffStrategy, current, initial, maxDelay time.Duration, factor float64, jitter bool) time.Duration {
+	var next time.Duration
+	switch BackoffStrategy(strings.ToLower(string(strategy))) {
+	case BackoffConstant:
+		next = initial
+	case BackoffLinear:
+		next = current + initial
+	default:
+		next = time.Duration(float64(current) * factor)
+	}
+
+	if maxDelay > 0 && next > maxDelay {
+		next = maxDelay
+	}
+
+	// Jitter prevents a thundering herd of clients reconnecting in lockstep
+	if jitter {
+		next += time.Duration(float64(next) * 0.1 * (2*rand.Float64() - 1))
+	}
+	return next
+}
@@ -0,0 +1,48 @@
+package tunnel
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNextBackoffDelay(t *testing.T) {
+	tests := []struct {
+		strategy BackoffStrategy
+		current  time.Duration
+		want     time.Duration
+	}{
+		{"", 4 * time.Second, 8 * time.Second},
+		{BackoffExponential, 4 * time.Second, 8 * time.Second},
+		{BackoffExponential, 40 * time.Second, time.Minute}, // capped
+		{BackoffLinear, 4 * time.Second, 5 * time.Second},
+		{BackoffLinear, time.Minute, time.Minute},
+		{BackoffConstant, 4 * time.Second, time.Second},
+		{"Linear", 4 * time.Second, 5 * time.Second},
+	}
+	for _, tt := range tests {
+		got := nextBackoffDelay(tt.strategy, tt.current, time.Second, time.Minute, 2, false)
+		if got != tt.want {
+			t.Errorf("nextBackoffDelay(%q, %v) = %v, want %v", tt.strategy, tt.current, got, tt.want)
+		}
+	}
+
+	// Jitter stays within ±10%
+	for i := 0; i < 100; i++ {
+		got := nextBackoffDelay(BackoffConstant, 0, 10*time.Second, time.Minute, 2, true)
+		if got < 9*time.Second || got > 11*time.Second {
+			t.Fatalf("jittered delay %v outside 9s-11s", got)
+		}
+	}
+}
+
+func TestParseBackoffStrategy(t *testing.T) {
+	if s, err := ParseBackoffStrategy(""); err != nil || s != BackoffExponential {
+		t.Errorf(`ParseBackoffStrategy("") = %q, %v`, s, err)
+	}
+	if s, err := ParseBackoffStrategy(" Constant "); err != nil || s != BackoffConstant {
+		t.Errorf(`ParseBackoffStrategy(" Constant ") = %q, %v`, s, err)
+	}
+	if _, err := ParseBackoffStrategy("fibonacci"); err == nil {
+		t.Error("expected an error for an unknown strategy")
+	}
+}
@@ -0,0 +1,136 @@
+package tunnel
+
+import (
+	"context"
+	"io"
+	"math"
+	"sync"
+
+	"golang.org/x/time/rate"
+)
+
+// bandwidthLimiter caps the throughput of one traffic direction for a tunnel.
+// All requests on the tunnel share the bucket; a rate of 0 means unlimited.
+type bandwidthLimiter struct {
+	mu      sync.RWMutex
+	limiter *rate.Limiter // nil when unlimited
+}
+
+// newBandwidthLimiter creates a limiter allowing bytesPerSec (0 = unlimited)
+func newBandwidthLimiter(bytesPerSec int64) *bandwidthLimiter {
+	b := &bandwidthLimiter{}
+	b.SetRate(bytesPerSec)
+	return b
+}
+
+// SetRate changes the limit; in-flight transfers pick it up on their next write
+func (b *bandwidthLimiter) SetRate(bytesPerSec int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if bytesPerSec <= 0 {
+		b.limiter = nil
+		return
+	}
+	// One second worth of bytes can go out at once
+	burst := int(min(bytesPerSec, math.MaxInt32))
+	if b.limiter == nil {
+		b.limiter = rate.NewLimiter(rate.Limit(bytesPerSec), burst)
+		return
+	}
+	b.limiter.SetLimit(rate.Limit(bytesPerSec))
+	b.limiter.SetBurst(burst)
+}
+
+// maxChunk returns the largest piece that can be waited for at once (0 = unlimited)
+func (b *bandwidthLimiter) maxChunk() int {
+	if b == nil {
+		return 0
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.limiter == nil {
+		return 0
+	}
+	return b.limiter.Burst()
+}
+
+// WaitN blocks until n bytes may be sent, or ctx is done
+func (b *bandwidthLimiter) WaitN(ctx context.Context, n int) error {
+	if b == nil {
+		return nil
+	}
+	for n > 0 {
+		b.mu.RLock()
+		limiter := b.limiter
+		b.mu.RUnlock()
+		if limiter == nil {
+			return nil
+		}
+
+		// WaitN rejects requests larger than the burst, so wait in burst-sized pieces
+		step := min(n, limiter.Burst())
+		if err := limiter.WaitN(ctx, step); err != nil {
+			return err
+		}
+		n -= step
+	}
+	return nil
+}
+
+// throttledReader limits how fast data can be read through it
+type throttledReader struct {
+	ctx     context.Context
+	r       io.Reader
+	limiter *bandwidthLimiter
+}
+
+// newThrottledReader wraps r; reads are paced by limiter (nil = unlimited)
+func newThrottledReader(ctx context.Context, r io.Reader, limiter *bandwidthLimiter) io.Reader {
+	return &throttledReader{ctx: ctx, r: r, limiter: limiter}
+}
+
+func (t *throttledReader) Read(p []byte) (int, error) {
+	if limit := t.limiter.maxChunk(); limit > 0 && len(p) > limit {
+		p = p[:limit]
+	}
+	n, err := t.r.Read(p)
+	if n > 0 {
+		if waitErr := t.limiter.WaitN(t.ctx, n); waitErr != nil && err == nil {
+			err = waitErr
+		}
+	}
+	return n, err
+}
+
+// throttledWriter limits how fast data can be written through it
+type throttledWriter struct {
+	ctx     context.Context
+	w       io.Writer
+	limiter *bandwidthLimiter
+}
+
+// newThrottledWriter wraps w; writes are paced by limiter (nil = unlimited)
+func newThrottledWriter(ctx context.Context, w io.Writer, limiter *bandwidthLimiter) io.Writer {
+	return &throttledWriter{ctx: ctx, w: w, limiter: limiter}
+}
+
+func (t *throttledWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		step := len(p)
+		if limit := t.limiter.maxChunk(); limit > 0 && step > limit {
+			step = limit
+		}
+		if err := t.limiter.WaitN(t.ctx, step); err != nil {
+			return written, err
+		}
+		n, err := t.w.Write(p[:step])
+		written += n
+		if err != nil {
+			return written, err
+		}
+		p = p[step:]
+	}
+	return written, nil
+}
@@ -0,0 +1,71 @@
+package tunnel
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestThrottledWriterPacesWrites(t *testing.T) {
+	limiter := newBandwidthLimiter(10000) // 10KB/s, 10KB burst
+	var buf bytes.Buffer
+	w := newThrottledWriter(context.Background(), &buf, limiter)
+
+	start := time.Now()
+	n, err := w.Write(make([]byte, 15000))
+	elapsed := time.Since(start)
+
+	if err != nil || n != 15000 {
+		t.Fatalf("Write() = %d, %v; want 15000, nil", n, err)
+	}
+	if buf.Len() != 15000 {
+		t.Fatalf("wrote %d bytes, want 15000", buf.Len())
+	}
+	// The burst goes out immediately, the remaining 5KB takes ~0.5s
+	if elapsed < 400*time.Millisecond {
+		t.Fatalf("15KB at 10KB/s took %v, want >= 400ms", elapsed)
+	}
+}
+
+func TestThrottledReaderUnlimited(t *testing.T) {
+	limiter := newBandwidthLimiter(0)
+	r := newThrottledReader(context.Background(), bytes.NewReader(make([]byte, 1<<20)), limiter)
+
+	start := time.Now()
+	data, err := io.ReadAll(r)
+	if err != nil || len(data) != 1<<20 {
+		t.Fatalf("ReadAll() = %d bytes, %v", len(data), err)
+	}
+	if elapsed := time.Since(start); elapsed > 200*time.Millisecond {
+		t.Fatalf("unlimited read took %v", elapsed)
+	}
+
+	// A nil limiter is also unlimited
+	if err := (*bandwidthLimiter)(nil).WaitN(context.Background(), 1<<20); err != nil {
+		t.Fatalf("nil limiter WaitN() = %v", err)
+	}
+}
+
+func TestBandwidthLimiterCancel(t *testing.T) {
+	limiter := newBandwidthLimiter(1000)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	// 1KB burst, then 4KB more would take 4s
+	if err := limiter.WaitN(ctx, 5000); err == nil {
+		t.Fatal("WaitN() returned nil after context deadline")
+	}
+}
+
+func TestBandwidthLimiterSetRate(t *testing.T) {
+	limiter := newBandwidthLimiter(1000)
+	if got := limiter.maxChunk(); got != 1000 {
+		t.Fatalf("maxChunk() = %d, want 1000", got)
+	}
+	limiter.SetRate(0)
+	if got := limiter.maxChunk(); got != 0 {
+		t.Fatalf("maxChunk() after SetRate(0) = %d, want 0", got)
+	}
+}
@@ -0,0 +1,94 @@
+package tunnel
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"time"
+)
+
+// DefaultCertExpiryWarning is how long before expiry certificates start being reported
+const DefaultCertExpiryWarning = 14 * 24 * time.Hour
+
+// CertExpiry describes when a configured certificate stops being valid
+type CertExpiry struct {
+	Name     string // "client" or "CA"
+	Path     string
+	NotAfter time.Time
+}
+
+// Remaining returns the time left until the certificate expires (negative once expired)
+func (c CertExpiry) Remaining() time.Duration {
+	return time.Until(c.NotAfter)
+}
+
+// ExpiresWithin reports whether the certificate expires within d (or already has)
+func (c CertExpiry) ExpiresWithin(d time.Duration) bool {
+	return c.Remaining() <= d
+}
+
+// String formats the expiry for log lines, e.g. "client certificate expires 2025-01-02 (in 3 days)"
+func (c CertExpiry) String() string {
+	date := c.NotAfter.Local().Format("2006-01-02")
+	remaining := c.Remaining()
+	if remaining <= 0 {
+		return fmt.Sprintf("%s certificate expired on %s", c.Name, date)
+	}
+	days := int(remaining.Hours() / 24)
+	if days == 0 {
+		return fmt.Sprintf("%s certificate expires %s (in less than a day)", c.Name, date)
+	}
+	return fmt.Sprintf("%s certificate expires %s (in %d days)", c.Name, date, days)
+}
+
+// CheckCertExpiry reads the client and CA certificates configured in sec and returns
+// when each expires. Certificates that aren't configured are skipped.
+func CheckCertExpiry(sec SecurityConfig) ([]CertExpiry, error) {
+	var expiries []CertExpiry
+	for _, c := range []struct{ name, path string }{
+		{"client", sec.ClientCert},
+		{"CA", sec.CACert},
+	} {
+		if c.path == "" {
+			continue
+		}
+		notAfter, err := certFileNotAfter(c.path)
+		if err != nil {
+			return expiries, err
+		}
+		expiries = append(expiries, CertExpiry{Name: c.name, Path: c.path, NotAfter: notAfter})
+	}
+	return expiries, nil
+}
+
+// certFileNotAfter returns the earliest NotAfter of the certificates in a PEM file
+func certFileNotAfter(path string) (time.Time, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to read certificate %s: %w", path, err)
+	}
+
+	var notAfter time.Time
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("failed to parse certificate %s: %w", path, err)
+		}
+		if notAfter.IsZero() || cert.NotAfter.Before(notAfter) {
+			notAfter = cert.NotAfter
+		}
+	}
+	if notAfter.IsZero() {
+		return time.Time{}, fmt.Errorf("no certificate found in %s", path)
+	}
+	return notAfter, nil
+}
@@ -0,0 +1,67 @@
+package tunnel
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeTestCert writes a self-signed PEM certificate valid until notAfter
+func writeTestCert(t *testing.T, path string, notAfter time.Time) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckCertExpiry(t *testing.T) {
+	dir := t.TempDir()
+	clientPath := filepath.Join(dir, "client.crt")
+	caPath := filepath.Join(dir, "ca.crt")
+	clientExpiry := time.Now().Add(5 * 24 * time.Hour).Truncate(time.Second)
+	writeTestCert(t, clientPath, clientExpiry)
+	writeTestCert(t, caPath, time.Now().Add(365*24*time.Hour))
+
+	expiries, err := CheckCertExpiry(SecurityConfig{ClientCert: clientPath, CACert: caPath})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(expiries) != 2 {
+		t.Fatalf("got %d expiries, want 2", len(expiries))
+	}
+
+	client := expiries[0]
+	if client.Name != "client" || !client.NotAfter.Equal(clientExpiry) {
+		t.Errorf("client expiry = %+v, want NotAfter %v", client, clientExpiry)
+	}
+	if !client.ExpiresWithin(DefaultCertExpiryWarning) {
+		t.Error("client certificate expiring in 5 days should be within the default window")
+	}
+	if expiries[1].ExpiresWithin(DefaultCertExpiryWarning) {
+		t.Error("CA certificate expiring in a year should not be within the default window")
+	}
+
+	if _, err := CheckCertExpiry(SecurityConfig{ClientCert: filepath.Join(dir, "missing.crt")}); err == nil {
+		t.Error("expected an error for a missing certificate")
+	}
+}
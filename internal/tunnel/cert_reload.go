@@ -0,0 +1,96 @@
+package tunnel
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+)
+
+// errCertificatesReloaded is the reason recorded for reconnects ReloadCertificates asks for
+var errCertificatesReloaded = errors.New("certificates reloaded from disk")
+
+// CertReloadRequests delivers a value each time the process is asked to reload its
+// certificates until ctx ends: SIGHUP, or on Windows the CertReloadEvent named event.
+// Requests arriving while one is pending are merged.
+func CertReloadRequests(ctx context.Context) (<-chan struct{}, error) {
+	requests := make(chan struct{}, 1)
+	if err := notifyCertReload(ctx, requests); err != nil {
+		return nil, err
+	}
+	return requests, nil
+}
+
+// ReloadCertificates re-reads the certificates named in the config file and reconnects so the
+// server sees them, e.g. after cert-management tooling rotated them on disk. Certificates that
+// don't load are reported and the current connections are left alone. The gRPC client is kept
+// with its client ID: it reconnects in place, failing the requests in flight on the old stream.
+func (t *Tunnel) ReloadCertificates() error {
+	cfg, err := LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	tlsConfig, err := tcpTunnelTLSConfig(cfg)
+	if err != nil {
+		return err
+	}
+	if expiries, err := CheckCertExpiry(cfg.Security); err == nil {
+		t.SetCertExpiries(expiries)
+	}
+
+	t.certMu.Lock()
+	t.reloadedTLS = tlsConfig
+	if t.certsRotated != nil {
+		close(t.certsRotated)
+		t.certsRotated = nil
+	}
+	t.certMu.Unlock()
+
+	client := t.grpcClient
+	if client == nil || !client.IsConnected() {
+		t.logger.Info("🔐 Certificates reloaded - the tunnel presents them when it reconnects")
+		return nil
+	}
+	t.logger.Info("🔐 Certificates reloaded, reconnecting the tunnel (Client ID: %s)", client.GetClientID())
+
+	// WebSocket tunnels first: they fall back to a full reconnect once gRPC is down
+	t.wsConnsMu.Lock()
+	hasWebSockets := len(t.wsConns) > 0
+	t.wsConnsMu.Unlock()
+	if hasWebSockets {
+		t.reconnectTCPTunnelOnly()
+	}
+	client.Reconnect(errCertificatesReloaded)
+	return nil
+}
+
+// certificateReloads returns the TLS config ReloadCertificates loaded last (nil = none yet)
+// and a channel closed at the next reload
+func (t *Tunnel) certificateReloads() (*tls.Config, <-chan struct{}) {
+	t.certMu.Lock()
+	defer t.certMu.Unlock()
+	if t.certsRotated == nil {
+		t.certsRotated = make(chan struct{})
+	}
+	return t.reloadedTLS, t.certsRotated
+}
+
+// ReloadCertificates reloads the certificates of every tunnel and reconnects them
+func (m *MultiTunnel) ReloadCertificates() error {
+	var errs []error
+	for _, t := range m.tunnels {
+		if err := t.ReloadCertificates(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", t.domain, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Reconnect replaces the tunnel stream with a new connection, which reads the certificates
+// from disk again. The client keeps its ID; requests in flight on the old stream fail.
+func (c *GRPCTunnelClient) Reconnect(reason error) {
+	c.mu.Lock()
+	c.lastError = reason
+	c.mu.Unlock()
+	go c.reconnect()
+}
@@ -0,0 +1,55 @@
+package tunnel
+
+import (
+	"os"
+	"testing"
+)
+
+func TestReloadCertificates(t *testing.T) {
+	initTestLogger(t)
+	t.Setenv("GIRAFFECLOUD_HOME", t.TempDir())
+	certs, err := writeTestCertificates(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := DefaultConfig
+	cfg.Token = "tok"
+	cfg.Security.CACert, cfg.Security.ClientCert, cfg.Security.ClientKey = certs.caCert, certs.clientCert, certs.clientKey
+	if err := SaveConfig(&cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	tun := NewTunnel()
+	_, rotated := tun.certificateReloads()
+	if err := tun.ReloadCertificates(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-rotated:
+	default:
+		t.Fatal("connections using the old certificates not told to redial")
+	}
+	reloaded, rotated := tun.certificateReloads()
+	if reloaded == nil || len(reloaded.Certificates) != 1 {
+		t.Fatalf("reloaded TLS config: %+v", reloaded)
+	}
+	if tun.GetStats()["client_cert_expires"] == nil {
+		t.Error("certificate expiry not refreshed")
+	}
+
+	// A half-written rotation is refused and the tunnel keeps what it has
+	if err := os.Remove(certs.clientKey); err != nil {
+		t.Fatal(err)
+	}
+	if tun.ReloadCertificates() == nil {
+		t.Error("missing client key accepted")
+	}
+	select {
+	case <-rotated:
+		t.Error("connections redialed after a failed reload")
+	default:
+	}
+	if current, _ := tun.certificateReloads(); current != reloaded {
+		t.Error("failed reload replaced the TLS config")
+	}
+}
@@ -0,0 +1,31 @@
+//go:build !windows
+
+package tunnel
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// notifyCertReload forwards SIGHUP to requests until ctx ends
+func notifyCertReload(ctx context.Context, requests chan<- struct{}) error {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(signals)
+		for {
+			select {
+			case <-signals:
+				select {
+				case requests <- struct{}{}:
+				default:
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return nil
+}
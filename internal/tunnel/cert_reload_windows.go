@@ -0,0 +1,59 @@
+//go:build windows
+
+package tunnel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"golang.org/x/sys/windows"
+)
+
+// CertReloadEvent is the named event that stands in for SIGHUP on Windows, e.g. from PowerShell:
+//
+//	[Threading.EventWaitHandle]::OpenExisting('Global\GiraffeCloudReloadCertificates').Set()
+//
+// Creating Global\ objects takes a privilege services have; a tunnel started from a console
+// without it creates the event in its session's Local\ namespace instead.
+const CertReloadEvent = `Global\GiraffeCloudReloadCertificates`
+
+// notifyCertReload forwards each time CertReloadEvent is set to requests until ctx ends
+func notifyCertReload(ctx context.Context, requests chan<- struct{}) error {
+	event, err := createCertReloadEvent(CertReloadEvent)
+	if errors.Is(err, windows.ERROR_ACCESS_DENIED) {
+		event, err = createCertReloadEvent(`Local\GiraffeCloudReloadCertificates`)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create certificate reload event: %w", err)
+	}
+	go func() {
+		defer windows.CloseHandle(event)
+		for ctx.Err() == nil {
+			result, err := windows.WaitForSingleObject(event, 1000)
+			if err != nil {
+				return
+			}
+			if result == windows.WAIT_OBJECT_0 {
+				select {
+				case requests <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// createCertReloadEvent creates (or opens, when it exists already) an auto-reset event
+func createCertReloadEvent(name string) (windows.Handle, error) {
+	namePtr, err := windows.UTF16PtrFromString(name)
+	if err != nil {
+		return 0, err
+	}
+	event, err := windows.CreateEvent(nil, 0, 0, namePtr)
+	if event != 0 && errors.Is(err, windows.ERROR_ALREADY_EXISTS) {
+		err = nil
+	}
+	return event, err
+}
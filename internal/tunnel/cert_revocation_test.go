@@ -0,0 +1,82 @@
+package tunnel
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/osa911/giraffecloud/internal/logging"
+)
+
+// writeTestCRL writes a PEM CRL revoking the given serial numbers
+func writeTestCRL(t *testing.T, path string, number int64, serials ...int64) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer := &x509.Certificate{SerialNumber: big.NewInt(1), KeyUsage: x509.KeyUsageCRLSign, SubjectKeyId: []byte{1}}
+	template := &x509.RevocationList{Number: big.NewInt(number), ThisUpdate: time.Now(), NextUpdate: time.Now().Add(time.Hour)}
+	for _, serial := range serials {
+		template.RevokedCertificateEntries = append(template.RevokedCertificateEntries,
+			x509.RevocationListEntry{SerialNumber: big.NewInt(serial), RevocationTime: time.Now()})
+	}
+	der, err := x509.CreateRevocationList(rand.Reader, template, issuer, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCertRevocation(t *testing.T) {
+	initTestLogger(t)
+	crlPath := filepath.Join(t.TempDir(), "clients.crl")
+	writeTestCRL(t, crlPath, 1, 2)
+	check, err := CRLRevocationCheck(crlPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetCertRevocationCheck(check)
+	defer SetCertRevocationCheck(nil)
+
+	revokedCert := &x509.Certificate{SerialNumber: big.NewInt(2)}
+	goodCert := &x509.Certificate{SerialNumber: big.NewInt(3)}
+	if verifyClientNotRevoked(tls.ConnectionState{PeerCertificates: []*x509.Certificate{revokedCert}}) == nil {
+		t.Error("revoked certificate accepted at handshake")
+	}
+	if err := verifyClientNotRevoked(tls.ConnectionState{PeerCertificates: []*x509.Certificate{goodCert}}); err != nil {
+		t.Errorf("valid certificate refused: %v", err)
+	}
+
+	s := &GRPCTunnelServer{logger: logging.GetGlobalLogger(), tunnelStreams: make(map[string]*TunnelStream)}
+	newStream := func(domain string, cert *x509.Certificate) *TunnelStream {
+		ts := &TunnelStream{Domain: domain, Context: context.Background(), clientCert: cert, revoked: make(chan struct{}), lastActivity: time.Now()}
+		s.tunnelStreams[domain] = ts
+		return ts
+	}
+	good := newStream("good.example.com", goodCert)
+	if n := s.revokeStreams(); n != 0 {
+		t.Fatalf("revoked %d streams before the CRL changed", n)
+	}
+
+	// The certificate of a connected stream gets revoked: the next check closes it
+	writeTestCRL(t, crlPath, 2, 2, 3)
+	os.Chtimes(crlPath, time.Now().Add(time.Minute), time.Now().Add(time.Minute))
+	if n := s.revokeStreams(); n != 1 {
+		t.Fatalf("revoked %d streams, want 1", n)
+	}
+	if err := s.monitorTunnelHealth(good); err != errStreamCertRevoked {
+		t.Errorf("stream ended with %v, want errStreamCertRevoked", err)
+	}
+}
@@ -0,0 +1,98 @@
+package tunnel
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+)
+
+// RotateClientCert replaces the client certificate and key at the paths in security with
+// certPEM/keyPEM. The new pair must form a valid keypair and chain to the CA: caPEM when
+// given (which then replaces the CA file too), otherwise the CA already on disk.
+//
+// Every file is written next to its target first and renamed into place, so a tunnel
+// reconnecting meanwhile sees either the old or the new file, never a partial one.
+func RotateClientCert(security SecurityConfig, certPEM, keyPEM, caPEM []byte) error {
+	certPath := expandTildePath(security.ClientCert)
+	keyPath := expandTildePath(security.ClientKey)
+	caPath := expandTildePath(security.CACert)
+	if certPath == "" || keyPath == "" || caPath == "" {
+		return fmt.Errorf("certificate paths are not configured - run 'giraffecloud login' first")
+	}
+
+	if err := verifyClientCert(certPEM, keyPEM, caPEM, caPath); err != nil {
+		return err
+	}
+
+	files := []struct{ path, tmp string }{}
+	stage := func(path string, data []byte) error {
+		tmp := path + ".tmp"
+		if err := os.WriteFile(tmp, data, 0600); err != nil {
+			return fmt.Errorf("failed to write %s: %w", tmp, err)
+		}
+		files = append(files, struct{ path, tmp string }{path, tmp})
+		return nil
+	}
+	cleanup := func() {
+		for _, f := range files {
+			os.Remove(f.tmp)
+		}
+	}
+
+	// Stage everything before swapping anything, so a failed write leaves the old set intact
+	if caPEM != nil {
+		if err := stage(caPath, caPEM); err != nil {
+			cleanup()
+			return err
+		}
+	}
+	if err := stage(keyPath, keyPEM); err != nil {
+		cleanup()
+		return err
+	}
+	if err := stage(certPath, certPEM); err != nil {
+		cleanup()
+		return err
+	}
+
+	for i, f := range files {
+		if err := os.Rename(f.tmp, f.path); err != nil {
+			for _, rest := range files[i:] {
+				os.Remove(rest.tmp)
+			}
+			return fmt.Errorf("failed to replace %s: %w", f.path, err)
+		}
+	}
+	return nil
+}
+
+// verifyClientCert checks that certPEM/keyPEM form a keypair whose certificate is signed
+// by caPEM, or by the CA certificate at caPath when caPEM is nil
+func verifyClientCert(certPEM, keyPEM, caPEM []byte, caPath string) error {
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return fmt.Errorf("client certificate and key do not form a valid keypair: %w", err)
+	}
+	leaf, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return fmt.Errorf("failed to parse client certificate: %w", err)
+	}
+
+	if caPEM == nil {
+		if caPEM, err = os.ReadFile(caPath); err != nil {
+			return fmt.Errorf("failed to read CA certificate: %w", err)
+		}
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(caPEM) {
+		return fmt.Errorf("CA certificate contains no valid PEM certificates")
+	}
+	if _, err := leaf.Verify(x509.VerifyOptions{
+		Roots:     roots,
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}); err != nil {
+		return fmt.Errorf("new client certificate does not chain to the CA: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package tunnel
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCA issues client certificates for rotation tests
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns a PEM client certificate and key signed by the CA
+func (ca *testCA) issue(t *testing.T) (certPEM, keyPEM []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(12 * time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func TestRotateClientCert(t *testing.T) {
+	dir := t.TempDir()
+	security := SecurityConfig{
+		CACert:     filepath.Join(dir, "ca.crt"),
+		ClientCert: filepath.Join(dir, "client.crt"),
+		ClientKey:  filepath.Join(dir, "client.key"),
+	}
+	ca := newTestCA(t)
+	oldCert, oldKey := ca.issue(t)
+	for path, data := range map[string][]byte{security.CACert: ca.pem, security.ClientCert: oldCert, security.ClientKey: oldKey} {
+		if err := os.WriteFile(path, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	assertFile := func(path string, want []byte) {
+		t.Helper()
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != string(want) {
+			t.Errorf("%s was not the expected content", filepath.Base(path))
+		}
+	}
+
+	// A pair from the same CA replaces the client files and leaves the CA alone
+	newCert, newKey := ca.issue(t)
+	if err := RotateClientCert(security, newCert, newKey, nil); err != nil {
+		t.Fatalf("RotateClientCert: %v", err)
+	}
+	assertFile(security.ClientCert, newCert)
+	assertFile(security.ClientKey, newKey)
+	assertFile(security.CACert, ca.pem)
+
+	// Mismatched cert and key are rejected without touching anything
+	otherCert, _ := ca.issue(t)
+	if err := RotateClientCert(security, otherCert, newKey, nil); err == nil {
+		t.Error("expected an error for a certificate that doesn't match the key")
+	}
+	assertFile(security.ClientCert, newCert)
+
+	// A pair from a different CA needs that CA to be rotated along with it
+	otherCA := newTestCA(t)
+	caCert, caKey := otherCA.issue(t)
+	if err := RotateClientCert(security, caCert, caKey, nil); err == nil {
+		t.Error("expected an error for a certificate signed by another CA")
+	}
+	assertFile(security.ClientCert, newCert)
+	if err := RotateClientCert(security, caCert, caKey, otherCA.pem); err != nil {
+		t.Fatalf("RotateClientCert with CA: %v", err)
+	}
+	assertFile(security.ClientCert, caCert)
+	assertFile(security.CACert, otherCA.pem)
+
+	if leftovers, _ := filepath.Glob(filepath.Join(dir, "*.tmp")); len(leftovers) > 0 {
+		t.Errorf("temporary files left behind: %v", leftovers)
+	}
+}
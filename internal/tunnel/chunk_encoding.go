@@ -0,0 +1,138 @@
+package tunnel
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/osa911/giraffecloud/internal/tunnel/proto"
+)
+
+// Chunk encodings, negotiated through TunnelCapabilities.supported_encodings and
+// named per message in HTTPResponse.chunk_encoding
+const (
+	ChunkEncodingGzip    = "gzip"
+	ChunkEncodingDeflate = "deflate"
+)
+
+// supportedChunkEncodings lists the encodings this build can compress and decompress, in preference order
+var supportedChunkEncodings = []string{ChunkEncodingGzip, ChunkEncodingDeflate}
+
+// negotiateChunkEncodings returns the encodings from offered that this build supports
+func negotiateChunkEncodings(offered []string) []string {
+	var accepted []string
+	for _, enc := range supportedChunkEncodings {
+		for _, o := range offered {
+			if strings.EqualFold(strings.TrimSpace(o), enc) {
+				accepted = append(accepted, enc)
+				break
+			}
+		}
+	}
+	return accepted
+}
+
+// selectChunkEncoding picks the preferred encoding from the ones the server accepted
+func selectChunkEncoding(accepted []string) string {
+	if encodings := negotiateChunkEncodings(accepted); len(encodings) > 0 {
+		return encodings[0]
+	}
+	return ""
+}
+
+// acceptedCapabilities answers a client's handshake capabilities with the chunk encodings
+// the server can decode and whether it puts chunks back in order (see chunkReorderer); nil
+// when the client offered neither
+func acceptedCapabilities(offered *proto.TunnelCapabilities) *proto.TunnelCapabilities {
+	var encodings []string
+	if offered.GetSupportsCompression() {
+		encodings = negotiateChunkEncodings(offered.GetSupportedEncodings())
+	}
+	reorder := offered.GetSupportsChunkReorder()
+	if len(encodings) == 0 && !reorder {
+		return nil
+	}
+	return &proto.TunnelCapabilities{SupportsCompression: len(encodings) > 0, SupportedEncodings: encodings, SupportsChunkReorder: reorder}
+}
+
+// isCompressibleResponse reports whether a response body is worth compressing per chunk.
+// Bodies already carrying a Content-Encoding and binary media types are sent as-is.
+func isCompressibleResponse(headers map[string]string) bool {
+	for k, v := range headers {
+		if strings.EqualFold(k, "Content-Encoding") && v != "" && !strings.EqualFold(v, "identity") {
+			return false
+		}
+	}
+
+	var contentType string
+	for k, v := range headers {
+		if strings.EqualFold(k, "Content-Type") {
+			contentType = strings.ToLower(v)
+			break
+		}
+	}
+	if contentType == "" {
+		return false
+	}
+
+	if strings.HasPrefix(contentType, "text/") {
+		return true
+	}
+	for _, t := range []string{"json", "javascript", "xml", "yaml", "csv", "svg", "x-ndjson"} {
+		if strings.Contains(contentType, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// encodeChunk compresses a chunk body with the given encoding
+func encodeChunk(encoding string, data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	var w io.WriteCloser
+
+	switch encoding {
+	case ChunkEncodingGzip:
+		w = gzip.NewWriter(&buf)
+	case ChunkEncodingDeflate:
+		fw, err := flate.NewWriter(&buf, flate.DefaultCompression)
+		if err != nil {
+			return nil, err
+		}
+		w = fw
+	default:
+		return nil, fmt.Errorf("unsupported chunk encoding: %s", encoding)
+	}
+
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeChunk decompresses a chunk body produced by encodeChunk
+func decodeChunk(encoding string, data []byte) ([]byte, error) {
+	var r io.ReadCloser
+
+	switch encoding {
+	case ChunkEncodingGzip:
+		gr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		r = gr
+	case ChunkEncodingDeflate:
+		r = flate.NewReader(bytes.NewReader(data))
+	default:
+		return nil, fmt.Errorf("unsupported chunk encoding: %s", encoding)
+	}
+	defer r.Close()
+
+	return io.ReadAll(r)
+}
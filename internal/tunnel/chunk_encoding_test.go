@@ -0,0 +1,124 @@
+package tunnel
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/osa911/giraffecloud/internal/logging"
+	"github.com/osa911/giraffecloud/internal/tunnel/proto"
+)
+
+func TestChunkEncoding_RoundTrip(t *testing.T) {
+	data := bytes.Repeat([]byte(`{"level":"info","msg":"request served"}`+"\n"), 1000)
+
+	for _, encoding := range supportedChunkEncodings {
+		encoded, err := encodeChunk(encoding, data)
+		if err != nil {
+			t.Fatalf("encodeChunk(%s) failed: %v", encoding, err)
+		}
+		if len(encoded) >= len(data) {
+			t.Errorf("%s: expected compressed chunk to be smaller, got %d >= %d", encoding, len(encoded), len(data))
+		}
+
+		decoded, err := decodeChunk(encoding, encoded)
+		if err != nil {
+			t.Fatalf("decodeChunk(%s) failed: %v", encoding, err)
+		}
+		if !bytes.Equal(decoded, data) {
+			t.Errorf("%s: round trip mismatch", encoding)
+		}
+	}
+
+	if _, err := encodeChunk("br", data); err == nil {
+		t.Error("Expected unsupported encoding to fail")
+	}
+}
+
+func TestSelectChunkEncoding(t *testing.T) {
+	tests := []struct {
+		values []string
+		want   string
+	}{
+		{nil, ""},
+		{[]string{"deflate", "gzip"}, ChunkEncodingGzip},
+		{[]string{"deflate"}, ChunkEncodingDeflate},
+		{[]string{"br", " DEFLATE"}, ChunkEncodingDeflate},
+		{[]string{"br"}, ""},
+	}
+	for _, tt := range tests {
+		if got := selectChunkEncoding(tt.values); got != tt.want {
+			t.Errorf("selectChunkEncoding(%v) = %q, want %q", tt.values, got, tt.want)
+		}
+	}
+}
+
+func TestIsCompressibleResponse(t *testing.T) {
+	tests := []struct {
+		headers map[string]string
+		want    bool
+	}{
+		{map[string]string{"Content-Type": "application/json"}, true},
+		{map[string]string{"Content-Type": "text/plain; charset=utf-8"}, true},
+		{map[string]string{"Content-Type": "text/html", "Content-Encoding": "gzip"}, false},
+		{map[string]string{"Content-Type": "video/mp4"}, false},
+		{map[string]string{}, false},
+	}
+	for _, tt := range tests {
+		if got := isCompressibleResponse(tt.headers); got != tt.want {
+			t.Errorf("isCompressibleResponse(%v) = %v, want %v", tt.headers, got, tt.want)
+		}
+	}
+}
+
+func TestAcceptedCapabilities(t *testing.T) {
+	tests := []struct {
+		offered *proto.TunnelCapabilities
+		want    []string
+	}{
+		{nil, nil},
+		{&proto.TunnelCapabilities{SupportedEncodings: []string{"gzip"}}, nil},
+		{&proto.TunnelCapabilities{SupportsCompression: true, SupportedEncodings: []string{"br"}}, nil},
+		{&proto.TunnelCapabilities{SupportsCompression: true, SupportedEncodings: []string{"deflate", "br", "gzip"}}, []string{ChunkEncodingGzip, ChunkEncodingDeflate}},
+	}
+	for _, tt := range tests {
+		got := acceptedCapabilities(tt.offered).GetSupportedEncodings()
+		if len(got) != len(tt.want) || (len(got) > 0 && got[0] != tt.want[0]) {
+			t.Errorf("acceptedCapabilities(%v) encodings = %v, want %v", tt.offered, got, tt.want)
+		}
+	}
+
+	// Chunk reordering is answered on its own, with or without compression
+	if caps := acceptedCapabilities(&proto.TunnelCapabilities{SupportsChunkReorder: true}); !caps.GetSupportsChunkReorder() || caps.GetSupportsCompression() {
+		t.Errorf("reorder-only offer answered with %v", caps)
+	}
+	if caps := acceptedCapabilities(&proto.TunnelCapabilities{SupportsCompression: true, SupportedEncodings: []string{"gzip"}}); caps.GetSupportsChunkReorder() {
+		t.Error("chunk reordering accepted for a client that didn't offer it")
+	}
+}
+
+func TestHandleHTTPResponse_DecodesChunkEncoding(t *testing.T) {
+	initTestLogger(t)
+	s := &GRPCTunnelServer{logger: logging.GetGlobalLogger()}
+	responseChan := make(chan *proto.TunnelMessage, 1)
+	tunnelStream := &TunnelStream{pendingRequests: map[string]chan *proto.TunnelMessage{"req-1": responseChan}}
+
+	body := []byte(`{"ok":true}`)
+	encoded, err := encodeChunk(ChunkEncodingGzip, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.handleHTTPResponse(tunnelStream, &proto.TunnelMessage{
+		RequestId: "req-1",
+		MessageType: &proto.TunnelMessage_HttpResponse{HttpResponse: &proto.HTTPResponse{
+			StatusCode:    200,
+			Headers:       map[string]string{"Content-Type": "application/json"},
+			Body:          encoded,
+			ChunkEncoding: ChunkEncodingGzip,
+		}},
+	})
+
+	resp := (<-responseChan).GetHttpResponse()
+	if !bytes.Equal(resp.GetBody(), body) || resp.GetChunkEncoding() != "" {
+		t.Errorf("Expected decoded body %q, got %q (encoding %q)", body, resp.GetBody(), resp.GetChunkEncoding())
+	}
+}
@@ -0,0 +1,122 @@
+package tunnel
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/osa911/giraffecloud/internal/tunnel/proto"
+)
+
+// MaxChunkWindowSize caps the chunks a client keeps in flight per response, and the
+// out-of-order chunks the server buffers for one
+const MaxChunkWindowSize = 32
+
+// chunkNumber returns the number in a response chunk ID ("chunk-7" or "chunk-7_final")
+func chunkNumber(chunkID string) (int, bool) {
+	rest, ok := strings.CutPrefix(chunkID, "chunk-")
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.Atoi(strings.TrimSuffix(rest, "_final"))
+	if err != nil || n < 1 {
+		return 0, false
+	}
+	return n, true
+}
+
+// chunkReorderer hands out the chunks of one response in chunk number order, holding back
+// those that arrive ahead of a gap
+type chunkReorderer struct {
+	next    int
+	pending map[int]*proto.HTTPResponse
+}
+
+func newChunkReorderer() *chunkReorderer {
+	return &chunkReorderer{next: 1, pending: make(map[int]*proto.HTTPResponse)}
+}
+
+// push adds a chunk and returns the chunks that are now due, in order
+func (r *chunkReorderer) push(chunk *proto.HTTPResponse) ([]*proto.HTTPResponse, error) {
+	num, ok := chunkNumber(chunk.ChunkId)
+	if !ok {
+		return []*proto.HTTPResponse{chunk}, nil
+	}
+
+	// A re-issued response is sent in order and numbered on from the chunks read before
+	// the stream broke. The gap before its first chunk is chunks lost with the old stream;
+	// their bytes come again, and the stream offset skips what was already forwarded.
+	if _, resumed := chunk.Headers[StreamOffsetHeader]; resumed && num > r.next {
+		for n := range r.pending {
+			if n < num {
+				delete(r.pending, n)
+			}
+		}
+		r.next = num
+	}
+	if num < r.next {
+		return nil, nil // Already handed out
+	}
+
+	r.pending[num] = chunk
+	if len(r.pending) > MaxChunkWindowSize {
+		return nil, fmt.Errorf("more than %d chunks arrived ahead of chunk %d", MaxChunkWindowSize, r.next)
+	}
+
+	var ready []*proto.HTTPResponse
+	for {
+		due, ok := r.pending[r.next]
+		if !ok {
+			return ready, nil
+		}
+		delete(r.pending, r.next)
+		ready = append(ready, due)
+		r.next++
+	}
+}
+
+// chunkPipeline runs the sends of one response with up to a window of them in flight
+type chunkPipeline struct {
+	slots chan struct{}
+	wg    sync.WaitGroup
+
+	mu  sync.Mutex
+	err error
+}
+
+func newChunkPipeline(window int) *chunkPipeline {
+	return &chunkPipeline{slots: make(chan struct{}, window)}
+}
+
+// Go runs send once a slot is free; the first error is kept for Err and Wait
+func (p *chunkPipeline) Go(send func() error) {
+	p.slots <- struct{}{}
+	p.wg.Add(1)
+	go func() {
+		defer func() {
+			<-p.slots
+			p.wg.Done()
+		}()
+		if err := send(); err != nil {
+			p.mu.Lock()
+			if p.err == nil {
+				p.err = err
+			}
+			p.mu.Unlock()
+		}
+	}()
+}
+
+// Err returns the first error of a finished send, if any
+func (p *chunkPipeline) Err() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
+
+// Wait waits for all sends and returns the first error
+func (p *chunkPipeline) Wait() error {
+	p.wg.Wait()
+	return p.Err()
+}
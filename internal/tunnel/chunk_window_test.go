@@ -0,0 +1,135 @@
+package tunnel
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"io"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/osa911/giraffecloud/internal/tunnel/proto"
+)
+
+func TestChunkReorderer(t *testing.T) {
+	chunk := func(id string, headers map[string]string) *proto.HTTPResponse {
+		return &proto.HTTPResponse{ChunkId: id, Headers: headers, Body: []byte(id), IsChunked: true}
+	}
+	ids := func(chunks []*proto.HTTPResponse) []string {
+		var out []string
+		for _, c := range chunks {
+			out = append(out, c.ChunkId)
+		}
+		return out
+	}
+
+	r := newChunkReorderer()
+	steps := []struct {
+		push *proto.HTTPResponse
+		want []string
+	}{
+		{chunk("chunk-2", nil), nil},
+		{chunk("chunk-3", nil), nil},
+		{chunk("chunk-1", nil), []string{"chunk-1", "chunk-2", "chunk-3"}},
+		{chunk("chunk-5_final", nil), nil},
+		{chunk("chunk-4", nil), []string{"chunk-4", "chunk-5_final"}},
+	}
+	for i, step := range steps {
+		ready, err := r.push(step.push)
+		if err != nil {
+			t.Fatalf("step %d: %v", i, err)
+		}
+		if got := ids(ready); len(got) != len(step.want) || (len(got) > 0 && got[len(got)-1] != step.want[len(step.want)-1]) {
+			t.Errorf("step %d: pushing %s released %v, want %v", i, step.push.ChunkId, got, step.want)
+		}
+	}
+
+	// A re-issued response skips the chunks lost with the broken stream
+	r = newChunkReorderer()
+	r.push(chunk("chunk-1", nil))
+	r.push(chunk("chunk-3", nil))
+	ready, _ := r.push(chunk("chunk-5", map[string]string{StreamOffsetHeader: "0"}))
+	if got := ids(ready); len(got) != 1 || got[0] != "chunk-5" {
+		t.Errorf("first resumed chunk released %v, want [chunk-5]", got)
+	}
+
+	// IDs without a number pass straight through
+	if ready, _ := newChunkReorderer().push(chunk("", nil)); len(ready) != 1 {
+		t.Errorf("unnumbered chunk released %d chunks, want 1", len(ready))
+	}
+
+	// A client can't make the server buffer without bound
+	r = newChunkReorderer()
+	var err error
+	for n := 2; err == nil && n < MaxChunkWindowSize+10; n++ {
+		_, err = r.push(chunk("chunk-"+strconv.Itoa(n), nil))
+	}
+	if err == nil {
+		t.Error("expected an error once too many chunks wait for a gap")
+	}
+}
+
+func TestChunkWindowDownload(t *testing.T) {
+	if testing.Short() {
+		t.Skip("end-to-end tunnel test")
+	}
+	initTestLogger(t)
+	ts, err := NewTestServer()
+	if err != nil {
+		t.Fatalf("NewTestServer: %v", err)
+	}
+	defer ts.Close()
+	t.Setenv("GIRAFFECLOUD_HOME", ts.ConfigHome)
+
+	// Distinct bytes everywhere, so a chunk out of place changes the digest
+	payload := make([]byte, 24*1024*1024)
+	for i := range payload {
+		payload[i] = byte(i * 7 / 3)
+	}
+	ts.SetHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Length", strconv.Itoa(len(payload)))
+		w.Write(payload)
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	tun, err := ts.Connect(ctx)
+	if err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	defer tun.Disconnect()
+
+	config := DefaultStreamingConfig()
+	config.ChunkWindowSize = 4
+	tun.UpdateStreamingConfig(config)
+	if window := tun.grpcClient.chunkWindow(false, &chunkStreamProgress{}); window != 4 {
+		t.Fatalf("chunk window = %d, want 4 (server advertises reordering)", window)
+	}
+
+	for i := 0; i < 3; i++ {
+		resp, err := http.Get(ts.URL + "/download.bin")
+		if err != nil {
+			t.Fatalf("GET: %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || len(body) != len(payload) {
+			t.Fatalf("GET = %d with %d bytes, want 200 with %d", resp.StatusCode, len(body), len(payload))
+		}
+		if sha256.Sum256(body) != sha256.Sum256(payload) {
+			t.Fatalf("download %d differs from the payload (first difference at %d)", i, firstDifference(body, payload))
+		}
+	}
+}
+
+func firstDifference(a, b []byte) int {
+	for i := range a {
+		if i >= len(b) || a[i] != b[i] {
+			return i
+		}
+	}
+	return bytes.Compare(a, b)
+}
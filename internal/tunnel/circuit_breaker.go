@@ -0,0 +1,149 @@
+package tunnel
+
+import (
+	"sync"
+	"time"
+)
+
+// CircuitState is the state of a per-domain circuit breaker
+type CircuitState int
+
+const (
+	// CircuitClosed lets all requests through
+	CircuitClosed CircuitState = iota
+	// CircuitOpen rejects requests until the cooldown expires
+	CircuitOpen
+	// CircuitHalfOpen lets a single probe request through to test recovery
+	CircuitHalfOpen
+)
+
+// String returns the state name
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// domainCircuit tracks breaker state for a single domain
+type domainCircuit struct {
+	state        CircuitState
+	failures     int
+	openedAt     time.Time
+	probeStarted time.Time // Zero when no half-open probe is in flight
+}
+
+// circuitBreakers is a set of closed/open/half-open breakers keyed by domain.
+// After threshold consecutive failures a domain's breaker opens and requests are shed
+// for cooldown; then one probe is let through and its outcome closes or re-opens it.
+type circuitBreakers struct {
+	mu        sync.Mutex
+	circuits  map[string]*domainCircuit
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+}
+
+// newCircuitBreakers creates a breaker set; a threshold <= 0 disables shedding
+func newCircuitBreakers(threshold int, cooldown time.Duration) *circuitBreakers {
+	return &circuitBreakers{
+		circuits:  make(map[string]*domainCircuit),
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       time.Now,
+	}
+}
+
+// Configure updates the thresholds; existing breaker states are kept
+func (b *circuitBreakers) Configure(threshold int, cooldown time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.threshold = threshold
+	b.cooldown = cooldown
+}
+
+// Allow reports whether a request for domain may proceed
+func (b *circuitBreakers) Allow(domain string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c, ok := b.circuits[domain]
+	if !ok || b.threshold <= 0 {
+		return true
+	}
+
+	now := b.now()
+	switch c.state {
+	case CircuitOpen:
+		if now.Sub(c.openedAt) < b.cooldown {
+			return false
+		}
+		c.state = CircuitHalfOpen
+		c.probeStarted = now
+		return true
+	case CircuitHalfOpen:
+		// A probe that never reported back (e.g. client went away) must not wedge the breaker
+		if !c.probeStarted.IsZero() && now.Sub(c.probeStarted) < b.cooldown {
+			return false
+		}
+		c.probeStarted = now
+		return true
+	default:
+		return true
+	}
+}
+
+// RecordSuccess closes the domain's breaker and resets its failure count
+func (b *circuitBreakers) RecordSuccess(domain string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// Closed domains with no failures aren't tracked
+	delete(b.circuits, domain)
+}
+
+// RecordFailure counts a failure and returns the resulting state.
+// A failed half-open probe re-opens the breaker immediately.
+func (b *circuitBreakers) RecordFailure(domain string) CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c, ok := b.circuits[domain]
+	if !ok {
+		c = &domainCircuit{}
+		b.circuits[domain] = c
+	}
+	c.failures++
+
+	if b.threshold > 0 && (c.state == CircuitHalfOpen || c.failures >= b.threshold) {
+		c.state = CircuitOpen
+		c.openedAt = b.now()
+		c.probeStarted = time.Time{}
+	}
+	return c.state
+}
+
+// State returns the current state for domain
+func (b *circuitBreakers) State(domain string) CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if c, ok := b.circuits[domain]; ok {
+		return c.state
+	}
+	return CircuitClosed
+}
+
+// States returns the state of every domain that has recorded failures
+func (b *circuitBreakers) States() map[string]CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	states := make(map[string]CircuitState, len(b.circuits))
+	for domain, c := range b.circuits {
+		states[domain] = c.state
+	}
+	return states
+}
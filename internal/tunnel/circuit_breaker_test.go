@@ -0,0 +1,63 @@
+package tunnel
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakers_StateMachine(t *testing.T) {
+	now := time.Now()
+	b := newCircuitBreakers(3, 30*time.Second)
+	b.now = func() time.Time { return now }
+
+	// Failures below the threshold keep the breaker closed
+	for i := 0; i < 2; i++ {
+		if state := b.RecordFailure("a.example.com"); state != CircuitClosed {
+			t.Fatalf("Expected closed after %d failures, got %s", i+1, state)
+		}
+	}
+	if !b.Allow("a.example.com") {
+		t.Fatal("Expected closed breaker to allow requests")
+	}
+
+	// Threshold reached: open and shed
+	if state := b.RecordFailure("a.example.com"); state != CircuitOpen {
+		t.Fatalf("Expected open after threshold, got %s", state)
+	}
+	if b.Allow("a.example.com") {
+		t.Error("Expected open breaker to reject requests")
+	}
+	if !b.Allow("b.example.com") {
+		t.Error("Expected other domains to be unaffected")
+	}
+
+	// Cooldown over: exactly one probe goes through
+	now = now.Add(31 * time.Second)
+	if !b.Allow("a.example.com") {
+		t.Fatal("Expected a probe after cooldown")
+	}
+	if state := b.State("a.example.com"); state != CircuitHalfOpen {
+		t.Fatalf("Expected half-open, got %s", state)
+	}
+	if b.Allow("a.example.com") {
+		t.Error("Expected only a single probe in half-open")
+	}
+
+	// Failed probe re-opens immediately
+	if state := b.RecordFailure("a.example.com"); state != CircuitOpen {
+		t.Fatalf("Expected failed probe to re-open, got %s", state)
+	}
+
+	// Successful probe closes the breaker
+	now = now.Add(31 * time.Second)
+	if !b.Allow("a.example.com") {
+		t.Fatal("Expected a probe after second cooldown")
+	}
+	b.RecordSuccess("a.example.com")
+	if state := b.State("a.example.com"); state != CircuitClosed {
+		t.Errorf("Expected closed after successful probe, got %s", state)
+	}
+	if len(b.States()) != 0 {
+		t.Errorf("Expected no tracked domains after recovery, got %v", b.States())
+	}
+}
@@ -0,0 +1,43 @@
+package tunnel
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+)
+
+// watchClientDisconnect calls cancel if the visitor closes conn before the response
+// starts, so a request nobody will read stops waiting on the tunnel and the client
+// stops working on it. Only requests without a body are watched: while a body streams,
+// reading conn belongs to the upload. Call the returned function before writing the
+// response; it stops watching without cancelling.
+func (r *HybridTunnelRouter) watchClientDisconnect(conn net.Conn, req *http.Request, cancel context.CancelFunc) func() {
+	if req.ContentLength != 0 || len(req.TransferEncoding) > 0 {
+		return func() {}
+	}
+
+	stopped := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		// The router answers one request per connection, so a well-behaved visitor sends
+		// nothing more; the read only returns when it hangs up (or stop interrupts it)
+		var b [1]byte
+		if _, err := conn.Read(b[:]); err != nil {
+			select {
+			case <-stopped:
+			default:
+				r.logger.Debug("[HYBRID] Client went away before the response (%s %s): %v", req.Method, req.URL.Path, err)
+				cancel()
+			}
+		}
+	}()
+
+	return func() {
+		close(stopped)
+		conn.SetReadDeadline(time.Now()) // Interrupt the pending read
+		<-exited
+		conn.SetReadDeadline(time.Time{})
+	}
+}
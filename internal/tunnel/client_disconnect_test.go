@@ -0,0 +1,110 @@
+package tunnel
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/osa911/giraffecloud/internal/logging"
+	"github.com/osa911/giraffecloud/internal/tunnel/proto"
+
+	"google.golang.org/grpc"
+)
+
+// fakeServerStream is a minimal EstablishTunnel server stream that hands sent messages to sent
+type fakeServerStream struct {
+	grpc.ServerStream
+	sent chan *proto.TunnelMessage
+}
+
+func (f *fakeServerStream) Send(msg *proto.TunnelMessage) error {
+	f.sent <- msg
+	return nil
+}
+
+func (f *fakeServerStream) Recv() (*proto.TunnelMessage, error) { return nil, io.EOF }
+
+func TestSendRequestAndWaitResponse_ClientCancel(t *testing.T) {
+	initTestLogger(t)
+	s := &GRPCTunnelServer{logger: logging.GetGlobalLogger(), config: DefaultGRPCTunnelConfig()}
+	stream := &fakeServerStream{sent: make(chan *proto.TunnelMessage, 2)}
+	tunnelStream := &TunnelStream{
+		Stream:          stream,
+		Context:         context.Background(),
+		pendingRequests: make(map[string]chan *proto.TunnelMessage),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := s.sendRequestAndWaitResponse(ctx, tunnelStream, &proto.TunnelMessage{RequestId: "req-1"})
+		errCh <- err
+	}()
+	<-stream.sent
+	cancel()
+
+	select {
+	case err := <-errCh:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("err = %v, want context.Canceled", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("request kept waiting after the client went away")
+	}
+	if n := len(tunnelStream.pendingRequests); n != 0 {
+		t.Errorf("%d pending requests left behind", n)
+	}
+	select {
+	case msg := <-stream.sent:
+		if msg.GetControl().GetCancelRequest().GetRequestId() != "req-1" {
+			t.Errorf("expected a cancel for req-1, got %v", msg)
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("the tunnel client was not told to cancel")
+	}
+}
+
+func TestWatchClientDisconnect(t *testing.T) {
+	initTestLogger(t)
+	r := &HybridTunnelRouter{config: &HybridRouterConfig{}, logger: logging.GetGlobalLogger()}
+	head := "GET /slow HTTP/1.1\r\nHost: app.example.com\r\n\r\n"
+
+	// Hanging up cancels the request
+	routerConn, clientConn := net.Pipe()
+	defer routerConn.Close()
+	req, err := r.parseHTTPRequest([]byte(head), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stop := r.watchClientDisconnect(routerConn, req, cancel)
+	clientConn.Close()
+	select {
+	case <-ctx.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("disconnect did not cancel the request")
+	}
+	stop()
+
+	// Stopping leaves the request alone and the connection usable for the response
+	routerConn, clientConn = net.Pipe()
+	defer routerConn.Close()
+	defer clientConn.Close()
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	r.watchClientDisconnect(routerConn, req, cancel)()
+	if ctx.Err() != nil {
+		t.Error("stopping the watcher cancelled the request")
+	}
+	go routerConn.Write([]byte("HTTP/1.1 200 OK\r\n\r\n"))
+	buf := make([]byte, 64)
+	n, _ := clientConn.Read(buf)
+	if !strings.HasPrefix(string(buf[:n]), "HTTP/1.1 200") {
+		t.Errorf("response not delivered after stop: %q", buf[:n])
+	}
+}
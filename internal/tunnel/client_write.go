@@ -0,0 +1,121 @@
+package tunnel
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultClientWriteTimeout is how long a write of a response to the visitor may make no
+// progress before the response is abandoned
+const DefaultClientWriteTimeout = time.Minute
+
+// maxAbandonedResponseDrain bounds how much of an abandoned response is read off a pooled
+// tunnel connection to keep it in sync; longer responses retire the connection instead
+const maxAbandonedResponseDrain = 256 << 10
+
+// deadlineWriter writes to the visitor's connection, giving each write timeout to complete
+// (0 = no deadline), so a visitor that stops reading can't hold the response, and the tunnel
+// connection or stream it comes from, forever. A slow download keeps going as long as every
+// write makes progress.
+type deadlineWriter struct {
+	conn    net.Conn
+	timeout time.Duration
+}
+
+func (w deadlineWriter) Write(p []byte) (int, error) {
+	if w.timeout > 0 {
+		w.conn.SetWriteDeadline(time.Now().Add(w.timeout))
+	}
+	return w.conn.Write(p)
+}
+
+// isClientWriteTimeout reports whether err is a write to the visitor that hit its deadline
+func isClientWriteTimeout(err error) bool {
+	return errors.Is(err, os.ErrDeadlineExceeded)
+}
+
+// clientWriteFailed cleans up after a response the visitor stopped receiving over the gRPC
+// tunnel: closing the body tells the client to stop sending the rest. The visitor going away
+// or stalling past ClientWriteTimeout is their doing, not the origin's, so it isn't counted
+// as a routing error.
+func (r *HybridTunnelRouter) clientWriteFailed(tag string, response *http.Response, err error) {
+	response.Body.Close()
+	switch {
+	case isClientWriteTimeout(err):
+		atomic.AddInt64(&r.clientWriteTimeouts, 1)
+		r.logger.Info("%s Client stopped reading for %v, abandoning the response", tag, r.config.ClientWriteTimeout)
+	case r.isClientDisconnectionError(err):
+		r.logger.Debug("%s Client disconnected during response write: %v", tag, err)
+	default:
+		r.logger.Error("%s Error writing response: %v", tag, err)
+	}
+}
+
+// drainedBody lets a response the visitor stopped receiving finish reading from its tunnel
+// connection when little is left, keeping the connection in sync for the next request
+type drainedBody struct {
+	io.ReadCloser
+	eof bool
+}
+
+func (b *drainedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err == io.EOF {
+		b.eof = true
+	}
+	return n, err
+}
+
+// Close reads what's left of the body up to maxAbandonedResponseDrain; a longer remainder is
+// left unread, and the connection it comes from has to be retired
+func (b *drainedBody) Close() error {
+	if !b.eof {
+		io.CopyN(io.Discard, b, maxAbandonedResponseDrain)
+		if !b.eof {
+			return nil
+		}
+	}
+	return b.ReadCloser.Close()
+}
+
+// writeResponse writes a response read from a tunnel connection to the visitor on conn, giving
+// every write ClientWriteTimeout to make progress. synced reports whether the whole response was
+// read off the tunnel connection, even when the visitor stopped receiving it.
+func (s *TunnelServer) writeResponse(conn net.Conn, response *http.Response) (synced bool, err error) {
+	body := &drainedBody{ReadCloser: response.Body, eof: response.Body == nil || response.Body == http.NoBody}
+	if !body.eof {
+		response.Body = body
+	}
+	writer := bufio.NewWriter(deadlineWriter{conn, s.streamConfig.ClientWriteTimeout})
+	if err = response.Write(writer); err == nil {
+		err = writer.Flush()
+	}
+	return body.eof, err
+}
+
+// abandonResponse cleans up after the visitor stopped receiving a response read from
+// tunnelConn, by going away or stalling past ClientWriteTimeout. That's the visitor's doing,
+// not the origin's: the circuit breaker isn't told, and a pooled connection the response was
+// fully read from stays pooled.
+func (s *TunnelServer) abandonResponse(domain string, tunnelConn *TunnelConnection, isOnDemand, synced bool, err error) {
+	if isClientWriteTimeout(err) {
+		atomic.AddInt64(&s.clientWriteTimeouts, 1)
+		s.logger.Info("[HYBRID] Client stopped reading for %v, abandoning the response for %s", s.streamConfig.ClientWriteTimeout, domain)
+	} else {
+		s.logger.Debug("[HYBRID] Client went away during the response for %s: %v", domain, err)
+	}
+
+	if isOnDemand {
+		go tunnelConn.Close()
+	} else if !synced {
+		s.logger.Debug("[HYBRID] Abandoned response too long to drain, retiring the connection")
+		s.connections.RemoveSpecificHTTPConnection(domain, tunnelConn)
+		go tunnelConn.Close()
+	}
+}
@@ -0,0 +1,55 @@
+package tunnel
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/osa911/giraffecloud/internal/logging"
+)
+
+func TestAbandonResponseToStalledClient(t *testing.T) {
+	initTestLogger(t)
+	s := &TunnelServer{
+		logger:       logging.GetGlobalLogger(),
+		connections:  NewConnectionManager(),
+		streamConfig: &StreamingConfig{ClientWriteTimeout: 50 * time.Millisecond},
+	}
+	tunnelSide, _ := net.Pipe()
+	tunnelConn := s.connections.AddConnection("app.example.com", tunnelSide, 0, ConnectionTypeHTTP, 1, 1)
+
+	// The visitor never reads: the write gives up instead of blocking forever
+	abandon := func(body string) {
+		t.Helper()
+		visitor, _ := net.Pipe()
+		response, err := http.ReadResponse(bufio.NewReader(strings.NewReader(
+			"HTTP/1.1 200 OK\r\nContent-Length: "+strconv.Itoa(len(body))+"\r\n\r\n"+body)), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		synced, err := s.writeResponse(visitor, response)
+		if !isClientWriteTimeout(err) {
+			t.Fatalf("write to stalled visitor: %v", err)
+		}
+		s.abandonResponse("app.example.com", tunnelConn, false, synced, err)
+	}
+
+	// A short remainder is drained and the pooled connection kept
+	abandon("hello world")
+	if n := s.connections.GetHTTPPoolSize("app.example.com"); n != 1 {
+		t.Errorf("pool size after a drained response = %d, want 1", n)
+	}
+	if n := s.GetMetrics()["client_write_timeouts"]; n != 1 {
+		t.Errorf("client_write_timeouts = %d, want 1", n)
+	}
+
+	// A long one retires the connection rather than reading it all
+	abandon(strings.Repeat("x", 2*maxAbandonedResponseDrain))
+	if n := s.connections.GetHTTPPoolSize("app.example.com"); n != 0 {
+		t.Errorf("pool size after an undrainable response = %d, want 0", n)
+	}
+}
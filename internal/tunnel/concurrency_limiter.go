@@ -0,0 +1,162 @@
+package tunnel
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultQueueDepth and DefaultQueueTimeout apply when a per-domain concurrency cap is set
+const (
+	DefaultQueueDepth   = 100
+	DefaultQueueTimeout = 30 * time.Second
+)
+
+var (
+	// errQueueFull is returned when a domain's wait queue has no room left
+	errQueueFull = errors.New("too many queued requests")
+	// errQueueTimeout is returned when a queued request didn't get a slot in time
+	errQueueTimeout = errors.New("timed out waiting for a free request slot")
+)
+
+// DomainConcurrency is the current load of one domain under the concurrency cap
+type DomainConcurrency struct {
+	InFlight int `json:"in_flight"`
+	Queued   int `json:"queued"`
+}
+
+// domainSlots is the semaphore and wait queue of one domain
+type domainSlots struct {
+	sem     chan struct{}
+	waiting int // Requests queued for a slot
+	users   int // In-flight plus queued; the entry is dropped at zero
+}
+
+// concurrencyLimiter caps in-flight requests per domain. Requests over the cap wait in a
+// bounded queue for up to queueTimeout; requests beyond the queue are rejected at once.
+type concurrencyLimiter struct {
+	maxConcurrent int
+	queueDepth    int
+	queueTimeout  time.Duration
+
+	mu      sync.Mutex
+	domains map[string]*domainSlots
+
+	rejected int64 // Requests turned away because the queue was full or timed out
+}
+
+// newConcurrencyLimiter creates a limiter; maxConcurrent <= 0 disables it
+func newConcurrencyLimiter(maxConcurrent, queueDepth int, queueTimeout time.Duration) *concurrencyLimiter {
+	if queueDepth < 0 {
+		queueDepth = 0
+	}
+	if queueTimeout <= 0 {
+		queueTimeout = DefaultQueueTimeout
+	}
+	return &concurrencyLimiter{
+		maxConcurrent: maxConcurrent,
+		queueDepth:    queueDepth,
+		queueTimeout:  queueTimeout,
+		domains:       make(map[string]*domainSlots),
+	}
+}
+
+// Acquire takes a request slot for domain, queueing if the domain is at its cap.
+// The returned release func must be called once the request is done.
+func (l *concurrencyLimiter) Acquire(domain string) (func(), error) {
+	if l == nil || l.maxConcurrent <= 0 {
+		return func() {}, nil
+	}
+
+	l.mu.Lock()
+	slots, ok := l.domains[domain]
+	if !ok {
+		slots = &domainSlots{sem: make(chan struct{}, l.maxConcurrent)}
+		l.domains[domain] = slots
+	}
+	select {
+	case slots.sem <- struct{}{}:
+		slots.users++
+		l.mu.Unlock()
+		return l.releaser(domain, slots), nil
+	default:
+	}
+	if slots.waiting >= l.queueDepth {
+		l.mu.Unlock()
+		atomic.AddInt64(&l.rejected, 1)
+		return nil, errQueueFull
+	}
+	slots.waiting++
+	slots.users++
+	l.mu.Unlock()
+
+	timer := time.NewTimer(l.queueTimeout)
+	defer timer.Stop()
+	select {
+	case slots.sem <- struct{}{}:
+		l.mu.Lock()
+		slots.waiting--
+		l.mu.Unlock()
+		return l.releaser(domain, slots), nil
+	case <-timer.C:
+		l.mu.Lock()
+		slots.waiting--
+		l.dropUser(domain, slots)
+		l.mu.Unlock()
+		atomic.AddInt64(&l.rejected, 1)
+		return nil, errQueueTimeout
+	}
+}
+
+// releaser returns the func freeing a slot taken by Acquire (safe to call more than once)
+func (l *concurrencyLimiter) releaser(domain string, slots *domainSlots) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			<-slots.sem
+			l.mu.Lock()
+			l.dropUser(domain, slots)
+			l.mu.Unlock()
+		})
+	}
+}
+
+// dropUser forgets an idle domain; l.mu must be held
+func (l *concurrencyLimiter) dropUser(domain string, slots *domainSlots) {
+	slots.users--
+	if slots.users == 0 && l.domains[domain] == slots {
+		delete(l.domains, domain)
+	}
+}
+
+// Stats returns the in-flight and queued requests of every domain with any
+func (l *concurrencyLimiter) Stats() map[string]DomainConcurrency {
+	stats := make(map[string]DomainConcurrency)
+	if l == nil {
+		return stats
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for domain, slots := range l.domains {
+		stats[domain] = DomainConcurrency{InFlight: len(slots.sem), Queued: slots.waiting}
+	}
+	return stats
+}
+
+// QueuedTotal returns the number of requests waiting for a slot across all domains
+func (l *concurrencyLimiter) QueuedTotal() int {
+	total := 0
+	for _, stats := range l.Stats() {
+		total += stats.Queued
+	}
+	return total
+}
+
+// Rejected returns how many requests were turned away with a 503
+func (l *concurrencyLimiter) Rejected() int64 {
+	if l == nil {
+		return 0
+	}
+	return atomic.LoadInt64(&l.rejected)
+}
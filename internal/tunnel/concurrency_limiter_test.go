@@ -0,0 +1,78 @@
+package tunnel
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestConcurrencyLimiterQueue(t *testing.T) {
+	l := newConcurrencyLimiter(1, 1, 50*time.Millisecond)
+
+	release, err := l.Acquire("example.com")
+	if err != nil {
+		t.Fatalf("first request: %v", err)
+	}
+
+	// Second request queues and gets the slot once the first is released
+	acquired := make(chan error, 1)
+	go func() {
+		r, err := l.Acquire("example.com")
+		if err == nil {
+			defer r()
+		}
+		acquired <- err
+	}()
+	waitFor(t, func() bool { return l.Stats()["example.com"].Queued == 1 })
+
+	// Third request finds the queue full
+	if _, err := l.Acquire("example.com"); !errors.Is(err, errQueueFull) {
+		t.Errorf("third request error = %v, want errQueueFull", err)
+	}
+	// Other domains are unaffected
+	if r, err := l.Acquire("other.com"); err != nil {
+		t.Errorf("other domain: %v", err)
+	} else {
+		r()
+	}
+
+	release()
+	if err := <-acquired; err != nil {
+		t.Errorf("queued request: %v", err)
+	}
+
+	// A queued request times out while the slot stays taken
+	release, _ = l.Acquire("example.com")
+	if _, err := l.Acquire("example.com"); !errors.Is(err, errQueueTimeout) {
+		t.Errorf("queued request error = %v, want errQueueTimeout", err)
+	}
+	release()
+
+	if got := l.Rejected(); got != 2 {
+		t.Errorf("Rejected() = %d, want 2", got)
+	}
+	if stats := l.Stats(); len(stats) != 0 {
+		t.Errorf("idle domains not dropped: %v", stats)
+	}
+}
+
+func TestConcurrencyLimiterDisabled(t *testing.T) {
+	l := newConcurrencyLimiter(0, 0, 0)
+	for i := 0; i < 10; i++ {
+		if _, err := l.Acquire("example.com"); err != nil {
+			t.Fatalf("disabled limiter rejected a request: %v", err)
+		}
+	}
+}
+
+// waitFor polls cond for up to a second
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met within 1s")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -3,10 +3,13 @@ package tunnel
 import (
 	"encoding/json"
 	"fmt"
+	"net"
 	"os"
 	"os/user"
 	"path/filepath"
 	"runtime"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -18,14 +21,67 @@ type Server struct {
 
 // Config represents the tunnel client configuration
 type Config struct {
+	// Schema version of the file (see CurrentConfigVersion); LoadConfig upgrades older files
+	Version int `json:"version" config:"-"`
+
 	Token      string           `json:"token"`
 	Domain     string           `json:"domain"`
 	LocalPort  int              `json:"local_port"`
+	LocalHost  string           `json:"local_host,omitempty"` // Host of the local service (default: localhost)
 	Server     ServerConfig     `json:"server"`
 	API        ServerConfig     `json:"api"`
 	Security   SecurityConfig   `json:"security"`
 	AutoUpdate AutoUpdateConfig `json:"auto_update"`
 	TestMode   TestModeConfig   `json:"test_mode"`
+	Streaming  *StreamingConfig `json:"streaming,omitempty"` // Hot-reloadable via 'giraffecloud reload'
+	Retry      *RetryConfig     `json:"retry,omitempty"`     // Hot-reloadable via 'giraffecloud reload'
+
+	// Resolving the tunnel server (split-horizon DNS): static host → IP overrides, or a
+	// DNS server (host[:port]) to use instead of the system resolver
+	DNSOverride map[string]string `json:"dns_override,omitempty"`
+	DNSServer   string            `json:"dns_server,omitempty"`
+
+	// Proxy to reach the tunnel server through: http://, https://, socks5:// or socks5h://
+	// (user:password@ allowed). Empty uses HTTPS_PROXY/ALL_PROXY/HTTP_PROXY and NO_PROXY;
+	// "direct" ignores them.
+	Proxy string `json:"proxy,omitempty"`
+
+	// Protocol of the local service for requests over the gRPC tunnel: http (default) or
+	// https, and HTTP/2 only (h2c for http), e.g. for gRPC services
+	LocalScheme   string `json:"local_scheme,omitempty"`
+	LocalUseHTTP2 bool   `json:"local_use_http2,omitempty"`
+
+	// Secondary local port, e.g. a standby instance, that requests go to when nothing
+	// accepts connections on LocalPort (0 = none)
+	FallbackLocalPort int `json:"fallback_local_port,omitempty"`
+
+	// Request paths are rewritten before reaching the local service: PublicPathStrip is
+	// removed from paths under it, then LocalPathPrefix is prepended. With LocalPathPrefix
+	// "/app", https://domain/foo?x=1 is served by localhost:PORT/app/foo?x=1.
+	LocalPathPrefix string `json:"local_path_prefix,omitempty"`
+	PublicPathStrip string `json:"public_path_strip,omitempty"`
+
+	// Only serve links signed with SignedURLSecret ('giraffecloud sign'); anything else
+	// is rejected by the server with 403 before it reaches the local service
+	RequireSignedURL bool   `json:"require_signed_url,omitempty"`
+	SignedURLSecret  string `json:"signed_url_secret,omitempty"`
+
+	// Forward datagrams from the tunnel's public UDP port to this local UDP port (0 = off);
+	// UDPPublicPort asks the server for a specific public port (0 = any free one)
+	UDPPort       int `json:"udp_port,omitempty"`
+	UDPPublicPort int `json:"udp_public_port,omitempty"`
+
+	// Local UDP sockets opened for visitor sessions (0 = DefaultUDPMaxSessions); past it a
+	// new visitor replaces an idle session or is dropped
+	UDPMaxSessions int `json:"udp_max_sessions,omitempty"`
+
+	// POSTed a JSON event ({domain, oldState, newState, timestamp, error}) whenever the
+	// connection state changes, e.g. for alerting when the tunnel goes down (empty = off)
+	StateChangeWebhook string `json:"state_change_webhook,omitempty"`
+
+	// Treat every request alike, skipping the extension/path/Range media detection
+	// regardless of Streaming.EnableMediaOptimization, for apps it misclassifies
+	DisableMediaOptimization bool `json:"disable_media_optimization,omitempty"`
 }
 
 // TestModeConfig represents test mode settings
@@ -47,6 +103,7 @@ type AutoUpdateConfig struct {
 	BackupCount        int           `json:"backup_count"`            // Number of backups to keep
 	UpdateWindow       *TimeWindow   `json:"update_window,omitempty"` // Time window for automatic updates
 	Channel            string        `json:"channel"`                 // Release channel override
+	VerifyTimeout      time.Duration `json:"verify_timeout"`          // How long the tunnel has to reconnect after an update before it is rolled back
 }
 
 // TimeWindow represents a time window for updates
@@ -60,14 +117,78 @@ type TimeWindow struct {
 type ServerConfig struct {
 	Host string `json:"host"`
 	Port int    `json:"port"`
+
+	// Tunnel server only: ports for the TCP (WebSocket) and gRPC listeners. TCPPort
+	// falls back to Port, and both fall back to the server defaults.
+	TCPPort  int `json:"tcp_port,omitempty"`
+	GRPCPort int `json:"grpc_port,omitempty"`
+
+	// Client only: more tunnel servers to fail over to when Host is unreachable, sharing
+	// its ports. The one that last accepted the tunnel is tried first.
+	Hosts             []string          `json:"hosts,omitempty"`
+	EndpointSelection EndpointSelection `json:"endpoint_selection,omitempty"` // order (default) or latency
+}
+
+// Default tunnel server ports
+const (
+	DefaultTCPTunnelPort  = 4443
+	DefaultGRPCTunnelPort = 4444
+)
+
+// TCPTunnelPort returns the port of the TCP (WebSocket) tunnel listener
+func (s ServerConfig) TCPTunnelPort() int {
+	if s.TCPPort != 0 {
+		return s.TCPPort
+	}
+	if s.Port != 0 {
+		return s.Port
+	}
+	return DefaultTCPTunnelPort
+}
+
+// GRPCTunnelPort returns the port of the gRPC tunnel listener
+func (s ServerConfig) GRPCTunnelPort() int {
+	if s.GRPCPort != 0 {
+		return s.GRPCPort
+	}
+	return DefaultGRPCTunnelPort
+}
+
+// TCPAddr returns the host:port of the TCP (WebSocket) tunnel listener
+func (s ServerConfig) TCPAddr() string {
+	return net.JoinHostPort(s.Host, strconv.Itoa(s.TCPTunnelPort()))
+}
+
+// GRPCAddr returns the host:port of the gRPC tunnel listener
+func (s ServerConfig) GRPCAddr() string {
+	return net.JoinHostPort(s.Host, strconv.Itoa(s.GRPCTunnelPort()))
+}
+
+// AllHosts returns Host followed by the failover Hosts, without blanks or duplicates
+func (s ServerConfig) AllHosts() []string {
+	var hosts []string
+	seen := make(map[string]bool)
+	for _, host := range append([]string{s.Host}, s.Hosts...) {
+		host = strings.TrimSpace(host)
+		if host == "" || seen[strings.ToLower(host)] {
+			continue
+		}
+		seen[strings.ToLower(host)] = true
+		hosts = append(hosts, host)
+	}
+	return hosts
 }
 
 // SecurityConfig represents security settings
 type SecurityConfig struct {
-	InsecureSkipVerify bool   `json:"insecure_skip_verify"`
-	CACert             string `json:"ca_cert"`
-	ClientCert         string `json:"client_cert"`
-	ClientKey          string `json:"client_key"`
+	InsecureSkipVerify bool          `json:"insecure_skip_verify"` // Ignored: only the --insecure flag skips verification
+	CACert             string        `json:"ca_cert"`
+	ClientCert         string        `json:"client_cert"`
+	ClientKey          string        `json:"client_key"`
+	CertExpiryWarning  time.Duration `json:"cert_expiry_warning"` // Warn when a certificate expires within this window
+
+	// SHA-256 fingerprint the server's certificate must have, on top of CA validation (empty = no pinning)
+	ServerCertFingerprint string `json:"server_cert_fingerprint,omitempty"`
 }
 
 // StreamingConfig holds configuration for streaming optimizations
@@ -92,13 +213,88 @@ type StreamingConfig struct {
 
 	// Performance settings
 	ConcurrentMediaStreams int `json:"concurrent_media_streams"` // Max concurrent media streams per domain
+
+	// Connection retirement thresholds (server-side pool tuning)
+	MaxRequestsPerConnection int           `json:"max_requests_per_connection"` // Retire a connection after this many requests
+	ConnectionMaxAge         time.Duration `json:"connection_max_age"`          // Retire a connection older than this
+	HotPoolMaxRequests       int           `json:"hot_pool_max_requests"`       // Max requests before a connection leaves the hot pool
+	HotPoolMaxAge            time.Duration `json:"hot_pool_max_age"`            // Max age before a connection leaves the hot pool
+	RecycleMaxRequests       int           `json:"recycle_max_requests"`        // Proactively recycle connections past this request count
+	RecycleMaxAge            time.Duration `json:"recycle_max_age"`             // Proactively recycle connections past this age
+	CleanupInterval          time.Duration `json:"cleanup_interval"`            // How often dead connections are swept
+
+	// Per-domain circuit breaker (server-side load shedding)
+	CircuitBreakerThreshold int           `json:"circuit_breaker_threshold"` // Consecutive failures before a domain's breaker opens
+	CircuitBreakerCooldown  time.Duration `json:"circuit_breaker_cooldown"`  // How long an open breaker sheds requests before probing
+
+	// Bandwidth cap per tunnel, applied to uploads and downloads separately (0 = unlimited)
+	MaxBytesPerSec int64 `json:"max_bytes_per_sec"`
+
+	// Request a streamed GET from the local service again (once) if the tunnel stream
+	// breaks mid-response, so the download continues after reconnecting. Off by default
+	// because the local service sees the request twice.
+	ReissueOnStreamFailure bool `json:"reissue_on_stream_failure"`
+
+	// Chunks of a large response read ahead and sent without waiting for the previous one
+	// (0 or 1 = one at a time, at most MaxChunkWindowSize); helps on high-latency links
+	ChunkWindowSize int `json:"chunk_window_size,omitempty"`
+
+	// Close a proxied WebSocket after no bytes in either direction for this long (0 = never).
+	// With WebSocketPingInterval set, the client is pinged after that much silence, so only
+	// connections whose peer stopped responding reach the idle timeout.
+	WebSocketIdleTimeout  time.Duration `json:"websocket_idle_timeout"`
+	WebSocketPingInterval time.Duration `json:"websocket_ping_interval"` // 0 = no pings
+
+	// Largest X-Tunnel-Timeout the local service may set to give one response more time;
+	// larger values are ignored (0 = ignore the header)
+	MaxOriginTimeout time.Duration `json:"max_origin_timeout"`
+
+	// Largest response status line plus headers read from a tunnel connection (0 = unlimited)
+	MaxHeaderBytes int `json:"max_header_bytes,omitempty"`
+
+	// Abandon a response when a write to the visitor makes no progress for this long (0 = never)
+	ClientWriteTimeout time.Duration `json:"client_write_timeout,omitempty"`
+
+	// On-demand connections: how long the server waits for the client to dial one in, and the
+	// client for the request on it (0 = DefaultFreshConnectionTimeout)
+	FreshConnectionTimeout time.Duration `json:"fresh_connection_timeout,omitempty"`
+
+	// Kernel socket buffers of accepted tunnel connections (bytes, 0 = OS default);
+	// larger buffers keep high-throughput media streams from stalling on the window
+	TCPReadBufferSize  int `json:"tcp_read_buffer_size,omitempty"`
+	TCPWriteBufferSize int `json:"tcp_write_buffer_size,omitempty"`
+
+	// Public UDP ports handed out to UDP tunnels (both 0 = UDP tunnels disabled)
+	UDPPortMin int `json:"udp_port_min,omitempty"`
+	UDPPortMax int `json:"udp_port_max,omitempty"`
+
+	// Open visitor sessions per UDP tunnel (0 = DefaultUDPMaxSessions); past it a new visitor
+	// replaces an idle session or is dropped
+	UDPMaxSessions int `json:"udp_max_sessions,omitempty"`
+
+	// Tunnel connections arrive through a load balancer that prepends a PROXY protocol
+	// header (v1 or v2); the client address is taken from it. Connections without one are refused.
+	ProxyProtocol bool `json:"proxy_protocol,omitempty"`
+
+	// Send each session's requests over the same hot-pool connection while it lives, for local
+	// apps keeping per-connection state. Sessions are told apart by the StickySessionCookie
+	// value when set and present, otherwise by visitor IP; a session whose connection dies
+	// moves to another one. Bound connections skip HotPoolMaxRequests retirement, so the pool
+	// spreads load less evenly.
+	EnableStickySessions bool   `json:"enable_sticky_sessions,omitempty"`
+	StickySessionCookie  string `json:"sticky_session_cookie,omitempty"`
 }
 
+// DefaultLocalHost is the host used to reach the local service when none is configured
+const DefaultLocalHost = "localhost"
+
 // DefaultConfig provides default tunnel configuration
 var DefaultConfig = Config{
+	Version:   CurrentConfigVersion,
+	LocalHost: DefaultLocalHost,
 	Server: ServerConfig{
 		Host: "tunnel.giraffecloud.xyz",
-		Port: 4443,
+		Port: DefaultTCPTunnelPort,
 	},
 	API: ServerConfig{
 		Host: "api.giraffecloud.xyz",
@@ -108,6 +304,7 @@ var DefaultConfig = Config{
 		InsecureSkipVerify: false, // PRODUCTION: NEVER skip verification
 		// Certificate paths are set dynamically by the 'login' command
 		// after fetching certificates from the API server
+		CertExpiryWarning: DefaultCertExpiryWarning,
 	},
 	AutoUpdate: AutoUpdateConfig{
 		Enabled:            true,           // Enable auto-updates by default
@@ -118,6 +315,7 @@ var DefaultConfig = Config{
 		RestartService:     true,     // Restart service after update
 		BackupCount:        5,        // Keep 5 backups
 		Channel:            "stable", // Default to stable releases
+		VerifyTimeout:      2 * time.Minute,
 		UpdateWindow: &TimeWindow{ // Update during off-peak hours
 			StartHour: 2, // 2 AM
 			EndHour:   6, // 6 AM
@@ -132,6 +330,25 @@ var DefaultConfig = Config{
 	},
 }
 
+// configPathOverride holds an explicit config file path set via --config.
+// When non-empty it takes precedence over GIRAFFECLOUD_HOME and the default location.
+var configPathOverride string
+
+// SetConfigPathOverride forces LoadConfig/SaveConfig to use the given file path.
+// Passing an empty string restores the default lookup behavior.
+func SetConfigPathOverride(path string) error {
+	if path == "" {
+		configPathOverride = ""
+		return nil
+	}
+	absPath, err := filepath.Abs(expandTildePath(path))
+	if err != nil {
+		return fmt.Errorf("failed to resolve config path %s: %w", path, err)
+	}
+	configPathOverride = absPath
+	return nil
+}
+
 // GetConfigDir returns the directory where GiraffeCloud stores config files
 func GetConfigDir() (string, error) {
 	// Highest priority: explicit override
@@ -154,6 +371,9 @@ func GetConfigDir() (string, error) {
 
 // GetConfigPath returns the full path to the config.json
 func GetConfigPath() (string, error) {
+	if configPathOverride != "" {
+		return configPathOverride, nil
+	}
 	dir, err := GetConfigDir()
 	if err != nil {
 		return "", err
@@ -161,6 +381,17 @@ func GetConfigPath() (string, error) {
 	return filepath.Join(dir, "config.json"), nil
 }
 
+// GetInstanceDir returns the directory of the selected config file. Per-instance state
+// (the pid lock, control socket and last good server) lives there, so instances started
+// with different --config files don't share it.
+func GetInstanceDir() (string, error) {
+	path, err := GetConfigPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Dir(path), nil
+}
+
 // EnsureConsistentConfigHome sets GIRAFFECLOUD_HOME to the original sudo user's home when running as root with sudo
 // This keeps CLI behavior consistent with the non-root user's config path even when prefixed with sudo.
 func EnsureConsistentConfigHome() {
@@ -219,6 +450,10 @@ func applyAutoUpdateDefaults(cfg *Config) {
 	if cfg.AutoUpdate.Channel == "" {
 		cfg.AutoUpdate.Channel = DefaultConfig.AutoUpdate.Channel
 	}
+	// VerifyTimeout default
+	if cfg.AutoUpdate.VerifyTimeout == 0 {
+		cfg.AutoUpdate.VerifyTimeout = DefaultConfig.AutoUpdate.VerifyTimeout
+	}
 }
 
 // DefaultStreamingConfig returns default streaming configuration
@@ -252,11 +487,85 @@ func DefaultStreamingConfig() *StreamingConfig {
 		},
 
 		ConcurrentMediaStreams: 5,
+
+		// Connection retirement thresholds - tuned for fast gallery-style navigation
+		MaxRequestsPerConnection: 50,
+		ConnectionMaxAge:         10 * time.Minute,
+		HotPoolMaxRequests:       15,
+		HotPoolMaxAge:            5 * time.Minute,
+		RecycleMaxRequests:       100,
+		RecycleMaxAge:            15 * time.Minute,
+		CleanupInterval:          5 * time.Minute,
+
+		// Circuit breaker - shed a failing domain quickly instead of piling up timeouts
+		CircuitBreakerThreshold: 5,
+		CircuitBreakerCooldown:  30 * time.Second,
+
+		WebSocketIdleTimeout: 30 * time.Minute,
+
+		MaxOriginTimeout:       DefaultMaxOriginTimeout,
+		MaxHeaderBytes:         DefaultMaxHeaderBytes,
+		ClientWriteTimeout:     DefaultClientWriteTimeout,
+		FreshConnectionTimeout: DefaultFreshConnectionTimeout,
+	}
+}
+
+// UnmarshalJSON decodes onto DefaultStreamingConfig, so a partial "streaming" section keeps
+// the defaults for every field it leaves out
+func (c *StreamingConfig) UnmarshalJSON(data []byte) error {
+	type plain StreamingConfig // Drops the method so decoding doesn't recurse
+	decoded := plain(*DefaultStreamingConfig())
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
 	}
+	*c = StreamingConfig(decoded)
+	return nil
 }
 
-// LoadConfig loads the configuration from the default location
+// applyPoolDefaults backfills zero-valued pool thresholds (e.g. from older config files)
+func (c *StreamingConfig) applyPoolDefaults() {
+	defaults := DefaultStreamingConfig()
+	if c.MaxRequestsPerConnection <= 0 {
+		c.MaxRequestsPerConnection = defaults.MaxRequestsPerConnection
+	}
+	if c.ConnectionMaxAge <= 0 {
+		c.ConnectionMaxAge = defaults.ConnectionMaxAge
+	}
+	if c.HotPoolMaxRequests <= 0 {
+		c.HotPoolMaxRequests = defaults.HotPoolMaxRequests
+	}
+	if c.HotPoolMaxAge <= 0 {
+		c.HotPoolMaxAge = defaults.HotPoolMaxAge
+	}
+	if c.RecycleMaxRequests <= 0 {
+		c.RecycleMaxRequests = defaults.RecycleMaxRequests
+	}
+	if c.RecycleMaxAge <= 0 {
+		c.RecycleMaxAge = defaults.RecycleMaxAge
+	}
+	if c.CleanupInterval <= 0 {
+		c.CleanupInterval = defaults.CleanupInterval
+	}
+	if c.CircuitBreakerThreshold <= 0 {
+		c.CircuitBreakerThreshold = defaults.CircuitBreakerThreshold
+	}
+	if c.CircuitBreakerCooldown <= 0 {
+		c.CircuitBreakerCooldown = defaults.CircuitBreakerCooldown
+	}
+}
+
+// LoadConfig loads the configuration from the default location (or the --config override)
 func LoadConfig() (*Config, error) {
+	return loadConfig(false)
+}
+
+// LoadOrCreateConfig is LoadConfig for commands that write the config file (login):
+// a --config path that doesn't exist yet starts from the defaults instead of failing
+func LoadOrCreateConfig() (*Config, error) {
+	return loadConfig(true)
+}
+
+func loadConfig(allowMissing bool) (*Config, error) {
 	configPath, err := GetConfigPath()
 	if err != nil {
 		return nil, err
@@ -264,20 +573,27 @@ func LoadConfig() (*Config, error) {
 	data, err := os.ReadFile(configPath)
 	if err != nil {
 		if os.IsNotExist(err) {
+			// An explicitly requested config file must exist unless the caller is about to create it
+			if configPathOverride != "" && !allowMissing {
+				return nil, fmt.Errorf("config file not found: %s", configPath)
+			}
 			return &DefaultConfig, nil
 		}
 		return nil, fmt.Errorf("failed to read tunnel config file: %w", err)
 	}
 
-	var cfg Config
-	if err := json.Unmarshal(data, &cfg); err != nil {
+	cfg, changes, err := migrateConfig(data)
+	if err != nil {
 		return nil, fmt.Errorf("failed to parse tunnel config file: %w", err)
 	}
+	if len(changes) > 0 {
+		saveMigratedConfig(configPath, cfg, changes)
+	}
 
-	// Backfill defaults for configs created by older versions (missing auto_update fields)
-	applyAutoUpdateDefaults(&cfg)
+	// Hand-edited files may still lack values, whatever their version
+	applyConfigDefaults(cfg)
 
-	return &cfg, nil
+	return cfg, nil
 }
 
 // MergeConfig merges changes from new config into existing config
@@ -300,12 +616,42 @@ func MergeConfig(existing, new *Config) *Config {
 	if new.LocalPort != 0 {
 		merged.LocalPort = new.LocalPort
 	}
+	if new.LocalHost != "" {
+		merged.LocalHost = new.LocalHost
+	}
+	if new.FallbackLocalPort != 0 {
+		merged.FallbackLocalPort = new.FallbackLocalPort
+	}
+	if new.LocalScheme != "" {
+		merged.LocalScheme = new.LocalScheme
+	}
+	if new.LocalUseHTTP2 {
+		merged.LocalUseHTTP2 = true
+	}
+	if new.LocalPathPrefix != "" {
+		merged.LocalPathPrefix = new.LocalPathPrefix
+	}
+	if new.PublicPathStrip != "" {
+		merged.PublicPathStrip = new.PublicPathStrip
+	}
 	if new.Server.Host != "" {
 		merged.Server.Host = new.Server.Host
 	}
 	if new.Server.Port != 0 {
 		merged.Server.Port = new.Server.Port
 	}
+	if new.Server.TCPPort != 0 {
+		merged.Server.TCPPort = new.Server.TCPPort
+	}
+	if new.Server.GRPCPort != 0 {
+		merged.Server.GRPCPort = new.Server.GRPCPort
+	}
+	if len(new.Server.Hosts) > 0 {
+		merged.Server.Hosts = new.Server.Hosts
+	}
+	if new.Server.EndpointSelection != "" {
+		merged.Server.EndpointSelection = new.Server.EndpointSelection
+	}
 	if new.API.Host != "" {
 		merged.API.Host = new.API.Host
 	}
@@ -313,6 +659,13 @@ func MergeConfig(existing, new *Config) *Config {
 		merged.API.Port = new.API.Port
 	}
 
+	if new.Streaming != nil {
+		merged.Streaming = new.Streaming
+	}
+	if new.Retry != nil {
+		merged.Retry = new.Retry
+	}
+
 	// Always update test mode and auto-update settings
 	merged.TestMode = new.TestMode
 	merged.AutoUpdate = new.AutoUpdate
@@ -331,22 +684,24 @@ func MergeConfig(existing, new *Config) *Config {
 	return &merged
 }
 
-// SaveConfig saves the configuration to the default location
+// SaveConfig saves the configuration to the default location (or the --config override)
 func SaveConfig(cfg *Config) error {
 	if err := cfg.Validate(); err != nil {
 		return fmt.Errorf("invalid tunnel configuration: %w", err)
 	}
 
-	configDir, err := GetConfigDir()
+	configPath, err := GetConfigPath()
 	if err != nil {
 		return err
 	}
-	if err := os.MkdirAll(configDir, 0700); err != nil {
+	if err := os.MkdirAll(filepath.Dir(configPath), 0700); err != nil {
 		return fmt.Errorf("failed to create config directory: %w", err)
 	}
 
-	configPath := filepath.Join(configDir, "config.json")
-	data, err := json.MarshalIndent(cfg, "", "  ")
+	// Whatever it was loaded from, the file is written in the current schema
+	saved := *cfg
+	saved.Version = CurrentConfigVersion
+	data, err := json.MarshalIndent(&saved, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal tunnel config: %w", err)
 	}
@@ -368,8 +723,12 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("server host is required")
 	}
 
-	if c.Server.Port <= 0 || c.Server.Port > 65535 {
-		return fmt.Errorf("invalid server port: %d", c.Server.Port)
+	if port := c.Server.TCPTunnelPort(); port <= 0 || port > 65535 {
+		return fmt.Errorf("invalid server port: %d", port)
+	}
+
+	if port := c.Server.GRPCTunnelPort(); port <= 0 || port > 65535 {
+		return fmt.Errorf("invalid server gRPC port: %d", port)
 	}
 
 	if c.API.Host == "" {
@@ -398,6 +757,14 @@ func ResolveReleaseChannel() string {
 	return ""
 }
 
+// localServiceAddr builds the dial address for the local service, falling back to DefaultLocalHost
+func localServiceAddr(host string, port int) string {
+	if host == "" {
+		host = DefaultLocalHost
+	}
+	return net.JoinHostPort(host, strconv.Itoa(port))
+}
+
 // IsMediaExtension checks if a file extension is considered media
 func (c *StreamingConfig) IsMediaExtension(ext string) bool {
 	for _, mediaExt := range c.MediaExtensions {
@@ -0,0 +1,143 @@
+package tunnel
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// ConfigKeys returns the dotted keys accepted by GetConfigValue and SetConfigValue, e.g.
+// "local_port" or "server.host": the scalar fields of Config and of its nested sections,
+// named by their JSON tags
+func ConfigKeys() []string {
+	var keys []string
+	collectConfigKeys(reflect.TypeOf(Config{}), "", &keys)
+	sort.Strings(keys)
+	return keys
+}
+
+func collectConfigKeys(t reflect.Type, prefix string, keys *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := configKeyName(field)
+		if name == "" {
+			continue
+		}
+		switch {
+		case field.Type.Kind() == reflect.Struct:
+			collectConfigKeys(field.Type, prefix+name+".", keys)
+		case isConfigScalar(field.Type):
+			*keys = append(*keys, prefix+name)
+		}
+	}
+}
+
+// configKeyName returns the JSON name of an exported field, or "" for skipped fields
+// (config:"-" marks fields only the program sets)
+func configKeyName(field reflect.StructField) string {
+	if !field.IsExported() || field.Tag.Get("config") == "-" {
+		return ""
+	}
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+// isConfigScalar reports whether values of t can be set from a single string. Maps, lists
+// and optional (pointer) sections are left to the config file.
+func isConfigScalar(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.String, reflect.Bool, reflect.Int, reflect.Int32, reflect.Int64, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// configField finds the field for a dotted key
+func configField(cfg *Config, key string) (reflect.Value, error) {
+	v := reflect.ValueOf(cfg).Elem()
+	parts := strings.Split(key, ".")
+	for i, part := range parts {
+		found := false
+		for j := 0; j < v.NumField(); j++ {
+			field := v.Type().Field(j)
+			if configKeyName(field) != part {
+				continue
+			}
+			last := i == len(parts)-1
+			if (last && isConfigScalar(field.Type)) || (!last && field.Type.Kind() == reflect.Struct) {
+				v = v.Field(j)
+				found = true
+			}
+			break
+		}
+		if !found {
+			return reflect.Value{}, fmt.Errorf("unknown config key %q, valid keys:\n  %s", key, strings.Join(ConfigKeys(), "\n  "))
+		}
+	}
+	return v, nil
+}
+
+// GetConfigValue returns the value of a dotted config key (see ConfigKeys) as text
+func GetConfigValue(cfg *Config, key string) (string, error) {
+	v, err := configField(cfg, key)
+	if err != nil {
+		return "", err
+	}
+	if v.Type() == durationType {
+		return time.Duration(v.Int()).String(), nil
+	}
+	return fmt.Sprint(v.Interface()), nil
+}
+
+// SetConfigValue parses value for the type of a dotted config key (see ConfigKeys) and sets it.
+// Durations take Go syntax such as 30s or 24h; ports must be within 0-65535.
+func SetConfigValue(cfg *Config, key, value string) error {
+	v, err := configField(cfg, key)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("%s: invalid duration %q (e.g. 30s, 5m, 24h)", key, value)
+		}
+		v.SetInt(int64(d))
+	case v.Kind() == reflect.String:
+		v.SetString(value)
+	case v.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%s: invalid boolean %q (expected true or false)", key, value)
+		}
+		v.SetBool(b)
+	case v.Kind() == reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("%s: invalid number %q", key, value)
+		}
+		v.SetFloat(f)
+	default:
+		n, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%s: invalid integer %q", key, value)
+		}
+		if strings.HasSuffix(key, "port") && (n < 0 || n > 65535) {
+			return fmt.Errorf("%s: port %d out of range (0-65535)", key, n)
+		}
+		v.SetInt(n)
+	}
+	return nil
+}
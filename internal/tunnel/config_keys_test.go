@@ -0,0 +1,73 @@
+package tunnel
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConfigKeys(t *testing.T) {
+	keys := ConfigKeys()
+	for _, want := range []string{"token", "local_port", "server.host", "api.port", "security.ca_cert", "auto_update.check_interval"} {
+		if !slices.Contains(keys, want) {
+			t.Errorf("ConfigKeys() is missing %q", want)
+		}
+	}
+	for _, skipped := range []string{"server", "streaming", "retry", "dns_override", "auto_update.update_window", "test_mode.groups"} {
+		if slices.Contains(keys, skipped) {
+			t.Errorf("ConfigKeys() should not include %q", skipped)
+		}
+	}
+}
+
+func TestSetConfigValue(t *testing.T) {
+	cfg := DefaultConfig
+
+	sets := []struct{ key, value, want string }{
+		{"server.host", "tunnel.example.com", "tunnel.example.com"},
+		{"local_port", "3000", "3000"},
+		{"api.port", "8443", "8443"},
+		{"security.client_cert", "/etc/gc/client.crt", "/etc/gc/client.crt"},
+		{"auto_update.enabled", "false", "false"},
+		{"auto_update.check_interval", "12h", "12h0m0s"},
+	}
+	for _, set := range sets {
+		if err := SetConfigValue(&cfg, set.key, set.value); err != nil {
+			t.Fatalf("SetConfigValue(%q, %q): %v", set.key, set.value, err)
+		}
+		got, err := GetConfigValue(&cfg, set.key)
+		if err != nil {
+			t.Fatalf("GetConfigValue(%q): %v", set.key, err)
+		}
+		if got != set.want {
+			t.Errorf("%s = %q, want %q", set.key, got, set.want)
+		}
+	}
+
+	if cfg.Server.Host != "tunnel.example.com" || cfg.LocalPort != 3000 || cfg.AutoUpdate.CheckInterval != 12*time.Hour {
+		t.Errorf("config not updated: %+v", cfg)
+	}
+	if DefaultConfig.Server.Host == "tunnel.example.com" {
+		t.Error("DefaultConfig was modified through a copy")
+	}
+
+	errs := []struct{ key, value, want string }{
+		{"server.hostname", "x", "unknown config key"},
+		{"server", "x", "unknown config key"},
+		{"local_port", "abc", "invalid integer"},
+		{"local_port", "70000", "out of range"},
+		{"auto_update.enabled", "maybe", "invalid boolean"},
+		{"auto_update.check_interval", "12", "invalid duration"},
+	}
+	for _, e := range errs {
+		err := SetConfigValue(&cfg, e.key, e.value)
+		if err == nil || !strings.Contains(err.Error(), e.want) {
+			t.Errorf("SetConfigValue(%q, %q) = %v, want error containing %q", e.key, e.value, err, e.want)
+		}
+	}
+
+	if _, err := GetConfigValue(&cfg, "nope"); err == nil || !strings.Contains(err.Error(), "server.host") {
+		t.Errorf("unknown key error should list valid keys, got %v", err)
+	}
+}
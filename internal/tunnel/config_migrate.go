@@ -0,0 +1,167 @@
+package tunnel
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/osa911/giraffecloud/internal/logging"
+)
+
+// CurrentConfigVersion is the schema version of config files written by this build.
+// Bump it with a new entry in configMigrations whenever a field needs more than its zero
+// value in older files, or a key moves.
+const CurrentConfigVersion = 1
+
+// configMigration upgrades a config file from the previous schema version to version to
+type configMigration struct {
+	to      int
+	renames map[string]string // Dotted JSON keys that moved, old -> new; applied before decoding
+	apply   func(cfg *Config) // Fills in what files of the previous version lack
+}
+
+// configMigrations upgrade older config files in order
+var configMigrations = []configMigration{
+	// Files written before versioning: fields added since decode as zero values that mean
+	// something else, e.g. no certificate expiry warning or pool thresholds of 0
+	{to: 1, apply: applyConfigDefaults},
+}
+
+// applyConfigDefaults backfills the fields older config files may lack
+func applyConfigDefaults(cfg *Config) {
+	applyAutoUpdateDefaults(cfg)
+	if cfg.LocalHost == "" {
+		cfg.LocalHost = DefaultLocalHost
+	}
+	if cfg.Security.CertExpiryWarning <= 0 {
+		cfg.Security.CertExpiryWarning = DefaultCertExpiryWarning
+	}
+	if cfg.Streaming != nil {
+		cfg.Streaming.applyPoolDefaults()
+	}
+}
+
+// migrateConfig decodes a config file, upgrading it to CurrentConfigVersion. It returns what
+// changed, one line per key, or nothing when the file was current already. Files from a
+// newer version are decoded as they are.
+func migrateConfig(data []byte) (*Config, []string, error) {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, nil, err
+	}
+	version := 0
+	if v, ok := raw["version"].(float64); ok {
+		version = int(v)
+	}
+
+	var changes []string
+	pending := make([]configMigration, 0, len(configMigrations))
+	for _, migration := range configMigrations {
+		if migration.to <= version {
+			continue
+		}
+		pending = append(pending, migration)
+		for from, to := range migration.renames {
+			if moveConfigKey(raw, from, to) {
+				changes = append(changes, fmt.Sprintf("%s: moved to %s", from, to))
+			}
+		}
+	}
+	if len(changes) > 0 {
+		var err error
+		if data, err = json.Marshal(raw); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, nil, err
+	}
+	if len(pending) == 0 {
+		return &cfg, nil, nil
+	}
+
+	before := configSnapshot(&cfg)
+	for _, migration := range pending {
+		migration.apply(&cfg)
+	}
+	after := configSnapshot(&cfg)
+	for _, key := range ConfigKeys() {
+		if before[key] != after[key] {
+			changes = append(changes, fmt.Sprintf("%s: %q -> %q", key, before[key], after[key]))
+		}
+	}
+	if before["streaming"] != after["streaming"] {
+		changes = append(changes, "streaming: filled in missing settings")
+	}
+	changes = append(changes, fmt.Sprintf("version: %d -> %d", version, CurrentConfigVersion))
+	cfg.Version = CurrentConfigVersion
+	return &cfg, changes, nil
+}
+
+// saveMigratedConfig logs what an upgrade changed and writes the upgraded file. A file that
+// can't be saved (e.g. no token yet) is upgraded again on the next load.
+func saveMigratedConfig(path string, cfg *Config, changes []string) {
+	logger := logging.GetGlobalLogger()
+	if logger != nil {
+		logger.Info("Upgraded config file %s to version %d:\n  %s", path, CurrentConfigVersion, strings.Join(changes, "\n  "))
+	}
+	if err := SaveConfig(cfg); err != nil && logger != nil {
+		logger.Warn("Could not save the upgraded config file, using it for this run only: %v", err)
+	}
+}
+
+// configSnapshot returns the value of every config key, and the streaming section as JSON
+func configSnapshot(cfg *Config) map[string]string {
+	snapshot := make(map[string]string)
+	for _, key := range ConfigKeys() {
+		snapshot[key], _ = GetConfigValue(cfg, key)
+	}
+	if cfg.Streaming != nil {
+		data, _ := json.Marshal(cfg.Streaming)
+		snapshot["streaming"] = string(data)
+	}
+	return snapshot
+}
+
+// moveConfigKey moves the value at a dotted JSON key to another, keeping a value already
+// set at the new key. It reports whether anything moved.
+func moveConfigKey(raw map[string]interface{}, from, to string) bool {
+	parent, name := configSection(raw, from, false)
+	if parent == nil {
+		return false
+	}
+	value, ok := parent[name]
+	if !ok {
+		return false
+	}
+	newParent, newName := configSection(raw, to, true)
+	if newParent == nil {
+		return false
+	}
+	delete(parent, name)
+	if _, exists := newParent[newName]; !exists {
+		newParent[newName] = value
+	}
+	return true
+}
+
+// configSection returns the object holding a dotted key and the key's last part, creating
+// missing sections when create is set
+func configSection(raw map[string]interface{}, key string, create bool) (map[string]interface{}, string) {
+	parts := strings.Split(key, ".")
+	section := raw
+	for _, part := range parts[:len(parts)-1] {
+		next, ok := section[part].(map[string]interface{})
+		if !ok {
+			if !create || section[part] != nil {
+				return nil, ""
+			}
+			next = make(map[string]interface{})
+			section[part] = next
+		}
+		section = next
+	}
+	return section, parts[len(parts)-1]
+}
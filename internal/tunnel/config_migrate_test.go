@@ -0,0 +1,121 @@
+package tunnel
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfigMigratesOldFiles(t *testing.T) {
+	initTestLogger(t)
+	home := t.TempDir()
+	t.Setenv("GIRAFFECLOUD_HOME", home)
+	path := filepath.Join(home, "config.json")
+
+	// Written before versioning, auto_update backfill and the pool thresholds
+	legacy := `{
+  "token": "tok",
+  "domain": "app.example.com",
+  "local_port": 3000,
+  "server": {"host": "tunnel.example.com", "port": 4443},
+  "api": {"host": "api.example.com", "port": 443},
+  "security": {"ca_cert": "ca.crt"},
+  "auto_update": {"enabled": true},
+  "streaming": {"pool_size": 4}
+}`
+	if err := os.WriteFile(path, []byte(legacy), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Version != CurrentConfigVersion || cfg.LocalPort != 3000 || cfg.Streaming.PoolSize != 4 {
+		t.Errorf("settings lost in migration: %+v", cfg)
+	}
+	if cfg.Security.CertExpiryWarning != DefaultCertExpiryWarning || cfg.AutoUpdate.Channel != "stable" || cfg.Streaming.HotPoolMaxRequests == 0 {
+		t.Errorf("defaults not filled in: %+v", cfg)
+	}
+
+	// The upgraded file is saved and loads without further changes
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved map[string]interface{}
+	if err := json.Unmarshal(data, &saved); err != nil || saved["version"] != float64(CurrentConfigVersion) {
+		t.Fatalf("file not rewritten with the version: %s", data)
+	}
+	if _, changes, err := migrateConfig(data); err != nil || len(changes) != 0 {
+		t.Errorf("current file migrated again: %v %v", changes, err)
+	}
+}
+
+func TestMigrateConfigChanges(t *testing.T) {
+	_, changes, err := migrateConfig([]byte(`{"token": "tok", "security": {}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	joined := strings.Join(changes, "\n")
+	for _, want := range []string{`security.cert_expiry_warning: "0s" -> "`, `local_host: "" -> "localhost"`, "version: 0 -> "} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("changes missing %q:\n%s", want, joined)
+		}
+	}
+
+	raw := map[string]interface{}{"server": map[string]interface{}{"port": 4443.0}}
+	if !moveConfigKey(raw, "server.port", "tunnel.tcp_port") || raw["tunnel"].(map[string]interface{})["tcp_port"] != 4443.0 {
+		t.Errorf("key not moved: %v", raw)
+	}
+	if _, ok := raw["server"].(map[string]interface{})["port"]; ok || moveConfigKey(raw, "server.port", "tunnel.tcp_port") {
+		t.Errorf("old key left behind: %v", raw)
+	}
+}
+
+func TestLoadOrCreateConfigAllowsMissingOverride(t *testing.T) {
+	initTestLogger(t)
+	path := filepath.Join(t.TempDir(), "new", "config.json")
+	if err := SetConfigPathOverride(path); err != nil {
+		t.Fatal(err)
+	}
+	defer SetConfigPathOverride("")
+
+	if _, err := LoadConfig(); err == nil {
+		t.Fatal("LoadConfig should reject a missing --config file")
+	}
+	cfg, err := LoadOrCreateConfig()
+	if err != nil {
+		t.Fatalf("LoadOrCreateConfig: %v", err)
+	}
+	cfg.Token = "tok"
+	if err := SaveConfig(cfg); err != nil {
+		t.Fatalf("SaveConfig: %v", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("config file not created: %v", err)
+	}
+}
+
+func TestInstanceStateFollowsConfigOverride(t *testing.T) {
+	initTestLogger(t)
+	t.Setenv("GIRAFFECLOUD_HOME", t.TempDir())
+	dir := filepath.Join(t.TempDir(), "second")
+	if err := SetConfigPathOverride(filepath.Join(dir, "config.json")); err != nil {
+		t.Fatal(err)
+	}
+	defer SetConfigPathOverride("")
+
+	if socketPath, err := GetControlSocketPath(); err != nil || filepath.Dir(socketPath) != dir {
+		t.Errorf("control socket %s (%v), want it in %s", socketPath, err, dir)
+	}
+	sm, err := NewSingletonManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Dir(sm.PidFile) != dir {
+		t.Errorf("pid file %s, want it in %s", sm.PidFile, dir)
+	}
+}
@@ -0,0 +1,150 @@
+package tunnel
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"os"
+	"time"
+)
+
+// hostResolveTimeout bounds each DNS lookup during config validation
+const hostResolveTimeout = 5 * time.Second
+
+// ConfigCheck is the result of validating a single config field
+type ConfigCheck struct {
+	Name   string
+	OK     bool
+	Detail string
+}
+
+// ValidateConfig checks the fields a tunnel needs to connect and returns one result per field.
+// It never stops at the first failure so the caller can show the full checklist.
+func ValidateConfig(cfg *Config) []ConfigCheck {
+	var checks []ConfigCheck
+	add := func(name string, err error, okDetail string) {
+		if err != nil {
+			checks = append(checks, ConfigCheck{Name: name, Detail: err.Error()})
+			return
+		}
+		checks = append(checks, ConfigCheck{Name: name, OK: true, Detail: okDetail})
+	}
+
+	if cfg.Token == "" {
+		add("token", fmt.Errorf("not set - run 'giraffecloud login --token YOUR_API_TOKEN'"), "")
+	} else {
+		add("token", nil, "set")
+	}
+
+	if cfg.Domain == "" {
+		add("domain", fmt.Errorf("not set - connect once to fetch it from the server, or set it in the config file"), "")
+	} else {
+		add("domain", nil, cfg.Domain)
+	}
+
+	add("local_port", validatePort(cfg.LocalPort), fmt.Sprintf("%d", cfg.LocalPort))
+	if cfg.FallbackLocalPort != 0 {
+		err := validatePort(cfg.FallbackLocalPort)
+		if err == nil && cfg.FallbackLocalPort == cfg.LocalPort {
+			err = fmt.Errorf("same as local_port - use the port of a second instance")
+		}
+		add("fallback_local_port", err, fmt.Sprintf("%d", cfg.FallbackLocalPort))
+	}
+	if cfg.LocalScheme != "" {
+		add("local_scheme", ValidateLocalScheme(cfg.LocalScheme), cfg.LocalScheme)
+	}
+	if cfg.LocalPathPrefix != "" {
+		add("local_path_prefix", ValidatePathPrefix(cfg.LocalPathPrefix), cfg.LocalPathPrefix)
+	}
+	if cfg.PublicPathStrip != "" {
+		add("public_path_strip", ValidatePathPrefix(cfg.PublicPathStrip), cfg.PublicPathStrip)
+	}
+	if cfg.UDPPort != 0 {
+		add("udp_port", validatePort(cfg.UDPPort), fmt.Sprintf("%d", cfg.UDPPort))
+	}
+	if cfg.RequireSignedURL || cfg.SignedURLSecret != "" {
+		add("signed_url_secret", ValidateSignedURLSecret(cfg.SignedURLSecret), "set")
+	}
+	if cfg.Retry != nil {
+		add("retry", cfg.Retry.Validate(), fmt.Sprintf("initial delay %v, health check every %v",
+			cfg.Retry.InitialDelay, cfg.Retry.HealthCheckInterval))
+	}
+	add("server.port", validatePort(cfg.Server.TCPTunnelPort()), fmt.Sprintf("%d", cfg.Server.TCPTunnelPort()))
+	add("server.grpc_port", validatePort(cfg.Server.GRPCTunnelPort()), fmt.Sprintf("%d", cfg.Server.GRPCTunnelPort()))
+	add("api.port", validatePort(cfg.API.Port), fmt.Sprintf("%d", cfg.API.Port))
+	add("server.host", resolveHost(cfg.Server.Host), cfg.Server.Host)
+	for i, host := range cfg.Server.Hosts {
+		add(fmt.Sprintf("server.hosts[%d]", i), resolveHost(host), host)
+	}
+	if cfg.Server.EndpointSelection != "" {
+		_, err := ParseEndpointSelection(string(cfg.Server.EndpointSelection))
+		add("server.endpoint_selection", err, string(cfg.Server.EndpointSelection))
+	}
+	add("api.host", resolveHost(cfg.API.Host), cfg.API.Host)
+
+	caPath := expandTildePath(cfg.Security.CACert)
+	add("security.ca_cert", validateCACert(caPath), caPath)
+
+	certPath := expandTildePath(cfg.Security.ClientCert)
+	keyPath := expandTildePath(cfg.Security.ClientKey)
+	add("security.client_cert", validateClientCert(certPath, keyPath), certPath)
+	if cfg.Security.ServerCertFingerprint != "" {
+		_, err := ParseCertFingerprint(cfg.Security.ServerCertFingerprint)
+		add("security.server_cert_fingerprint", err, "pinned")
+	}
+
+	return checks
+}
+
+// validatePort checks that port is a usable TCP port
+func validatePort(port int) error {
+	if port < 1 || port > 65535 {
+		return fmt.Errorf("%d is out of range (1-65535)", port)
+	}
+	return nil
+}
+
+// resolveHost checks that host is set and resolves (IP literals always pass)
+func resolveHost(host string) error {
+	if host == "" {
+		return fmt.Errorf("not set")
+	}
+	if net.ParseIP(host) != nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), hostResolveTimeout)
+	defer cancel()
+	if _, err := net.DefaultResolver.LookupHost(ctx, host); err != nil {
+		return fmt.Errorf("%s does not resolve: %v", host, err)
+	}
+	return nil
+}
+
+// validateCACert checks that the CA bundle exists and contains at least one certificate
+func validateCACert(path string) error {
+	if path == "" {
+		return fmt.Errorf("not set - run 'giraffecloud login' to download certificates")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("cannot read %s: %v", path, err)
+	}
+	if !x509.NewCertPool().AppendCertsFromPEM(data) {
+		return fmt.Errorf("%s contains no valid PEM certificates", path)
+	}
+	return nil
+}
+
+// validateClientCert checks that the client certificate and key exist and form a valid pair
+func validateClientCert(certPath, keyPath string) error {
+	if certPath == "" || keyPath == "" {
+		return fmt.Errorf("client_cert/client_key not set - run 'giraffecloud login' to download certificates")
+	}
+	if _, err := tls.LoadX509KeyPair(certPath, keyPath); err != nil {
+		return fmt.Errorf("cannot load %s with key %s: %v", certPath, keyPath, err)
+	}
+	return nil
+}
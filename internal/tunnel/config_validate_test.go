@@ -0,0 +1,81 @@
+package tunnel
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestValidateConfig(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "gc-validate")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	badCA := filepath.Join(tempDir, "ca.crt")
+	if err := os.WriteFile(badCA, []byte("not a certificate"), 0600); err != nil {
+		t.Fatalf("Failed to write CA file: %v", err)
+	}
+
+	cfg := &Config{
+		Token:     "token",
+		LocalPort: 70000,
+		Server:    ServerConfig{Host: "127.0.0.1", Port: 4443},
+		API:       ServerConfig{Host: "::1", Port: 443},
+		Security: SecurityConfig{
+			CACert:     badCA,
+			ClientCert: filepath.Join(tempDir, "missing.crt"),
+			ClientKey:  filepath.Join(tempDir, "missing.key"),
+		},
+	}
+
+	want := map[string]bool{
+		"token":                true,
+		"domain":               false,
+		"local_port":           false,
+		"server.port":          true,
+		"server.grpc_port":     true,
+		"api.port":             true,
+		"server.host":          true,
+		"api.host":             true,
+		"security.ca_cert":     false,
+		"security.client_cert": false,
+	}
+
+	checks := ValidateConfig(cfg)
+	if len(checks) != len(want) {
+		t.Fatalf("Expected %d checks, got %d", len(want), len(checks))
+	}
+	for _, check := range checks {
+		expected, ok := want[check.Name]
+		if !ok {
+			t.Errorf("Unexpected check %q", check.Name)
+			continue
+		}
+		if check.OK != expected {
+			t.Errorf("Check %q: expected OK=%v, got OK=%v (%s)", check.Name, expected, check.OK, check.Detail)
+		}
+	}
+}
+
+func TestServerConfigTunnelAddrs(t *testing.T) {
+	tests := []struct {
+		server   ServerConfig
+		tcpAddr  string
+		grpcAddr string
+	}{
+		{ServerConfig{Host: "tunnel.example.com"}, "tunnel.example.com:4443", "tunnel.example.com:4444"},
+		{ServerConfig{Host: "tunnel.example.com", Port: 443}, "tunnel.example.com:443", "tunnel.example.com:4444"},
+		{ServerConfig{Host: "tunnel.example.com", Port: 4443, TCPPort: 443, GRPCPort: 8443}, "tunnel.example.com:443", "tunnel.example.com:8443"},
+		{ServerConfig{Host: "::1", GRPCPort: 9000}, "[::1]:4443", "[::1]:9000"},
+	}
+	for _, tt := range tests {
+		if got := tt.server.TCPAddr(); got != tt.tcpAddr {
+			t.Errorf("%+v TCPAddr() = %q, want %q", tt.server, got, tt.tcpAddr)
+		}
+		if got := tt.server.GRPCAddr(); got != tt.grpcAddr {
+			t.Errorf("%+v GRPCAddr() = %q, want %q", tt.server, got, tt.grpcAddr)
+		}
+	}
+}
@@ -13,6 +13,11 @@ type ConnectionType string
 const (
 	ConnectionTypeHTTP      ConnectionType = "http"
 	ConnectionTypeWebSocket ConnectionType = "websocket"
+	ConnectionTypeUDP       ConnectionType = "udp" // Datagrams for a local UDP service, see udp_tunnel.go
+
+	// A connection dialed in for one HTTP request when the pool is exhausted: handed to the
+	// waiting request, never pooled, closed after the response (see createFreshTunnelConnection)
+	ConnectionTypeHTTPOnDemand ConnectionType = "http-on-demand"
 )
 
 // TunnelConnectionPool manages a pool of HTTP tunnel connections for a domain
@@ -20,7 +25,8 @@ type TunnelConnectionPool struct {
 	domain      string
 	targetPort  int
 	connections []*TunnelConnection
-	roundRobin  uint64 // Atomic counter for round-robin distribution
+	roundRobin  uint64                    // Atomic counter for round-robin distribution
+	sticky      map[string]*stickyBinding // Session key → connection, see sticky_sessions.go
 	mu          sync.RWMutex
 }
 
@@ -67,6 +73,23 @@ func (p *TunnelConnectionPool) IsConnectionHealthy(conn *TunnelConnection) bool
 	return false
 }
 
+// GetDomainConnectionCounts returns the number of pooled HTTP and WebSocket connections per domain
+func (m *ConnectionManager) GetDomainConnectionCounts() map[string]map[ConnectionType]int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	counts := make(map[string]map[ConnectionType]int, len(m.connections))
+	for domain, domainConns := range m.connections {
+		domainConns.mu.RLock()
+		counts[domain] = map[ConnectionType]int{
+			ConnectionTypeHTTP:      domainConns.httpPool.Size(),
+			ConnectionTypeWebSocket: domainConns.wsPool.Size(),
+		}
+		domainConns.mu.RUnlock()
+	}
+	return counts
+}
+
 // CleanupDeadConnections removes dead connections from the pool
 func (p *TunnelConnectionPool) CleanupDeadConnections() int {
 	p.mu.Lock()
@@ -80,11 +103,13 @@ func (p *TunnelConnectionPool) CleanupDeadConnections() int {
 			healthyConnections = append(healthyConnections, conn)
 		} else {
 			conn.Close()
+			p.unbindSessionsLocked(conn)
 			removedCount++
 		}
 	}
 
 	p.connections = healthyConnections
+	p.pruneSessionsLocked()
 	return removedCount
 }
 
@@ -133,6 +158,7 @@ func (p *TunnelConnectionPool) RemoveConnection(targetConn *TunnelConnection) {
 			conn.Close()
 			// Remove from slice
 			p.connections = append(p.connections[:i], p.connections[i+1:]...)
+			p.unbindSessionsLocked(conn)
 			break
 		}
 	}
@@ -148,6 +174,7 @@ func (p *TunnelConnectionPool) RemoveConnectionWithoutClosing(targetConn *Tunnel
 		if conn == targetConn {
 			// Remove from slice WITHOUT closing
 			p.connections = append(p.connections[:i], p.connections[i+1:]...)
+			p.unbindSessionsLocked(conn)
 			break
 		}
 	}
@@ -169,6 +196,7 @@ func (p *TunnelConnectionPool) Close() {
 		conn.Close()
 	}
 	p.connections = p.connections[:0]
+	p.sticky = nil
 }
 
 // DomainConnections holds both HTTP pool and WebSocket connections for a domain
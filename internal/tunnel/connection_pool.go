@@ -3,6 +3,7 @@ package tunnel
 import (
 	"fmt"
 	"net"
+	"strconv"
 	"sync"
 	"time"
 )
@@ -111,7 +112,7 @@ func (p *ConnectionPool) createConnection() (net.Conn, error) {
 		KeepAlive: 30 * time.Second, // Keep connections alive longer
 	}
 
-	addr := fmt.Sprintf("%s:%d", p.host, p.port)
+	addr := net.JoinHostPort(p.host, strconv.Itoa(p.port))
 	conn, err := dialer.Dial("tcp", addr)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to %s: %w", addr, err)
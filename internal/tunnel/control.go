@@ -0,0 +1,196 @@
+package tunnel
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/osa911/giraffecloud/internal/logging"
+)
+
+// ErrNoRunningInstance is returned when no running tunnel process answers on the control socket
+var ErrNoRunningInstance = errors.New("no running tunnel instance found")
+
+// ControlRequest is a single command sent to the running tunnel over the control socket
+type ControlRequest struct {
+	Command string `json:"command"`
+}
+
+// ControlResponse is the reply written back by the running tunnel
+type ControlResponse struct {
+	OK      bool            `json:"ok"`
+	Message string          `json:"message,omitempty"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+// ControlHandler handles a control command and returns optional data to send back
+type ControlHandler func() (interface{}, error)
+
+// ControlServer exposes a local unix socket that lets CLI commands talk to a running tunnel
+type ControlServer struct {
+	socketPath string
+	listener   net.Listener
+	handlers   map[string]ControlHandler
+	mu         sync.RWMutex
+	wg         sync.WaitGroup
+	logger     *logging.Logger
+}
+
+// GetControlSocketPath returns the path of the control socket next to the selected config
+func GetControlSocketPath() (string, error) {
+	dir, err := GetInstanceDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "control.sock"), nil
+}
+
+// NewControlServer creates a control server bound to the default socket path
+func NewControlServer() (*ControlServer, error) {
+	socketPath, err := GetControlSocketPath()
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve control socket path: %w", err)
+	}
+	return &ControlServer{
+		socketPath: socketPath,
+		handlers:   make(map[string]ControlHandler),
+		logger:     logging.GetGlobalLogger(),
+	}, nil
+}
+
+// Handle registers a handler for the given command name
+func (s *ControlServer) Handle(command string, handler ControlHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[command] = handler
+}
+
+// Start begins listening on the control socket
+func (s *ControlServer) Start() error {
+	if err := os.MkdirAll(filepath.Dir(s.socketPath), 0700); err != nil {
+		return fmt.Errorf("failed to create control socket directory: %w", err)
+	}
+
+	// A socket that still answers belongs to another running instance; anything else is stale
+	if conn, err := net.DialTimeout("unix", s.socketPath, time.Second); err == nil {
+		conn.Close()
+		return fmt.Errorf("control socket %s is in use by another tunnel instance", s.socketPath)
+	}
+	if err := os.Remove(s.socketPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove stale control socket: %w", err)
+	}
+
+	listener, err := net.Listen("unix", s.socketPath)
+	if err != nil {
+		return fmt.Errorf("failed to listen on control socket: %w", err)
+	}
+	// Restrict access to the current user
+	_ = os.Chmod(s.socketPath, 0600)
+	s.listener = listener
+
+	s.wg.Add(1)
+	go s.acceptLoop()
+
+	s.logger.Debug("Control socket listening on %s", s.socketPath)
+	return nil
+}
+
+// Stop closes the control socket and waits for in-flight commands to finish
+func (s *ControlServer) Stop() {
+	if s.listener == nil {
+		return
+	}
+	s.listener.Close()
+	s.wg.Wait()
+	_ = os.Remove(s.socketPath)
+}
+
+func (s *ControlServer) acceptLoop() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return // Listener closed
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handleConn(conn)
+		}()
+	}
+}
+
+func (s *ControlServer) handleConn(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(30 * time.Second))
+
+	var req ControlRequest
+	if err := json.NewDecoder(bufio.NewReader(conn)).Decode(&req); err != nil {
+		s.writeResponse(conn, &ControlResponse{OK: false, Message: fmt.Sprintf("invalid request: %v", err)})
+		return
+	}
+
+	s.mu.RLock()
+	handler, ok := s.handlers[req.Command]
+	s.mu.RUnlock()
+	if !ok {
+		s.writeResponse(conn, &ControlResponse{OK: false, Message: fmt.Sprintf("unknown command: %s", req.Command)})
+		return
+	}
+
+	s.logger.Info("Received control command: %s", req.Command)
+	data, err := handler()
+	if err != nil {
+		s.writeResponse(conn, &ControlResponse{OK: false, Message: err.Error()})
+		return
+	}
+
+	resp := &ControlResponse{OK: true}
+	if data != nil {
+		raw, err := json.Marshal(data)
+		if err != nil {
+			s.writeResponse(conn, &ControlResponse{OK: false, Message: fmt.Sprintf("failed to encode response: %v", err)})
+			return
+		}
+		resp.Data = raw
+	}
+	s.writeResponse(conn, resp)
+}
+
+func (s *ControlServer) writeResponse(conn net.Conn, resp *ControlResponse) {
+	if err := json.NewEncoder(conn).Encode(resp); err != nil {
+		s.logger.Debug("Failed to write control response: %v", err)
+	}
+}
+
+// SendControlCommand sends a command to the running tunnel and returns its response.
+// Returns ErrNoRunningInstance when no tunnel process is listening.
+func SendControlCommand(command string) (*ControlResponse, error) {
+	socketPath, err := GetControlSocketPath()
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve control socket path: %w", err)
+	}
+
+	conn, err := net.DialTimeout("unix", socketPath, 2*time.Second)
+	if err != nil {
+		return nil, ErrNoRunningInstance
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(30 * time.Second))
+
+	if err := json.NewEncoder(conn).Encode(&ControlRequest{Command: command}); err != nil {
+		return nil, fmt.Errorf("failed to send control command: %w", err)
+	}
+
+	var resp ControlResponse
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("failed to read control response: %w", err)
+	}
+	return &resp, nil
+}
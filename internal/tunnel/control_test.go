@@ -0,0 +1,106 @@
+package tunnel
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestControlServer_RoundTrip(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "gc-ctl")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	initTestLogger(t)
+
+	originalHome := os.Getenv("GIRAFFECLOUD_HOME")
+	os.Setenv("GIRAFFECLOUD_HOME", tempDir)
+	defer os.Setenv("GIRAFFECLOUD_HOME", originalHome)
+
+	// No server yet
+	if _, err := SendControlCommand("reload"); !errors.Is(err, ErrNoRunningInstance) {
+		t.Fatalf("Expected ErrNoRunningInstance, got %v", err)
+	}
+
+	server, err := NewControlServer()
+	if err != nil {
+		t.Fatalf("Failed to create control server: %v", err)
+	}
+	server.Handle("reload", func() (interface{}, error) {
+		return []string{"streaming"}, nil
+	})
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start control server: %v", err)
+	}
+	defer server.Stop()
+
+	// A second instance must not take over the live socket
+	second, err := NewControlServer()
+	if err != nil {
+		t.Fatalf("Failed to create control server: %v", err)
+	}
+	if err := second.Start(); err == nil {
+		second.Stop()
+		t.Fatal("Second control server started over a live socket")
+	}
+
+	resp, err := SendControlCommand("reload")
+	if err != nil {
+		t.Fatalf("SendControlCommand failed: %v", err)
+	}
+	if !resp.OK {
+		t.Fatalf("Expected OK response, got %q", resp.Message)
+	}
+	var applied []string
+	if err := json.Unmarshal(resp.Data, &applied); err != nil || len(applied) != 1 || applied[0] != "streaming" {
+		t.Errorf("Unexpected response data: %s (%v)", resp.Data, err)
+	}
+
+	resp, err = SendControlCommand("bogus")
+	if err != nil {
+		t.Fatalf("SendControlCommand failed: %v", err)
+	}
+	if resp.OK {
+		t.Error("Expected unknown command to fail")
+	}
+}
+
+func TestReloadPartialRetryConfig(t *testing.T) {
+	initTestLogger(t)
+
+	var cfg Config
+	if err := json.Unmarshal([]byte(`{"retry": {"max_retries": 5}, "streaming": {"pool_size": 4}}`), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	defaults := DefaultRetryConfig()
+	if cfg.Retry.MaxRetries != 5 || cfg.Retry.InitialDelay != defaults.InitialDelay || cfg.Retry.HealthCheckInterval != defaults.HealthCheckInterval {
+		t.Errorf("partial retry section lost its defaults: %+v", cfg.Retry)
+	}
+	if cfg.Streaming.PoolSize != 4 || cfg.Streaming.CleanupInterval != DefaultStreamingConfig().CleanupInterval {
+		t.Errorf("partial streaming section lost its defaults: %+v", cfg.Streaming)
+	}
+
+	t.Setenv("GIRAFFECLOUD_HOME", t.TempDir())
+	tun := NewTunnel()
+	applied := tun.ApplyReloadableConfig(&cfg)
+	if len(applied) != 2 || tun.retryConfig.Load().MaxRetries != 5 {
+		t.Fatalf("expected streaming and retry applied, got %v", applied)
+	}
+
+	// Explicit zero intervals are rejected, keeping the running settings
+	for _, retry := range []string{`{"health_check_interval": 0}`, `{"initial_delay": -1}`} {
+		cfg := Config{}
+		if err := json.Unmarshal([]byte(`{"retry": `+retry+`}`), &cfg); err != nil {
+			t.Fatal(err)
+		}
+		if cfg.Retry.Validate() == nil {
+			t.Errorf("%s: expected a validation error", retry)
+		}
+		if applied := tun.ApplyReloadableConfig(&cfg); len(applied) != 0 || tun.retryConfig.Load().MaxRetries != 5 {
+			t.Errorf("%s: invalid retry section applied: %v", retry, applied)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package tunnel
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// DefaultServerDialTimeout bounds connecting to the tunnel server
+const DefaultServerDialTimeout = 10 * time.Second
+
+// ServerDialer connects to the tunnel server, resolving its host through static
+// overrides (host → IP) or a specific DNS server instead of the system resolver, and
+// optionally through an HTTP(S) or SOCKS5 proxy. TLS still verifies the certificate
+// against the original host name.
+type ServerDialer struct {
+	Overrides map[string]string // Host → IP, checked before any DNS lookup
+	DNSServer string            // host:port of a DNS server to resolve through (empty = system resolver)
+	Proxy     string            // Proxy URL (http, https, socks5, socks5h); empty = proxy environment variables, "direct" = none
+	Timeout   time.Duration
+}
+
+// NewServerDialer returns a dialer for the DNS and proxy settings in cfg, or nil if there
+// are none (a nil dialer still honours the proxy environment variables)
+func NewServerDialer(cfg *Config) *ServerDialer {
+	if len(cfg.DNSOverride) == 0 && cfg.DNSServer == "" && cfg.Proxy == "" {
+		return nil
+	}
+	overrides := make(map[string]string, len(cfg.DNSOverride))
+	for host, ip := range cfg.DNSOverride {
+		overrides[strings.ToLower(host)] = ip
+	}
+	return &ServerDialer{Overrides: overrides, DNSServer: cfg.DNSServer, Proxy: cfg.Proxy}
+}
+
+// netDialer returns the dialer used for the TCP connection
+func (d *ServerDialer) netDialer() *net.Dialer {
+	timeout := DefaultServerDialTimeout
+	if d != nil && d.Timeout > 0 {
+		timeout = d.Timeout
+	}
+	dialer := &net.Dialer{Timeout: timeout}
+	if d != nil && d.DNSServer != "" {
+		dnsServer := d.DNSServer
+		if _, _, err := net.SplitHostPort(dnsServer); err != nil {
+			dnsServer = net.JoinHostPort(dnsServer, "53")
+		}
+		dialer.Resolver = &net.Resolver{
+			PreferGo: true, // Required for Dial to be used
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				return (&net.Dialer{Timeout: 5 * time.Second}).DialContext(ctx, network, dnsServer)
+			},
+		}
+	}
+	return dialer
+}
+
+// resolveAddr applies the static overrides to addr (host:port)
+func (d *ServerDialer) resolveAddr(addr string) (string, error) {
+	if d == nil || len(d.Overrides) == 0 {
+		return addr, nil
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", fmt.Errorf("invalid server address %q: %w", addr, err)
+	}
+	if ip, ok := d.Overrides[strings.ToLower(host)]; ok {
+		return net.JoinHostPort(ip, port), nil
+	}
+	return addr, nil
+}
+
+// DialContext opens a TCP connection to addr, through the proxy if one applies. A nil
+// dialer uses the system resolver and the proxy environment variables.
+func (d *ServerDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	proxyURL, err := d.proxyFor(addr)
+	if err != nil {
+		return nil, err
+	}
+	target, err := d.resolveAddr(addr)
+	if err != nil {
+		return nil, err
+	}
+	if proxyURL != nil {
+		return d.dialProxy(ctx, network, target, proxyURL)
+	}
+	return d.netDialer().DialContext(ctx, network, target)
+}
+
+// DialTLS opens a TLS connection to addr, verifying the server against the host in addr
+// even when the connection goes to an overridden IP. A nil dialer uses the system resolver.
+func (d *ServerDialer) DialTLS(network, addr string, tlsConfig *tls.Config) (*tls.Conn, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid server address %q: %w", addr, err)
+	}
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{}
+	}
+	if tlsConfig.ServerName == "" {
+		tlsConfig = tlsConfig.Clone()
+		tlsConfig.ServerName = host
+	}
+
+	dialer := d.netDialer()
+	ctx, cancel := context.WithTimeout(context.Background(), dialer.Timeout)
+	defer cancel()
+
+	rawConn, err := d.DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+	conn := tls.Client(rawConn, tlsConfig)
+	if err := conn.HandshakeContext(ctx); err != nil {
+		rawConn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// ParseDNSOverrides parses "host=ip" (or curl-style "host:ip") entries into a map
+func ParseDNSOverrides(entries []string) (map[string]string, error) {
+	overrides := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		host, ip, ok := strings.Cut(entry, "=")
+		if !ok {
+			host, ip, ok = strings.Cut(entry, ":")
+		}
+		host, ip = strings.TrimSpace(host), strings.TrimSpace(ip)
+		if !ok || host == "" || net.ParseIP(ip) == nil {
+			return nil, fmt.Errorf("invalid DNS override %q, expected host=ip", entry)
+		}
+		overrides[strings.ToLower(host)] = ip
+	}
+	return overrides, nil
+}
@@ -0,0 +1,204 @@
+package tunnel
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/net/http/httpproxy"
+	"golang.org/x/net/proxy"
+)
+
+// ProxyDirect as ServerDialer.Proxy (or --proxy) connects directly, ignoring proxy variables
+const ProxyDirect = "direct"
+
+// ProxyFromEnvironment returns the proxy for a TLS connection to addr (host:port) from the
+// environment: HTTPS_PROXY, else ALL_PROXY, else HTTP_PROXY (or their lowercase forms),
+// skipping hosts listed in NO_PROXY and loopback addresses. Nil means connect directly.
+func ProxyFromEnvironment(addr string) (*url.URL, error) {
+	env := httpproxy.FromEnvironment()
+	for _, fallback := range []string{getenvAny("ALL_PROXY", "all_proxy"), env.HTTPProxy} {
+		if env.HTTPSProxy == "" {
+			env.HTTPSProxy = fallback
+		}
+	}
+	if env.HTTPSProxy == "" {
+		return nil, nil
+	}
+	proxyURL, err := env.ProxyFunc()(&url.URL{Scheme: "https", Host: addr})
+	if err != nil || proxyURL == nil {
+		return nil, err
+	}
+	return ParseProxyURL(proxyURL.String())
+}
+
+// ParseProxyURL parses a proxy setting: http://, https://, socks5:// (the client resolves
+// the server's name) or socks5h:// (the proxy does), with optional user:password@. A bare
+// host:port is an HTTP proxy. Ports default to 80/443 for HTTP proxies and 1080 for SOCKS.
+func ParseProxyURL(raw string) (*url.URL, error) {
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+	proxyURL, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL: %w", err)
+	}
+	proxyURL.Scheme = strings.ToLower(proxyURL.Scheme)
+	if proxyURL.Hostname() == "" {
+		return nil, fmt.Errorf("invalid proxy URL %q: missing host", raw)
+	}
+
+	defaultPort := ""
+	switch proxyURL.Scheme {
+	case "http":
+		defaultPort = "80"
+	case "https":
+		defaultPort = "443"
+	case "socks5", "socks5h":
+		defaultPort = "1080"
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q (use http, https, socks5 or socks5h)", proxyURL.Scheme)
+	}
+	if proxyURL.Port() == "" {
+		proxyURL.Host = net.JoinHostPort(proxyURL.Hostname(), defaultPort)
+	}
+	return proxyURL, nil
+}
+
+// proxyFor returns the proxy to reach addr through, or nil to connect directly
+func (d *ServerDialer) proxyFor(addr string) (*url.URL, error) {
+	setting := ""
+	if d != nil {
+		setting = strings.TrimSpace(d.Proxy)
+	}
+	switch {
+	case setting == "":
+		return ProxyFromEnvironment(addr)
+	case strings.EqualFold(setting, ProxyDirect):
+		return nil, nil
+	}
+	return ParseProxyURL(setting)
+}
+
+// dialProxy connects to addr through the proxy at proxyURL: a SOCKS5 CONNECT, or an HTTP
+// CONNECT tunnel for http(s) proxies
+func (d *ServerDialer) dialProxy(ctx context.Context, network, addr string, proxyURL *url.URL) (net.Conn, error) {
+	forward := d.netDialer()
+
+	if proxyURL.Scheme == "socks5" || proxyURL.Scheme == "socks5h" {
+		if proxyURL.Scheme == "socks5" {
+			// Resolve locally (through any DNS server setting); socks5h leaves it to the proxy
+			host, port, err := net.SplitHostPort(addr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid server address %q: %w", addr, err)
+			}
+			if net.ParseIP(host) == nil {
+				resolver := forward.Resolver
+				if resolver == nil {
+					resolver = net.DefaultResolver
+				}
+				ips, err := resolver.LookupIPAddr(ctx, host)
+				if err != nil {
+					return nil, err
+				}
+				addr = net.JoinHostPort(ips[0].IP.String(), port)
+			}
+		}
+		var auth *proxy.Auth
+		if user := proxyURL.User; user != nil {
+			password, _ := user.Password()
+			auth = &proxy.Auth{User: user.Username(), Password: password}
+		}
+		socks, err := proxy.SOCKS5("tcp", proxyURL.Host, auth, forward)
+		if err != nil {
+			return nil, err
+		}
+		conn, err := socks.(proxy.ContextDialer).DialContext(ctx, network, addr)
+		if err != nil {
+			return nil, fmt.Errorf("SOCKS proxy %s: %w", proxyURL.Host, err)
+		}
+		return conn, nil
+	}
+
+	return dialHTTPConnect(ctx, forward, proxyURL, addr)
+}
+
+// dialHTTPConnect opens a tunnel to addr with an HTTP CONNECT request to the proxy
+func dialHTTPConnect(ctx context.Context, forward *net.Dialer, proxyURL *url.URL, addr string) (net.Conn, error) {
+	conn, err := forward.DialContext(ctx, "tcp", proxyURL.Host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to proxy %s: %w", proxyURL.Host, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if proxyURL.Scheme == "https" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: proxyURL.Hostname()})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("TLS handshake with proxy %s: %w", proxyURL.Host, err)
+		}
+		conn = tlsConn
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	if user := proxyURL.User; user != nil {
+		password, _ := user.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(user.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to send CONNECT to proxy %s: %w", proxyURL.Host, err)
+	}
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to read CONNECT response from proxy %s: %w", proxyURL.Host, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("proxy %s refused CONNECT to %s: %s", proxyURL.Host, addr, resp.Status)
+	}
+
+	conn.SetDeadline(time.Time{})
+	if reader.Buffered() > 0 {
+		return &bufferedProxyConn{Conn: conn, reader: reader}, nil
+	}
+	return conn, nil
+}
+
+// bufferedProxyConn hands out bytes the proxy sent right after its CONNECT response first
+type bufferedProxyConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *bufferedProxyConn) Read(p []byte) (int, error) {
+	return c.reader.Read(p)
+}
+
+func getenvAny(names ...string) string {
+	for _, name := range names {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,147 @@
+package tunnel
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseDNSOverrides(t *testing.T) {
+	overrides, err := ParseDNSOverrides([]string{"Tunnel.Example.com=10.0.0.5", "other.example.com:2001:db8::1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := overrides["tunnel.example.com"]; got != "10.0.0.5" {
+		t.Errorf("tunnel.example.com = %q, want 10.0.0.5", got)
+	}
+	if got := overrides["other.example.com"]; got != "2001:db8::1" {
+		t.Errorf("other.example.com = %q, want 2001:db8::1", got)
+	}
+
+	for _, entry := range []string{"tunnel.example.com", "=10.0.0.5", "tunnel.example.com=not-an-ip"} {
+		if _, err := ParseDNSOverrides([]string{entry}); err == nil {
+			t.Errorf("ParseDNSOverrides(%q): expected an error", entry)
+		}
+	}
+}
+
+func TestServerDialerOverride(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		if conn, err := listener.Accept(); err == nil {
+			conn.Close()
+		}
+	}()
+
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	d := NewServerDialer(&Config{DNSOverride: map[string]string{"Tunnel.Invalid": "127.0.0.1"}})
+	conn, err := d.DialContext(context.Background(), "tcp", net.JoinHostPort("tunnel.invalid", port))
+	if err != nil {
+		t.Fatalf("dial through override: %v", err)
+	}
+	conn.Close()
+
+	if NewServerDialer(&Config{}) != nil {
+		t.Error("expected no dialer without DNS settings")
+	}
+}
+
+func TestServerDialerHTTPProxy(t *testing.T) {
+	backend, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backend.Close()
+	go func() {
+		if conn, err := backend.Accept(); err == nil {
+			conn.Write([]byte("hello"))
+			conn.Close()
+		}
+	}()
+
+	// A CONNECT proxy that requires credentials and tunnels to the requested address
+	connects := make(chan string, 1)
+	proxyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect || r.Header.Get("Proxy-Authorization") != "Basic "+base64.StdEncoding.EncodeToString([]byte("alice:secret")) {
+			w.WriteHeader(http.StatusProxyAuthRequired)
+			return
+		}
+		connects <- r.Host
+		upstream, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		conn, _, _ := w.(http.Hijacker).Hijack()
+		conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+		go io.Copy(upstream, conn)
+		io.Copy(conn, upstream)
+		conn.Close()
+	}))
+	defer proxyServer.Close()
+
+	_, port, _ := net.SplitHostPort(backend.Addr().String())
+	proxyHost := strings.TrimPrefix(proxyServer.URL, "http://")
+	t.Setenv("HTTPS_PROXY", "http://alice:secret@"+proxyHost)
+	t.Setenv("NO_PROXY", "")
+
+	// A nil dialer picks the proxy up from the environment; the override is applied first
+	d := &ServerDialer{Overrides: map[string]string{"tunnel.invalid": "127.0.0.1"}}
+	conn, err := d.DialContext(context.Background(), "tcp", net.JoinHostPort("tunnel.invalid", port))
+	if err != nil {
+		t.Fatalf("dial through proxy: %v", err)
+	}
+	data, _ := io.ReadAll(conn)
+	conn.Close()
+	if string(data) != "hello" {
+		t.Errorf("read %q through the proxy, want hello", data)
+	}
+	if got := <-connects; got != backend.Addr().String() {
+		t.Errorf("CONNECT %s, want %s", got, backend.Addr())
+	}
+
+	// Wrong credentials are reported, not silently bypassed
+	d.Proxy = "http://alice:wrong@" + proxyHost
+	if _, err := d.DialContext(context.Background(), "tcp", net.JoinHostPort("tunnel.invalid", port)); err == nil || !strings.Contains(err.Error(), "407") {
+		t.Errorf("expected a 407 refusal, got %v", err)
+	}
+
+	// NO_PROXY and "direct" skip the proxy
+	t.Setenv("NO_PROXY", ".invalid")
+	if proxyURL, _ := (*ServerDialer)(nil).proxyFor("tunnel.invalid:443"); proxyURL != nil {
+		t.Errorf("NO_PROXY host routed through %s", proxyURL)
+	}
+	if proxyURL, _ := (&ServerDialer{Proxy: ProxyDirect}).proxyFor("example.com:443"); proxyURL != nil {
+		t.Errorf("direct routed through %s", proxyURL)
+	}
+}
+
+func TestParseProxyURL(t *testing.T) {
+	tests := []struct{ raw, want string }{
+		{"proxy.corp:3128", "http://proxy.corp:3128"},
+		{"http://proxy.corp", "http://proxy.corp:80"},
+		{"HTTPS://proxy.corp", "https://proxy.corp:443"},
+		{"socks5://user:pw@127.0.0.1", "socks5://user:pw@127.0.0.1:1080"},
+		{"socks5h://[::1]:9050", "socks5h://[::1]:9050"},
+	}
+	for _, tt := range tests {
+		got, err := ParseProxyURL(tt.raw)
+		if err != nil || got.String() != tt.want {
+			t.Errorf("ParseProxyURL(%q) = %v, %v; want %s", tt.raw, got, err, tt.want)
+		}
+	}
+	for _, raw := range []string{"ftp://proxy.corp", "http://", "socks4://proxy.corp"} {
+		if _, err := ParseProxyURL(raw); err == nil {
+			t.Errorf("ParseProxyURL(%q): expected an error", raw)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package tunnel
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DuplicateStreamPolicy decides what happens when a handshake arrives for a domain that
+// already has a connected gRPC stream
+type DuplicateStreamPolicy string
+
+const (
+	// DuplicateStreamReplace closes the older stream (the default): it usually belongs to a
+	// client whose connection died without the server noticing yet
+	DuplicateStreamReplace DuplicateStreamPolicy = "replace"
+	// DuplicateStreamReject refuses the new handshake with ALREADY_CONNECTED while the older
+	// stream is connected
+	DuplicateStreamReject DuplicateStreamPolicy = "reject"
+)
+
+// ParseDuplicateStreamPolicy parses "replace" or "reject" ("" = replace)
+func ParseDuplicateStreamPolicy(value string) (DuplicateStreamPolicy, error) {
+	switch policy := DuplicateStreamPolicy(strings.ToLower(strings.TrimSpace(value))); policy {
+	case "", DuplicateStreamReplace:
+		return DuplicateStreamReplace, nil
+	case DuplicateStreamReject:
+		return policy, nil
+	}
+	return "", fmt.Errorf("invalid duplicate stream policy %q: expected replace or reject", value)
+}
+
+// errStreamSuperseded ends a stream replaced by a newer handshake for its domain
+var errStreamSuperseded = status.Error(codes.Aborted, "tunnel replaced by a newer connection for the same domain")
+
+// connectedStream returns the domain's stream if it is still connected
+func (s *GRPCTunnelServer) connectedStream(domain string) *TunnelStream {
+	s.tunnelStreamsMux.RLock()
+	defer s.tunnelStreamsMux.RUnlock()
+	if ts, ok := s.tunnelStreams[domain]; ok && ts.connected {
+		return ts
+	}
+	return nil
+}
+
+// registerTunnelStream makes tunnelStream the one requests for its domain go to. An older
+// stream for the domain is told to close, so requests never go to a stale stream.
+func (s *GRPCTunnelServer) registerTunnelStream(tunnelStream *TunnelStream, peer string) {
+	s.tunnelStreamsMux.Lock()
+	previous := s.tunnelStreams[tunnelStream.Domain]
+	s.tunnelStreams[tunnelStream.Domain] = tunnelStream
+	s.tunnelStreamsMux.Unlock()
+
+	if previous != nil && previous != tunnelStream {
+		s.logger.Warn("[TAKEOVER] New connection from %s takes over tunnel %s, closing the older stream (connected: %v, idle %v)",
+			peer, tunnelStream.Domain, previous.connected, time.Since(previous.lastActivity).Round(time.Second))
+		previous.supersede()
+	}
+}
+
+// unregisterTunnelStream removes tunnelStream from the domain map, reporting false when a
+// newer stream has taken the domain over (its route and limits must then be left alone)
+func (s *GRPCTunnelServer) unregisterTunnelStream(tunnelStream *TunnelStream) bool {
+	s.tunnelStreamsMux.Lock()
+	defer s.tunnelStreamsMux.Unlock()
+	if s.tunnelStreams[tunnelStream.Domain] != tunnelStream {
+		return false
+	}
+	delete(s.tunnelStreams, tunnelStream.Domain)
+	return true
+}
+
+// supersede tells the stream's EstablishTunnel call to return
+func (ts *TunnelStream) supersede() {
+	if ts.superseded != nil {
+		ts.supersedeOnce.Do(func() { close(ts.superseded) })
+	}
+}
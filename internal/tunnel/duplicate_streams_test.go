@@ -0,0 +1,59 @@
+package tunnel
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/osa911/giraffecloud/internal/logging"
+)
+
+func TestDuplicateStreamTakeover(t *testing.T) {
+	initTestLogger(t)
+	s := &GRPCTunnelServer{logger: logging.GetGlobalLogger(), tunnelStreams: make(map[string]*TunnelStream)}
+	newStream := func() *TunnelStream {
+		return &TunnelStream{Domain: "app.example.com", Context: context.Background(), superseded: make(chan struct{}), connected: true, lastActivity: time.Now()}
+	}
+
+	older := newStream()
+	s.registerTunnelStream(older, "10.0.0.1")
+	if s.connectedStream("app.example.com") != older {
+		t.Fatal("first stream not registered")
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- s.monitorTunnelHealth(older) }()
+
+	newer := newStream()
+	s.registerTunnelStream(newer, "10.0.0.2")
+	select {
+	case err := <-done:
+		if err != errStreamSuperseded {
+			t.Errorf("older stream ended with %v, want errStreamSuperseded", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("older stream was not closed on takeover")
+	}
+
+	// The older stream's cleanup must not remove its replacement
+	if s.unregisterTunnelStream(older) {
+		t.Error("older stream unregistered the domain")
+	}
+	if s.connectedStream("app.example.com") != newer {
+		t.Fatal("newer stream lost the domain")
+	}
+	if !s.unregisterTunnelStream(newer) || s.connectedStream("app.example.com") != nil {
+		t.Error("newer stream not unregistered")
+	}
+}
+
+func TestParseDuplicateStreamPolicy(t *testing.T) {
+	for value, want := range map[string]DuplicateStreamPolicy{"": DuplicateStreamReplace, "replace": DuplicateStreamReplace, " Reject ": DuplicateStreamReject} {
+		if got, err := ParseDuplicateStreamPolicy(value); err != nil || got != want {
+			t.Errorf("ParseDuplicateStreamPolicy(%q) = %q, %v; want %q", value, got, err, want)
+		}
+	}
+	if _, err := ParseDuplicateStreamPolicy("both"); err == nil {
+		t.Error("invalid policy accepted")
+	}
+}
@@ -0,0 +1,36 @@
+package tunnel
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/joho/godotenv"
+)
+
+// LoadEnvFiles loads environment variables from path (when set, it must exist) and then
+// from .env in the config directory, if present. Variables already in the environment are
+// kept, so the shell wins over path, which wins over the config directory's file.
+// Returns the files that were loaded.
+func LoadEnvFiles(path string) ([]string, error) {
+	var loaded []string
+	if path != "" {
+		if err := godotenv.Load(path); err != nil {
+			return nil, fmt.Errorf("failed to load env file %s: %w", path, err)
+		}
+		loaded = append(loaded, path)
+	}
+
+	dir, err := GetConfigDir()
+	if err != nil {
+		return loaded, nil
+	}
+	defaultPath := filepath.Join(dir, ".env")
+	if _, err := os.Stat(defaultPath); err != nil {
+		return loaded, nil
+	}
+	if err := godotenv.Load(defaultPath); err != nil {
+		return loaded, fmt.Errorf("failed to load env file %s: %w", defaultPath, err)
+	}
+	return append(loaded, defaultPath), nil
+}
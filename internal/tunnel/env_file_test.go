@@ -0,0 +1,42 @@
+package tunnel
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadEnvFiles(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("GIRAFFECLOUD_HOME", home)
+	for _, key := range []string{"GC_TEST_SHELL", "GC_TEST_FILE", "GC_TEST_HOME"} {
+		t.Setenv(key, "")
+		os.Unsetenv(key)
+	}
+	t.Setenv("GC_TEST_SHELL", "shell")
+
+	explicit := filepath.Join(t.TempDir(), "tunnel.env")
+	if err := os.WriteFile(explicit, []byte("GC_TEST_SHELL=file\nGC_TEST_FILE=file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, ".env"), []byte("GC_TEST_FILE=home\nGC_TEST_HOME=home\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadEnvFiles(explicit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded) != 2 {
+		t.Fatalf("loaded %v, want both files", loaded)
+	}
+	for key, want := range map[string]string{"GC_TEST_SHELL": "shell", "GC_TEST_FILE": "file", "GC_TEST_HOME": "home"} {
+		if got := os.Getenv(key); got != want {
+			t.Errorf("%s = %q, want %q", key, got, want)
+		}
+	}
+
+	if _, err := LoadEnvFiles(filepath.Join(home, "missing.env")); err == nil {
+		t.Error("missing --env-file accepted")
+	}
+}
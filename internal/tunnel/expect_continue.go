@@ -0,0 +1,65 @@
+package tunnel
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// continueResponse is the interim response that tells a client to send its request body
+const continueResponse = "HTTP/1.1 100 Continue\r\n\r\n"
+
+// expectContinueReader sends "100 Continue" to the client the first time the request body
+// is read. Requests answered without reading the body (errors, early rejections) never
+// get it, so the client can skip sending the body as RFC 9110 intends.
+type expectContinueReader struct {
+	mu    sync.Mutex
+	conn  io.Writer
+	body  io.ReadCloser
+	state int // 0 = not sent yet, 1 = sent, 2 = final response started, never send
+}
+
+func (e *expectContinueReader) Read(p []byte) (int, error) {
+	e.mu.Lock()
+	if e.state == 0 {
+		e.state = 1
+		if _, err := io.WriteString(e.conn, continueResponse); err != nil {
+			e.mu.Unlock()
+			return 0, err
+		}
+	}
+	e.mu.Unlock()
+	return e.body.Read(p)
+}
+
+func (e *expectContinueReader) Close() error {
+	return e.body.Close()
+}
+
+// stop prevents a late "100 Continue" once the final response is being written
+func (e *expectContinueReader) stop() {
+	e.mu.Lock()
+	if e.state == 0 {
+		e.state = 2
+	}
+	e.mu.Unlock()
+}
+
+// expectContinue handles "Expect: 100-continue": the header is dropped (the router owns
+// the client connection, so the origin must not wait for the body on its own) and the
+// body is wrapped to send the interim response when the tunnel starts streaming it.
+// Call the returned function before writing the final response.
+func (r *HybridTunnelRouter) expectContinue(conn io.Writer, req *http.Request) func() {
+	if !strings.EqualFold(strings.TrimSpace(req.Header.Get("Expect")), "100-continue") {
+		return func() {}
+	}
+	req.Header.Del("Expect")
+	if req.Body == nil || req.Body == http.NoBody {
+		return func() {}
+	}
+
+	body := &expectContinueReader{conn: conn, body: req.Body}
+	req.Body = body
+	return body.stop
+}
@@ -0,0 +1,86 @@
+package tunnel
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExpectContinueUpload(t *testing.T) {
+	r := &HybridTunnelRouter{config: &HybridRouterConfig{}}
+	routerConn, clientConn := net.Pipe()
+	defer routerConn.Close()
+	defer clientConn.Close()
+
+	// Like curl: send the headers, then hold the body back until "100 Continue" arrives
+	const payload = "large upload body"
+	head := "PUT /upload HTTP/1.1\r\nHost: app.example.com\r\nContent-Length: 17\r\nExpect: 100-continue\r\n\r\n"
+	bodyReader, bodyWriter := io.Pipe()
+	clientErr := make(chan error, 1)
+	go func() {
+		line, err := bufio.NewReader(clientConn).ReadString('\n')
+		if err != nil {
+			clientErr <- err
+			return
+		}
+		if !strings.HasPrefix(line, "HTTP/1.1 100 Continue") {
+			clientErr <- io.ErrUnexpectedEOF
+			return
+		}
+		io.WriteString(bodyWriter, payload)
+		bodyWriter.Close()
+		clientErr <- nil
+	}()
+
+	req, err := r.parseHTTPRequest([]byte(head), bodyReader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop := r.expectContinue(routerConn, req)
+	if req.Header.Get("Expect") != "" {
+		t.Error("Expect header should not be forwarded to the origin")
+	}
+
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop()
+	if string(body) != payload {
+		t.Errorf("body = %q, want %q", body, payload)
+	}
+	if err := <-clientErr; err != nil {
+		t.Fatalf("client did not get 100 Continue: %v", err)
+	}
+}
+
+func TestExpectContinueNotSentAfterFinalResponse(t *testing.T) {
+	r := &HybridTunnelRouter{config: &HybridRouterConfig{}}
+	routerConn, clientConn := net.Pipe()
+	defer routerConn.Close()
+	defer clientConn.Close()
+
+	head := "POST /upload HTTP/1.1\r\nHost: app.example.com\r\nContent-Length: 4\r\nExpect: 100-continue\r\n\r\n"
+	req, err := r.parseHTTPRequest([]byte(head), strings.NewReader("body"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Rejected before the body was read: the client must not be told to continue
+	stop := r.expectContinue(routerConn, req)
+	stop()
+	done := make(chan struct{})
+	go func() {
+		io.ReadAll(req.Body)
+		close(done)
+	}()
+
+	clientConn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	if n, _ := clientConn.Read(make([]byte, 64)); n > 0 {
+		t.Error("100 Continue was sent after the final response started")
+	}
+	<-done
+}
@@ -1,6 +1,8 @@
 package tunnel
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -85,7 +87,7 @@ func (s *GRPCTunnelServer) handleChunkedStreaming(
 	s.logger.Debug("[CHUNKED] Forwarding request to client: %s %s", httpReq.Method, httpReq.Path)
 
 	// Use existing request/response mechanism to get the full response
-	response, err := s.sendRequestAndWaitResponse(tunnelStream, tunnelMsg)
+	response, err := s.sendRequestAndWaitResponse(stream.Context(), tunnelStream, tunnelMsg)
 	if err != nil {
 		s.logger.Error("[CHUNKED] Failed to get response from tunnel: %v", err)
 		return fmt.Errorf("tunnel request failed: %w", err)
@@ -215,6 +217,9 @@ func (s *GRPCTunnelServer) isLargeFileRequest(httpReq *http.Request) bool {
 			return size > ChunkedStreamingThreshold // >16MB
 		}
 	}
+	if s.disableMediaOptimization {
+		return false
+	}
 
 	// 2. Second priority: File extensions that are typically large (>16MB)
 	path := strings.ToLower(httpReq.URL.Path)
@@ -262,6 +267,11 @@ func (s *GRPCTunnelServer) isLargeFileRequest(httpReq *http.Request) bool {
 	return false
 }
 
+// isRangeRequest reports whether httpReq is a GET for part of a resource (Range header)
+func isRangeRequest(httpReq *http.Request) bool {
+	return httpReq.Method == http.MethodGet && httpReq.Header.Get("Range") != ""
+}
+
 // estimateResponseSize estimates the expected response size based on request characteristics
 func (s *GRPCTunnelServer) estimateResponseSize(httpReq *http.Request) int64 {
 	// Check if we have a content-length header
@@ -291,22 +301,119 @@ func (s *GRPCTunnelServer) estimateResponseSize(httpReq *http.Request) int64 {
 	return 1024 * 1024 // 1MB
 }
 
+// ErrPayloadTooLarge is returned when a streamed upload exceeds the configured MaxUploadBytes
+var ErrPayloadTooLarge = errors.New("request body exceeds maximum upload size")
+
+// abortUpload tells the client to drop an in-flight upload and forgets its response channel
+func (s *GRPCTunnelServer) abortUpload(tunnelStream *TunnelStream, requestID string, code int32, reason string) {
+	tunnelStream.requestsMux.Lock()
+	delete(tunnelStream.pendingRequests, requestID)
+	tunnelStream.requestsMux.Unlock()
+
+	errMsg := &proto.TunnelMessage{
+		RequestId: requestID,
+		Timestamp: time.Now().Unix(),
+		MessageType: &proto.TunnelMessage_Error{
+			Error: &proto.ErrorMessage{
+				Code:      code,
+				Message:   reason,
+				Retryable: false,
+			},
+		},
+	}
+	tunnelStream.sendMux.Lock()
+	err := tunnelStream.Stream.Send(errMsg)
+	tunnelStream.sendMux.Unlock()
+	if err != nil {
+		s.logger.Warn("[CHUNKED UPLOAD] Failed to notify client about aborted upload %s: %v", requestID, err)
+	}
+}
+
+// cancelUpload forgets an upload that won't complete (the visitor went away mid-body, or
+// the stream failed) and tells the client to stop feeding it to the local service
+func (s *GRPCTunnelServer) cancelUpload(tunnelStream *TunnelStream, requestID, reason string) {
+	tunnelStream.requestsMux.Lock()
+	delete(tunnelStream.pendingRequests, requestID)
+	tunnelStream.requestsMux.Unlock()
+	s.sendCancel(tunnelStream, requestID, reason)
+}
+
+// sendCancel tells the client to stop working on requestID, over the control channel
+// when the client has one (delivered ahead of queued data) or the data stream otherwise
+func (s *GRPCTunnelServer) sendCancel(tunnelStream *TunnelStream, requestID, reason string) {
+	tunnelStream.controlMux.RLock()
+	controlStream := tunnelStream.ControlStream
+	tunnelStream.controlMux.RUnlock()
+
+	cancelMsg := &proto.CancelRequest{
+		RequestId: requestID,
+		Reason:    reason,
+		Timestamp: time.Now().Unix(),
+	}
+
+	if controlStream != nil {
+		controlMsg := &proto.ControlMessage{
+			RequestId: requestID,
+			Timestamp: time.Now().Unix(),
+			MessageType: &proto.ControlMessage_Cancel{
+				Cancel: cancelMsg,
+			},
+		}
+		if err := controlStream.Send(controlMsg); err != nil {
+			s.logger.Debug("[CHUNKED] ⚠️ Control channel send failed: %v", err)
+		} else {
+			s.logger.Debug("[CHUNKED] ✅ Cancel sent via CONTROL CHANNEL (instant)")
+		}
+		return
+	}
+
+	// Fallback to data channel (backward compatibility with old clients)
+	s.logger.Debug("[CHUNKED] ℹ️ Control channel not available, using data channel (may be delayed)")
+	dataMsg := &proto.TunnelMessage{
+		RequestId: requestID,
+		Timestamp: time.Now().Unix(),
+		MessageType: &proto.TunnelMessage_Control{
+			Control: &proto.TunnelControl{
+				ControlType: &proto.TunnelControl_CancelRequest{
+					CancelRequest: cancelMsg,
+				},
+			},
+		},
+	}
+	tunnelStream.sendMux.Lock()
+	err := tunnelStream.Stream.Send(dataMsg)
+	tunnelStream.sendMux.Unlock()
+	if err != nil {
+		s.logger.Debug("[CHUNKED] Could not send cancel via data channel: %v", err)
+	}
+}
+
 // ProxyHTTPRequestWithChunking handles HTTP requests with intelligent routing
 // PERFECT BINARY SPLIT: ≤16MB = Regular gRPC (16MB), >16MB = Unlimited Chunked Streaming
 func (s *GRPCTunnelServer) ProxyHTTPRequestWithChunking(domain string, httpReq *http.Request, clientIP string) (*http.Response, error) {
 	// Always stream uploads via Start/Chunk/End to avoid 16MB gRPC limits
+	start := time.Now()
 	switch strings.ToUpper(httpReq.Method) {
 	case http.MethodPost, http.MethodPut, http.MethodPatch:
-		return s.handleLargeFileUploadWithStreaming(domain, httpReq, clientIP)
+		resp, err := s.handleLargeFileUploadWithStreaming(domain, httpReq, clientIP)
+		if err == nil {
+			s.latency.Observe(domain, time.Since(start))
+		}
+		return resp, err
 	}
 
-	// Check if this should use chunked streaming for unlimited size
-	if s.isLargeFileRequest(httpReq) {
+	// Check if this should use chunked streaming for unlimited size. Range requests come
+	// from media players seeking in large files, and a single range can exceed 16MB.
+	if isRangeRequest(httpReq) || s.isLargeFileRequest(httpReq) {
 		s.logger.Info("[CHUNKED] 🚀 Large file (>16MB) detected → UNLIMITED chunked streaming: %s %s",
 			httpReq.Method, httpReq.URL.Path)
 
 		// Download path: old LargeFileRequest flow
-		return s.handleLargeFileDownloadWithChunking(domain, httpReq, clientIP)
+		resp, err := s.handleLargeFileDownloadWithChunking(domain, httpReq, clientIP)
+		if err == nil {
+			s.latency.Observe(domain, time.Since(start)) // Time to response headers, not the whole download
+		}
+		return resp, err
 	}
 
 	// Small files: use regular gRPC (≤16MB, perfect alignment)
@@ -327,6 +434,12 @@ func (s *GRPCTunnelServer) handleLargeFileUploadWithStreaming(domain string, htt
 		return nil, fmt.Errorf("no active tunnel for domain: %s", domain)
 	}
 
+	// Reject early when the declared size is already over the limit
+	if s.maxUploadBytes > 0 && httpReq.ContentLength > s.maxUploadBytes {
+		s.logger.Warn("[CHUNKED UPLOAD] Rejecting upload for %s: Content-Length %d exceeds limit %d", domain, httpReq.ContentLength, s.maxUploadBytes)
+		return nil, fmt.Errorf("%w: %d > %d bytes", ErrPayloadTooLarge, httpReq.ContentLength, s.maxUploadBytes)
+	}
+
 	// Convert headers only; body will be streamed via Start/Chunk/End
 	// Build Start message directly
 	headers := make(map[string]string)
@@ -364,6 +477,7 @@ func (s *GRPCTunnelServer) handleLargeFileUploadWithStreaming(domain string, htt
 	err := tunnelStream.Stream.Send(startMsg)
 	tunnelStream.sendMux.Unlock()
 	if err != nil {
+		s.cancelUpload(tunnelStream, requestID, "upload_send_failed")
 		return nil, fmt.Errorf("failed to send upload start: %w", err)
 	}
 
@@ -381,6 +495,13 @@ func (s *GRPCTunnelServer) handleLargeFileUploadWithStreaming(domain string, htt
 				chunkCount++
 				totalBytes += int64(n)
 
+				// Enforce the upload limit as bytes flow through (Content-Length may be absent or wrong)
+				if s.maxUploadBytes > 0 && totalBytes > s.maxUploadBytes {
+					s.logger.Warn("[CHUNKED UPLOAD] ⛔ Upload %s: Exceeded limit of %d bytes, aborting", requestID, s.maxUploadBytes)
+					s.abortUpload(tunnelStream, requestID, http.StatusRequestEntityTooLarge, ErrPayloadTooLarge.Error())
+					return nil, fmt.Errorf("%w: more than %d bytes", ErrPayloadTooLarge, s.maxUploadBytes)
+				}
+
 				data := make([]byte, n)
 				copy(data, buf[:n])
 
@@ -411,6 +532,7 @@ func (s *GRPCTunnelServer) handleLargeFileUploadWithStreaming(domain string, htt
 				sendErr := tunnelStream.Stream.Send(chunkMsg)
 				tunnelStream.sendMux.Unlock()
 				if sendErr != nil {
+					s.cancelUpload(tunnelStream, requestID, "upload_send_failed")
 					return nil, fmt.Errorf("failed to send upload chunk: %w", sendErr)
 				}
 			}
@@ -420,6 +542,8 @@ func (s *GRPCTunnelServer) handleLargeFileUploadWithStreaming(domain string, htt
 				break
 			}
 			if er != nil {
+				// Usually the visitor aborting the upload: the local service must not wait for the rest
+				s.cancelUpload(tunnelStream, requestID, "downstream_disconnected")
 				return nil, fmt.Errorf("read upload body failed: %w", er)
 			}
 		}
@@ -437,13 +561,14 @@ func (s *GRPCTunnelServer) handleLargeFileUploadWithStreaming(domain string, htt
 	err = tunnelStream.Stream.Send(endMsg)
 	tunnelStream.sendMux.Unlock()
 	if err != nil {
+		s.cancelUpload(tunnelStream, requestID, "upload_send_failed")
 		return nil, fmt.Errorf("failed to send upload end: %w", err)
 	}
 
 	s.logger.Info("[CHUNKED UPLOAD] ⏳ Upload %s: Waiting for response...", requestID)
 
 	// Now collect chunked response using existing io.Pipe pathway without re-sending request
-	return s.collectChunkedResponseNoSend(tunnelStream, requestID, nil)
+	return s.collectChunkedResponseNoSend(httpReq.Context(), tunnelStream, requestID, nil, false)
 }
 
 // handleLargeFileDownloadWithChunking uses the old LargeFileRequest path for downloads
@@ -477,12 +602,53 @@ func (s *GRPCTunnelServer) handleLargeFileDownloadWithChunking(domain string, ht
 	s.logger.Debug("[CHUNKED] Sending large file request to client (download): %s", httpReq.URL.Path)
 
 	// Send large file request to client and collect chunked response (this method sends the HTTPRequest)
-	return s.collectChunkedResponse(tunnelStream, largeFileReq)
+	return s.collectChunkedResponse(httpReq.Context(), tunnelStream, largeFileReq)
+}
+
+// Defaults for GRPCTunnelConfig.ChunkCollectionTimeout and ChunkMetadataTimeout
+const (
+	DefaultChunkCollectionTimeout = 10 * time.Minute // Increased from 2 minutes for large file stability
+	DefaultChunkMetadataTimeout   = 60 * time.Second
+)
+
+// chunkTimeouts returns how long to wait for a chunked response's headers and for the
+// whole response (unless the origin asks for more), falling back to the defaults
+func (s *GRPCTunnelServer) chunkTimeouts() (metadata, collection time.Duration) {
+	metadata, collection = DefaultChunkMetadataTimeout, DefaultChunkCollectionTimeout
+	if s.config.ChunkMetadataTimeout > 0 {
+		metadata = s.config.ChunkMetadataTimeout
+	}
+	if s.config.ChunkCollectionTimeout > 0 {
+		collection = s.config.ChunkCollectionTimeout
+	}
+	return metadata, collection
 }
 
-// collectChunkedResponse sends large file request to client and streams response with minimal memory usage
-func (s *GRPCTunnelServer) collectChunkedResponse(tunnelStream *TunnelStream, req *proto.LargeFileRequest) (*http.Response, error) {
+// applyOriginTimeout extends the collection timer (running for limit) when the first chunk
+// carries OriginTimeoutHeader, and returns the limit now in effect
+func (s *GRPCTunnelServer) applyOriginTimeout(domain string, chunk *proto.HTTPResponse, timer *time.Timer, limit time.Duration) time.Duration {
+	value := originTimeoutValue(chunk.Headers)
+	if value == "" {
+		return limit
+	}
+	extended, err := parseOriginTimeout(value, s.maxOriginTimeout)
+	if err != nil {
+		s.logger.Warn("[CHUNKED] Ignoring %s from %s: %v", OriginTimeoutHeader, domain, err)
+		return limit
+	}
+	if extended <= limit {
+		return limit
+	}
+	timer.Reset(extended)
+	s.logger.Debug("[CHUNKED] %s extended the response timeout to %v", domain, extended)
+	return extended
+}
+
+// collectChunkedResponse sends large file request to client and streams response with minimal memory usage.
+// Cancelling ctx (the visitor went away, or its deadline passed) cancels the request on the client.
+func (s *GRPCTunnelServer) collectChunkedResponse(ctx context.Context, tunnelStream *TunnelStream, req *proto.LargeFileRequest) (*http.Response, error) {
 	s.logger.Debug("[CHUNKED] 📦 Starting MEMORY-EFFICIENT chunk collection for request: %s", req.RequestId)
+	metadataTimeout, collectionTimeout := s.chunkTimeouts()
 
 	// Create response channel and register it in pendingRequests (CRITICAL!)
 	responseChan := make(chan *proto.TunnelMessage, 250) // Larger buffer for faster streaming
@@ -523,6 +689,7 @@ func (s *GRPCTunnelServer) collectChunkedResponse(tunnelStream *TunnelStream, re
 	// Channel to receive response metadata from first chunk
 	metadataCh := make(chan *proto.HTTPResponse, 1)
 	errorCh := make(chan error, 1)
+	final := &streamTrailers{}
 
 	// Start goroutine to collect chunks and stream them directly to pipe
 	go func() {
@@ -549,14 +716,22 @@ func (s *GRPCTunnelServer) collectChunkedResponse(tunnelStream *TunnelStream, re
 
 		var firstChunk *proto.HTTPResponse
 		chunkCount := 0
+		reorder := newChunkReorderer()
 
 		// Set timeout for chunk collection (generous timeout for large files - activity tracking prevents tunnel timeout)
-		timeout := time.After(10 * time.Minute) // Increased from 2 minutes for large file stability
+		limit := collectionTimeout
+		timeout := time.NewTimer(limit)
+		defer timeout.Stop()
 
 		for {
 			select {
-			case <-timeout:
-				errorCh <- fmt.Errorf("timeout waiting for chunked response after 10 minutes")
+			case <-timeout.C:
+				errorCh <- fmt.Errorf("timeout waiting for chunked response after %v", limit)
+				return
+
+			case <-ctx.Done():
+				s.sendCancel(tunnelStream, req.RequestId, "downstream_cancelled")
+				errorCh <- fmt.Errorf("request cancelled: %w", ctx.Err())
 				return
 
 			case response, ok := <-responseChan:
@@ -581,90 +756,55 @@ func (s *GRPCTunnelServer) collectChunkedResponse(tunnelStream *TunnelStream, re
 					// Store the first chunk for headers and status
 					if firstChunk == nil {
 						firstChunk = chunk
+						limit = s.applyOriginTimeout(tunnelStream.Domain, chunk, timeout, limit)
 						metadataCh <- chunk
 						s.logger.Debug("[CHUNKED] 📋 Response metadata: status=%d, content-type=%s",
 							chunk.StatusCode, chunk.Headers["Content-Type"])
 					}
 
-					// If this is a chunked response, stream chunks directly to pipe
+					// If this is a chunked response, stream chunks directly to pipe, in order
 					if chunk.IsChunked {
-						chunkCount++
+						ready, err := reorder.push(chunk)
+						if err != nil {
+							go s.sendCancel(tunnelStream, response.RequestId, "chunk_window_exceeded")
+							errorCh <- err
+							return
+						}
+						for _, chunk := range ready {
+							chunkCount++
 
-						if len(chunk.Body) > 0 {
-							// Track traffic for this chunk
-							if s.usage != nil && tunnelStream != nil {
-								s.usage.Increment(tunnelStream.UserID, tunnelStream.TunnelID, tunnelStream.Domain, 0, int64(len(chunk.Body)), 0)
-							}
+							if len(chunk.Body) > 0 {
+								// Track traffic for this chunk
+								if s.usage != nil && tunnelStream != nil {
+									s.usage.Increment(tunnelStream.UserID, tunnelStream.TunnelID, tunnelStream.Domain, 0, int64(len(chunk.Body)), 0)
+								}
 
-							// MEMORY EFFICIENT: Write chunk directly to pipe (no buffering)
-							if _, writeErr := pipeWriter.Write(chunk.Body); writeErr != nil {
-								// Client disconnected (broken pipe) - SEND CANCEL SIGNAL to stop client immediately
-								s.logger.Info("[CHUNKED] 🛑 Client disconnected, sending cancel signal to stop streaming")
-
-								// Send cancel via dedicated control channel (instant delivery!)
-								go func() {
-									// Try control channel first (no data blocking)
-									tunnelStream.controlMux.RLock()
-									controlStream := tunnelStream.ControlStream
-									tunnelStream.controlMux.RUnlock()
-
-									cancelMsg := &proto.CancelRequest{
-										RequestId: response.RequestId,
-										Reason:    "downstream_disconnected",
-										Timestamp: time.Now().Unix(),
-									}
-
-									if controlStream != nil {
-										// Control channel available - instant delivery!
-										controlMsg := &proto.ControlMessage{
-											RequestId: response.RequestId,
-											Timestamp: time.Now().Unix(),
-											MessageType: &proto.ControlMessage_Cancel{
-												Cancel: cancelMsg,
-											},
-										}
-										if sendErr := controlStream.Send(controlMsg); sendErr != nil {
-											s.logger.Debug("[CHUNKED] ⚠️ Control channel send failed: %v", sendErr)
-										} else {
-											s.logger.Debug("[CHUNKED] ✅ Cancel sent via CONTROL CHANNEL (instant)")
-										}
-									} else {
-										// Fallback to data channel (backward compatibility with old clients)
-										s.logger.Debug("[CHUNKED] ℹ️ Control channel not available, using data channel (may be delayed)")
-										dataMsg := &proto.TunnelMessage{
-											RequestId: response.RequestId,
-											Timestamp: time.Now().Unix(),
-											MessageType: &proto.TunnelMessage_Control{
-												Control: &proto.TunnelControl{
-													ControlType: &proto.TunnelControl_CancelRequest{
-														CancelRequest: cancelMsg,
-													},
-												},
-											},
-										}
-										if sendErr := tunnelStream.Stream.Send(dataMsg); sendErr != nil {
-											s.logger.Debug("[CHUNKED] Could not send cancel via data channel: %v", sendErr)
-										}
-									}
-								}()
-
-								errorCh <- fmt.Errorf("failed to write chunk to pipe: %w", writeErr)
-								return
-							}
+								// MEMORY EFFICIENT: Write chunk directly to pipe (no buffering)
+								if _, writeErr := pipeWriter.Write(chunk.Body); writeErr != nil {
+									// Client disconnected (broken pipe) - SEND CANCEL SIGNAL to stop client immediately
+									s.logger.Info("[CHUNKED] 🛑 Client disconnected, sending cancel signal to stop streaming")
 
-							s.logger.Debug("[CHUNKED] 📥 Streamed chunk %d (%d bytes) directly to pipe",
-								chunkCount, len(chunk.Body))
+									go s.sendCancel(tunnelStream, response.RequestId, "downstream_disconnected")
 
-							// Update activity for each chunk to keep tunnel alive during large transfers
-							tunnelStream.mu.Lock()
-							tunnelStream.lastActivity = time.Now()
-							tunnelStream.mu.Unlock()
-						}
+									errorCh <- fmt.Errorf("failed to write chunk to pipe: %w", writeErr)
+									return
+								}
 
-						// Check if this is the final chunk
-						if strings.HasSuffix(chunk.ChunkId, "_final") {
-							s.logger.Info("[CHUNKED] ✅ All %d chunks streamed directly (ZERO memory buffering)", chunkCount)
-							return // Close the pipe writer in defer
+								s.logger.Debug("[CHUNKED] 📥 Streamed chunk %d (%d bytes) directly to pipe",
+									chunkCount, len(chunk.Body))
+
+								// Update activity for each chunk to keep tunnel alive during large transfers
+								tunnelStream.mu.Lock()
+								tunnelStream.lastActivity = time.Now()
+								tunnelStream.mu.Unlock()
+							}
+
+							// Check if this is the final chunk
+							if strings.HasSuffix(chunk.ChunkId, "_final") {
+								final.values = chunk.Trailers
+								s.logger.Info("[CHUNKED] ✅ All %d chunks streamed directly (ZERO memory buffering)", chunkCount)
+								return // Close the pipe writer in defer
+							}
 						}
 					} else {
 						// Non-chunked response - write entire body and finish
@@ -710,8 +850,15 @@ func (s *GRPCTunnelServer) collectChunkedResponse(tunnelStream *TunnelStream, re
 			response.Header.Set(key, value)
 		}
 
-		// Remove Content-Length as we're streaming
-		response.Header.Del("Content-Length")
+		// Remove Content-Length as we're streaming, except for partial content: media
+		// players need the exact length of the range next to Content-Range
+		if length, ok := partialContentLength(response); ok {
+			response.ContentLength = length
+		} else {
+			response.Header.Del("Content-Length")
+		}
+		response.Header.Del(OriginTimeoutHeader)
+		applyTrailers(response, firstChunk, final)
 
 		s.logger.Info("[CHUNKED] 🚀 MEMORY-EFFICIENT streaming response created (no buffering)")
 		return response, nil
@@ -721,16 +868,31 @@ func (s *GRPCTunnelServer) collectChunkedResponse(tunnelStream *TunnelStream, re
 		s.logger.Warn("[CHUNKED] ❌ Chunked streaming failed: %v", err)
 		return nil, err
 
-	case <-time.After(60 * time.Second):
+	case <-time.After(metadataTimeout):
 		pipeReader.Close()
-		s.logger.Error("[CHUNKED] ⏰ Timeout waiting for chunked response metadata after 60s")
+		s.logger.Error("[CHUNKED] ⏰ Timeout waiting for chunked response metadata after %v", metadataTimeout)
 		return nil, fmt.Errorf("timeout waiting for chunked response metadata")
 	}
 }
 
-// collectChunkedResponseNoSend streams the response for a request that was already started (no HTTPRequest send here)
-func (s *GRPCTunnelServer) collectChunkedResponseNoSend(tunnelStream *TunnelStream, requestID string, initialChunk *proto.TunnelMessage) (*http.Response, error) {
+// partialContentLength returns the Content-Length of a 206 response carrying Content-Range
+func partialContentLength(response *http.Response) (int64, bool) {
+	if response.StatusCode != http.StatusPartialContent || response.Header.Get("Content-Range") == "" {
+		return 0, false
+	}
+	length, err := strconv.ParseInt(response.Header.Get("Content-Length"), 10, 64)
+	if err != nil || length < 0 {
+		return 0, false
+	}
+	return length, true
+}
+
+// collectChunkedResponseNoSend streams the response for a request that was already started (no HTTPRequest send here).
+// A resumable response may continue on a new stream of the same tunnel if this one breaks.
+// Cancelling ctx cancels the request on the client, as in collectChunkedResponse.
+func (s *GRPCTunnelServer) collectChunkedResponseNoSend(ctx context.Context, tunnelStream *TunnelStream, requestID string, initialChunk *proto.TunnelMessage, resumable bool) (*http.Response, error) {
 	s.logger.Debug("[CHUNKED] 📦 Starting response collection (no-send) for request: %s", requestID)
+	metadataTimeout, collectionTimeout := s.chunkTimeouts()
 
 	// Lookup existing response channel
 	tunnelStream.requestsMux.RLock()
@@ -739,12 +901,16 @@ func (s *GRPCTunnelServer) collectChunkedResponseNoSend(tunnelStream *TunnelStre
 	if !exists {
 		return nil, fmt.Errorf("no pending request channel for request: %s", requestID)
 	}
+	if resumable {
+		s.registerResumable(tunnelStream, requestID, responseChan)
+	}
 
 	// Create a streaming pipe
 	pipeReader, pipeWriter := io.Pipe()
 
 	metadataCh := make(chan *proto.HTTPResponse, 1)
 	errorCh := make(chan error, 1)
+	final := &streamTrailers{}
 
 	// Start goroutine to forward chunks to pipe
 	go func() {
@@ -753,6 +919,9 @@ func (s *GRPCTunnelServer) collectChunkedResponseNoSend(tunnelStream *TunnelStre
 		// Cleanup pendingRequests entry on exit
 		defer func() {
 			s.logger.Debug("[CHUNKED] 🧹 Goroutine exiting (no-send), cleaning up request: %s", requestID)
+			if resumable {
+				s.unregisterResumable(requestID, responseChan)
+			}
 			tunnelStream.requestsMux.Lock()
 			if ch, ok := tunnelStream.pendingRequests[requestID]; ok {
 				delete(tunnelStream.pendingRequests, requestID)
@@ -767,24 +936,59 @@ func (s *GRPCTunnelServer) collectChunkedResponseNoSend(tunnelStream *TunnelStre
 
 		var firstChunk *proto.HTTPResponse
 		chunkCount := 0
-		timeout := time.After(10 * time.Minute) // Increased from 2 minutes for large file stability
+		var written int64 // Body bytes forwarded so far
+		reorder := newChunkReorderer()
+		limit := collectionTimeout
+		timeout := time.NewTimer(limit)
+		defer timeout.Stop()
+
+		// forwardChunk puts a chunk in order and writes the chunks now due to the pipe;
+		// done is true once the final chunk is written
+		forwardChunk := func(chunk *proto.HTTPResponse) (done bool, err error) {
+			ready, err := reorder.push(chunk)
+			if err != nil {
+				go s.sendCancel(tunnelStream, requestID, "chunk_window_exceeded")
+				return false, err
+			}
+			for _, chunk := range ready {
+				chunkCount++
+				body, err := resumedChunkBody(chunk, written)
+				if err != nil {
+					return false, err
+				}
+				if len(body) > 0 {
+					// Track traffic for this chunk
+					if s.usage != nil && tunnelStream != nil {
+						s.usage.Increment(tunnelStream.UserID, tunnelStream.TunnelID, tunnelStream.Domain, 0, int64(len(body)), 0)
+					}
+
+					if _, err := pipeWriter.Write(body); err != nil {
+						return false, fmt.Errorf("failed to write chunk to pipe: %w", err)
+					}
+					written += int64(len(body))
+				}
+				if strings.HasSuffix(chunk.ChunkId, "_final") {
+					final.values = chunk.Trailers
+					return true, nil
+				}
+			}
+			return false, nil
+		}
 
 		// Process initial chunk if provided (DEADLOCK FIX: Avoids pushing back to full channel)
 		if initialChunk != nil {
 			if httpResp := initialChunk.GetHttpResponse(); httpResp != nil {
 				firstChunk = httpResp
+				limit = s.applyOriginTimeout(tunnelStream.Domain, httpResp, timeout, limit)
 				metadataCh <- httpResp // Send metadata immediately
 
-				// If it has body, write it
-				if len(httpResp.Body) > 0 {
-					if _, err := pipeWriter.Write(httpResp.Body); err != nil {
-						errorCh <- fmt.Errorf("failed to write initial chunk to pipe: %w", err)
-						return
-					}
+				// BUG FIX: This may also be the final chunk (common for small files)
+				done, err := forwardChunk(httpResp)
+				if err != nil {
+					errorCh <- fmt.Errorf("failed to forward initial chunk: %w", err)
+					return
 				}
-
-				// BUG FIX: Check if this is also the final chunk (common for small files)
-				if strings.HasSuffix(httpResp.ChunkId, "_final") {
+				if done {
 					return
 				}
 
@@ -794,8 +998,12 @@ func (s *GRPCTunnelServer) collectChunkedResponseNoSend(tunnelStream *TunnelStre
 
 		for {
 			select {
-			case <-timeout:
-				errorCh <- fmt.Errorf("timeout waiting for chunked response after 10 minutes")
+			case <-timeout.C:
+				errorCh <- fmt.Errorf("timeout waiting for chunked response after %v", limit)
+				return
+			case <-ctx.Done():
+				s.sendCancel(tunnelStream, requestID, "downstream_cancelled")
+				errorCh <- fmt.Errorf("request cancelled: %w", ctx.Err())
 				return
 			case response, ok := <-responseChan:
 				if !ok {
@@ -813,22 +1021,16 @@ func (s *GRPCTunnelServer) collectChunkedResponseNoSend(tunnelStream *TunnelStre
 					chunk := msgType.HttpResponse
 					if firstChunk == nil {
 						firstChunk = chunk
+						limit = s.applyOriginTimeout(tunnelStream.Domain, chunk, timeout, limit)
 						metadataCh <- chunk
 					}
 					if chunk.IsChunked {
-						chunkCount++
-						if len(chunk.Body) > 0 {
-							// Track traffic for this chunk
-							if s.usage != nil && tunnelStream != nil {
-								s.usage.Increment(tunnelStream.UserID, tunnelStream.TunnelID, tunnelStream.Domain, 0, int64(len(chunk.Body)), 0)
-							}
-
-							if _, err := pipeWriter.Write(chunk.Body); err != nil {
-								errorCh <- fmt.Errorf("failed to write chunk to pipe: %w", err)
-								return
-							}
+						done, err := forwardChunk(chunk)
+						if err != nil {
+							errorCh <- err
+							return
 						}
-						if strings.HasSuffix(chunk.ChunkId, "_final") {
+						if done {
 							return
 						}
 					} else {
@@ -865,11 +1067,13 @@ func (s *GRPCTunnelServer) collectChunkedResponseNoSend(tunnelStream *TunnelStre
 			response.Header.Set(k, v)
 		}
 		response.Header.Del("Content-Length")
+		response.Header.Del(OriginTimeoutHeader)
+		applyTrailers(response, firstChunk, final)
 		return response, nil
 	case err := <-errorCh:
 		pipeReader.Close()
 		return nil, err
-	case <-time.After(60 * time.Second):
+	case <-time.After(metadataTimeout):
 		pipeReader.Close()
 		return nil, fmt.Errorf("timeout waiting for chunked response metadata")
 	}
@@ -1,11 +1,15 @@
 package tunnel
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -17,8 +21,10 @@ import (
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
+	_ "google.golang.org/grpc/encoding/gzip" // EnableCompression; both ends need the codec registered
 	"google.golang.org/grpc/keepalive"
 	"google.golang.org/grpc/status"
+	gproto "google.golang.org/protobuf/proto"
 )
 
 // Global counter for generating unique client IDs
@@ -37,8 +43,21 @@ type GRPCTunnelClient struct {
 	serverAddr string
 	domain     string
 	targetPort int32
+	localHost  string
+	tunnelID   uint32
 	token      string
 
+	// Local port requests are retried on when targetPort is unreachable (0 = none)
+	fallbackPort int
+
+	// Public request paths mapped to the local service's (see Config.LocalPathPrefix)
+	paths pathRewrite
+
+	// Shared by every request to the local service so keep-alive connections are reused
+	// (see LocalScheme/LocalUseHTTP2 and the LocalMaxIdleConns* settings)
+	localTransport *http.Transport
+	localClient    *http.Client
+
 	// gRPC connection
 	conn          *grpc.ClientConn
 	client        proto.TunnelServiceClient
@@ -67,17 +86,70 @@ type GRPCTunnelClient struct {
 	activeStreams   map[string]context.CancelFunc
 	activeStreamsMu sync.RWMutex
 
+	// WebSockets bridged over the stream (requestID -> session)
+	wsSessions   map[string]*wsBridgeConn
+	wsSessionsMu sync.Mutex
+
+	// Outstanding health pings (requestID -> reply signal)
+	pendingPings   map[string]chan struct{}
+	pendingPingsMu sync.Mutex
+	pingSupported  int32 // Set once the server has answered a health ping
+
+	// Chunk body encoding negotiated with the server ("" = send raw bytes)
+	chunkEncoding string
+
+	// Chunks in flight per response (GRPCClientConfig.ChunkWindowSize), and whether the
+	// server puts chunks back in order so more than one may be
+	chunkWindowSize int32
+	chunkReorder    bool
+
+	// Bandwidth caps shared with the owning tunnel (nil = unlimited)
+	uploadLimiter   *bandwidthLimiter
+	downloadLimiter *bandwidthLimiter
+
+	// Local usage accounting (nil = disabled)
+	usage        UsageRecorder
+	servedDomain string // Domain the server assigned in the handshake, for usage when none was requested
+
+	// Re-issue streamed GETs whose tunnel stream broke mid-response (StreamingConfig.ReissueOnStreamFailure)
+	reissueOnStreamFailure int32
+	streamGeneration       int64 // Incremented for every established tunnel stream
+	reconnecting           int32
+
 	// Tunnel establishment callback
 	tunnelEstablishHandler func(*proto.TunnelEstablishRequest) error
 
+	// Called when a reconnect is refused over quota (true) and when one succeeds again (false)
+	quotaHandler func(exceeded bool)
+
+	// Told when the stream is lost and reconnecting starts, and when it is back
+	reconnectHandler func(reconnecting bool, err error)
+
+	// Called instead of reconnecting when GRPCClientConfig.DisableReconnect is set
+	disconnectHandler func(error)
+
+	// Picks the server to reconnect to after serverAddr failed (nil = always serverAddr)
+	serverFailover func(failed string) string
+
 	// Metrics
-	totalRequests     int64
-	totalResponses    int64
-	totalErrors       int64
-	reconnectCount    int64
-	timeoutErrors     int64
-	timeoutReconnects int64
-	lastError         error // Track the last error for reconnection classification
+	totalRequests      int64
+	totalResponses     int64
+	totalErrors        int64
+	reconnectCount     int64
+	timeoutErrors      int64
+	timeoutReconnects  int64
+	oversizedResponses int64 // Regular responses too big for one message, sent in chunks instead
+	cancelledRequests  int64 // Requests the server cancelled while they were in progress
+	localFailovers     int64 // Requests served by the fallback port because targetPort was unreachable
+	bytesIn            int64 // Request body bytes received from the server
+	bytesOut           int64 // Response body bytes sent to the server
+	lastError          error // Track the last error for reconnection classification
+
+	// Adaptive keepalive interval, tuned by reconnect frequency
+	keepAlive *keepAliveTuner
+
+	// Reconnect backoff strategy; starts as config.BackoffStrategy, swapped by SetBackoffStrategy (reload)
+	backoffStrategy atomic.Pointer[BackoffStrategy]
 
 	// Configuration
 	config *GRPCClientConfig
@@ -92,32 +164,88 @@ type GRPCClientConfig struct {
 	KeepAliveTime    time.Duration
 	KeepAliveTimeout time.Duration
 
+	// Bounds the keepalive interval adapts within, starting from KeepAliveTime: frequent
+	// reconnects shorten it to keep NAT mappings alive (0 = DefaultKeepAliveMin/DefaultKeepAliveMax)
+	KeepAliveMin time.Duration
+	KeepAliveMax time.Duration
+
+	// Local service timeouts. LocalRequestTimeout bounds regular requests; large-file
+	// requests and uploads get LocalStreamingTimeout. Responses streamed back in chunks
+	// (large, SSE or chunked bodies) are only timed until their headers arrive.
+	LocalRequestTimeout   time.Duration
+	LocalStreamingTimeout time.Duration
+
+	// Local service protocol: LocalScheme is http (default) or https, and LocalUseHTTP2
+	// speaks HTTP/2 only (h2c for http), e.g. for gRPC services
+	LocalScheme   string
+	LocalUseHTTP2 bool
+
+	// Request path rewriting before the local service (see Config.LocalPathPrefix)
+	LocalPathPrefix string
+	PublicPathStrip string
+
+	// Keep-alive pool for the local service (0 = DefaultLocalMaxIdleConns,
+	// DefaultLocalMaxIdleConnsPerHost and DefaultLocalIdleConnTimeout)
+	LocalMaxIdleConns        int
+	LocalMaxIdleConnsPerHost int
+	LocalIdleConnTimeout     time.Duration
+
+	// When RequireSignedURL is set the server only forwards requests for links signed
+	// with SignedURLSecret (see SignURL)
+	RequireSignedURL bool
+	SignedURLSecret  string
+
 	// Retry settings
 	MaxReconnectAttempts int
 	ReconnectDelay       time.Duration
 	BackoffMultiplier    float64
+	BackoffStrategy      BackoffStrategy // Same as RetryConfig.BackoffStrategy ("" = exponential)
+	DisableReconnect     bool            // Report a lost stream to the disconnect handler instead of reconnecting
 
 	// Security settings
-	InsecureSkipVerify bool
+	InsecureSkipVerify    bool   // Only set by the --insecure flag
+	ServerCertFingerprint string // Pinned SHA-256 fingerprint of the server certificate (empty = none)
+
+	// Dialer resolves the server with custom DNS settings and reaches it through a proxy (nil = system resolver, proxy from the environment)
+	Dialer *ServerDialer
 
 	// Performance settings
 	MaxMessageSize    int
 	EnableCompression bool
+
+	// Adaptive response chunking (bytes); the chunk size starts at ChunkSizeBase and
+	// grows/shrinks with measured send time within [ChunkSizeMin, ChunkSizeMax]
+	ChunkSizeBase int
+	ChunkSizeMin  int
+	ChunkSizeMax  int
+
+	// Chunks of one response read ahead and in flight at once (1 = strictly sequential),
+	// for high-latency links; only used with servers that reorder chunks, up to MaxChunkWindowSize
+	ChunkWindowSize int
 }
 
 // DefaultGRPCClientConfig returns default client configuration
 func DefaultGRPCClientConfig() *GRPCClientConfig {
 	return &GRPCClientConfig{
-		ConnectTimeout:       30 * time.Second,
-		RequestTimeout:       30 * time.Second,
-		KeepAliveTime:        60 * time.Second, // Increased from 30s for large file stability
-		KeepAliveTimeout:     20 * time.Second, // Increased from 10s for large file stability
-		MaxReconnectAttempts: -1,               // Infinite retries
-		ReconnectDelay:       1 * time.Second,
-		BackoffMultiplier:    1.5,
-		InsecureSkipVerify:   false,            // PRODUCTION: Use proper certificate validation
-		MaxMessageSize:       16 * 1024 * 1024, // 16MB - small files only, large files use chunked streaming
-		EnableCompression:    true,
+		ConnectTimeout:           30 * time.Second,
+		RequestTimeout:           30 * time.Second,
+		KeepAliveTime:            60 * time.Second, // Increased from 30s for large file stability
+		KeepAliveTimeout:         20 * time.Second, // Increased from 10s for large file stability
+		LocalRequestTimeout:      2 * time.Minute,  // Fail fast if broken
+		LocalStreamingTimeout:    10 * time.Minute,
+		LocalMaxIdleConns:        DefaultLocalMaxIdleConns,
+		LocalMaxIdleConnsPerHost: DefaultLocalMaxIdleConnsPerHost,
+		LocalIdleConnTimeout:     DefaultLocalIdleConnTimeout,
+		MaxReconnectAttempts:     -1, // Infinite retries
+		ReconnectDelay:           1 * time.Second,
+		BackoffMultiplier:        1.5,
+		InsecureSkipVerify:       false,            // PRODUCTION: Use proper certificate validation
+		MaxMessageSize:           16 * 1024 * 1024, // 16MB - small files only, large files use chunked streaming
+		EnableCompression:        true,
+		ChunkSizeBase:            DefaultAdaptiveChunkBase,
+		ChunkSizeMin:             DefaultAdaptiveChunkMin,
+		ChunkSizeMax:             MaxChunkSize,
+		ChunkWindowSize:          1,
 	}
 }
 
@@ -129,12 +257,22 @@ func NewGRPCTunnelClient(serverAddr, domain, token string, targetPort int32, con
 
 	ctx, cancel := context.WithCancel(context.Background())
 
+	if scheme, err := normalizeLocalScheme(config.LocalScheme); err == nil {
+		config.LocalScheme = scheme
+	} else {
+		logging.GetGlobalLogger().Warn("%v, using %s", err, DefaultLocalScheme)
+		config.LocalScheme = DefaultLocalScheme
+	}
+
 	// Generate stable client ID for the process on first use
 	if processStableClientID == "" {
 		processStableClientID = fmt.Sprintf("grpc-client-%d", atomic.AddInt64(&globalClientCounter, 1))
 	}
 	clientID := processStableClientID
 
+	// Per-request timeouts are set on each request's context, so one client serves all
+	localTransport := newLocalTransport(config)
+
 	client := &GRPCTunnelClient{
 		clientID:         clientID,
 		serverAddr:       serverAddr,
@@ -147,9 +285,17 @@ func NewGRPCTunnelClient(serverAddr, domain, token string, targetPort int32, con
 		stopping:         false, // Initialize stopping flag
 		responseChannels: make(map[string]chan *proto.TunnelMessage),
 		activeStreams:    make(map[string]context.CancelFunc),
+		pendingPings:     make(map[string]chan struct{}),
+		wsSessions:       make(map[string]*wsBridgeConn),
+		localTransport:   localTransport,
+		localClient:      &http.Client{Transport: localTransport},
 		config:           config,
 		logger:           logging.GetGlobalLogger(),
+		chunkWindowSize:  int32(config.ChunkWindowSize),
+		keepAlive:        newKeepAliveTuner(config.KeepAliveTime, config.KeepAliveMin, config.KeepAliveMax),
+		paths:            newPathRewrite(config.PublicPathStrip, config.LocalPathPrefix),
 	}
+	client.SetBackoffStrategy(config.BackoffStrategy)
 
 	return client
 }
@@ -159,11 +305,115 @@ func (c *GRPCTunnelClient) SetRequestHandler(handler func(*proto.TunnelMessage)
 	c.requestHandler = handler
 }
 
+// SetLocalHost sets the host of the local service requests are forwarded to
+func (c *GRPCTunnelClient) SetLocalHost(host string) {
+	c.localHost = host
+}
+
+// SetTunnelID selects the tunnel to attach to by ID instead of by domain (0 = not set)
+func (c *GRPCTunnelClient) SetTunnelID(id uint32) {
+	c.tunnelID = id
+}
+
+// ResetLocalConnections closes idle connections to the local service, e.g. after it
+// restarted, so the next request dials the new process instead of a dead keep-alive
+func (c *GRPCTunnelClient) ResetLocalConnections() {
+	c.localTransport.CloseIdleConnections()
+}
+
+// localServiceURL builds the URL of the local service for the given public request path
+func (c *GRPCTunnelClient) localServiceURL(path string) string {
+	return c.config.LocalScheme + "://" + localServiceAddr(c.localHost, int(c.targetPort)) + c.paths.apply(path)
+}
+
+// SetBandwidthLimiters sets the upload (to local service) and download (to server) bandwidth caps
+func (c *GRPCTunnelClient) SetBandwidthLimiters(upload, download *bandwidthLimiter) {
+	c.uploadLimiter = upload
+	c.downloadLimiter = download
+}
+
+// SetUsageRecorder records the traffic of this tunnel to rec
+func (c *GRPCTunnelClient) SetUsageRecorder(rec UsageRecorder) {
+	c.usage = rec
+}
+
+// recordUsage adds request/response bytes to the usage recorder, if any
+func (c *GRPCTunnelClient) recordUsage(bytesIn, bytesOut, requests int64) {
+	if c.usage == nil {
+		return
+	}
+	domain := c.domain
+	if domain == "" {
+		c.mu.RLock()
+		domain = c.servedDomain
+		c.mu.RUnlock()
+	}
+	c.usage.Increment(0, c.tunnelID, domain, bytesIn, bytesOut, requests)
+}
+
+// ServedDomain returns the domain the server assigned in the last successful handshake
+func (c *GRPCTunnelClient) ServedDomain() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.servedDomain
+}
+
+// SetReissueOnStreamFailure enables re-requesting a streamed GET from the local service
+// when the tunnel stream breaks mid-response, so the download continues on the new stream
+func (c *GRPCTunnelClient) SetReissueOnStreamFailure(enabled bool) {
+	var v int32
+	if enabled {
+		v = 1
+	}
+	atomic.StoreInt32(&c.reissueOnStreamFailure, v)
+}
+
+// SetChunkWindowSize sets how many chunks of a response may be in flight at once (1 = sequential)
+func (c *GRPCTunnelClient) SetChunkWindowSize(window int) {
+	atomic.StoreInt32(&c.chunkWindowSize, int32(window))
+}
+
+// SetBackoffStrategy changes how the delay between reconnect attempts grows ("" = exponential)
+func (c *GRPCTunnelClient) SetBackoffStrategy(strategy BackoffStrategy) {
+	c.backoffStrategy.Store(&strategy)
+}
+
 // SetTunnelEstablishHandler sets the function to handle tunnel establishment requests
 func (c *GRPCTunnelClient) SetTunnelEstablishHandler(handler func(*proto.TunnelEstablishRequest) error) {
 	c.tunnelEstablishHandler = handler
 }
 
+// SetQuotaHandler sets the function told when reconnects are refused because the
+// monthly bandwidth quota is used up, and when the tunnel is back
+func (c *GRPCTunnelClient) SetQuotaHandler(handler func(exceeded bool)) {
+	c.quotaHandler = handler
+}
+
+// SetReconnectHandler sets the function told when the stream is lost and reconnecting
+// starts (with the error that broke it, if known), and when it is re-established
+func (c *GRPCTunnelClient) SetReconnectHandler(handler func(reconnecting bool, err error)) {
+	c.reconnectHandler = handler
+}
+
+// SetDisconnectHandler sets the function told why the tunnel stream was lost when
+// reconnecting is disabled
+func (c *GRPCTunnelClient) SetDisconnectHandler(handler func(error)) {
+	c.disconnectHandler = handler
+}
+
+// SetServerFailover sets the function picking the server to reconnect to after a
+// reconnect attempt to the failed one didn't succeed
+func (c *GRPCTunnelClient) SetServerFailover(failover func(failed string) string) {
+	c.serverFailover = failover
+}
+
+// SetServerAddr changes the server the next Start connects to
+func (c *GRPCTunnelClient) SetServerAddr(addr string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.serverAddr = addr
+}
+
 // GetClientID returns the unique client identifier
 func (c *GRPCTunnelClient) GetClientID() string {
 	return c.clientID
@@ -196,6 +446,7 @@ func (c *GRPCTunnelClient) Start() error {
 	// Start message handling
 	go c.handleIncomingMessages()
 	go c.monitorConnection()
+	go c.probeLocalService()
 
 	return nil
 }
@@ -227,6 +478,8 @@ func (c *GRPCTunnelClient) Stop() error {
 	if c.conn != nil {
 		c.conn.Close()
 	}
+	c.localTransport.CloseIdleConnections()
+	c.closeWebSocketSessions()
 
 	c.connected = false
 	c.logger.Info("[%s] gRPC tunnel stopped for domain: %s", c.clientID, c.domain)
@@ -291,7 +544,15 @@ func (c *GRPCTunnelClient) connect() error {
 		return fmt.Errorf("TLS CONFIGURATION ERROR: %w", err)
 	}
 
-	c.logger.Info("🔐 PRODUCTION-GRADE: Using secure TLS with certificate validation (InsecureSkipVerify: FALSE)")
+	if c.config.InsecureSkipVerify {
+		tlsConfig = insecureTLSConfig(tlsConfig)
+		warnInsecureSkipVerify(c.logger, c.serverAddr)
+	} else {
+		c.logger.Info("🔐 PRODUCTION-GRADE: Using secure TLS with certificate validation (InsecureSkipVerify: FALSE)")
+	}
+	if tlsConfig, err = pinServerCertificate(tlsConfig, c.config.ServerCertFingerprint, c.logger); err != nil {
+		return fmt.Errorf("CONFIGURATION ERROR: %w", err)
+	}
 
 	// CRITICAL: Force fresh TLS state by disabling session resumption during reconnection
 	// This prevents ERR_SSL_PROTOCOL_ERROR after server restarts
@@ -307,7 +568,7 @@ func (c *GRPCTunnelClient) connect() error {
 	dialOpts := []grpc.DialOption{
 		grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)),
 		grpc.WithKeepaliveParams(keepalive.ClientParameters{
-			Time:                c.config.KeepAliveTime,
+			Time:                c.keepAlive.Interval(),
 			Timeout:             c.config.KeepAliveTimeout,
 			PermitWithoutStream: true,
 		}),
@@ -321,6 +582,14 @@ func (c *GRPCTunnelClient) connect() error {
 		dialOpts = append(dialOpts, grpc.WithDefaultCallOptions(grpc.UseCompressor("gzip")))
 	}
 
+	// Always dial through ServerDialer (nil-safe) so DNS overrides and HTTP/SOCKS proxies,
+	// configured or from the environment, apply. The authority stays the server's host
+	// name, so TLS verification is unaffected.
+	dialer := c.config.Dialer
+	dialOpts = append(dialOpts, grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+		return dialer.DialContext(ctx, "tcp", addr)
+	}))
+
 	c.logger.Debug("[%s] [CONNECT] Starting gRPC dial with %d second timeout", c.clientID, int(c.config.ConnectTimeout.Seconds()))
 
 	connectCtx, connectCancel := context.WithTimeout(c.ctx, c.config.ConnectTimeout)
@@ -360,6 +629,8 @@ func (c *GRPCTunnelClient) connect() error {
 
 	// Wait for handshake response
 	c.logger.Debug("[%s] [CONNECT] Waiting for handshake response", c.clientID)
+	c.chunkEncoding = ""
+	c.chunkReorder = false
 	if err := c.waitForHandshakeResponse(); err != nil {
 		c.logger.Error("[%s] [CONNECT] Handshake response failed: %v", c.clientID, err)
 		stream.CloseSend()
@@ -367,7 +638,12 @@ func (c *GRPCTunnelClient) connect() error {
 		return fmt.Errorf("handshake response failed: %w", err)
 	}
 
+	if c.chunkEncoding != "" {
+		c.logger.Debug("[%s] [CONNECT] Chunk compression negotiated: %s", c.clientID, c.chunkEncoding)
+	}
+
 	c.logger.Info("[%s] [CONNECT] ✅ gRPC data tunnel established", c.clientID)
+	atomic.AddInt64(&c.streamGeneration, 1)
 
 	// Establish control channel (for instant cancels and control messages)
 	c.logger.Debug("[%s] [CONNECT] Establishing control channel", c.clientID)
@@ -451,16 +727,7 @@ func (c *GRPCTunnelClient) handleControlMessages() {
 
 		// Handle cancel requests
 		if cancel := msg.GetCancel(); cancel != nil {
-			c.logger.Info("[%s] [CANCEL] Received cancellation for request %s (reason: %s)",
-				c.clientID, cancel.RequestId, cancel.Reason)
-
-			// Cancel the active stream
-			c.activeStreamsMu.Lock()
-			if cancelFunc, exists := c.activeStreams[cancel.RequestId]; exists {
-				cancelFunc()
-				delete(c.activeStreams, cancel.RequestId)
-			}
-			c.activeStreamsMu.Unlock()
+			c.handleCancelRequest(cancel)
 		}
 
 		// Handle ping requests
@@ -498,9 +765,14 @@ func (c *GRPCTunnelClient) sendHandshake() error {
 							SupportsChunkedStreaming: true,
 							SupportsCompression:      true,
 							MaxChunkSize:             1024 * 1024, // 1MB chunks
-							SupportedEncodings:       []string{"gzip", "deflate"},
+							SupportedEncodings:       supportedChunkEncodings,
+							SupportsWebsocket:        true,
+							SupportsChunkReorder:     true,
 						},
-						ClientVersion: "1.0.0",
+						ClientVersion:    "1.0.0",
+						RequireSignedUrl: c.config.RequireSignedURL,
+						SignedUrlSecret:  c.config.SignedURLSecret,
+						TunnelId:         c.tunnelID,
 					},
 				},
 			},
@@ -535,6 +807,12 @@ func (c *GRPCTunnelClient) waitForHandshakeResponse() error {
 			if status := control.GetStatus(); status != nil {
 				if status.State == proto.TunnelState_TUNNEL_STATE_CONNECTED {
 					c.logger.Info("[%s] Handshake successful for domain: %s", c.clientID, c.domain)
+					// connect runs under c.mu (Start and reconnect hold it)
+					c.servedDomain = status.Domain
+					// Servers that can decode compressed chunks list the encodings they accepted
+					c.chunkEncoding = selectChunkEncoding(status.GetCapabilities().GetSupportedEncodings())
+					// and whether they put chunks back in order, so several may be in flight
+					c.chunkReorder = status.GetCapabilities().GetSupportsChunkReorder()
 
 					// CRITICAL: Save domain and port to config like old handshake (RESTORED FUNCTIONALITY)
 					if err := c.saveHandshakeResponseToConfig(status); err != nil {
@@ -544,6 +822,12 @@ func (c *GRPCTunnelClient) waitForHandshakeResponse() error {
 
 					return nil
 				}
+				if status.State == proto.TunnelState_TUNNEL_STATE_QUOTA_EXCEEDED {
+					return fmt.Errorf("%w (%s)", ErrQuotaExceeded, status.ErrorMessage)
+				}
+				if status.ErrorCode != "" {
+					return fmt.Errorf("handshake failed: %w", &HandshakeError{Code: HandshakeErrorCode(status.ErrorCode), Message: status.ErrorMessage})
+				}
 				return fmt.Errorf("handshake failed: %s", status.ErrorMessage)
 			}
 		}
@@ -611,6 +895,7 @@ func (c *GRPCTunnelClient) saveHandshakeResponseToConfig(status *proto.TunnelSta
 
 // handleIncomingMessages handles messages from the server
 func (c *GRPCTunnelClient) handleIncomingMessages() {
+	stream := c.stream
 	for {
 		select {
 		case <-c.stopChan:
@@ -620,7 +905,7 @@ func (c *GRPCTunnelClient) handleIncomingMessages() {
 		default:
 		}
 
-		msg, err := c.stream.Recv()
+		msg, err := stream.Recv()
 		if err != nil {
 			if err == io.EOF {
 				c.logger.Info("[%s] Server closed the tunnel stream", c.clientID)
@@ -653,7 +938,11 @@ func (c *GRPCTunnelClient) handleIncomingMessages() {
 					c.mu.RUnlock()
 					return
 				}
+				replaced := c.stream != stream
 				c.mu.RUnlock()
+				if replaced {
+					return // A failed send already reconnected
+				}
 
 				go c.reconnect()
 				return
@@ -681,6 +970,10 @@ func (c *GRPCTunnelClient) handleMessage(msg *proto.TunnelMessage) error {
 		return c.handleUploadChunk(msg)
 	case *proto.TunnelMessage_HttpRequestEnd:
 		return c.handleUploadEnd(msg)
+	case *proto.TunnelMessage_WebsocketStart:
+		return c.handleWebSocketStart(msg)
+	case *proto.TunnelMessage_WebsocketData, *proto.TunnelMessage_WebsocketEnd:
+		return c.handleWebSocketMessage(msg)
 
 	case *proto.TunnelMessage_Control:
 		// Handle control message
@@ -690,6 +983,7 @@ func (c *GRPCTunnelClient) handleMessage(msg *proto.TunnelMessage) error {
 		// Handle error message
 		c.logger.Error("Received error from server: %s (code: %d)",
 			msgType.Error.Message, msgType.Error.Code)
+		c.abortUploadSession(msg.RequestId, msgType.Error.Message)
 		return nil
 
 	default:
@@ -714,11 +1008,12 @@ func (c *GRPCTunnelClient) handleUploadStart(msg *proto.TunnelMessage) error {
 		return nil
 	}
 
+	c.recordUsage(0, 0, 1)
 	pr, pw := io.Pipe()
 
 	// Build local request without Content-Length
-	url := fmt.Sprintf("http://127.0.0.1:%d%s", c.targetPort, start.Path)
-	req, err := http.NewRequest(start.Method, url, pr)
+	url := c.localServiceURL(start.Path)
+	req, err := http.NewRequest(start.Method, url, newThrottledReader(context.Background(), pr, c.uploadLimiter))
 	if err != nil {
 		pw.Close()
 		return c.sendErrorResponse(msg.RequestId, fmt.Sprintf("failed to create local request: %v", err))
@@ -726,6 +1021,9 @@ func (c *GRPCTunnelClient) handleUploadStart(msg *proto.TunnelMessage) error {
 	for k, v := range start.Headers {
 		req.Header.Set(k, v)
 	}
+	if c.config.LocalUseHTTP2 {
+		stripHopHeaders(req.Header)
+	}
 	req.ContentLength = -1
 
 	// Save session before starting request
@@ -737,25 +1035,17 @@ func (c *GRPCTunnelClient) handleUploadStart(msg *proto.TunnelMessage) error {
 	go func(requestID string) {
 		defer pr.Close()
 
-		// Create cancellable context for this request
-		streamCtx, streamCancel := context.WithCancel(context.Background())
-		defer streamCancel()
+		// Cancellable by the server for this request
+		streamCtx, done := c.trackRequest(requestID)
+		defer done()
 
-		// Register cancel function for server-initiated cancellation
-		c.activeStreamsMu.Lock()
-		c.activeStreams[requestID] = streamCancel
-		c.activeStreamsMu.Unlock()
-
-		// Clean up on completion
-		defer func() {
-			c.activeStreamsMu.Lock()
-			delete(c.activeStreams, requestID)
-			c.activeStreamsMu.Unlock()
-		}()
-
-		resp, err := (&http.Client{Timeout: 10 * time.Minute}).Do(req)
+		reqCtx, reqCancel := context.WithTimeout(streamCtx, c.config.LocalStreamingTimeout)
+		defer reqCancel()
+		resp, err := c.localClient.Do(req.WithContext(reqCtx))
 		if err != nil {
-			c.sendErrorResponse(requestID, fmt.Sprintf("Local service request failed: %v", err))
+			if streamCtx.Err() == nil {
+				c.sendLocalRequestError(streamCtx, requestID, err)
+			}
 			return
 		}
 		defer resp.Body.Close()
@@ -777,6 +1067,8 @@ func (c *GRPCTunnelClient) handleUploadChunk(msg *proto.TunnelMessage) error {
 		return nil
 	}
 	if len(chunk.Data) > 0 {
+		atomic.AddInt64(&c.bytesIn, int64(len(chunk.Data)))
+		c.recordUsage(int64(len(chunk.Data)), 0, 0)
 		if _, err := sess.pipeWriter.Write(chunk.Data); err != nil {
 			return c.sendErrorResponse(msg.RequestId, fmt.Sprintf("Failed to write upload chunk: %v", err))
 		}
@@ -784,6 +1076,20 @@ func (c *GRPCTunnelClient) handleUploadChunk(msg *proto.TunnelMessage) error {
 	return nil
 }
 
+// abortUploadSession fails an in-flight upload so the local request is not left waiting for more chunks
+func (c *GRPCTunnelClient) abortUploadSession(requestID, reason string) {
+	if requestID == "" {
+		return
+	}
+	uploadSessionsMu.Lock()
+	sess := uploadSessions[requestID]
+	delete(uploadSessions, requestID)
+	uploadSessionsMu.Unlock()
+	if sess != nil && sess.pipeWriter != nil {
+		sess.pipeWriter.CloseWithError(fmt.Errorf("upload aborted by server: %s", reason))
+	}
+}
+
 func (c *GRPCTunnelClient) handleUploadEnd(msg *proto.TunnelMessage) error {
 	uploadSessionsMu.Lock()
 	sess := uploadSessions[msg.RequestId]
@@ -798,6 +1104,7 @@ func (c *GRPCTunnelClient) handleUploadEnd(msg *proto.TunnelMessage) error {
 // handleHTTPRequest handles an HTTP request from the server
 func (c *GRPCTunnelClient) handleHTTPRequest(msg *proto.TunnelMessage) error {
 	atomic.AddInt64(&c.totalRequests, 1)
+	c.recordUsage(0, 0, 1)
 
 	if c.requestHandler != nil {
 		return c.requestHandler(msg)
@@ -877,107 +1184,155 @@ func (c *GRPCTunnelClient) shouldUseChunkedStreaming(httpReq *proto.HTTPRequest)
 func (c *GRPCTunnelClient) forwardLargeFileWithChunking(msg *proto.TunnelMessage, httpReq *proto.HTTPRequest) error {
 	c.logger.Info("[CHUNKED CLIENT] 📦 Implementing chunked response streaming for unlimited file size")
 
-	// Create cancellable context for this request
-	streamCtx, streamCancel := context.WithCancel(context.Background())
-	defer streamCancel()
-
-	// Register cancel function for server-initiated cancellation
-	c.activeStreamsMu.Lock()
-	c.activeStreams[msg.RequestId] = streamCancel
-	c.activeStreamsMu.Unlock()
-
-	// Clean up on completion
-	defer func() {
-		c.activeStreamsMu.Lock()
-		delete(c.activeStreams, msg.RequestId)
-		c.activeStreamsMu.Unlock()
-	}()
+	// Cancellable by the server for this request
+	streamCtx, done := c.trackRequest(msg.RequestId)
+	defer done()
 
 	// Make request to local service
-	response, err := c.makeLocalServiceRequest(httpReq)
+	response, err := c.makeLocalServiceRequest(streamCtx, httpReq)
 	if err != nil {
-		return c.sendErrorResponse(msg.RequestId, fmt.Sprintf("Local service request failed: %v", err))
+		if streamCtx.Err() != nil {
+			return nil // Cancelled by the server, nobody is waiting for an answer
+		}
+		return c.sendLocalRequestError(streamCtx, msg.RequestId, err)
 	}
 	defer response.Body.Close()
 
 	// Stream the response back in chunks for unlimited file size support
-	return c.streamResponseInChunksWithContext(streamCtx, msg.RequestId, response)
+	return c.streamLocalResponse(streamCtx, msg.RequestId, httpReq, response)
 }
 
 // forwardRegularRequest handles regular requests but auto-upgrades to streaming for large responses
 func (c *GRPCTunnelClient) forwardRegularRequest(msg *proto.TunnelMessage, httpReq *proto.HTTPRequest) error {
-	response, err := c.makeLocalServiceRequest(httpReq)
+	// Cancellable by the server for this request
+	ctx, done := c.trackRequest(msg.RequestId)
+	defer done()
+
+	response, err := c.makeLocalServiceRequest(ctx, httpReq)
 	if err != nil {
-		return c.sendErrorResponse(msg.RequestId, fmt.Sprintf("Local service request failed: %v", err))
+		if ctx.Err() != nil {
+			return nil // Cancelled by the server, nobody is waiting for an answer
+		}
+		return c.sendLocalRequestError(ctx, msg.RequestId, err)
 	}
 	defer response.Body.Close()
 
 	// CHECK: If response is large (>8MB) or unknown size, switch to chunked streaming
-	// This ensures that even small GET requests that return large files are handled safely
-	isLargeResponse := response.ContentLength > 8*1024*1024 || response.ContentLength == -1
-
-	if isLargeResponse {
+	// This ensures that even small GET requests that return large files are handled safely.
+	// SSE and chunked responses never reach EOF on their own schedule; reading them
+	// whole would hold every event back until the local service closes the stream.
+	if isStreamedLocalResponse(response) {
 		c.logger.Info("[REGULAR CLIENT] 🔄 Auto-upgrading to chunked streaming for large response: %s (Length: %d)",
 			httpReq.Path, response.ContentLength)
-		// Create a cancellable context for the stream
-		streamCtx, cancel := context.WithCancel(context.Background())
-
-		// Register cancel function
-		c.activeStreamsMu.Lock()
-		c.activeStreams[msg.RequestId] = cancel
-		c.activeStreamsMu.Unlock()
-
-		defer func() {
-			c.activeStreamsMu.Lock()
-			delete(c.activeStreams, msg.RequestId)
-			c.activeStreamsMu.Unlock()
-			cancel()
-		}()
-
-		return c.streamResponseInChunksWithContext(streamCtx, msg.RequestId, response)
+		return c.streamLocalResponse(ctx, msg.RequestId, httpReq, response)
 	}
 
 	// Read entire response for small files
-	body, err := io.ReadAll(response.Body)
+	body, err := io.ReadAll(newThrottledReader(ctx, response.Body, c.downloadLimiter))
 	if err != nil {
+		if ctx.Err() != nil {
+			c.logger.Debug("[REGULAR CLIENT] Request %s cancelled by server while reading the response", msg.RequestId)
+			return nil
+		}
 		return c.sendErrorResponse(msg.RequestId, fmt.Sprintf("Failed to read response: %v", err))
 	}
 
 	// Send single response back to server
-	return c.sendCompleteResponse(msg.RequestId, response, body)
+	return c.sendCompleteResponse(ctx, msg.RequestId, response, body)
 }
 
-// makeLocalServiceRequest makes the actual HTTP request to the local service
-func (c *GRPCTunnelClient) makeLocalServiceRequest(httpReq *proto.HTTPRequest) (*http.Response, error) {
+var errLocalRequestTimeout = errors.New("local service did not respond in time")
+
+// isStreamedLocalResponse reports whether a local response is sent back in chunks as it is
+// read (large, unknown-length, SSE or chunked bodies) rather than read whole
+func isStreamedLocalResponse(response *http.Response) bool {
+	return response.ContentLength > 8*1024*1024 || response.ContentLength == -1 ||
+		isEventStream(response.Header) || isChunkedTransfer(response.TransferEncoding)
+}
+
+// localRequestTimeout returns the timeout for a request to the local service
+func (c *GRPCTunnelClient) localRequestTimeout(httpReq *proto.HTTPRequest) time.Duration {
+	if httpReq.IsLargeFile {
+		return c.config.LocalStreamingTimeout
+	}
+	return c.config.LocalRequestTimeout
+}
+
+// releaseOnClose releases a request's context and timer when its response body is closed
+type releaseOnClose struct {
+	io.ReadCloser
+	release func()
+}
+
+func (r *releaseOnClose) Close() error {
+	err := r.ReadCloser.Close()
+	r.release()
+	return err
+}
+
+// makeLocalServiceRequest makes the actual HTTP request to the local service. Cancelling
+// ctx aborts it, including reads of the response body.
+func (c *GRPCTunnelClient) makeLocalServiceRequest(ctx context.Context, httpReq *proto.HTTPRequest) (*http.Response, error) {
 	// Build URL for local service
-	url := fmt.Sprintf("http://127.0.0.1:%d%s", c.targetPort, httpReq.Path)
+	url := c.localServiceURL(httpReq.Path)
+	atomic.AddInt64(&c.bytesIn, int64(len(httpReq.Body)))
+	c.recordUsage(int64(len(httpReq.Body)), 0, 0)
 
 	// Create HTTP request
-	req, err := http.NewRequest(httpReq.Method, url, strings.NewReader(string(httpReq.Body)))
+	// The timeout covers the whole exchange for regular responses, but streamed
+	// responses only until their headers arrive
+	timeout := c.localRequestTimeout(httpReq)
+	ctx, cancel := context.WithCancelCause(ctx)
+	timer := time.AfterFunc(timeout, func() { cancel(errLocalRequestTimeout) })
+	release := func() {
+		timer.Stop()
+		cancel(nil)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, httpReq.Method, url, strings.NewReader(string(httpReq.Body)))
 	if err != nil {
+		release()
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
+	if len(httpReq.Body) > 0 {
+		// Keeps the Content-Length set above while pacing the body to the upload cap
+		req.Body = io.NopCloser(newThrottledReader(context.Background(), req.Body, c.uploadLimiter))
+	}
 
 	// Set headers
 	for key, value := range httpReq.Headers {
 		req.Header.Set(key, value)
 	}
-
-	// Make request to local service with generous timeout
-	client := &http.Client{
-		Timeout: 2 * time.Minute, // 2 minutes - fail fast if broken
+	if c.config.LocalUseHTTP2 {
+		stripHopHeaders(req.Header)
 	}
 
 	startTime := time.Now()
 	c.logger.Debug("[gRPC CLIENT] Forwarding request to local service: %s %s", httpReq.Method, httpReq.Path)
 
-	resp, err := client.Do(req)
+	resp, err := c.localClient.Do(req)
+	if err != nil && c.fallbackPort > 0 && isLocalUnreachable(err) && ctx.Err() == nil {
+		retry := c.fallbackRequest(req, httpReq)
+		if resp, err = c.localClient.Do(retry); err == nil {
+			atomic.AddInt64(&c.localFailovers, 1)
+			c.logger.Warn("[gRPC CLIENT] Local service on %s unreachable, %s %s served by fallback %s",
+				req.URL.Host, httpReq.Method, httpReq.Path, retry.URL.Host)
+		}
+	}
 	processingTime := time.Since(startTime)
 
 	if err != nil {
+		if errors.Is(context.Cause(ctx), errLocalRequestTimeout) {
+			err = fmt.Errorf("%w (%v)", errLocalRequestTimeout, timeout)
+		}
+		release()
 		c.logger.Error("[gRPC CLIENT] Local service request failed after %v: %v", processingTime, err)
 		return nil, err
 	}
+	if isStreamedLocalResponse(resp) {
+		timer.Stop()
+	}
+	resp.Body = &releaseOnClose{ReadCloser: resp.Body, release: release}
 
 	c.logger.Debug("[gRPC CLIENT] Local service responded in %v: %d %s",
 		processingTime, resp.StatusCode, httpReq.Path)
@@ -985,9 +1340,111 @@ func (c *GRPCTunnelClient) makeLocalServiceRequest(httpReq *proto.HTTPRequest) (
 	return resp, nil
 }
 
+// streamReissueWait bounds how long a broken streamed GET waits for the tunnel to
+// reconnect before giving up; the server keeps the response open for streamResumeGrace
+const streamReissueWait = 20 * time.Second
+
+// errStreamBroken reports that the tunnel stream failed while a response was being streamed
+var errStreamBroken = errors.New("tunnel stream broken mid-response")
+
+// chunkStreamProgress tracks a streamed response across a re-issue on a new tunnel stream
+type chunkStreamProgress struct {
+	chunks           int   // Chunks sent so far; numbering continues after a re-issue
+	offset           int64 // Body offset of the next chunk
+	resumed          bool  // Chunks carry StreamOffsetHeader so the server can skip what it has
+	brokenGeneration int64 // streamGeneration of the stream a send failed on
+}
+
+// streamLocalResponse streams a local response in chunks. If the tunnel stream breaks
+// mid-response and re-issuing is enabled, an idempotent GET is requested again from the
+// local service (once) and streamed from the start on the new stream; the server skips
+// the bytes it has already forwarded.
+func (c *GRPCTunnelClient) streamLocalResponse(ctx context.Context, requestID string, httpReq *proto.HTTPRequest, response *http.Response) error {
+	progress := &chunkStreamProgress{}
+	err := c.streamResponseChunks(ctx, requestID, response, progress)
+	if !errors.Is(err, errStreamBroken) || !c.canReissue(httpReq, response) {
+		return err
+	}
+	response.Body.Close()
+
+	c.logger.Info("[CHUNKED CLIENT] 🔁 Re-issuing %s %s after broken stream (%d bytes sent)",
+		httpReq.Method, httpReq.Path, progress.offset)
+	if err := c.waitForNewStream(ctx, progress.brokenGeneration, streamReissueWait); err != nil {
+		c.logger.Warn("[CHUNKED CLIENT] Not re-issuing %s: %v", requestID, err)
+		return err
+	}
+
+	retry, err := c.makeLocalServiceRequest(ctx, httpReq)
+	if err != nil {
+		return c.sendErrorResponse(requestID, fmt.Sprintf("Local service request failed: %v", err))
+	}
+	defer retry.Body.Close()
+	if retry.StatusCode != response.StatusCode {
+		return c.sendErrorResponse(requestID, fmt.Sprintf("Re-issued request returned %d instead of %d", retry.StatusCode, response.StatusCode))
+	}
+
+	progress.offset = 0
+	progress.resumed = true
+	return c.streamResponseChunks(ctx, requestID, retry, progress)
+}
+
+// canReissue reports whether a request may be sent to the local service a second time
+// after its response stream broke
+func (c *GRPCTunnelClient) canReissue(httpReq *proto.HTTPRequest, response *http.Response) bool {
+	if atomic.LoadInt32(&c.reissueOnStreamFailure) == 0 {
+		return false
+	}
+	// An event stream would replay different events, so its bytes cannot be skipped
+	return httpReq.Method == http.MethodGet && !isEventStream(response.Header)
+}
+
+// waitForNewStream waits until a tunnel stream newer than generation is established
+func (c *GRPCTunnelClient) waitForNewStream(ctx context.Context, generation int64, timeout time.Duration) error {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	deadline := time.After(timeout)
+	for atomic.LoadInt64(&c.streamGeneration) == generation {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-deadline:
+			return fmt.Errorf("tunnel did not reconnect within %v", timeout)
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+// isBrokenStreamError reports whether a send failed because the tunnel stream is gone
+// (as opposed to a problem with the message itself)
+func isBrokenStreamError(err error) bool {
+	if err == io.EOF {
+		return true
+	}
+	switch status.Code(err) {
+	case codes.Unavailable, codes.Canceled, codes.Aborted:
+		return true
+	}
+	return false
+}
+
+// chunkWindow returns how many chunks of a response may be in flight at once. Incremental
+// bodies are sent as data arrives and re-issued ones strictly in order (see chunkReorderer).
+func (c *GRPCTunnelClient) chunkWindow(incremental bool, progress *chunkStreamProgress) int {
+	window := int(atomic.LoadInt32(&c.chunkWindowSize))
+	if !c.chunkReorder || incremental || progress.resumed || window < 2 {
+		return 1
+	}
+	return min(window, MaxChunkWindowSize)
+}
+
 // streamResponseInChunksWithContext streams large responses with cancellation support
 func (c *GRPCTunnelClient) streamResponseInChunksWithContext(ctx context.Context, requestID string, response *http.Response) error {
-	const ChunkSize = 2 * 1024 * 1024         // 2MB chunks for better reliability (reduced from 4MB)
+	return c.streamResponseChunks(ctx, requestID, response, &chunkStreamProgress{})
+}
+
+// streamResponseChunks streams response in chunks, continuing the numbering and offsets in progress
+func (c *GRPCTunnelClient) streamResponseChunks(ctx context.Context, requestID string, response *http.Response, progress *chunkStreamProgress) error {
 	const MaxStreamingTime = 30 * time.Minute // Increased timeout for very large files (increased from 10 minutes)
 
 	// OPTIMIZATION: Fast-path for empty responses - skip chunked streaming overhead
@@ -1001,10 +1458,17 @@ func (c *GRPCTunnelClient) streamResponseInChunksWithContext(ctx context.Context
 				headers[key] = values[0]
 			}
 		}
-		return c.sendCompleteResponse(requestID, response, []byte{})
+		return c.sendCompleteResponse(ctx, requestID, response, []byte{})
 	}
 
-	c.logger.Info("[CHUNKED CLIENT] 📡 Streaming response in %dKB chunks (UNLIMITED SIZE)", ChunkSize/1024)
+	sizer := newAdaptiveChunkSizer(c.config.ChunkSizeBase, c.config.ChunkSizeMin, c.config.ChunkSizeMax)
+	c.logger.Info("[CHUNKED CLIENT] 📡 Streaming response in adaptive chunks starting at %dKB (range %d-%dKB, UNLIMITED SIZE)",
+		sizer.Size()/1024, sizer.min/1024, sizer.max/1024)
+
+	// SSE and chunked bodies are sent as data arrives instead of waiting to fill a chunk.
+	// Event streams are long-lived by design, so they are not subject to MaxStreamingTime.
+	eventStream := isEventStream(response.Header)
+	incremental := eventStream || isChunkedTransfer(response.TransferEncoding)
 
 	// Set overall timeout for chunked streaming
 	startTime := time.Now()
@@ -1017,13 +1481,153 @@ func (c *GRPCTunnelClient) streamResponseInChunksWithContext(ctx context.Context
 		}
 	}
 
-	chunkNum := 0
+	announceTrailers(headers, response)
+
+	// Compress chunk bodies when the server accepts it and the content is worth it
+	chunkEncoding := c.chunkEncoding
+	if chunkEncoding != "" && isCompressibleResponse(headers) {
+		c.logger.Debug("[CHUNKED CLIENT] Compressing chunks for %s with %s", requestID, chunkEncoding)
+	} else {
+		chunkEncoding = ""
+	}
+
+	chunkNum := progress.chunks
 	totalBytes := int64(0)
-	lastProgressLog := 0
+	lastProgressLog := chunkNum
 	progressInterval := 50 // Log every 50 chunks
-	buffer := make([]byte, ChunkSize)
+	buffer := make([]byte, sizer.max)
+
+	// sendChunk encodes and sends one chunk; on failure the server is told and the error returned
+	var pipeline *chunkPipeline
+	var brokenGeneration int64
+	sendChunk := func(chunkNum int, data []byte, isFinalChunk bool, chunkHeaders map[string]string) error {
+		// Chunks still queued in the pipeline when the server cancels are dropped
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		// Create chunk data
+		var chunkData []byte
+		if chunkEncoding != "" {
+			encoded, encErr := encodeChunk(chunkEncoding, data)
+			if encErr != nil {
+				c.logger.Error("[CHUNKED CLIENT] ❌ Failed to %s chunk %d: %v", chunkEncoding, chunkNum, encErr)
+				c.sendErrorResponse(requestID, fmt.Sprintf("Chunk compression failed: %v", encErr))
+				return encErr
+			}
+			chunkData = encoded
+		} else {
+			chunkData = make([]byte, len(data))
+			copy(chunkData, data)
+		}
+
+		// Determine chunk ID (mark final chunk appropriately); the body has been read to
+		// the end by then, so the final chunk carries the trailers
+		var chunkId string
+		var trailers map[string]string
+		if isFinalChunk {
+			chunkId = fmt.Sprintf("chunk-%d_final", chunkNum)
+			trailers = trailerValues(response.Trailer)
+		} else {
+			chunkId = fmt.Sprintf("chunk-%d", chunkNum)
+		}
+
+		// Send chunk response
+		chunkResponse := &proto.TunnelMessage{
+			RequestId: requestID,
+			Timestamp: time.Now().Unix(),
+			MessageType: &proto.TunnelMessage_HttpResponse{
+				HttpResponse: &proto.HTTPResponse{
+					StatusCode:    int32(response.StatusCode),
+					StatusText:    response.Status,
+					Headers:       chunkHeaders,
+					Body:          chunkData,
+					IsChunked:     true,
+					ChunkId:       chunkId,
+					Trailers:      trailers,
+					ChunkEncoding: chunkEncoding,
+				},
+			},
+		}
+
+		c.logger.Debug("[CHUNKED CLIENT] ✅ Sending chunk %d (%d bytes), final: %v", chunkNum, len(chunkData), isFinalChunk)
+
+		// Check if stream is still healthy before sending
+		if c.stream == nil {
+			c.logger.Error("[CHUNKED CLIENT] ❌ Stream is nil, stopping chunk streaming")
+			c.sendErrorResponse(requestID, "Stream connection lost")
+			return fmt.Errorf("stream connection lost")
+		}
+
+		// Send chunk (with sendMux for thread-safety)
+		sendStart := time.Now()
+		generation := atomic.LoadInt64(&c.streamGeneration)
+		c.sendMux.Lock()
+		sendErr := c.stream.Send(chunkResponse)
+		c.sendMux.Unlock()
+		if sendErr == nil {
+			// Send times overlap when chunks are pipelined, so only sequential sends adapt the size
+			if pipeline == nil {
+				sizer.Observe(len(data), time.Since(sendStart))
+			}
+			atomic.AddInt64(&c.bytesOut, int64(len(data)))
+			c.recordUsage(0, int64(len(data)), 0)
+			return nil
+		}
+
+		c.logger.Error("[CHUNKED CLIENT] Failed to send chunk %d: %v", chunkNum, sendErr)
+
+		// If stream send fails, trigger reconnection to recover
+		if isBrokenStreamError(sendErr) {
+			c.logger.Warn("[CHUNKED CLIENT] 🔌 Stream error detected, triggering reconnection")
+			go c.reconnect()
+
+			// The server keeps a GET response open for a re-issue on the new stream
+			if atomic.LoadInt32(&c.reissueOnStreamFailure) == 1 {
+				atomic.CompareAndSwapInt64(&brokenGeneration, 0, generation)
+				return fmt.Errorf("%w: %v", errStreamBroken, sendErr)
+			}
+		}
+
+		// Send error response to clean up server state
+		c.sendErrorResponse(requestID, fmt.Sprintf("Chunked streaming failed: %v", sendErr))
+		return sendErr
+	}
+
+	// Read ahead with several chunks in flight when the server puts them back in order
+	if window := c.chunkWindow(incremental, progress); window > 1 {
+		pipeline = newChunkPipeline(window)
+		c.logger.Debug("[CHUNKED CLIENT] Pipelining up to %d chunks for %s", window, requestID)
+	}
+	defer func() {
+		if pipeline != nil {
+			pipeline.Wait()
+		}
+		if generation := atomic.LoadInt64(&brokenGeneration); generation != 0 {
+			progress.brokenGeneration = generation
+		}
+	}()
+
+	// Send the headers right away: an event stream may stay quiet for longer than
+	// the server waits for a response to start
+	if eventStream {
+		if err := c.sendHeadersChunk(requestID, response, headers, chunkEncoding); err != nil {
+			c.logger.Error("[CHUNKED CLIENT] ❌ Failed to send event stream headers: %v", err)
+			c.sendErrorResponse(requestID, fmt.Sprintf("Chunked streaming failed: %v", err))
+			return err
+		}
+		chunkNum++
+		progress.chunks = chunkNum
+	}
 
 	for {
+		// A pipelined send failed; the server has been told
+		if pipeline != nil {
+			if err := pipeline.Err(); err != nil {
+				return err
+			}
+		}
+
 		// CRITICAL: Check for server-initiated cancellation FIRST (before reading)
 		select {
 		case <-ctx.Done():
@@ -1035,71 +1639,72 @@ func (c *GRPCTunnelClient) streamResponseInChunksWithContext(ctx context.Context
 		}
 
 		// Check for overall timeout
-		if time.Since(startTime) > MaxStreamingTime {
+		if !eventStream && time.Since(startTime) > MaxStreamingTime {
 			c.logger.Error("[CHUNKED CLIENT] ⏰ Streaming timeout after %v, stopping", MaxStreamingTime)
 			c.sendErrorResponse(requestID, "Streaming timeout exceeded")
 			return fmt.Errorf("streaming timeout exceeded")
 		}
 
-		// Read chunk from response
-		n, err := response.Body.Read(buffer)
+		// Read chunk from response (fill up to the current adaptive size, or whatever
+		// is available when streaming incrementally)
+		var n int
+		var err error
+		if incremental {
+			n, err = response.Body.Read(buffer[:sizer.Size()])
+		} else {
+			n, err = io.ReadFull(response.Body, buffer[:sizer.Size()])
+		}
+		if err == io.ErrUnexpectedEOF {
+			err = io.EOF
+		}
 
 		// If read fails, send error and stop immediately
 		if err != nil && err != io.EOF {
+			if ctx.Err() != nil {
+				c.logger.Info("[CHUNKED CLIENT] ⏹️  Request %s cancelled by server - stopped reading from local service", requestID)
+				return nil
+			}
 			c.logger.Error("[CHUNKED CLIENT] ❌ Failed to read from local service: %v", err)
 			c.sendErrorResponse(requestID, fmt.Sprintf("Local service read failed: %v", err))
 			return err
 		}
 
-		if n > 0 {
-			chunkNum++
-			totalBytes += int64(n)
+		// The body may end on a read that returns no data; the stream still needs a final chunk
+		if n == 0 && err == io.EOF && chunkNum == 0 {
+			return c.sendCompleteResponse(ctx, requestID, response, []byte{})
+		}
 
-			// Create chunk data
-			chunkData := make([]byte, n)
-			copy(chunkData, buffer[:n])
+		if n > 0 || err == io.EOF {
+			// Pace chunks to the tunnel's download cap; cancellation ends the wait early
+			if waitErr := c.downloadLimiter.WaitN(ctx, n); waitErr != nil {
+				c.logger.Info("[CHUNKED CLIENT] ⏹️  Request %s cancelled while throttled", requestID)
+				response.Body.Close()
+				return nil
+			}
 
-			// Determine chunk ID (mark final chunk appropriately)
-			var chunkId string
+			chunkNum++
+			totalBytes += int64(n)
 			isFinalChunk := (err == io.EOF)
-			if isFinalChunk {
-				chunkId = fmt.Sprintf("chunk-%d_final", chunkNum)
-			} else {
-				chunkId = fmt.Sprintf("chunk-%d", chunkNum)
-			}
 
-			// Send chunk response
-			chunkResponse := &proto.TunnelMessage{
-				RequestId: requestID,
-				Timestamp: time.Now().Unix(),
-				MessageType: &proto.TunnelMessage_HttpResponse{
-					HttpResponse: &proto.HTTPResponse{
-						StatusCode: int32(response.StatusCode),
-						StatusText: response.Status,
-						Headers:    headers,
-						Body:       chunkData,
-						IsChunked:  true,
-						ChunkId:    chunkId,
-					},
-				},
+			chunkHeaders := headers
+			if progress.resumed {
+				chunkHeaders = make(map[string]string, len(headers)+1)
+				for k, v := range headers {
+					chunkHeaders[k] = v
+				}
+				chunkHeaders[StreamOffsetHeader] = strconv.FormatInt(progress.offset, 10)
 			}
-
-			c.logger.Debug("[CHUNKED CLIENT] ✅ Sending chunk %d (%d bytes), final: %v", chunkNum, len(chunkData), isFinalChunk)
+			progress.chunks = chunkNum
+			progress.offset += int64(n)
 
 			// Progress logging every N chunks
 			if chunkNum-lastProgressLog >= progressInterval {
 				totalMB := float64(totalBytes) / (1024 * 1024)
-				c.logger.Info("[CHUNKED CLIENT] 📊 Progress: streamed %d chunks (%.1f MB) so far...", chunkNum, totalMB)
+				c.logger.Info("[CHUNKED CLIENT] 📊 Progress: streamed %d chunks (%.1f MB) so far, effective chunk size %dKB",
+					chunkNum, totalMB, sizer.Size()/1024)
 				lastProgressLog = chunkNum
 			}
 
-			// Check if stream is still healthy before sending
-			if c.stream == nil {
-				c.logger.Error("[CHUNKED CLIENT] ❌ Stream is nil, stopping chunk streaming")
-				c.sendErrorResponse(requestID, "Stream connection lost")
-				return fmt.Errorf("stream connection lost")
-			}
-
 			// CRITICAL: Check for cancellation AFTER reading but BEFORE sending
 			// This prevents sending chunks after browser disconnects
 			select {
@@ -1110,31 +1715,30 @@ func (c *GRPCTunnelClient) streamResponseInChunksWithContext(ctx context.Context
 			default:
 			}
 
-			// Send chunk (with sendMux for thread-safety)
-			c.sendMux.Lock()
-			sendErr := c.stream.Send(chunkResponse)
-			c.sendMux.Unlock()
-			if sendErr != nil {
-				c.logger.Error("[CHUNKED CLIENT] Failed to send chunk %d: %v", chunkNum, sendErr)
-
-				// If stream send fails, trigger reconnection to recover
-				if strings.Contains(sendErr.Error(), "EOF") ||
-					strings.Contains(sendErr.Error(), "connection") ||
-					strings.Contains(sendErr.Error(), "stream") {
-					c.logger.Warn("[CHUNKED CLIENT] 🔌 Stream error detected, triggering reconnection")
+			if pipeline == nil {
+				if sendErr := sendChunk(chunkNum, buffer[:n], isFinalChunk, chunkHeaders); sendErr != nil {
+					return sendErr
 				}
-
-				// Send error response to clean up server state
-				c.sendErrorResponse(requestID, fmt.Sprintf("Chunked streaming failed: %v", sendErr))
-				return sendErr
+			} else {
+				// The buffer is reused for the next read while this chunk is still being sent
+				data := append([]byte(nil), buffer[:n]...)
+				num := chunkNum
+				pipeline.Go(func() error { return sendChunk(num, data, isFinalChunk, chunkHeaders) })
 			}
 		}
 
 		// Check for end of file
 		if err == io.EOF {
+			if pipeline != nil {
+				if err := pipeline.Wait(); err != nil {
+					return err
+				}
+			}
+
 			// Calculate and log streaming performance
 			totalMB := float64(totalBytes) / (1024 * 1024)
-			c.logger.Info("[CHUNKED CLIENT] 🎉 Completed streaming %d chunks (%.1f MB) for large file", chunkNum, totalMB)
+			c.logger.Info("[CHUNKED CLIENT] 🎉 Completed streaming %d chunks (%.1f MB) for large file, final chunk size %dKB",
+				chunkNum, totalMB, sizer.Size()/1024)
 			break
 		} else if err != nil {
 			c.logger.Error("[CHUNKED CLIENT] Error reading response: %v", err)
@@ -1146,6 +1750,42 @@ func (c *GRPCTunnelClient) streamResponseInChunksWithContext(ctx context.Context
 	return nil
 }
 
+// sendHeadersChunk sends the first chunk of a streamed response with headers and no data
+func (c *GRPCTunnelClient) sendHeadersChunk(requestID string, response *http.Response, headers map[string]string, chunkEncoding string) error {
+	body := []byte{}
+	if chunkEncoding != "" {
+		// Every chunk carries the encoding, so even an empty one must decode
+		encoded, err := encodeChunk(chunkEncoding, body)
+		if err != nil {
+			return err
+		}
+		body = encoded
+	}
+
+	msg := &proto.TunnelMessage{
+		RequestId: requestID,
+		Timestamp: time.Now().Unix(),
+		MessageType: &proto.TunnelMessage_HttpResponse{
+			HttpResponse: &proto.HTTPResponse{
+				StatusCode:    int32(response.StatusCode),
+				StatusText:    response.Status,
+				Headers:       headers,
+				Body:          body,
+				IsChunked:     true,
+				ChunkId:       "chunk-1",
+				ChunkEncoding: chunkEncoding,
+			},
+		},
+	}
+
+	if c.stream == nil {
+		return fmt.Errorf("stream connection lost")
+	}
+	c.sendMux.Lock()
+	defer c.sendMux.Unlock()
+	return c.stream.Send(msg)
+}
+
 // resetChunkedStreamingState resets any chunked streaming state on reconnection
 // This prevents stale state from interfering with new connections
 func (c *GRPCTunnelClient) resetChunkedStreamingState() {
@@ -1157,8 +1797,9 @@ func (c *GRPCTunnelClient) resetChunkedStreamingState() {
 	c.logger.Debug("[CLEANUP] ✅ Chunked streaming state reset completed")
 }
 
-// sendCompleteResponse sends a complete response for regular files
-func (c *GRPCTunnelClient) sendCompleteResponse(requestID string, response *http.Response, body []byte) error {
+// sendCompleteResponse sends a complete response for regular files. A response too big for
+// one gRPC message is streamed in chunks instead, until ctx (the request's) is cancelled.
+func (c *GRPCTunnelClient) sendCompleteResponse(ctx context.Context, requestID string, response *http.Response, body []byte) error {
 	// Convert headers
 	headers := make(map[string]string)
 	for key, values := range response.Header {
@@ -1177,6 +1818,7 @@ func (c *GRPCTunnelClient) sendCompleteResponse(requestID string, response *http
 				StatusText: response.Status,
 				Headers:    headers,
 				Body:       body,
+				Trailers:   trailerValues(response.Trailer),
 				Metadata: &proto.ResponseMetadata{
 					ProcessingTimeMs: 0, // We can add timing if needed
 					ResponseSize:     int64(len(body)),
@@ -1186,13 +1828,40 @@ func (c *GRPCTunnelClient) sendCompleteResponse(requestID string, response *http
 		},
 	}
 
+	// A path that looked small can still return more than MaxMessageSize (e.g. a generated
+	// report). Sending it whole would fail with ResourceExhausted and take the stream down
+	// with it, so check the encoded size first.
+	if size := gproto.Size(responseMsg); c.config.MaxMessageSize > 0 && size > c.config.MaxMessageSize {
+		c.logger.Info("[REGULAR CLIENT] 🔄 Response of %d bytes exceeds the %d byte message limit, streaming it in chunks",
+			size, c.config.MaxMessageSize)
+		atomic.AddInt64(&c.oversizedResponses, 1)
+		return c.streamOversizedResponse(ctx, requestID, response, body)
+	}
+
 	atomic.AddInt64(&c.totalResponses, 1)
+	atomic.AddInt64(&c.bytesOut, int64(len(body)))
+	c.recordUsage(0, int64(len(body)), 0)
 	c.sendMux.Lock()
 	err := c.stream.Send(responseMsg)
 	c.sendMux.Unlock()
+	if status.Code(err) == codes.ResourceExhausted {
+		c.logger.Error("[REGULAR CLIENT] Response for %s exceeded the gRPC message limit: %v", requestID, err)
+	}
 	return err
 }
 
+// streamOversizedResponse sends an already read body through the chunked streaming path,
+// stopping when the server cancels the request (ctx)
+func (c *GRPCTunnelClient) streamOversizedResponse(ctx context.Context, requestID string, response *http.Response, body []byte) error {
+	chunked := *response
+	chunked.Header = response.Header.Clone()
+	chunked.Header.Del("Transfer-Encoding")
+	chunked.TransferEncoding = nil
+	chunked.ContentLength = int64(len(body))
+	chunked.Body = io.NopCloser(bytes.NewReader(body))
+	return c.streamResponseInChunksWithContext(ctx, requestID, &chunked)
+}
+
 // sendErrorResponse sends an error response back to the server
 func (c *GRPCTunnelClient) sendErrorResponse(requestId, errorMsg string) error {
 	// CRITICAL: Check if stream is nil during reconnection
@@ -1219,6 +1888,77 @@ func (c *GRPCTunnelClient) sendErrorResponse(requestId, errorMsg string) error {
 	return err
 }
 
+// Ping sends a health ping over the tunnel stream and waits up to timeout for the server's reply.
+// Servers that predate health pings never reply; until a first reply is seen, a successful
+// send is treated as healthy so older servers don't trigger reconnect loops.
+func (c *GRPCTunnelClient) Ping(timeout time.Duration) error {
+	if c.stream == nil {
+		return fmt.Errorf("stream is nil")
+	}
+
+	requestID := fmt.Sprintf("%s%d", HealthPingRequestPrefix, time.Now().UnixNano())
+	reply := make(chan struct{}, 1)
+	c.pendingPingsMu.Lock()
+	c.pendingPings[requestID] = reply
+	c.pendingPingsMu.Unlock()
+	defer func() {
+		c.pendingPingsMu.Lock()
+		delete(c.pendingPings, requestID)
+		c.pendingPingsMu.Unlock()
+	}()
+
+	ping := &proto.TunnelMessage{
+		RequestId: requestID,
+		Timestamp: time.Now().Unix(),
+		MessageType: &proto.TunnelMessage_Control{
+			Control: &proto.TunnelControl{
+				ControlType: &proto.TunnelControl_Status{
+					Status: &proto.TunnelStatus{
+						State:        proto.TunnelState_TUNNEL_STATE_CONNECTED,
+						Domain:       c.domain,
+						LastActivity: time.Now().Unix(),
+					},
+				},
+				Timestamp: time.Now().Unix(),
+			},
+		},
+	}
+
+	c.sendMux.Lock()
+	err := c.stream.Send(ping)
+	c.sendMux.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to send health ping: %w", err)
+	}
+
+	select {
+	case <-reply:
+		return nil
+	case <-time.After(timeout):
+		if atomic.LoadInt32(&c.pingSupported) == 0 {
+			c.logger.Debug("[%s] [HEALTH] No ping reply (server may not support health pings), send succeeded", c.clientID)
+			return nil
+		}
+		return fmt.Errorf("no health ping reply within %v", timeout)
+	case <-c.ctx.Done():
+		return c.ctx.Err()
+	}
+}
+
+// resolvePing signals the waiter for a health ping reply
+func (c *GRPCTunnelClient) resolvePing(requestID string) {
+	atomic.StoreInt32(&c.pingSupported, 1)
+	c.pendingPingsMu.Lock()
+	reply, ok := c.pendingPings[requestID]
+	c.pendingPingsMu.Unlock()
+	if ok {
+		select {
+		case reply <- struct{}{}:
+		default:
+		}
+	}
+}
+
 // handleControlMessage handles control messages from the server
 func (c *GRPCTunnelClient) handleControlMessage(msg *proto.TunnelMessage) error {
 	control := msg.GetControl()
@@ -1228,6 +1968,12 @@ func (c *GRPCTunnelClient) handleControlMessage(msg *proto.TunnelMessage) error
 
 	switch controlType := control.ControlType.(type) {
 	case *proto.TunnelControl_Status:
+		// Reply to one of our health pings
+		if strings.HasPrefix(msg.RequestId, HealthPingRequestPrefix) {
+			c.resolvePing(msg.RequestId)
+			return nil
+		}
+
 		// Health check response
 		c.logger.Debug("Received status update: %s", controlType.Status.State)
 
@@ -1260,20 +2006,40 @@ func (c *GRPCTunnelClient) handleCancelRequest(cancel *proto.CancelRequest) erro
 
 	c.logger.Info("[CANCEL] Received cancellation for request %s (reason: %s)", cancel.RequestId, cancel.Reason)
 
-	// Find and cancel the active stream for this request
+	// Find and cancel the active stream for this request; its local request and body
+	// reads are aborted through the context
 	c.activeStreamsMu.Lock()
 	if cancelFunc, exists := c.activeStreams[cancel.RequestId]; exists {
 		c.logger.Info("[CANCEL] ✅ Cancelling active stream for request %s", cancel.RequestId)
 		cancelFunc()
 		delete(c.activeStreams, cancel.RequestId)
+		atomic.AddInt64(&c.cancelledRequests, 1)
 	} else {
 		c.logger.Debug("[CANCEL] Request %s not found in active streams (already completed)", cancel.RequestId)
 	}
 	c.activeStreamsMu.Unlock()
 
+	// An upload still receiving chunks won't get the rest
+	c.abortUploadSession(cancel.RequestId, cancel.Reason)
 	return nil
 }
 
+// trackRequest returns a context cancelled when the server cancels requestID (the visitor
+// went away), and the function to call once the request is done. Register before calling
+// the local service so a cancel arriving while it is still working isn't missed.
+func (c *GRPCTunnelClient) trackRequest(requestID string) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(c.ctx)
+	c.activeStreamsMu.Lock()
+	c.activeStreams[requestID] = cancel
+	c.activeStreamsMu.Unlock()
+	return ctx, func() {
+		c.activeStreamsMu.Lock()
+		delete(c.activeStreams, requestID)
+		c.activeStreamsMu.Unlock()
+		cancel()
+	}
+}
+
 // monitorConnection monitors the connection health
 func (c *GRPCTunnelClient) monitorConnection() {
 	ticker := time.NewTicker(1 * time.Minute)
@@ -1288,6 +2054,7 @@ func (c *GRPCTunnelClient) monitorConnection() {
 		case <-ticker.C:
 			// Report metrics
 			c.reportMetrics()
+			c.tuneKeepAlive()
 		}
 	}
 }
@@ -1305,9 +2072,33 @@ func (c *GRPCTunnelClient) reportMetrics() {
 		c.clientID, c.domain, total, responses, errors, timeoutErrors, reconnects, timeoutReconnects)
 }
 
+// tuneKeepAlive adapts the keepalive interval used by the next dial to the reconnect rate
+func (c *GRPCTunnelClient) tuneKeepAlive() {
+	reconnects := atomic.LoadInt64(&c.reconnectCount)
+	old, interval := c.keepAlive.observe(reconnects, time.Now())
+	switch {
+	case interval < old:
+		c.logger.Info("[%s] Keepalive interval %v -> %v: frequent reconnects, keeping NAT mappings alive", c.clientID, old, interval)
+	case interval > old:
+		c.logger.Info("[%s] Keepalive interval %v -> %v: connection stable", c.clientID, old, interval)
+	}
+}
+
+// SetKeepAliveBounds changes the bounds of the adaptive keepalive interval (0 = defaults)
+func (c *GRPCTunnelClient) SetKeepAliveBounds(min, max time.Duration) {
+	c.keepAlive.setBounds(min, max)
+}
+
 // reconnect attempts to reconnect the tunnel
 func (c *GRPCTunnelClient) reconnect() {
+	// Both a failed send and the receive loop may notice the same broken stream
+	if !atomic.CompareAndSwapInt32(&c.reconnecting, 0, 1) {
+		return
+	}
+	defer atomic.StoreInt32(&c.reconnecting, 0)
+
 	atomic.AddInt64(&c.reconnectCount, 1)
+	c.tuneKeepAlive()
 
 	// Check if this reconnection was triggered by a timeout error
 	c.mu.Lock()
@@ -1345,13 +2136,31 @@ func (c *GRPCTunnelClient) reconnect() {
 
 	// Reset any stale chunked streaming state
 	c.resetChunkedStreamingState()
+	c.closeWebSocketSessions()
 
 	c.logger.Info("[CLEANUP] 🧹 Resetting chunked streaming state for domain: %s", c.domain)
 
+	if c.config.DisableReconnect {
+		c.logger.Warn("[%s] Reconnection disabled - not reconnecting gRPC tunnel for domain: %s", c.clientID, c.domain)
+		if lastErr == nil {
+			lastErr = errors.New("gRPC tunnel stream closed")
+		}
+		if c.disconnectHandler != nil {
+			c.disconnectHandler(lastErr)
+		}
+		return
+	}
+
+	if c.reconnectHandler != nil {
+		c.reconnectHandler(true, lastErr)
+	}
+
 	// Retry connection with exponential backoff
 	delay := c.config.ReconnectDelay
 	attempts := 0
+	roundStart := c.serverAddr
 	consecutiveFailures := 0
+	overQuota := false
 	maxConsecutiveFailures := 10 // Circuit breaker threshold
 
 	for {
@@ -1382,6 +2191,44 @@ func (c *GRPCTunnelClient) reconnect() {
 				return
 			}
 
+			// Refused for a reason retrying can't fix (token revoked, tunnel removed, ...)
+			if code, ok := handshakeErrorCode(err); ok && code.Permanent() {
+				c.logger.Error("[%s] FATAL: Server refused the tunnel (%s) - stopping reconnection attempts", c.clientID, code)
+				return
+			}
+
+			// Over quota: the server refuses every attempt until the quota frees up
+			if errors.Is(err, ErrQuotaExceeded) {
+				c.logger.Error("[%s] Monthly bandwidth quota reached for domain %s - the tunnel is paused until the quota resets or the plan is upgraded; checking again in %v",
+					c.clientID, c.domain, QuotaRetryInterval)
+				if !overQuota && c.quotaHandler != nil {
+					c.quotaHandler(true)
+				}
+				overQuota = true
+				consecutiveFailures = 0
+
+				select {
+				case <-time.After(QuotaRetryInterval):
+				case <-c.stopChan:
+					return
+				case <-c.ctx.Done():
+					return
+				}
+				delay = c.config.ReconnectDelay
+				continue
+			}
+
+			// Several servers: try the next one at once, backing off after a round through all
+			if c.serverFailover != nil {
+				if next := c.serverFailover(c.serverAddr); next != "" && next != c.serverAddr {
+					c.logger.Warn("[%s] Failing over from %s to %s", c.clientID, c.serverAddr, next)
+					c.serverAddr = next
+					if next != roundStart && consecutiveFailures < maxConsecutiveFailures {
+						continue
+					}
+				}
+			}
+
 			// CIRCUIT BREAKER: If too many consecutive failures, take a longer break
 			if consecutiveFailures >= maxConsecutiveFailures {
 				longDelay := 5 * time.Minute
@@ -1400,7 +2247,7 @@ func (c *GRPCTunnelClient) reconnect() {
 				continue
 			}
 
-			// Exponential backoff with maximum cap
+			// Back off (capped at 30s) before the next attempt
 			select {
 			case <-time.After(delay):
 				// Continue with backoff
@@ -1410,10 +2257,7 @@ func (c *GRPCTunnelClient) reconnect() {
 				return
 			}
 
-			delay = time.Duration(float64(delay) * c.config.BackoffMultiplier)
-			if delay > 30*time.Second {
-				delay = 30 * time.Second
-			}
+			delay = nextBackoffDelay(*c.backoffStrategy.Load(), delay, c.config.ReconnectDelay, 30*time.Second, c.config.BackoffMultiplier, false)
 			continue
 		}
 
@@ -1421,6 +2265,12 @@ func (c *GRPCTunnelClient) reconnect() {
 		consecutiveFailures = 0
 		c.connected = true
 		c.logger.Info("[%s] Successfully reconnected gRPC tunnel for domain: %s", c.clientID, c.domain)
+		if overQuota && c.quotaHandler != nil {
+			c.quotaHandler(false)
+		}
+		if c.reconnectHandler != nil {
+			c.reconnectHandler(false, nil)
+		}
 
 		// Ensure clean state for new connection
 		c.resetChunkedStreamingState()
@@ -1467,16 +2317,21 @@ func isTimeoutError(err error) bool {
 // GetMetrics returns current client metrics
 func (c *GRPCTunnelClient) GetMetrics() map[string]interface{} {
 	return map[string]interface{}{
-		"client_id":          c.clientID,
-		"connected":          c.connected,
-		"total_requests":     atomic.LoadInt64(&c.totalRequests),
-		"total_responses":    atomic.LoadInt64(&c.totalResponses),
-		"total_errors":       atomic.LoadInt64(&c.totalErrors),
-		"timeout_errors":     atomic.LoadInt64(&c.timeoutErrors),
-		"reconnect_count":    atomic.LoadInt64(&c.reconnectCount),
-		"timeout_reconnects": atomic.LoadInt64(&c.timeoutReconnects),
-		"domain":             c.domain,
-		"target_port":        c.targetPort,
+		"client_id":           c.clientID,
+		"connected":           c.connected,
+		"total_requests":      atomic.LoadInt64(&c.totalRequests),
+		"total_responses":     atomic.LoadInt64(&c.totalResponses),
+		"total_errors":        atomic.LoadInt64(&c.totalErrors),
+		"timeout_errors":      atomic.LoadInt64(&c.timeoutErrors),
+		"reconnect_count":     atomic.LoadInt64(&c.reconnectCount),
+		"timeout_reconnects":  atomic.LoadInt64(&c.timeoutReconnects),
+		"oversized_responses": atomic.LoadInt64(&c.oversizedResponses),
+		"cancelled_requests":  atomic.LoadInt64(&c.cancelledRequests),
+		"local_failovers":     atomic.LoadInt64(&c.localFailovers),
+		"bytes_in":            atomic.LoadInt64(&c.bytesIn),
+		"bytes_out":           atomic.LoadInt64(&c.bytesOut),
+		"domain":              c.domain,
+		"target_port":         c.targetPort,
 	}
 }
 
@@ -1,7 +1,21 @@
 package tunnel
 
 import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/osa911/giraffecloud/internal/tunnel/proto"
+
+	"google.golang.org/grpc"
 )
 
 func TestGRPCTunnelClient_ClientID(t *testing.T) {
@@ -52,3 +66,329 @@ func TestGRPCTunnelClient_ClientIDFormat(t *testing.T) {
 		t.Errorf("Client ID should start with 'grpc-client-', got: %s", clientID)
 	}
 }
+
+// fakeTunnelStream is a minimal EstablishTunnel client stream that hands sent messages to onSend
+type fakeTunnelStream struct {
+	grpc.ClientStream
+	onSend func(*proto.TunnelMessage) error
+}
+
+func (f *fakeTunnelStream) Send(msg *proto.TunnelMessage) error { return f.onSend(msg) }
+func (f *fakeTunnelStream) Recv() (*proto.TunnelMessage, error) { return nil, io.EOF }
+
+func TestGRPCTunnelClient_Ping(t *testing.T) {
+	initTestLogger(t)
+
+	client := NewGRPCTunnelClient("localhost:4444", "test.example.com", "token", 8080, nil)
+
+	// Server that answers pings
+	client.stream = &fakeTunnelStream{onSend: func(msg *proto.TunnelMessage) error {
+		go client.handleControlMessage(&proto.TunnelMessage{
+			RequestId: msg.RequestId,
+			MessageType: &proto.TunnelMessage_Control{Control: &proto.TunnelControl{
+				ControlType: &proto.TunnelControl_Status{Status: &proto.TunnelStatus{State: proto.TunnelState_TUNNEL_STATE_ACTIVE}},
+			}},
+		})
+		return nil
+	}}
+	if err := client.Ping(time.Second); err != nil {
+		t.Fatalf("Expected ping to succeed, got %v", err)
+	}
+
+	// Server stops answering once it is known to support pings
+	client.stream = &fakeTunnelStream{onSend: func(*proto.TunnelMessage) error { return nil }}
+	if err := client.Ping(50 * time.Millisecond); err == nil {
+		t.Error("Expected ping to time out")
+	}
+
+	// Broken stream
+	client.stream = &fakeTunnelStream{onSend: func(*proto.TunnelMessage) error { return errors.New("transport is closing") }}
+	if err := client.Ping(time.Second); err == nil {
+		t.Error("Expected ping to fail on send error")
+	}
+}
+
+func TestGRPCTunnelClient_StreamsServerSentEvents(t *testing.T) {
+	initTestLogger(t)
+
+	// Local service sends one event, then holds the stream open until released
+	release := make(chan struct{})
+	local := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, "data: one\n\n")
+		w.(http.Flusher).Flush()
+		<-release
+		io.WriteString(w, "data: two\n\n")
+	}))
+	defer local.Close()
+	defer close(release)
+
+	port, _ := strconv.Atoi(local.URL[strings.LastIndex(local.URL, ":")+1:])
+	client := NewGRPCTunnelClient("localhost:4444", "test.example.com", "token", int32(port), nil)
+	client.SetLocalHost("127.0.0.1")
+
+	sent := make(chan *proto.HTTPResponse, 16)
+	client.stream = &fakeTunnelStream{onSend: func(msg *proto.TunnelMessage) error {
+		sent <- msg.GetHttpResponse()
+		return nil
+	}}
+
+	go client.forwardToLocalService(&proto.TunnelMessage{
+		RequestId:   "sse-1",
+		MessageType: &proto.TunnelMessage_HttpRequest{HttpRequest: &proto.HTTPRequest{Method: http.MethodGet, Path: "/events"}},
+	})
+
+	next := func() *proto.HTTPResponse {
+		t.Helper()
+		select {
+		case resp := <-sent:
+			if resp == nil {
+				t.Fatal("expected an HTTP response message")
+			}
+			return resp
+		case <-time.After(2 * time.Second):
+			t.Fatal("timed out waiting for a chunk")
+			return nil
+		}
+	}
+
+	// Headers go out before any event, then the first event without waiting for EOF
+	if first := next(); !first.IsChunked || len(first.Body) != 0 || first.Headers["Content-Type"] != "text/event-stream" {
+		t.Fatalf("first message = chunked %v, body %q, headers %v; want empty headers chunk", first.IsChunked, first.Body, first.Headers)
+	}
+	if event := next(); string(event.Body) != "data: one\n\n" {
+		t.Fatalf("first event = %q", event.Body)
+	}
+
+	release <- struct{}{}
+	var rest strings.Builder
+	for {
+		chunk := next()
+		rest.Write(chunk.Body)
+		if strings.HasSuffix(chunk.ChunkId, "_final") {
+			break
+		}
+	}
+	if rest.String() != "data: two\n\n" {
+		t.Fatalf("remaining events = %q", rest.String())
+	}
+}
+
+func TestGRPCTunnelClient_CancelStopsLocalRequest(t *testing.T) {
+	initTestLogger(t)
+
+	// Local service: /slow never answers, /events sends one event and then goes quiet
+	aborted := make(chan string, 2)
+	local := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/events" {
+			w.Header().Set("Content-Type", "text/event-stream")
+			io.WriteString(w, "data: one\n\n")
+			w.(http.Flusher).Flush()
+		}
+		<-r.Context().Done()
+		aborted <- r.URL.Path
+	}))
+	defer local.Close()
+
+	port, _ := strconv.Atoi(local.URL[strings.LastIndex(local.URL, ":")+1:])
+	client := NewGRPCTunnelClient("localhost:4444", "test.example.com", "token", int32(port), nil)
+	client.SetLocalHost("127.0.0.1")
+
+	sent := make(chan *proto.TunnelMessage, 16)
+	client.stream = &fakeTunnelStream{onSend: func(msg *proto.TunnelMessage) error {
+		sent <- msg
+		return nil
+	}}
+
+	for _, path := range []string{"/slow", "/events"} {
+		requestID := "req" + strings.ReplaceAll(path, "/", "-")
+		done := make(chan error, 1)
+		go func() {
+			done <- client.forwardToLocalService(&proto.TunnelMessage{
+				RequestId:   requestID,
+				MessageType: &proto.TunnelMessage_HttpRequest{HttpRequest: &proto.HTTPRequest{Method: http.MethodGet, Path: path}},
+			})
+		}()
+		if path == "/events" {
+			<-sent // Headers
+			<-sent // First event
+		} else {
+			time.Sleep(100 * time.Millisecond) // Waiting on the local service
+		}
+
+		client.handleCancelRequest(&proto.CancelRequest{RequestId: requestID, Reason: "downstream_cancelled"})
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Errorf("%s: cancelled request returned %v", path, err)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("%s: request kept running after cancel", path)
+		}
+		select {
+		case got := <-aborted:
+			if got != path {
+				t.Errorf("aborted %s, want %s", got, path)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("%s: local request not aborted", path)
+		}
+		select {
+		case msg := <-sent:
+			t.Errorf("%s: sent %v after cancel", path, msg)
+		default:
+		}
+	}
+	if n := client.GetMetrics()["cancelled_requests"]; n != int64(2) {
+		t.Errorf("cancelled_requests = %v, want 2", n)
+	}
+}
+
+func TestGRPCTunnelClient_LocalRequestTimeout(t *testing.T) {
+	initTestLogger(t)
+
+	local := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(300 * time.Millisecond)
+		io.WriteString(w, "slow")
+	}))
+	defer local.Close()
+
+	port, _ := strconv.Atoi(local.URL[strings.LastIndex(local.URL, ":")+1:])
+	config := DefaultGRPCClientConfig()
+	config.LocalRequestTimeout = 50 * time.Millisecond
+	config.LocalStreamingTimeout = 5 * time.Second
+	client := NewGRPCTunnelClient("localhost:4444", "test.example.com", "token", int32(port), config)
+	client.SetLocalHost("127.0.0.1")
+
+	_, err := client.makeLocalServiceRequest(context.Background(), &proto.HTTPRequest{Method: http.MethodGet, Path: "/admin/report"})
+	if !errors.Is(err, errLocalRequestTimeout) {
+		t.Fatalf("regular request error = %v, want %v", err, errLocalRequestTimeout)
+	}
+
+	// Large-file requests get the longer streaming timeout
+	resp, err := client.makeLocalServiceRequest(context.Background(), &proto.HTTPRequest{Method: http.MethodGet, Path: "/files/backup.zip", IsLargeFile: true})
+	if err != nil {
+		t.Fatalf("large-file request error = %v", err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil || string(body) != "slow" {
+		t.Fatalf("large-file body = %q, %v", body, err)
+	}
+}
+
+func TestGRPCTunnelClient_ReusesLocalConnections(t *testing.T) {
+	initTestLogger(t)
+
+	var dials atomic.Int32
+	local := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	local.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			dials.Add(1)
+		}
+	}
+	local.Start()
+	defer local.Close()
+
+	port, _ := strconv.Atoi(local.URL[strings.LastIndex(local.URL, ":")+1:])
+	client := NewGRPCTunnelClient("localhost:4444", "test.example.com", "token", int32(port), nil)
+	client.SetLocalHost("127.0.0.1")
+
+	for i := 0; i < 5; i++ {
+		resp, err := client.makeLocalServiceRequest(context.Background(), &proto.HTTPRequest{Method: http.MethodGet, Path: "/"})
+		if err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+	if n := dials.Load(); n != 1 {
+		t.Errorf("local service saw %d connections for 5 sequential requests, want 1", n)
+	}
+}
+
+func TestGRPCTunnelClient_OversizedResponseFallsBackToChunks(t *testing.T) {
+	initTestLogger(t)
+
+	// A path that doesn't look large, with a body bigger than one message
+	report := strings.Repeat("id,name\n", 200)
+	local := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/csv")
+		io.WriteString(w, report)
+	}))
+	defer local.Close()
+
+	port, _ := strconv.Atoi(local.URL[strings.LastIndex(local.URL, ":")+1:])
+	config := DefaultGRPCClientConfig()
+	config.MaxMessageSize = 1024
+	client := NewGRPCTunnelClient("localhost:4444", "test.example.com", "token", int32(port), config)
+	client.SetLocalHost("127.0.0.1")
+
+	var sent []*proto.HTTPResponse
+	client.stream = &fakeTunnelStream{onSend: func(msg *proto.TunnelMessage) error {
+		sent = append(sent, msg.GetHttpResponse())
+		return nil
+	}}
+
+	err := client.forwardToLocalService(&proto.TunnelMessage{
+		RequestId:   "report-1",
+		MessageType: &proto.TunnelMessage_HttpRequest{HttpRequest: &proto.HTTPRequest{Method: http.MethodGet, Path: "/report"}},
+	})
+	if err != nil {
+		t.Fatalf("forwardToLocalService: %v", err)
+	}
+
+	if len(sent) == 0 || !sent[0].IsChunked || sent[0].Headers["Content-Type"] != "text/csv" {
+		t.Fatalf("got %d messages, want a chunked response", len(sent))
+	}
+	var body strings.Builder
+	for _, chunk := range sent {
+		body.Write(chunk.Body)
+	}
+	if body.String() != report || !strings.HasSuffix(sent[len(sent)-1].ChunkId, "_final") {
+		t.Errorf("reassembled %d of %d bytes", body.Len(), len(report))
+	}
+	if got := client.GetMetrics()["oversized_responses"]; got != int64(1) {
+		t.Errorf("oversized_responses = %v, want 1", got)
+	}
+}
+
+func TestGRPCTunnelClient_OversizedResponseStopsOnCancel(t *testing.T) {
+	initTestLogger(t)
+
+	report := strings.Repeat("x", 64*1024)
+	local := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", strconv.Itoa(len(report)))
+		io.WriteString(w, report)
+	}))
+	defer local.Close()
+
+	port, _ := strconv.Atoi(local.URL[strings.LastIndex(local.URL, ":")+1:])
+	config := DefaultGRPCClientConfig()
+	config.MaxMessageSize = 1024
+	config.ChunkSizeBase, config.ChunkSizeMin, config.ChunkSizeMax = 1024, 1024, 1024
+	client := NewGRPCTunnelClient("localhost:4444", "test.example.com", "token", int32(port), config)
+	client.SetLocalHost("127.0.0.1")
+
+	// The server cancels the request once the first chunk arrives
+	var sent int
+	client.stream = &fakeTunnelStream{onSend: func(msg *proto.TunnelMessage) error {
+		if sent++; sent == 1 {
+			client.handleCancelRequest(&proto.CancelRequest{RequestId: msg.RequestId})
+		}
+		return nil
+	}}
+
+	err := client.forwardToLocalService(&proto.TunnelMessage{
+		RequestId:   "report-1",
+		MessageType: &proto.TunnelMessage_HttpRequest{HttpRequest: &proto.HTTPRequest{Method: http.MethodGet, Path: "/report"}},
+	})
+	if err != nil {
+		t.Fatalf("forwardToLocalService: %v", err)
+	}
+	if sent != 1 {
+		t.Errorf("sent %d of %d chunks after the server cancelled", sent, len(report)/1024)
+	}
+}
@@ -0,0 +1,67 @@
+package tunnel
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/osa911/giraffecloud/internal/tunnel/proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+// registerHealthServices registers the standard grpc.health.v1.Health service (and server
+// reflection if enabled) on srv. Both the overall status ("") and the tunnel service are
+// reported, so probes may ask for either.
+func (s *GRPCTunnelServer) registerHealthServices(srv *grpc.Server) {
+	healthpb.RegisterHealthServer(srv, s.health)
+	if s.config.EnableReflection {
+		reflection.Register(srv)
+	}
+}
+
+// startHealthListener serves only the health (and reflection) services without TLS on
+// addr, for probes that can't present a client certificate to the mTLS tunnel port
+func (s *GRPCTunnelServer) startHealthListener(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on gRPC health address %s: %w", addr, err)
+	}
+
+	s.healthServer = grpc.NewServer()
+	s.registerHealthServices(s.healthServer)
+	go func() {
+		if err := s.healthServer.Serve(listener); err != nil {
+			s.logger.Error("gRPC health server error: %v", err)
+		}
+	}()
+	s.logger.Info("✓ gRPC health checks available without TLS on %s", addr)
+	return nil
+}
+
+// setServing reports every registered service as SERVING or NOT_SERVING
+func (s *GRPCTunnelServer) setServing(serving bool) {
+	status := healthpb.HealthCheckResponse_NOT_SERVING
+	if serving {
+		status = healthpb.HealthCheckResponse_SERVING
+	}
+	for _, service := range []string{"", proto.TunnelService_ServiceDesc.ServiceName} {
+		s.health.SetServingStatus(service, status)
+	}
+}
+
+// MarkNotServing makes health checks report NOT_SERVING from now on, so load balancers
+// stop sending new tunnels here while the server drains. It can't be undone.
+func (s *GRPCTunnelServer) MarkNotServing() {
+	s.health.Shutdown()
+}
+
+// newHealthServer creates the health service with every service NOT_SERVING until Start
+func newHealthServer() *health.Server {
+	h := health.NewServer()
+	h.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	h.SetServingStatus(proto.TunnelService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_NOT_SERVING)
+	return h
+}
@@ -0,0 +1,39 @@
+package tunnel
+
+import (
+	"context"
+	"testing"
+
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestGRPCTunnelServerHealthStatus(t *testing.T) {
+	s := &GRPCTunnelServer{health: newHealthServer()}
+
+	check := func(service string) healthpb.HealthCheckResponse_ServingStatus {
+		t.Helper()
+		resp, err := s.health.Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+		if err != nil {
+			t.Fatalf("Check(%q): %v", service, err)
+		}
+		return resp.Status
+	}
+
+	if got := check(""); got != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Errorf("before Start = %v, want NOT_SERVING", got)
+	}
+
+	s.setServing(true)
+	for _, service := range []string{"", "tunnel.TunnelService"} {
+		if got := check(service); got != healthpb.HealthCheckResponse_SERVING {
+			t.Errorf("%q after Start = %v, want SERVING", service, got)
+		}
+	}
+
+	// Draining is final: a later setServing(true) must not flip it back
+	s.MarkNotServing()
+	s.setServing(true)
+	if got := check("tunnel.TunnelService"); got != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Errorf("after drain = %v, want NOT_SERVING", got)
+	}
+}
@@ -4,11 +4,13 @@ import (
 	"bytes"
 	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
 	"os"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -21,6 +23,7 @@ import (
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
 	"google.golang.org/grpc/keepalive"
 	"google.golang.org/grpc/status"
 )
@@ -45,19 +48,31 @@ type GRPCTunnelServer struct {
 	grpcServer *grpc.Server
 	listener   net.Listener
 
+	// Standard health service, SERVING while the listener is up and NOT_SERVING while stopping
+	health       *health.Server
+	healthServer *grpc.Server // Plaintext health-only server (nil unless HealthListenAddr is set)
+
 	// Active tunnel streams (domain -> stream connection)
 	tunnelStreams    map[string]*TunnelStream
 	tunnelStreamsMux sync.RWMutex
 
+	// Streamed GET responses that can continue on a new stream (requestID -> response)
+	resumable    map[string]*resumableResponse
+	resumableMux sync.RWMutex
+
 	// Performance metrics (atomic for thread safety)
 	totalRequests  int64
 	concurrentReqs int64
 	totalResponses int64
 	totalErrors    int64
 	timeoutErrors  int64
+	pendingReaped  int64 // Leaked pendingRequests dropped by the reaper
 	totalBytesIn   int64
 	totalBytesOut  int64
 
+	// Rolling time-to-response latency per domain
+	latency *LatencyTracker
+
 	// Configuration
 	config *GRPCTunnelConfig
 
@@ -71,6 +86,25 @@ type GRPCTunnelServer struct {
 
 	// Tunnel status cache (for fast active status checks)
 	statusCache *TunnelStatusCache
+
+	// Maximum streamed upload size in bytes (0 = unlimited)
+	maxUploadBytes int64
+
+	// Largest X-Tunnel-Timeout honored on streamed responses (0 = ignore the header)
+	maxOriginTimeout time.Duration
+
+	// Choose chunked downloads by size and Range only, without extension/path guesses
+	disableMediaOptimization bool
+
+	// Keepalive for WebSockets bridged over the stream (see StreamingConfig.WebSocketIdleTimeout)
+	wsIdleTimeout  time.Duration
+	wsPingInterval time.Duration
+
+	// Cached GET responses served without a round trip to the client (nil = caching disabled)
+	cache *responseCache
+
+	// Per-user connection limit checked on handshakes (nil = unlimited)
+	userConnectionCheck UserConnectionCheck
 }
 
 // SetUsageRecorder wires a usage recorder for accounting.
@@ -81,6 +115,23 @@ func (s *GRPCTunnelServer) SetUsageRecorder(rec UsageRecorder) {
 // SetQuotaChecker wires quota checker
 func (s *GRPCTunnelServer) SetQuotaChecker(q QuotaChecker) { s.quota = q }
 
+// SetMaxOriginTimeout sets the largest X-Tunnel-Timeout honored on streamed responses (0 = ignore the header)
+func (s *GRPCTunnelServer) SetMaxOriginTimeout(max time.Duration) { s.maxOriginTimeout = max }
+
+// SetDisableMediaOptimization turns off the extension/path heuristics picking chunked downloads
+func (s *GRPCTunnelServer) SetDisableMediaOptimization(disable bool) {
+	s.disableMediaOptimization = disable
+}
+
+// SetWebSocketKeepalive sets the idle timeout and ping interval of bridged WebSockets
+func (s *GRPCTunnelServer) SetWebSocketKeepalive(idleTimeout, pingInterval time.Duration) {
+	s.wsIdleTimeout = idleTimeout
+	s.wsPingInterval = pingInterval
+}
+
+// SetMaxUploadBytes caps the size of streamed request bodies (0 = unlimited)
+func (s *GRPCTunnelServer) SetMaxUploadBytes(limit int64) { s.maxUploadBytes = limit }
+
 // SetTCPEstablishmentResponseCallback sets a callback for TCP tunnel establishment responses
 func (s *GRPCTunnelServer) SetTCPEstablishmentResponseCallback(callback func(domain string, requestId string, success bool)) {
 	s.onTCPEstablishmentResponse = callback
@@ -104,10 +155,28 @@ type TunnelStream struct {
 
 	// Request correlation (requestID -> response channel)
 	pendingRequests map[string]chan *proto.TunnelMessage
+	pendingSeen     map[string]time.Time // When the reaper first saw each request (see reapPendingRequestsOnce)
 	requestsMux     sync.RWMutex
 
 	// Note: Chunked response handling now uses memory-efficient streaming via io.Pipe()
 
+	// WebSockets bridged over this stream (requestID -> session), if the client supports it
+	supportsWebSocket bool
+	websockets        map[string]*wsBridgeConn
+	websocketsMux     sync.Mutex
+
+	// Link-signing secret when the client set RequireSignedURL (see checkSignedURL)
+	signedURLSecret string
+
+	// Closed when a newer handshake for the domain takes over (see registerTunnelStream)
+	superseded    chan struct{}
+	supersedeOnce sync.Once
+
+	// Client certificate of the stream, and closed when it is found revoked (see revokeStreams)
+	clientCert *x509.Certificate
+	revoked    chan struct{}
+	revokeOnce sync.Once
+
 	// Stream state
 	connected     bool
 	lastActivity  time.Time
@@ -131,26 +200,55 @@ type GRPCTunnelConfig struct {
 	MaxRequestSize  int64
 	MaxResponseSize int64
 
+	// Chunked responses: how long to wait for the headers, and for the whole body unless
+	// the origin extends it with X-Tunnel-Timeout (0 = DefaultChunkMetadataTimeout and
+	// DefaultChunkCollectionTimeout). A request's own context deadline applies on top.
+	ChunkMetadataTimeout   time.Duration
+	ChunkCollectionTimeout time.Duration
+
 	// Security settings
 	RequireAuthentication bool
 	AllowedOrigins        []string
-	RateLimitRPM          int
+	RateLimitRPM          int // Per-domain refill rate (0 = unlimited)
 	RateLimitBurst        int
+	ClientRateLimitRPM    int // Per-client-IP refill rate within a domain (0 = unlimited)
+	ClientRateLimitBurst  int
+
+	// Cache GET responses the origin marks cacheable, up to ResponseCacheSize bytes of bodies
+	// (0 = disabled), each fresh for at most ResponseCacheTTL (0 = DefaultResponseCacheTTL)
+	ResponseCacheSize int64
+	ResponseCacheTTL  time.Duration
+
+	// What to do when a handshake arrives for a domain with a connected stream ("" = replace)
+	DuplicateStreamPolicy DuplicateStreamPolicy
+
+	// Drop pending requests older than this, whose handlers leaked them (0 = DefaultPendingRequestMaxAge)
+	PendingRequestMaxAge time.Duration
+
+	// How often connected streams' client certificates go through the SetCertRevocationCheck
+	// check again (0 = DefaultCertRevocationCheckInterval); new connections are always checked
+	CertRevocationCheckInterval time.Duration
+
+	// Probes
+	EnableReflection bool   // Register gRPC server reflection next to the health service
+	HealthListenAddr string // Also serve health checks without TLS on this address (empty = tunnel port only)
 }
 
 // DefaultGRPCTunnelConfig returns production-ready default configuration
 func DefaultGRPCTunnelConfig() *GRPCTunnelConfig {
 	return &GRPCTunnelConfig{
-		MaxConcurrentStreams:  5000,             // 5000 concurrent users - conservative and safe (~75 MB memory)
-		MaxMessageSize:        16 * 1024 * 1024, // 16MB - small files only, large files use chunked streaming
-		KeepAliveTimeout:      30 * time.Second,
-		KeepAliveInterval:     5 * time.Second,
-		RequestTimeout:        30 * time.Second,
-		MaxRequestSize:        16 * 1024 * 1024, // 16MB - small files only
-		MaxResponseSize:       16 * 1024 * 1024, // 16MB - small files only
-		RequireAuthentication: true,
-		RateLimitRPM:          5000, // 5000 requests per minute per tunnel
-		RateLimitBurst:        500,  // 500 requests per minute per tunnel
+		MaxConcurrentStreams:   5000,             // 5000 concurrent users - conservative and safe (~75 MB memory)
+		MaxMessageSize:         16 * 1024 * 1024, // 16MB - small files only, large files use chunked streaming
+		KeepAliveTimeout:       30 * time.Second,
+		KeepAliveInterval:      5 * time.Second,
+		RequestTimeout:         30 * time.Second,
+		MaxRequestSize:         16 * 1024 * 1024, // 16MB - small files only
+		MaxResponseSize:        16 * 1024 * 1024, // 16MB - small files only
+		ChunkMetadataTimeout:   DefaultChunkMetadataTimeout,
+		ChunkCollectionTimeout: DefaultChunkCollectionTimeout,
+		RequireAuthentication:  true,
+		RateLimitRPM:           5000, // 5000 requests per minute per tunnel
+		RateLimitBurst:         500,  // 500 requests per minute per tunnel
 	}
 }
 
@@ -171,11 +269,18 @@ func NewGRPCTunnelServer(
 		tunnelRepo:    tunnelRepo,
 		tunnelService: tunnelService,
 		tunnelStreams: make(map[string]*TunnelStream),
+		resumable:     make(map[string]*resumableResponse),
 		config:        config,
 		rateLimiter:   NewRateLimiter(config.RateLimitRPM, config.RateLimitBurst),
 		security:      NewSecurityMiddleware(),
 		statusCache:   NewTunnelStatusCache(tunnelService, 5*time.Second), // Refresh every 5s
+		latency:       NewLatencyTracker(DefaultLatencyWindow),
+		health:        newHealthServer(),
+		cache:         newResponseCache(config.ResponseCacheSize, config.ResponseCacheTTL),
+
+		maxOriginTimeout: DefaultMaxOriginTimeout,
 	}
+	server.rateLimiter.SetClientLimit(config.ClientRateLimitRPM, config.ClientRateLimitBurst)
 
 	return server
 }
@@ -197,8 +302,11 @@ func (s *GRPCTunnelServer) Start(addr string) error {
 	// Create secure TLS configuration with mutual authentication
 	tlsConfig, err := CreateSecureServerTLSConfig(certPath, keyPath, caPath)
 	if err != nil {
-		s.logger.Warn("Failed to create secure TLS config, using fallback: %v", err)
-		// Fallback configuration for compatibility
+		// Without the CA the server cannot verify client certificates; only allow that in development
+		if env != "development" && env != "" {
+			return fmt.Errorf("failed to create secure TLS config: %w", err)
+		}
+		s.logger.Warn("⚠️  Failed to create secure TLS config, using fallback WITHOUT client certificate verification (development only): %v", err)
 		tlsConfig = &tls.Config{
 			GetCertificate: func(info *tls.ClientHelloInfo) (*tls.Certificate, error) {
 				cert, err := tls.LoadX509KeyPair(certPath, keyPath)
@@ -218,6 +326,29 @@ func (s *GRPCTunnelServer) Start(addr string) error {
 		s.logger.Info("🔐 gRPC Server using PRODUCTION-GRADE TLS with mutual authentication")
 	}
 
+	// Create listener
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to create listener: %w", err)
+	}
+	if err := s.serve(listener, tlsConfig); err != nil {
+		return err
+	}
+
+	// Start metrics reporting
+	go s.reportMetrics()
+	go s.reapPendingRequests()
+	go s.watchCertRevocations()
+
+	// Start tunnel status cache for fast active checks
+	s.statusCache.Start()
+
+	s.logger.Info("gRPC Tunnel Server started successfully")
+	return nil
+}
+
+// serve runs the tunnel service on listener, returning once it accepts connections
+func (s *GRPCTunnelServer) serve(listener net.Listener, tlsConfig *tls.Config) error {
 	creds := credentials.NewTLS(tlsConfig)
 
 	// Create gRPC server with production settings
@@ -236,37 +367,33 @@ func (s *GRPCTunnelServer) Start(addr string) error {
 		}),
 	)
 
-	// Register the tunnel service
+	// Register the tunnel service, plus grpc.health.v1 so orchestrators can probe the port
 	proto.RegisterTunnelServiceServer(s.grpcServer, s)
+	s.registerHealthServices(s.grpcServer)
 
-	// Create listener
-	listener, err := net.Listen("tcp", addr)
-	if err != nil {
-		return fmt.Errorf("failed to create listener: %w", err)
-	}
 	s.listener = listener
 
 	// Start server in background
 	go func() {
-		s.logger.Info("gRPC Tunnel Server starting on %s", addr)
+		s.logger.Info("gRPC Tunnel Server starting on %s", listener.Addr())
 		if err := s.grpcServer.Serve(s.listener); err != nil {
 			s.logger.Error("gRPC server error: %v", err)
 		}
 	}()
 
-	// Start metrics reporting
-	go s.reportMetrics()
-
-	// Start tunnel status cache for fast active checks
-	s.statusCache.Start()
-
-	s.logger.Info("gRPC Tunnel Server started successfully")
+	s.setServing(true)
+	if s.config.HealthListenAddr != "" {
+		if err := s.startHealthListener(s.config.HealthListenAddr); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
 // Stop gracefully stops the gRPC tunnel server
 func (s *GRPCTunnelServer) Stop() error {
 	s.logger.Info("Stopping gRPC Tunnel Server...")
+	s.MarkNotServing()
 
 	if s.grpcServer != nil {
 		// Graceful stop with timeout
@@ -291,6 +418,10 @@ func (s *GRPCTunnelServer) Stop() error {
 		s.statusCache.Stop()
 	}
 
+	if s.healthServer != nil {
+		s.healthServer.Stop()
+	}
+
 	return nil
 }
 
@@ -309,25 +440,61 @@ func (s *GRPCTunnelServer) EstablishTunnel(stream proto.TunnelService_EstablishT
 	// Validate handshake
 	handshake := handshakeMsg.GetControl().GetHandshake()
 	if handshake == nil {
-		return status.Errorf(codes.InvalidArgument, "invalid handshake message")
+		// Not a handshake this server knows: the client speaks another protocol version
+		return s.refuseHandshake(stream, handshakeMsg.RequestId, "", HandshakeVersionIncompatible, codes.InvalidArgument, "invalid handshake message")
+	}
+
+	if handshake.RequireSignedUrl && handshake.SignedUrlSecret == "" {
+		return s.refuseHandshake(stream, handshakeMsg.RequestId, handshake.Domain, HandshakeInvalidRequest, codes.InvalidArgument, "require_signed_url needs a signed_url_secret")
 	}
 
 	// Authenticate the tunnel
 	tunnel, err := s.authenticateTunnel(ctx, handshake)
 	if err != nil {
 		s.logger.Error("Tunnel authentication failed: %v", err)
-		return status.Errorf(codes.Unauthenticated, "authentication failed: %v", err)
+		code, grpcCode := handshakeCodeOf(err), codes.Unauthenticated
+		if code == HandshakeInternal {
+			grpcCode = codes.Internal
+		}
+		return s.refuseHandshake(stream, handshakeMsg.RequestId, handshake.Domain, code, grpcCode, fmt.Sprintf("authentication failed: %v", err))
 	}
 
 	s.logger.Info("Authenticated tunnel for domain: %s, user: %d", tunnel.Domain, tunnel.UserID)
 
-	// CRITICAL: Update client IP and trigger Caddy configuration (RESTORED FROM OLD HANDSHAKE)
+	// An owner over quota would have every request refused; tell the client why instead
+	if s.quota != nil {
+		if res, _ := s.quota.CheckUser(ctx, tunnel.UserID); res.Decision == QuotaBlock {
+			s.logger.Warn("Refusing tunnel for domain %s: user %d is over quota (%d/%d bytes)", tunnel.Domain, tunnel.UserID, res.UsedBytes, res.LimitBytes)
+			if err := stream.Send(quotaExceededMessage(handshakeMsg.RequestId, tunnel.Domain, res)); err != nil {
+				s.logger.Error("Failed to send quota exceeded response: %v", err)
+			}
+			return status.Errorf(codes.ResourceExhausted, "%v", ErrQuotaExceeded)
+		}
+	}
+
+	// Two clients for one domain would have them take the tunnel from each other in turn
 	clientIP := getPeerIP(ctx)
+	if s.config.DuplicateStreamPolicy == DuplicateStreamReject {
+		if existing := s.connectedStream(tunnel.Domain); existing != nil {
+			s.logger.Warn("Refusing second connection for domain %s from %s: a stream is already connected", tunnel.Domain, clientIP)
+			return s.refuseHandshake(stream, handshakeMsg.RequestId, tunnel.Domain, HandshakeAlreadyConnected, codes.AlreadyExists, "another client is already connected for this domain")
+		}
+	}
+
+	// One account can't hold more than its share of tunnel connections; taking over the
+	// domain's own stream doesn't add one
+	if s.userConnectionCheck != nil {
+		if err := s.userConnectionCheck(tunnel.UserID, s.connectedStream(tunnel.Domain) != nil); err != nil {
+			return s.refuseHandshake(stream, handshakeMsg.RequestId, tunnel.Domain, handshakeCodeOf(err), codes.ResourceExhausted, err.Error())
+		}
+	}
+
+	// CRITICAL: Update client IP and trigger Caddy configuration (RESTORED FROM OLD HANDSHAKE)
 	if s.tunnelService != nil {
 		s.logger.Info("🔧 Updating client IP and configuring Caddy for domain: %s -> %s", tunnel.Domain, clientIP)
 		if err := s.tunnelService.UpdateClientIP(ctx, uint32(tunnel.ID), clientIP); err != nil {
 			s.logger.Error("Failed to update client IP and configure Caddy: %v", err)
-			return status.Errorf(codes.Internal, "failed to configure tunnel: %v", err)
+			return s.refuseHandshake(stream, handshakeMsg.RequestId, tunnel.Domain, HandshakeInternal, codes.Internal, fmt.Sprintf("failed to configure tunnel: %v", err))
 		}
 		s.logger.Info("✅ Successfully configured Caddy route for domain: %s", tunnel.Domain)
 	} else {
@@ -348,23 +515,39 @@ func (s *GRPCTunnelServer) EstablishTunnel(stream proto.TunnelService_EstablishT
 		Context:         ctx,
 		UserID:          tunnel.UserID,
 		pendingRequests: make(map[string]chan *proto.TunnelMessage),
+		superseded:      make(chan struct{}),
+		clientCert:      peerCertificate(ctx),
+		revoked:         make(chan struct{}),
 		connected:       true,
 		lastActivity:    time.Now(),
 	}
+	if caps := handshake.Capabilities; caps != nil && caps.SupportsWebsocket {
+		tunnelStream.supportsWebSocket = true
+		tunnelStream.websockets = make(map[string]*wsBridgeConn)
+	}
+	if handshake.RequireSignedUrl {
+		tunnelStream.signedURLSecret = handshake.SignedUrlSecret
+		s.logger.Info("Tunnel %s only serves signed links", tunnel.Domain)
+	}
 
-	// Register tunnel stream
-	s.tunnelStreamsMux.Lock()
-	s.tunnelStreams[tunnel.Domain] = tunnelStream
-	s.tunnelStreamsMux.Unlock()
+	// Register tunnel stream, closing an older one for the domain
+	s.registerTunnelStream(tunnelStream, clientIP)
 
 	defer func() {
-		s.tunnelStreamsMux.Lock()
-		delete(s.tunnelStreams, tunnel.Domain)
-		s.tunnelStreamsMux.Unlock()
+		replaced := !s.unregisterTunnelStream(tunnelStream)
 
 		// Clean up all pending requests and chunked streaming state
 		s.cleanupTunnelStreamState(tunnelStream)
 
+		if replaced {
+			// A newer stream serves the domain now: keep its route and rate limits
+			s.logger.Info("Replaced tunnel stream closed for domain: %s", tunnel.Domain)
+			return
+		}
+
+		// A reconnecting tunnel starts with full buckets
+		s.rateLimiter.Reset(tunnel.Domain)
+
 		// CRITICAL: Remove Caddy route when tunnel disconnects (RESTORED FROM OLD HANDSHAKE)
 		// NOTE: Use background context for cleanup since the tunnel context is already cancelled
 		if s.tunnelService != nil {
@@ -394,6 +577,7 @@ func (s *GRPCTunnelServer) EstablishTunnel(stream proto.TunnelService_EstablishT
 						ConnectedAt:       time.Now().Unix(),
 						ActiveConnections: 1,
 						LastActivity:      time.Now().Unix(),
+						Capabilities:      acceptedCapabilities(handshake.Capabilities),
 					},
 				},
 			},
@@ -536,8 +720,69 @@ func (s *GRPCTunnelServer) HealthCheck(ctx context.Context, req *proto.HealthChe
 	}, nil
 }
 
-// ProxyHTTPRequest handles HTTP request proxying through the gRPC tunnel
+// GetMetrics returns the gRPC tunnel server's performance counters
+func (s *GRPCTunnelServer) GetMetrics() map[string]int64 {
+	s.tunnelStreamsMux.RLock()
+	activeTunnels := len(s.tunnelStreams)
+	s.tunnelStreamsMux.RUnlock()
+
+	metrics := map[string]int64{
+		"requests":            atomic.LoadInt64(&s.totalRequests),
+		"concurrent_requests": atomic.LoadInt64(&s.concurrentReqs),
+		"errors":              atomic.LoadInt64(&s.totalErrors),
+		"timeout_errors":      atomic.LoadInt64(&s.timeoutErrors),
+		"active_tunnels":      int64(activeTunnels),
+		"pending_requests":    int64(s.pendingRequestCount()),
+		"pending_reaped":      atomic.LoadInt64(&s.pendingReaped),
+	}
+	if s.cache != nil {
+		counts, size := s.cache.Stats()
+		for _, status := range []proto.CacheStatus{
+			proto.CacheStatus_CACHE_STATUS_HIT,
+			proto.CacheStatus_CACHE_STATUS_MISS,
+			proto.CacheStatus_CACHE_STATUS_STALE,
+			proto.CacheStatus_CACHE_STATUS_BYPASS,
+		} {
+			metrics["cache_"+strings.ToLower(cacheStatusName(status))] = counts[status]
+		}
+		metrics["cache_bytes"] = size
+	}
+	return metrics
+}
+
+// GetLatency returns the rolling time-to-response percentiles per domain
+func (s *GRPCTunnelServer) GetLatency() map[string]LatencyPercentiles {
+	return s.latency.Snapshot()
+}
+
+// GetActiveDomains returns the domains with a connected gRPC tunnel stream
+func (s *GRPCTunnelServer) GetActiveDomains() []string {
+	s.tunnelStreamsMux.RLock()
+	defer s.tunnelStreamsMux.RUnlock()
+
+	domains := make([]string, 0, len(s.tunnelStreams))
+	for domain, stream := range s.tunnelStreams {
+		if stream.connected {
+			domains = append(domains, domain)
+		}
+	}
+	return domains
+}
+
+// CheckRateLimit reports whether a request for domain from clientIP is within the rate limits,
+// and if not, how long the client should wait before retrying. Domains without an active
+// tunnel are not limited (they only get the offline page), so they never get buckets.
+func (s *GRPCTunnelServer) CheckRateLimit(domain, clientIP string) (bool, time.Duration) {
+	if !s.IsTunnelActive(domain) {
+		return true, 0
+	}
+	return s.rateLimiter.Reserve(domain, clientIP)
+}
+
+// ProxyHTTPRequest handles HTTP request proxying through the gRPC tunnel.
+// Rate limits are enforced by the router (CheckRateLimit) before the request gets here.
 func (s *GRPCTunnelServer) ProxyHTTPRequest(domain string, req *http.Request, clientIP string) (*http.Response, error) {
+	start := time.Now()
 	atomic.AddInt64(&s.totalRequests, 1)
 	atomic.AddInt64(&s.concurrentReqs, 1)
 	defer atomic.AddInt64(&s.concurrentReqs, -1)
@@ -586,21 +831,33 @@ func (s *GRPCTunnelServer) ProxyHTTPRequest(domain string, req *http.Request, cl
 			}
 		}
 	}
-	// Rate limiter check
-	if !s.rateLimiter.Allow(domain) {
-		atomic.AddInt64(&s.totalErrors, 1)
-		return nil, fmt.Errorf("rate limit exceeded for domain: %s", domain)
+
+	// Fresh cached responses skip the tunnel; stale ones are revalidated with the origin
+	var stale *cachedResponse
+	cacheStatus := proto.CacheStatus_CACHE_STATUS_UNKNOWN
+	originReq := req
+	if s.cache != nil {
+		var cached *http.Response
+		cached, stale, cacheStatus = s.serveFromCache(domain, req)
+		if cached != nil {
+			atomic.AddInt64(&s.totalResponses, 1)
+			return cached, nil
+		}
+		if stale != nil {
+			originReq = req.Clone(req.Context())
+			stale.setValidators(originReq)
+		}
 	}
 
 	// Convert HTTP request to protobuf message
-	grpcReq, err := s.httpToGRPC(req, clientIP)
+	grpcReq, err := s.httpToGRPC(originReq, clientIP)
 	if err != nil {
 		atomic.AddInt64(&s.totalErrors, 1)
 		return nil, fmt.Errorf("failed to convert HTTP request: %w", err)
 	}
 
 	// Send request and wait for response
-	response, err := s.sendRequestAndWaitResponse(tunnelStream, grpcReq)
+	response, err := s.sendRequestAndWaitResponse(req.Context(), tunnelStream, grpcReq)
 	if err != nil {
 		atomic.AddInt64(&s.totalErrors, 1)
 		if isTimeoutError(err) {
@@ -608,6 +865,7 @@ func (s *GRPCTunnelServer) ProxyHTTPRequest(domain string, req *http.Request, cl
 		}
 		return nil, err
 	}
+	s.latency.Observe(domain, time.Since(start))
 
 	// Usage: count request bytes, response bytes, and request count
 	if s.usage != nil {
@@ -616,24 +874,46 @@ func (s *GRPCTunnelServer) ProxyHTTPRequest(domain string, req *http.Request, cl
 			reqBytes = int64(len(b))
 		}
 
-		// Track response bytes by reading the response body length
-		var respBytes int64
-		if response != nil && response.Body != nil {
-			// Read response body to get accurate size
-			body, err := io.ReadAll(response.Body)
-			if err == nil {
-				respBytes = int64(len(body))
-				// Recreate the response body for the caller
-				response.Body = io.NopCloser(bytes.NewReader(body))
-			}
+		// Response bytes are counted as the visitor reads them, so streamed responses
+		// (SSE, chunked) aren't held back until they end
+		userID, tunnelID := tunnelStream.UserID, tunnelStream.TunnelID
+		record := func(respBytes int64) {
+			s.usage.Increment(userID, tunnelID, domain, reqBytes, respBytes, 1)
 		}
-
-		s.usage.Increment(tunnelStream.UserID, tunnelStream.TunnelID, domain, reqBytes, respBytes, 1)
+		if response.Body != nil {
+			response.Body = &usageBody{ReadCloser: response.Body, record: record}
+		} else {
+			record(0)
+		}
+	}
+	if s.cache != nil {
+		response = s.cacheResponse(domain, req, stale, cacheStatus, response)
 	}
 	atomic.AddInt64(&s.totalResponses, 1)
 	return response, nil
 }
 
+// usageBody counts the response bytes read through it and records them once, when the
+// response body is closed
+type usageBody struct {
+	io.ReadCloser
+	n      int64
+	once   sync.Once
+	record func(respBytes int64)
+}
+
+func (b *usageBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	return n, err
+}
+
+func (b *usageBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() { b.record(b.n) })
+	return err
+}
+
 // cleanupTunnelStreamState cleans up all state associated with a tunnel stream
 // This prevents stale state from interfering with reconnections
 func (s *GRPCTunnelServer) cleanupTunnelStreamState(tunnelStream *TunnelStream) {
@@ -645,6 +925,12 @@ func (s *GRPCTunnelServer) cleanupTunnelStreamState(tunnelStream *TunnelStream)
 
 	// Close all response channels and clear pending requests
 	for requestID, responseChan := range tunnelStream.pendingRequests {
+		// Streamed GETs wait for the client to resume them on a new stream
+		if s.isResumable(requestID, responseChan) {
+			pendingCount--
+			continue
+		}
+
 		s.logger.Debug("[CLEANUP] 🚮 Cleaning up pending request: %s", requestID)
 
 		// Safely close the channel to signal any waiting goroutines
@@ -663,6 +949,13 @@ func (s *GRPCTunnelServer) cleanupTunnelStreamState(tunnelStream *TunnelStream)
 
 	tunnelStream.requestsMux.Unlock()
 
+	s.parkResumableResponses(tunnelStream)
+
+	// The domain may be served by a different app when it comes back
+	if s.cache != nil {
+		s.cache.purgeDomain(tunnelStream.Domain)
+	}
+
 	if pendingCount > 0 {
 		s.logger.Info("[CLEANUP] ✅ Cleaned up %d pending requests for domain: %s", pendingCount, tunnelStream.Domain)
 		s.logger.Info("[CLEANUP] 🔄 Ready for clean reconnection - no stale state")
@@ -15,41 +15,9 @@ import (
 	"github.com/osa911/giraffecloud/internal/db/ent"
 	"github.com/osa911/giraffecloud/internal/tunnel/proto"
 
-	"golang.org/x/time/rate"
 	"google.golang.org/grpc/peer"
 )
 
-// RateLimiter provides rate limiting functionality for tunnel requests
-type RateLimiter struct {
-	limiters map[string]*rate.Limiter
-	mu       sync.RWMutex
-	rpm      int
-	burst    int
-}
-
-// NewRateLimiter creates a new rate limiter
-func NewRateLimiter(rpm, burst int) *RateLimiter {
-	return &RateLimiter{
-		limiters: make(map[string]*rate.Limiter),
-		rpm:      rpm,
-		burst:    burst,
-	}
-}
-
-// Allow checks if the request is allowed for the given domain
-func (rl *RateLimiter) Allow(domain string) bool {
-	rl.mu.Lock()
-	defer rl.mu.Unlock()
-
-	limiter, exists := rl.limiters[domain]
-	if !exists {
-		limiter = rate.NewLimiter(rate.Limit(rl.rpm)/60, rl.burst) // Convert RPM to per-second
-		rl.limiters[domain] = limiter
-	}
-
-	return limiter.Allow()
-}
-
 // SecurityMiddleware provides security functionality
 type SecurityMiddleware struct {
 	allowedOrigins map[string]bool
@@ -88,13 +56,16 @@ func getPeerIP(ctx context.Context) string {
 
 // authenticateTunnel authenticates a tunnel handshake using shared authentication logic
 func (s *GRPCTunnelServer) authenticateTunnel(ctx context.Context, handshake *proto.TunnelHandshake) (*ent.Tunnel, error) {
-	tunnel, err := AuthenticateTunnelByToken(ctx, handshake.Token, handshake.Domain, s.tokenRepo, s.tunnelRepo)
+	tunnelID := handshake.GetTunnelId()
+	tunnel, err := AuthenticateTunnelByToken(ctx, handshake.Token, handshake.Domain, tunnelID, s.tokenRepo, s.tunnelRepo)
 	if err != nil {
 		return nil, err
 	}
 
 	// Log successful authentication
-	if handshake.Domain != "" {
+	if tunnelID != 0 {
+		s.logger.Info("[gRPC AUTH] Matched tunnel by ID %d: domain=%s, target_port=%d", tunnelID, tunnel.Domain, tunnel.TargetPort)
+	} else if handshake.Domain != "" {
 		s.logger.Info("[gRPC AUTH] Matched active tunnel: domain=%s, target_port=%d", tunnel.Domain, tunnel.TargetPort)
 	} else {
 		s.logger.Info("[gRPC AUTH] Single active tunnel found, using: domain=%s, target_port=%d", tunnel.Domain, tunnel.TargetPort)
@@ -125,6 +96,8 @@ func (s *GRPCTunnelServer) handleClientMessages(tunnelStream *TunnelStream) {
 			s.handleUploadChunk(tunnelStream, msg)
 		case *proto.TunnelMessage_HttpRequestEnd:
 			s.handleUploadEnd(tunnelStream, msg)
+		case *proto.TunnelMessage_WebsocketData, *proto.TunnelMessage_WebsocketEnd:
+			s.handleWebSocketMessage(tunnelStream, msg)
 		case *proto.TunnelMessage_Control:
 			s.handleControlMessage(tunnelStream, msg)
 		case *proto.TunnelMessage_Error:
@@ -193,6 +166,28 @@ func (s *GRPCTunnelServer) handleHTTPResponse(tunnelStream *TunnelStream, msg *p
 	// in collectChunkedResponse() using io.Pipe(). The old buffering approach
 	// has been removed to prevent conflicts and memory issues.
 
+	// Undo per-chunk compression here so every consumer sees the original body
+	if encoding := httpResp.ChunkEncoding; encoding != "" {
+		body, err := decodeChunk(encoding, httpResp.Body)
+		if err != nil {
+			s.logger.Error("Failed to decode %s chunk for request %s: %v", encoding, msg.RequestId, err)
+			// Hand the waiting request an error instead of a corrupt body
+			s.handleRegularHTTPResponse(tunnelStream, &proto.TunnelMessage{
+				RequestId: msg.RequestId,
+				Timestamp: time.Now().Unix(),
+				MessageType: &proto.TunnelMessage_Error{
+					Error: &proto.ErrorMessage{
+						Code:    http.StatusBadGateway,
+						Message: fmt.Sprintf("invalid %s chunk: %v", encoding, err),
+					},
+				},
+			})
+			return
+		}
+		httpResp.Body = body
+		httpResp.ChunkEncoding = ""
+	}
+
 	// Handle all responses as regular responses (streaming is handled at request level)
 	s.handleRegularHTTPResponse(tunnelStream, msg)
 }
@@ -204,6 +199,11 @@ func (s *GRPCTunnelServer) handleRegularHTTPResponse(tunnelStream *TunnelStream,
 	tunnelStream.requestsMux.RUnlock()
 
 	if !exists {
+		// A chunk resuming a streamed GET that started on an earlier stream
+		if s.deliverResumed(tunnelStream, msg) {
+			return
+		}
+
 		// Suppress noisy warnings for late chunks after a client disconnect/cleanup
 		if httpResp := msg.GetHttpResponse(); httpResp != nil && httpResp.IsChunked {
 			// Don't log each late chunk - they're expected after client disconnection
@@ -268,6 +268,13 @@ func (s *GRPCTunnelServer) handleControlMessage(tunnelStream *TunnelStream, msg
 	switch controlType := control.ControlType.(type) {
 	case *proto.TunnelControl_Status:
 		status := controlType.Status
+
+		// Client health ping: echo it back so the client knows the stream is alive
+		if strings.HasPrefix(msg.RequestId, HealthPingRequestPrefix) {
+			s.replyHealthPing(tunnelStream, msg.RequestId)
+			return
+		}
+
 		s.logger.Debug("Tunnel %s status: %s, active connections: %d",
 			tunnelStream.Domain, status.State, status.ActiveConnections)
 
@@ -288,6 +295,36 @@ func (s *GRPCTunnelServer) handleControlMessage(tunnelStream *TunnelStream, msg
 	}
 }
 
+// HealthPingRequestPrefix marks TunnelControl status messages that are client health pings
+const HealthPingRequestPrefix = "health-ping-"
+
+// replyHealthPing answers a client health ping on the data stream
+func (s *GRPCTunnelServer) replyHealthPing(tunnelStream *TunnelStream, requestID string) {
+	reply := &proto.TunnelMessage{
+		RequestId: requestID,
+		Timestamp: time.Now().Unix(),
+		MessageType: &proto.TunnelMessage_Control{
+			Control: &proto.TunnelControl{
+				ControlType: &proto.TunnelControl_Status{
+					Status: &proto.TunnelStatus{
+						State:        proto.TunnelState_TUNNEL_STATE_ACTIVE,
+						Domain:       tunnelStream.Domain,
+						LastActivity: time.Now().Unix(),
+					},
+				},
+				Timestamp: time.Now().Unix(),
+			},
+		},
+	}
+
+	tunnelStream.sendMux.Lock()
+	err := tunnelStream.Stream.Send(reply)
+	tunnelStream.sendMux.Unlock()
+	if err != nil {
+		s.logger.Warn("Failed to reply to health ping from tunnel %s: %v", tunnelStream.Domain, err)
+	}
+}
+
 // handleErrorMessage handles error messages from the client
 func (s *GRPCTunnelServer) handleErrorMessage(tunnelStream *TunnelStream, msg *proto.TunnelMessage) {
 	errorMsg := msg.GetError()
@@ -317,6 +354,9 @@ func (s *GRPCTunnelServer) handleErrorMessage(tunnelStream *TunnelStream, msg *p
 			delete(tunnelStream.pendingRequests, msg.RequestId)
 			close(responseChan)
 			tunnelStream.requestsMux.Unlock()
+		} else {
+			// The client gave up resuming a streamed GET from an earlier stream
+			s.deliverResumed(tunnelStream, msg)
 		}
 	}
 }
@@ -332,6 +372,13 @@ func (s *GRPCTunnelServer) monitorTunnelHealth(tunnelStream *TunnelStream) error
 			s.logger.Info("Tunnel context cancelled for domain: %s", tunnelStream.Domain)
 			return tunnelStream.Context.Err()
 
+		case <-tunnelStream.superseded:
+			s.logger.Info("Closing replaced tunnel stream for domain: %s", tunnelStream.Domain)
+			return errStreamSuperseded
+
+		case <-tunnelStream.revoked:
+			return errStreamCertRevoked
+
 		case <-ticker.C:
 			// REVERSE PROXY OPTIMIZATION: Don't close on inactivity timeout
 			// Instead, rely on health check failures and gRPC keepalives to detect broken connections
@@ -397,8 +444,8 @@ func (s *GRPCTunnelServer) reportMetrics() {
 		// Estimate memory usage (15KB per tunnel)
 		estimatedMemoryMB := float64(activeStreams) * 15.0 / 1024.0
 
-		s.logger.Info("[gRPC METRICS] Active Streams: %d (idle: %d, active: %d), Estimated Memory: %.2f MB, Total Requests: %d, Concurrent: %d, Responses: %d, Errors: %d (Timeout: %d)",
-			activeStreams, idleStreams, activeStreams-idleStreams, estimatedMemoryMB, totalReqs, concurrentReqs, totalResponses, totalErrors, timeoutErrors)
+		s.logger.Info("[gRPC METRICS] Active Streams: %d (idle: %d, active: %d), Estimated Memory: %.2f MB, Total Requests: %d, Concurrent: %d, Pending: %d (reaped: %d), Responses: %d, Errors: %d (Timeout: %d)",
+			activeStreams, idleStreams, activeStreams-idleStreams, estimatedMemoryMB, totalReqs, concurrentReqs, s.pendingRequestCount(), atomic.LoadInt64(&s.pendingReaped), totalResponses, totalErrors, timeoutErrors)
 
 		// Warn if approaching MaxConcurrentStreams limit
 		maxStreams := s.config.MaxConcurrentStreams
@@ -462,10 +509,11 @@ func (s *GRPCTunnelServer) httpToGRPC(req *http.Request, clientIP string) (*prot
 	return grpcMsg, nil
 }
 
-// sendRequestAndWaitResponse sends a request and waits for the response
-func (s *GRPCTunnelServer) sendRequestAndWaitResponse(tunnelStream *TunnelStream, grpcMsg *proto.TunnelMessage) (*http.Response, error) {
-	// Create response channel
-	responseChan := make(chan *proto.TunnelMessage, 1)
+// sendRequestAndWaitResponse sends a request and waits for the response, giving up when ctx is done
+func (s *GRPCTunnelServer) sendRequestAndWaitResponse(ctx context.Context, tunnelStream *TunnelStream, grpcMsg *proto.TunnelMessage) (*http.Response, error) {
+	// Create response channel. A response may turn out chunked and continue on this channel,
+	// so leave room for a window of chunks that arrive before collection takes over.
+	responseChan := make(chan *proto.TunnelMessage, 2*MaxChunkWindowSize)
 
 	// Register pending request
 	tunnelStream.requestsMux.Lock()
@@ -502,7 +550,8 @@ func (s *GRPCTunnelServer) sendRequestAndWaitResponse(tunnelStream *TunnelStream
 
 			// Delegate availability of the channel to the streaming handler
 			// It will handle reading subsequent chunks and cleaning up
-			return s.collectChunkedResponseNoSend(tunnelStream, grpcMsg.RequestId, responseMsg)
+			resumable := grpcMsg.GetHttpRequest().GetMethod() == http.MethodGet
+			return s.collectChunkedResponseNoSend(ctx, tunnelStream, grpcMsg.RequestId, responseMsg, resumable)
 		}
 
 		// Convert response back to HTTP
@@ -518,6 +567,17 @@ func (s *GRPCTunnelServer) sendRequestAndWaitResponse(tunnelStream *TunnelStream
 		tunnelStream.requestsMux.Unlock()
 		return nil, fmt.Errorf("request timeout after %v", timeout)
 
+	case <-ctx.Done():
+		// The visitor went away or its deadline passed: free the slot and stop the client's work
+		tunnelStream.requestsMux.Lock()
+		if ch, exists := tunnelStream.pendingRequests[grpcMsg.RequestId]; exists && ch == responseChan {
+			delete(tunnelStream.pendingRequests, grpcMsg.RequestId)
+			close(responseChan)
+		}
+		tunnelStream.requestsMux.Unlock()
+		go s.sendCancel(tunnelStream, grpcMsg.RequestId, "downstream_cancelled")
+		return nil, fmt.Errorf("request cancelled: %w", ctx.Err())
+
 	case <-tunnelStream.Context.Done():
 		// Clean up on context cancellation - safe close (only if we still own the channel)
 		tunnelStream.requestsMux.Lock()
@@ -562,6 +622,8 @@ func (s *GRPCTunnelServer) grpcToHTTP(msg *proto.TunnelMessage) (*http.Response,
 	for key, value := range httpResp.Headers {
 		resp.Header.Set(key, value)
 	}
+	resp.Header.Del(OriginTimeoutHeader) // Only meaningful for streamed responses
+	applyTrailers(resp, httpResp, nil)
 
 	// Record usage best-effort (response bytes). Requests counted at call site.
 	if s.usage != nil {
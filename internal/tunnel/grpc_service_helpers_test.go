@@ -0,0 +1,75 @@
+package tunnel
+
+import (
+	"context"
+	"testing"
+
+	"github.com/osa911/giraffecloud/internal/api/mapper"
+	"github.com/osa911/giraffecloud/internal/db/ent"
+	"github.com/osa911/giraffecloud/internal/logging"
+	"github.com/osa911/giraffecloud/internal/repository"
+	"github.com/osa911/giraffecloud/internal/tunnel/proto"
+)
+
+// fakeTokenRepo accepts any token as belonging to user 1
+type fakeTokenRepo struct {
+	repository.TokenRepository
+}
+
+func (r *fakeTokenRepo) GetByToken(ctx context.Context, token string) (*mapper.Token, error) {
+	return &mapper.Token{UserID: 1}, nil
+}
+
+// fakeUserTunnelRepo answers GetByUserID with a fixed list of tunnels
+type fakeUserTunnelRepo struct {
+	repository.TunnelRepository
+	tunnels []*ent.Tunnel
+}
+
+func (r *fakeUserTunnelRepo) GetByUserID(ctx context.Context, userID uint32) ([]*ent.Tunnel, error) {
+	return r.tunnels, nil
+}
+
+func TestAuthenticateTunnelByHandshakeTunnelID(t *testing.T) {
+	initTestLogger(t)
+	s := &GRPCTunnelServer{
+		logger:    logging.GetGlobalLogger(),
+		tokenRepo: &fakeTokenRepo{},
+		tunnelRepo: &fakeUserTunnelRepo{tunnels: []*ent.Tunnel{
+			{ID: 1, Domain: "one.example.com", IsEnabled: true},
+			{ID: 2, Domain: "two.example.com", IsEnabled: true},
+		}},
+	}
+
+	tunnel, err := s.authenticateTunnel(context.Background(), &proto.TunnelHandshake{Token: "t", TunnelId: 2})
+	if err != nil || tunnel.Domain != "two.example.com" {
+		t.Errorf("Expected tunnel 2, got %v (%v)", tunnel, err)
+	}
+
+	if _, err := s.authenticateTunnel(context.Background(), &proto.TunnelHandshake{Token: "t", TunnelId: 3}); err == nil {
+		t.Error("Expected error for unknown tunnel ID")
+	}
+
+	// Without an ID or domain, several enabled tunnels are ambiguous
+	if _, err := s.authenticateTunnel(context.Background(), &proto.TunnelHandshake{Token: "t"}); err == nil {
+		t.Error("Expected error when no tunnel is selected")
+	}
+}
+
+func TestGRPCTunnelClient_HandshakeCarriesTunnelID(t *testing.T) {
+	initTestLogger(t)
+	client := NewGRPCTunnelClient("localhost:4444", "", "token", 8080, nil)
+	client.SetTunnelID(42)
+
+	var handshake *proto.TunnelHandshake
+	client.stream = &fakeTunnelStream{onSend: func(msg *proto.TunnelMessage) error {
+		handshake = msg.GetControl().GetHandshake()
+		return nil
+	}}
+	if err := client.sendHandshake(); err != nil {
+		t.Fatal(err)
+	}
+	if handshake.GetTunnelId() != 42 {
+		t.Errorf("Expected tunnel ID 42 in handshake, got %d", handshake.GetTunnelId())
+	}
+}
@@ -0,0 +1,132 @@
+package tunnel
+
+import (
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/osa911/giraffecloud/internal/tunnel/proto"
+)
+
+// StreamOffsetHeader carries the body offset of a response chunk sent after the client
+// re-issued a GET on a new stream, so the server can skip the bytes it already forwarded
+const StreamOffsetHeader = "X-Giraffecloud-Stream-Offset"
+
+// streamResumeGrace is how long a streamed GET response waits for the client to
+// resume it on a new tunnel stream before it is abandoned
+const streamResumeGrace = 30 * time.Second
+
+// resumableResponse is a streamed GET response that may continue on another tunnel
+// stream of the same tunnel after the one it started on breaks
+type resumableResponse struct {
+	requestID string
+	origin    *TunnelStream // Stream the request was sent on; owns the response channel
+	ch        chan *proto.TunnelMessage
+
+	via          atomic.Pointer[TunnelStream] // Stream the last resumed chunk arrived on
+	lastDelivery atomic.Int64                 // UnixNano of the last resumed chunk
+}
+
+// registerResumable lets chunks for requestID arrive on a later stream of the same tunnel
+func (s *GRPCTunnelServer) registerResumable(tunnelStream *TunnelStream, requestID string, ch chan *proto.TunnelMessage) {
+	s.resumableMux.Lock()
+	s.resumable[requestID] = &resumableResponse{requestID: requestID, origin: tunnelStream, ch: ch}
+	s.resumableMux.Unlock()
+}
+
+// unregisterResumable forgets requestID once its response collection has finished
+func (s *GRPCTunnelServer) unregisterResumable(requestID string, ch chan *proto.TunnelMessage) {
+	s.resumableMux.Lock()
+	if r, ok := s.resumable[requestID]; ok && r.ch == ch {
+		delete(s.resumable, requestID)
+	}
+	s.resumableMux.Unlock()
+}
+
+// isResumable reports whether ch is the channel of a resumable response
+func (s *GRPCTunnelServer) isResumable(requestID string, ch chan *proto.TunnelMessage) bool {
+	s.resumableMux.RLock()
+	defer s.resumableMux.RUnlock()
+	r, ok := s.resumable[requestID]
+	return ok && r.ch == ch
+}
+
+// deliverResumed routes a response message that arrived on tunnelStream for a request
+// sent on an earlier stream of the same tunnel. Returns false if there is no such request.
+func (s *GRPCTunnelServer) deliverResumed(tunnelStream *TunnelStream, msg *proto.TunnelMessage) bool {
+	s.resumableMux.RLock()
+	r, ok := s.resumable[msg.RequestId]
+	s.resumableMux.RUnlock()
+	if !ok || r.origin == tunnelStream ||
+		r.origin.Domain != tunnelStream.Domain || r.origin.UserID != tunnelStream.UserID {
+		return false
+	}
+
+	r.via.Store(tunnelStream)
+	r.lastDelivery.Store(time.Now().UnixNano())
+
+	r.origin.requestsMux.Lock()
+	defer r.origin.requestsMux.Unlock()
+	if ch, exists := r.origin.pendingRequests[msg.RequestId]; !exists || ch != r.ch {
+		return true // Collection already finished; drop the late chunk
+	}
+	select {
+	case r.ch <- msg:
+	case <-time.After(5 * time.Second):
+		s.logger.Warn("Timeout delivering resumed chunk for request ID: %s", msg.RequestId)
+	}
+	return true
+}
+
+// parkResumableResponses gives the resumable responses carried by a disconnected
+// stream streamResumeGrace to continue on a new stream, and abandons the rest
+func (s *GRPCTunnelServer) parkResumableResponses(tunnelStream *TunnelStream) {
+	disconnectedAt := time.Now().UnixNano()
+
+	s.resumableMux.RLock()
+	var parked []*resumableResponse
+	for _, r := range s.resumable {
+		if r.origin == tunnelStream || r.via.Load() == tunnelStream {
+			parked = append(parked, r)
+		}
+	}
+	s.resumableMux.RUnlock()
+
+	for _, r := range parked {
+		r := r
+		time.AfterFunc(streamResumeGrace, func() {
+			if r.lastDelivery.Load() > disconnectedAt {
+				return // Resumed on a new stream
+			}
+			r.origin.requestsMux.Lock()
+			if ch, exists := r.origin.pendingRequests[r.requestID]; exists && ch == r.ch {
+				s.logger.Info("[CLEANUP] Abandoning request %s: tunnel did not resume it within %v", r.requestID, streamResumeGrace)
+				delete(r.origin.pendingRequests, r.requestID)
+				close(ch)
+			}
+			r.origin.requestsMux.Unlock()
+		})
+	}
+}
+
+// resumedChunkBody returns the part of chunk's body not yet forwarded, given that written
+// bytes of the response body have been. Chunks without StreamOffsetHeader follow on directly.
+func resumedChunkBody(chunk *proto.HTTPResponse, written int64) ([]byte, error) {
+	value, ok := chunk.Headers[StreamOffsetHeader]
+	if !ok {
+		return chunk.Body, nil
+	}
+	offset, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || offset < 0 {
+		return nil, fmt.Errorf("invalid %s %q", StreamOffsetHeader, value)
+	}
+	if offset > written {
+		return nil, fmt.Errorf("resumed chunk at offset %d leaves a gap after %d bytes", offset, written)
+	}
+	skip := written - offset
+	if skip >= int64(len(chunk.Body)) {
+		return nil, nil
+	}
+	return chunk.Body[skip:], nil
+}
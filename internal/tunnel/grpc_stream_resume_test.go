@@ -0,0 +1,71 @@
+package tunnel
+
+import (
+	"testing"
+
+	"github.com/osa911/giraffecloud/internal/logging"
+	"github.com/osa911/giraffecloud/internal/tunnel/proto"
+)
+
+func TestResumedChunkBody(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		written int64
+		want    string
+		wantErr bool
+	}{
+		{"no offset header", nil, 10, "abcd", false},
+		{"already forwarded", map[string]string{StreamOffsetHeader: "0"}, 10, "", false},
+		{"overlaps", map[string]string{StreamOffsetHeader: "8"}, 10, "cd", false},
+		{"continues exactly", map[string]string{StreamOffsetHeader: "10"}, 10, "abcd", false},
+		{"gap", map[string]string{StreamOffsetHeader: "12"}, 10, "", true},
+		{"invalid", map[string]string{StreamOffsetHeader: "x"}, 10, "", true},
+	}
+	for _, tt := range tests {
+		chunk := &proto.HTTPResponse{Headers: tt.headers, Body: []byte("abcd")}
+		got, err := resumedChunkBody(chunk, tt.written)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if string(got) != tt.want {
+			t.Errorf("%s: body = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDeliverResumedRoutesToOriginStream(t *testing.T) {
+	initTestLogger(t)
+	s := &GRPCTunnelServer{logger: logging.GetGlobalLogger(), resumable: make(map[string]*resumableResponse)}
+
+	origin := &TunnelStream{Domain: "app.example.com", UserID: 1, pendingRequests: make(map[string]chan *proto.TunnelMessage)}
+	ch := make(chan *proto.TunnelMessage, 1)
+	origin.pendingRequests["req-1"] = ch
+	s.registerResumable(origin, "req-1", ch)
+
+	// The old stream disconnects: the resumable request keeps its channel open
+	s.cleanupTunnelStreamState(origin)
+	if _, ok := origin.pendingRequests["req-1"]; !ok {
+		t.Fatal("resumable request was cleaned up with its stream")
+	}
+
+	msg := &proto.TunnelMessage{RequestId: "req-1"}
+	other := &TunnelStream{Domain: "other.example.com", UserID: 1, pendingRequests: make(map[string]chan *proto.TunnelMessage)}
+	if s.deliverResumed(other, msg) {
+		t.Fatal("chunk from another tunnel was routed to the request")
+	}
+
+	next := &TunnelStream{Domain: "app.example.com", UserID: 1, pendingRequests: make(map[string]chan *proto.TunnelMessage)}
+	if !s.deliverResumed(next, msg) {
+		t.Fatal("chunk on the new stream was not routed")
+	}
+	if got := <-ch; got != msg {
+		t.Fatalf("delivered %v, want %v", got, msg)
+	}
+
+	s.unregisterResumable("req-1", ch)
+	if s.deliverResumed(next, msg) {
+		t.Fatal("chunk routed after the response finished")
+	}
+}
@@ -0,0 +1,511 @@
+package tunnel
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/osa911/giraffecloud/internal/tunnel/proto"
+)
+
+const (
+	// Frames buffered per bridged WebSocket; a session whose reader falls further behind
+	// is closed rather than stalling every request on the stream's receive loop
+	wsBridgeBuffer = 256
+
+	// Time the local service has to answer a WebSocket upgrade
+	wsUpgradeTimeout = 10 * time.Second
+
+	// Largest body forwarded with a refused (non-101) upgrade response
+	wsMaxRefusalBody = 64 * 1024
+)
+
+// errQuotaExceeded is returned by ProxyWebSocket when the domain owner is over quota
+var errQuotaExceeded = errors.New("quota exceeded")
+
+// wsBridgeConn carries one upgraded connection as WebSocketData/WebSocketEnd messages on
+// the tunnel stream. The receive loop delivers the peer's messages; reads return io.EOF
+// once the peer sends WebSocketEnd, done is closed or the bridge is closed. Close sends
+// WebSocketEnd unless the peer already ended the session.
+type wsBridgeConn struct {
+	requestID string
+	send      func(*proto.TunnelMessage) error
+	incoming  chan *proto.TunnelMessage
+	done      <-chan struct{}
+	onClose   func()
+
+	buf         []byte
+	remoteEnded atomic.Bool
+	closed      chan struct{}
+	closeOnce   sync.Once
+}
+
+func newWSBridgeConn(requestID string, send func(*proto.TunnelMessage) error, done <-chan struct{}, onClose func()) *wsBridgeConn {
+	return &wsBridgeConn{
+		requestID: requestID,
+		send:      send,
+		incoming:  make(chan *proto.TunnelMessage, wsBridgeBuffer),
+		done:      done,
+		onClose:   onClose,
+		closed:    make(chan struct{}),
+	}
+}
+
+// deliver hands a message from the peer to the reader without blocking. Returns false if
+// the bridge is closed or its buffer is full because the reader did not keep up.
+func (b *wsBridgeConn) deliver(msg *proto.TunnelMessage) bool {
+	select {
+	case <-b.closed:
+		return false
+	default:
+	}
+	select {
+	case b.incoming <- msg:
+		return true
+	default:
+		return false
+	}
+}
+
+func (b *wsBridgeConn) Read(p []byte) (int, error) {
+	for len(b.buf) == 0 {
+		if b.remoteEnded.Load() {
+			return 0, io.EOF
+		}
+		select {
+		case msg := <-b.incoming:
+			if data := msg.GetWebsocketData(); data != nil {
+				b.buf = data.Data
+			} else {
+				b.remoteEnded.Store(true)
+			}
+		case <-b.closed:
+			return 0, io.EOF
+		case <-b.done:
+			return 0, io.EOF
+		}
+	}
+	n := copy(p, b.buf)
+	b.buf = b.buf[n:]
+	return n, nil
+}
+
+func (b *wsBridgeConn) Write(p []byte) (int, error) {
+	select {
+	case <-b.closed:
+		return 0, io.ErrClosedPipe
+	default:
+	}
+	err := b.send(&proto.TunnelMessage{
+		RequestId: b.requestID,
+		Timestamp: time.Now().Unix(),
+		MessageType: &proto.TunnelMessage_WebsocketData{
+			WebsocketData: &proto.WebSocketData{
+				RequestId: b.requestID,
+				Data:      append([]byte(nil), p...),
+			},
+		},
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (b *wsBridgeConn) Close() error {
+	b.closeOnce.Do(func() {
+		close(b.closed)
+		if !b.remoteEnded.Load() {
+			b.send(&proto.TunnelMessage{
+				RequestId: b.requestID,
+				Timestamp: time.Now().Unix(),
+				MessageType: &proto.TunnelMessage_WebsocketEnd{
+					WebsocketEnd: &proto.WebSocketEnd{RequestId: b.requestID},
+				},
+			})
+		}
+		if b.onClose != nil {
+			b.onClose()
+		}
+	})
+	return nil
+}
+
+// SupportsWebSocket reports whether domain's tunnel is connected with a client that can
+// bridge WebSockets over the gRPC stream
+func (s *GRPCTunnelServer) SupportsWebSocket(domain string) bool {
+	s.tunnelStreamsMux.RLock()
+	tunnelStream, exists := s.tunnelStreams[domain]
+	s.tunnelStreamsMux.RUnlock()
+	return exists && tunnelStream.connected && tunnelStream.supportsWebSocket
+}
+
+// ProxyWebSocket forwards a WebSocket upgrade to the client over the gRPC stream and, once
+// the local service accepts it, bridges clientConn with it until either side closes.
+// An error means nothing was written to clientConn; otherwise the local service's
+// response (101 or a refusal) has been passed on.
+func (s *GRPCTunnelServer) ProxyWebSocket(domain string, clientConn net.Conn, r *http.Request, clientIP string) error {
+	s.tunnelStreamsMux.RLock()
+	tunnelStream, exists := s.tunnelStreams[domain]
+	s.tunnelStreamsMux.RUnlock()
+	if !exists || !tunnelStream.connected || !tunnelStream.supportsWebSocket {
+		return fmt.Errorf("no WebSocket-capable tunnel for domain: %s", domain)
+	}
+
+	if s.statusCache != nil && !s.statusCache.IsEnabled(domain) {
+		return fmt.Errorf("tunnel is disabled")
+	}
+	if s.quota != nil {
+		if res, _ := s.quota.CheckUser(context.Background(), tunnelStream.UserID); res.Decision == QuotaBlock {
+			return errQuotaExceeded
+		}
+	}
+
+	headers := make(map[string]string)
+	for key, values := range r.Header {
+		if len(values) > 0 {
+			headers[key] = values[0]
+		}
+	}
+	headers["Host"] = r.Host
+
+	requestID := generateRequestID()
+	responseChan := make(chan *proto.TunnelMessage, 1)
+	bridge := newWSBridgeConn(requestID, func(msg *proto.TunnelMessage) error {
+		tunnelStream.sendMux.Lock()
+		defer tunnelStream.sendMux.Unlock()
+		return tunnelStream.Stream.Send(msg)
+	}, tunnelStream.Context.Done(), func() {
+		tunnelStream.websocketsMux.Lock()
+		delete(tunnelStream.websockets, requestID)
+		tunnelStream.websocketsMux.Unlock()
+	})
+
+	tunnelStream.requestsMux.Lock()
+	tunnelStream.pendingRequests[requestID] = responseChan
+	tunnelStream.requestsMux.Unlock()
+	tunnelStream.websocketsMux.Lock()
+	tunnelStream.websockets[requestID] = bridge
+	tunnelStream.websocketsMux.Unlock()
+
+	// Drop the bridge and any unanswered upgrade on the way out
+	defer func() {
+		tunnelStream.requestsMux.Lock()
+		if ch, ok := tunnelStream.pendingRequests[requestID]; ok && ch == responseChan {
+			delete(tunnelStream.pendingRequests, requestID)
+			close(responseChan)
+		}
+		tunnelStream.requestsMux.Unlock()
+		bridge.Close()
+	}()
+
+	start := &proto.TunnelMessage{
+		RequestId: requestID,
+		Timestamp: time.Now().Unix(),
+		MessageType: &proto.TunnelMessage_WebsocketStart{
+			WebsocketStart: &proto.WebSocketStart{
+				RequestId: requestID,
+				Path:      r.URL.Path,
+				Query:     r.URL.RawQuery,
+				Headers:   headers,
+				ClientIp:  clientIP,
+			},
+		},
+	}
+	tunnelStream.sendMux.Lock()
+	err := tunnelStream.Stream.Send(start)
+	tunnelStream.sendMux.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to send WebSocket start: %w", err)
+	}
+
+	var reply *proto.TunnelMessage
+	select {
+	case msg, ok := <-responseChan:
+		if !ok {
+			return fmt.Errorf("tunnel closed before WebSocket upgrade completed")
+		}
+		reply = msg
+	case <-time.After(s.config.RequestTimeout):
+		return fmt.Errorf("WebSocket upgrade timeout after %v", s.config.RequestTimeout)
+	case <-tunnelStream.Context.Done():
+		return fmt.Errorf("tunnel closed before WebSocket upgrade completed")
+	}
+	if errMsg := reply.GetError(); errMsg != nil {
+		return fmt.Errorf("client error: %s", errMsg.Message)
+	}
+	httpResp := reply.GetHttpResponse()
+	if httpResp == nil {
+		return fmt.Errorf("unexpected reply to WebSocket upgrade: %T", reply.MessageType)
+	}
+
+	response := &http.Response{
+		StatusCode: int(httpResp.StatusCode),
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     make(http.Header),
+		Request:    r, // A GET keeps Content-Length off the 101
+	}
+	for key, value := range httpResp.Headers {
+		response.Header.Set(key, value)
+	}
+	response.Header.Set(TransportHeader, TransportGRPC)
+	if response.StatusCode != http.StatusSwitchingProtocols {
+		response.ContentLength = int64(len(httpResp.Body))
+		response.Body = io.NopCloser(bytes.NewReader(httpResp.Body))
+		response.Close = true
+	}
+
+	clientWriter := bufio.NewWriter(clientConn)
+	if err := response.Write(clientWriter); err != nil {
+		s.logger.Debug("[WEBSOCKET] Failed to write upgrade response for %s: %v", domain, err)
+		return nil
+	}
+	if err := clientWriter.Flush(); err != nil {
+		s.logger.Debug("[WEBSOCKET] Failed to flush upgrade response for %s: %v", domain, err)
+		return nil
+	}
+	if response.StatusCode != http.StatusSwitchingProtocols {
+		s.logger.Debug("[WEBSOCKET] Local service refused upgrade for %s with status %d", domain, response.StatusCode)
+		return nil
+	}
+
+	s.logger.Debug("[WEBSOCKET] Bridging WebSocket over gRPC for domain %s (request %s)", domain, requestID)
+
+	// Close the connection if it goes idle; frames towards the local service are masked
+	monitor := newWSIdleMonitor(s.wsIdleTimeout, s.wsPingInterval)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		if monitor.run(done, wsPeer{conn: clientConn}, wsPeer{conn: bridge, masked: true}, isWebSocketUpgrade(response)) {
+			s.logger.Info("[WEBSOCKET] Closed WebSocket for domain %s after %v without traffic", domain, monitor.idleTimeout)
+		}
+	}()
+
+	errChan := make(chan error, 2)
+	go func() {
+		n, err := io.Copy(bridge, monitor.reader(clientConn))
+		atomic.AddInt64(&s.totalBytesIn, n)
+		if s.usage != nil && n > 0 {
+			s.usage.Increment(tunnelStream.UserID, tunnelStream.TunnelID, domain, n, 0, 0)
+		}
+		errChan <- err
+	}()
+	go func() {
+		n, err := io.Copy(clientConn, monitor.reader(bridge))
+		atomic.AddInt64(&s.totalBytesOut, n)
+		if s.usage != nil && n > 0 {
+			s.usage.Increment(tunnelStream.UserID, tunnelStream.TunnelID, domain, 0, n, 1)
+		}
+		errChan <- err
+	}()
+
+	// Either side finishing ends the session: closing both unblocks the other copy
+	if err := <-errChan; err != nil {
+		s.logger.Debug("[WEBSOCKET] Bridged WebSocket for %s closed: %v", domain, err)
+	}
+	bridge.Close()
+	clientConn.Close()
+	<-errChan
+
+	s.logger.Debug("[WEBSOCKET] Bridged WebSocket session completed for domain: %s", domain)
+	return nil
+}
+
+// handleWebSocketMessage routes WebSocketData/WebSocketEnd from the client to its session
+func (s *GRPCTunnelServer) handleWebSocketMessage(tunnelStream *TunnelStream, msg *proto.TunnelMessage) {
+	tunnelStream.websocketsMux.Lock()
+	bridge := tunnelStream.websockets[msg.RequestId]
+	tunnelStream.websocketsMux.Unlock()
+	if bridge == nil {
+		// Late frames after either side closed are expected
+		s.logger.Debug("Received WebSocket message for unknown request ID: %s", msg.RequestId)
+		return
+	}
+	if !bridge.deliver(msg) {
+		s.logger.Warn("Backpressure: closing bridged WebSocket %s for %s", msg.RequestId, tunnelStream.Domain)
+		bridge.Close()
+	}
+}
+
+// handleWebSocketStart opens a WebSocket to the local service for the server and bridges it
+// over the stream. The upgrade response goes back as an HTTPResponse; failures to reach the
+// local service are reported as errors.
+func (c *GRPCTunnelClient) handleWebSocketStart(msg *proto.TunnelMessage) error {
+	start := msg.GetWebsocketStart()
+	if start == nil {
+		return nil
+	}
+	atomic.AddInt64(&c.totalRequests, 1)
+	c.recordUsage(0, 0, 1)
+
+	// Bind the session to the current stream: it ends with that stream
+	stream := c.stream
+	if stream == nil {
+		return fmt.Errorf("stream connection lost")
+	}
+
+	requestID := msg.RequestId
+	bridge := newWSBridgeConn(requestID, func(m *proto.TunnelMessage) error {
+		c.sendMux.Lock()
+		defer c.sendMux.Unlock()
+		return stream.Send(m)
+	}, c.ctx.Done(), func() {
+		c.wsSessionsMu.Lock()
+		delete(c.wsSessions, requestID)
+		c.wsSessionsMu.Unlock()
+	})
+	c.wsSessionsMu.Lock()
+	c.wsSessions[requestID] = bridge
+	c.wsSessionsMu.Unlock()
+
+	go c.bridgeLocalWebSocket(start, bridge)
+	return nil
+}
+
+// bridgeLocalWebSocket performs the upgrade against the local service and copies frames
+// between it and the bridge until either side closes
+func (c *GRPCTunnelClient) bridgeLocalWebSocket(start *proto.WebSocketStart, bridge *wsBridgeConn) {
+	defer bridge.Close()
+
+	addr := localServiceAddr(c.localHost, int(c.targetPort))
+	dialer := &net.Dialer{Timeout: wsUpgradeTimeout}
+	var localConn net.Conn
+	var err error
+	if c.config.LocalScheme == "https" {
+		// Same trust as localTransport: local services commonly use self-signed certificates
+		localConn, err = tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{InsecureSkipVerify: true})
+	} else {
+		localConn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		bridge.remoteEnded.Store(true) // The server only waits for the error below
+		c.sendErrorResponse(bridge.requestID, fmt.Sprintf("Local service WebSocket dial failed: %v", err))
+		return
+	}
+	defer localConn.Close()
+
+	// Replay the upgrade request; hop-by-hop Connection/Upgrade headers are required here
+	target := start.Path
+	if start.Query != "" {
+		target += "?" + start.Query
+	}
+	target = c.paths.apply(target)
+	host := start.Headers["Host"]
+	if host == "" {
+		host = addr
+	}
+	var request strings.Builder
+	fmt.Fprintf(&request, "GET %s HTTP/1.1\r\nHost: %s\r\n", target, host)
+	for key, value := range start.Headers {
+		if key != "Host" {
+			fmt.Fprintf(&request, "%s: %s\r\n", key, value)
+		}
+	}
+	request.WriteString("\r\n")
+
+	localConn.SetDeadline(time.Now().Add(wsUpgradeTimeout))
+	if _, err := io.WriteString(localConn, request.String()); err != nil {
+		bridge.remoteEnded.Store(true)
+		c.sendErrorResponse(bridge.requestID, fmt.Sprintf("Failed to send WebSocket upgrade: %v", err))
+		return
+	}
+	localReader := bufio.NewReader(localConn)
+	resp, err := http.ReadResponse(localReader, nil)
+	if err != nil {
+		bridge.remoteEnded.Store(true)
+		c.sendErrorResponse(bridge.requestID, fmt.Sprintf("Failed to read WebSocket upgrade response: %v", err))
+		return
+	}
+	localConn.SetDeadline(time.Time{})
+
+	headers := make(map[string]string)
+	for key, values := range resp.Header {
+		if len(values) > 0 {
+			headers[key] = values[0]
+		}
+	}
+	var body []byte
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		body, _ = io.ReadAll(io.LimitReader(resp.Body, wsMaxRefusalBody))
+		resp.Body.Close()
+		bridge.remoteEnded.Store(true) // The server stops listening after a refusal
+	}
+	if err := bridge.send(&proto.TunnelMessage{
+		RequestId: bridge.requestID,
+		Timestamp: time.Now().Unix(),
+		MessageType: &proto.TunnelMessage_HttpResponse{
+			HttpResponse: &proto.HTTPResponse{
+				StatusCode: int32(resp.StatusCode),
+				StatusText: resp.Status,
+				Headers:    headers,
+				Body:       body,
+			},
+		},
+	}); err != nil || resp.StatusCode != http.StatusSwitchingProtocols {
+		return
+	}
+	atomic.AddInt64(&c.totalResponses, 1)
+
+	errChan := make(chan error, 2)
+	go func() {
+		// localReader may already hold frames sent right after the upgrade
+		n, err := io.Copy(bridge, localReader)
+		atomic.AddInt64(&c.bytesOut, n)
+		c.recordUsage(0, n, 0)
+		errChan <- err
+	}()
+	go func() {
+		n, err := io.Copy(localConn, bridge)
+		atomic.AddInt64(&c.bytesIn, n)
+		c.recordUsage(n, 0, 0)
+		errChan <- err
+	}()
+
+	if err := <-errChan; err != nil {
+		c.logger.Debug("[%s] Bridged WebSocket %s closed: %v", c.clientID, bridge.requestID, err)
+	}
+	bridge.Close()
+	localConn.Close()
+	<-errChan
+}
+
+// handleWebSocketMessage routes WebSocketData/WebSocketEnd from the server to its session
+func (c *GRPCTunnelClient) handleWebSocketMessage(msg *proto.TunnelMessage) error {
+	c.wsSessionsMu.Lock()
+	bridge := c.wsSessions[msg.RequestId]
+	c.wsSessionsMu.Unlock()
+	if bridge == nil {
+		return nil
+	}
+	if !bridge.deliver(msg) {
+		c.logger.Warn("[%s] Backpressure: closing bridged WebSocket %s", c.clientID, msg.RequestId)
+		bridge.Close()
+	}
+	return nil
+}
+
+// closeWebSocketSessions ends every bridged WebSocket, e.g. when the stream they ran on is gone
+func (c *GRPCTunnelClient) closeWebSocketSessions() {
+	c.wsSessionsMu.Lock()
+	sessions := make([]*wsBridgeConn, 0, len(c.wsSessions))
+	for _, bridge := range c.wsSessions {
+		sessions = append(sessions, bridge)
+	}
+	c.wsSessionsMu.Unlock()
+
+	for _, bridge := range sessions {
+		bridge.Close()
+	}
+}
@@ -0,0 +1,139 @@
+package tunnel
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/osa911/giraffecloud/internal/tunnel/proto"
+)
+
+func TestBridgeLocalWebSocket(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	// Minimal WebSocket server: accept the upgrade, then echo raw bytes
+	upgrades := make(chan *http.Request, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		req, err := http.ReadRequest(reader)
+		if err != nil {
+			return
+		}
+		upgrades <- req
+		io.WriteString(conn, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n")
+		io.Copy(conn, reader)
+	}()
+
+	_, portStr, _ := net.SplitHostPort(listener.Addr().String())
+	port, _ := strconv.Atoi(portStr)
+	c := NewGRPCTunnelClient("", "example.com", "", int32(port), DefaultGRPCClientConfig())
+	c.SetLocalHost("127.0.0.1")
+
+	sent := make(chan *proto.TunnelMessage, 16)
+	bridge := newWSBridgeConn("ws-1", func(msg *proto.TunnelMessage) error {
+		sent <- msg
+		return nil
+	}, nil, nil)
+
+	finished := make(chan struct{})
+	go func() {
+		c.bridgeLocalWebSocket(&proto.WebSocketStart{
+			RequestId: "ws-1",
+			Path:      "/socket",
+			Query:     "room=1",
+			Headers:   map[string]string{"Host": "example.com", "Upgrade": "websocket", "Connection": "Upgrade"},
+		}, bridge)
+		close(finished)
+	}()
+
+	next := func() *proto.TunnelMessage {
+		t.Helper()
+		select {
+		case msg := <-sent:
+			return msg
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for a message to the server")
+			return nil
+		}
+	}
+
+	if resp := next().GetHttpResponse(); resp == nil || resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("upgrade reply = %v, want 101", resp)
+	}
+	req := <-upgrades
+	if req.URL.RequestURI() != "/socket?room=1" || req.Host != "example.com" || req.Header.Get("Upgrade") != "websocket" {
+		t.Fatalf("local service got %s %s (Host %q, Upgrade %q)", req.Method, req.URL, req.Host, req.Header.Get("Upgrade"))
+	}
+
+	// Frames from the server reach the local service and its replies come back
+	bridge.deliver(&proto.TunnelMessage{
+		RequestId:   "ws-1",
+		MessageType: &proto.TunnelMessage_WebsocketData{WebsocketData: &proto.WebSocketData{RequestId: "ws-1", Data: []byte("hello")}},
+	})
+	var echoed []byte
+	for len(echoed) < len("hello") {
+		data := next().GetWebsocketData()
+		if data == nil {
+			t.Fatal("expected echoed WebSocket data")
+		}
+		echoed = append(echoed, data.Data...)
+	}
+	if string(echoed) != "hello" {
+		t.Fatalf("echoed %q, want %q", echoed, "hello")
+	}
+
+	// WebSocketEnd from the server closes the local connection without echoing an end back
+	bridge.deliver(&proto.TunnelMessage{
+		RequestId:   "ws-1",
+		MessageType: &proto.TunnelMessage_WebsocketEnd{WebsocketEnd: &proto.WebSocketEnd{RequestId: "ws-1"}},
+	})
+	select {
+	case <-finished:
+	case <-time.After(5 * time.Second):
+		t.Fatal("bridge did not finish after WebSocketEnd")
+	}
+	for len(sent) > 0 {
+		if msg := <-sent; msg.GetWebsocketEnd() != nil {
+			t.Fatal("client answered the server's WebSocketEnd with its own")
+		}
+	}
+}
+
+func TestWSBridgeConn_DeliverDoesNotBlockWhenFull(t *testing.T) {
+	bridge := newWSBridgeConn("ws-1", func(*proto.TunnelMessage) error { return nil }, nil, nil)
+	msg := &proto.TunnelMessage{
+		RequestId:   "ws-1",
+		MessageType: &proto.TunnelMessage_WebsocketData{WebsocketData: &proto.WebSocketData{RequestId: "ws-1", Data: []byte("x")}},
+	}
+	for i := 0; i < wsBridgeBuffer; i++ {
+		if !bridge.deliver(msg) {
+			t.Fatalf("deliver %d refused before the buffer was full", i)
+		}
+	}
+
+	start := time.Now()
+	if bridge.deliver(msg) {
+		t.Fatal("deliver accepted a message past the buffer")
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Fatalf("deliver on a full bridge blocked for %v", elapsed)
+	}
+
+	bridge.Close()
+	if bridge.deliver(msg) {
+		t.Fatal("deliver accepted a message after Close")
+	}
+}
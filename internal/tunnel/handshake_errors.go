@@ -0,0 +1,106 @@
+package tunnel
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/osa911/giraffecloud/internal/tunnel/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// HandshakeErrorCode says why the server refused a tunnel handshake. It travels in
+// TunnelStatus.error_code on the gRPC tunnel and in TunnelHandshakeResponse.Code on TCP
+// connections, so clients can decide how to react without parsing messages.
+type HandshakeErrorCode string
+
+const (
+	HandshakeInvalidToken        HandshakeErrorCode = "INVALID_TOKEN"        // Unknown or revoked API token
+	HandshakeNoTunnel            HandshakeErrorCode = "NO_TUNNEL"            // No enabled tunnel matches the token, domain or ID
+	HandshakeMaintenance         HandshakeErrorCode = "MAINTENANCE"          // Server is going down or under maintenance; come back later
+	HandshakeQuotaExceeded       HandshakeErrorCode = "QUOTA_EXCEEDED"       // Owner is over their bandwidth quota
+	HandshakeVersionIncompatible HandshakeErrorCode = "VERSION_INCOMPATIBLE" // Server doesn't understand this client's handshake
+	HandshakeInvalidRequest      HandshakeErrorCode = "INVALID_REQUEST"      // Handshake options the server rejects as given
+	HandshakeAlreadyConnected    HandshakeErrorCode = "ALREADY_CONNECTED"    // Another client holds the domain's tunnel (DuplicateStreamReject)
+	HandshakeTooManyConnections  HandshakeErrorCode = "TOO_MANY_CONNECTIONS" // Owner holds MaxConnectionsPerUser connections already
+	HandshakeInternal            HandshakeErrorCode = "INTERNAL"             // Server-side failure; retrying may help
+)
+
+// Permanent reports whether retrying the same handshake can't succeed until the user
+// changes something (token, tunnel, client version or options)
+func (c HandshakeErrorCode) Permanent() bool {
+	switch c {
+	case HandshakeInvalidToken, HandshakeNoTunnel, HandshakeVersionIncompatible, HandshakeInvalidRequest:
+		return true
+	}
+	return false
+}
+
+// HandshakeError is a handshake refused by the server with a code
+type HandshakeError struct {
+	Code    HandshakeErrorCode
+	Message string
+}
+
+func (e *HandshakeError) Error() string {
+	return e.Message
+}
+
+// Is makes QUOTA_EXCEEDED refusals match ErrQuotaExceeded
+func (e *HandshakeError) Is(target error) bool {
+	return target == ErrQuotaExceeded && e.Code == HandshakeQuotaExceeded
+}
+
+// handshakeErrorf returns a HandshakeError with a formatted message
+func handshakeErrorf(code HandshakeErrorCode, format string, args ...interface{}) error {
+	return &HandshakeError{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// handshakeErrorCode returns the code of a refused handshake anywhere in err's chain
+func handshakeErrorCode(err error) (HandshakeErrorCode, bool) {
+	var handshakeErr *HandshakeError
+	if errors.As(err, &handshakeErr) {
+		return handshakeErr.Code, true
+	}
+	return "", false
+}
+
+// handshakeCodeOf returns the code to send for an error refusing a handshake; errors that
+// don't carry one are server-side failures
+func handshakeCodeOf(err error) HandshakeErrorCode {
+	if code, ok := handshakeErrorCode(err); ok {
+		return code
+	}
+	return HandshakeInternal
+}
+
+// handshakeRefusedMessage is the gRPC handshake response refusing a tunnel with code
+func handshakeRefusedMessage(requestID, domain string, code HandshakeErrorCode, message string) *proto.TunnelMessage {
+	return &proto.TunnelMessage{
+		RequestId: requestID,
+		Timestamp: time.Now().Unix(),
+		MessageType: &proto.TunnelMessage_Control{
+			Control: &proto.TunnelControl{
+				ControlType: &proto.TunnelControl_Status{
+					Status: &proto.TunnelStatus{
+						State:        proto.TunnelState_TUNNEL_STATE_ERROR,
+						Domain:       domain,
+						ErrorMessage: message,
+						ErrorCode:    string(code),
+					},
+				},
+			},
+		},
+	}
+}
+
+// refuseHandshake sends the client a status refusing the tunnel with code, then returns the
+// gRPC error ending the stream. Clients that predate codes still see grpcCode and message.
+func (s *GRPCTunnelServer) refuseHandshake(stream proto.TunnelService_EstablishTunnelServer, requestID, domain string, code HandshakeErrorCode, grpcCode codes.Code, message string) error {
+	if err := stream.Send(handshakeRefusedMessage(requestID, domain, code, message)); err != nil {
+		s.logger.Error("Failed to send handshake refusal: %v", err)
+	}
+	return status.Error(grpcCode, message)
+}
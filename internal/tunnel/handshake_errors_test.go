@@ -0,0 +1,57 @@
+package tunnel
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestHandshakeErrorCodes(t *testing.T) {
+	initTestLogger(t)
+	ts, err := NewTestServer()
+	if err != nil {
+		t.Fatalf("NewTestServer: %v", err)
+	}
+	defer ts.Close()
+	t.Setenv("GIRAFFECLOUD_HOME", ts.ConfigHome)
+
+	start := func(domain, token string) error {
+		client := NewGRPCTunnelClient(ts.ServerAddr(), domain, token, int32(ts.LocalPort()), DefaultGRPCClientConfig())
+		defer client.Stop()
+		return client.Start()
+	}
+	tests := []struct {
+		name, domain, token string
+		want                HandshakeErrorCode
+	}{
+		{"revoked token", ts.Domain, "not-a-token", HandshakeInvalidToken},
+		{"unknown domain", "other.example.com", ts.Token, HandshakeNoTunnel},
+	}
+	for _, tt := range tests {
+		err := start(tt.domain, tt.token)
+		if code, ok := handshakeErrorCode(err); !ok || code != tt.want {
+			t.Errorf("%s: Start() = %v, want a %s refusal", tt.name, err, tt.want)
+		}
+		if !isAuthenticationError(err) {
+			t.Errorf("%s: %v should stop the retry loop", tt.name, err)
+		}
+	}
+
+	// The code decides, whatever the message says
+	wrapped := fmt.Errorf("handshake failed: %w", &HandshakeError{Code: HandshakeMaintenance, Message: "back at 10:00 UTC"})
+	if !isMaintenanceError(wrapped) || isAuthenticationError(wrapped) {
+		t.Errorf("MAINTENANCE refusal classified wrong: maintenance=%v auth=%v", isMaintenanceError(wrapped), isAuthenticationError(wrapped))
+	}
+	internal := &HandshakeError{Code: HandshakeInternal, Message: "authentication failed: failed to look up token"}
+	if isAuthenticationError(internal) {
+		t.Error("INTERNAL refusals must be retried")
+	}
+	if !errors.Is(&HandshakeError{Code: HandshakeQuotaExceeded}, ErrQuotaExceeded) {
+		t.Error("QUOTA_EXCEEDED refusal should match ErrQuotaExceeded")
+	}
+
+	// Servers without codes are still understood by their messages
+	if !isAuthenticationError(errors.New("handshake failed: invalid token: not found")) {
+		t.Error("uncoded invalid token error should stop the retry loop")
+	}
+}
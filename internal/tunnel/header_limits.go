@@ -0,0 +1,71 @@
+package tunnel
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// DefaultMaxHeaderBytes caps the request line plus headers of proxied requests, and the
+// status line plus headers of responses read from tunnel connections
+const DefaultMaxHeaderBytes = 64 << 10
+
+// ErrHeaderTooLarge is returned when a request or response head exceeds the configured limit
+var ErrHeaderTooLarge = errors.New("header fields too large")
+
+// checkRequestHeaderSize rejects a raw request head larger than limit (0 = no limit)
+func checkRequestHeaderSize(requestData []byte, limit int) error {
+	if limit > 0 && len(requestData) > limit {
+		return fmt.Errorf("request head is %d bytes, limit %d: %w", len(requestData), limit, ErrHeaderTooLarge)
+	}
+	return nil
+}
+
+// newTunnelResponseReader buffers a tunnel connection so a whole response head within
+// limit fits, letting readTunnelResponse check it before parsing
+func newTunnelResponseReader(r io.Reader, limit int) *bufio.Reader {
+	if limit <= 0 {
+		return bufio.NewReader(r)
+	}
+	return bufio.NewReaderSize(r, limit)
+}
+
+// readTunnelResponse reads a response off a TCP tunnel connection, failing with
+// ErrHeaderTooLarge when its head doesn't end within limit bytes (0 = no limit), and marks
+// it with TransportHeader. br must come from newTunnelResponseReader.
+func readTunnelResponse(br *bufio.Reader, req *http.Request, limit int) (*http.Response, error) {
+	if limit > 0 {
+		if err := peekResponseHead(br, limit); err != nil {
+			return nil, err
+		}
+	}
+	response, err := http.ReadResponse(br, req)
+	if err != nil {
+		return nil, err
+	}
+	response.Header.Set(TransportHeader, TransportTCP)
+	return response, nil
+}
+
+// peekResponseHead buffers input until the blank line ending the head shows up, without
+// consuming anything. Read errors are left for http.ReadResponse to report.
+func peekResponseHead(br *bufio.Reader, limit int) error {
+	scanned := 0
+	for n := 1; ; {
+		if _, err := br.Peek(n); err != nil {
+			return nil
+		}
+		buf, _ := br.Peek(br.Buffered())
+		if bytes.Contains(buf[max(scanned-3, 0):], []byte("\r\n\r\n")) || bytes.Contains(buf[max(scanned-1, 0):], []byte("\n\n")) {
+			return nil
+		}
+		if len(buf) >= limit {
+			return fmt.Errorf("response head exceeds %d bytes: %w", limit, ErrHeaderTooLarge)
+		}
+		scanned = len(buf)
+		n = min(len(buf)+1, limit)
+	}
+}
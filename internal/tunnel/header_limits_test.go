@@ -0,0 +1,63 @@
+package tunnel
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestParseHTTPRequestHeaderLimit(t *testing.T) {
+	r := &HybridTunnelRouter{config: &HybridRouterConfig{MaxHeaderBytes: 256}}
+
+	small := "GET / HTTP/1.1\r\nHost: app.example.com\r\n\r\n"
+	if _, err := r.parseHTTPRequest([]byte(small), nil); err != nil {
+		t.Fatalf("small request rejected: %v", err)
+	}
+
+	large := "GET / HTTP/1.1\r\nHost: app.example.com\r\nCookie: " + strings.Repeat("a", 300) + "\r\n\r\n"
+	if _, err := r.parseHTTPRequest([]byte(large), nil); !errors.Is(err, ErrHeaderTooLarge) {
+		t.Fatalf("large request: got %v, want ErrHeaderTooLarge", err)
+	}
+
+	r.config.MaxHeaderBytes = 0
+	if _, err := r.parseHTTPRequest([]byte(large), nil); err != nil {
+		t.Fatalf("unlimited: large request rejected: %v", err)
+	}
+}
+
+func TestReadTunnelResponseHeaderLimit(t *testing.T) {
+	const limit = 256
+	read := func(raw string) (string, error) {
+		br := newTunnelResponseReader(io.MultiReader(strings.NewReader(raw[:len(raw)/2]), strings.NewReader(raw[len(raw)/2:])), limit)
+		resp, err := readTunnelResponse(br, nil, limit)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		return string(body), err
+	}
+
+	body, err := read("HTTP/1.1 200 OK\r\nContent-Length: 5\r\n\r\nhello")
+	if err != nil || body != "hello" {
+		t.Fatalf("small response: body %q, err %v", body, err)
+	}
+
+	// The body may be larger than the limit, only the head counts
+	big := strings.Repeat("b", 4*limit)
+	body, err = read("HTTP/1.1 200 OK\r\nContent-Length: 1024\r\n\r\n" + big)
+	if err != nil || body != big {
+		t.Fatalf("large body: got %d bytes, err %v", len(body), err)
+	}
+
+	_, err = read("HTTP/1.1 200 OK\r\nX-Big: " + strings.Repeat("h", limit) + "\r\nContent-Length: 0\r\n\r\n")
+	if !errors.Is(err, ErrHeaderTooLarge) {
+		t.Fatalf("large head: got %v, want ErrHeaderTooLarge", err)
+	}
+
+	// A truncated response is reported by the parser, not as too large
+	if _, err = read("HTTP/1.1 200 OK\r\nContent-"); err == nil || errors.Is(err, ErrHeaderTooLarge) {
+		t.Fatalf("truncated response: got %v", err)
+	}
+}
@@ -2,10 +2,14 @@ package tunnel
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"math"
 	"net"
 	"net/http"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -32,10 +36,30 @@ type HybridTunnelRouter struct {
 	websocketUpgrades int64
 	routingErrors     int64
 	timeoutErrors     int64
+	rateLimited       int64
+
+	// Responses abandoned because the visitor stopped reading (see ClientWriteTimeout)
+	clientWriteTimeouts int64
 
 	// Configuration
 	config *HybridRouterConfig
 
+	// ForceTCPPaths/ForceGRPCPaths, compiled once in NewHybridTunnelRouter
+	forceTCPPaths  []pathPattern
+	forceGRPCPaths []pathPattern
+
+	// Prometheus endpoint (nil unless MetricsListenAddr is set)
+	metricsServer *MetricsServer
+
+	// Custom page for domains whose tunnel is down (nil = built-in pages)
+	maintenance *maintenancePages
+
+	// Access log of proxied requests (nil = off)
+	accessLog *accessLog
+
+	// Per-domain cap on in-flight HTTP requests with a bounded wait queue
+	concurrency *concurrencyLimiter
+
 	// Usage aggregation
 	usage UsageRecorder
 	// Quotas
@@ -70,7 +94,8 @@ type HybridRouterConfig struct {
 	GRPCAddress string
 	TCPAddress  string
 
-	// Request classification
+	// Request classification: plain substrings of the request target, or anchored
+	// "glob:" / "regex:" patterns of the path (see pathPattern)
 	ForceGRPCPaths []string // Paths that must use gRPC
 	ForceTCPPaths  []string // Paths that must use TCP
 
@@ -79,13 +104,121 @@ type HybridRouterConfig struct {
 	MaxGRPCFileSize     int64    // Max file size for gRPC (bytes), larger files use TCP streaming
 	LargeFilePaths      []string // URL patterns that likely contain large files
 
+	// Skip the extension/path media and large-file heuristics, routing every request
+	// alike; responses are still chunked when their size calls for it
+	DisableMediaOptimization bool
+
 	// Performance settings
-	EnableMetrics   bool
-	MetricsInterval time.Duration
+	EnableMetrics     bool
+	MetricsInterval   time.Duration
+	MetricsListenAddr string // Address for the Prometheus /metrics endpoint (empty = disabled)
 
 	// Security settings
-	EnableRateLimit   bool
-	MaxRequestsPerMin int
+	EnableRateLimit     bool
+	MaxRequestsPerMin   int // Per-domain token refill rate
+	RateLimitBurst      int // Per-domain bucket size
+	MaxRequestsPerMinIP int // Per-client-IP refill rate within a domain (0 = no per-IP limit)
+	RateLimitBurstPerIP int
+
+	// Upload limits
+	MaxUploadBytes int64 // Max request body size for streamed uploads (0 = unlimited), larger uploads get 413
+
+	// Max request line plus headers (0 = unlimited), larger requests get 431. Also caps response
+	// heads read from TCP tunnel connections, which fail with 502.
+	MaxHeaderBytes int
+
+	// Per-domain concurrency: at most MaxConcurrentPerDomain HTTP requests in flight (0 = unlimited);
+	// up to QueueDepth more wait QueueTimeout for a slot, the rest get 503. WebSockets aren't counted.
+	MaxConcurrentPerDomain int
+	QueueDepth             int
+	QueueTimeout           time.Duration
+
+	// Tunnel connections one user may hold across all their tunnels: gRPC streams plus pooled
+	// TCP connections (0 = unlimited). Handshakes over the limit get TOO_MANY_CONNECTIONS.
+	MaxConnectionsPerUser int
+
+	// Response headers added to every gRPC-tunneled response (e.g. X-Served-By, Strict-Transport-Security)
+	ResponseHeaders         map[string]string
+	OverrideResponseHeaders bool // Replace headers already set by the origin instead of keeping them
+
+	// Add X-Forwarded-For, X-Real-IP and X-Forwarded-Proto to requests sent to the origin
+	ForwardedHeaders bool
+
+	// Request headers removed before forwarding to the origin, on top of the hop-by-hop
+	// headers that are always removed (see stripRequestHeaders)
+	StripRequestHeaders []string
+
+	// Point Location and Refresh headers that name a loopback address (e.g. a redirect to
+	// http://localhost:3000/login) at the public tunnel domain instead
+	RewriteRedirects bool
+
+	// WebSocket keepalive (see StreamingConfig.WebSocketIdleTimeout)
+	WebSocketIdleTimeout  time.Duration // Close proxied WebSockets idle for this long (0 = never)
+	WebSocketPingInterval time.Duration // Ping the client after this much silence (0 = no pings)
+
+	// Bridge WebSockets over the gRPC stream for clients that advertise support in the
+	// handshake, instead of waiting for an on-demand TCP tunnel
+	WebSocketOverGRPC bool
+
+	// Largest X-Tunnel-Timeout an origin may set to give one response more time (0 = ignore the header)
+	MaxOriginTimeout time.Duration
+
+	// Abandon a response when a write to the visitor makes no progress for this long (0 = never),
+	// freeing the tunnel connection or stream a stalled visitor would otherwise hold
+	ClientWriteTimeout time.Duration
+
+	// How long to wait for a client to dial in an on-demand TCP connection (0 = DefaultFreshConnectionTimeout)
+	FreshConnectionTimeout time.Duration
+
+	// Chunked responses: wait this long for the headers, and for the whole body (0 = defaults)
+	ChunkMetadataTimeout   time.Duration
+	ChunkCollectionTimeout time.Duration
+
+	// Kernel socket buffers of accepted TCP tunnel connections (bytes, 0 = OS default)
+	TCPReadBufferSize  int
+	TCPWriteBufferSize int
+
+	// Public UDP ports handed out to UDP tunnels (both 0 = UDP tunnels disabled)
+	UDPPortMin     int
+	UDPPortMax     int
+	UDPMaxSessions int // Open visitor sessions per UDP tunnel (0 = DefaultUDPMaxSessions)
+
+	// Expect a PROXY protocol header on TCP tunnel connections (see StreamingConfig.ProxyProtocol)
+	ProxyProtocol bool
+
+	// Keep a session on one hot-pool connection (see StreamingConfig.EnableStickySessions)
+	EnableStickySessions bool
+	StickySessionCookie  string // Cookie identifying the session (empty = visitor IP)
+
+	// Apache/nginx-style access log of proxied requests (empty = off), rotated like the server
+	// log; "{domain}" in the path writes one file per tunnel. Format: combined (default) or common.
+	AccessLogFile   string
+	AccessLogFormat string
+
+	// Cache GET responses the origin allows (Cache-Control/Expires, revalidated by ETag or
+	// Last-Modified) up to ResponseCacheSize bytes (0 = disabled), for at most ResponseCacheTTL
+	ResponseCacheSize int64
+	ResponseCacheTTL  time.Duration
+
+	// A second client connecting for a domain takes the gRPC tunnel over (replace, the default)
+	// or is refused while the first one is connected (reject)
+	DuplicateStreamPolicy DuplicateStreamPolicy
+
+	// Drop gRPC pending requests left behind for longer than this (0 = DefaultPendingRequestMaxAge)
+	PendingRequestMaxAge time.Duration
+
+	// Re-check connected gRPC clients with the SetCertRevocationCheck check this often
+	// (0 = DefaultCertRevocationCheckInterval)
+	CertRevocationCheckInterval time.Duration
+
+	// Maintenance page served with a 503 while a domain's tunnel is down
+	MaintenancePage       string        // HTML template file, or an inline template (empty = built-in page)
+	MaintenancePageDir    string        // Directory of <domain>.html templates overriding MaintenancePage
+	MaintenanceRetryAfter time.Duration // Retry-After sent with the page
+
+	// gRPC probes: grpc.health.v1 is always served on the tunnel port
+	GRPCReflection bool   // Also register server reflection
+	GRPCHealthAddr string // Serve health checks without TLS on this address too (empty = disabled)
 }
 
 // DefaultHybridRouterConfig returns production-ready configuration
@@ -103,10 +236,25 @@ func DefaultHybridRouterConfig() *HybridRouterConfig {
 		MaxGRPCFileSize: 50 * 1024 * 1024,                                                   // 50MB - files larger than this use TCP streaming
 		LargeFilePaths:  []string{"/video/", "/download/", "/file/", "/original/", "/raw/"}, // Paths likely to contain large files
 
+		ForwardedHeaders: true,
+
 		EnableMetrics:     true,
 		MetricsInterval:   1 * time.Minute,
 		EnableRateLimit:   true,
 		MaxRequestsPerMin: 10000,
+		RateLimitBurst:    1000,
+
+		WebSocketIdleTimeout: DefaultStreamingConfig().WebSocketIdleTimeout,
+		MaxOriginTimeout:     DefaultMaxOriginTimeout,
+		MaxHeaderBytes:       DefaultMaxHeaderBytes,
+		ClientWriteTimeout:   DefaultClientWriteTimeout,
+
+		FreshConnectionTimeout: DefaultFreshConnectionTimeout,
+
+		MaintenanceRetryAfter: DefaultMaintenanceRetryAfter,
+
+		QueueDepth:   DefaultQueueDepth,
+		QueueTimeout: DefaultQueueTimeout,
 	}
 }
 
@@ -129,12 +277,74 @@ func NewHybridTunnelRouter(
 		establishmentInProgress: make(map[string]bool),
 	}
 
+	maintenance, err := newMaintenancePages(config.MaintenancePage, config.MaintenancePageDir, config.MaintenanceRetryAfter)
+	if err != nil {
+		router.logger.Error("Maintenance page disabled, using built-in pages: %v", err)
+	}
+	router.maintenance = maintenance
+
+	if router.accessLog, err = newAccessLog(config.AccessLogFile, config.AccessLogFormat); err != nil {
+		router.logger.Error("Access log disabled: %v", err)
+	}
+
+	if router.forceTCPPaths, err = compilePathPatterns(config.ForceTCPPaths); err != nil {
+		router.logger.Error("Ignoring invalid ForceTCPPaths rules: %v", err)
+	}
+	if router.forceGRPCPaths, err = compilePathPatterns(config.ForceGRPCPaths); err != nil {
+		router.logger.Error("Ignoring invalid ForceGRPCPaths rules: %v", err)
+	}
+	router.concurrency = newConcurrencyLimiter(config.MaxConcurrentPerDomain, config.QueueDepth, config.QueueTimeout)
+
 	// Create gRPC tunnel server (for HTTP traffic)
 	grpcConfig := DefaultGRPCTunnelConfig()
+	// The router checks rate limits through the gRPC server's limiter, which is cleared when a domain disconnects
+	grpcConfig.RateLimitRPM = 0
+	if config.EnableRateLimit {
+		grpcConfig.RateLimitRPM = config.MaxRequestsPerMin
+		grpcConfig.RateLimitBurst = config.RateLimitBurst
+		grpcConfig.ClientRateLimitRPM = config.MaxRequestsPerMinIP
+		grpcConfig.ClientRateLimitBurst = config.RateLimitBurstPerIP
+	}
+	grpcConfig.EnableReflection = config.GRPCReflection
+	grpcConfig.HealthListenAddr = config.GRPCHealthAddr
+	if config.ChunkMetadataTimeout > 0 {
+		grpcConfig.ChunkMetadataTimeout = config.ChunkMetadataTimeout
+	}
+	if config.ChunkCollectionTimeout > 0 {
+		grpcConfig.ChunkCollectionTimeout = config.ChunkCollectionTimeout
+	}
+	grpcConfig.ResponseCacheSize = config.ResponseCacheSize
+	grpcConfig.ResponseCacheTTL = config.ResponseCacheTTL
+	grpcConfig.DuplicateStreamPolicy = config.DuplicateStreamPolicy
+	grpcConfig.PendingRequestMaxAge = config.PendingRequestMaxAge
+	grpcConfig.CertRevocationCheckInterval = config.CertRevocationCheckInterval
 	router.grpcTunnel = NewGRPCTunnelServer(tokenRepo, tunnelRepo, tunnelService, grpcConfig)
+	router.grpcTunnel.SetMaxUploadBytes(config.MaxUploadBytes)
+	router.grpcTunnel.SetMaxOriginTimeout(config.MaxOriginTimeout)
+	router.grpcTunnel.SetDisableMediaOptimization(config.DisableMediaOptimization)
+	router.grpcTunnel.SetWebSocketKeepalive(config.WebSocketIdleTimeout, config.WebSocketPingInterval)
 
 	// Create TCP tunnel server (for WebSocket traffic)
 	router.tcpTunnel = NewServer(tokenRepo, tunnelRepo, tunnelService)
+	router.tcpTunnel.streamConfig.WebSocketIdleTimeout = config.WebSocketIdleTimeout
+	router.tcpTunnel.streamConfig.WebSocketPingInterval = config.WebSocketPingInterval
+	router.tcpTunnel.streamConfig.MaxOriginTimeout = config.MaxOriginTimeout
+	if config.MaxConnectionsPerUser > 0 {
+		router.grpcTunnel.SetUserConnectionCheck(router.checkUserConnections)
+		router.tcpTunnel.SetUserConnectionCheck(router.checkUserConnections)
+	}
+	router.tcpTunnel.streamConfig.MaxHeaderBytes = config.MaxHeaderBytes
+	router.tcpTunnel.streamConfig.ClientWriteTimeout = config.ClientWriteTimeout
+	router.tcpTunnel.streamConfig.FreshConnectionTimeout = config.FreshConnectionTimeout
+	router.tcpTunnel.streamConfig.TCPReadBufferSize = config.TCPReadBufferSize
+	router.tcpTunnel.streamConfig.TCPWriteBufferSize = config.TCPWriteBufferSize
+	router.tcpTunnel.streamConfig.UDPPortMin = config.UDPPortMin
+	router.tcpTunnel.streamConfig.UDPPortMax = config.UDPPortMax
+	router.tcpTunnel.streamConfig.UDPMaxSessions = config.UDPMaxSessions
+	router.tcpTunnel.streamConfig.ProxyProtocol = config.ProxyProtocol
+	router.tcpTunnel.streamConfig.EnableStickySessions = config.EnableStickySessions
+	router.tcpTunnel.streamConfig.StickySessionCookie = config.StickySessionCookie
+	router.tcpTunnel.disableMediaOptimization = config.DisableMediaOptimization
 
 	// Set up TCP tunnel establishment callback
 	router.tcpTunnel.SetTCPTunnelEstablishedCallback(router.OnTCPTunnelEstablished)
@@ -161,6 +371,20 @@ func NewHybridTunnelRouter(
 		return fmt.Errorf("no active gRPC tunnel for domain: %s", domain)
 	})
 
+	// Set up on-demand connection callback: the client dials in a dedicated connection tagged with requestID
+	router.tcpTunnel.SetRequestFreshTunnelCallback(func(domain, requestID string) error {
+		if !router.grpcTunnel.IsTunnelActive(domain) {
+			return fmt.Errorf("no active gRPC tunnel for domain: %s", domain)
+		}
+		return router.grpcTunnel.SendTunnelEstablishRequest(domain, &proto.TunnelEstablishRequest{
+			RequestId:      requestID,
+			Domain:         domain,
+			TunnelType:     proto.TunnelType_TUNNEL_TYPE_TCP,
+			Reason:         "On-demand HTTP connection request",
+			ConnectionType: string(ConnectionTypeHTTPOnDemand), // One request/response, not a WebSocket tunnel
+		})
+	})
+
 	// Set up gRPC tunnel establishment response callback (for logging and failure handling only)
 	// CRITICAL: Do NOT wake connections here - wait for actual TCP server callback
 	router.grpcTunnel.SetTCPEstablishmentResponseCallback(func(domain string, requestId string, success bool) {
@@ -231,10 +455,31 @@ func (r *HybridTunnelRouter) Start() error {
 		go r.reportMetrics()
 	}
 
+	// Expose metrics for Prometheus scraping
+	if r.config.MetricsListenAddr != "" {
+		registry := NewMetricsRegistry()
+		registry.Register(r.collectMetrics)
+		metricsServer, err := StartMetricsServer(r.config.MetricsListenAddr, registry)
+		if err != nil {
+			return err
+		}
+		r.metricsServer = metricsServer
+		r.logger.Info("✓ Prometheus metrics available on %s/metrics", r.config.MetricsListenAddr)
+	}
+
 	r.logger.Info("🚀 Hybrid Tunnel Router started successfully - Ready to compete with Cloudflare!")
 	return nil
 }
 
+// Drain lets in-flight TCP tunnel requests finish (up to ctx's deadline) before Stop.
+// gRPC health checks report NOT_SERVING from here on; the gRPC server drains its own
+// streams in Stop via GracefulStop.
+func (r *HybridTunnelRouter) Drain(ctx context.Context) error {
+	r.logger.Info("Draining Hybrid Tunnel Router...")
+	r.grpcTunnel.MarkNotServing()
+	return r.tcpTunnel.Drain(ctx)
+}
+
 // Stop gracefully stops both tunnel servers
 func (r *HybridTunnelRouter) Stop() error {
 	r.logger.Info("Stopping Hybrid Tunnel Router...")
@@ -249,6 +494,19 @@ func (r *HybridTunnelRouter) Stop() error {
 		r.logger.Error("Error stopping TCP tunnel server: %v", err)
 	}
 
+	// Stop metrics endpoint
+	if r.metricsServer != nil {
+		if err := r.metricsServer.Stop(); err != nil {
+			r.logger.Error("Error stopping metrics server: %v", err)
+		}
+	}
+
+	if r.accessLog != nil {
+		if err := r.accessLog.Close(); err != nil {
+			r.logger.Error("Error closing access log: %v", err)
+		}
+	}
+
 	r.logger.Info("Hybrid Tunnel Router stopped")
 	return nil
 }
@@ -262,6 +520,35 @@ func (r *HybridTunnelRouter) ProxyConnection(domain string, conn net.Conn, reque
 	// Extract client IP for logging and security
 	clientIP := r.extractClientIP(conn)
 
+	if r.accessLog != nil {
+		logged := newAccessLogConn(conn)
+		defer r.accessLog.record(domain, clientIP, r.loggedRequest(domain, requestData), logged, time.Now())
+		conn = logged
+	}
+
+	if allowed, retryAfter := r.grpcTunnel.CheckRateLimit(domain, clientIP); !allowed {
+		atomic.AddInt64(&r.rateLimited, 1)
+		r.logger.Debug("[HYBRID] Rate limit exceeded for %s from %s, retry after %v", domain, clientIP, retryAfter)
+		r.writeRateLimited(conn, retryAfter)
+		return
+	}
+
+	// Tunnels that only serve signed links reject everything else before it is forwarded
+	requestData, grant, err := r.checkSignedURL(domain, requestData)
+	if err != nil {
+		r.logger.Debug("[HYBRID] Rejecting unsigned request for %s from %s: %v", domain, clientIP, err)
+		message := "Forbidden - This link is not valid"
+		if errors.Is(err, errSignatureExpired) {
+			message = "Forbidden - This link has expired"
+		}
+		r.writeHTTPError(conn, domain, http.StatusForbidden, message)
+		return
+	}
+	if grant != nil {
+		// Lets the page's assets and follow-up requests through without a signature
+		conn = newSetCookieConn(conn, grant)
+	}
+
 	// Parse the request to determine routing
 	shouldUseTCP, httpMethod, requestPath := r.analyzeRequest(requestData)
 
@@ -272,6 +559,23 @@ func (r *HybridTunnelRouter) ProxyConnection(domain string, conn net.Conn, reque
 	r.logger.Debug("[HYBRID] Request from %s: %s %s (TCP: %t, WebSocket: %t, LargeFile: %t)",
 		clientIP, httpMethod, requestPath, shouldUseTCP, isActualWebSocket, isLargeFile)
 
+	// Long-lived WebSockets would pin concurrency slots, so only HTTP requests are capped
+	if !isActualWebSocket {
+		release, err := r.concurrency.Acquire(domain)
+		if err != nil {
+			r.logger.Debug("[HYBRID] Rejecting %s %s for %s: %v", httpMethod, requestPath, domain, err)
+			r.writeRetryLater(conn, http.StatusServiceUnavailable, "Service Unavailable - Too many concurrent requests", r.config.QueueTimeout)
+			return
+		}
+		defer release()
+	}
+
+	// WebSockets skip the TCP detour when the client can bridge them over its gRPC stream
+	if isActualWebSocket && r.config.WebSocketOverGRPC && r.grpcTunnel.SupportsWebSocket(domain) {
+		r.routeWebSocketToGRPC(domain, conn, requestData, requestBody, clientIP)
+		return
+	}
+
 	// Route based on request type
 	if shouldUseTCP {
 		if isActualWebSocket {
@@ -296,7 +600,7 @@ func (r *HybridTunnelRouter) routeToGRPCTunnel(domain string, conn net.Conn, req
 		// Serve user-friendly "Not Connected" page instead of generic 502
 		// This happens when the tunnel exists in DB (checked by Caddy) but client is offline
 		r.logger.Debug("[HYBRID→gRPC] Tunnel not active for domain: %s, serving Not Connected page", domain)
-		WriteNotConnectedPage(conn, domain)
+		r.writeTunnelOffline(conn, domain)
 		return
 	}
 
@@ -305,9 +609,16 @@ func (r *HybridTunnelRouter) routeToGRPCTunnel(domain string, conn net.Conn, req
 	if err != nil {
 		r.logger.Error("[HYBRID→gRPC] Failed to parse HTTP request: %v", err)
 		atomic.AddInt64(&r.routingErrors, 1)
-		r.writeHTTPError(conn, 400, "Bad Request - Invalid HTTP request")
+		r.writeRequestError(conn, domain, err, "Bad Request - Invalid HTTP request")
 		return
 	}
+	r.stripRequestHeaders(httpReq, false)
+	r.applyForwardedHeaders(httpReq, clientIP)
+	stopContinue := r.expectContinue(conn, httpReq)
+	ctx, cancel := context.WithCancel(httpReq.Context())
+	defer cancel()
+	httpReq = httpReq.WithContext(ctx)
+	stopWatching := r.watchClientDisconnect(conn, httpReq, cancel)
 
 	// Proxy through gRPC tunnel
 	var response *http.Response
@@ -316,37 +627,51 @@ func (r *HybridTunnelRouter) routeToGRPCTunnel(domain string, conn net.Conn, req
 		// Stream uploads to avoid 16MB gRPC limits
 		response, err = r.grpcTunnel.ProxyHTTPRequestWithChunking(domain, httpReq, clientIP)
 	default:
+		if isRangeRequest(httpReq) {
+			// Ranges of large media go through the chunked download path
+			response, err = r.grpcTunnel.ProxyHTTPRequestWithChunking(domain, httpReq, clientIP)
+			break
+		}
 		// Fast path for GET/HEAD and small requests
 		response, err = r.grpcTunnel.ProxyHTTPRequest(domain, httpReq, clientIP)
 	}
+	stopContinue()
+	stopWatching()
 	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			r.logger.Debug("[HYBRID→gRPC] Request cancelled, client disconnected: %s %s", method, path)
+			return
+		}
+		if errors.Is(err, ErrPayloadTooLarge) {
+			r.logger.Warn("[HYBRID→gRPC] Upload rejected for %s: %v", domain, err)
+			r.writeHTTPError(conn, domain, http.StatusRequestEntityTooLarge, "Payload Too Large")
+			return
+		}
 		r.logger.Error("[HYBRID→gRPC] gRPC proxy error: %v", err)
 		atomic.AddInt64(&r.routingErrors, 1)
 		if isTimeoutError(err) {
 			atomic.AddInt64(&r.timeoutErrors, 1)
 		}
-		r.writeHTTPError(conn, 502, fmt.Sprintf("Bad Gateway - %v", err))
+		r.writeHTTPError(conn, domain, 502, fmt.Sprintf("Bad Gateway - %v", err))
 		return
 	}
 
 	// Write response back to client
-	writer := bufio.NewWriter(conn)
-	if err := response.Write(writer); err != nil {
-		// Client disconnection is normal (user navigated away, etc.)
-		if r.isClientDisconnectionError(err) {
-			r.logger.Debug("[HYBRID→gRPC] Client disconnected during response write: %v", err)
-		} else {
-			r.logger.Error("[HYBRID→gRPC] Error writing response: %v", err)
-		}
+	response.Header.Set(TransportHeader, TransportGRPC)
+	r.applyResponseHeaders(response)
+	r.rewriteRedirects(response, domain, httpReq)
+	writer := bufio.NewWriter(deadlineWriter{conn, r.config.ClientWriteTimeout})
+	var out io.Writer = writer
+	if isEventStream(response.Header) {
+		out = flushWriter{writer} // Deliver each event as soon as its chunk arrives
+	}
+	if err := response.Write(out); err != nil {
+		r.clientWriteFailed("[HYBRID→gRPC]", response, err)
 		return
 	}
 
 	if err := writer.Flush(); err != nil {
-		if r.isClientDisconnectionError(err) {
-			r.logger.Debug("[HYBRID→gRPC] Client disconnected during flush: %v", err)
-		} else {
-			r.logger.Error("[HYBRID→gRPC] Error flushing response: %v", err)
-		}
+		r.clientWriteFailed("[HYBRID→gRPC]", response, err)
 		return
 	}
 
@@ -365,9 +690,10 @@ func (r *HybridTunnelRouter) routeToTCPTunnel(domain string, conn net.Conn, requ
 	if err != nil {
 		r.logger.Error("[HYBRID→TCP] Failed to parse WebSocket request: %v", err)
 		atomic.AddInt64(&r.routingErrors, 1)
-		r.writeHTTPError(conn, 400, "Bad Request - Invalid WebSocket request")
+		r.writeRequestError(conn, domain, err, "Bad Request - Invalid WebSocket request")
 		return
 	}
+	r.stripRequestHeaders(httpReq, true)
 
 	// CRITICAL: Check specifically for WebSocket connection, not just any tunnel
 	// IsTunnelDomain() can return false if HTTP pool is empty, even if WS tunnel exists
@@ -391,7 +717,7 @@ func (r *HybridTunnelRouter) routeToTCPTunnel(domain string, conn net.Conn, requ
 			// Timeout or error establishing tunnel
 			r.logger.Error("[HYBRID→TCP] Failed to establish TCP tunnel for domain: %s", domain)
 			atomic.AddInt64(&r.routingErrors, 1)
-			r.writeHTTPError(conn, 502, "Bad Gateway - TCP tunnel establishment timeout")
+			r.writeHTTPError(conn, domain, 502, "Bad Gateway - TCP tunnel establishment timeout")
 			return
 		}
 	}
@@ -409,7 +735,7 @@ func (r *HybridTunnelRouter) routeToTCPTunnel(domain string, conn net.Conn, requ
 			if !r.grpcTunnel.IsTunnelActive(domain) {
 				r.logger.Info("[HYBRID→TCP] ⚠️  Main gRPC tunnel is offline, cannot re-establish TCP tunnel")
 				atomic.AddInt64(&r.routingErrors, 1)
-				r.writeHTTPError(conn, 503, "Service Unavailable - Tunnel offline")
+				r.writeTunnelOffline(conn, domain)
 				return
 			}
 
@@ -420,18 +746,48 @@ func (r *HybridTunnelRouter) routeToTCPTunnel(domain string, conn net.Conn, requ
 			} else {
 				r.logger.Error("[HYBRID→TCP] Failed to re-establish TCP tunnel for domain: %s", domain)
 				atomic.AddInt64(&r.routingErrors, 1)
-				r.writeHTTPError(conn, 502, "Bad Gateway - TCP tunnel re-establishment failed")
+				r.writeHTTPError(conn, domain, 502, "Bad Gateway - TCP tunnel re-establishment failed")
 			}
 		} else {
 			r.logger.Error("[HYBRID→TCP] WebSocket proxy error: %v", err)
 			atomic.AddInt64(&r.routingErrors, 1)
-			r.writeHTTPError(conn, 500, "Internal Server Error - WebSocket proxy failed")
+			r.writeHTTPError(conn, domain, 500, "Internal Server Error - WebSocket proxy failed")
 		}
 	}
 
 	r.logger.Debug("[HYBRID→TCP] WebSocket proxy completed")
 }
 
+// routeWebSocketToGRPC bridges a WebSocket upgrade over the domain's gRPC stream
+func (r *HybridTunnelRouter) routeWebSocketToGRPC(domain string, conn net.Conn, requestData []byte, requestBody io.Reader, clientIP string) {
+	atomic.AddInt64(&r.grpcRequests, 1)
+	atomic.AddInt64(&r.websocketUpgrades, 1)
+
+	r.logger.Debug("[HYBRID→gRPC] Bridging WebSocket upgrade")
+
+	httpReq, err := r.parseHTTPRequest(requestData, requestBody)
+	if err != nil {
+		r.logger.Error("[HYBRID→gRPC] Failed to parse WebSocket request: %v", err)
+		atomic.AddInt64(&r.routingErrors, 1)
+		r.writeRequestError(conn, domain, err, "Bad Request - Invalid WebSocket request")
+		return
+	}
+	r.stripRequestHeaders(httpReq, true)
+
+	if err := r.grpcTunnel.ProxyWebSocket(domain, conn, httpReq, clientIP); err != nil {
+		atomic.AddInt64(&r.routingErrors, 1)
+		if errors.Is(err, errQuotaExceeded) {
+			r.writeHTTPError(conn, domain, http.StatusPaymentRequired, "Quota exceeded")
+			return
+		}
+		r.logger.Error("[HYBRID→gRPC] WebSocket bridge error: %v", err)
+		r.writeHTTPError(conn, domain, 502, "Bad Gateway - WebSocket bridge failed")
+		return
+	}
+
+	r.logger.Debug("[HYBRID→gRPC] WebSocket bridge completed")
+}
+
 // analyzeRequest analyzes the request to determine routing strategy
 func (r *HybridTunnelRouter) analyzeRequest(requestData []byte) (isWebSocket bool, method string, path string) {
 	requestStr := string(requestData)
@@ -456,12 +812,10 @@ func (r *HybridTunnelRouter) analyzeRequest(requestData []byte) (isWebSocket boo
 
 	// Force routing based on configuration - TCP paths take priority over gRPC paths
 	// Check TCP paths first (more specific WebSocket patterns)
-	for _, forcePath := range r.config.ForceTCPPaths {
-		if strings.Contains(path, forcePath) {
-			isWebSocket = true
-			r.logger.Debug("[HYBRID] Path %s matched ForceTCPPaths pattern: %s", path, forcePath)
-			return isWebSocket, method, path
-		}
+	if rule, ok := matchPathPatterns(r.forceTCPPaths, path); ok {
+		isWebSocket = true
+		r.logger.Debug("[HYBRID] Path %s matched ForceTCPPaths pattern: %s", path, rule)
+		return isWebSocket, method, path
 	}
 
 	// Check for large files that should use gRPC chunked streaming (downloads)
@@ -472,12 +826,9 @@ func (r *HybridTunnelRouter) analyzeRequest(requestData []byte) (isWebSocket boo
 	}
 
 	// Then check gRPC paths (only if not already matched by TCP or large file)
-	for _, forcePath := range r.config.ForceGRPCPaths {
-		if strings.Contains(path, forcePath) {
-			isWebSocket = false
-			r.logger.Debug("[HYBRID] Path %s matched ForceGRPCPaths pattern: %s", path, forcePath)
-			break
-		}
+	if rule, ok := matchPathPatterns(r.forceGRPCPaths, path); ok {
+		isWebSocket = false
+		r.logger.Debug("[HYBRID] Path %s matched ForceGRPCPaths pattern: %s", path, rule)
 	}
 
 	return isWebSocket, method, path
@@ -485,6 +836,10 @@ func (r *HybridTunnelRouter) analyzeRequest(requestData []byte) (isWebSocket boo
 
 // isLargeFile determines if a file path is likely to be a large file that should use TCP streaming
 func (r *HybridTunnelRouter) isLargeFile(path string) bool {
+	if r.config.DisableMediaOptimization {
+		return false
+	}
+
 	pathLower := strings.ToLower(path)
 
 	// Check for large file extensions
@@ -520,7 +875,7 @@ func (r *HybridTunnelRouter) routeToGRPCChunkedStreaming(domain string, conn net
 	// Check if gRPC tunnel is available
 	if !r.grpcTunnel.IsTunnelActive(domain) {
 		r.logger.Debug("[HYBRID→gRPC-CHUNKED] Tunnel not active for domain: %s, serving Not Connected page", domain)
-		WriteNotConnectedPage(conn, domain)
+		r.writeTunnelOffline(conn, domain)
 		return
 	}
 
@@ -529,48 +884,66 @@ func (r *HybridTunnelRouter) routeToGRPCChunkedStreaming(domain string, conn net
 	if err != nil {
 		r.logger.Error("[HYBRID→gRPC-CHUNKED] Failed to parse large file request: %v", err)
 		atomic.AddInt64(&r.routingErrors, 1)
-		r.writeHTTPError(conn, 400, "Bad Request - Invalid HTTP request")
+		r.writeRequestError(conn, domain, err, "Bad Request - Invalid HTTP request")
 		return
 	}
+	r.stripRequestHeaders(httpReq, false)
+	r.applyForwardedHeaders(httpReq, clientIP)
+	stopContinue := r.expectContinue(conn, httpReq)
+	ctx, cancel := context.WithCancel(httpReq.Context())
+	defer cancel()
+	httpReq = httpReq.WithContext(ctx)
+	stopWatching := r.watchClientDisconnect(conn, httpReq, cancel)
 
 	// Use the enhanced gRPC proxy with chunking support
 	response, err := r.grpcTunnel.ProxyHTTPRequestWithChunking(domain, httpReq, clientIP)
+	stopContinue()
+	stopWatching()
 	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			r.logger.Debug("[HYBRID→gRPC-CHUNKED] Request cancelled, client disconnected: %s %s", method, path)
+			return
+		}
+		if errors.Is(err, ErrPayloadTooLarge) {
+			r.logger.Warn("[HYBRID→gRPC-CHUNKED] Upload rejected for %s: %v", domain, err)
+			r.writeHTTPError(conn, domain, http.StatusRequestEntityTooLarge, "Payload Too Large")
+			return
+		}
 		r.logger.Error("[HYBRID→gRPC-CHUNKED] gRPC chunked proxy error: %v", err)
 		atomic.AddInt64(&r.routingErrors, 1)
 		if isTimeoutError(err) {
 			atomic.AddInt64(&r.timeoutErrors, 1)
 		}
-		r.writeHTTPError(conn, 502, fmt.Sprintf("Bad Gateway - %v", err))
+		r.writeHTTPError(conn, domain, 502, fmt.Sprintf("Bad Gateway - %v", err))
 		return
 	}
 
 	// Write response back to client
-	writer := bufio.NewWriter(conn)
+	response.Header.Set(TransportHeader, TransportGRPC)
+	r.applyResponseHeaders(response)
+	r.rewriteRedirects(response, domain, httpReq)
+	writer := bufio.NewWriter(deadlineWriter{conn, r.config.ClientWriteTimeout})
 	if err := response.Write(writer); err != nil {
 		// Broken pipe is NORMAL - client stopped downloading (seek, cancel, etc.)
-		if r.isClientDisconnectionError(err) {
-			r.logger.Info("[HYBRID→gRPC-CHUNKED] Client disconnected during streaming (normal for video seek/cancel): %v", err)
-		} else {
-			r.logger.Error("[HYBRID→gRPC-CHUNKED] Error writing response: %v", err)
-		}
+		r.clientWriteFailed("[HYBRID→gRPC-CHUNKED]", response, err)
 		return
 	}
 
 	if err := writer.Flush(); err != nil {
-		if r.isClientDisconnectionError(err) {
-			r.logger.Debug("[HYBRID→gRPC-CHUNKED] Client disconnected during flush: %v", err)
-		} else {
-			r.logger.Error("[HYBRID→gRPC-CHUNKED] Error flushing response: %v", err)
-		}
+		r.clientWriteFailed("[HYBRID→gRPC-CHUNKED]", response, err)
 		return
 	}
 
 	r.logger.Debug("[HYBRID→gRPC-CHUNKED] ✅ Large file streaming completed via gRPC")
 }
 
-// parseHTTPRequest parses raw HTTP request data into http.Request
+// parseHTTPRequest parses raw HTTP request data into http.Request. Heads over
+// MaxHeaderBytes fail with ErrHeaderTooLarge.
 func (r *HybridTunnelRouter) parseHTTPRequest(requestData []byte, requestBody io.Reader) (*http.Request, error) {
+	if err := checkRequestHeaderSize(requestData, r.config.MaxHeaderBytes); err != nil {
+		return nil, err
+	}
+
 	// Create a reader for the request
 	requestReader := bufio.NewReader(strings.NewReader(string(requestData)))
 
@@ -588,16 +961,36 @@ func (r *HybridTunnelRouter) parseHTTPRequest(requestData []byte, requestBody io
 	return httpReq, nil
 }
 
-// extractClientIP extracts client IP from connection
+// extractClientIP extracts client IP from connection (the address from the PROXY header
+// when the listener expects one)
 func (r *HybridTunnelRouter) extractClientIP(conn net.Conn) string {
-	if tcpConn, ok := conn.(*net.TCPConn); ok {
-		return tcpConn.RemoteAddr().(*net.TCPAddr).IP.String()
+	addr := conn.RemoteAddr()
+	if tcpAddr, ok := addr.(*net.TCPAddr); ok {
+		return tcpAddr.IP.String()
+	}
+	if host, _, err := net.SplitHostPort(addr.String()); err == nil {
+		return host
 	}
-	return conn.RemoteAddr().String()
+	return addr.String()
 }
 
-// writeHTTPError writes an HTTP error response
-func (r *HybridTunnelRouter) writeHTTPError(conn net.Conn, statusCode int, message string) {
+// writeRequestError answers a request parseHTTPRequest rejected: 431 when its head is
+// too large, otherwise 400 with message
+func (r *HybridTunnelRouter) writeRequestError(conn net.Conn, domain string, err error, message string) {
+	if errors.Is(err, ErrHeaderTooLarge) {
+		r.writeHTTPError(conn, domain, http.StatusRequestHeaderFieldsTooLarge, "Request Header Fields Too Large")
+		return
+	}
+	r.writeHTTPError(conn, domain, http.StatusBadRequest, message)
+}
+
+// writeHTTPError writes an HTTP error response. A 503 is served as the domain's
+// maintenance page when one is configured, otherwise errors are plain text.
+func (r *HybridTunnelRouter) writeHTTPError(conn net.Conn, domain string, statusCode int, message string) {
+	if statusCode == http.StatusServiceUnavailable && r.writeMaintenancePage(conn, domain) {
+		return
+	}
+
 	statusText := http.StatusText(statusCode)
 	if statusText == "" {
 		statusText = "Unknown Error"
@@ -615,6 +1008,74 @@ func (r *HybridTunnelRouter) writeHTTPError(conn net.Conn, statusCode int, messa
 	conn.Write([]byte(response))
 }
 
+// writeTunnelOffline tells the client the domain's tunnel is down, using the configured
+// maintenance page or the built-in "Not Connected" page
+func (r *HybridTunnelRouter) writeTunnelOffline(conn net.Conn, domain string) {
+	if !r.writeMaintenancePage(conn, domain) {
+		WriteNotConnectedPage(conn, domain)
+	}
+}
+
+// writeMaintenancePage writes the domain's maintenance page with a 503 and Retry-After.
+// Returns false without writing anything if no page is configured.
+func (r *HybridTunnelRouter) writeMaintenancePage(conn net.Conn, domain string) bool {
+	page, ok := r.maintenance.render(domain)
+	if !ok {
+		return false
+	}
+
+	response := fmt.Sprintf("HTTP/1.1 503 Service Unavailable\r\n"+
+		"Content-Type: text/html; charset=UTF-8\r\n"+
+		"Content-Length: %d\r\n"+
+		"Connection: close\r\n"+
+		"Retry-After: %d\r\n"+
+		"X-Tunnel-Router: hybrid\r\n"+
+		"\r\n", len(page), r.maintenance.retryAfterSeconds())
+	conn.Write(append([]byte(response), page...))
+	return true
+}
+
+// ServeMaintenancePage writes the domain's maintenance page to w with a 503 and
+// Retry-After, for requests that never reach the router because the domain has no
+// active tunnel. Returns false without writing anything if no page is configured.
+func (r *HybridTunnelRouter) ServeMaintenancePage(w http.ResponseWriter, domain string) bool {
+	page, ok := r.maintenance.render(domain)
+	if !ok {
+		return false
+	}
+	w.Header().Set("Content-Type", "text/html; charset=UTF-8")
+	w.Header().Set("Retry-After", strconv.Itoa(r.maintenance.retryAfterSeconds()))
+	w.WriteHeader(http.StatusServiceUnavailable)
+	w.Write(page)
+	return true
+}
+
+// writeRateLimited writes a 429 response telling the client when to retry
+func (r *HybridTunnelRouter) writeRateLimited(conn net.Conn, retryAfter time.Duration) {
+	r.writeRetryLater(conn, http.StatusTooManyRequests, "Rate limit exceeded", retryAfter)
+}
+
+// writeRetryLater writes a plain-text error response with a Retry-After header
+func (r *HybridTunnelRouter) writeRetryLater(conn net.Conn, statusCode int, message string, retryAfter time.Duration) {
+	// Retry-After is in whole seconds; round up so clients don't retry too early
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+
+	response := fmt.Sprintf("HTTP/1.1 %d %s\r\n"+
+		"Content-Type: text/plain\r\n"+
+		"Content-Length: %d\r\n"+
+		"Retry-After: %d\r\n"+
+		"Connection: close\r\n"+
+		"X-Tunnel-Router: hybrid\r\n"+
+		"\r\n"+
+		"%s",
+		statusCode, http.StatusText(statusCode), len(message), seconds, message)
+
+	conn.Write([]byte(response))
+}
+
 // isClientDisconnectionError checks if an error indicates normal client disconnection
 func (r *HybridTunnelRouter) isClientDisconnectionError(err error) bool {
 	if err == nil {
@@ -671,6 +1132,15 @@ func (r *HybridTunnelRouter) reportMetrics() {
 
 		r.logger.Info("[HYBRID METRICS] Total: %d, gRPC: %d (%.1f%%), TCP: %d (%.1f%%), WebSocket Requests: %d, Errors: %d (Timeout: %d)",
 			total, grpc, grpcPercent, tcp, tcpPercent, ws, errors, timeoutErrors)
+		r.logger.Info("[PERF] gRPC latency: %s", r.grpcTunnel.latency.Percentiles(""))
+		if r.config.MaxConcurrentPerDomain > 0 {
+			r.logger.Info("[PERF] Queued requests: %d (rejected: %d), in flight: gRPC %d, TCP %d",
+				r.concurrency.QueuedTotal(), r.concurrency.Rejected(),
+				r.grpcTunnel.GetMetrics()["concurrent_requests"], r.tcpTunnel.GetMetrics()["concurrent_requests"])
+		}
+		for domain, latency := range r.grpcTunnel.GetLatency() {
+			r.logger.Debug("[PERF] gRPC latency %s: %s", domain, latency)
+		}
 		r.logger.Info("[WEBSOCKET POOL] Active Tunnels: %d, Domains: %d, Max Per Domain: %d/25",
 			wsPoolStats["total_tunnels"], wsPoolStats["total_domains"], wsPoolStats["max_per_domain"])
 	}
@@ -678,14 +1148,200 @@ func (r *HybridTunnelRouter) reportMetrics() {
 
 // GetMetrics returns current routing metrics
 func (r *HybridTunnelRouter) GetMetrics() map[string]interface{} {
-	return map[string]interface{}{
+	metrics := map[string]interface{}{
 		"total_requests":     atomic.LoadInt64(&r.totalRequests),
 		"grpc_requests":      atomic.LoadInt64(&r.grpcRequests),
 		"tcp_requests":       atomic.LoadInt64(&r.tcpRequests),
 		"websocket_upgrades": atomic.LoadInt64(&r.websocketUpgrades),
 		"routing_errors":     atomic.LoadInt64(&r.routingErrors),
 		"timeout_errors":     atomic.LoadInt64(&r.timeoutErrors),
+		"rate_limited":       atomic.LoadInt64(&r.rateLimited),
+		"queued_requests":    r.concurrency.QueuedTotal(),
+		"queue_rejected":     r.concurrency.Rejected(),
+		"domain_concurrency": r.concurrency.Stats(),
+		"latency":            r.grpcTunnel.latency.Percentiles(""),
+		"domain_latency":     r.grpcTunnel.GetLatency(),
+		"tcp_domain_latency": r.tcpTunnel.GetLatency(),
+		"user_connections":   r.UserConnectionCounts(),
+	}
+
+	// Responses abandoned on stalled visitors, over either transport
+	metrics["client_write_timeouts"] = atomic.LoadInt64(&r.clientWriteTimeouts) + r.tcpTunnel.GetMetrics()["client_write_timeouts"]
+	return metrics
+}
+
+// collectMetrics writes router, gRPC and TCP tunnel metrics for Prometheus
+func (r *HybridTunnelRouter) collectMetrics(w *MetricsWriter) {
+	w.Counter("giraffecloud_router_requests_total", "Total requests handled by the hybrid router", float64(atomic.LoadInt64(&r.totalRequests)), nil)
+	w.Counter("giraffecloud_router_grpc_requests_total", "Requests routed through the gRPC tunnel", float64(atomic.LoadInt64(&r.grpcRequests)), nil)
+	w.Counter("giraffecloud_router_tcp_requests_total", "Requests routed through the TCP tunnel", float64(atomic.LoadInt64(&r.tcpRequests)), nil)
+	w.Counter("giraffecloud_router_websocket_upgrades_total", "WebSocket upgrade requests", float64(atomic.LoadInt64(&r.websocketUpgrades)), nil)
+	w.Counter("giraffecloud_router_errors_total", "Routing errors", float64(atomic.LoadInt64(&r.routingErrors)), nil)
+	w.Counter("giraffecloud_router_timeout_errors_total", "Routing errors caused by timeouts", float64(atomic.LoadInt64(&r.timeoutErrors)), nil)
+	w.Counter("giraffecloud_router_rate_limited_total", "Requests rejected with 429 by the rate limiter", float64(atomic.LoadInt64(&r.rateLimited)), nil)
+	tcpMetrics := r.tcpTunnel.GetMetrics()
+	w.Counter("giraffecloud_router_client_write_timeouts_total", "Responses abandoned because the visitor stopped reading", float64(atomic.LoadInt64(&r.clientWriteTimeouts)), map[string]string{"transport": TransportGRPC})
+	w.Counter("giraffecloud_router_client_write_timeouts_total", "Responses abandoned because the visitor stopped reading", float64(tcpMetrics["client_write_timeouts"]), map[string]string{"transport": TransportTCP})
+
+	grpcMetrics := r.grpcTunnel.GetMetrics()
+	w.Counter("giraffecloud_grpc_requests_total", "Requests proxied over gRPC tunnel streams", float64(grpcMetrics["requests"]), nil)
+	w.Counter("giraffecloud_grpc_errors_total", "gRPC tunnel proxy errors", float64(grpcMetrics["errors"]), nil)
+	w.Counter("giraffecloud_grpc_timeout_errors_total", "gRPC tunnel proxy timeouts", float64(grpcMetrics["timeout_errors"]), nil)
+	w.Gauge("giraffecloud_grpc_concurrent_requests", "In-flight gRPC tunnel requests", float64(grpcMetrics["concurrent_requests"]), nil)
+	w.Gauge("giraffecloud_grpc_active_tunnels", "Connected gRPC tunnel streams", float64(grpcMetrics["active_tunnels"]), nil)
+	w.Gauge("giraffecloud_grpc_pending_requests", "Requests waiting for a response on gRPC tunnel streams", float64(grpcMetrics["pending_requests"]), nil)
+	w.Counter("giraffecloud_grpc_pending_reaped_total", "Pending gRPC requests dropped after outliving the max age", float64(grpcMetrics["pending_reaped"]), nil)
+	if r.config.ResponseCacheSize > 0 {
+		for _, status := range []string{"hit", "miss", "stale", "bypass"} {
+			w.Counter("giraffecloud_response_cache_requests_total", "GET requests by response cache status", float64(grpcMetrics["cache_"+status]), map[string]string{"status": status})
+		}
+		w.Gauge("giraffecloud_response_cache_bytes", "Response body bytes held in the response cache", float64(grpcMetrics["cache_bytes"]), nil)
+	}
+
+	w.Counter("giraffecloud_tcp_requests_total", "Requests proxied over TCP tunnel connections", float64(tcpMetrics["requests"]), nil)
+	w.Counter("giraffecloud_tcp_pool_hits_total", "TCP requests served from the connection pool", float64(tcpMetrics["pool_hits"]), nil)
+	w.Counter("giraffecloud_tcp_pool_misses_total", "TCP requests that found no pooled connection", float64(tcpMetrics["pool_misses"]), nil)
+	w.Gauge("giraffecloud_tcp_concurrent_requests", "In-flight TCP tunnel requests", float64(tcpMetrics["concurrent_requests"]), nil)
+	for userID, count := range r.UserConnectionCounts() {
+		w.Gauge("giraffecloud_user_connections", "Tunnel connections (gRPC streams and pooled TCP) held per user", float64(count), map[string]string{"user_id": strconv.FormatUint(uint64(userID), 10)})
+	}
+
+	// Rolling latency percentiles per domain: time to response for gRPC, whole request for TCP
+	latencies := map[string]map[string]LatencyPercentiles{
+		"grpc": r.grpcTunnel.GetLatency(),
+		"tcp":  r.tcpTunnel.GetLatency(),
+	}
+	for connType, byDomain := range latencies {
+		for domain, p := range byDomain {
+			w.Summary("giraffecloud_request_latency_seconds", "Request latency percentiles per domain over the last 5 minutes",
+				map[string]float64{"0.5": p.P50.Seconds(), "0.95": p.P95.Seconds(), "0.99": p.P99.Seconds()}, p.Count,
+				map[string]string{"domain": domain, "type": connType})
+		}
+	}
+	for connType, byDomain := range latencies {
+		for domain, p := range byDomain {
+			w.Gauge("giraffecloud_request_latency_samples", "Requests in the latency window per domain", float64(p.Count),
+				map[string]string{"domain": domain, "type": connType})
+		}
+	}
+
+	// Per-domain concurrency cap: in-flight and queued requests, and rejections
+	w.Counter("giraffecloud_router_queue_rejected_total", "Requests rejected with 503 by the per-domain concurrency cap", float64(r.concurrency.Rejected()), nil)
+	domainLoad := r.concurrency.Stats()
+	for domain, load := range domainLoad {
+		w.Gauge("giraffecloud_domain_inflight_requests", "HTTP requests holding a concurrency slot per domain", float64(load.InFlight), map[string]string{"domain": domain})
+	}
+	for domain, load := range domainLoad {
+		w.Gauge("giraffecloud_domain_queued_requests", "HTTP requests waiting for a concurrency slot per domain", float64(load.Queued), map[string]string{"domain": domain})
+	}
+
+	// Per-domain circuit breaker state; only domains with recent failures are listed
+	for domain, state := range r.tcpTunnel.GetCircuitStates() {
+		w.Gauge("giraffecloud_circuit_breaker_state", "TCP tunnel circuit breaker state per domain (0=closed, 1=open, 2=half-open)", float64(state), map[string]string{"domain": domain})
+	}
+
+	// Per-domain active connections
+	for _, domain := range r.grpcTunnel.GetActiveDomains() {
+		w.Gauge("giraffecloud_domain_active_connections", "Active tunnel connections per domain", 1, map[string]string{"domain": domain, "type": "grpc"})
+	}
+	for domain, counts := range r.tcpTunnel.GetDomainConnectionCounts() {
+		for connType, count := range counts {
+			w.Gauge("giraffecloud_domain_active_connections", "Active tunnel connections per domain", float64(count), map[string]string{"domain": domain, "type": string(connType)})
+		}
+	}
+}
+
+// TransportHeader tells which tunnel transport carried a proxied response, TransportGRPC or
+// TransportTCP ('giraffecloud test-connection' reports it). Errors the router answers
+// itself have X-Tunnel-Router instead.
+const TransportHeader = "X-Tunnel-Transport"
+
+const (
+	TransportGRPC = "grpc"
+	TransportTCP  = "tcp"
+)
+
+// applyResponseHeaders sets the configured response headers; origin headers win unless overriding
+func (r *HybridTunnelRouter) applyResponseHeaders(response *http.Response) {
+	for key, value := range r.config.ResponseHeaders {
+		if !r.config.OverrideResponseHeaders && response.Header.Get(key) != "" {
+			continue
+		}
+		response.Header.Set(key, value)
+	}
+}
+
+// stripRequestHeaders removes hop-by-hop headers (RFC 9110 section 7.6.1) and the
+// configured StripRequestHeaders from a request before it goes to the origin. WebSocket
+// upgrades keep Connection and Upgrade, which the handshake needs; "TE: trailers" is kept
+// as gRPC origins require it.
+func (r *HybridTunnelRouter) stripRequestHeaders(req *http.Request, isWebSocket bool) {
+	h := req.Header
+	if !isWebSocket {
+		// Headers named in Connection apply to this hop only
+		for _, value := range h.Values("Connection") {
+			for _, name := range strings.Split(value, ",") {
+				if name = strings.TrimSpace(name); name != "" {
+					h.Del(name)
+				}
+			}
+		}
+		h.Del("Connection")
+		h.Del("Upgrade")
+	}
+	keepTrailers := false
+	for _, value := range h.Values("Te") {
+		for _, token := range strings.Split(value, ",") {
+			keepTrailers = keepTrailers || strings.EqualFold(strings.TrimSpace(token), "trailers")
+		}
+	}
+	for _, name := range []string{"Keep-Alive", "Te", "Trailer"} {
+		h.Del(name)
+	}
+	if keepTrailers {
+		h.Set("Te", "trailers")
+	}
+	for name := range h {
+		if strings.HasPrefix(name, "Proxy-") {
+			delete(h, name)
+		}
+	}
+
+	for _, name := range r.config.StripRequestHeaders {
+		h.Del(name)
+	}
+}
+
+// applyForwardedHeaders tells the origin who the client is: clientIP, the address the
+// connection came from, is appended to the X-Forwarded-For chain and is always the X-Real-IP.
+// Earlier X-Forwarded-For entries are whatever the visitor sent and are only passed along.
+// X-Forwarded-Proto is set unless a proxy in front of the router already set it.
+func (r *HybridTunnelRouter) applyForwardedHeaders(req *http.Request, clientIP string) {
+	if !r.config.ForwardedHeaders {
+		return
+	}
+
+	req.Header.Set("X-Real-IP", clientIP)
+	chain := req.Header.Values("X-Forwarded-For")
+	req.Header.Set("X-Forwarded-For", strings.Join(append(chain, clientIP), ", "))
+
+	if req.Header.Get("X-Forwarded-Proto") == "" {
+		req.Header.Set("X-Forwarded-Proto", "https")
+	}
+}
+
+// RoutingDomain picks the tunnel domain for a request: the Host header when it names an
+// active tunnel, otherwise the TLS server name (SNI) when that does, e.g. for clients that
+// send no Host or one that doesn't match the name they connected to
+func (r *HybridTunnelRouter) RoutingDomain(host, serverName string) string {
+	if serverName == "" || r.IsTunnelDomain(host) {
+		return host
+	}
+	if r.IsTunnelDomain(serverName) {
+		r.logger.Debug("[HYBRID] Routing by SNI %s (Host: %q)", serverName, host)
+		return serverName
 	}
+	return host
 }
 
 // IsTunnelDomain checks if any tunnel (gRPC or TCP) is active for the domain
@@ -0,0 +1,140 @@
+package tunnel
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestHybridTunnelRouter_ApplyResponseHeaders(t *testing.T) {
+	newResponse := func() *http.Response {
+		resp := &http.Response{Header: make(http.Header)}
+		resp.Header.Set("X-Served-By", "origin")
+		return resp
+	}
+	config := &HybridRouterConfig{
+		ResponseHeaders: map[string]string{
+			"X-Served-By":               "giraffecloud",
+			"Strict-Transport-Security": "max-age=31536000",
+		},
+	}
+	r := &HybridTunnelRouter{config: config}
+
+	resp := newResponse()
+	r.applyResponseHeaders(resp)
+	if got := resp.Header.Get("X-Served-By"); got != "origin" {
+		t.Errorf("Expected origin header to win, got %q", got)
+	}
+	if got := resp.Header.Get("Strict-Transport-Security"); got != "max-age=31536000" {
+		t.Errorf("Expected configured header to be added, got %q", got)
+	}
+
+	config.OverrideResponseHeaders = true
+	resp = newResponse()
+	r.applyResponseHeaders(resp)
+	if got := resp.Header.Get("X-Served-By"); got != "giraffecloud" {
+		t.Errorf("Expected configured header to override origin, got %q", got)
+	}
+}
+
+func TestHybridTunnelRouter_ApplyForwardedHeaders(t *testing.T) {
+	r := &HybridTunnelRouter{config: &HybridRouterConfig{ForwardedHeaders: true}}
+
+	// Direct connection: the peer is the client
+	req, _ := http.NewRequest(http.MethodGet, "http://app.example.com/", nil)
+	r.applyForwardedHeaders(req, "203.0.113.7")
+	if got := req.Header.Get("X-Forwarded-For"); got != "203.0.113.7" {
+		t.Errorf("X-Forwarded-For = %q", got)
+	}
+	if got := req.Header.Get("X-Real-IP"); got != "203.0.113.7" {
+		t.Errorf("X-Real-IP = %q", got)
+	}
+	if got := req.Header.Get("X-Forwarded-Proto"); got != "https" {
+		t.Errorf("X-Forwarded-Proto = %q", got)
+	}
+
+	// Visitor-supplied headers: the chain is extended, but the real IP is the connection's
+	req, _ = http.NewRequest(http.MethodGet, "http://app.example.com/", nil)
+	req.Header.Set("X-Forwarded-For", "198.51.100.1, 10.0.0.2")
+	req.Header.Set("X-Real-IP", "198.51.100.1")
+	req.Header.Set("X-Forwarded-Proto", "http")
+	r.applyForwardedHeaders(req, "203.0.113.7")
+	if got := req.Header.Get("X-Forwarded-For"); got != "198.51.100.1, 10.0.0.2, 203.0.113.7" {
+		t.Errorf("X-Forwarded-For = %q", got)
+	}
+	if got := req.Header.Get("X-Real-IP"); got != "203.0.113.7" {
+		t.Errorf("X-Real-IP = %q, want the connection's address", got)
+	}
+	if got := req.Header.Get("X-Forwarded-Proto"); got != "http" {
+		t.Errorf("X-Forwarded-Proto = %q, want the proxy's value kept", got)
+	}
+
+	// Disabled: headers are left alone
+	r.config.ForwardedHeaders = false
+	req, _ = http.NewRequest(http.MethodGet, "http://app.example.com/", nil)
+	r.applyForwardedHeaders(req, "203.0.113.7")
+	if len(req.Header) != 0 {
+		t.Errorf("Expected no headers, got %v", req.Header)
+	}
+}
+
+func TestHybridTunnelRouter_StripRequestHeaders(t *testing.T) {
+	r := &HybridTunnelRouter{config: &HybridRouterConfig{StripRequestHeaders: []string{"X-Internal-Auth", "x-tunnel-router"}}}
+	newRequest := func() *http.Request {
+		req, _ := http.NewRequest(http.MethodGet, "http://app.example.com/", nil)
+		req.Header.Set("Connection", "keep-alive, X-Hop")
+		req.Header.Set("X-Hop", "1")
+		req.Header.Set("Keep-Alive", "timeout=5")
+		req.Header.Set("Upgrade", "websocket")
+		req.Header.Set("Proxy-Authorization", "Basic Zm9vOmJhcg==")
+		req.Header.Set("Proxy-Connection", "keep-alive")
+		req.Header.Set("Te", "gzip, trailers")
+		req.Header.Set("Trailer", "Expires")
+		req.Header.Set("X-Internal-Auth", "secret")
+		req.Header.Set("X-Tunnel-Router", "hybrid")
+		req.Header.Set("Authorization", "Bearer token")
+		req.Header.Set("Cookie", "session=1")
+		req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+		return req
+	}
+
+	req := newRequest()
+	r.stripRequestHeaders(req, false)
+	for _, name := range []string{"Connection", "X-Hop", "Keep-Alive", "Upgrade", "Proxy-Authorization",
+		"Proxy-Connection", "Trailer", "X-Internal-Auth", "X-Tunnel-Router"} {
+		if got := req.Header.Get(name); got != "" {
+			t.Errorf("%s = %q, want it stripped", name, got)
+		}
+	}
+	for name, want := range map[string]string{
+		"Te":            "trailers",
+		"Authorization": "Bearer token",
+		"Cookie":        "session=1",
+	} {
+		if got := req.Header.Get(name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+
+	// WebSocket upgrades keep what the handshake needs
+	req = newRequest()
+	r.stripRequestHeaders(req, true)
+	for name, want := range map[string]string{
+		"Connection":        "keep-alive, X-Hop",
+		"Upgrade":           "websocket",
+		"Sec-WebSocket-Key": "dGhlIHNhbXBsZSBub25jZQ==",
+		"Proxy-Connection":  "",
+		"X-Internal-Auth":   "",
+	} {
+		if got := req.Header.Get(name); got != want {
+			t.Errorf("websocket %s = %q, want %q", name, got, want)
+		}
+	}
+
+	// Without TE: trailers nothing is re-added
+	req, _ = http.NewRequest(http.MethodGet, "http://app.example.com/", nil)
+	req.Header.Set("Te", "gzip")
+	r.stripRequestHeaders(req, false)
+	if _, ok := req.Header["Te"]; ok {
+		t.Errorf("Te = %q, want it stripped", req.Header.Get("Te"))
+	}
+}
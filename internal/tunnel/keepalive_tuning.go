@@ -0,0 +1,110 @@
+package tunnel
+
+import (
+	"sync"
+	"time"
+)
+
+// Bounds of the adaptive keepalive interval (GRPCClientConfig.KeepAliveMin/KeepAliveMax)
+const (
+	DefaultKeepAliveMin = 15 * time.Second
+	DefaultKeepAliveMax = 60 * time.Second
+)
+
+// grpc-go never pings more often than this, whatever the configured interval
+const minKeepAliveTime = 10 * time.Second
+
+const (
+	keepAliveUnstableWindow     = 10 * time.Minute // Reconnects closer together than this count as unstable
+	keepAliveUnstableReconnects = 2                // Reconnects within the window that shorten the interval
+	keepAliveStablePeriod       = 30 * time.Minute // Without reconnects for this long, the interval is relaxed a step
+)
+
+// keepAliveTuner adapts the gRPC keepalive interval to the network: frequent reconnects
+// (typically a NAT or firewall dropping idle mappings) halve it towards min, and a stable
+// connection doubles it back towards max. The new interval applies on the next dial.
+type keepAliveTuner struct {
+	mu         sync.Mutex
+	min, max   time.Duration
+	current    time.Duration
+	reconnects []time.Time // Reconnects since the last change, within keepAliveUnstableWindow
+	lastChange time.Time   // Last change or reconnect, whichever is later
+	seen       int64       // Reconnect count already accounted for
+}
+
+// newKeepAliveTuner starts at interval, clamped to [min, max] (0 = defaults)
+func newKeepAliveTuner(interval, min, max time.Duration) *keepAliveTuner {
+	t := &keepAliveTuner{current: interval, lastChange: time.Now()}
+	t.setBounds(min, max)
+	return t
+}
+
+// setBounds changes the interval bounds (0 = defaults), clamping the current interval
+func (t *keepAliveTuner) setBounds(min, max time.Duration) {
+	if min <= 0 {
+		min = DefaultKeepAliveMin
+	}
+	if min < minKeepAliveTime {
+		min = minKeepAliveTime
+	}
+	if max <= 0 {
+		max = DefaultKeepAliveMax
+	}
+	if max < min {
+		max = min
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.min, t.max = min, max
+	t.current = t.clamp(t.current)
+}
+
+func (t *keepAliveTuner) clamp(d time.Duration) time.Duration {
+	if d < t.min {
+		return t.min
+	}
+	if d > t.max {
+		return t.max
+	}
+	return d
+}
+
+// Interval returns the keepalive interval to dial with
+func (t *keepAliveTuner) Interval() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.current
+}
+
+// observe accounts for the client's reconnect count at now and returns the previous and new
+// interval; they differ when the interval changed
+func (t *keepAliveTuner) observe(reconnectCount int64, now time.Time) (time.Duration, time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	old := t.current
+	for ; t.seen < reconnectCount; t.seen++ {
+		t.reconnects = append(t.reconnects, now)
+		t.lastChange = now
+	}
+
+	recent := t.reconnects[:0]
+	for _, at := range t.reconnects {
+		if now.Sub(at) < keepAliveUnstableWindow {
+			recent = append(recent, at)
+		}
+	}
+	t.reconnects = recent
+
+	switch {
+	case len(t.reconnects) >= keepAliveUnstableReconnects && t.current > t.min:
+		t.current = t.clamp(t.current / 2)
+		t.reconnects = nil
+		t.lastChange = now
+	case len(t.reconnects) == 0 && now.Sub(t.lastChange) >= keepAliveStablePeriod && t.current < t.max:
+		t.current = t.clamp(t.current * 2)
+		t.lastChange = now
+	}
+	return old, t.current
+}
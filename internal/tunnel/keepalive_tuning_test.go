@@ -0,0 +1,61 @@
+package tunnel
+
+import (
+	"testing"
+	"time"
+)
+
+func TestKeepAliveTuner(t *testing.T) {
+	tuner := newKeepAliveTuner(60*time.Second, 0, 0)
+	if got := tuner.Interval(); got != 60*time.Second {
+		t.Fatalf("initial interval = %v, want 60s", got)
+	}
+
+	now := time.Now()
+
+	// A single reconnect is not enough to shorten the interval
+	if _, got := tuner.observe(1, now); got != 60*time.Second {
+		t.Fatalf("after one reconnect interval = %v, want 60s", got)
+	}
+
+	// Frequent reconnects halve it down to the minimum
+	for i, want := range []time.Duration{30 * time.Second, DefaultKeepAliveMin, DefaultKeepAliveMin} {
+		now = now.Add(time.Minute)
+		if _, got := tuner.observe(int64(2*i+2), now); got != want {
+			t.Fatalf("step %d: interval = %v, want %v", i, got, want)
+		}
+		now = now.Add(time.Minute)
+		tuner.observe(int64(2*i+3), now)
+	}
+
+	// Reconnects further apart than the window don't count
+	now = now.Add(keepAliveUnstableWindow)
+	tuner.observe(8, now)
+
+	// Stable connections relax it back towards the maximum, one step per stable period
+	for _, want := range []time.Duration{30 * time.Second, DefaultKeepAliveMax, DefaultKeepAliveMax} {
+		now = now.Add(keepAliveStablePeriod - time.Second)
+		if old, got := tuner.observe(8, now); got != old {
+			t.Fatalf("interval relaxed %v -> %v before the stable period", old, got)
+		}
+		now = now.Add(time.Second)
+		if _, got := tuner.observe(8, now); got != want {
+			t.Fatalf("interval = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestKeepAliveTunerBounds(t *testing.T) {
+	tuner := newKeepAliveTuner(60*time.Second, time.Second, 20*time.Second)
+	if got := tuner.Interval(); got != 20*time.Second {
+		t.Fatalf("interval = %v, want clamped to max 20s", got)
+	}
+	if tuner.min != minKeepAliveTime {
+		t.Fatalf("min = %v, want gRPC floor %v", tuner.min, minKeepAliveTime)
+	}
+
+	tuner.setBounds(30*time.Second, 2*time.Minute)
+	if got := tuner.Interval(); got != 30*time.Second {
+		t.Fatalf("interval = %v, want raised to new min 30s", got)
+	}
+}
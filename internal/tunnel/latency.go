@@ -0,0 +1,212 @@
+package tunnel
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// DefaultLatencyWindow is how far back latency percentiles look
+const DefaultLatencyWindow = 5 * time.Minute
+
+// latencySlots is the number of time slices a window is split into. Expired slices are
+// reused, so old samples age out gradually instead of all at once.
+const latencySlots = 10
+
+// latencyBounds are the upper bounds of the histogram buckets; slower requests go into
+// a final overflow bucket
+var latencyBounds = [...]time.Duration{
+	time.Millisecond, 2 * time.Millisecond, 5 * time.Millisecond,
+	10 * time.Millisecond, 25 * time.Millisecond, 50 * time.Millisecond,
+	100 * time.Millisecond, 250 * time.Millisecond, 500 * time.Millisecond,
+	time.Second, 2500 * time.Millisecond, 5 * time.Second,
+	10 * time.Second, 30 * time.Second, time.Minute, 2 * time.Minute,
+}
+
+// LatencyPercentiles summarizes the request durations seen in the window
+type LatencyPercentiles struct {
+	Count int64         `json:"count"`
+	P50   time.Duration `json:"p50"`
+	P95   time.Duration `json:"p95"`
+	P99   time.Duration `json:"p99"`
+}
+
+// String formats the percentiles for log lines
+func (p LatencyPercentiles) String() string {
+	if p.Count == 0 {
+		return "p50=- p95=- p99=- (n=0)"
+	}
+	return fmt.Sprintf("p50=%v p95=%v p99=%v (n=%d)", roundLatency(p.P50), roundLatency(p.P95), roundLatency(p.P99), p.Count)
+}
+
+// roundLatency keeps log lines short without hiding sub-millisecond latencies
+func roundLatency(d time.Duration) time.Duration {
+	if d < time.Millisecond {
+		return d.Round(time.Microsecond)
+	}
+	return d.Round(time.Millisecond)
+}
+
+// latencySlot holds the bucket counts of one time slice
+type latencySlot struct {
+	epoch  int64 // Index of the time slice the counts belong to
+	counts [len(latencyBounds) + 1]int64
+}
+
+// latencyHistogram is a bucketed histogram over a rolling window
+type latencyHistogram struct {
+	slots [latencySlots]latencySlot
+}
+
+func (h *latencyHistogram) observe(epoch int64, d time.Duration) {
+	slot := &h.slots[epoch%latencySlots]
+	if slot.epoch != epoch {
+		*slot = latencySlot{epoch: epoch}
+	}
+	bucket := len(latencyBounds)
+	for i, bound := range latencyBounds {
+		if d <= bound {
+			bucket = i
+			break
+		}
+	}
+	slot.counts[bucket]++
+}
+
+// merged sums the slots that are still inside the window ending at epoch
+func (h *latencyHistogram) merged(epoch int64) (counts [len(latencyBounds) + 1]int64, total int64) {
+	for i := range h.slots {
+		slot := &h.slots[i]
+		if slot.epoch <= epoch-latencySlots || slot.epoch > epoch {
+			continue
+		}
+		for b, c := range slot.counts {
+			counts[b] += c
+			total += c
+		}
+	}
+	return counts, total
+}
+
+// percentiles estimates p50/p95/p99 by interpolating within buckets
+func (h *latencyHistogram) percentiles(epoch int64) LatencyPercentiles {
+	counts, total := h.merged(epoch)
+	p := LatencyPercentiles{Count: total}
+	if total == 0 {
+		return p
+	}
+	p.P50 = latencyQuantile(counts, total, 0.50)
+	p.P95 = latencyQuantile(counts, total, 0.95)
+	p.P99 = latencyQuantile(counts, total, 0.99)
+	return p
+}
+
+func latencyQuantile(counts [len(latencyBounds) + 1]int64, total int64, q float64) time.Duration {
+	rank := int64(math.Ceil(q * float64(total)))
+	var seen int64
+	for b, c := range counts {
+		if c == 0 || seen+c < rank {
+			seen += c
+			continue
+		}
+		if b == len(latencyBounds) {
+			return latencyBounds[b-1] // Overflow bucket: only the lower bound is known
+		}
+		var lower time.Duration
+		if b > 0 {
+			lower = latencyBounds[b-1]
+		}
+		fraction := float64(rank-seen) / float64(c)
+		return lower + time.Duration(fraction*float64(latencyBounds[b]-lower))
+	}
+	return latencyBounds[len(latencyBounds)-1]
+}
+
+// LatencyTracker keeps rolling latency histograms per domain and across all domains
+type LatencyTracker struct {
+	slotDuration time.Duration
+	now          func() time.Time
+
+	mu      sync.Mutex
+	all     latencyHistogram
+	domains map[string]*latencyHistogram
+}
+
+// NewLatencyTracker creates a tracker whose percentiles cover the last window.
+// A window of 0 uses DefaultLatencyWindow.
+func NewLatencyTracker(window time.Duration) *LatencyTracker {
+	if window <= 0 {
+		window = DefaultLatencyWindow
+	}
+	slotDuration := window / latencySlots
+	if slotDuration <= 0 {
+		slotDuration = 1
+	}
+	return &LatencyTracker{
+		slotDuration: slotDuration,
+		now:          time.Now,
+		domains:      make(map[string]*latencyHistogram),
+	}
+}
+
+func (t *LatencyTracker) epoch() int64 {
+	return t.now().UnixNano() / int64(t.slotDuration)
+}
+
+// Observe records how long a request for domain took
+func (t *LatencyTracker) Observe(domain string, d time.Duration) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	epoch := t.epoch()
+	t.all.observe(epoch, d)
+	h := t.domains[domain]
+	if h == nil {
+		h = &latencyHistogram{}
+		t.domains[domain] = h
+	}
+	h.observe(epoch, d)
+}
+
+// Percentiles returns the percentiles for domain, or across all domains if domain is empty
+func (t *LatencyTracker) Percentiles(domain string) LatencyPercentiles {
+	if t == nil {
+		return LatencyPercentiles{}
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if domain == "" {
+		return t.all.percentiles(t.epoch())
+	}
+	if h := t.domains[domain]; h != nil {
+		return h.percentiles(t.epoch())
+	}
+	return LatencyPercentiles{}
+}
+
+// Snapshot returns the percentiles of every domain with requests in the window.
+// Domains without recent requests are forgotten.
+func (t *LatencyTracker) Snapshot() map[string]LatencyPercentiles {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	epoch := t.epoch()
+	snapshot := make(map[string]LatencyPercentiles, len(t.domains))
+	for domain, h := range t.domains {
+		p := h.percentiles(epoch)
+		if p.Count == 0 {
+			delete(t.domains, domain)
+			continue
+		}
+		snapshot[domain] = p
+	}
+	return snapshot
+}
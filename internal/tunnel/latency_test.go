@@ -0,0 +1,49 @@
+package tunnel
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLatencyTrackerPercentiles(t *testing.T) {
+	now := time.Unix(1000, 0)
+	tracker := NewLatencyTracker(time.Minute)
+	tracker.now = func() time.Time { return now }
+
+	// 90 fast requests, 9 slow ones and one very slow outlier
+	for i := 0; i < 90; i++ {
+		tracker.Observe("a.example.com", 3*time.Millisecond)
+	}
+	for i := 0; i < 9; i++ {
+		tracker.Observe("a.example.com", 400*time.Millisecond)
+	}
+	tracker.Observe("b.example.com", 20*time.Second)
+
+	p := tracker.Percentiles("")
+	if p.Count != 100 {
+		t.Fatalf("Count = %d, want 100", p.Count)
+	}
+	if p.P50 <= 2*time.Millisecond || p.P50 > 5*time.Millisecond {
+		t.Errorf("P50 = %v, want within the 2-5ms bucket", p.P50)
+	}
+	if p.P95 <= 250*time.Millisecond || p.P95 > 500*time.Millisecond {
+		t.Errorf("P95 = %v, want within the 250-500ms bucket", p.P95)
+	}
+	if p.P99 <= 250*time.Millisecond || p.P99 > 500*time.Millisecond {
+		t.Errorf("P99 = %v, want within the 250-500ms bucket", p.P99)
+	}
+	if b := tracker.Percentiles("b.example.com"); b.Count != 1 || b.P99 <= 10*time.Second {
+		t.Errorf("b.example.com = %+v", b)
+	}
+
+	// Samples age out of the window, and idle domains are forgotten
+	now = now.Add(2 * time.Minute)
+	tracker.Observe("b.example.com", time.Millisecond)
+	snapshot := tracker.Snapshot()
+	if len(snapshot) != 1 || snapshot["b.example.com"].Count != 1 {
+		t.Errorf("Snapshot() after window = %+v", snapshot)
+	}
+	if p := tracker.Percentiles(""); p.Count != 1 {
+		t.Errorf("Count after window = %d, want 1", p.Count)
+	}
+}
@@ -0,0 +1,59 @@
+package tunnel
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/osa911/giraffecloud/internal/tunnel/proto"
+)
+
+// localDialTimeout bounds each dial to the local service on the TCP tunnel
+const localDialTimeout = 5 * time.Second
+
+// SetFallbackLocalPort sets a secondary local port that requests go to when nothing accepts
+// connections on the primary one (0 = no fallback)
+func (t *Tunnel) SetFallbackLocalPort(port int) {
+	t.fallbackLocalPort = port
+}
+
+// SetFallbackLocalPort sets the local port requests are retried on when the primary
+// port refuses the connection (0 = no fallback)
+func (c *GRPCTunnelClient) SetFallbackLocalPort(port int) {
+	c.fallbackPort = port
+}
+
+// dialLocalService connects to the local service, or to the fallback port when the primary
+// one is unreachable. Failovers are logged and counted in the local_failovers stat.
+func (t *Tunnel) dialLocalService() (net.Conn, error) {
+	primary := localServiceAddr(t.localHost, t.localPort)
+	conn, err := net.DialTimeout("tcp", primary, localDialTimeout)
+	if err == nil || t.fallbackLocalPort <= 0 {
+		return conn, err
+	}
+
+	fallback := localServiceAddr(t.localHost, t.fallbackLocalPort)
+	conn, fallbackErr := net.DialTimeout("tcp", fallback, localDialTimeout)
+	if fallbackErr != nil {
+		return nil, fmt.Errorf("%w (fallback %s: %v)", err, fallback, fallbackErr)
+	}
+	atomic.AddInt64(&t.localFailovers, 1)
+	t.logger.Warn("Local service on %s unreachable (%v), using fallback %s", primary, err, fallback)
+	return conn, nil
+}
+
+// fallbackRequest rebuilds a local request that could not connect so it goes to the
+// fallback port instead; the body is read again from httpReq
+func (c *GRPCTunnelClient) fallbackRequest(req *http.Request, httpReq *proto.HTTPRequest) *http.Request {
+	retry := req.Clone(req.Context())
+	retry.URL.Host = localServiceAddr(c.localHost, c.fallbackPort)
+	retry.Host = retry.URL.Host
+	if len(httpReq.Body) > 0 {
+		retry.Body = io.NopCloser(newThrottledReader(req.Context(), strings.NewReader(string(httpReq.Body)), c.uploadLimiter))
+	}
+	return retry
+}
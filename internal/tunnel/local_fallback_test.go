@@ -0,0 +1,70 @@
+package tunnel
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/osa911/giraffecloud/internal/tunnel/proto"
+)
+
+// closedPort returns a local port nothing listens on
+func closedPort(t *testing.T) int {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+	return port
+}
+
+func TestLocalServiceFallback(t *testing.T) {
+	initTestLogger(t)
+	fallback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Write([]byte("fallback:" + r.URL.Path + ":" + string(body)))
+	}))
+	defer fallback.Close()
+	fallbackPort, _ := strconv.Atoi(fallback.URL[strings.LastIndex(fallback.URL, ":")+1:])
+	primaryPort := closedPort(t)
+
+	client := NewGRPCTunnelClient("localhost:4444", "test.example.com", "token", int32(primaryPort), nil)
+	client.SetLocalHost("127.0.0.1")
+	if _, err := client.makeLocalServiceRequest(context.Background(), &proto.HTTPRequest{Method: http.MethodGet, Path: "/"}); !isLocalUnreachable(err) {
+		t.Fatalf("without a fallback: err = %v, want a dial error", err)
+	}
+
+	client.SetFallbackLocalPort(fallbackPort)
+	resp, err := client.makeLocalServiceRequest(context.Background(), &proto.HTTPRequest{Method: http.MethodPost, Path: "/upload", Body: []byte("data")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "fallback:/upload:data" {
+		t.Errorf("body = %q", body)
+	}
+	if n := client.GetMetrics()["local_failovers"]; n != int64(1) {
+		t.Errorf("local_failovers = %v, want 1", n)
+	}
+
+	tun := NewTunnel()
+	tun.SetLocalHost("127.0.0.1")
+	tun.localPort = primaryPort
+	tun.SetFallbackLocalPort(fallbackPort)
+	conn, err := tun.dialLocalService()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+	if n := tun.GetStats()["local_failovers"]; n != int64(1) {
+		t.Errorf("tunnel local_failovers = %v, want 1", n)
+	}
+}
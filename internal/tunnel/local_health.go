@@ -0,0 +1,178 @@
+package tunnel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// localHealthDialTimeout bounds each poll of the local service port
+const localHealthDialTimeout = 2 * time.Second
+
+// DefaultLocalWaitTimeout is how long 'connect --wait-for-local' waits when given no value
+const DefaultLocalWaitTimeout = 2 * time.Minute
+
+// localWaitPollInterval is how often the local port is dialed while waiting for it
+const localWaitPollInterval = 500 * time.Millisecond
+
+// Local service states reported by the health poll
+const (
+	localServiceUnknown int32 = iota
+	localServiceUp
+	localServiceDown
+)
+
+// localServiceStateNames maps local service states to their GetStats values
+var localServiceStateNames = map[int32]string{
+	localServiceUnknown: "unknown",
+	localServiceUp:      "up",
+	localServiceDown:    "down",
+}
+
+// startLocalHealthPolling polls the local service port every RetryConfig.LocalHealthInterval
+// until the tunnel stops or the interval is set to 0. It is a no-op when polling is disabled
+// or already running.
+func (t *Tunnel) startLocalHealthPolling() {
+	if t.retryConfig.Load().LocalHealthInterval <= 0 || t.ctx == nil {
+		return
+	}
+	if !atomic.CompareAndSwapInt32(&t.localHealthPolling, 0, 1) {
+		return
+	}
+
+	t.logger.Info("Polling the local service every %v", t.retryConfig.Load().LocalHealthInterval)
+	go func() {
+		defer atomic.StoreInt32(&t.localHealthPolling, 0)
+		for {
+			// Re-read the interval so 'giraffecloud reload' can change or disable it
+			interval := t.retryConfig.Load().LocalHealthInterval
+			if interval <= 0 {
+				atomic.StoreInt32(&t.localServiceState, localServiceUnknown)
+				t.logger.Info("Local service polling disabled")
+				return
+			}
+
+			select {
+			case <-time.After(interval):
+				t.checkLocalService()
+			case <-t.ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// SetWaitForLocal makes the first connection wait up to timeout for the local service to
+// accept connections instead of failing at once (0 = don't wait)
+func (t *Tunnel) SetWaitForLocal(timeout time.Duration) {
+	t.waitForLocal = timeout
+}
+
+// waitForLocalService polls the local port until it accepts a connection, timeout elapses
+// or ctx is done. Only the first connection waits: once the service is up, waitForLocal
+// is cleared and later reconnects check the port as usual.
+func (t *Tunnel) waitForLocalService(ctx context.Context, timeout time.Duration) error {
+	addr := localServiceAddr(t.localHost, t.localPort)
+	deadline := time.Now().Add(timeout)
+	for attempt := 0; ; attempt++ {
+		dialTimeout := min(localHealthDialTimeout, time.Until(deadline))
+		if dialTimeout <= 0 {
+			return fmt.Errorf("no service listening on %s after waiting %v - make sure your service starts", addr, timeout)
+		}
+		if conn, err := net.DialTimeout("tcp", addr, dialTimeout); err == nil {
+			conn.Close()
+			if attempt > 0 {
+				t.logger.Info("✓ Local service on %s is up", addr)
+			}
+			t.waitForLocal = 0
+			return nil
+		}
+		if attempt == 0 {
+			t.logger.Info("Waiting up to %v for a service to listen on %s...", timeout, addr)
+		}
+
+		select {
+		case <-time.After(min(localWaitPollInterval, time.Until(deadline))):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// checkLocalService dials the local service once and handles a change of state
+func (t *Tunnel) checkLocalService() {
+	addr := localServiceAddr(t.localHost, t.localPort)
+	state := localServiceUp
+	conn, err := net.DialTimeout("tcp", addr, localHealthDialTimeout)
+	if err != nil {
+		state = localServiceDown
+	} else {
+		conn.Close()
+	}
+
+	previous := atomic.SwapInt32(&t.localServiceState, state)
+	switch {
+	case state == localServiceDown && previous != localServiceDown:
+		t.logger.Warn("Local service on %s is not reachable: %v", addr, err)
+	case state == localServiceUp && previous == localServiceDown:
+		t.logger.Info("✓ Local service on %s is back, resuming forwarding", addr)
+		t.onLocalServiceRecovered()
+	}
+}
+
+// onLocalServiceRecovered drops connections to the previous local process and cuts short
+// a reconnect backoff that was waiting for the local port
+func (t *Tunnel) onLocalServiceRecovered() {
+	if t.grpcClient != nil {
+		t.grpcClient.ResetLocalConnections()
+	}
+	select {
+	case t.localRecovered <- struct{}{}:
+	default:
+	}
+}
+
+// probeLocalService checks once, right after the handshake, that something listens on the
+// local port, so a tunnel that is up but can't serve anything is obvious in the logs
+func (c *GRPCTunnelClient) probeLocalService() {
+	addr := localServiceAddr(c.localHost, int(c.targetPort))
+	conn, err := net.DialTimeout("tcp", addr, localHealthDialTimeout)
+	if err != nil {
+		c.logger.Warn("[%s] ⚠️  ================================================================", c.clientID)
+		c.logger.Warn("[%s] ⚠️  Tunnel for %s is up, but the local service on %s is not reachable: %v", c.clientID, c.domain, addr, err)
+		c.logger.Warn("[%s] ⚠️  Visitors get a 502 until it starts - is your app running on port %d?", c.clientID, c.targetPort)
+		c.logger.Warn("[%s] ⚠️  ================================================================", c.clientID)
+		return
+	}
+	conn.Close()
+	c.logger.Info("[%s] ✓ Local service on %s is reachable", c.clientID, addr)
+}
+
+// isLocalUnreachable reports whether a local request failed because nothing accepted the
+// connection (refused, no route, unknown host), rather than failing mid-request
+func isLocalUnreachable(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// sendLocalRequestError answers a request the local service could not handle. When the
+// service isn't running the visitor gets a plain 502 saying so instead of the dial error.
+func (c *GRPCTunnelClient) sendLocalRequestError(ctx context.Context, requestID string, err error) error {
+	if !isLocalUnreachable(err) {
+		return c.sendErrorResponse(requestID, fmt.Sprintf("Local service request failed: %v", err))
+	}
+	body := fmt.Sprintf("local service on port %d is not running\n", c.targetPort)
+	response := &http.Response{
+		StatusCode: http.StatusBadGateway,
+		Status:     "502 Bad Gateway",
+		Header: http.Header{
+			"Content-Type":           {"text/plain; charset=utf-8"},
+			"X-Content-Type-Options": {"nosniff"},
+		},
+	}
+	return c.sendCompleteResponse(ctx, requestID, response, []byte(body))
+}
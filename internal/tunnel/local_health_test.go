@@ -0,0 +1,152 @@
+package tunnel
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckLocalServiceRecovery(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, portStr, _ := net.SplitHostPort(listener.Addr().String())
+	port, _ := strconv.Atoi(portStr)
+	listener.Close()
+
+	tun := NewTunnel()
+	tun.localHost = "127.0.0.1"
+	tun.localPort = port
+
+	// Port closed: down, and no recovery signal
+	tun.checkLocalService()
+	if got := tun.localServiceState; got != localServiceDown {
+		t.Fatalf("state = %d, want down", got)
+	}
+	select {
+	case <-tun.localRecovered:
+		t.Fatal("unexpected recovery signal while down")
+	default:
+	}
+
+	// Service restarted on the same port: up, and a pending reconnect is woken
+	listener, err = net.Listen("tcp", net.JoinHostPort("127.0.0.1", portStr))
+	if err != nil {
+		t.Skipf("port %d taken before the service could restart: %v", port, err)
+	}
+	defer listener.Close()
+
+	tun.checkLocalService()
+	if got := tun.localServiceState; got != localServiceUp {
+		t.Fatalf("state = %d, want up", got)
+	}
+	select {
+	case <-tun.localRecovered:
+	default:
+		t.Fatal("expected a recovery signal after the service came back")
+	}
+
+	// Staying up doesn't signal again
+	tun.checkLocalService()
+	select {
+	case <-tun.localRecovered:
+		t.Fatal("unexpected second recovery signal")
+	default:
+	}
+}
+
+func TestLocalServiceNotRunning(t *testing.T) {
+	if testing.Short() {
+		t.Skip("end-to-end tunnel test")
+	}
+	initTestLogger(t)
+	ts, err := NewTestServer()
+	if err != nil {
+		t.Fatalf("NewTestServer: %v", err)
+	}
+	defer ts.Close()
+	t.Setenv("GIRAFFECLOUD_HOME", ts.ConfigHome)
+
+	// The tunnel connects even though nothing listens on the local port
+	ts.local.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	tun, err := ts.Connect(ctx)
+	if err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	defer tun.Disconnect()
+
+	want := fmt.Sprintf("local service on port %d is not running\n", ts.LocalPort())
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		req, _ := http.NewRequest(method, ts.URL+"/", strings.NewReader("hello"))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s through the tunnel: %v", method, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadGateway || string(body) != want {
+			t.Errorf("%s = %d %q, want 502 %q", method, resp.StatusCode, body, want)
+		}
+	}
+
+	if !isLocalUnreachable(&net.OpError{Op: "dial", Err: fmt.Errorf("connection refused")}) {
+		t.Error("a dial error should count as unreachable")
+	}
+	if isLocalUnreachable(&net.OpError{Op: "read", Err: fmt.Errorf("connection reset")}) {
+		t.Error("a read error mid-request should not count as unreachable")
+	}
+}
+
+func TestWaitForLocalService(t *testing.T) {
+	initTestLogger(t)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	_, portStr, _ := net.SplitHostPort(addr)
+	port, _ := strconv.Atoi(portStr)
+	listener.Close()
+
+	tun := NewTunnel()
+	tun.localHost = "127.0.0.1"
+	tun.localPort = port
+	tun.SetWaitForLocal(5 * time.Second)
+
+	// Nothing listens: give up after the timeout, or when the context ends
+	if err := tun.waitForLocalService(context.Background(), 300*time.Millisecond); err == nil || !strings.Contains(err.Error(), "after waiting") {
+		t.Fatalf("closed port: got %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := tun.waitForLocalService(ctx, 5*time.Second); err != context.Canceled {
+		t.Fatalf("cancelled: got %v", err)
+	}
+
+	// The service starts while waiting
+	go func() {
+		time.Sleep(700 * time.Millisecond)
+		if l, err := net.Listen("tcp", addr); err == nil {
+			t.Cleanup(func() { l.Close() })
+		}
+	}()
+	start := time.Now()
+	if err := tun.waitForLocalService(context.Background(), 5*time.Second); err != nil {
+		t.Fatalf("service started late: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 500*time.Millisecond {
+		t.Errorf("returned after %v, before the service started", elapsed)
+	}
+	if tun.waitForLocal != 0 {
+		t.Error("later connections should not wait again")
+	}
+}
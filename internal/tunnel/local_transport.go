@@ -0,0 +1,82 @@
+package tunnel
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultLocalScheme is the scheme used to reach the local service when none is configured
+const DefaultLocalScheme = "http"
+
+// normalizeLocalScheme lower-cases scheme and checks it is http or https ("" = DefaultLocalScheme)
+func normalizeLocalScheme(scheme string) (string, error) {
+	switch s := strings.ToLower(strings.TrimSpace(scheme)); s {
+	case "":
+		return DefaultLocalScheme, nil
+	case "http", "https":
+		return s, nil
+	default:
+		return "", fmt.Errorf("unsupported local scheme %q (use http or https)", scheme)
+	}
+}
+
+// Keep-alive pool defaults for the local service. Every forwarded request goes to the same
+// host, so the per-host limit matters most: Go's default of 2 makes busy apps redial.
+const (
+	DefaultLocalMaxIdleConns        = 100
+	DefaultLocalMaxIdleConnsPerHost = 64
+	DefaultLocalIdleConnTimeout     = 90 * time.Second
+)
+
+// newLocalTransport builds the transport for requests to the local service, pooling
+// keep-alive connections as sized in config (zero values = defaults). With LocalUseHTTP2
+// it speaks HTTP/2 only: h2c with prior knowledge for http, negotiated via ALPN for https.
+func newLocalTransport(config *GRPCClientConfig) *http.Transport {
+	scheme, useHTTP2 := config.LocalScheme, config.LocalUseHTTP2
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConns = DefaultLocalMaxIdleConns
+	if config.LocalMaxIdleConns > 0 {
+		transport.MaxIdleConns = config.LocalMaxIdleConns
+	}
+	transport.MaxIdleConnsPerHost = DefaultLocalMaxIdleConnsPerHost
+	if config.LocalMaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = config.LocalMaxIdleConnsPerHost
+	}
+	transport.IdleConnTimeout = DefaultLocalIdleConnTimeout
+	if config.LocalIdleConnTimeout > 0 {
+		transport.IdleConnTimeout = config.LocalIdleConnTimeout
+	}
+	if scheme == "https" {
+		// Local services typically use self-signed certificates; the public side is verified by the server
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	if useHTTP2 {
+		protocols := new(http.Protocols)
+		if scheme == "https" {
+			protocols.SetHTTP2(true)
+		} else {
+			protocols.SetUnencryptedHTTP2(true)
+		}
+		transport.Protocols = protocols
+	}
+	return transport
+}
+
+// localHopHeaders are connection-specific headers HTTP/2 forbids in requests
+var localHopHeaders = []string{"Connection", "Keep-Alive", "Proxy-Connection", "Transfer-Encoding", "Upgrade"}
+
+// stripHopHeaders removes the headers an HTTP/2 transport would reject
+func stripHopHeaders(h http.Header) {
+	for _, name := range localHopHeaders {
+		h.Del(name)
+	}
+}
+
+// ValidateLocalScheme checks that scheme is a supported local service scheme ("" = default)
+func ValidateLocalScheme(scheme string) error {
+	_, err := normalizeLocalScheme(scheme)
+	return err
+}
@@ -0,0 +1,58 @@
+package tunnel
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNormalizeLocalScheme(t *testing.T) {
+	for in, want := range map[string]string{"": "http", "HTTPS": "https", " http ": "http"} {
+		if got, err := normalizeLocalScheme(in); err != nil || got != want {
+			t.Errorf("normalizeLocalScheme(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := normalizeLocalScheme("ftp"); err == nil {
+		t.Error("expected an error for ftp")
+	}
+}
+
+func TestLocalTransportHTTP2(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Proto)
+	})
+
+	get := func(transport *http.Transport, url string) string {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, url, nil)
+		req.Header.Set("Connection", "keep-alive")
+		stripHopHeaders(req.Header)
+		resp, err := (&http.Client{Transport: transport}).Do(req)
+		if err != nil {
+			t.Fatalf("GET %s: %v", url, err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+
+	// h2c with prior knowledge
+	h2c := httptest.NewUnstartedServer(handler)
+	h2c.Config.Protocols = new(http.Protocols)
+	h2c.Config.Protocols.SetUnencryptedHTTP2(true)
+	h2c.Start()
+	defer h2c.Close()
+	if got := get(newLocalTransport(&GRPCClientConfig{LocalScheme: "http", LocalUseHTTP2: true}), h2c.URL); got != "HTTP/2.0" {
+		t.Errorf("h2c proto = %q, want HTTP/2.0", got)
+	}
+
+	// HTTP/2 over TLS with a self-signed certificate
+	tlsServer := httptest.NewUnstartedServer(handler)
+	tlsServer.EnableHTTP2 = true
+	tlsServer.StartTLS()
+	defer tlsServer.Close()
+	if got := get(newLocalTransport(&GRPCClientConfig{LocalScheme: "https", LocalUseHTTP2: true}), tlsServer.URL); got != "HTTP/2.0" {
+		t.Errorf("https proto = %q, want HTTP/2.0", got)
+	}
+}
@@ -0,0 +1,137 @@
+package tunnel
+
+import (
+	"context"
+	"io"
+	"os"
+	"time"
+)
+
+// logTailPollInterval is how often TailFile checks a followed file for new data
+const logTailPollInterval = 500 * time.Millisecond
+
+// TailFile writes the last n lines of path to out. With follow set it keeps
+// writing lines as they are appended until ctx is done, reopening the file
+// when it is rotated or truncated (as the client log is by its size limit).
+func TailFile(ctx context.Context, path string, n int, follow bool, out io.Writer) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { f.Close() }()
+
+	offset, err := lastLinesOffset(f, n)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, f); err != nil {
+		return err
+	}
+	if !follow {
+		return nil
+	}
+
+	ticker := time.NewTicker(logTailPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		if rotated, err := logFileReplaced(f, path); err == nil && rotated {
+			// Drain what was written before the rotation, then continue with the new file
+			io.Copy(out, f)
+			newFile, err := os.Open(path)
+			if err != nil {
+				continue // The new file may not exist yet
+			}
+			f.Close()
+			f = newFile
+		}
+
+		if _, err := io.Copy(out, f); err != nil {
+			return err
+		}
+	}
+}
+
+// logFileReplaced reports whether path now refers to a different file than f,
+// or f was truncated below the current read position
+func logFileReplaced(f *os.File, path string) (bool, error) {
+	current, err := f.Stat()
+	if err != nil {
+		return false, err
+	}
+	latest, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+	if !os.SameFile(current, latest) {
+		return true, nil
+	}
+
+	pos, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return false, err
+	}
+	if latest.Size() < pos {
+		_, err := f.Seek(0, io.SeekStart)
+		return false, err
+	}
+	return false, nil
+}
+
+// lastLinesOffset returns the offset where the last n lines of f begin
+func lastLinesOffset(f *os.File, n int) (int64, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	size := info.Size()
+	if n <= 0 {
+		return size, nil
+	}
+
+	const blockSize = 64 * 1024
+	buf := make([]byte, blockSize)
+	end := size
+	newlines := 0
+
+	// A trailing newline ends the last line rather than starting an empty one
+	if size > 0 {
+		last := make([]byte, 1)
+		if _, err := f.ReadAt(last, size-1); err != nil {
+			return 0, err
+		}
+		if last[0] == '\n' {
+			end = size - 1
+		}
+	}
+
+	for pos := end; pos > 0; {
+		readSize := int64(blockSize)
+		if pos < readSize {
+			readSize = pos
+		}
+		pos -= readSize
+
+		if _, err := f.ReadAt(buf[:readSize], pos); err != nil {
+			return 0, err
+		}
+		for i := readSize - 1; i >= 0; i-- {
+			if buf[i] != '\n' {
+				continue
+			}
+			newlines++
+			if newlines == n {
+				return pos + i + 1, nil
+			}
+		}
+	}
+	return 0, nil
+}
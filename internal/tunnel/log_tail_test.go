@@ -0,0 +1,103 @@
+package tunnel
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncBuffer is a bytes.Buffer safe to read while TailFile writes to it
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestTailFileLastLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "client.log")
+	if err := os.WriteFile(path, []byte("one\ntwo\nthree\nfour\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		n    int
+		want string
+	}{
+		{2, "three\nfour\n"},
+		{4, "one\ntwo\nthree\nfour\n"},
+		{10, "one\ntwo\nthree\nfour\n"},
+		{0, ""},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		if err := TailFile(context.Background(), path, tt.n, false, &out); err != nil {
+			t.Fatalf("TailFile(n=%d) error: %v", tt.n, err)
+		}
+		if out.String() != tt.want {
+			t.Errorf("TailFile(n=%d) = %q, want %q", tt.n, out.String(), tt.want)
+		}
+	}
+}
+
+func TestTailFileFollowRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "client.log")
+	if err := os.WriteFile(path, []byte("old\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	out := &syncBuffer{}
+	done := make(chan error, 1)
+	go func() { done <- TailFile(ctx, path, 10, true, out) }()
+
+	waitFor := func(want string) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for !strings.Contains(out.String(), want) {
+			if time.Now().After(deadline) {
+				t.Fatalf("output %q never contained %q", out.String(), want)
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+
+	waitFor("old\n")
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("appended\n")
+	f.Close()
+	waitFor("appended\n")
+
+	// Rotate: move the file away and start a new one
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("rotated\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	waitFor("rotated\n")
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("TailFile() = %v", err)
+	}
+}
@@ -0,0 +1,16 @@
+package tunnel
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/osa911/giraffecloud/internal/logging"
+)
+
+// initTestLogger points the global logger at a file in the test's temp dir
+func initTestLogger(t *testing.T) {
+	t.Helper()
+	if err := logging.InitLogger(&logging.LogConfig{File: filepath.Join(t.TempDir(), "test.log"), MaxSize: 1}); err != nil {
+		t.Fatalf("Failed to init logger: %v", err)
+	}
+}
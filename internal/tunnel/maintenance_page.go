@@ -0,0 +1,130 @@
+package tunnel
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultMaintenanceRetryAfter is the Retry-After sent with the maintenance page
+const DefaultMaintenanceRetryAfter = 30 * time.Second
+
+// maintenancePageData is passed to maintenance page templates
+type maintenancePageData struct {
+	Domain     string
+	RetryAfter int // Seconds
+}
+
+// cachedMaintenancePage is a parsed per-domain template, reparsed when the file changes
+type cachedMaintenancePage struct {
+	modTime time.Time
+	tmpl    *template.Template
+}
+
+// maintenancePages renders the HTML page served with a 503 while a domain's tunnel is
+// down. A file named <domain>.html in dir overrides the default page for that domain.
+// Pages are html/template templates; {{.Domain}} and {{.RetryAfter}} are available.
+type maintenancePages struct {
+	defaultPage *template.Template // nil = only per-domain pages
+	dir         string
+	retryAfter  time.Duration
+
+	mu    sync.Mutex
+	cache map[string]cachedMaintenancePage // Keyed by file path
+}
+
+// newMaintenancePages loads the default page (a file path, or an inline template if it
+// contains markup). Returns nil if neither a page nor a directory is configured.
+func newMaintenancePages(page, dir string, retryAfter time.Duration) (*maintenancePages, error) {
+	if page == "" && dir == "" {
+		return nil, nil
+	}
+	if retryAfter <= 0 {
+		retryAfter = DefaultMaintenanceRetryAfter
+	}
+
+	p := &maintenancePages{
+		dir:        dir,
+		retryAfter: retryAfter,
+		cache:      make(map[string]cachedMaintenancePage),
+	}
+	if page != "" {
+		source := page
+		if !strings.Contains(page, "<") {
+			data, err := os.ReadFile(page)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read maintenance page: %w", err)
+			}
+			source = string(data)
+		}
+		tmpl, err := template.New("maintenance").Parse(source)
+		if err != nil {
+			return nil, fmt.Errorf("invalid maintenance page template: %w", err)
+		}
+		p.defaultPage = tmpl
+	}
+	return p, nil
+}
+
+// retryAfterSeconds returns the Retry-After value in whole seconds
+func (p *maintenancePages) retryAfterSeconds() int {
+	seconds := int(p.retryAfter.Round(time.Second) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	return seconds
+}
+
+// render returns the maintenance page for domain, or false if none is configured
+func (p *maintenancePages) render(domain string) ([]byte, bool) {
+	if p == nil {
+		return nil, false
+	}
+	tmpl, err := p.domainPage(domain)
+	if err != nil || tmpl == nil {
+		tmpl = p.defaultPage // A broken per-domain page falls back to the default
+	}
+	if tmpl == nil {
+		return nil, false
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, maintenancePageData{Domain: domain, RetryAfter: p.retryAfterSeconds()}); err != nil {
+		return nil, false
+	}
+	return buf.Bytes(), true
+}
+
+// domainPage returns the template from <dir>/<domain>.html, or nil if there is none
+func (p *maintenancePages) domainPage(domain string) (*template.Template, error) {
+	// The domain comes from the Host header, so never let it escape the directory
+	if p.dir == "" || domain == "" || strings.ContainsAny(domain, `/\`) || strings.HasPrefix(domain, ".") {
+		return nil, nil
+	}
+	path := filepath.Join(p.dir, domain+".html")
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if cached, ok := p.cache[path]; ok && cached.modTime.Equal(info.ModTime()) {
+		return cached.tmpl, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err := template.New(domain).Parse(string(data))
+	if err != nil {
+		return nil, err
+	}
+	p.cache[path] = cachedMaintenancePage{modTime: info.ModTime(), tmpl: tmpl}
+	return tmpl, nil
+}
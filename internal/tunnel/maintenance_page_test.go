@@ -0,0 +1,51 @@
+package tunnel
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMaintenancePages(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "shop.example.com.html"), []byte("<p>shop is down</p>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	pages, err := newMaintenancePages(`<h1>{{.Domain}} back in {{.RetryAfter}}s</h1>`, dir, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	page, ok := pages.render("<b>.example.com")
+	if !ok || string(page) != "<h1>&lt;b&gt;.example.com back in 60s</h1>" {
+		t.Errorf("default page = %q, %v", page, ok)
+	}
+	if page, ok := pages.render("shop.example.com"); !ok || string(page) != "<p>shop is down</p>" {
+		t.Errorf("per-domain page = %q, %v", page, ok)
+	}
+	if page, _ := pages.render("../shop.example.com"); !strings.HasPrefix(string(page), "<h1>") {
+		t.Errorf("path traversal served %q", page)
+	}
+}
+
+func TestMaintenancePagesUnconfigured(t *testing.T) {
+	pages, err := newMaintenancePages("", "", 0)
+	if err != nil || pages != nil {
+		t.Fatalf("newMaintenancePages() = %v, %v, want nil", pages, err)
+	}
+	if _, ok := pages.render("example.com"); ok {
+		t.Error("nil pages rendered a page")
+	}
+
+	// Only per-domain pages: other domains keep the built-in behavior
+	pages, err = newMaintenancePages("", t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := pages.render("example.com"); ok {
+		t.Error("rendered a page for a domain without one")
+	}
+}
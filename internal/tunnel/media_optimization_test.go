@@ -0,0 +1,54 @@
+package tunnel
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/osa911/giraffecloud/internal/logging"
+)
+
+func TestDisableMediaOptimization(t *testing.T) {
+	initTestLogger(t)
+	logger := logging.GetGlobalLogger()
+
+	// An API endpoint that happens to live under /media/
+	req := httptest.NewRequest(http.MethodGet, "/api/media/list", nil)
+	raw := []byte("GET /api/media/list HTTP/1.1\r\nHost: example.com\r\n\r\n")
+	video := httptest.NewRequest(http.MethodGet, "/clips/intro.mp4", nil)
+	sized := httptest.NewRequest(http.MethodPut, "/upload", nil)
+	sized.Header.Set("Content-Length", "33554432")
+
+	client := &Tunnel{logger: logger}
+	client.streamConfig.Store(DefaultStreamingConfig())
+	server := &TunnelServer{logger: logger, streamConfig: DefaultStreamingConfig()}
+	grpcServer := &GRPCTunnelServer{logger: logger}
+	router := &HybridTunnelRouter{logger: logger, config: DefaultHybridRouterConfig()}
+
+	if !client.isMediaRequest(req) || !server.isMediaRequest(raw) || !grpcServer.isLargeFileRequest(video) || !router.isLargeFile("/clips/intro.mp4") {
+		t.Fatal("expected the heuristics to apply by default")
+	}
+
+	client.SetDisableMediaOptimization(true)
+	server.disableMediaOptimization = true
+	grpcServer.SetDisableMediaOptimization(true)
+	router.config.DisableMediaOptimization = true
+
+	if client.isMediaRequest(req) {
+		t.Error("client still treats the request as media")
+	}
+	if server.isMediaRequest(raw) {
+		t.Error("server still treats the request as media")
+	}
+	if grpcServer.isLargeFileRequest(video) {
+		t.Error("a .mp4 path still picks the chunked download path")
+	}
+	if router.isLargeFile("/clips/intro.mp4") {
+		t.Error("router still routes a .mp4 path as a large file")
+	}
+
+	// Known sizes still decide, since a message can't carry more than 16MB
+	if !grpcServer.isLargeFileRequest(sized) {
+		t.Error("a 32MB request body should still be chunked")
+	}
+}
@@ -0,0 +1,242 @@
+package tunnel
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/osa911/giraffecloud/internal/logging"
+
+	"gopkg.in/yaml.v3"
+)
+
+// TunnelEntry describes one tunnel in a multi-tunnel config file
+type TunnelEntry struct {
+	Domain    string `yaml:"domain"`
+	LocalPort int    `yaml:"localPort"`
+	LocalHost string `yaml:"localHost,omitempty"` // Defaults to localhost
+	TunnelID  uint32 `yaml:"tunnelId,omitempty"`
+}
+
+// tunnelsFile is the layout of a multi-tunnel config file
+type tunnelsFile struct {
+	Tunnels []TunnelEntry `yaml:"tunnels"`
+}
+
+// LoadTunnelEntries reads a YAML file listing the tunnels to run, e.g.
+//
+//	tunnels:
+//	  - domain: app.example.com
+//	    localPort: 3000
+//	  - domain: api.example.com
+//	    localPort: 8080
+func LoadTunnelEntries(path string) ([]TunnelEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tunnel config: %w", err)
+	}
+
+	var file tunnelsFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse tunnel config %s: %w", path, err)
+	}
+	if len(file.Tunnels) == 0 {
+		return nil, fmt.Errorf("tunnel config %s has no tunnels", path)
+	}
+
+	seen := make(map[string]bool, len(file.Tunnels))
+	for i, entry := range file.Tunnels {
+		if entry.Domain == "" {
+			return nil, fmt.Errorf("tunnel #%d: domain is required", i+1)
+		}
+		if entry.LocalPort < 1 || entry.LocalPort > 65535 {
+			return nil, fmt.Errorf("tunnel %s: localPort %d is out of range (1-65535)", entry.Domain, entry.LocalPort)
+		}
+		if seen[entry.Domain] {
+			return nil, fmt.Errorf("tunnel %s is listed more than once", entry.Domain)
+		}
+		seen[entry.Domain] = true
+	}
+	return file.Tunnels, nil
+}
+
+// MultiTunnel runs several tunnels from one process. Each entry gets its own Tunnel
+// (and gRPC client); the TLS config and singleton lock are shared.
+type MultiTunnel struct {
+	tunnels          []*Tunnel
+	singletonManager *SingletonManager
+	logger           *logging.Logger
+}
+
+// NewMultiTunnel creates one tunnel per entry
+func NewMultiTunnel(entries []TunnelEntry) *MultiTunnel {
+	singletonManager, err := NewSingletonManager()
+	if err != nil {
+		logging.GetGlobalLogger().Warn("Failed to create singleton manager: %v", err)
+	}
+
+	m := &MultiTunnel{
+		singletonManager: singletonManager,
+		logger:           logging.GetGlobalLogger(),
+	}
+	for _, entry := range entries {
+		t := NewTunnel()
+		// The lock is held once for the whole process, not per tunnel
+		t.singletonManager = nil
+		t.domain = entry.Domain
+		t.localPort = entry.LocalPort
+		if entry.LocalHost != "" {
+			t.SetLocalHost(entry.LocalHost)
+		}
+		t.SetTunnelID(entry.TunnelID)
+		m.tunnels = append(m.tunnels, t)
+	}
+	return m
+}
+
+// Tunnels returns the managed tunnels
+func (m *MultiTunnel) Tunnels() []*Tunnel {
+	return m.tunnels
+}
+
+// Connect acquires the singleton lock and connects all tunnels in parallel.
+// If any tunnel fails, the ones that connected are disconnected again.
+func (m *MultiTunnel) Connect(ctx context.Context, serverAddr, token string, tlsConfig *tls.Config) error {
+	if m.singletonManager != nil {
+		if err := m.singletonManager.CleanupStaleLock(); err != nil {
+			m.logger.Warn("Failed to cleanup stale lock: %v", err)
+		}
+		if err := m.singletonManager.CheckServiceConflict(); err != nil {
+			return fmt.Errorf("service conflict detected: %w", err)
+		}
+		if err := m.singletonManager.AcquireLock(); err != nil {
+			return fmt.Errorf("failed to acquire singleton lock: %w", err)
+		}
+	}
+
+	errs := make([]error, len(m.tunnels))
+	var wg sync.WaitGroup
+	for i, t := range m.tunnels {
+		wg.Add(1)
+		go func(i int, t *Tunnel) {
+			defer wg.Done()
+			if err := t.Connect(ctx, serverAddr, token, t.domain, t.localPort, tlsConfig); err != nil {
+				errs[i] = fmt.Errorf("%s: %w", t.domain, err)
+			}
+		}(i, t)
+	}
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		m.Disconnect()
+		return err
+	}
+	return nil
+}
+
+// Disconnect closes all tunnels and releases the singleton lock
+func (m *MultiTunnel) Disconnect() error {
+	var errs []error
+	for _, t := range m.tunnels {
+		if t.cancel == nil {
+			continue // Never connected
+		}
+		if err := t.Disconnect(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", t.domain, err))
+		}
+	}
+
+	if m.singletonManager != nil {
+		if err := m.singletonManager.ReleaseLock(); err != nil {
+			m.logger.Warn("Failed to release singleton lock: %v", err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// IsConnected returns true when every tunnel is connected
+func (m *MultiTunnel) IsConnected() bool {
+	for _, t := range m.tunnels {
+		if !t.IsConnected() {
+			return false
+		}
+	}
+	return len(m.tunnels) > 0
+}
+
+// GetConnectionCount returns the total connection count across tunnels
+func (m *MultiTunnel) GetConnectionCount() int {
+	count := 0
+	for _, t := range m.tunnels {
+		count += t.GetConnectionCount()
+	}
+	return count
+}
+
+// PreserveState preserves the state of every tunnel
+func (m *MultiTunnel) PreserveState() (interface{}, error) {
+	states := make([]interface{}, 0, len(m.tunnels))
+	for _, t := range m.tunnels {
+		state, err := t.PreserveState()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", t.domain, err)
+		}
+		states = append(states, state)
+	}
+	return states, nil
+}
+
+// RestoreState restores state produced by PreserveState
+func (m *MultiTunnel) RestoreState(stateInterface interface{}) error {
+	states, ok := stateInterface.([]interface{})
+	if !ok || len(states) != len(m.tunnels) {
+		return fmt.Errorf("invalid state type for restoration")
+	}
+	for i, t := range m.tunnels {
+		if err := t.RestoreState(states[i]); err != nil {
+			return fmt.Errorf("%s: %w", t.domain, err)
+		}
+	}
+	return nil
+}
+
+// ApplyReloadableConfig applies hot-reloadable settings to every tunnel
+func (m *MultiTunnel) ApplyReloadableConfig(cfg *Config) []string {
+	var applied []string
+	for _, t := range m.tunnels {
+		applied = t.ApplyReloadableConfig(cfg)
+	}
+	return applied
+}
+
+// GetStats returns per-tunnel stats keyed by domain plus aggregated totals
+func (m *MultiTunnel) GetStats() map[string]interface{} {
+	perTunnel := make(map[string]interface{}, len(m.tunnels))
+	connected := 0
+	var bytesIn, bytesOut int64
+
+	for _, t := range m.tunnels {
+		stats := t.GetStats()
+		perTunnel[t.domain] = stats
+		if t.IsConnected() {
+			connected++
+		}
+		if v, ok := stats["bytes_in"].(int64); ok {
+			bytesIn += v
+		}
+		if v, ok := stats["bytes_out"].(int64); ok {
+			bytesOut += v
+		}
+	}
+
+	return map[string]interface{}{
+		"tunnels":      perTunnel,
+		"tunnel_count": len(m.tunnels),
+		"connected":    connected,
+		"bytes_in":     bytesIn,
+		"bytes_out":    bytesOut,
+	}
+}
@@ -0,0 +1,53 @@
+package tunnel
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadTunnelEntries(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "gc-multi")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	write := func(name, content string) string {
+		path := filepath.Join(tempDir, name)
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+		return path
+	}
+
+	path := write("tunnels.yaml", `tunnels:
+  - domain: app.example.com
+    localPort: 3000
+  - domain: api.example.com
+    localPort: 8080
+    localHost: 192.168.1.50
+`)
+	entries, err := LoadTunnelEntries(path)
+	if err != nil {
+		t.Fatalf("LoadTunnelEntries failed: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+	if entries[1].Domain != "api.example.com" || entries[1].LocalPort != 8080 || entries[1].LocalHost != "192.168.1.50" {
+		t.Errorf("Unexpected entry: %+v", entries[1])
+	}
+
+	invalid := map[string]string{
+		"empty.yaml":     "tunnels: []\n",
+		"nodomain.yaml":  "tunnels:\n  - localPort: 3000\n",
+		"badport.yaml":   "tunnels:\n  - domain: a.example.com\n    localPort: 0\n",
+		"duplicate.yaml": "tunnels:\n  - domain: a.example.com\n    localPort: 1\n  - domain: a.example.com\n    localPort: 2\n",
+	}
+	for name, content := range invalid {
+		if _, err := LoadTunnelEntries(write(name, content)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package tunnel
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OriginTimeoutHeader lets the local service give one slow response more time: its value
+// (in seconds) replaces the server-side read timeout for the rest of that response. It is
+// only honored on the first response (headers) and never forwarded to the client.
+const OriginTimeoutHeader = "X-Tunnel-Timeout"
+
+// DefaultMaxOriginTimeout is the largest OriginTimeoutHeader value honored by default
+const DefaultMaxOriginTimeout = time.Hour
+
+// parseOriginTimeout parses an OriginTimeoutHeader value, rejecting values above max
+// (max <= 0 disables the header)
+func parseOriginTimeout(value string, max time.Duration) (time.Duration, error) {
+	if max <= 0 {
+		return 0, fmt.Errorf("origin timeouts are disabled")
+	}
+	seconds, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || seconds <= 0 {
+		return 0, fmt.Errorf("invalid value %q, expected a positive number of seconds", value)
+	}
+	timeout := time.Duration(seconds) * time.Second
+	if timeout > max {
+		return 0, fmt.Errorf("%v exceeds the maximum of %v", timeout, max)
+	}
+	return timeout, nil
+}
+
+// originTimeoutValue returns the OriginTimeoutHeader value from proto response headers
+func originTimeoutValue(headers map[string]string) string {
+	for key, value := range headers {
+		if strings.EqualFold(key, OriginTimeoutHeader) {
+			return value
+		}
+	}
+	return ""
+}
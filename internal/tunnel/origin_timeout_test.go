@@ -0,0 +1,24 @@
+package tunnel
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseOriginTimeout(t *testing.T) {
+	if got, err := parseOriginTimeout(" 300 ", time.Hour); err != nil || got != 5*time.Minute {
+		t.Errorf("parseOriginTimeout(300) = %v, %v; want 5m", got, err)
+	}
+	for _, value := range []string{"", "abc", "0", "-5", "1.5", "7200"} {
+		if _, err := parseOriginTimeout(value, time.Hour); err == nil {
+			t.Errorf("parseOriginTimeout(%q): expected an error", value)
+		}
+	}
+	if _, err := parseOriginTimeout("10", 0); err == nil {
+		t.Error("expected an error when origin timeouts are disabled")
+	}
+
+	if got := originTimeoutValue(map[string]string{"x-tunnel-timeout": "60"}); got != "60" {
+		t.Errorf("originTimeoutValue = %q, want 60", got)
+	}
+}
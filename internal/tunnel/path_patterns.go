@@ -0,0 +1,108 @@
+package tunnel
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Prefixes selecting how a ForceGRPCPaths/ForceTCPPaths rule matches. Rules without one
+// are plain substrings, as they always were.
+const (
+	pathPatternSubstring = "substr:"
+	pathPatternGlob      = "glob:"
+	pathPatternRegex     = "regex:"
+)
+
+// pathPattern is one precompiled routing rule.
+//
+// Substring rules look anywhere in the request target, query string included
+// (e.g. "transport=websocket"). Glob and regex rules must match the whole path, without
+// the query string: "glob:/api/**" matches /api/users but not /apiary/. In globs `*`
+// stays within one path segment, `**` spans segments and `?` is one character.
+type pathPattern struct {
+	rule      string
+	substring string
+	re        *regexp.Regexp
+}
+
+// compilePathPatterns compiles routing rules once at startup. Invalid rules are left
+// out and reported in the error; the valid ones are still returned.
+func compilePathPatterns(rules []string) ([]pathPattern, error) {
+	patterns := make([]pathPattern, 0, len(rules))
+	var errs []error
+	for _, rule := range rules {
+		pattern, err := compilePathPattern(rule)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, errors.Join(errs...)
+}
+
+func compilePathPattern(rule string) (pathPattern, error) {
+	switch {
+	case strings.HasPrefix(rule, pathPatternGlob):
+		glob := strings.TrimPrefix(rule, pathPatternGlob)
+		if glob == "" {
+			return pathPattern{}, fmt.Errorf("empty glob in routing rule %q", rule)
+		}
+		return pathPattern{rule: rule, re: regexp.MustCompile(globToRegexp(glob))}, nil
+	case strings.HasPrefix(rule, pathPatternRegex):
+		re, err := regexp.Compile(`^(?:` + strings.TrimPrefix(rule, pathPatternRegex) + `)$`)
+		if err != nil {
+			return pathPattern{}, fmt.Errorf("invalid regex in routing rule %q: %w", rule, err)
+		}
+		return pathPattern{rule: rule, re: re}, nil
+	}
+	substring := strings.TrimPrefix(rule, pathPatternSubstring)
+	if substring == "" {
+		return pathPattern{}, fmt.Errorf("empty routing rule %q", rule)
+	}
+	return pathPattern{rule: rule, substring: substring}, nil
+}
+
+// globToRegexp translates a path glob into an anchored regular expression
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				b.WriteString(".*")
+				i++
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
+
+// match reports whether target, a request path with an optional query string, matches
+func (p pathPattern) match(target string) bool {
+	if p.re == nil {
+		return strings.Contains(target, p.substring)
+	}
+	path, _, _ := strings.Cut(target, "?")
+	return p.re.MatchString(path)
+}
+
+// matchPathPatterns returns the first rule matching target
+func matchPathPatterns(patterns []pathPattern, target string) (string, bool) {
+	for _, p := range patterns {
+		if p.match(target) {
+			return p.rule, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,83 @@
+package tunnel
+
+import (
+	"testing"
+
+	"github.com/osa911/giraffecloud/internal/logging"
+)
+
+func TestPathPatternMatch(t *testing.T) {
+	tests := []struct {
+		rule   string
+		target string
+		want   bool
+	}{
+		// Plain substrings keep their old behaviour, query string included
+		{"/api", "/apiary/hive", true},
+		{"transport=websocket", "/socket.io/?EIO=4&transport=websocket", true},
+		{"substr:/ws/", "/app/ws/chat", true},
+		{"substr:/ws/", "/wsx/chat", false},
+
+		// Globs are anchored to the whole path
+		{"glob:/api/**", "/api/users/1", true},
+		{"glob:/api/**", "/apiary/hive", false},
+		{"glob:/api/**", "/v1/api/users", false},
+		{"glob:/api/*", "/api/users", true},
+		{"glob:/api/*", "/api/users/1", false},
+		{"glob:/api/*", "/api/users?page=2", true},
+		{"glob:/files/*.mp?", "/files/clip.mp4", true},
+		{"glob:/files/*.mp?", "/files/clip.mp4.txt", false},
+		{"glob:/a.b", "/aXb", false},
+		{"glob:/search", "/search?q=/api/", true},
+
+		// Regexes are anchored to the whole path too, without the query string
+		{"regex:/v[0-9]+/.*", "/v2/users", true},
+		{"regex:/v[0-9]+/.*", "/static/v2/users", false},
+		{"regex:/(ws|socket)/.*", "/socket/x", true},
+		{"regex:/ws", "/ws?transport=websocket", true},
+		{"regex:/ws", "/ws/", false},
+	}
+	for _, tt := range tests {
+		p, err := compilePathPattern(tt.rule)
+		if err != nil {
+			t.Fatalf("compilePathPattern(%q): %v", tt.rule, err)
+		}
+		if got := p.match(tt.target); got != tt.want {
+			t.Errorf("%q matching %q = %v, want %v", tt.rule, tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestCompilePathPatternsSkipsInvalidRules(t *testing.T) {
+	patterns, err := compilePathPatterns([]string{"/ws/", "regex:/(unclosed", "glob:", "", "glob:/api/**"})
+	if err == nil {
+		t.Error("expected an error for the invalid rules")
+	}
+	if len(patterns) != 2 || patterns[0].rule != "/ws/" || patterns[1].rule != "glob:/api/**" {
+		t.Errorf("compiled %+v, want the two valid rules", patterns)
+	}
+}
+
+func TestHybridTunnelRouter_AnalyzeRequestForcedPaths(t *testing.T) {
+	initTestLogger(t)
+	r := &HybridTunnelRouter{logger: logging.GetGlobalLogger(), config: &HybridRouterConfig{}}
+	r.forceTCPPaths, _ = compilePathPatterns([]string{"glob:/live/**", "transport=websocket"})
+	r.forceGRPCPaths, _ = compilePathPatterns([]string{"glob:/api/**"})
+
+	tests := []struct {
+		path   string
+		useTCP bool
+	}{
+		{"/live/feed", true},
+		{"/lively/page", false},
+		{"/socket.io/?transport=websocket", true},
+		{"/api/live/feed", false},
+		{"/api/users?redirect=/live/feed", false},
+	}
+	for _, tt := range tests {
+		useTCP, method, path := r.analyzeRequest([]byte("GET " + tt.path + " HTTP/1.1\r\nHost: example.com\r\n\r\n"))
+		if useTCP != tt.useTCP || method != "GET" || path != tt.path {
+			t.Errorf("analyzeRequest(%s) = %v %s %s, want TCP=%v", tt.path, useTCP, method, path, tt.useTCP)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package tunnel
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// pathRewrite maps public request paths to the local service's: strip (PublicPathStrip) is
+// removed from the front of paths under it, then prefix (LocalPathPrefix) is prepended.
+// The zero value leaves paths alone.
+type pathRewrite struct {
+	strip  string
+	prefix string
+}
+
+// newPathRewrite normalizes the prefixes to "/a/b" form ("" or "/" = none)
+func newPathRewrite(strip, prefix string) pathRewrite {
+	return pathRewrite{strip: normalizePathPrefix(strip), prefix: normalizePathPrefix(prefix)}
+}
+
+func normalizePathPrefix(prefix string) string {
+	prefix = strings.TrimRight(strings.TrimSpace(prefix), "/")
+	if prefix != "" && !strings.HasPrefix(prefix, "/") {
+		prefix = "/" + prefix
+	}
+	return prefix
+}
+
+// ValidatePathPrefix checks a LocalPathPrefix or PublicPathStrip value ("" = none)
+func ValidatePathPrefix(prefix string) error {
+	if prefix == "" {
+		return nil
+	}
+	if !strings.HasPrefix(prefix, "/") {
+		return fmt.Errorf("%q must start with /", prefix)
+	}
+	if strings.ContainsAny(prefix, "?# ") {
+		return fmt.Errorf("%q must be a plain path, without query, fragment or spaces", prefix)
+	}
+	return nil
+}
+
+// apply rewrites a request target (path with optional ?query); the query is kept as is.
+// With strip "/api", "/api/users?x=1" becomes "/users?x=1" and "/api" becomes "/"; other
+// paths aren't stripped. A trailing slash is kept, so "/" with prefix "/app" is "/app/".
+func (p pathRewrite) apply(target string) string {
+	if p.strip == "" && p.prefix == "" {
+		return target
+	}
+	path, query, hasQuery := strings.Cut(target, "?")
+	if !strings.HasPrefix(path, "/") {
+		return target // "*" or absolute-form targets
+	}
+	if p.strip != "" && (path == p.strip || strings.HasPrefix(path, p.strip+"/")) {
+		path = path[len(p.strip):]
+		if path == "" {
+			path = "/"
+		}
+	}
+	path = p.prefix + path
+	if hasQuery {
+		return path + "?" + query
+	}
+	return path
+}
+
+// applyToRequest rewrites the target of a request read off a TCP tunnel connection
+func (p pathRewrite) applyToRequest(req *http.Request) {
+	target := req.URL.RequestURI()
+	rewritten := p.apply(target)
+	if rewritten == target {
+		return
+	}
+	if u, err := url.ParseRequestURI(rewritten); err == nil {
+		req.URL.Path, req.URL.RawPath, req.URL.RawQuery = u.Path, u.RawPath, u.RawQuery
+		req.RequestURI = rewritten
+	}
+}
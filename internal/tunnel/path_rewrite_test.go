@@ -0,0 +1,49 @@
+package tunnel
+
+import (
+	"bufio"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestPathRewrite(t *testing.T) {
+	tests := []struct {
+		strip, prefix, target, want string
+	}{
+		{"", "", "/foo?x=1", "/foo?x=1"},
+		{"", "/app", "/foo", "/app/foo"},
+		{"", "app/", "/foo?x=1&y=/z", "/app/foo?x=1&y=/z"},
+		{"", "/app", "/", "/app/"},
+		{"", "/app", "/foo/", "/app/foo/"},
+		{"", "/app", "/?q", "/app/?q"},
+		{"/api", "", "/api/users?id=2", "/users?id=2"},
+		{"/api/", "", "/api", "/"},
+		{"/api", "", "/api?x", "/?x"},
+		{"/api", "", "/apis/x", "/apis/x"},
+		{"/api", "", "/other", "/other"},
+		{"/api", "/v1", "/api/users", "/v1/users"},
+		{"/", "/", "/foo", "/foo"},
+		{"", "/app", "*", "*"},
+	}
+	for _, tt := range tests {
+		if got := newPathRewrite(tt.strip, tt.prefix).apply(tt.target); got != tt.want {
+			t.Errorf("strip %q, prefix %q: %q -> %q, want %q", tt.strip, tt.prefix, tt.target, got, tt.want)
+		}
+	}
+
+	req, err := http.ReadRequest(bufio.NewReader(strings.NewReader("GET /api/a%20b?x=1 HTTP/1.1\r\nHost: example.com\r\n\r\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	newPathRewrite("/api", "/app").applyToRequest(req)
+	if got := req.URL.RequestURI(); got != "/app/a%20b?x=1" {
+		t.Errorf("rewritten request URI = %q", got)
+	}
+
+	for _, bad := range []string{"app", "/app?x", "/a b"} {
+		if ValidatePathPrefix(bad) == nil {
+			t.Errorf("ValidatePathPrefix(%q) accepted", bad)
+		}
+	}
+}
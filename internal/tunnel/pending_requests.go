@@ -0,0 +1,105 @@
+package tunnel
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/osa911/giraffecloud/internal/tunnel/proto"
+)
+
+// DefaultPendingRequestMaxAge is how long a request may stay in a stream's pendingRequests
+// before the reaper drops it. Every request path removes its own entry well before that;
+// anything older is a leak (a handler that returned without cleaning up).
+const DefaultPendingRequestMaxAge = 2 * time.Hour
+
+const pendingReapInterval = time.Minute
+
+// pendingRequestMaxAge never goes below the longest a response may legitimately take,
+// so raising the response timeouts can't get live requests reaped
+func (s *GRPCTunnelServer) pendingRequestMaxAge() time.Duration {
+	maxAge := s.config.PendingRequestMaxAge
+	if maxAge <= 0 {
+		maxAge = DefaultPendingRequestMaxAge
+	}
+	return max(maxAge, s.config.ChunkCollectionTimeout+pendingReapInterval, s.maxOriginTimeout+pendingReapInterval)
+}
+
+// reapPendingRequests periodically drops pendingRequests older than pendingRequestMaxAge
+func (s *GRPCTunnelServer) reapPendingRequests() {
+	ticker := time.NewTicker(pendingReapInterval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		s.reapPendingRequestsOnce(now, s.pendingRequestMaxAge())
+	}
+}
+
+// reapPendingRequestsOnce closes and removes every pending request first seen more than
+// maxAge before now. Entries are timestamped the first time a sweep sees them, so the
+// age is accurate to one sweep without touching the places that register requests.
+// Bridged WebSockets and streamed GETs waiting to resume have their own lifetimes.
+func (s *GRPCTunnelServer) reapPendingRequestsOnce(now time.Time, maxAge time.Duration) int {
+	s.tunnelStreamsMux.RLock()
+	streams := make([]*TunnelStream, 0, len(s.tunnelStreams))
+	for _, tunnelStream := range s.tunnelStreams {
+		streams = append(streams, tunnelStream)
+	}
+	s.tunnelStreamsMux.RUnlock()
+
+	reaped := 0
+	for _, tunnelStream := range streams {
+		tunnelStream.websocketsMux.Lock()
+		websockets := make(map[string]bool, len(tunnelStream.websockets))
+		for requestID := range tunnelStream.websockets {
+			websockets[requestID] = true
+		}
+		tunnelStream.websocketsMux.Unlock()
+
+		tunnelStream.requestsMux.Lock()
+		if tunnelStream.pendingSeen == nil {
+			tunnelStream.pendingSeen = make(map[string]time.Time)
+		}
+		for requestID := range tunnelStream.pendingSeen {
+			if _, exists := tunnelStream.pendingRequests[requestID]; !exists {
+				delete(tunnelStream.pendingSeen, requestID)
+			}
+		}
+		for requestID, responseChan := range tunnelStream.pendingRequests {
+			seen, ok := tunnelStream.pendingSeen[requestID]
+			if !ok {
+				tunnelStream.pendingSeen[requestID] = now
+				continue
+			}
+			if now.Sub(seen) <= maxAge || websockets[requestID] || s.isResumable(requestID, responseChan) {
+				continue
+			}
+
+			s.logger.Warn("[PENDING] Reaping request %s on %s, pending for %v", requestID, tunnelStream.Domain, now.Sub(seen).Round(time.Second))
+			func(ch chan *proto.TunnelMessage) {
+				defer func() { recover() }() // Already closed by its handler
+				close(ch)
+			}(responseChan)
+			delete(tunnelStream.pendingRequests, requestID)
+			delete(tunnelStream.pendingSeen, requestID)
+			reaped++
+		}
+		tunnelStream.requestsMux.Unlock()
+	}
+
+	atomic.AddInt64(&s.pendingReaped, int64(reaped))
+	return reaped
+}
+
+// pendingRequestCount counts the pending requests of every connected stream
+func (s *GRPCTunnelServer) pendingRequestCount() int {
+	s.tunnelStreamsMux.RLock()
+	defer s.tunnelStreamsMux.RUnlock()
+
+	count := 0
+	for _, tunnelStream := range s.tunnelStreams {
+		tunnelStream.requestsMux.RLock()
+		count += len(tunnelStream.pendingRequests)
+		tunnelStream.requestsMux.RUnlock()
+	}
+	return count
+}
@@ -0,0 +1,55 @@
+package tunnel
+
+import (
+	"testing"
+	"time"
+
+	"github.com/osa911/giraffecloud/internal/logging"
+	"github.com/osa911/giraffecloud/internal/tunnel/proto"
+)
+
+func TestReapPendingRequests(t *testing.T) {
+	initTestLogger(t)
+	s := &GRPCTunnelServer{
+		logger:        logging.GetGlobalLogger(),
+		config:        DefaultGRPCTunnelConfig(),
+		tunnelStreams: make(map[string]*TunnelStream),
+		resumable:     make(map[string]*resumableResponse),
+	}
+	leaked := make(chan *proto.TunnelMessage, 1)
+	websocket := make(chan *proto.TunnelMessage, 1)
+	ts := &TunnelStream{
+		Domain:          "app.example.com",
+		pendingRequests: map[string]chan *proto.TunnelMessage{"leaked": leaked, "ws": websocket},
+		websockets:      map[string]*wsBridgeConn{"ws": nil},
+	}
+	s.tunnelStreams[ts.Domain] = ts
+
+	start := time.Now()
+	if n := s.reapPendingRequestsOnce(start, time.Hour); n != 0 {
+		t.Fatalf("first sweep reaped %d requests", n)
+	}
+	fresh := make(chan *proto.TunnelMessage, 1)
+	ts.pendingRequests["fresh"] = fresh
+
+	if n := s.reapPendingRequestsOnce(start.Add(2*time.Hour), time.Hour); n != 1 {
+		t.Fatalf("reaped %d requests, want 1", n)
+	}
+	if _, ok := <-leaked; ok {
+		t.Error("leaked request channel not closed")
+	}
+	if _, ok := ts.pendingRequests["leaked"]; ok {
+		t.Error("leaked request still pending")
+	}
+	if _, ok := ts.pendingRequests["ws"]; !ok {
+		t.Error("bridged WebSocket reaped")
+	}
+	if _, ok := ts.pendingRequests["fresh"]; !ok {
+		t.Error("request first seen in the last sweep reaped")
+	}
+
+	metrics := s.GetMetrics()
+	if metrics["pending_requests"] != 2 || metrics["pending_reaped"] != 1 {
+		t.Errorf("pending_requests = %d, pending_reaped = %d", metrics["pending_requests"], metrics["pending_reaped"])
+	}
+}
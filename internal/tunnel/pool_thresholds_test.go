@@ -0,0 +1,113 @@
+package tunnel
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/osa911/giraffecloud/internal/logging"
+)
+
+func TestApplyPoolDefaults(t *testing.T) {
+	defaults := DefaultStreamingConfig()
+	tests := []struct {
+		name  string
+		value int
+		age   time.Duration
+		want  int
+		wantA time.Duration
+	}{
+		{"zero falls back to defaults", 0, 0, defaults.HotPoolMaxRequests, defaults.HotPoolMaxAge},
+		{"negative falls back to defaults", -5, -time.Minute, defaults.HotPoolMaxRequests, defaults.HotPoolMaxAge},
+		{"positive values are kept", 7, 3 * time.Second, 7, 3 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &StreamingConfig{
+				MaxRequestsPerConnection: tt.value,
+				ConnectionMaxAge:         tt.age,
+				HotPoolMaxRequests:       tt.value,
+				HotPoolMaxAge:            tt.age,
+				RecycleMaxRequests:       tt.value,
+				RecycleMaxAge:            tt.age,
+				CleanupInterval:          tt.age,
+				CircuitBreakerThreshold:  tt.value,
+				CircuitBreakerCooldown:   tt.age,
+			}
+			c.applyPoolDefaults()
+			if tt.value > 0 {
+				if c.MaxRequestsPerConnection != tt.value || c.RecycleMaxRequests != tt.value || c.CircuitBreakerThreshold != tt.value ||
+					c.ConnectionMaxAge != tt.age || c.RecycleMaxAge != tt.age || c.CleanupInterval != tt.age || c.CircuitBreakerCooldown != tt.age {
+					t.Errorf("configured thresholds overwritten: %+v", c)
+				}
+			} else if c.MaxRequestsPerConnection != defaults.MaxRequestsPerConnection || c.ConnectionMaxAge != defaults.ConnectionMaxAge ||
+				c.RecycleMaxRequests != defaults.RecycleMaxRequests || c.RecycleMaxAge != defaults.RecycleMaxAge ||
+				c.CleanupInterval != defaults.CleanupInterval || c.CircuitBreakerThreshold != defaults.CircuitBreakerThreshold ||
+				c.CircuitBreakerCooldown != defaults.CircuitBreakerCooldown {
+				t.Errorf("thresholds not backfilled: %+v", c)
+			}
+			if c.HotPoolMaxRequests != tt.want || c.HotPoolMaxAge != tt.wantA {
+				t.Errorf("hot pool thresholds = %d/%v, want %d/%v", c.HotPoolMaxRequests, c.HotPoolMaxAge, tt.want, tt.wantA)
+			}
+		})
+	}
+}
+
+func TestConnectionRetirementThresholds(t *testing.T) {
+	initTestLogger(t)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	s := &TunnelServer{logger: logging.GetGlobalLogger(), connections: NewConnectionManager(), breakers: newCircuitBreakers(0, 0)}
+	s.UpdateStreamingConfig(&StreamingConfig{
+		MaxRequestsPerConnection: 10,
+		ConnectionMaxAge:         time.Minute,
+		HotPoolMaxRequests:       5,
+		HotPoolMaxAge:            30 * time.Second,
+	})
+
+	tests := []struct {
+		name      string
+		requests  int64
+		age       time.Duration
+		keepInHot bool
+		reuse     bool
+	}{
+		{"fresh", 1, time.Second, true, true},
+		{"over hot pool requests", 6, time.Second, false, true},
+		{"over hot pool age", 1, 45 * time.Second, false, true},
+		{"over max requests", 11, time.Second, false, false},
+		{"over max age", 1, 2 * time.Minute, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, err := net.Dial("tcp", listener.Addr().String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+			tunnelConn := NewTunnelConnection("app.example.com", conn, 3000)
+			tunnelConn.requestCount = tt.requests
+			tunnelConn.createdAt = time.Now().Add(-tt.age)
+
+			if got := s.shouldKeepInHotPool("app.example.com", tunnelConn, nil); got != tt.keepInHot {
+				t.Errorf("shouldKeepInHotPool = %v, want %v", got, tt.keepInHot)
+			}
+			if got := s.isConnectionCleanForReuse(tunnelConn, nil); got != tt.reuse {
+				t.Errorf("isConnectionCleanForReuse = %v, want %v", got, tt.reuse)
+			}
+		})
+	}
+}
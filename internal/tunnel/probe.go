@@ -0,0 +1,60 @@
+package tunnel
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// ProbeUserAgent identifies requests sent by 'giraffecloud test-connection' in access logs
+const ProbeUserAgent = "giraffecloud-test-connection"
+
+// ProbeResult describes one request sent through a tunnel's public URL
+type ProbeResult struct {
+	URL         string
+	StatusCode  int
+	Latency     time.Duration // Until the response headers arrived
+	Total       time.Duration // Until the whole body was read
+	BodyBytes   int64
+	Transport   string // TransportHeader of the response ("" = not reported)
+	RouterError bool   // The router answered itself: the request never reached the local service
+	CacheStatus string // CacheStatusHeader of the response, if any
+}
+
+// Reached reports whether the response came from the local service through the tunnel
+func (r *ProbeResult) Reached() bool {
+	return r.Transport != "" && !r.RouterError
+}
+
+// ProbeTunnel sends a GET to rawURL, bypassing the response cache, and reports how it came back
+func ProbeTunnel(ctx context.Context, client *http.Client, rawURL string) (*ProbeResult, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+	req.Header.Set("User-Agent", ProbeUserAgent)
+	req.Header.Set("Cache-Control", "no-cache")
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	result := &ProbeResult{
+		URL:         rawURL,
+		StatusCode:  resp.StatusCode,
+		Latency:     time.Since(start),
+		Transport:   resp.Header.Get(TransportHeader),
+		RouterError: resp.Header.Get("X-Tunnel-Router") != "",
+		CacheStatus: resp.Header.Get(CacheStatusHeader),
+	}
+	result.BodyBytes, err = io.Copy(io.Discard, resp.Body)
+	result.Total = time.Since(start)
+	if err != nil {
+		return result, fmt.Errorf("reading response body: %w", err)
+	}
+	return result, nil
+}
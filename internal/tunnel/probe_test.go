@@ -0,0 +1,40 @@
+package tunnel
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProbeTunnel(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("User-Agent") != ProbeUserAgent || r.Header.Get("Cache-Control") != "no-cache" {
+			t.Errorf("probe headers: %v", r.Header)
+		}
+		switch r.URL.Path {
+		case "/grpc":
+			w.Header().Set(TransportHeader, TransportGRPC)
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("not here"))
+		case "/offline":
+			w.Header().Set("X-Tunnel-Router", "hybrid")
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer srv.Close()
+
+	result, err := ProbeTunnel(context.Background(), srv.Client(), srv.URL+"/grpc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Reached() || result.StatusCode != http.StatusNotFound || result.Transport != TransportGRPC || result.BodyBytes != 8 {
+		t.Errorf("origin response: %+v", result)
+	}
+
+	for _, path := range []string{"/offline", "/plain"} {
+		if result, err := ProbeTunnel(context.Background(), srv.Client(), srv.URL+path); err != nil || result.Reached() {
+			t.Errorf("%s: reached = %v, err = %v", path, result != nil && result.Reached(), err)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package tunnel
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// RunningTunnelPID returns the PID the running tunnel recorded in its PID file, or
+// ErrNoRunningInstance when no process holds the singleton lock
+func RunningTunnelPID() (int, error) {
+	sm, err := NewSingletonManager()
+	if err != nil {
+		return 0, err
+	}
+	pid := sm.GetRunningPID()
+	if pid == 0 {
+		return 0, ErrNoRunningInstance
+	}
+	return pid, nil
+}
+
+// StopRunningTunnel asks the running tunnel to shut down gracefully (SIGTERM) and waits
+// up to timeout for it to release the singleton lock. It returns the PID it signalled.
+func StopRunningTunnel(timeout time.Duration) (int, error) {
+	sm, err := NewSingletonManager()
+	if err != nil {
+		return 0, err
+	}
+	pid := sm.GetRunningPID()
+	if pid == 0 {
+		return 0, ErrNoRunningInstance
+	}
+
+	if err := terminateProcess(pid); err != nil {
+		return pid, fmt.Errorf("failed to signal tunnel (PID %d): %w", pid, err)
+	}
+	if err := sm.WaitForLock(timeout); err != nil {
+		return pid, fmt.Errorf("tunnel (PID %d) did not exit within %v", pid, timeout)
+	}
+	return pid, nil
+}
+
+// StartDetached runs the current executable with args in the background, detached from
+// the terminal. Its output is discarded: the tunnel logs to client.log in the config dir.
+func StartDetached(args []string) (*exec.Cmd, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate executable: %w", err)
+	}
+	devNull, err := os.OpenFile(os.DevNull, os.O_RDWR, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", os.DevNull, err)
+	}
+	defer devNull.Close()
+
+	cmd := exec.Command(executable, args...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = devNull, devNull, devNull
+	cmd.SysProcAttr = detachedProcAttr()
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start background tunnel: %w", err)
+	}
+	return cmd, nil
+}
@@ -0,0 +1,82 @@
+//go:build !windows
+
+package tunnel
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// TestHelperHoldLock is run as a child process by TestStopRunningTunnel: it holds the
+// singleton lock like a connected tunnel until it gets SIGTERM
+func TestHelperHoldLock(t *testing.T) {
+	if os.Getenv("GIRAFFECLOUD_TEST_HOLD_LOCK") != "1" {
+		t.Skip("helper process")
+	}
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGTERM)
+
+	sm, err := NewSingletonManager()
+	if err != nil {
+		os.Exit(2)
+	}
+	if err := sm.AcquireLock(); err != nil {
+		os.Exit(3)
+	}
+	os.Stdout.WriteString("ready\n")
+	<-sigChan
+	sm.ReleaseLock()
+	os.Exit(0)
+}
+
+func TestStopRunningTunnel(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	if _, err := RunningTunnelPID(); !errors.Is(err, ErrNoRunningInstance) {
+		t.Fatalf("RunningTunnelPID with no tunnel: got %v, want %v", err, ErrNoRunningInstance)
+	}
+	if _, err := StopRunningTunnel(time.Second); !errors.Is(err, ErrNoRunningInstance) {
+		t.Fatalf("StopRunningTunnel with no tunnel: got %v, want %v", err, ErrNoRunningInstance)
+	}
+
+	child := exec.Command(os.Args[0], "-test.run=^TestHelperHoldLock$")
+	child.Env = append(os.Environ(), "GIRAFFECLOUD_TEST_HOLD_LOCK=1")
+	stdout, err := child.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := child.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer child.Process.Kill()
+	if line, err := bufio.NewReader(stdout).ReadString('\n'); err != nil || line != "ready\n" {
+		t.Fatalf("helper did not take the lock: %q, %v", line, err)
+	}
+
+	pid, err := RunningTunnelPID()
+	if err != nil || pid != child.Process.Pid {
+		t.Fatalf("RunningTunnelPID = %d, %v; want %d", pid, err, child.Process.Pid)
+	}
+
+	stopped, err := StopRunningTunnel(5 * time.Second)
+	if err != nil {
+		t.Fatalf("StopRunningTunnel: %v", err)
+	}
+	if stopped != child.Process.Pid {
+		t.Errorf("stopped PID %d, want %d", stopped, child.Process.Pid)
+	}
+	if err := child.Wait(); err != nil {
+		t.Errorf("tunnel did not shut down cleanly: %v", err)
+	}
+	if manager, err := NewSingletonManager(); err == nil {
+		if _, err := os.Stat(manager.PidFile); !os.IsNotExist(err) {
+			t.Error("PID file was not removed on shutdown")
+		}
+	}
+}
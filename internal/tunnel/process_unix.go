@@ -0,0 +1,15 @@
+//go:build !windows
+
+package tunnel
+
+import "syscall"
+
+// terminateProcess asks pid to shut down gracefully
+func terminateProcess(pid int) error {
+	return syscall.Kill(pid, syscall.SIGTERM)
+}
+
+// detachedProcAttr starts a process in its own session, so it outlives the terminal
+func detachedProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Setsid: true}
+}
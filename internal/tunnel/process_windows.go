@@ -0,0 +1,21 @@
+//go:build windows
+
+package tunnel
+
+import (
+	"errors"
+	"syscall"
+
+	"golang.org/x/sys/windows"
+)
+
+// terminateProcess would ask pid to shut down gracefully, but Windows has no SIGTERM
+// and console control events can't be sent to a detached process
+func terminateProcess(pid int) error {
+	return errors.New("not supported on Windows - press Ctrl+C in the tunnel's console or run 'giraffecloud service stop'")
+}
+
+// detachedProcAttr starts a process without a console, so it outlives the terminal
+func detachedProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{CreationFlags: windows.CREATE_NEW_PROCESS_GROUP | windows.DETACHED_PROCESS}
+}
@@ -0,0 +1,187 @@
+package tunnel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/osa911/giraffecloud/internal/logging"
+)
+
+// MetricsCollector writes its current metric values into w on every scrape
+type MetricsCollector func(w *MetricsWriter)
+
+// MetricsWriter renders metrics in the Prometheus text exposition format. Samples are
+// grouped by family, so collectors may write a family's samples in any order.
+type MetricsWriter struct {
+	families []*metricFamily
+	byName   map[string]*metricFamily
+}
+
+// metricFamily holds the HELP/TYPE and rendered samples of one metric family
+type metricFamily struct {
+	name, help, kind string
+	samples          strings.Builder
+}
+
+// labelValueEscaper escapes label values as the text format requires; everything else,
+// unicode included, is written as-is
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// Counter writes a monotonically increasing value
+func (w *MetricsWriter) Counter(name, help string, value float64, labels map[string]string) {
+	w.family(name, help, "counter").sample(name, value, labels)
+}
+
+// Gauge writes a value that can go up and down
+func (w *MetricsWriter) Gauge(name, help string, value float64, labels map[string]string) {
+	w.family(name, help, "gauge").sample(name, value, labels)
+}
+
+// Summary writes precomputed quantiles (keyed by their "quantile" label value) and the
+// observation count of one summary series
+func (w *MetricsWriter) Summary(name, help string, quantiles map[string]float64, count int64, labels map[string]string) {
+	keys := make([]string, 0, len(quantiles))
+	for q := range quantiles {
+		keys = append(keys, q)
+	}
+	sort.Strings(keys)
+
+	f := w.family(name, help, "summary")
+	for _, q := range keys {
+		quantileLabels := map[string]string{"quantile": q}
+		for k, v := range labels {
+			quantileLabels[k] = v
+		}
+		f.sample(name, quantiles[q], quantileLabels)
+	}
+	f.sample(name+"_count", float64(count), labels)
+}
+
+// family returns the family called name, declaring it on first use
+func (w *MetricsWriter) family(name, help, kind string) *metricFamily {
+	if f, ok := w.byName[name]; ok {
+		return f
+	}
+	if w.byName == nil {
+		w.byName = make(map[string]*metricFamily)
+	}
+	f := &metricFamily{name: name, help: help, kind: kind}
+	w.byName[name] = f
+	w.families = append(w.families, f)
+	return f
+}
+
+// sample renders one sample line into the family
+func (f *metricFamily) sample(name string, value float64, labels map[string]string) {
+	f.samples.WriteString(name)
+	if len(labels) > 0 {
+		keys := make([]string, 0, len(labels))
+		for k := range labels {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		f.samples.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				f.samples.WriteByte(',')
+			}
+			fmt.Fprintf(&f.samples, "%s=\"%s\"", k, labelValueEscaper.Replace(labels[k]))
+		}
+		f.samples.WriteByte('}')
+	}
+	f.samples.WriteByte(' ')
+	f.samples.WriteString(strconv.FormatFloat(value, 'g', -1, 64))
+	f.samples.WriteByte('\n')
+}
+
+// String returns the rendered metrics, each family's HELP/TYPE followed by all its samples
+func (w *MetricsWriter) String() string {
+	var sb strings.Builder
+	for _, f := range w.families {
+		fmt.Fprintf(&sb, "# HELP %s %s\n# TYPE %s %s\n", f.name, f.help, f.name, f.kind)
+		sb.WriteString(f.samples.String())
+	}
+	return sb.String()
+}
+
+// MetricsRegistry is a minimal Prometheus-compatible registry backed by collectors
+// that read the existing atomic counters at scrape time
+type MetricsRegistry struct {
+	mu         sync.RWMutex
+	collectors []MetricsCollector
+}
+
+// NewMetricsRegistry creates an empty metrics registry
+func NewMetricsRegistry() *MetricsRegistry {
+	return &MetricsRegistry{}
+}
+
+// Register adds a collector to the registry
+func (r *MetricsRegistry) Register(collector MetricsCollector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collectors = append(r.collectors, collector)
+}
+
+// Render collects all registered metrics into the text exposition format
+func (r *MetricsRegistry) Render() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	w := &MetricsWriter{}
+	for _, collect := range r.collectors {
+		collect(w)
+	}
+	return w.String()
+}
+
+// ServeHTTP implements http.Handler for the /metrics endpoint
+func (r *MetricsRegistry) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	rw.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	rw.Write([]byte(r.Render()))
+}
+
+// MetricsServer exposes a registry on /metrics
+type MetricsServer struct {
+	server *http.Server
+}
+
+// StartMetricsServer starts serving the registry on addr in the background
+func StartMetricsServer(addr string, registry *MetricsRegistry) (*MetricsServer, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on metrics address %s: %w", addr, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", registry)
+
+	s := &MetricsServer{
+		server: &http.Server{
+			Handler:           mux,
+			ReadHeaderTimeout: 10 * time.Second,
+		},
+	}
+	go func() {
+		if err := s.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logging.GetGlobalLogger().Error("Metrics server stopped: %v", err)
+		}
+	}()
+	return s, nil
+}
+
+// Stop shuts the metrics server down
+func (s *MetricsServer) Stop() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return s.server.Shutdown(ctx)
+}
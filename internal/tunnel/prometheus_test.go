@@ -0,0 +1,71 @@
+package tunnel
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetricsRegistry_Render(t *testing.T) {
+	registry := NewMetricsRegistry()
+	registry.Register(func(w *MetricsWriter) {
+		w.Counter("test_requests_total", "Total requests", 42, nil)
+		w.Gauge("test_domain_connections", "Connections per domain", 2, map[string]string{"type": "http", "domain": "a.example.com"})
+		w.Gauge("test_domain_connections", "Connections per domain", 1, map[string]string{"type": "grpc", "domain": "b.example.com"})
+	})
+
+	rec := httptest.NewRecorder()
+	registry.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("Unexpected content type: %s", ct)
+	}
+
+	expected := `# HELP test_requests_total Total requests
+# TYPE test_requests_total counter
+test_requests_total 42
+# HELP test_domain_connections Connections per domain
+# TYPE test_domain_connections gauge
+test_domain_connections{domain="a.example.com",type="http"} 2
+test_domain_connections{domain="b.example.com",type="grpc"} 1
+`
+	if got := rec.Body.String(); got != expected {
+		t.Errorf("Unexpected metrics output:\n%s\nexpected:\n%s", got, expected)
+	}
+}
+
+func TestMetricsWriter_Summary(t *testing.T) {
+	w := &MetricsWriter{}
+	w.Summary("test_latency_seconds", "Latency", map[string]float64{"0.99": 0.3, "0.5": 0.1}, 12, map[string]string{"domain": "a.example.com"})
+
+	expected := `# HELP test_latency_seconds Latency
+# TYPE test_latency_seconds summary
+test_latency_seconds{domain="a.example.com",quantile="0.5"} 0.1
+test_latency_seconds{domain="a.example.com",quantile="0.99"} 0.3
+test_latency_seconds_count{domain="a.example.com"} 12
+`
+	if got := w.String(); got != expected {
+		t.Errorf("Unexpected summary output:\n%s\nexpected:\n%s", got, expected)
+	}
+}
+
+func TestMetricsWriter_GroupsFamiliesAndEscapesLabels(t *testing.T) {
+	w := &MetricsWriter{}
+	w.Gauge("test_inflight", "In flight", 1, map[string]string{"domain": "a.example.com"})
+	w.Gauge("test_queued", "Queued", 2, map[string]string{"domain": "a.example.com"})
+	w.Gauge("test_inflight", "In flight", 3, map[string]string{"domain": "bücher.example.com"})
+	w.Gauge("test_queued", "Queued", 4, map[string]string{"domain": "say \"hi\"\\\n"})
+
+	expected := `# HELP test_inflight In flight
+# TYPE test_inflight gauge
+test_inflight{domain="a.example.com"} 1
+test_inflight{domain="bücher.example.com"} 3
+# HELP test_queued Queued
+# TYPE test_queued gauge
+test_queued{domain="a.example.com"} 2
+test_queued{domain="say \"hi\"\\\n"} 4
+`
+	if got := w.String(); got != expected {
+		t.Errorf("Unexpected metrics output:\n%s\nexpected:\n%s", got, expected)
+	}
+}
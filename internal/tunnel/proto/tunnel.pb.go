@@ -86,6 +86,7 @@ const (
 	TunnelState_TUNNEL_STATE_DISCONNECTING  TunnelState = 5
 	TunnelState_TUNNEL_STATE_DISCONNECTED   TunnelState = 6
 	TunnelState_TUNNEL_STATE_ERROR          TunnelState = 7
+	TunnelState_TUNNEL_STATE_QUOTA_EXCEEDED TunnelState = 8 // Owner is over their bandwidth quota; retrying won't help
 )
 
 // Enum value maps for TunnelState.
@@ -99,6 +100,7 @@ var (
 		5: "TUNNEL_STATE_DISCONNECTING",
 		6: "TUNNEL_STATE_DISCONNECTED",
 		7: "TUNNEL_STATE_ERROR",
+		8: "TUNNEL_STATE_QUOTA_EXCEEDED",
 	}
 	TunnelState_value = map[string]int32{
 		"TUNNEL_STATE_UNKNOWN":        0,
@@ -109,6 +111,7 @@ var (
 		"TUNNEL_STATE_DISCONNECTING":  5,
 		"TUNNEL_STATE_DISCONNECTED":   6,
 		"TUNNEL_STATE_ERROR":          7,
+		"TUNNEL_STATE_QUOTA_EXCEEDED": 8,
 	}
 )
 
@@ -421,7 +424,7 @@ func (x ErrorMessage_ErrorType) Number() protoreflect.EnumNumber {
 
 // Deprecated: Use ErrorMessage_ErrorType.Descriptor instead.
 func (ErrorMessage_ErrorType) EnumDescriptor() ([]byte, []int) {
-	return file_tunnel_proto_rawDescGZIP(), []int{16, 0}
+	return file_tunnel_proto_rawDescGZIP(), []int{19, 0}
 }
 
 // TunnelMessage represents bidirectional communication over the tunnel
@@ -438,6 +441,9 @@ type TunnelMessage struct {
 	//	*TunnelMessage_HttpRequestStart
 	//	*TunnelMessage_HttpRequestChunk
 	//	*TunnelMessage_HttpRequestEnd
+	//	*TunnelMessage_WebsocketStart
+	//	*TunnelMessage_WebsocketData
+	//	*TunnelMessage_WebsocketEnd
 	MessageType   isTunnelMessage_MessageType `protobuf_oneof:"message_type"`
 	RequestId     string                      `protobuf:"bytes,10,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
 	Timestamp     int64                       `protobuf:"varint,11,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
@@ -563,6 +569,33 @@ func (x *TunnelMessage) GetHttpRequestEnd() *HTTPRequestEnd {
 	return nil
 }
 
+func (x *TunnelMessage) GetWebsocketStart() *WebSocketStart {
+	if x != nil {
+		if x, ok := x.MessageType.(*TunnelMessage_WebsocketStart); ok {
+			return x.WebsocketStart
+		}
+	}
+	return nil
+}
+
+func (x *TunnelMessage) GetWebsocketData() *WebSocketData {
+	if x != nil {
+		if x, ok := x.MessageType.(*TunnelMessage_WebsocketData); ok {
+			return x.WebsocketData
+		}
+	}
+	return nil
+}
+
+func (x *TunnelMessage) GetWebsocketEnd() *WebSocketEnd {
+	if x != nil {
+		if x, ok := x.MessageType.(*TunnelMessage_WebsocketEnd); ok {
+			return x.WebsocketEnd
+		}
+	}
+	return nil
+}
+
 func (x *TunnelMessage) GetRequestId() string {
 	if x != nil {
 		return x.RequestId
@@ -618,6 +651,19 @@ type TunnelMessage_HttpRequestEnd struct {
 	HttpRequestEnd *HTTPRequestEnd `protobuf:"bytes,9,opt,name=http_request_end,json=httpRequestEnd,proto3,oneof"`
 }
 
+type TunnelMessage_WebsocketStart struct {
+	// WebSocket bridging (requires supports_websocket)
+	WebsocketStart *WebSocketStart `protobuf:"bytes,12,opt,name=websocket_start,json=websocketStart,proto3,oneof"`
+}
+
+type TunnelMessage_WebsocketData struct {
+	WebsocketData *WebSocketData `protobuf:"bytes,13,opt,name=websocket_data,json=websocketData,proto3,oneof"`
+}
+
+type TunnelMessage_WebsocketEnd struct {
+	WebsocketEnd *WebSocketEnd `protobuf:"bytes,14,opt,name=websocket_end,json=websocketEnd,proto3,oneof"`
+}
+
 func (*TunnelMessage_Handshake) isTunnelMessage_MessageType() {}
 
 func (*TunnelMessage_HttpRequest) isTunnelMessage_MessageType() {}
@@ -636,6 +682,12 @@ func (*TunnelMessage_HttpRequestChunk) isTunnelMessage_MessageType() {}
 
 func (*TunnelMessage_HttpRequestEnd) isTunnelMessage_MessageType() {}
 
+func (*TunnelMessage_WebsocketStart) isTunnelMessage_MessageType() {}
+
+func (*TunnelMessage_WebsocketData) isTunnelMessage_MessageType() {}
+
+func (*TunnelMessage_WebsocketEnd) isTunnelMessage_MessageType() {}
+
 // ControlMessage is used exclusively for the ControlChannel
 // This keeps control messages separate from data traffic to prevent blocking
 type ControlMessage struct {
@@ -855,14 +907,17 @@ func (x *ControlHandshake) GetClientVersion() string {
 
 // TunnelHandshake initiates the tunnel connection
 type TunnelHandshake struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Token         string                 `protobuf:"bytes,1,opt,name=token,proto3" json:"token,omitempty"`
-	Domain        string                 `protobuf:"bytes,2,opt,name=domain,proto3" json:"domain,omitempty"`
-	TargetPort    int32                  `protobuf:"varint,3,opt,name=target_port,json=targetPort,proto3" json:"target_port,omitempty"`
-	ClientVersion string                 `protobuf:"bytes,4,opt,name=client_version,json=clientVersion,proto3" json:"client_version,omitempty"`
-	Capabilities  *TunnelCapabilities    `protobuf:"bytes,5,opt,name=capabilities,proto3" json:"capabilities,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Token            string                 `protobuf:"bytes,1,opt,name=token,proto3" json:"token,omitempty"`
+	Domain           string                 `protobuf:"bytes,2,opt,name=domain,proto3" json:"domain,omitempty"`
+	TargetPort       int32                  `protobuf:"varint,3,opt,name=target_port,json=targetPort,proto3" json:"target_port,omitempty"`
+	ClientVersion    string                 `protobuf:"bytes,4,opt,name=client_version,json=clientVersion,proto3" json:"client_version,omitempty"`
+	Capabilities     *TunnelCapabilities    `protobuf:"bytes,5,opt,name=capabilities,proto3" json:"capabilities,omitempty"`
+	RequireSignedUrl bool                   `protobuf:"varint,6,opt,name=require_signed_url,json=requireSignedUrl,proto3" json:"require_signed_url,omitempty"` // Reject requests without a valid ?sig=&exp= link
+	SignedUrlSecret  string                 `protobuf:"bytes,7,opt,name=signed_url_secret,json=signedUrlSecret,proto3" json:"signed_url_secret,omitempty"`     // HMAC key the links are signed with
+	TunnelId         uint32                 `protobuf:"varint,8,opt,name=tunnel_id,json=tunnelId,proto3" json:"tunnel_id,omitempty"`                           // Selects one of the token's tunnels; 0 lets the server pick
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
 }
 
 func (x *TunnelHandshake) Reset() {
@@ -930,6 +985,27 @@ func (x *TunnelHandshake) GetCapabilities() *TunnelCapabilities {
 	return nil
 }
 
+func (x *TunnelHandshake) GetRequireSignedUrl() bool {
+	if x != nil {
+		return x.RequireSignedUrl
+	}
+	return false
+}
+
+func (x *TunnelHandshake) GetSignedUrlSecret() string {
+	if x != nil {
+		return x.SignedUrlSecret
+	}
+	return ""
+}
+
+func (x *TunnelHandshake) GetTunnelId() uint32 {
+	if x != nil {
+		return x.TunnelId
+	}
+	return 0
+}
+
 // TunnelCapabilities describes client/server capabilities
 type TunnelCapabilities struct {
 	state                    protoimpl.MessageState `protogen:"open.v1"`
@@ -937,6 +1013,8 @@ type TunnelCapabilities struct {
 	SupportsCompression      bool                   `protobuf:"varint,2,opt,name=supports_compression,json=supportsCompression,proto3" json:"supports_compression,omitempty"`
 	MaxChunkSize             int64                  `protobuf:"varint,3,opt,name=max_chunk_size,json=maxChunkSize,proto3" json:"max_chunk_size,omitempty"`
 	SupportedEncodings       []string               `protobuf:"bytes,4,rep,name=supported_encodings,json=supportedEncodings,proto3" json:"supported_encodings,omitempty"`
+	SupportsWebsocket        bool                   `protobuf:"varint,5,opt,name=supports_websocket,json=supportsWebsocket,proto3" json:"supports_websocket,omitempty"`            // Can bridge WebSocket frames over the stream
+	SupportsChunkReorder     bool                   `protobuf:"varint,6,opt,name=supports_chunk_reorder,json=supportsChunkReorder,proto3" json:"supports_chunk_reorder,omitempty"` // Puts response chunks back in order, so several may be in flight
 	unknownFields            protoimpl.UnknownFields
 	sizeCache                protoimpl.SizeCache
 }
@@ -999,6 +1077,20 @@ func (x *TunnelCapabilities) GetSupportedEncodings() []string {
 	return nil
 }
 
+func (x *TunnelCapabilities) GetSupportsWebsocket() bool {
+	if x != nil {
+		return x.SupportsWebsocket
+	}
+	return false
+}
+
+func (x *TunnelCapabilities) GetSupportsChunkReorder() bool {
+	if x != nil {
+		return x.SupportsChunkReorder
+	}
+	return false
+}
+
 // HTTPRequest represents an HTTP request to be forwarded
 type HTTPRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -1123,9 +1215,11 @@ type HTTPResponse struct {
 	StatusText    string                 `protobuf:"bytes,2,opt,name=status_text,json=statusText,proto3" json:"status_text,omitempty"`
 	Headers       map[string]string      `protobuf:"bytes,3,rep,name=headers,proto3" json:"headers,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
 	Body          []byte                 `protobuf:"bytes,4,opt,name=body,proto3" json:"body,omitempty"`
-	IsChunked     bool                   `protobuf:"varint,5,opt,name=is_chunked,json=isChunked,proto3" json:"is_chunked,omitempty"` // Indicates if response is chunked
-	ChunkId       string                 `protobuf:"bytes,6,opt,name=chunk_id,json=chunkId,proto3" json:"chunk_id,omitempty"`        // For chunked responses
-	Metadata      *ResponseMetadata      `protobuf:"bytes,7,opt,name=metadata,proto3" json:"metadata,omitempty"`                     // Response metadata
+	IsChunked     bool                   `protobuf:"varint,5,opt,name=is_chunked,json=isChunked,proto3" json:"is_chunked,omitempty"`                                                       // Indicates if response is chunked
+	ChunkId       string                 `protobuf:"bytes,6,opt,name=chunk_id,json=chunkId,proto3" json:"chunk_id,omitempty"`                                                              // For chunked responses
+	Metadata      *ResponseMetadata      `protobuf:"bytes,7,opt,name=metadata,proto3" json:"metadata,omitempty"`                                                                           // Response metadata
+	Trailers      map[string]string      `protobuf:"bytes,8,rep,name=trailers,proto3" json:"trailers,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // HTTP trailers (sent with the final chunk)
+	ChunkEncoding string                 `protobuf:"bytes,9,opt,name=chunk_encoding,json=chunkEncoding,proto3" json:"chunk_encoding,omitempty"`                                            // Compression applied to body (negotiated via capabilities)
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -1209,6 +1303,20 @@ func (x *HTTPResponse) GetMetadata() *ResponseMetadata {
 	return nil
 }
 
+func (x *HTTPResponse) GetTrailers() map[string]string {
+	if x != nil {
+		return x.Trailers
+	}
+	return nil
+}
+
+func (x *HTTPResponse) GetChunkEncoding() string {
+	if x != nil {
+		return x.ChunkEncoding
+	}
+	return ""
+}
+
 // Large file streaming messages for memory-efficient transfers
 type LargeFileRequest struct {
 	state             protoimpl.MessageState `protogen:"open.v1"`
@@ -1575,6 +1683,198 @@ func (x *HTTPRequestEnd) GetRequestId() string {
 	return ""
 }
 
+// WebSocketStart asks the client to open a WebSocket to the local service.
+// The client answers with an HTTPResponse carrying the upgrade status.
+type WebSocketStart struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RequestId     string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	Path          string                 `protobuf:"bytes,2,opt,name=path,proto3" json:"path,omitempty"`
+	Query         string                 `protobuf:"bytes,3,opt,name=query,proto3" json:"query,omitempty"`
+	Headers       map[string]string      `protobuf:"bytes,4,rep,name=headers,proto3" json:"headers,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	ClientIp      string                 `protobuf:"bytes,5,opt,name=client_ip,json=clientIp,proto3" json:"client_ip,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WebSocketStart) Reset() {
+	*x = WebSocketStart{}
+	mi := &file_tunnel_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WebSocketStart) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WebSocketStart) ProtoMessage() {}
+
+func (x *WebSocketStart) ProtoReflect() protoreflect.Message {
+	mi := &file_tunnel_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WebSocketStart.ProtoReflect.Descriptor instead.
+func (*WebSocketStart) Descriptor() ([]byte, []int) {
+	return file_tunnel_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *WebSocketStart) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+func (x *WebSocketStart) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *WebSocketStart) GetQuery() string {
+	if x != nil {
+		return x.Query
+	}
+	return ""
+}
+
+func (x *WebSocketStart) GetHeaders() map[string]string {
+	if x != nil {
+		return x.Headers
+	}
+	return nil
+}
+
+func (x *WebSocketStart) GetClientIp() string {
+	if x != nil {
+		return x.ClientIp
+	}
+	return ""
+}
+
+// WebSocketData carries raw bytes of an upgraded connection in either direction
+type WebSocketData struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RequestId     string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	Data          []byte                 `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WebSocketData) Reset() {
+	*x = WebSocketData{}
+	mi := &file_tunnel_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WebSocketData) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WebSocketData) ProtoMessage() {}
+
+func (x *WebSocketData) ProtoReflect() protoreflect.Message {
+	mi := &file_tunnel_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WebSocketData.ProtoReflect.Descriptor instead.
+func (*WebSocketData) Descriptor() ([]byte, []int) {
+	return file_tunnel_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *WebSocketData) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+func (x *WebSocketData) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+// WebSocketEnd closes an upgraded connection from either side
+type WebSocketEnd struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RequestId     string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	StatusCode    int32                  `protobuf:"varint,2,opt,name=status_code,json=statusCode,proto3" json:"status_code,omitempty"`
+	Reason        string                 `protobuf:"bytes,3,opt,name=reason,proto3" json:"reason,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WebSocketEnd) Reset() {
+	*x = WebSocketEnd{}
+	mi := &file_tunnel_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WebSocketEnd) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WebSocketEnd) ProtoMessage() {}
+
+func (x *WebSocketEnd) ProtoReflect() protoreflect.Message {
+	mi := &file_tunnel_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WebSocketEnd.ProtoReflect.Descriptor instead.
+func (*WebSocketEnd) Descriptor() ([]byte, []int) {
+	return file_tunnel_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *WebSocketEnd) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+func (x *WebSocketEnd) GetStatusCode() int32 {
+	if x != nil {
+		return x.StatusCode
+	}
+	return 0
+}
+
+func (x *WebSocketEnd) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
 // TunnelControl handles tunnel lifecycle management
 type TunnelControl struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
@@ -1595,7 +1895,7 @@ type TunnelControl struct {
 
 func (x *TunnelControl) Reset() {
 	*x = TunnelControl{}
-	mi := &file_tunnel_proto_msgTypes[12]
+	mi := &file_tunnel_proto_msgTypes[15]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1607,7 +1907,7 @@ func (x *TunnelControl) String() string {
 func (*TunnelControl) ProtoMessage() {}
 
 func (x *TunnelControl) ProtoReflect() protoreflect.Message {
-	mi := &file_tunnel_proto_msgTypes[12]
+	mi := &file_tunnel_proto_msgTypes[15]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1620,7 +1920,7 @@ func (x *TunnelControl) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use TunnelControl.ProtoReflect.Descriptor instead.
 func (*TunnelControl) Descriptor() ([]byte, []int) {
-	return file_tunnel_proto_rawDescGZIP(), []int{12}
+	return file_tunnel_proto_rawDescGZIP(), []int{15}
 }
 
 func (x *TunnelControl) GetControlType() isTunnelControl_ControlType {
@@ -1750,7 +2050,7 @@ type CancelRequest struct {
 
 func (x *CancelRequest) Reset() {
 	*x = CancelRequest{}
-	mi := &file_tunnel_proto_msgTypes[13]
+	mi := &file_tunnel_proto_msgTypes[16]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1762,7 +2062,7 @@ func (x *CancelRequest) String() string {
 func (*CancelRequest) ProtoMessage() {}
 
 func (x *CancelRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_tunnel_proto_msgTypes[13]
+	mi := &file_tunnel_proto_msgTypes[16]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1775,7 +2075,7 @@ func (x *CancelRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CancelRequest.ProtoReflect.Descriptor instead.
 func (*CancelRequest) Descriptor() ([]byte, []int) {
-	return file_tunnel_proto_rawDescGZIP(), []int{13}
+	return file_tunnel_proto_rawDescGZIP(), []int{16}
 }
 
 func (x *CancelRequest) GetRequestId() string {
@@ -1801,20 +2101,21 @@ func (x *CancelRequest) GetTimestamp() int64 {
 
 // TunnelEstablishRequest requests client to establish a specific tunnel type
 type TunnelEstablishRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	TunnelType    TunnelType             `protobuf:"varint,1,opt,name=tunnel_type,json=tunnelType,proto3,enum=tunnel.TunnelType" json:"tunnel_type,omitempty"`
-	Domain        string                 `protobuf:"bytes,2,opt,name=domain,proto3" json:"domain,omitempty"`
-	TargetPort    int32                  `protobuf:"varint,3,opt,name=target_port,json=targetPort,proto3" json:"target_port,omitempty"`
-	RequestId     string                 `protobuf:"bytes,4,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
-	TimeoutMs     int64                  `protobuf:"varint,5,opt,name=timeout_ms,json=timeoutMs,proto3" json:"timeout_ms,omitempty"` // How long server will wait
-	Reason        string                 `protobuf:"bytes,6,opt,name=reason,proto3" json:"reason,omitempty"`                         // Reason for the request
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	TunnelType     TunnelType             `protobuf:"varint,1,opt,name=tunnel_type,json=tunnelType,proto3,enum=tunnel.TunnelType" json:"tunnel_type,omitempty"`
+	Domain         string                 `protobuf:"bytes,2,opt,name=domain,proto3" json:"domain,omitempty"`
+	TargetPort     int32                  `protobuf:"varint,3,opt,name=target_port,json=targetPort,proto3" json:"target_port,omitempty"`
+	RequestId      string                 `protobuf:"bytes,4,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	TimeoutMs      int64                  `protobuf:"varint,5,opt,name=timeout_ms,json=timeoutMs,proto3" json:"timeout_ms,omitempty"`               // How long server will wait
+	Reason         string                 `protobuf:"bytes,6,opt,name=reason,proto3" json:"reason,omitempty"`                                       // Reason for the request
+	ConnectionType string                 `protobuf:"bytes,7,opt,name=connection_type,json=connectionType,proto3" json:"connection_type,omitempty"` // "http-on-demand" for one fresh HTTP request, otherwise a WebSocket connection
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
 }
 
 func (x *TunnelEstablishRequest) Reset() {
 	*x = TunnelEstablishRequest{}
-	mi := &file_tunnel_proto_msgTypes[14]
+	mi := &file_tunnel_proto_msgTypes[17]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1826,7 +2127,7 @@ func (x *TunnelEstablishRequest) String() string {
 func (*TunnelEstablishRequest) ProtoMessage() {}
 
 func (x *TunnelEstablishRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_tunnel_proto_msgTypes[14]
+	mi := &file_tunnel_proto_msgTypes[17]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1839,7 +2140,7 @@ func (x *TunnelEstablishRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use TunnelEstablishRequest.ProtoReflect.Descriptor instead.
 func (*TunnelEstablishRequest) Descriptor() ([]byte, []int) {
-	return file_tunnel_proto_rawDescGZIP(), []int{14}
+	return file_tunnel_proto_rawDescGZIP(), []int{17}
 }
 
 func (x *TunnelEstablishRequest) GetTunnelType() TunnelType {
@@ -1884,6 +2185,13 @@ func (x *TunnelEstablishRequest) GetReason() string {
 	return ""
 }
 
+func (x *TunnelEstablishRequest) GetConnectionType() string {
+	if x != nil {
+		return x.ConnectionType
+	}
+	return ""
+}
+
 // TunnelConfig for configuration updates
 type TunnelConfig struct {
 	state             protoimpl.MessageState `protogen:"open.v1"`
@@ -1896,7 +2204,7 @@ type TunnelConfig struct {
 
 func (x *TunnelConfig) Reset() {
 	*x = TunnelConfig{}
-	mi := &file_tunnel_proto_msgTypes[15]
+	mi := &file_tunnel_proto_msgTypes[18]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1908,7 +2216,7 @@ func (x *TunnelConfig) String() string {
 func (*TunnelConfig) ProtoMessage() {}
 
 func (x *TunnelConfig) ProtoReflect() protoreflect.Message {
-	mi := &file_tunnel_proto_msgTypes[15]
+	mi := &file_tunnel_proto_msgTypes[18]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1921,7 +2229,7 @@ func (x *TunnelConfig) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use TunnelConfig.ProtoReflect.Descriptor instead.
 func (*TunnelConfig) Descriptor() ([]byte, []int) {
-	return file_tunnel_proto_rawDescGZIP(), []int{15}
+	return file_tunnel_proto_rawDescGZIP(), []int{18}
 }
 
 func (x *TunnelConfig) GetMaxConcurrent() int32 {
@@ -1959,7 +2267,7 @@ type ErrorMessage struct {
 
 func (x *ErrorMessage) Reset() {
 	*x = ErrorMessage{}
-	mi := &file_tunnel_proto_msgTypes[16]
+	mi := &file_tunnel_proto_msgTypes[19]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1971,7 +2279,7 @@ func (x *ErrorMessage) String() string {
 func (*ErrorMessage) ProtoMessage() {}
 
 func (x *ErrorMessage) ProtoReflect() protoreflect.Message {
-	mi := &file_tunnel_proto_msgTypes[16]
+	mi := &file_tunnel_proto_msgTypes[19]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1984,7 +2292,7 @@ func (x *ErrorMessage) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ErrorMessage.ProtoReflect.Descriptor instead.
 func (*ErrorMessage) Descriptor() ([]byte, []int) {
-	return file_tunnel_proto_rawDescGZIP(), []int{16}
+	return file_tunnel_proto_rawDescGZIP(), []int{19}
 }
 
 func (x *ErrorMessage) GetType() ErrorMessage_ErrorType {
@@ -2032,13 +2340,15 @@ type TunnelStatus struct {
 	ActiveConnections int32                  `protobuf:"varint,5,opt,name=active_connections,json=activeConnections,proto3" json:"active_connections,omitempty"`
 	LastActivity      int64                  `protobuf:"varint,6,opt,name=last_activity,json=lastActivity,proto3" json:"last_activity,omitempty"`
 	ErrorMessage      string                 `protobuf:"bytes,7,opt,name=error_message,json=errorMessage,proto3" json:"error_message,omitempty"`
+	ErrorCode         string                 `protobuf:"bytes,8,opt,name=error_code,json=errorCode,proto3" json:"error_code,omitempty"` // Why a handshake was refused, e.g. INVALID_TOKEN (see tunnel.HandshakeErrorCode)
+	Capabilities      *TunnelCapabilities    `protobuf:"bytes,9,opt,name=capabilities,proto3" json:"capabilities,omitempty"`            // What the server accepted from the client's capabilities
 	unknownFields     protoimpl.UnknownFields
 	sizeCache         protoimpl.SizeCache
 }
 
 func (x *TunnelStatus) Reset() {
 	*x = TunnelStatus{}
-	mi := &file_tunnel_proto_msgTypes[17]
+	mi := &file_tunnel_proto_msgTypes[20]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -2050,7 +2360,7 @@ func (x *TunnelStatus) String() string {
 func (*TunnelStatus) ProtoMessage() {}
 
 func (x *TunnelStatus) ProtoReflect() protoreflect.Message {
-	mi := &file_tunnel_proto_msgTypes[17]
+	mi := &file_tunnel_proto_msgTypes[20]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2063,7 +2373,7 @@ func (x *TunnelStatus) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use TunnelStatus.ProtoReflect.Descriptor instead.
 func (*TunnelStatus) Descriptor() ([]byte, []int) {
-	return file_tunnel_proto_rawDescGZIP(), []int{17}
+	return file_tunnel_proto_rawDescGZIP(), []int{20}
 }
 
 func (x *TunnelStatus) GetState() TunnelState {
@@ -2115,6 +2425,20 @@ func (x *TunnelStatus) GetErrorMessage() string {
 	return ""
 }
 
+func (x *TunnelStatus) GetErrorCode() string {
+	if x != nil {
+		return x.ErrorCode
+	}
+	return ""
+}
+
+func (x *TunnelStatus) GetCapabilities() *TunnelCapabilities {
+	if x != nil {
+		return x.Capabilities
+	}
+	return nil
+}
+
 // TunnelMetrics provides performance metrics
 type TunnelMetrics struct {
 	state               protoimpl.MessageState `protogen:"open.v1"`
@@ -2132,7 +2456,7 @@ type TunnelMetrics struct {
 
 func (x *TunnelMetrics) Reset() {
 	*x = TunnelMetrics{}
-	mi := &file_tunnel_proto_msgTypes[18]
+	mi := &file_tunnel_proto_msgTypes[21]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -2144,7 +2468,7 @@ func (x *TunnelMetrics) String() string {
 func (*TunnelMetrics) ProtoMessage() {}
 
 func (x *TunnelMetrics) ProtoReflect() protoreflect.Message {
-	mi := &file_tunnel_proto_msgTypes[18]
+	mi := &file_tunnel_proto_msgTypes[21]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2157,7 +2481,7 @@ func (x *TunnelMetrics) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use TunnelMetrics.ProtoReflect.Descriptor instead.
 func (*TunnelMetrics) Descriptor() ([]byte, []int) {
-	return file_tunnel_proto_rawDescGZIP(), []int{18}
+	return file_tunnel_proto_rawDescGZIP(), []int{21}
 }
 
 func (x *TunnelMetrics) GetTotalRequests() int64 {
@@ -2226,7 +2550,7 @@ type ControlPing struct {
 
 func (x *ControlPing) Reset() {
 	*x = ControlPing{}
-	mi := &file_tunnel_proto_msgTypes[19]
+	mi := &file_tunnel_proto_msgTypes[22]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -2238,7 +2562,7 @@ func (x *ControlPing) String() string {
 func (*ControlPing) ProtoMessage() {}
 
 func (x *ControlPing) ProtoReflect() protoreflect.Message {
-	mi := &file_tunnel_proto_msgTypes[19]
+	mi := &file_tunnel_proto_msgTypes[22]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2251,7 +2575,7 @@ func (x *ControlPing) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ControlPing.ProtoReflect.Descriptor instead.
 func (*ControlPing) Descriptor() ([]byte, []int) {
-	return file_tunnel_proto_rawDescGZIP(), []int{19}
+	return file_tunnel_proto_rawDescGZIP(), []int{22}
 }
 
 func (x *ControlPing) GetTimestamp() int64 {
@@ -2271,7 +2595,7 @@ type ControlPong struct {
 
 func (x *ControlPong) Reset() {
 	*x = ControlPong{}
-	mi := &file_tunnel_proto_msgTypes[20]
+	mi := &file_tunnel_proto_msgTypes[23]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -2283,7 +2607,7 @@ func (x *ControlPong) String() string {
 func (*ControlPong) ProtoMessage() {}
 
 func (x *ControlPong) ProtoReflect() protoreflect.Message {
-	mi := &file_tunnel_proto_msgTypes[20]
+	mi := &file_tunnel_proto_msgTypes[23]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2296,7 +2620,7 @@ func (x *ControlPong) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ControlPong.ProtoReflect.Descriptor instead.
 func (*ControlPong) Descriptor() ([]byte, []int) {
-	return file_tunnel_proto_rawDescGZIP(), []int{20}
+	return file_tunnel_proto_rawDescGZIP(), []int{23}
 }
 
 func (x *ControlPong) GetTimestamp() int64 {
@@ -2323,7 +2647,7 @@ type HealthCheckRequest struct {
 
 func (x *HealthCheckRequest) Reset() {
 	*x = HealthCheckRequest{}
-	mi := &file_tunnel_proto_msgTypes[21]
+	mi := &file_tunnel_proto_msgTypes[24]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -2335,7 +2659,7 @@ func (x *HealthCheckRequest) String() string {
 func (*HealthCheckRequest) ProtoMessage() {}
 
 func (x *HealthCheckRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_tunnel_proto_msgTypes[21]
+	mi := &file_tunnel_proto_msgTypes[24]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2348,7 +2672,7 @@ func (x *HealthCheckRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use HealthCheckRequest.ProtoReflect.Descriptor instead.
 func (*HealthCheckRequest) Descriptor() ([]byte, []int) {
-	return file_tunnel_proto_rawDescGZIP(), []int{21}
+	return file_tunnel_proto_rawDescGZIP(), []int{24}
 }
 
 func (x *HealthCheckRequest) GetService() string {
@@ -2370,7 +2694,7 @@ type HealthCheckResponse struct {
 
 func (x *HealthCheckResponse) Reset() {
 	*x = HealthCheckResponse{}
-	mi := &file_tunnel_proto_msgTypes[22]
+	mi := &file_tunnel_proto_msgTypes[25]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -2382,7 +2706,7 @@ func (x *HealthCheckResponse) String() string {
 func (*HealthCheckResponse) ProtoMessage() {}
 
 func (x *HealthCheckResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_tunnel_proto_msgTypes[22]
+	mi := &file_tunnel_proto_msgTypes[25]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2395,7 +2719,7 @@ func (x *HealthCheckResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use HealthCheckResponse.ProtoReflect.Descriptor instead.
 func (*HealthCheckResponse) Descriptor() ([]byte, []int) {
-	return file_tunnel_proto_rawDescGZIP(), []int{22}
+	return file_tunnel_proto_rawDescGZIP(), []int{25}
 }
 
 func (x *HealthCheckResponse) GetStatus() HealthStatus {
@@ -2439,7 +2763,7 @@ type RequestMetadata struct {
 
 func (x *RequestMetadata) Reset() {
 	*x = RequestMetadata{}
-	mi := &file_tunnel_proto_msgTypes[23]
+	mi := &file_tunnel_proto_msgTypes[26]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -2451,7 +2775,7 @@ func (x *RequestMetadata) String() string {
 func (*RequestMetadata) ProtoMessage() {}
 
 func (x *RequestMetadata) ProtoReflect() protoreflect.Message {
-	mi := &file_tunnel_proto_msgTypes[23]
+	mi := &file_tunnel_proto_msgTypes[26]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2464,7 +2788,7 @@ func (x *RequestMetadata) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use RequestMetadata.ProtoReflect.Descriptor instead.
 func (*RequestMetadata) Descriptor() ([]byte, []int) {
-	return file_tunnel_proto_rawDescGZIP(), []int{23}
+	return file_tunnel_proto_rawDescGZIP(), []int{26}
 }
 
 func (x *RequestMetadata) GetType() RequestType {
@@ -2508,7 +2832,7 @@ type ResponseMetadata struct {
 
 func (x *ResponseMetadata) Reset() {
 	*x = ResponseMetadata{}
-	mi := &file_tunnel_proto_msgTypes[24]
+	mi := &file_tunnel_proto_msgTypes[27]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -2520,7 +2844,7 @@ func (x *ResponseMetadata) String() string {
 func (*ResponseMetadata) ProtoMessage() {}
 
 func (x *ResponseMetadata) ProtoReflect() protoreflect.Message {
-	mi := &file_tunnel_proto_msgTypes[24]
+	mi := &file_tunnel_proto_msgTypes[27]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2533,7 +2857,7 @@ func (x *ResponseMetadata) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ResponseMetadata.ProtoReflect.Descriptor instead.
 func (*ResponseMetadata) Descriptor() ([]byte, []int) {
-	return file_tunnel_proto_rawDescGZIP(), []int{24}
+	return file_tunnel_proto_rawDescGZIP(), []int{27}
 }
 
 func (x *ResponseMetadata) GetProcessingTimeMs() int64 {
@@ -2568,7 +2892,7 @@ var File_tunnel_proto protoreflect.FileDescriptor
 
 const file_tunnel_proto_rawDesc = "" +
 	"\n" +
-	"\ftunnel.proto\x12\x06tunnel\"\xf5\x04\n" +
+	"\ftunnel.proto\x12\x06tunnel\"\xb5\x06\n" +
 	"\rTunnelMessage\x127\n" +
 	"\thandshake\x18\x01 \x01(\v2\x17.tunnel.TunnelHandshakeH\x00R\thandshake\x128\n" +
 	"\fhttp_request\x18\x02 \x01(\v2\x13.tunnel.HTTPRequestH\x00R\vhttpRequest\x12;\n" +
@@ -2578,7 +2902,10 @@ const file_tunnel_proto_rawDesc = "" +
 	"\x06status\x18\x06 \x01(\v2\x14.tunnel.TunnelStatusH\x00R\x06status\x12H\n" +
 	"\x12http_request_start\x18\a \x01(\v2\x18.tunnel.HTTPRequestStartH\x00R\x10httpRequestStart\x12H\n" +
 	"\x12http_request_chunk\x18\b \x01(\v2\x18.tunnel.HTTPRequestChunkH\x00R\x10httpRequestChunk\x12B\n" +
-	"\x10http_request_end\x18\t \x01(\v2\x16.tunnel.HTTPRequestEndH\x00R\x0ehttpRequestEnd\x12\x1d\n" +
+	"\x10http_request_end\x18\t \x01(\v2\x16.tunnel.HTTPRequestEndH\x00R\x0ehttpRequestEnd\x12A\n" +
+	"\x0fwebsocket_start\x18\f \x01(\v2\x16.tunnel.WebSocketStartH\x00R\x0ewebsocketStart\x12>\n" +
+	"\x0ewebsocket_data\x18\r \x01(\v2\x15.tunnel.WebSocketDataH\x00R\rwebsocketData\x12;\n" +
+	"\rwebsocket_end\x18\x0e \x01(\v2\x14.tunnel.WebSocketEndH\x00R\fwebsocketEnd\x12\x1d\n" +
 	"\n" +
 	"request_id\x18\n" +
 	" \x01(\tR\trequestId\x12\x1c\n" +
@@ -2598,19 +2925,24 @@ const file_tunnel_proto_rawDesc = "" +
 	"\fmessage_type\"Q\n" +
 	"\x10ControlHandshake\x12\x16\n" +
 	"\x06domain\x18\x01 \x01(\tR\x06domain\x12%\n" +
-	"\x0eclient_version\x18\x02 \x01(\tR\rclientVersion\"\xc7\x01\n" +
+	"\x0eclient_version\x18\x02 \x01(\tR\rclientVersion\"\xbe\x02\n" +
 	"\x0fTunnelHandshake\x12\x14\n" +
 	"\x05token\x18\x01 \x01(\tR\x05token\x12\x16\n" +
 	"\x06domain\x18\x02 \x01(\tR\x06domain\x12\x1f\n" +
 	"\vtarget_port\x18\x03 \x01(\x05R\n" +
 	"targetPort\x12%\n" +
 	"\x0eclient_version\x18\x04 \x01(\tR\rclientVersion\x12>\n" +
-	"\fcapabilities\x18\x05 \x01(\v2\x1a.tunnel.TunnelCapabilitiesR\fcapabilities\"\xdc\x01\n" +
+	"\fcapabilities\x18\x05 \x01(\v2\x1a.tunnel.TunnelCapabilitiesR\fcapabilities\x12,\n" +
+	"\x12require_signed_url\x18\x06 \x01(\bR\x10requireSignedUrl\x12*\n" +
+	"\x11signed_url_secret\x18\a \x01(\tR\x0fsignedUrlSecret\x12\x1b\n" +
+	"\ttunnel_id\x18\b \x01(\rR\btunnelId\"\xc1\x02\n" +
 	"\x12TunnelCapabilities\x12<\n" +
 	"\x1asupports_chunked_streaming\x18\x01 \x01(\bR\x18supportsChunkedStreaming\x121\n" +
 	"\x14supports_compression\x18\x02 \x01(\bR\x13supportsCompression\x12$\n" +
 	"\x0emax_chunk_size\x18\x03 \x01(\x03R\fmaxChunkSize\x12/\n" +
-	"\x13supported_encodings\x18\x04 \x03(\tR\x12supportedEncodings\"\x97\x03\n" +
+	"\x13supported_encodings\x18\x04 \x03(\tR\x12supportedEncodings\x12-\n" +
+	"\x12supports_websocket\x18\x05 \x01(\bR\x11supportsWebsocket\x124\n" +
+	"\x16supports_chunk_reorder\x18\x06 \x01(\bR\x14supportsChunkReorder\"\x97\x03\n" +
 	"\vHTTPRequest\x12\x16\n" +
 	"\x06method\x18\x01 \x01(\tR\x06method\x12\x12\n" +
 	"\x04path\x18\x02 \x01(\tR\x04path\x12\x14\n" +
@@ -2626,7 +2958,7 @@ const file_tunnel_proto_rawDesc = "" +
 	" \x01(\v2\x17.tunnel.RequestMetadataR\bmetadata\x1a:\n" +
 	"\fHeadersEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
-	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xcd\x02\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xf1\x03\n" +
 	"\fHTTPResponse\x12\x1f\n" +
 	"\vstatus_code\x18\x01 \x01(\x05R\n" +
 	"statusCode\x12\x1f\n" +
@@ -2637,9 +2969,14 @@ const file_tunnel_proto_rawDesc = "" +
 	"\n" +
 	"is_chunked\x18\x05 \x01(\bR\tisChunked\x12\x19\n" +
 	"\bchunk_id\x18\x06 \x01(\tR\achunkId\x124\n" +
-	"\bmetadata\x18\a \x01(\v2\x18.tunnel.ResponseMetadataR\bmetadata\x1a:\n" +
+	"\bmetadata\x18\a \x01(\v2\x18.tunnel.ResponseMetadataR\bmetadata\x12>\n" +
+	"\btrailers\x18\b \x03(\v2\".tunnel.HTTPResponse.TrailersEntryR\btrailers\x12%\n" +
+	"\x0echunk_encoding\x18\t \x01(\tR\rchunkEncoding\x1a:\n" +
 	"\fHeadersEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\x1a;\n" +
+	"\rTrailersEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
 	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xb7\x01\n" +
 	"\x10LargeFileRequest\x12\x1d\n" +
 	"\n" +
@@ -2682,7 +3019,27 @@ const file_tunnel_proto_rawDesc = "" +
 	"\x04data\x18\x02 \x01(\fR\x04data\"/\n" +
 	"\x0eHTTPRequestEnd\x12\x1d\n" +
 	"\n" +
-	"request_id\x18\x01 \x01(\tR\trequestId\"\xb2\x03\n" +
+	"request_id\x18\x01 \x01(\tR\trequestId\"\xf1\x01\n" +
+	"\x0eWebSocketStart\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x01 \x01(\tR\trequestId\x12\x12\n" +
+	"\x04path\x18\x02 \x01(\tR\x04path\x12\x14\n" +
+	"\x05query\x18\x03 \x01(\tR\x05query\x12=\n" +
+	"\aheaders\x18\x04 \x03(\v2#.tunnel.WebSocketStart.HeadersEntryR\aheaders\x12\x1b\n" +
+	"\tclient_ip\x18\x05 \x01(\tR\bclientIp\x1a:\n" +
+	"\fHeadersEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"B\n" +
+	"\rWebSocketData\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x01 \x01(\tR\trequestId\x12\x12\n" +
+	"\x04data\x18\x02 \x01(\fR\x04data\"f\n" +
+	"\fWebSocketEnd\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x01 \x01(\tR\trequestId\x12\x1f\n" +
+	"\vstatus_code\x18\x02 \x01(\x05R\n" +
+	"statusCode\x12\x16\n" +
+	"\x06reason\x18\x03 \x01(\tR\x06reason\"\xb2\x03\n" +
 	"\rTunnelControl\x127\n" +
 	"\thandshake\x18\x01 \x01(\v2\x17.tunnel.TunnelHandshakeH\x00R\thandshake\x12.\n" +
 	"\x06status\x18\x02 \x01(\v2\x14.tunnel.TunnelStatusH\x00R\x06status\x12.\n" +
@@ -2698,7 +3055,7 @@ const file_tunnel_proto_rawDesc = "" +
 	"\n" +
 	"request_id\x18\x01 \x01(\tR\trequestId\x12\x16\n" +
 	"\x06reason\x18\x02 \x01(\tR\x06reason\x12\x1c\n" +
-	"\ttimestamp\x18\x03 \x01(\x03R\ttimestamp\"\xdc\x01\n" +
+	"\ttimestamp\x18\x03 \x01(\x03R\ttimestamp\"\x85\x02\n" +
 	"\x16TunnelEstablishRequest\x123\n" +
 	"\vtunnel_type\x18\x01 \x01(\x0e2\x12.tunnel.TunnelTypeR\n" +
 	"tunnelType\x12\x16\n" +
@@ -2709,7 +3066,8 @@ const file_tunnel_proto_rawDesc = "" +
 	"request_id\x18\x04 \x01(\tR\trequestId\x12\x1d\n" +
 	"\n" +
 	"timeout_ms\x18\x05 \x01(\x03R\ttimeoutMs\x12\x16\n" +
-	"\x06reason\x18\x06 \x01(\tR\x06reason\"\x8d\x01\n" +
+	"\x06reason\x18\x06 \x01(\tR\x06reason\x12'\n" +
+	"\x0fconnection_type\x18\a \x01(\tR\x0econnectionType\"\x8d\x01\n" +
 	"\fTunnelConfig\x12%\n" +
 	"\x0emax_concurrent\x18\x01 \x01(\x05R\rmaxConcurrent\x12'\n" +
 	"\x0ftimeout_seconds\x18\x02 \x01(\x05R\x0etimeoutSeconds\x12-\n" +
@@ -2728,7 +3086,7 @@ const file_tunnel_proto_rawDesc = "" +
 	"\x0fREQUEST_TIMEOUT\x10\x04\x12\x10\n" +
 	"\fRATE_LIMITED\x10\x05\x12\x0f\n" +
 	"\vCHUNK_ERROR\x10\x06\x12\x13\n" +
-	"\x0fSTREAMING_ERROR\x10\a\"\x8e\x02\n" +
+	"\x0fSTREAMING_ERROR\x10\a\"\xed\x02\n" +
 	"\fTunnelStatus\x12)\n" +
 	"\x05state\x18\x01 \x01(\x0e2\x13.tunnel.TunnelStateR\x05state\x12\x16\n" +
 	"\x06domain\x18\x02 \x01(\tR\x06domain\x12\x1f\n" +
@@ -2737,7 +3095,10 @@ const file_tunnel_proto_rawDesc = "" +
 	"\fconnected_at\x18\x04 \x01(\x03R\vconnectedAt\x12-\n" +
 	"\x12active_connections\x18\x05 \x01(\x05R\x11activeConnections\x12#\n" +
 	"\rlast_activity\x18\x06 \x01(\x03R\flastActivity\x12#\n" +
-	"\rerror_message\x18\a \x01(\tR\ferrorMessage\"\xe6\x02\n" +
+	"\rerror_message\x18\a \x01(\tR\ferrorMessage\x12\x1d\n" +
+	"\n" +
+	"error_code\x18\b \x01(\tR\terrorCode\x12>\n" +
+	"\fcapabilities\x18\t \x01(\v2\x1a.tunnel.TunnelCapabilitiesR\fcapabilities\"\xe6\x02\n" +
 	"\rTunnelMetrics\x12%\n" +
 	"\x0etotal_requests\x18\x01 \x01(\x03R\rtotalRequests\x12'\n" +
 	"\x0ftotal_responses\x18\x02 \x01(\x03R\x0etotalResponses\x12(\n" +
@@ -2778,7 +3139,7 @@ const file_tunnel_proto_rawDesc = "" +
 	"\x13TUNNEL_TYPE_UNKNOWN\x10\x00\x12\x13\n" +
 	"\x0fTUNNEL_TYPE_TCP\x10\x01\x12\x14\n" +
 	"\x10TUNNEL_TYPE_GRPC\x10\x02\x12\x16\n" +
-	"\x12TUNNEL_TYPE_HYBRID\x10\x03*\xf1\x01\n" +
+	"\x12TUNNEL_TYPE_HYBRID\x10\x03*\x92\x02\n" +
 	"\vTunnelState\x12\x18\n" +
 	"\x14TUNNEL_STATE_UNKNOWN\x10\x00\x12\x1b\n" +
 	"\x17TUNNEL_STATE_CONNECTING\x10\x01\x12\x1a\n" +
@@ -2787,7 +3148,8 @@ const file_tunnel_proto_rawDesc = "" +
 	"\x13TUNNEL_STATE_ACTIVE\x10\x04\x12\x1e\n" +
 	"\x1aTUNNEL_STATE_DISCONNECTING\x10\x05\x12\x1d\n" +
 	"\x19TUNNEL_STATE_DISCONNECTED\x10\x06\x12\x16\n" +
-	"\x12TUNNEL_STATE_ERROR\x10\a*\x86\x01\n" +
+	"\x12TUNNEL_STATE_ERROR\x10\a\x12\x1f\n" +
+	"\x1bTUNNEL_STATE_QUOTA_EXCEEDED\x10\b*\x86\x01\n" +
 	"\fHealthStatus\x12\x19\n" +
 	"\x15HEALTH_STATUS_UNKNOWN\x10\x00\x12\x19\n" +
 	"\x15HEALTH_STATUS_SERVING\x10\x01\x12\x1d\n" +
@@ -2830,7 +3192,7 @@ func file_tunnel_proto_rawDescGZIP() []byte {
 }
 
 var file_tunnel_proto_enumTypes = make([]protoimpl.EnumInfo, 7)
-var file_tunnel_proto_msgTypes = make([]protoimpl.MessageInfo, 30)
+var file_tunnel_proto_msgTypes = make([]protoimpl.MessageInfo, 35)
 var file_tunnel_proto_goTypes = []any{
 	(TunnelType)(0),                // 0: tunnel.TunnelType
 	(TunnelState)(0),               // 1: tunnel.TunnelState
@@ -2851,77 +3213,88 @@ var file_tunnel_proto_goTypes = []any{
 	(*HTTPRequestStart)(nil),       // 16: tunnel.HTTPRequestStart
 	(*HTTPRequestChunk)(nil),       // 17: tunnel.HTTPRequestChunk
 	(*HTTPRequestEnd)(nil),         // 18: tunnel.HTTPRequestEnd
-	(*TunnelControl)(nil),          // 19: tunnel.TunnelControl
-	(*CancelRequest)(nil),          // 20: tunnel.CancelRequest
-	(*TunnelEstablishRequest)(nil), // 21: tunnel.TunnelEstablishRequest
-	(*TunnelConfig)(nil),           // 22: tunnel.TunnelConfig
-	(*ErrorMessage)(nil),           // 23: tunnel.ErrorMessage
-	(*TunnelStatus)(nil),           // 24: tunnel.TunnelStatus
-	(*TunnelMetrics)(nil),          // 25: tunnel.TunnelMetrics
-	(*ControlPing)(nil),            // 26: tunnel.ControlPing
-	(*ControlPong)(nil),            // 27: tunnel.ControlPong
-	(*HealthCheckRequest)(nil),     // 28: tunnel.HealthCheckRequest
-	(*HealthCheckResponse)(nil),    // 29: tunnel.HealthCheckResponse
-	(*RequestMetadata)(nil),        // 30: tunnel.RequestMetadata
-	(*ResponseMetadata)(nil),       // 31: tunnel.ResponseMetadata
-	nil,                            // 32: tunnel.HTTPRequest.HeadersEntry
-	nil,                            // 33: tunnel.HTTPResponse.HeadersEntry
-	nil,                            // 34: tunnel.LargeFileChunk.HeadersEntry
-	nil,                            // 35: tunnel.HTTPRequestStart.HeadersEntry
-	nil,                            // 36: tunnel.HealthCheckResponse.DetailsEntry
+	(*WebSocketStart)(nil),         // 19: tunnel.WebSocketStart
+	(*WebSocketData)(nil),          // 20: tunnel.WebSocketData
+	(*WebSocketEnd)(nil),           // 21: tunnel.WebSocketEnd
+	(*TunnelControl)(nil),          // 22: tunnel.TunnelControl
+	(*CancelRequest)(nil),          // 23: tunnel.CancelRequest
+	(*TunnelEstablishRequest)(nil), // 24: tunnel.TunnelEstablishRequest
+	(*TunnelConfig)(nil),           // 25: tunnel.TunnelConfig
+	(*ErrorMessage)(nil),           // 26: tunnel.ErrorMessage
+	(*TunnelStatus)(nil),           // 27: tunnel.TunnelStatus
+	(*TunnelMetrics)(nil),          // 28: tunnel.TunnelMetrics
+	(*ControlPing)(nil),            // 29: tunnel.ControlPing
+	(*ControlPong)(nil),            // 30: tunnel.ControlPong
+	(*HealthCheckRequest)(nil),     // 31: tunnel.HealthCheckRequest
+	(*HealthCheckResponse)(nil),    // 32: tunnel.HealthCheckResponse
+	(*RequestMetadata)(nil),        // 33: tunnel.RequestMetadata
+	(*ResponseMetadata)(nil),       // 34: tunnel.ResponseMetadata
+	nil,                            // 35: tunnel.HTTPRequest.HeadersEntry
+	nil,                            // 36: tunnel.HTTPResponse.HeadersEntry
+	nil,                            // 37: tunnel.HTTPResponse.TrailersEntry
+	nil,                            // 38: tunnel.LargeFileChunk.HeadersEntry
+	nil,                            // 39: tunnel.HTTPRequestStart.HeadersEntry
+	nil,                            // 40: tunnel.WebSocketStart.HeadersEntry
+	nil,                            // 41: tunnel.HealthCheckResponse.DetailsEntry
 }
 var file_tunnel_proto_depIdxs = []int32{
 	10, // 0: tunnel.TunnelMessage.handshake:type_name -> tunnel.TunnelHandshake
 	12, // 1: tunnel.TunnelMessage.http_request:type_name -> tunnel.HTTPRequest
 	13, // 2: tunnel.TunnelMessage.http_response:type_name -> tunnel.HTTPResponse
-	19, // 3: tunnel.TunnelMessage.control:type_name -> tunnel.TunnelControl
-	23, // 4: tunnel.TunnelMessage.error:type_name -> tunnel.ErrorMessage
-	24, // 5: tunnel.TunnelMessage.status:type_name -> tunnel.TunnelStatus
+	22, // 3: tunnel.TunnelMessage.control:type_name -> tunnel.TunnelControl
+	26, // 4: tunnel.TunnelMessage.error:type_name -> tunnel.ErrorMessage
+	27, // 5: tunnel.TunnelMessage.status:type_name -> tunnel.TunnelStatus
 	16, // 6: tunnel.TunnelMessage.http_request_start:type_name -> tunnel.HTTPRequestStart
 	17, // 7: tunnel.TunnelMessage.http_request_chunk:type_name -> tunnel.HTTPRequestChunk
 	18, // 8: tunnel.TunnelMessage.http_request_end:type_name -> tunnel.HTTPRequestEnd
-	9,  // 9: tunnel.ControlMessage.handshake:type_name -> tunnel.ControlHandshake
-	20, // 10: tunnel.ControlMessage.cancel:type_name -> tunnel.CancelRequest
-	28, // 11: tunnel.ControlMessage.health_check:type_name -> tunnel.HealthCheckRequest
-	29, // 12: tunnel.ControlMessage.health_response:type_name -> tunnel.HealthCheckResponse
-	26, // 13: tunnel.ControlMessage.ping:type_name -> tunnel.ControlPing
-	27, // 14: tunnel.ControlMessage.pong:type_name -> tunnel.ControlPong
-	11, // 15: tunnel.TunnelHandshake.capabilities:type_name -> tunnel.TunnelCapabilities
-	32, // 16: tunnel.HTTPRequest.headers:type_name -> tunnel.HTTPRequest.HeadersEntry
-	30, // 17: tunnel.HTTPRequest.metadata:type_name -> tunnel.RequestMetadata
-	33, // 18: tunnel.HTTPResponse.headers:type_name -> tunnel.HTTPResponse.HeadersEntry
-	31, // 19: tunnel.HTTPResponse.metadata:type_name -> tunnel.ResponseMetadata
-	12, // 20: tunnel.LargeFileRequest.http_request:type_name -> tunnel.HTTPRequest
-	34, // 21: tunnel.LargeFileChunk.headers:type_name -> tunnel.LargeFileChunk.HeadersEntry
-	35, // 22: tunnel.HTTPRequestStart.headers:type_name -> tunnel.HTTPRequestStart.HeadersEntry
-	10, // 23: tunnel.TunnelControl.handshake:type_name -> tunnel.TunnelHandshake
-	24, // 24: tunnel.TunnelControl.status:type_name -> tunnel.TunnelStatus
-	22, // 25: tunnel.TunnelControl.config:type_name -> tunnel.TunnelConfig
-	25, // 26: tunnel.TunnelControl.metrics:type_name -> tunnel.TunnelMetrics
-	21, // 27: tunnel.TunnelControl.establish_request:type_name -> tunnel.TunnelEstablishRequest
-	20, // 28: tunnel.TunnelControl.cancel_request:type_name -> tunnel.CancelRequest
-	0,  // 29: tunnel.TunnelEstablishRequest.tunnel_type:type_name -> tunnel.TunnelType
-	6,  // 30: tunnel.ErrorMessage.type:type_name -> tunnel.ErrorMessage.ErrorType
-	1,  // 31: tunnel.TunnelStatus.state:type_name -> tunnel.TunnelState
-	2,  // 32: tunnel.HealthCheckResponse.status:type_name -> tunnel.HealthStatus
-	25, // 33: tunnel.HealthCheckResponse.metrics:type_name -> tunnel.TunnelMetrics
-	36, // 34: tunnel.HealthCheckResponse.details:type_name -> tunnel.HealthCheckResponse.DetailsEntry
-	3,  // 35: tunnel.RequestMetadata.type:type_name -> tunnel.RequestType
-	4,  // 36: tunnel.RequestMetadata.priority:type_name -> tunnel.Priority
-	5,  // 37: tunnel.ResponseMetadata.cache_status:type_name -> tunnel.CacheStatus
-	7,  // 38: tunnel.TunnelService.EstablishTunnel:input_type -> tunnel.TunnelMessage
-	8,  // 39: tunnel.TunnelService.ControlChannel:input_type -> tunnel.ControlMessage
-	14, // 40: tunnel.TunnelService.StreamLargeFile:input_type -> tunnel.LargeFileRequest
-	28, // 41: tunnel.TunnelService.HealthCheck:input_type -> tunnel.HealthCheckRequest
-	7,  // 42: tunnel.TunnelService.EstablishTunnel:output_type -> tunnel.TunnelMessage
-	8,  // 43: tunnel.TunnelService.ControlChannel:output_type -> tunnel.ControlMessage
-	15, // 44: tunnel.TunnelService.StreamLargeFile:output_type -> tunnel.LargeFileChunk
-	29, // 45: tunnel.TunnelService.HealthCheck:output_type -> tunnel.HealthCheckResponse
-	42, // [42:46] is the sub-list for method output_type
-	38, // [38:42] is the sub-list for method input_type
-	38, // [38:38] is the sub-list for extension type_name
-	38, // [38:38] is the sub-list for extension extendee
-	0,  // [0:38] is the sub-list for field type_name
+	19, // 9: tunnel.TunnelMessage.websocket_start:type_name -> tunnel.WebSocketStart
+	20, // 10: tunnel.TunnelMessage.websocket_data:type_name -> tunnel.WebSocketData
+	21, // 11: tunnel.TunnelMessage.websocket_end:type_name -> tunnel.WebSocketEnd
+	9,  // 12: tunnel.ControlMessage.handshake:type_name -> tunnel.ControlHandshake
+	23, // 13: tunnel.ControlMessage.cancel:type_name -> tunnel.CancelRequest
+	31, // 14: tunnel.ControlMessage.health_check:type_name -> tunnel.HealthCheckRequest
+	32, // 15: tunnel.ControlMessage.health_response:type_name -> tunnel.HealthCheckResponse
+	29, // 16: tunnel.ControlMessage.ping:type_name -> tunnel.ControlPing
+	30, // 17: tunnel.ControlMessage.pong:type_name -> tunnel.ControlPong
+	11, // 18: tunnel.TunnelHandshake.capabilities:type_name -> tunnel.TunnelCapabilities
+	35, // 19: tunnel.HTTPRequest.headers:type_name -> tunnel.HTTPRequest.HeadersEntry
+	33, // 20: tunnel.HTTPRequest.metadata:type_name -> tunnel.RequestMetadata
+	36, // 21: tunnel.HTTPResponse.headers:type_name -> tunnel.HTTPResponse.HeadersEntry
+	34, // 22: tunnel.HTTPResponse.metadata:type_name -> tunnel.ResponseMetadata
+	37, // 23: tunnel.HTTPResponse.trailers:type_name -> tunnel.HTTPResponse.TrailersEntry
+	12, // 24: tunnel.LargeFileRequest.http_request:type_name -> tunnel.HTTPRequest
+	38, // 25: tunnel.LargeFileChunk.headers:type_name -> tunnel.LargeFileChunk.HeadersEntry
+	39, // 26: tunnel.HTTPRequestStart.headers:type_name -> tunnel.HTTPRequestStart.HeadersEntry
+	40, // 27: tunnel.WebSocketStart.headers:type_name -> tunnel.WebSocketStart.HeadersEntry
+	10, // 28: tunnel.TunnelControl.handshake:type_name -> tunnel.TunnelHandshake
+	27, // 29: tunnel.TunnelControl.status:type_name -> tunnel.TunnelStatus
+	25, // 30: tunnel.TunnelControl.config:type_name -> tunnel.TunnelConfig
+	28, // 31: tunnel.TunnelControl.metrics:type_name -> tunnel.TunnelMetrics
+	24, // 32: tunnel.TunnelControl.establish_request:type_name -> tunnel.TunnelEstablishRequest
+	23, // 33: tunnel.TunnelControl.cancel_request:type_name -> tunnel.CancelRequest
+	0,  // 34: tunnel.TunnelEstablishRequest.tunnel_type:type_name -> tunnel.TunnelType
+	6,  // 35: tunnel.ErrorMessage.type:type_name -> tunnel.ErrorMessage.ErrorType
+	1,  // 36: tunnel.TunnelStatus.state:type_name -> tunnel.TunnelState
+	11, // 37: tunnel.TunnelStatus.capabilities:type_name -> tunnel.TunnelCapabilities
+	2,  // 38: tunnel.HealthCheckResponse.status:type_name -> tunnel.HealthStatus
+	28, // 39: tunnel.HealthCheckResponse.metrics:type_name -> tunnel.TunnelMetrics
+	41, // 40: tunnel.HealthCheckResponse.details:type_name -> tunnel.HealthCheckResponse.DetailsEntry
+	3,  // 41: tunnel.RequestMetadata.type:type_name -> tunnel.RequestType
+	4,  // 42: tunnel.RequestMetadata.priority:type_name -> tunnel.Priority
+	5,  // 43: tunnel.ResponseMetadata.cache_status:type_name -> tunnel.CacheStatus
+	7,  // 44: tunnel.TunnelService.EstablishTunnel:input_type -> tunnel.TunnelMessage
+	8,  // 45: tunnel.TunnelService.ControlChannel:input_type -> tunnel.ControlMessage
+	14, // 46: tunnel.TunnelService.StreamLargeFile:input_type -> tunnel.LargeFileRequest
+	31, // 47: tunnel.TunnelService.HealthCheck:input_type -> tunnel.HealthCheckRequest
+	7,  // 48: tunnel.TunnelService.EstablishTunnel:output_type -> tunnel.TunnelMessage
+	8,  // 49: tunnel.TunnelService.ControlChannel:output_type -> tunnel.ControlMessage
+	15, // 50: tunnel.TunnelService.StreamLargeFile:output_type -> tunnel.LargeFileChunk
+	32, // 51: tunnel.TunnelService.HealthCheck:output_type -> tunnel.HealthCheckResponse
+	48, // [48:52] is the sub-list for method output_type
+	44, // [44:48] is the sub-list for method input_type
+	44, // [44:44] is the sub-list for extension type_name
+	44, // [44:44] is the sub-list for extension extendee
+	0,  // [0:44] is the sub-list for field type_name
 }
 
 func init() { file_tunnel_proto_init() }
@@ -2939,6 +3312,9 @@ func file_tunnel_proto_init() {
 		(*TunnelMessage_HttpRequestStart)(nil),
 		(*TunnelMessage_HttpRequestChunk)(nil),
 		(*TunnelMessage_HttpRequestEnd)(nil),
+		(*TunnelMessage_WebsocketStart)(nil),
+		(*TunnelMessage_WebsocketData)(nil),
+		(*TunnelMessage_WebsocketEnd)(nil),
 	}
 	file_tunnel_proto_msgTypes[1].OneofWrappers = []any{
 		(*ControlMessage_Handshake)(nil),
@@ -2948,7 +3324,7 @@ func file_tunnel_proto_init() {
 		(*ControlMessage_Ping)(nil),
 		(*ControlMessage_Pong)(nil),
 	}
-	file_tunnel_proto_msgTypes[12].OneofWrappers = []any{
+	file_tunnel_proto_msgTypes[15].OneofWrappers = []any{
 		(*TunnelControl_Handshake)(nil),
 		(*TunnelControl_Status)(nil),
 		(*TunnelControl_Config)(nil),
@@ -2962,7 +3338,7 @@ func file_tunnel_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_tunnel_proto_rawDesc), len(file_tunnel_proto_rawDesc)),
 			NumEnums:      7,
-			NumMessages:   30,
+			NumMessages:   35,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -0,0 +1,175 @@
+package tunnel
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultProxyHeaderTimeout bounds how long a connection may take to send its PROXY header
+const DefaultProxyHeaderTimeout = 10 * time.Second
+
+// proxyV2Signature starts every PROXY protocol v2 header
+var proxyV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+// maxProxyV1Length is the longest v1 header line the spec allows, CRLF included
+const maxProxyV1Length = 107
+
+var errProxyHeaderMissing = errors.New("connection did not start with a PROXY protocol header")
+
+// NewProxyProtocolListener wraps a listener whose connections come from a load balancer
+// speaking the PROXY protocol (v1 or v2). Each connection's RemoteAddr is the client the
+// load balancer reported; connections without a valid header fail on first use, so only
+// enable this when every connection passes through such a load balancer.
+func NewProxyProtocolListener(inner net.Listener, headerTimeout time.Duration) net.Listener {
+	if headerTimeout <= 0 {
+		headerTimeout = DefaultProxyHeaderTimeout
+	}
+	return &proxyProtocolListener{Listener: inner, headerTimeout: headerTimeout}
+}
+
+type proxyProtocolListener struct {
+	net.Listener
+	headerTimeout time.Duration
+}
+
+// Accept returns the connection without waiting for its header, so a slow client can't
+// hold up the accept loop; the header is read on first Read or RemoteAddr
+func (l *proxyProtocolListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &proxyProtocolConn{Conn: conn, headerTimeout: l.headerTimeout}, nil
+}
+
+// proxyProtocolConn strips the PROXY header from a connection and reports its source address
+type proxyProtocolConn struct {
+	net.Conn
+	headerTimeout time.Duration
+
+	once   sync.Once
+	reader *bufio.Reader
+	remote net.Addr // nil when the header carried no address (LOCAL, UNKNOWN)
+	err    error
+}
+
+func (c *proxyProtocolConn) Read(p []byte) (int, error) {
+	c.once.Do(c.readHeader)
+	if c.err != nil {
+		return 0, c.err
+	}
+	return c.reader.Read(p)
+}
+
+func (c *proxyProtocolConn) RemoteAddr() net.Addr {
+	c.once.Do(c.readHeader)
+	if c.remote != nil {
+		return c.remote
+	}
+	return c.Conn.RemoteAddr()
+}
+
+func (c *proxyProtocolConn) readHeader() {
+	c.reader = bufio.NewReader(c.Conn)
+	c.Conn.SetReadDeadline(time.Now().Add(c.headerTimeout))
+	defer c.Conn.SetReadDeadline(time.Time{})
+
+	c.remote, c.err = parseProxyHeader(c.reader)
+	if c.err != nil {
+		c.err = fmt.Errorf("PROXY protocol from %s: %w", c.Conn.RemoteAddr(), c.err)
+	}
+}
+
+// parseProxyHeader consumes a v1 or v2 header from r and returns the source address it
+// names, or nil for headers that don't carry one (health checks from the load balancer)
+func parseProxyHeader(r *bufio.Reader) (net.Addr, error) {
+	first, err := r.Peek(1)
+	if err != nil {
+		return nil, err
+	}
+	switch first[0] {
+	case 'P':
+		return parseProxyV1(r)
+	case proxyV2Signature[0]:
+		return parseProxyV2(r)
+	}
+	return nil, errProxyHeaderMissing
+}
+
+// parseProxyV1 reads "PROXY TCP4|TCP6|UNKNOWN src dst sport dport\r\n"
+func parseProxyV1(r *bufio.Reader) (net.Addr, error) {
+	var line []byte
+	for !bytes.HasSuffix(line, []byte("\r\n")) {
+		if len(line) >= maxProxyV1Length {
+			return nil, errors.New("v1 header too long")
+		}
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		line = append(line, b)
+	}
+
+	fields := strings.Fields(string(line))
+	if len(fields) < 2 || fields[0] != "PROXY" {
+		return nil, errProxyHeaderMissing
+	}
+	if fields[1] == "UNKNOWN" {
+		return nil, nil
+	}
+	if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		return nil, fmt.Errorf("malformed v1 header %q", strings.TrimSpace(string(line)))
+	}
+	ip := net.ParseIP(fields[2])
+	port, err := strconv.ParseUint(fields[4], 10, 16)
+	if ip == nil || err != nil {
+		return nil, fmt.Errorf("malformed v1 source address %s:%s", fields[2], fields[4])
+	}
+	return &net.TCPAddr{IP: ip, Port: int(port)}, nil
+}
+
+// parseProxyV2 reads the binary header: signature, version/command, family, length, addresses
+func parseProxyV2(r *bufio.Reader) (net.Addr, error) {
+	header := make([]byte, 16)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(header[:12], proxyV2Signature) {
+		return nil, errProxyHeaderMissing
+	}
+	if header[12]>>4 != 2 {
+		return nil, fmt.Errorf("unsupported v2 version %d", header[12]>>4)
+	}
+	payload := make([]byte, binary.BigEndian.Uint16(header[14:16]))
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, err
+	}
+
+	// LOCAL connections come from the load balancer itself
+	if header[12]&0x0F == 0 {
+		return nil, nil
+	}
+	switch header[13] >> 4 {
+	case 1: // AF_INET: src, dst (4 bytes each), sport, dport
+		if len(payload) < 12 {
+			return nil, errors.New("truncated v2 IPv4 addresses")
+		}
+		return &net.TCPAddr{IP: net.IP(payload[0:4]), Port: int(binary.BigEndian.Uint16(payload[8:10]))}, nil
+	case 2: // AF_INET6: src, dst (16 bytes each), sport, dport
+		if len(payload) < 36 {
+			return nil, errors.New("truncated v2 IPv6 addresses")
+		}
+		return &net.TCPAddr{IP: net.IP(payload[0:16]), Port: int(binary.BigEndian.Uint16(payload[32:34]))}, nil
+	}
+	// AF_UNSPEC or AF_UNIX: nothing useful to report
+	return nil, nil
+}
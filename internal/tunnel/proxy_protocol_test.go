@@ -0,0 +1,89 @@
+package tunnel
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseProxyHeader(t *testing.T) {
+	v2 := func(cmd, family byte, addrs []byte) string {
+		header := append([]byte{}, proxyV2Signature...)
+		header = append(header, 0x20|cmd, family, 0, 0)
+		binary.BigEndian.PutUint16(header[14:], uint16(len(addrs)))
+		return string(append(header, addrs...))
+	}
+	ipv4 := []byte{203, 0, 113, 7, 10, 0, 0, 1, 0x30, 0x39, 0x01, 0xBB} // 203.0.113.7:12345 -> 10.0.0.1:443
+	ipv6 := make([]byte, 36)
+	copy(ipv6, net.ParseIP("2001:db8::1"))
+	binary.BigEndian.PutUint16(ipv6[32:], 8080)
+
+	tests := []struct {
+		name    string
+		header  string
+		want    string // "" = no address in the header
+		wantErr bool
+	}{
+		{"v1 TCP4", "PROXY TCP4 203.0.113.7 10.0.0.1 12345 443\r\n", "203.0.113.7:12345", false},
+		{"v1 TCP6", "PROXY TCP6 2001:db8::1 2001:db8::2 8080 443\r\n", "[2001:db8::1]:8080", false},
+		{"v1 UNKNOWN", "PROXY UNKNOWN\r\n", "", false},
+		{"v1 malformed", "PROXY TCP4 nope 10.0.0.1 1 2\r\n", "", true},
+		{"v1 too long", "PROXY TCP4 " + strings.Repeat("1", 120) + "\r\n", "", true},
+		{"v2 IPv4", v2(1, 0x11, ipv4), "203.0.113.7:12345", false},
+		{"v2 IPv6", v2(1, 0x21, ipv6), "[2001:db8::1]:8080", false},
+		{"v2 LOCAL", v2(0, 0x00, nil), "", false},
+		{"v2 truncated", v2(1, 0x11, ipv4[:6]), "", true},
+		{"no header", "GET / HTTP/1.1\r\n", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := bufio.NewReader(strings.NewReader(tt.header + "payload"))
+			addr, err := parseProxyHeader(r)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			got := ""
+			if addr != nil {
+				got = addr.String()
+			}
+			if got != tt.want {
+				t.Errorf("addr = %q, want %q", got, tt.want)
+			}
+			if rest, _ := io.ReadAll(r); string(rest) != "payload" {
+				t.Errorf("data after the header = %q, want %q", rest, "payload")
+			}
+		})
+	}
+}
+
+func TestProxyProtocolConn(t *testing.T) {
+	serverConn, clientConn := net.Pipe()
+	defer clientConn.Close()
+	conn := &proxyProtocolConn{Conn: serverConn, headerTimeout: time.Second}
+	defer conn.Close()
+	go io.WriteString(clientConn, "PROXY TCP4 198.51.100.9 10.0.0.1 4000 8081\r\nGET / HTTP/1.1\r\n")
+
+	if ip := (&HybridTunnelRouter{}).extractClientIP(conn); ip != "198.51.100.9" {
+		t.Errorf("client IP = %q, want the address from the PROXY header", ip)
+	}
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil || line != "GET / HTTP/1.1\r\n" {
+		t.Errorf("first line = %q (%v), want the request without the header", line, err)
+	}
+
+	// Without a header the connection is refused rather than attributed to the balancer
+	serverConn, clientConn = net.Pipe()
+	defer clientConn.Close()
+	conn = &proxyProtocolConn{Conn: serverConn, headerTimeout: time.Second}
+	go io.WriteString(clientConn, "GET / HTTP/1.1\r\n")
+	if _, err := conn.Read(make([]byte, 16)); err == nil {
+		t.Error("expected a connection without a PROXY header to fail")
+	}
+}
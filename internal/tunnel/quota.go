@@ -1,6 +1,13 @@
 package tunnel
 
-import "context"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/osa911/giraffecloud/internal/tunnel/proto"
+)
 
 type QuotaDecision string
 
@@ -20,3 +27,32 @@ type QuotaResult struct {
 type QuotaChecker interface {
 	CheckUser(ctx context.Context, userID uint32) (QuotaResult, error)
 }
+
+// ErrQuotaExceeded is returned when the server refuses a tunnel because its owner has
+// used up their monthly bandwidth quota
+var ErrQuotaExceeded = errors.New("monthly bandwidth quota reached")
+
+// QuotaRetryInterval is how long a client over quota waits before asking again. The
+// quota only frees up when the month rolls over or the plan is upgraded, so there is no
+// point retrying at the usual pace.
+const QuotaRetryInterval = 15 * time.Minute
+
+// quotaExceededMessage is the handshake response refusing a tunnel whose owner is over quota
+func quotaExceededMessage(requestID, domain string, res QuotaResult) *proto.TunnelMessage {
+	return &proto.TunnelMessage{
+		RequestId: requestID,
+		Timestamp: time.Now().Unix(),
+		MessageType: &proto.TunnelMessage_Control{
+			Control: &proto.TunnelControl{
+				ControlType: &proto.TunnelControl_Status{
+					Status: &proto.TunnelStatus{
+						State:        proto.TunnelState_TUNNEL_STATE_QUOTA_EXCEEDED,
+						Domain:       domain,
+						ErrorMessage: fmt.Sprintf("used %d of %d bytes this month", res.UsedBytes, res.LimitBytes),
+						ErrorCode:    string(HandshakeQuotaExceeded),
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,34 @@
+package tunnel
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type blockingQuota struct{}
+
+func (blockingQuota) CheckUser(ctx context.Context, userID uint32) (QuotaResult, error) {
+	return QuotaResult{Decision: QuotaBlock, UsedBytes: 2048, LimitBytes: 1024}, nil
+}
+
+func TestHandshakeRefusedOverQuota(t *testing.T) {
+	initTestLogger(t)
+	ts, err := NewTestServer()
+	if err != nil {
+		t.Fatalf("NewTestServer: %v", err)
+	}
+	defer ts.Close()
+	t.Setenv("GIRAFFECLOUD_HOME", ts.ConfigHome)
+	ts.grpc.SetQuotaChecker(blockingQuota{})
+
+	client := NewGRPCTunnelClient(ts.ServerAddr(), ts.Domain, ts.Token, int32(ts.LocalPort()), nil)
+	defer client.Stop()
+	err = client.Start()
+	if !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("Start() error = %v, want %v", err, ErrQuotaExceeded)
+	}
+	if ts.grpc.IsTunnelActive(ts.Domain) {
+		t.Error("tunnel registered despite the quota")
+	}
+}
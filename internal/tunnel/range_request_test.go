@@ -0,0 +1,73 @@
+package tunnel
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRangeRequestPartialContent(t *testing.T) {
+	if testing.Short() {
+		t.Skip("end-to-end tunnel test")
+	}
+	initTestLogger(t)
+	ts, err := NewTestServer()
+	if err != nil {
+		t.Fatalf("NewTestServer: %v", err)
+	}
+	defer ts.Close()
+	t.Setenv("GIRAFFECLOUD_HOME", ts.ConfigHome)
+
+	// Larger than a single gRPC message, so long ranges only fit the chunked path
+	clip := bytes.Repeat([]byte("0123456789abcdef"), ChunkedStreamingThreshold/16+64*1024)
+	ts.SetHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "clip.bin", time.Time{}, bytes.NewReader(clip))
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	tun, err := ts.Connect(ctx)
+	if err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	defer tun.Disconnect()
+
+	ranges := []struct {
+		header string
+		start  int
+		end    int
+	}{
+		{"bytes=100-199", 100, 199},
+		{"bytes=-4096", len(clip) - 4096, len(clip) - 1},
+		{"bytes=1000-", 1000, len(clip) - 1},
+	}
+	for _, rg := range ranges {
+		req, _ := http.NewRequest(http.MethodGet, ts.URL+"/clip.bin", nil)
+		req.Header.Set("Range", rg.header)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET %s: %v", rg.header, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		wantRange := "bytes " + strconv.Itoa(rg.start) + "-" + strconv.Itoa(rg.end) + "/" + strconv.Itoa(len(clip))
+		if resp.StatusCode != http.StatusPartialContent || resp.Header.Get("Content-Range") != wantRange {
+			t.Errorf("%s = %d %q, want 206 %q", rg.header, resp.StatusCode, resp.Header.Get("Content-Range"), wantRange)
+		}
+		if resp.ContentLength != int64(len(body)) || !bytes.Equal(body, clip[rg.start:rg.end+1]) {
+			t.Errorf("%s: got %d bytes (Content-Length %d), want %d bytes of the clip", rg.header, len(body), resp.ContentLength, rg.end-rg.start+1)
+		}
+	}
+
+	if !isRangeRequest(&http.Request{Method: http.MethodGet, Header: http.Header{"Range": {"bytes=0-"}}}) {
+		t.Error("a GET with Range should be a range request")
+	}
+	if isRangeRequest(&http.Request{Method: http.MethodHead, Header: http.Header{"Range": {"bytes=0-"}}}) {
+		t.Error("a HEAD should not be a range request")
+	}
+}
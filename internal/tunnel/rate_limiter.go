@@ -0,0 +1,150 @@
+package tunnel
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// rateLimiterSweepInterval is how often Reserve drops buckets that have refilled
+const rateLimiterSweepInterval = time.Minute
+
+// RateLimiter is a token-bucket limiter keyed by domain, with optional
+// per-client-IP buckets inside each domain. Limits are given in requests per
+// minute (the refill rate) plus a burst size; an RPM <= 0 disables that bucket.
+// Buckets that sat idle until full are swept, since a fresh one behaves the same.
+type RateLimiter struct {
+	limiters       map[string]*rate.Limiter            // domain -> bucket
+	clientLimiters map[string]map[string]*rate.Limiter // domain -> client IP -> bucket
+	mu             sync.Mutex
+	rpm            int
+	burst          int
+	clientRPM      int
+	clientBurst    int
+	lastSweep      time.Time
+	now            func() time.Time
+}
+
+// NewRateLimiter creates a new rate limiter
+func NewRateLimiter(rpm, burst int) *RateLimiter {
+	return &RateLimiter{
+		limiters:       make(map[string]*rate.Limiter),
+		clientLimiters: make(map[string]map[string]*rate.Limiter),
+		rpm:            rpm,
+		burst:          burst,
+		now:            time.Now,
+	}
+}
+
+// SetClientLimit enables per-client-IP buckets within each domain (rpm <= 0 disables them)
+func (rl *RateLimiter) SetClientLimit(rpm, burst int) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rl.clientRPM = rpm
+	rl.clientBurst = burst
+	rl.clientLimiters = make(map[string]map[string]*rate.Limiter)
+}
+
+// Allow checks if the request is allowed for the given domain
+func (rl *RateLimiter) Allow(domain string) bool {
+	allowed, _ := rl.Reserve(domain, "")
+	return allowed
+}
+
+// Reserve takes a token from the domain bucket and, when per-client limits are
+// enabled and clientIP is set, from that client's bucket. When either bucket is
+// empty no token is taken and the time until the request would be allowed is returned.
+func (rl *RateLimiter) Reserve(domain, clientIP string) (bool, time.Duration) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := rl.now()
+	if now.Sub(rl.lastSweep) >= rateLimiterSweepInterval {
+		rl.sweep(now)
+	}
+
+	var buckets []*rate.Limiter
+	if rl.rpm > 0 {
+		limiter, exists := rl.limiters[domain]
+		if !exists {
+			limiter = newBucket(rl.rpm, rl.burst)
+			rl.limiters[domain] = limiter
+		}
+		buckets = append(buckets, limiter)
+	}
+	if rl.clientRPM > 0 && clientIP != "" {
+		clients, exists := rl.clientLimiters[domain]
+		if !exists {
+			clients = make(map[string]*rate.Limiter)
+			rl.clientLimiters[domain] = clients
+		}
+		limiter, exists := clients[clientIP]
+		if !exists {
+			limiter = newBucket(rl.clientRPM, rl.clientBurst)
+			clients[clientIP] = limiter
+		}
+		buckets = append(buckets, limiter)
+	}
+
+	reservations := make([]*rate.Reservation, 0, len(buckets))
+	var wait time.Duration
+	for _, bucket := range buckets {
+		r := bucket.ReserveN(now, 1)
+		reservations = append(reservations, r)
+		if delay := r.DelayFrom(now); delay > wait {
+			wait = delay
+		}
+	}
+
+	if wait > 0 {
+		// Give the tokens back so a rejected request doesn't push the other bucket further out
+		for _, r := range reservations {
+			r.CancelAt(now)
+		}
+		return false, wait
+	}
+	return true, 0
+}
+
+// Reset drops the domain's buckets, e.g. when its tunnel disconnects
+func (rl *RateLimiter) Reset(domain string) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	delete(rl.limiters, domain)
+	delete(rl.clientLimiters, domain)
+}
+
+// sweep drops buckets back at full burst, so visitors and domains that stopped sending
+// don't hold memory forever. Callers must hold rl.mu.
+func (rl *RateLimiter) sweep(now time.Time) {
+	rl.lastSweep = now
+	for domain, limiter := range rl.limiters {
+		if isFull(limiter, now) {
+			delete(rl.limiters, domain)
+		}
+	}
+	for domain, clients := range rl.clientLimiters {
+		for clientIP, limiter := range clients {
+			if isFull(limiter, now) {
+				delete(clients, clientIP)
+			}
+		}
+		if len(clients) == 0 {
+			delete(rl.clientLimiters, domain)
+		}
+	}
+}
+
+// isFull reports whether a bucket has refilled to its burst, i.e. is idle
+func isFull(limiter *rate.Limiter, now time.Time) bool {
+	return limiter.TokensAt(now) >= float64(limiter.Burst())
+}
+
+// newBucket creates a token bucket refilling at rpm requests per minute
+func newBucket(rpm, burst int) *rate.Limiter {
+	if burst < 1 {
+		burst = 1 // A zero burst would reject every request
+	}
+	return rate.NewLimiter(rate.Limit(rpm)/60, burst) // Convert RPM to per-second
+}
@@ -0,0 +1,138 @@
+package tunnel
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestRateLimiter(rpm, burst int) (*RateLimiter, *time.Time) {
+	now := time.Unix(1700000000, 0)
+	rl := NewRateLimiter(rpm, burst)
+	rl.now = func() time.Time { return now }
+	return rl, &now
+}
+
+func TestRateLimiterBurstAndRetryAfter(t *testing.T) {
+	rl, now := newTestRateLimiter(60, 2) // 1 token per second
+
+	for i := 0; i < 2; i++ {
+		if ok, _ := rl.Reserve("app.example.com", ""); !ok {
+			t.Fatalf("request %d within burst was rejected", i+1)
+		}
+	}
+
+	ok, retryAfter := rl.Reserve("app.example.com", "")
+	if ok {
+		t.Fatal("request over burst was allowed")
+	}
+	if retryAfter <= 0 || retryAfter > time.Second {
+		t.Fatalf("retryAfter = %v, want (0, 1s]", retryAfter)
+	}
+
+	// Other domains have their own bucket
+	if ok, _ := rl.Reserve("api.example.com", ""); !ok {
+		t.Fatal("other domain was limited")
+	}
+
+	*now = now.Add(time.Second)
+	if ok, _ := rl.Reserve("app.example.com", ""); !ok {
+		t.Fatal("request after refill was rejected")
+	}
+}
+
+func TestRateLimiterPerClient(t *testing.T) {
+	rl, _ := newTestRateLimiter(600, 10)
+	rl.SetClientLimit(60, 1)
+
+	if ok, _ := rl.Reserve("app.example.com", "10.0.0.1"); !ok {
+		t.Fatal("first request from client was rejected")
+	}
+	if ok, _ := rl.Reserve("app.example.com", "10.0.0.1"); ok {
+		t.Fatal("second request from same client was allowed")
+	}
+	if ok, _ := rl.Reserve("app.example.com", "10.0.0.2"); !ok {
+		t.Fatal("request from another client was rejected")
+	}
+
+	// The rejected request must not have used a domain token: 2 taken, 8 left
+	for i := 0; i < 8; i++ {
+		if ok, _ := rl.Reserve("app.example.com", ""); !ok {
+			t.Fatalf("domain request %d was rejected", i+1)
+		}
+	}
+	if ok, _ := rl.Reserve("app.example.com", ""); ok {
+		t.Fatal("domain bucket should be empty")
+	}
+}
+
+func TestRateLimiterReset(t *testing.T) {
+	rl, _ := newTestRateLimiter(60, 1)
+	rl.SetClientLimit(60, 1)
+
+	rl.Reserve("app.example.com", "10.0.0.1")
+	if ok, _ := rl.Reserve("app.example.com", "10.0.0.1"); ok {
+		t.Fatal("expected limit before reset")
+	}
+
+	rl.Reset("app.example.com")
+	if ok, _ := rl.Reserve("app.example.com", "10.0.0.1"); !ok {
+		t.Fatal("request after reset was rejected")
+	}
+}
+
+func TestRateLimiterDisabled(t *testing.T) {
+	rl, _ := newTestRateLimiter(0, 0)
+	for i := 0; i < 100; i++ {
+		if !rl.Allow("app.example.com") {
+			t.Fatal("disabled limiter rejected a request")
+		}
+	}
+}
+
+func TestRateLimiterSweepsIdleBuckets(t *testing.T) {
+	rl, now := newTestRateLimiter(1, 2) // Refills one token per minute
+	rl.SetClientLimit(1, 2)
+
+	rl.Reserve("app.example.com", "10.0.0.1")
+	rl.Reserve("api.example.com", "10.0.0.2")
+
+	// api.example.com keeps sending; app.example.com goes quiet and refills
+	*now = now.Add(rateLimiterSweepInterval - time.Second)
+	rl.Reserve("api.example.com", "10.0.0.2")
+	*now = now.Add(time.Second)
+	rl.Reserve("api.example.com", "10.0.0.2")
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	if _, ok := rl.limiters["app.example.com"]; ok {
+		t.Error("idle domain bucket was not swept")
+	}
+	if _, ok := rl.clientLimiters["app.example.com"]; ok {
+		t.Error("idle client buckets were not swept")
+	}
+	if _, ok := rl.clientLimiters["api.example.com"]["10.0.0.2"]; !ok {
+		t.Error("busy client bucket was swept")
+	}
+}
+
+func TestCheckRateLimitSkipsInactiveTunnels(t *testing.T) {
+	rl, _ := newTestRateLimiter(1, 1)
+	s := &GRPCTunnelServer{
+		rateLimiter:   rl,
+		tunnelStreams: map[string]*TunnelStream{"app.example.com": {connected: true}},
+	}
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := s.CheckRateLimit("offline.example.com", "10.0.0.1"); !ok {
+			t.Fatal("domain without an active tunnel was rate limited")
+		}
+	}
+	if _, ok := rl.limiters["offline.example.com"]; ok {
+		t.Error("bucket created for a domain without an active tunnel")
+	}
+
+	s.CheckRateLimit("app.example.com", "10.0.0.1")
+	if ok, _ := s.CheckRateLimit("app.example.com", "10.0.0.1"); ok {
+		t.Error("active tunnel was not rate limited")
+	}
+}
@@ -0,0 +1,69 @@
+package tunnel
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// refreshPattern splits a Refresh header ("5; url=http://localhost:3000/") into the delay
+// and separator, an optional quote, and the URL
+var refreshPattern = regexp.MustCompile(`(?i)^(\s*[\d.]*\s*[;,]\s*(?:url\s*=\s*)?)(['"]?)([^'"]*?)(['"]?\s*)$`)
+
+// rewriteRedirects points Location and Refresh headers that name a loopback address at the
+// public tunnel domain, so redirects built from the origin's own address (localhost:PORT)
+// keep the browser on the tunnel (HybridRouterConfig.RewriteRedirects)
+func (r *HybridTunnelRouter) rewriteRedirects(response *http.Response, domain string, req *http.Request) {
+	if !r.config.RewriteRedirects {
+		return
+	}
+
+	scheme := "https"
+	if proto, _, _ := strings.Cut(req.Header.Get("X-Forwarded-Proto"), ","); strings.TrimSpace(proto) == "http" {
+		scheme = "http"
+	}
+
+	if location := response.Header.Get("Location"); location != "" {
+		if rewritten, ok := rewriteLoopbackURL(location, scheme, domain); ok {
+			r.logger.Debug("[HYBRID] Rewrote redirect %s -> %s", location, rewritten)
+			response.Header.Set("Location", rewritten)
+		}
+	}
+	if refresh := response.Header.Get("Refresh"); refresh != "" {
+		match := refreshPattern.FindStringSubmatch(refresh)
+		if match == nil {
+			return
+		}
+		if rewritten, ok := rewriteLoopbackURL(match[3], scheme, domain); ok {
+			response.Header.Set("Refresh", match[1]+match[2]+rewritten+match[4])
+		}
+	}
+}
+
+// rewriteLoopbackURL replaces the scheme and host of an absolute (or scheme-relative) URL
+// pointing at a loopback address with scheme and domain; other URLs are left alone
+func rewriteLoopbackURL(raw, scheme, domain string) (string, bool) {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" || !isLoopbackHost(u.Hostname()) {
+		return raw, false
+	}
+	if u.Scheme != "" {
+		u.Scheme = scheme
+	}
+	u.Host = domain
+	u.User = nil
+	return u.String(), true
+}
+
+// isLoopbackHost reports whether host is localhost or a loopback/unspecified IP, i.e. an
+// address that only means something on the tunnel client's machine
+func isLoopbackHost(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && (ip.IsLoopback() || ip.IsUnspecified())
+}
@@ -0,0 +1,54 @@
+package tunnel
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/osa911/giraffecloud/internal/logging"
+)
+
+func TestRewriteRedirects(t *testing.T) {
+	initTestLogger(t)
+	tests := []struct {
+		header, value, want string
+	}{
+		{"Location", "http://localhost:3000/login?next=%2Fhome", "https://app.example.com/login?next=%2Fhome"},
+		{"Location", "http://127.0.0.1:8080/", "https://app.example.com/"},
+		{"Location", "http://[::1]:8080/a#top", "https://app.example.com/a#top"},
+		{"Location", "http://0.0.0.0:5000/", "https://app.example.com/"},
+		{"Location", "//localhost:3000/x", "//app.example.com/x"},
+		{"Location", "/relative/path", "/relative/path"},
+		{"Location", "https://accounts.google.com/o/oauth2", "https://accounts.google.com/o/oauth2"},
+		{"Refresh", "5; url=http://localhost:3000/done", "5; url=https://app.example.com/done"},
+		{"Refresh", "0;URL='http://localhost:3000/'", "0;URL='https://app.example.com/'"},
+		{"Refresh", "10", "10"},
+	}
+
+	r := &HybridTunnelRouter{config: &HybridRouterConfig{RewriteRedirects: true}, logger: logging.GetGlobalLogger()}
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	for _, tt := range tests {
+		response := &http.Response{StatusCode: http.StatusFound, Header: http.Header{}}
+		response.Header.Set(tt.header, tt.value)
+		r.rewriteRedirects(response, "app.example.com", req)
+		if got := response.Header.Get(tt.header); got != tt.want {
+			t.Errorf("%s: %q rewritten to %q, want %q", tt.header, tt.value, got, tt.want)
+		}
+	}
+
+	// The scheme follows the client's connection
+	req.Header.Set("X-Forwarded-Proto", "http")
+	response := &http.Response{Header: http.Header{"Location": {"https://localhost:3000/"}}}
+	r.rewriteRedirects(response, "app.example.com", req)
+	if got := response.Header.Get("Location"); got != "http://app.example.com/" {
+		t.Errorf("Location = %q, want http://app.example.com/", got)
+	}
+
+	// Off unless configured
+	r.config.RewriteRedirects = false
+	response = &http.Response{Header: http.Header{"Location": {"http://localhost:3000/"}}}
+	r.rewriteRedirects(response, "app.example.com", req)
+	if got := response.Header.Get("Location"); got != "http://localhost:3000/" {
+		t.Errorf("Location rewritten to %q with RewriteRedirects off", got)
+	}
+}
@@ -0,0 +1,426 @@
+package tunnel
+
+import (
+	"bytes"
+	"container/list"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/osa911/giraffecloud/internal/tunnel/proto"
+)
+
+// DefaultResponseCacheTTL caps how long a cached response is served without asking the
+// origin again (GRPCTunnelConfig.ResponseCacheTTL)
+const DefaultResponseCacheTTL = 5 * time.Minute
+
+// CacheStatusHeader tells visitors how the response cache handled a request: HIT, MISS,
+// STALE (revalidated with the origin) or BYPASS
+const CacheStatusHeader = "X-Cache-Status"
+
+// cacheableStatuses are the statuses stored when the origin allows it (RFC 9111 section 4.2.2)
+var cacheableStatuses = map[int]bool{
+	http.StatusOK:                   true,
+	http.StatusNonAuthoritativeInfo: true,
+	http.StatusMultipleChoices:      true,
+	http.StatusMovedPermanently:     true,
+	http.StatusPermanentRedirect:    true,
+	http.StatusNotFound:             true,
+	http.StatusGone:                 true,
+}
+
+// cachedResponse is a stored origin response
+type cachedResponse struct {
+	key    string
+	domain string
+	status int
+	header http.Header
+	body   []byte
+
+	// Request header values the response varies on (its Vary header)
+	vary map[string]string
+
+	storedAt time.Time
+	expires  time.Time // Fresh until then, revalidated with the origin after
+}
+
+// responseCache is an in-memory LRU of GET responses, bounded by total body size. It
+// follows the origin's Cache-Control/Expires within maxTTL, never stores no-store or
+// private responses, and revalidates stale entries with ETag/Last-Modified.
+type responseCache struct {
+	maxBytes int64
+	maxTTL   time.Duration
+
+	mu      sync.Mutex
+	size    int64
+	entries map[string]*list.Element // By key, values are *cachedResponse
+	lru     *list.List               // Most recently used first
+
+	// Requests by cache status
+	counts map[proto.CacheStatus]int64
+}
+
+// newResponseCache returns a cache of up to maxBytes of response bodies, or nil if
+// maxBytes is 0 (0 maxTTL = DefaultResponseCacheTTL)
+func newResponseCache(maxBytes int64, maxTTL time.Duration) *responseCache {
+	if maxBytes <= 0 {
+		return nil
+	}
+	if maxTTL <= 0 {
+		maxTTL = DefaultResponseCacheTTL
+	}
+	return &responseCache{
+		maxBytes: maxBytes,
+		maxTTL:   maxTTL,
+		entries:  make(map[string]*list.Element),
+		lru:      list.New(),
+		counts:   make(map[proto.CacheStatus]int64),
+	}
+}
+
+// responseCacheKey identifies a cached resource; the domain comes first so purgeDomain can find it
+func responseCacheKey(domain string, req *http.Request) string {
+	return domain + " " + req.URL.RequestURI()
+}
+
+// cacheableRequest reports whether a request may be answered from, or stored in, the cache:
+// plain GETs without credentials whose sender didn't ask to skip caches
+func cacheableRequest(req *http.Request) bool {
+	if req.Method != http.MethodGet || req.Header.Get("Authorization") != "" || req.Header.Get("Range") != "" {
+		return false
+	}
+	directives := parseCacheControl(req.Header)
+	if _, ok := directives["no-store"]; ok {
+		return false
+	}
+	if _, ok := directives["no-cache"]; ok {
+		return false
+	}
+	return !strings.EqualFold(req.Header.Get("Pragma"), "no-cache")
+}
+
+// lookup returns the entry for key matching the request's varied headers, if any
+func (c *responseCache) lookup(key string, req *http.Request) *cachedResponse {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	entry := elem.Value.(*cachedResponse)
+	for name, value := range entry.vary {
+		if req.Header.Get(name) != value {
+			return nil
+		}
+	}
+	c.lru.MoveToFront(elem)
+	return entry
+}
+
+// count records how a request was handled, for metrics
+func (c *responseCache) count(status proto.CacheStatus) {
+	c.mu.Lock()
+	c.counts[status]++
+	c.mu.Unlock()
+}
+
+// Stats returns the request counts by cache status and the cached body bytes
+func (c *responseCache) Stats() (map[proto.CacheStatus]int64, int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	counts := make(map[proto.CacheStatus]int64, len(c.counts))
+	for status, n := range c.counts {
+		counts[status] = n
+	}
+	return counts, c.size
+}
+
+// store caches an origin response to req if the response allows it
+func (c *responseCache) store(key, domain string, req *http.Request, resp *http.Response, body []byte, now time.Time) {
+	ttl, ok := c.freshness(resp, now)
+	if !ok || int64(len(body)) > c.maxBytes || len(resp.Trailer) > 0 {
+		return // Cached copies are served without trailers
+	}
+
+	entry := &cachedResponse{
+		key:      key,
+		domain:   domain,
+		status:   resp.StatusCode,
+		header:   resp.Header.Clone(),
+		body:     body,
+		storedAt: now,
+		expires:  now.Add(ttl),
+	}
+	entry.header.Del(CacheStatusHeader)
+	for _, value := range resp.Header.Values("Vary") {
+		for _, name := range strings.Split(value, ",") {
+			if name = http.CanonicalHeaderKey(strings.TrimSpace(name)); name != "" {
+				if entry.vary == nil {
+					entry.vary = make(map[string]string)
+				}
+				entry.vary[name] = req.Header.Get(name)
+			}
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[key]; ok {
+		c.removeLocked(elem)
+	}
+	c.entries[key] = c.lru.PushFront(entry)
+	c.size += int64(len(body))
+	for c.size > c.maxBytes {
+		c.removeLocked(c.lru.Back())
+	}
+}
+
+// buffers reports whether a response is worth reading whole to store: its size is known
+// up front and fits, and the origin allows storing it
+func (c *responseCache) buffers(resp *http.Response, now time.Time) bool {
+	if resp.ContentLength < 0 || resp.ContentLength > c.maxBytes || len(resp.Trailer) > 0 {
+		return false
+	}
+	_, ok := c.freshness(resp, now)
+	return ok
+}
+
+// freshness returns how long a response stays fresh, and whether it may be stored at all.
+// Responses marked no-cache, or with no lifetime but a validator, are stored to be
+// revalidated on every use.
+func (c *responseCache) freshness(resp *http.Response, now time.Time) (time.Duration, bool) {
+	if !cacheableStatuses[resp.StatusCode] || resp.Header.Get("Set-Cookie") != "" || resp.Header.Get("Vary") == "*" {
+		return 0, false
+	}
+	directives := parseCacheControl(resp.Header)
+	for _, directive := range []string{"no-store", "private"} {
+		if _, ok := directives[directive]; ok {
+			return 0, false
+		}
+	}
+	hasValidator := resp.Header.Get("ETag") != "" || resp.Header.Get("Last-Modified") != ""
+
+	var ttl time.Duration
+	explicit := false
+	if _, ok := directives["no-cache"]; ok {
+		explicit = true
+	} else if age, ok := cacheControlSeconds(directives, "s-maxage"); ok {
+		ttl, explicit = age, true
+	} else if age, ok := cacheControlSeconds(directives, "max-age"); ok {
+		ttl, explicit = age, true
+	} else if expires := resp.Header.Get("Expires"); expires != "" {
+		explicit = true
+		if at, err := http.ParseTime(expires); err == nil {
+			date := now
+			if d, err := http.ParseTime(resp.Header.Get("Date")); err == nil {
+				date = d
+			}
+			ttl = at.Sub(date)
+		}
+	}
+
+	if !explicit && !hasValidator {
+		return 0, false
+	}
+	if ttl <= 0 {
+		// Only useful if it can be revalidated cheaply
+		return 0, hasValidator
+	}
+	return min(ttl, c.maxTTL), true
+}
+
+// refresh replaces a stale entry after the origin confirmed it with 304 Not Modified and
+// returns the updated entry. Entries are never modified once stored, so they can be served
+// without holding c.mu.
+func (c *responseCache) refresh(entry *cachedResponse, notModified *http.Response, now time.Time) *cachedResponse {
+	updated := *entry
+	updated.header = entry.header.Clone()
+	for _, name := range []string{"Cache-Control", "Expires", "Date", "ETag", "Last-Modified"} {
+		if value := notModified.Header.Get(name); value != "" {
+			updated.header.Set(name, value)
+		}
+	}
+	updated.storedAt = now
+	updated.expires = now
+	ttl, storable := c.freshness(&http.Response{StatusCode: updated.status, Header: updated.header}, now)
+	if storable {
+		updated.expires = now.Add(ttl)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[entry.key]; ok && elem.Value == entry {
+		if storable {
+			elem.Value = &updated
+		} else {
+			// The origin no longer allows caching it
+			c.removeLocked(elem)
+		}
+	}
+	return &updated
+}
+
+// purgeDomain drops every entry of a domain, e.g. when its tunnel goes away
+func (c *responseCache) purgeDomain(domain string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, elem := range c.entries {
+		if elem.Value.(*cachedResponse).domain == domain {
+			c.removeLocked(elem)
+		}
+	}
+}
+
+// removeLocked drops an entry. Callers hold c.mu.
+func (c *responseCache) removeLocked(elem *list.Element) {
+	entry := c.lru.Remove(elem).(*cachedResponse)
+	delete(c.entries, entry.key)
+	c.size -= int64(len(entry.body))
+}
+
+// fresh reports whether the entry may be served without asking the origin
+func (e *cachedResponse) fresh(now time.Time) bool {
+	return now.Before(e.expires)
+}
+
+// setValidators turns a copy of the visitor's request into a conditional request for the
+// entry, replacing the visitor's own conditions; the entry is served when the origin answers 304
+func (e *cachedResponse) setValidators(req *http.Request) {
+	req.Header.Del("If-None-Match")
+	req.Header.Del("If-Modified-Since")
+	if etag := e.header.Get("ETag"); etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	if modified := e.header.Get("Last-Modified"); modified != "" {
+		req.Header.Set("If-Modified-Since", modified)
+	}
+}
+
+// response builds the visitor's response from the entry: 304 if the visitor's
+// If-None-Match already names it, the stored response otherwise
+func (e *cachedResponse) response(req *http.Request, status proto.CacheStatus, now time.Time) *http.Response {
+	header := e.header.Clone()
+	header.Set("Age", strconv.Itoa(int(now.Sub(e.storedAt).Seconds())))
+	header.Set(CacheStatusHeader, cacheStatusName(status))
+
+	statusCode, body := e.status, e.body
+	if etag := header.Get("ETag"); etag != "" && etagMatches(req.Header.Get("If-None-Match"), etag) {
+		statusCode, body = http.StatusNotModified, nil
+		header.Del("Content-Length")
+	}
+
+	return &http.Response{
+		StatusCode:    statusCode,
+		Status:        strconv.Itoa(statusCode) + " " + http.StatusText(statusCode),
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+	}
+}
+
+// etagMatches reports whether an If-None-Match value names etag (weak comparison)
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// cacheStatusName is the CacheStatusHeader value for a cache status, e.g. HIT
+func cacheStatusName(status proto.CacheStatus) string {
+	return strings.TrimPrefix(status.String(), "CACHE_STATUS_")
+}
+
+// parseCacheControl returns the Cache-Control directives by lowercased name
+func parseCacheControl(header http.Header) map[string]string {
+	directives := make(map[string]string)
+	for _, value := range header.Values("Cache-Control") {
+		for _, directive := range strings.Split(value, ",") {
+			name, arg, _ := strings.Cut(strings.TrimSpace(directive), "=")
+			if name != "" {
+				directives[strings.ToLower(name)] = strings.Trim(arg, `"`)
+			}
+		}
+	}
+	return directives
+}
+
+// cacheControlSeconds returns a delta-seconds directive such as max-age
+func cacheControlSeconds(directives map[string]string, name string) (time.Duration, bool) {
+	value, ok := directives[name]
+	if !ok {
+		return 0, false
+	}
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || seconds < 0 {
+		return 0, false
+	}
+	return time.Duration(seconds) * time.Second, true
+}
+
+// serveFromCache answers a cacheable request from the cache when the entry is fresh. It
+// returns the entry to revalidate when it's stale (see setValidators), and the cache status
+// of the request so far.
+func (s *GRPCTunnelServer) serveFromCache(domain string, req *http.Request) (*http.Response, *cachedResponse, proto.CacheStatus) {
+	if !cacheableRequest(req) {
+		return nil, nil, proto.CacheStatus_CACHE_STATUS_BYPASS
+	}
+	entry := s.cache.lookup(responseCacheKey(domain, req), req)
+	if entry == nil {
+		return nil, nil, proto.CacheStatus_CACHE_STATUS_MISS
+	}
+	now := time.Now()
+	if entry.fresh(now) {
+		s.cache.count(proto.CacheStatus_CACHE_STATUS_HIT)
+		return entry.response(req, proto.CacheStatus_CACHE_STATUS_HIT, now), nil, proto.CacheStatus_CACHE_STATUS_HIT
+	}
+	if entry.header.Get("ETag") == "" && entry.header.Get("Last-Modified") == "" {
+		// Nothing to revalidate with: fetch it again like a miss
+		return nil, nil, proto.CacheStatus_CACHE_STATUS_MISS
+	}
+	return nil, entry, proto.CacheStatus_CACHE_STATUS_STALE
+}
+
+// cacheResponse stores the origin's response to a cacheable request, or serves the stale
+// entry the origin just confirmed, and marks the response with its CacheStatusHeader
+func (s *GRPCTunnelServer) cacheResponse(domain string, req *http.Request, stale *cachedResponse, status proto.CacheStatus, resp *http.Response) *http.Response {
+	now := time.Now()
+	if stale != nil {
+		if resp.StatusCode == http.StatusNotModified {
+			resp.Body.Close()
+			entry := s.cache.refresh(stale, resp, now)
+			s.cache.count(proto.CacheStatus_CACHE_STATUS_STALE)
+			return entry.response(req, proto.CacheStatus_CACHE_STATUS_STALE, now)
+		}
+		// Changed at the origin: replace the entry like a miss
+		status = proto.CacheStatus_CACHE_STATUS_MISS
+	}
+	s.cache.count(status)
+	resp.Header.Set(CacheStatusHeader, cacheStatusName(status))
+
+	// Only bodies of a known size that will be stored are read here; anything else, such as
+	// a streamed SSE or chunked response, reaches the visitor as it arrives
+	if status == proto.CacheStatus_CACHE_STATUS_MISS && s.cache.buffers(resp, now) {
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		if err == nil {
+			s.cache.store(responseCacheKey(domain, req), domain, req, resp, body, now)
+		}
+	}
+	return resp
+}
@@ -0,0 +1,236 @@
+package tunnel
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/osa911/giraffecloud/internal/tunnel/proto"
+)
+
+func originResponse(status int, body string, header ...string) *http.Response {
+	resp := &http.Response{StatusCode: status, Header: make(http.Header), Body: io.NopCloser(bytes.NewReader([]byte(body))), ContentLength: int64(len(body))}
+	for i := 0; i+1 < len(header); i += 2 {
+		resp.Header.Add(header[i], header[i+1])
+	}
+	return resp
+}
+
+func readBody(t *testing.T, resp *http.Response) string {
+	t.Helper()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+func TestResponseCacheFreshness(t *testing.T) {
+	cache := newResponseCache(1<<20, time.Minute)
+	now := time.Now()
+
+	tests := []struct {
+		name     string
+		resp     *http.Response
+		wantTTL  time.Duration
+		storable bool
+	}{
+		{"max-age", originResponse(200, "", "Cache-Control", "public, max-age=30"), 30 * time.Second, true},
+		{"s-maxage wins", originResponse(200, "", "Cache-Control", "max-age=10, s-maxage=20"), 20 * time.Second, true},
+		{"capped by TTL", originResponse(200, "", "Cache-Control", "max-age=3600"), time.Minute, true},
+		{"expires", originResponse(200, "", "Expires", now.Add(40*time.Second).UTC().Format(http.TimeFormat)), 39 * time.Second, true},
+		{"no-store", originResponse(200, "", "Cache-Control", "no-store, max-age=30"), 0, false},
+		{"private", originResponse(200, "", "Cache-Control", "private, max-age=30"), 0, false},
+		{"set-cookie", originResponse(200, "", "Cache-Control", "max-age=30", "Set-Cookie", "a=b"), 0, false},
+		{"vary star", originResponse(200, "", "Cache-Control", "max-age=30", "Vary", "*"), 0, false},
+		{"uncacheable status", originResponse(500, "", "Cache-Control", "max-age=30"), 0, false},
+		{"no lifetime", originResponse(200, ""), 0, false},
+		{"etag only", originResponse(200, "", "ETag", `"v1"`), 0, true},
+		{"no-cache with etag", originResponse(200, "", "Cache-Control", "no-cache", "ETag", `"v1"`), 0, true},
+		{"no-cache without validator", originResponse(200, "", "Cache-Control", "no-cache"), 0, false},
+	}
+	for _, tt := range tests {
+		ttl, storable := cache.freshness(tt.resp, now)
+		if storable != tt.storable || (storable && (ttl < tt.wantTTL || ttl > tt.wantTTL+time.Second)) {
+			t.Errorf("%s: freshness = %v, %v; want %v, %v", tt.name, ttl, storable, tt.wantTTL, tt.storable)
+		}
+	}
+}
+
+func TestResponseCacheServe(t *testing.T) {
+	s := &GRPCTunnelServer{cache: newResponseCache(1<<20, time.Minute)}
+	const domain = "app.example.com"
+
+	fetch := func(req *http.Request, origin func(*http.Request) *http.Response) *http.Response {
+		cached, stale, status := s.serveFromCache(domain, req)
+		if cached != nil {
+			return cached
+		}
+		originReq := req
+		if stale != nil {
+			originReq = req.Clone(req.Context())
+			stale.setValidators(originReq)
+		}
+		return s.cacheResponse(domain, req, stale, status, origin(originReq))
+	}
+
+	calls := 0
+	origin := func(req *http.Request) *http.Response {
+		calls++
+		if req.Header.Get("If-None-Match") == `"v1"` {
+			return originResponse(http.StatusNotModified, "", "ETag", `"v1"`, "Cache-Control", "max-age=60")
+		}
+		return originResponse(200, "hello", "ETag", `"v1"`, "Cache-Control", "max-age=60")
+	}
+
+	resp := fetch(httptest.NewRequest("GET", "/page?x=1", nil), origin)
+	if got := resp.Header.Get(CacheStatusHeader); got != "MISS" || readBody(t, resp) != "hello" {
+		t.Fatalf("first request: status %q", got)
+	}
+	resp = fetch(httptest.NewRequest("GET", "/page?x=1", nil), origin)
+	if got := resp.Header.Get(CacheStatusHeader); got != "HIT" || readBody(t, resp) != "hello" || calls != 1 {
+		t.Fatalf("second request: status %q, %d origin calls", got, calls)
+	}
+
+	// The visitor's own validator gets a 304 from the cache
+	req := httptest.NewRequest("GET", "/page?x=1", nil)
+	req.Header.Set("If-None-Match", `W/"v1"`)
+	if resp = fetch(req, origin); resp.StatusCode != http.StatusNotModified {
+		t.Fatalf("conditional request: got %d, want 304", resp.StatusCode)
+	}
+
+	// Other queries, methods, credentials and no-cache requests don't hit the entry
+	if resp = fetch(httptest.NewRequest("GET", "/page?x=2", nil), origin); resp.Header.Get(CacheStatusHeader) != "MISS" {
+		t.Errorf("other query: status %q, want MISS", resp.Header.Get(CacheStatusHeader))
+	}
+	for _, req := range []*http.Request{
+		httptest.NewRequest("POST", "/page?x=1", nil),
+		func() *http.Request {
+			r := httptest.NewRequest("GET", "/page?x=1", nil)
+			r.Header.Set("Authorization", "Bearer t")
+			return r
+		}(),
+		func() *http.Request {
+			r := httptest.NewRequest("GET", "/page?x=1", nil)
+			r.Header.Set("Cache-Control", "no-cache")
+			return r
+		}(),
+	} {
+		if resp = fetch(req, origin); resp.Header.Get(CacheStatusHeader) != "BYPASS" {
+			t.Errorf("%s with %v: status %q, want BYPASS", req.Method, req.Header, resp.Header.Get(CacheStatusHeader))
+		}
+	}
+
+	// Once expired, the entry is revalidated with its ETag and served again on 304
+	key := responseCacheKey(domain, httptest.NewRequest("GET", "/page?x=1", nil))
+	s.cache.mu.Lock()
+	expired := *s.cache.entries[key].Value.(*cachedResponse)
+	expired.expires = time.Now().Add(-time.Second)
+	s.cache.entries[key].Value = &expired
+	s.cache.mu.Unlock()
+
+	calls = 0
+	resp = fetch(httptest.NewRequest("GET", "/page?x=1", nil), origin)
+	if got := resp.Header.Get(CacheStatusHeader); got != "STALE" || resp.StatusCode != 200 || readBody(t, resp) != "hello" || calls != 1 {
+		t.Fatalf("revalidation: status %q, code %d, %d origin calls", got, resp.StatusCode, calls)
+	}
+	if resp = fetch(httptest.NewRequest("GET", "/page?x=1", nil), origin); resp.Header.Get(CacheStatusHeader) != "HIT" {
+		t.Errorf("after revalidation: status %q, want HIT", resp.Header.Get(CacheStatusHeader))
+	}
+
+	counts, _ := s.cache.Stats()
+	if counts[proto.CacheStatus_CACHE_STATUS_HIT] != 3 || counts[proto.CacheStatus_CACHE_STATUS_STALE] != 1 || counts[proto.CacheStatus_CACHE_STATUS_BYPASS] != 3 {
+		t.Errorf("counts = %v", counts)
+	}
+
+	s.cache.purgeDomain(domain)
+	if resp = fetch(httptest.NewRequest("GET", "/page?x=1", nil), origin); resp.Header.Get(CacheStatusHeader) != "MISS" {
+		t.Errorf("after purge: status %q, want MISS", resp.Header.Get(CacheStatusHeader))
+	}
+}
+
+func TestResponseCacheVaryAndEviction(t *testing.T) {
+	cache := newResponseCache(10, time.Minute)
+	now := time.Now()
+
+	gzipReq := httptest.NewRequest("GET", "/a", nil)
+	gzipReq.Header.Set("Accept-Encoding", "gzip")
+	cache.store("d /a", "d", gzipReq, originResponse(200, "", "Cache-Control", "max-age=60", "Vary", "Accept-Encoding"), []byte("aaaa"), now)
+
+	if cache.lookup("d /a", gzipReq) == nil {
+		t.Fatal("no entry for the same Accept-Encoding")
+	}
+	if cache.lookup("d /a", httptest.NewRequest("GET", "/a", nil)) != nil {
+		t.Fatal("entry served for a different Accept-Encoding")
+	}
+
+	// Least recently used entries are evicted to stay within the size limit
+	cache.store("d /b", "d", httptest.NewRequest("GET", "/b", nil), originResponse(200, "", "Cache-Control", "max-age=60"), []byte("bbbb"), now)
+	cache.lookup("d /a", gzipReq)
+	cache.store("d /c", "d", httptest.NewRequest("GET", "/c", nil), originResponse(200, "", "Cache-Control", "max-age=60"), []byte("cccc"), now)
+
+	if _, size := cache.Stats(); size != 8 {
+		t.Errorf("size = %d, want 8", size)
+	}
+	if cache.lookup("d /b", httptest.NewRequest("GET", "/b", nil)) != nil {
+		t.Error("least recently used entry was not evicted")
+	}
+	if cache.lookup("d /a", gzipReq) == nil {
+		t.Error("recently used entry was evicted")
+	}
+
+	// Bodies larger than the whole cache are never stored
+	cache.store("d /big", "d", httptest.NewRequest("GET", "/big", nil), originResponse(200, "", "Cache-Control", "max-age=60"), []byte("0123456789a"), now)
+	if cache.lookup("d /big", httptest.NewRequest("GET", "/big", nil)) != nil {
+		t.Error("oversized body was cached")
+	}
+}
+
+func TestResponseCachePassesStreamsThrough(t *testing.T) {
+	s := &GRPCTunnelServer{cache: newResponseCache(16, time.Minute)}
+	const domain = "app.example.com"
+
+	tests := []struct {
+		name string
+		resp func(body io.ReadCloser) *http.Response
+	}{
+		{"unknown length", func(body io.ReadCloser) *http.Response {
+			resp := originResponse(200, "", "Cache-Control", "max-age=60", "Content-Type", "text/event-stream")
+			resp.Body, resp.ContentLength = body, -1
+			return resp
+		}},
+		{"too large", func(body io.ReadCloser) *http.Response {
+			resp := originResponse(200, "", "Cache-Control", "max-age=60")
+			resp.Body, resp.ContentLength = body, 17
+			return resp
+		}},
+		{"not storable", func(body io.ReadCloser) *http.Response {
+			resp := originResponse(200, "", "Cache-Control", "no-store")
+			resp.Body, resp.ContentLength = body, 4
+			return resp
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The origin has sent nothing yet: reading the body here would block
+			pr, pw := io.Pipe()
+			defer pw.Close()
+			req := httptest.NewRequest("GET", "/events", nil)
+			_, _, status := s.serveFromCache(domain, req)
+
+			done := make(chan *http.Response, 1)
+			go func() { done <- s.cacheResponse(domain, req, nil, status, tt.resp(pr)) }()
+			select {
+			case resp := <-done:
+				if resp.Body != pr {
+					t.Error("body was replaced")
+				}
+			case <-time.After(time.Second):
+				t.Fatal("cacheResponse waited for the body")
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package tunnel
+
+import (
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/osa911/giraffecloud/internal/tunnel/proto"
+)
+
+// trailerValues flattens response trailers for HTTPResponse.Trailers, keeping the first
+// value of each like the headers
+func trailerValues(trailer http.Header) map[string]string {
+	var values map[string]string
+	for key, v := range trailer {
+		if len(v) == 0 {
+			continue
+		}
+		if values == nil {
+			values = make(map[string]string, len(trailer))
+		}
+		values[key] = v[0]
+	}
+	return values
+}
+
+// announceTrailers names the trailers of a streamed response in the Trailer header of its
+// first chunk, so the server can declare them to the visitor before the body. gRPC
+// responses always end with grpc-status, even when the origin doesn't declare it.
+func announceTrailers(headers map[string]string, response *http.Response) {
+	names := make([]string, 0, len(response.Trailer))
+	for name := range response.Trailer {
+		names = append(names, name)
+	}
+	if len(names) == 0 && strings.HasPrefix(response.Header.Get("Content-Type"), "application/grpc") {
+		names = []string{"Grpc-Status", "Grpc-Message"}
+	}
+	if len(names) > 0 {
+		sort.Strings(names)
+		headers["Trailer"] = strings.Join(names, ", ")
+	}
+}
+
+// streamTrailers hands the final chunk's trailers from the chunk collector to the response
+// body; values is set before the collector closes the body's pipe
+type streamTrailers struct {
+	values map[string]string
+}
+
+// trailerBody fills in the response trailers once the body has been read to the end
+type trailerBody struct {
+	io.ReadCloser
+	trailer http.Header
+	final   *streamTrailers
+}
+
+func (b *trailerBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err == io.EOF {
+		for key, value := range b.final.values {
+			b.trailer.Set(key, value)
+		}
+	}
+	return n, err
+}
+
+// applyTrailers sets up a response rebuilt from tunnel messages to carry the origin's
+// trailers. A response sent whole has them already; a streamed one declares those named in
+// the first chunk's Trailer header and gets their values from the final chunk, in final.
+// Trailers need chunked transfer encoding, so such responses are sent as HTTP/1.1 chunked.
+func applyTrailers(response *http.Response, first *proto.HTTPResponse, final *streamTrailers) {
+	announced := response.Header.Get("Trailer")
+	response.Header.Del("Trailer")
+
+	if !first.IsChunked {
+		if len(first.Trailers) == 0 {
+			return
+		}
+		response.Trailer = make(http.Header, len(first.Trailers))
+		for key, value := range first.Trailers {
+			response.Trailer.Set(key, value)
+		}
+	} else {
+		if announced == "" || final == nil || response.ContentLength >= 0 {
+			return
+		}
+		response.Trailer = make(http.Header)
+		for _, name := range strings.Split(announced, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				response.Trailer[http.CanonicalHeaderKey(name)] = nil
+			}
+		}
+		response.Body = &trailerBody{ReadCloser: response.Body, trailer: response.Trailer, final: final}
+	}
+
+	response.Proto, response.ProtoMajor, response.ProtoMinor = "HTTP/1.1", 1, 1
+	response.TransferEncoding = []string{"chunked"}
+	response.ContentLength = -1
+	response.Header.Del("Content-Length")
+}
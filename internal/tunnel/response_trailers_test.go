@@ -0,0 +1,50 @@
+package tunnel
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/osa911/giraffecloud/internal/tunnel/proto"
+)
+
+func TestApplyTrailers(t *testing.T) {
+	// A streamed response: Grpc-Status is announced in the first chunk, its value arrives
+	// with the final chunk after the body
+	pipeReader, pipeWriter := io.Pipe()
+	first := &proto.HTTPResponse{StatusCode: 200, IsChunked: true, ChunkId: "chunk-1", Headers: map[string]string{"Trailer": "Grpc-Status"}}
+	response := &http.Response{StatusCode: 200, Status: "200 OK", Header: http.Header{"Trailer": {"Grpc-Status"}}, Body: pipeReader, ContentLength: -1}
+	final := &streamTrailers{}
+	applyTrailers(response, first, final)
+	go func() {
+		pipeWriter.Write([]byte("payload"))
+		final.values = map[string]string{"Grpc-Status": "0", "Grpc-Message": "done"}
+		pipeWriter.Close()
+	}()
+
+	var out bytes.Buffer
+	if err := response.Write(&out); err != nil {
+		t.Fatal(err)
+	}
+	visitor, err := http.ReadResponse(bufio.NewReader(&out), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := visitor.Trailer["Grpc-Status"]; !ok {
+		t.Errorf("Grpc-Status not declared before the body: %v", visitor.Trailer)
+	}
+	body, _ := io.ReadAll(visitor.Body)
+	if string(body) != "payload" || visitor.Trailer.Get("Grpc-Status") != "0" || visitor.Trailer.Get("Grpc-Message") != "done" {
+		t.Errorf("got %q with trailers %v", body, visitor.Trailer)
+	}
+
+	// A response sent whole carries its trailers in the same message
+	whole := &proto.HTTPResponse{StatusCode: 200, Body: []byte("x"), Trailers: map[string]string{"Grpc-Status": "5"}}
+	response = &http.Response{StatusCode: 200, Header: http.Header{"Content-Length": {"1"}}, Body: io.NopCloser(bytes.NewReader(whole.Body)), ContentLength: 1}
+	applyTrailers(response, whole, nil)
+	if response.Trailer.Get("Grpc-Status") != "5" || response.ContentLength != -1 || response.Header.Get("Content-Length") != "" {
+		t.Errorf("whole response: trailers %v, length %d, headers %v", response.Trailer, response.ContentLength, response.Header)
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"os"
 	"runtime"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -24,11 +25,14 @@ import (
 	FYI: We use this server to handle the handshake and proxy the connection to the local service
 
 TODO:
-- Add rate limiting
 - Add logging
 - Add metrics
 */
 
+// DefaultFreshConnectionTimeout bounds how long the server waits for a client to dial in an
+// on-demand connection, and the client for the request on it (StreamingConfig.FreshConnectionTimeout)
+const DefaultFreshConnectionTimeout = 10 * time.Second
+
 // ClientIPUpdateFunc is a callback function for client IP updates
 type ClientIPUpdateFunc func(ctx context.Context, tunnelID uint32, clientIP string) error
 
@@ -45,9 +49,24 @@ type TunnelServer struct {
 	usageRecorder UsageRecorder
 	quotaChecker  QuotaChecker
 
+	// Per-user connection limit checked on handshakes (nil = unlimited)
+	userConnectionCheck UserConnectionCheck
+
 	// Tunnel establishment callbacks
 	onTCPTunnelEstablished func(domain string)
 	onRequestTCPTunnel     func(domain string) error // Request new TCP/WebSocket tunnel from client
+	onRequestFreshTunnel   func(domain, requestID string) error
+
+	// On-demand connections awaiting client dial-in, keyed by establishment request ID
+	freshWaiters   map[string]chan *TunnelConnection
+	freshWaitersMu sync.Mutex
+
+	// Skip media detection whatever streamConfig says (HybridRouterConfig.DisableMediaOptimization)
+	disableMediaOptimization bool
+
+	// UDP tunnels by domain
+	udpTunnels map[string]*udpTunnel
+	udpMu      sync.Mutex
 
 	// Performance monitoring
 	requestCount   int64 // Total requests handled
@@ -55,14 +74,27 @@ type TunnelServer struct {
 	poolHits       int64 // Successful pool connections
 	poolMisses     int64 // Failed pool connections
 
+	// Responses abandoned because the visitor stopped reading (see ClientWriteTimeout)
+	clientWriteTimeouts int64
+
 	// Connection health monitoring
 	lastCleanup time.Time // Last cleanup time
 
-	// Circuit breaker for cascade failure prevention
-	recentTimeouts  int64     // Recent timeout count
-	lastTimeoutTime time.Time // Last timeout occurrence
+	// Per-domain circuit breakers for cascade failure prevention
+	breakers *circuitBreakers
+
+	// Rolling request latency per domain
+	latency *LatencyTracker
+
+	// Shutdown state
+	draining          int32 // Set while draining: new requests are rejected with 503
+	stopped           int32 // Set once the listener is closed so the accept loop exits
+	closeListenerOnce sync.Once
 }
 
+// drainPollInterval is how often Drain checks the in-flight request counter
+const drainPollInterval = 100 * time.Millisecond
+
 // NewServer creates a new tunnel server instance
 func NewServer(tokenRepo repository.TokenRepository, tunnelRepo repository.TunnelRepository, tunnelService interfaces.TunnelService) *TunnelServer {
 	// Determine certificate paths based on environment
@@ -85,14 +117,18 @@ func NewServer(tokenRepo repository.TokenRepository, tunnelRepo repository.Tunne
 	}
 	logging.GetGlobalLogger().Info("🔐 TCP Server using PRODUCTION-GRADE TLS with mutual authentication")
 
+	streamConfig := DefaultStreamingConfig()
 	return &TunnelServer{
 		logger:        logging.GetGlobalLogger(),
 		connections:   NewConnectionManager(),
-		streamConfig:  DefaultStreamingConfig(),
+		streamConfig:  streamConfig,
+		breakers:      newCircuitBreakers(streamConfig.CircuitBreakerThreshold, streamConfig.CircuitBreakerCooldown),
+		latency:       NewLatencyTracker(DefaultLatencyWindow),
 		tlsConfig:     serverTLSConfig,
 		tokenRepo:     tokenRepo,
 		tunnelRepo:    tunnelRepo,
 		tunnelService: tunnelService,
+		freshWaiters:  make(map[string]chan *TunnelConnection),
 	}
 }
 
@@ -112,12 +148,22 @@ func (s *TunnelServer) SetRequestTCPTunnelCallback(callback func(domain string)
 	s.onRequestTCPTunnel = callback
 }
 
+// SetRequestFreshTunnelCallback sets the callback used to ask a client to dial in a dedicated
+// on-demand connection; requestID must be echoed back by the client in its handshake
+func (s *TunnelServer) SetRequestFreshTunnelCallback(callback func(domain, requestID string) error) {
+	s.onRequestFreshTunnel = callback
+}
+
 // Start starts the tunnel server
 func (s *TunnelServer) Start(addr string) error {
 	tcpListener, err := net.Listen("tcp", addr)
 	if err != nil {
 		return fmt.Errorf("failed to create listener: %w", err)
 	}
+	if s.streamConfig.ProxyProtocol {
+		// The PROXY header precedes the TLS handshake
+		tcpListener = NewProxyProtocolListener(tcpListener, DefaultProxyHeaderTimeout)
+	}
 
 	s.listener = tls.NewListener(tcpListener, s.tlsConfig)
 	go s.acceptConnections()
@@ -128,20 +174,63 @@ func (s *TunnelServer) Start(addr string) error {
 
 // Stop stops the tunnel server
 func (s *TunnelServer) Stop() error {
+	return s.closeListener()
+}
+
+// closeListener stops accepting new tunnel connections (safe to call multiple times)
+func (s *TunnelServer) closeListener() error {
 	if s.listener == nil {
 		return nil
 	}
 
-	if err := s.listener.Close(); err != nil {
-		return fmt.Errorf("failed to close listener: %w", err)
+	var err error
+	s.closeListenerOnce.Do(func() {
+		atomic.StoreInt32(&s.stopped, 1)
+		if closeErr := s.listener.Close(); closeErr != nil {
+			err = fmt.Errorf("failed to close listener: %w", closeErr)
+		}
+	})
+	return err
+}
+
+// Drain stops accepting new connections and requests, waits for in-flight requests
+// to finish (or ctx to expire) and then closes all existing tunnel connections.
+// Returns ctx.Err() if requests were still in flight when the deadline hit.
+func (s *TunnelServer) Drain(ctx context.Context) error {
+	atomic.StoreInt32(&s.draining, 1)
+	if err := s.closeListener(); err != nil {
+		s.logger.Warn("[DRAIN] %v", err)
+	}
+
+	inFlight := atomic.LoadInt64(&s.concurrentReqs)
+	s.logger.Info("[DRAIN] Draining TCP tunnel server, %d request(s) in flight", inFlight)
+
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+
+	var drainErr error
+	for inFlight > 0 && drainErr == nil {
+		select {
+		case <-ctx.Done():
+			drainErr = ctx.Err()
+			s.logger.Warn("[DRAIN] Deadline reached with %d request(s) still in flight, closing tunnels anyway", inFlight)
+		case <-ticker.C:
+			inFlight = atomic.LoadInt64(&s.concurrentReqs)
+		}
 	}
 
-	return nil
+	if drainErr == nil {
+		s.logger.Info("[DRAIN] ✅ All in-flight requests completed")
+	}
+	s.connections.Close()
+	return drainErr
 }
 
 // UpdateStreamingConfig updates the streaming configuration
 func (s *TunnelServer) UpdateStreamingConfig(config *StreamingConfig) {
+	config.applyPoolDefaults()
 	s.streamConfig = config
+	s.breakers.Configure(config.CircuitBreakerThreshold, config.CircuitBreakerCooldown)
 	s.logger.Info("Updated streaming configuration: MediaOptimization=%v, PoolSize=%d, MediaBufferSize=%d",
 		config.EnableMediaOptimization, config.PoolSize, config.MediaBufferSize)
 }
@@ -155,17 +244,55 @@ func (s *TunnelServer) acceptConnections() {
 	for {
 		conn, err := s.listener.Accept()
 		if err != nil {
+			if atomic.LoadInt32(&s.stopped) == 1 {
+				return
+			}
 			s.logger.Error("Failed to accept connection: %v", err)
 			continue
 		}
 
+		s.applySocketBuffers(conn)
 		go s.handleConnection(conn)
 	}
 }
 
+// applySocketBuffers sets the configured kernel buffer sizes on an accepted connection
+func (s *TunnelServer) applySocketBuffers(conn net.Conn) {
+	if s.streamConfig.TCPReadBufferSize <= 0 && s.streamConfig.TCPWriteBufferSize <= 0 {
+		return
+	}
+	// The listener hands out TLS connections; the buffers belong to the TCP socket beneath
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		conn = tlsConn.NetConn()
+	}
+	if proxyConn, ok := conn.(*proxyProtocolConn); ok {
+		conn = proxyConn.Conn
+	}
+	tcpConn, ok := conn.(*net.TCPConn)
+	if !ok {
+		return
+	}
+	if size := s.streamConfig.TCPReadBufferSize; size > 0 {
+		if err := tcpConn.SetReadBuffer(size); err != nil {
+			s.logger.Debug("Failed to set read buffer to %d bytes: %v", size, err)
+		}
+	}
+	if size := s.streamConfig.TCPWriteBufferSize; size > 0 {
+		if err := tcpConn.SetWriteBuffer(size); err != nil {
+			s.logger.Debug("Failed to set write buffer to %d bytes: %v", size, err)
+		}
+	}
+}
+
 // handleConnection handles a new tunnel connection
 func (s *TunnelServer) handleConnection(conn net.Conn) {
-	defer conn.Close()
+	// On-demand connections are handed off to the waiting request and closed by it
+	handedOff := false
+	defer func() {
+		if !handedOff {
+			conn.Close()
+		}
+	}()
 
 	// Create JSON encoder/decoder
 	decoder := json.NewDecoder(conn)
@@ -178,19 +305,48 @@ func (s *TunnelServer) handleConnection(conn net.Conn) {
 		return
 	}
 
+	// A handshake that raced with Drain: tell the client to come back later
+	if atomic.LoadInt32(&s.draining) == 1 {
+		encoder.Encode(TunnelHandshakeResponse{
+			Status:  "error",
+			Message: "server is shutting down for maintenance",
+			Code:    HandshakeMaintenance,
+		})
+		return
+	}
+
 	// Authenticate using shared authentication logic
-	tunnel, err := AuthenticateTunnelByToken(context.Background(), req.Token, req.Domain, s.tokenRepo, s.tunnelRepo)
+	tunnel, err := AuthenticateTunnelByToken(context.Background(), req.Token, req.Domain, req.TunnelID, s.tokenRepo, s.tunnelRepo)
 	if err != nil {
 		s.logger.Error("Failed to authenticate: %v", err)
 		encoder.Encode(TunnelHandshakeResponse{
 			Status:  "error",
 			Message: err.Error(),
+			Code:    handshakeCodeOf(err),
 		})
 		return
 	}
 
 	s.logger.Info("User %d connected with token %s for domain %s", tunnel.UserID, tunnel.Token, tunnel.Domain)
 
+	// Pooled connections count towards the owner's limit; on-demand ones answer a request we made
+	if s.userConnectionCheck != nil && req.RequestID == "" {
+		if err := s.userConnectionCheck(tunnel.UserID, false); err != nil {
+			encoder.Encode(TunnelHandshakeResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Code:    handshakeCodeOf(err),
+			})
+			return
+		}
+	}
+
+	// UDP tunnels carry datagrams only; HTTP routing is left to the other connections
+	if req.ConnectionType == string(ConnectionTypeUDP) {
+		s.serveUDPTunnel(conn, encoder, tunnel, req.UDPPort)
+		return
+	}
+
 	// Get client IP from connection
 	clientIP, _, err := net.SplitHostPort(conn.RemoteAddr().String())
 	if err != nil {
@@ -198,6 +354,7 @@ func (s *TunnelServer) handleConnection(conn net.Conn) {
 		encoder.Encode(TunnelHandshakeResponse{
 			Status:  "error",
 			Message: "Failed to get client IP",
+			Code:    HandshakeInternal,
 		})
 		return
 	}
@@ -208,14 +365,18 @@ func (s *TunnelServer) handleConnection(conn net.Conn) {
 		encoder.Encode(TunnelHandshakeResponse{
 			Status:  "error",
 			Message: "Failed to update client IP",
+			Code:    HandshakeInternal,
 		})
 		return
 	}
 
 	// Determine connection type based on request
 	connType := ConnectionTypeHTTP
-	if req.ConnectionType == "websocket" {
+	switch ConnectionType(req.ConnectionType) {
+	case ConnectionTypeWebSocket:
 		connType = ConnectionTypeWebSocket
+	case ConnectionTypeHTTPOnDemand:
+		connType = ConnectionTypeHTTPOnDemand
 	}
 
 	// Send success response with domain and port
@@ -230,6 +391,17 @@ func (s *TunnelServer) handleConnection(conn net.Conn) {
 		return
 	}
 
+	// Answering a createFreshTunnelConnection request: hand off without pooling
+	if connType == ConnectionTypeHTTPOnDemand {
+		if s.deliverFreshConnection(req.RequestID, NewTunnelConnection(tunnel.Domain, conn, tunnel.TargetPort)) {
+			handedOff = true
+			s.logger.Info("[HYBRID] On-demand connection delivered for domain: %s (requestID: %s)", tunnel.Domain, req.RequestID)
+		} else {
+			s.logger.Warn("[HYBRID] Dropping on-demand connection for domain %s: nobody waiting for requestID %q", tunnel.Domain, req.RequestID)
+		}
+		return
+	}
+
 	// Create connection object and add to manager with type
 	s.connections.AddConnection(tunnel.Domain, conn, tunnel.TargetPort, connType, tunnel.UserID, uint32(tunnel.ID))
 	defer s.connections.RemoveConnection(tunnel.Domain, connType)
@@ -268,6 +440,33 @@ func (s *TunnelServer) GetWebSocketPoolStats() map[string]int {
 	return s.connections.GetWebSocketPoolStats()
 }
 
+// GetMetrics returns the TCP tunnel server's performance counters
+func (s *TunnelServer) GetMetrics() map[string]int64 {
+	return map[string]int64{
+		"requests":            atomic.LoadInt64(&s.requestCount),
+		"concurrent_requests": atomic.LoadInt64(&s.concurrentReqs),
+		"pool_hits":           atomic.LoadInt64(&s.poolHits),
+		"pool_misses":         atomic.LoadInt64(&s.poolMisses),
+
+		"client_write_timeouts": atomic.LoadInt64(&s.clientWriteTimeouts),
+	}
+}
+
+// GetLatency returns the rolling request latency percentiles per domain
+func (s *TunnelServer) GetLatency() map[string]LatencyPercentiles {
+	return s.latency.Snapshot()
+}
+
+// GetCircuitStates returns the circuit breaker state of every domain that has recently failed
+func (s *TunnelServer) GetCircuitStates() map[string]CircuitState {
+	return s.breakers.States()
+}
+
+// GetDomainConnectionCounts returns pooled HTTP/WebSocket connection counts per domain
+func (s *TunnelServer) GetDomainConnectionCounts() map[string]map[ConnectionType]int {
+	return s.connections.GetDomainConnectionCounts()
+}
+
 // RemoveDeadConnection removes a dead connection for the domain
 func (s *TunnelServer) RemoveDeadConnection(domain string) {
 	s.logger.Info("[CONNECTION CLEANUP] Removing dead WebSocket connection for domain: %s", domain)
@@ -287,6 +486,21 @@ func (s *TunnelServer) ProxyConnection(domain string, conn net.Conn, requestData
 	concurrent := atomic.AddInt64(&s.concurrentReqs, 1)
 	defer atomic.AddInt64(&s.concurrentReqs, -1)
 
+	// Reject new requests while draining (checked after incrementing so Drain can't miss us)
+	if atomic.LoadInt32(&s.draining) == 1 {
+		s.writeHTTPError(conn, 503, "Service Unavailable - Server is restarting, please retry")
+		return
+	}
+
+	// Shed requests for a domain whose breaker is open instead of waiting on another timeout
+	if !s.breakers.Allow(domain) {
+		s.writeHTTPError(conn, 503, "Service Unavailable - Tunnel is failing, please retry shortly")
+		return
+	}
+
+	start := time.Now()
+	defer func() { s.latency.Observe(domain, time.Since(start)) }()
+
 	// Log performance metrics every 10 requests and perform cleanup
 	if atomic.LoadInt64(&s.requestCount)%10 == 0 {
 		poolSize := s.connections.GetHTTPPoolSize(domain)
@@ -307,9 +521,9 @@ func (s *TunnelServer) ProxyConnection(domain string, conn net.Conn, requestData
 		projected50MB := connOverheadMB * 50
 		projected100MB := connOverheadMB * 100
 
-		// Perform periodic cleanup every 5 minutes (less aggressive for stability)
+		// Perform periodic cleanup (default every 5 minutes, less aggressive for stability)
 		now := time.Now()
-		if now.Sub(s.lastCleanup) > 5*time.Minute {
+		if now.Sub(s.lastCleanup) > s.streamConfig.CleanupInterval {
 			cleanupStats := s.connections.CleanupDeadConnections()
 			if len(cleanupStats) > 0 {
 				s.logger.Info("[CLEANUP] Removed dead connections: %v", cleanupStats)
@@ -320,9 +534,8 @@ func (s *TunnelServer) ProxyConnection(domain string, conn net.Conn, requestData
 			s.lastCleanup = now
 		}
 
-		recentTimeouts := atomic.LoadInt64(&s.recentTimeouts)
-		s.logger.Info("[PERF] Requests: %d, Concurrent: %d, Hot Pool: %d, Hits: %d, Misses: %d, Timeouts: %d",
-			atomic.LoadInt64(&s.requestCount), concurrent, poolSize, hits, misses, recentTimeouts)
+		s.logger.Info("[PERF] Requests: %d, Concurrent: %d, Hot Pool: %d, Hits: %d, Misses: %d, Circuit: %s, Latency: %s",
+			atomic.LoadInt64(&s.requestCount), concurrent, poolSize, hits, misses, s.breakers.State(domain), s.latency.Percentiles(domain))
 		s.logger.Info("[MEMORY] Total: %.1fMB, Per-Conn: ~%.2fMB, Projected-50: %.1fMB, Projected-100: %.1fMB, GC: %d",
 			totalMemoryMB, connOverheadMB, projected50MB, projected100MB, memStats.NumGC)
 	}
@@ -331,8 +544,12 @@ func (s *TunnelServer) ProxyConnection(domain string, conn net.Conn, requestData
 	var tunnelConn *TunnelConnection
 	var isOnDemand bool = false
 
-	// Step 1: Try to get from hot pool (fast path)
-	tunnelConn = s.connections.GetHTTPConnection(domain)
+	// Step 1: Try to get from hot pool (fast path), keeping sessions on their connection if asked to
+	if s.streamConfig.EnableStickySessions {
+		tunnelConn = s.connections.GetStickyHTTPConnection(domain, s.stickySessionKey(conn, requestData))
+	} else {
+		tunnelConn = s.connections.GetHTTPConnection(domain)
+	}
 	if tunnelConn != nil {
 		// Quick health check on hot pool connection
 		if tunnelConn.GetConn() != nil {
@@ -373,6 +590,7 @@ func (s *TunnelServer) ProxyConnection(domain string, conn net.Conn, requestData
 				isOnDemand = false
 			} else {
 				s.logger.Error("[HYBRID] Enhanced fallback failed - no connections available")
+				s.recordFailure(domain)
 				s.writeHTTPError(conn, 502, "Bad Gateway - No tunnel connections available")
 				return
 			}
@@ -444,22 +662,22 @@ func (s *TunnelServer) ProxyConnection(domain string, conn net.Conn, requestData
 		}
 	}
 
-	// Set a read timeout - use adaptive timeout based on circuit breaker
-	regularTimeout := s.getAdaptiveTimeout(false) // false = not media request
+	// Set a read timeout - use configured timeout
+	regularTimeout := s.getRequestTimeout(false) // false = not media request
 	tunnelConn.GetConn().SetReadDeadline(time.Now().Add(regularTimeout))
 	defer tunnelConn.GetConn().SetReadDeadline(time.Time{}) // Clear timeout
 
 	// Read the HTTP response from the tunnel
-	tunnelReader := bufio.NewReader(tunnelConn.GetConn())
+	tunnelReader := newTunnelResponseReader(tunnelConn.GetConn(), s.streamConfig.MaxHeaderBytes)
 
 	// Parse the response with better error handling
-	response, err := http.ReadResponse(tunnelReader, nil)
+	response, err := readTunnelResponse(tunnelReader, nil, s.streamConfig.MaxHeaderBytes)
 	if err != nil {
 		s.logger.Error("[HYBRID] Error reading response from tunnel: %v", err)
 
 		// Record timeout for circuit breaker if it's a timeout error
 		if strings.Contains(err.Error(), "timeout") || strings.Contains(err.Error(), "deadline") {
-			s.recordTimeout()
+			s.recordFailure(domain)
 		}
 
 		// Remove from hot pool if it was a hot pool connection
@@ -471,15 +689,12 @@ func (s *TunnelServer) ProxyConnection(domain string, conn net.Conn, requestData
 		return
 	}
 
-	// Write the response back to the client
-	clientWriter := bufio.NewWriter(conn)
-	if err := response.Write(clientWriter); err != nil {
-		s.logger.Debug("[HYBRID] Error writing response to client: %v", err)
-		return
-	}
+	s.recordSuccess(domain)
+	s.applyOriginTimeout(domain, tunnelConn.GetConn(), response, regularTimeout)
 
-	if err := clientWriter.Flush(); err != nil {
-		s.logger.Debug("[HYBRID] Error flushing response: %v", err)
+	// Write the response back to the client
+	if synced, err := s.writeResponse(conn, response); err != nil {
+		s.abandonResponse(domain, tunnelConn, isOnDemand, synced, err)
 		return
 	}
 	// Approximate bytes out: Content-Length header if present
@@ -513,7 +728,7 @@ func (s *TunnelServer) ProxyConnection(domain string, conn net.Conn, requestData
 		go tunnelConn.Close()
 	} else {
 		// Hot pool connections: Close if getting old/heavily used
-		if !s.shouldKeepInHotPool(tunnelConn, response) {
+		if !s.shouldKeepInHotPool(domain, tunnelConn, response) {
 			s.logger.Debug("[HYBRID] Hot pool connection getting stale, removing")
 			s.connections.RemoveSpecificHTTPConnection(domain, tunnelConn)
 			go tunnelConn.Close()
@@ -556,22 +771,22 @@ func (s *TunnelServer) retryWithFreshConnection(domain string, clientConn net.Co
 		}
 	}
 
-	// Set a read timeout for retry - use adaptive timeout
-	retryTimeout := s.getAdaptiveTimeout(false) // false = not media request
+	// Set a read timeout for retry - use configured timeout
+	retryTimeout := s.getRequestTimeout(false) // false = not media request
 	retryTunnelConn.GetConn().SetReadDeadline(time.Now().Add(retryTimeout))
 	defer retryTunnelConn.GetConn().SetReadDeadline(time.Time{})
 
 	// Read the HTTP response from the tunnel
-	tunnelReader := bufio.NewReader(retryTunnelConn.GetConn())
+	tunnelReader := newTunnelResponseReader(retryTunnelConn.GetConn(), s.streamConfig.MaxHeaderBytes)
 
 	// Parse the response
-	response, err := http.ReadResponse(tunnelReader, nil)
+	response, err := readTunnelResponse(tunnelReader, nil, s.streamConfig.MaxHeaderBytes)
 	if err != nil {
 		s.logger.Error("[PROXY DEBUG] Retry failed - error reading response: %v", err)
 
 		// Record timeout for circuit breaker if retry also timed out
 		if strings.Contains(err.Error(), "timeout") || strings.Contains(err.Error(), "deadline") {
-			s.recordTimeout()
+			s.recordFailure(domain)
 		}
 
 		s.connections.RemoveSpecificHTTPConnection(domain, retryTunnelConn)
@@ -579,17 +794,14 @@ func (s *TunnelServer) retryWithFreshConnection(domain string, clientConn net.Co
 		return
 	}
 
+	s.recordSuccess(domain)
+	s.applyOriginTimeout(domain, retryTunnelConn.GetConn(), response, retryTimeout)
+
 	s.logger.Info("[PROXY DEBUG] Retry successful - received response: %s", response.Status)
 
 	// Write the response back to the client
-	clientWriter := bufio.NewWriter(clientConn)
-	if err := response.Write(clientWriter); err != nil {
-		s.logger.Debug("[PROXY DEBUG] Retry - error writing response to client: %v", err)
-		return
-	}
-
-	if err := clientWriter.Flush(); err != nil {
-		s.logger.Debug("[PROXY DEBUG] Retry - error flushing response: %v", err)
+	if synced, err := s.writeResponse(clientConn, response); err != nil {
+		s.abandonResponse(domain, retryTunnelConn, false, synced, err)
 		return
 	}
 
@@ -598,7 +810,7 @@ func (s *TunnelServer) retryWithFreshConnection(domain string, clientConn net.Co
 
 // isMediaRequest checks if the request is for media content that should be streamed
 func (s *TunnelServer) isMediaRequest(requestData []byte) bool {
-	if !s.streamConfig.EnableMediaOptimization {
+	if s.disableMediaOptimization || !s.streamConfig.EnableMediaOptimization {
 		return false
 	}
 
@@ -689,7 +901,7 @@ func (s *TunnelServer) proxyMediaRequest(domain string, clientConn net.Conn, req
 
 	s.logger.Info("[MEDIA PROXY] Reading response from tunnel...")
 
-	// Set a read timeout to prevent hanging - use adaptive timeout for media
+	// Set a read timeout to prevent hanging - use configured timeout for media
 	// Under high load (stress), use much shorter timeouts to clear queue faster
 	currentConcurrent := atomic.LoadInt64(&s.concurrentReqs)
 	poolSize := int64(s.connections.GetHTTPPoolSize(domain)) // Use actual pool size
@@ -702,23 +914,23 @@ func (s *TunnelServer) proxyMediaRequest(domain string, clientConn net.Conn, req
 		mediaTimeout = 8 * time.Second // Moderate timeout under stress
 		s.logger.Debug("[MEDIA PROXY] System stressed (%d concurrent vs %d pool), using moderate 8s timeout", currentConcurrent, poolSize)
 	} else {
-		mediaTimeout = s.getAdaptiveTimeout(true) // Normal timeout
+		mediaTimeout = s.getRequestTimeout(true) // Normal timeout
 	}
 
 	tunnelConn.GetConn().SetReadDeadline(time.Now().Add(mediaTimeout))
 	defer tunnelConn.GetConn().SetReadDeadline(time.Time{}) // Clear timeout
 
 	// Read the HTTP response from the tunnel
-	tunnelReader := bufio.NewReader(tunnelConn.GetConn())
+	tunnelReader := newTunnelResponseReader(tunnelConn.GetConn(), s.streamConfig.MaxHeaderBytes)
 
 	// Parse the response with error handling
-	response, err := http.ReadResponse(tunnelReader, nil)
+	response, err := readTunnelResponse(tunnelReader, nil, s.streamConfig.MaxHeaderBytes)
 	if err != nil {
 		s.logger.Error("[MEDIA PROXY] Error reading response from tunnel: %v", err)
 
 		// Record timeout for circuit breaker if it's a timeout error
 		if strings.Contains(err.Error(), "timeout") || strings.Contains(err.Error(), "deadline") {
-			s.recordTimeout()
+			s.recordFailure(domain)
 
 			// Under stress, don't retry timeouts - just fail fast to clear queue
 			currentConcurrent := atomic.LoadInt64(&s.concurrentReqs)
@@ -746,6 +958,9 @@ func (s *TunnelServer) proxyMediaRequest(domain string, clientConn net.Conn, req
 		return
 	}
 
+	s.recordSuccess(domain)
+	s.applyOriginTimeout(domain, tunnelConn.GetConn(), response, mediaTimeout)
+
 	s.logger.Info("[MEDIA PROXY] Received response: %s", response.Status)
 
 	// CRITICAL: Check if client disconnected during media processing (fast clicking scenario)
@@ -795,7 +1010,10 @@ func (s *TunnelServer) proxyMediaRequest(domain string, clientConn net.Conn, req
 // writeHTTPError writes a proper HTTP error response
 func (s *TunnelServer) writeHTTPError(conn net.Conn, code int, message string) {
 	statusText := "Bad Gateway"
-	if code == 504 {
+	switch code {
+	case 503:
+		statusText = "Service Unavailable"
+	case 504:
 		statusText = "Gateway Timeout"
 	}
 
@@ -849,13 +1067,13 @@ func (s *TunnelServer) isConnectionCleanForReuse(tunnelConn *TunnelConnection, r
 
 	// NEVER reuse connections that have handled too many requests
 	requestCount := tunnelConn.GetRequestCount()
-	if requestCount > 50 { // Allow more requests per connection to prevent cascade failures
+	if requestCount > int64(s.streamConfig.MaxRequestsPerConnection) { // Allow more requests per connection to prevent cascade failures
 		s.logger.Debug("[CONNECTION] Connection has handled %d requests, retiring", requestCount)
 		return false
 	}
 
-	// NEVER reuse connections older than 10 minutes (less aggressive for fast clicking)
-	if time.Since(tunnelConn.GetCreatedAt()) > 10*time.Minute {
+	// NEVER reuse connections older than ConnectionMaxAge (less aggressive for fast clicking)
+	if time.Since(tunnelConn.GetCreatedAt()) > s.streamConfig.ConnectionMaxAge {
 		s.logger.Debug("[CONNECTION] Connection is %v old, retiring", time.Since(tunnelConn.GetCreatedAt()))
 		return false
 	}
@@ -888,41 +1106,49 @@ func (s *TunnelServer) isConnectionCleanForReuse(tunnelConn *TunnelConnection, r
 	return true
 }
 
-// getAdaptiveTimeout returns timeout based on recent failures (circuit breaker logic)
-func (s *TunnelServer) getAdaptiveTimeout(isMedia bool) time.Duration {
-	// Check if we're in a failure cascade (many recent timeouts)
-	recentTimeouts := atomic.LoadInt64(&s.recentTimeouts)
-	timeSinceLastTimeout := time.Since(s.lastTimeoutTime)
+// applyOriginTimeout extends the read deadline (set to timeout) for the rest of response when
+// the local service asked for more time with OriginTimeoutHeader, and hides the header from the client
+func (s *TunnelServer) applyOriginTimeout(domain string, tunnelConn net.Conn, response *http.Response, timeout time.Duration) {
+	value := response.Header.Get(OriginTimeoutHeader)
+	if value == "" {
+		return
+	}
+	response.Header.Del(OriginTimeoutHeader)
 
-	// Reset timeout counter if it's been quiet for a while
-	if timeSinceLastTimeout > 30*time.Second {
-		atomic.StoreInt64(&s.recentTimeouts, 0)
-		recentTimeouts = 0
+	extended, err := parseOriginTimeout(value, s.streamConfig.MaxOriginTimeout)
+	if err != nil {
+		s.logger.Warn("[HYBRID] Ignoring %s from %s: %v", OriginTimeoutHeader, domain, err)
+		return
+	}
+	if extended <= timeout {
+		return
 	}
+	tunnelConn.SetReadDeadline(time.Now().Add(extended))
+	s.logger.Debug("[HYBRID] %s extended the response timeout to %v", domain, extended)
+}
 
-	var baseTimeout time.Duration
+// getRequestTimeout returns the configured response timeout for media or regular requests
+func (s *TunnelServer) getRequestTimeout(isMedia bool) time.Duration {
 	if isMedia {
-		baseTimeout = s.streamConfig.MediaTimeout
-	} else {
-		baseTimeout = s.streamConfig.RegularTimeout
+		return s.streamConfig.MediaTimeout
 	}
+	return s.streamConfig.RegularTimeout
+}
 
-	// If we're seeing many timeouts, be more aggressive with shorter timeouts
-	if recentTimeouts > 3 {
-		aggressiveTimeout := baseTimeout / 3 // Much shorter timeout during failures
-		s.logger.Debug("[CIRCUIT BREAKER] Using aggressive timeout %v due to %d recent timeouts", aggressiveTimeout, recentTimeouts)
-		return aggressiveTimeout
+// recordFailure counts a tunnel failure for the domain's circuit breaker
+func (s *TunnelServer) recordFailure(domain string) {
+	if state := s.breakers.RecordFailure(domain); state == CircuitOpen {
+		s.logger.Warn("[CIRCUIT BREAKER] Circuit open for %s, shedding requests for %v",
+			domain, s.streamConfig.CircuitBreakerCooldown)
 	}
-
-	return baseTimeout
 }
 
-// recordTimeout records a timeout for circuit breaker logic
-func (s *TunnelServer) recordTimeout() {
-	atomic.AddInt64(&s.recentTimeouts, 1)
-	s.lastTimeoutTime = time.Now()
-	timeouts := atomic.LoadInt64(&s.recentTimeouts)
-	s.logger.Debug("[CIRCUIT BREAKER] Recorded timeout #%d", timeouts)
+// recordSuccess closes the domain's circuit breaker after a good tunnel response
+func (s *TunnelServer) recordSuccess(domain string) {
+	if state := s.breakers.State(domain); state != CircuitClosed {
+		s.logger.Info("[CIRCUIT BREAKER] Probe succeeded, circuit closed for %s", domain)
+	}
+	s.breakers.RecordSuccess(domain)
 }
 
 // recycleOldConnections proactively recycles connections that might be getting stuck
@@ -939,10 +1165,10 @@ func (s *TunnelServer) recycleOldConnections(domain string) {
 		shouldRecycle := false
 		reason := ""
 
-		if age > 15*time.Minute {
+		if age > s.streamConfig.RecycleMaxAge {
 			shouldRecycle = true
 			reason = "age"
-		} else if requests > 100 {
+		} else if requests > int64(s.streamConfig.RecycleMaxRequests) {
 			shouldRecycle = true
 			reason = "request_count"
 		}
@@ -1149,11 +1375,11 @@ func (s *TunnelServer) proxyWebSocketConnectionInternal(domain string, clientCon
 	s.logger.Debug("[WEBSOCKET DEBUG] Sent WebSocket upgrade request to tunnel")
 
 	// Read the upgrade response from the tunnel with timeout and WebSocket-aware parsing
-	tunnelReader := bufio.NewReader(tunnelConn.GetConn())
+	tunnelReader := newTunnelResponseReader(tunnelConn.GetConn(), s.streamConfig.MaxHeaderBytes)
 
 	// Set a reasonable timeout for reading the upgrade response
 	tunnelConn.GetConn().SetReadDeadline(time.Now().Add(10 * time.Second))
-	response, err := http.ReadResponse(tunnelReader, nil)
+	response, err := readTunnelResponse(tunnelReader, nil, s.streamConfig.MaxHeaderBytes)
 	tunnelConn.GetConn().SetReadDeadline(time.Time{}) // Clear deadline
 
 	if err != nil {
@@ -1167,12 +1393,15 @@ func (s *TunnelServer) proxyWebSocketConnectionInternal(domain string, clientCon
 			s.logger.Debug("[WEBSOCKET DEBUG] WebSocket upgrade assumed successful, starting bidirectional forwarding")
 			tunnelConn.Unlock()
 
-			// Start bidirectional copying immediately
+			// Start bidirectional copying immediately. The upgraded protocol is unknown,
+			// so an idle connection is closed without sending WebSocket frames.
+			monitor, stopMonitor := s.watchWebSocketIdle(domain, clientConn, tunnelConn.GetConn(), false)
+			defer stopMonitor()
 			errChan := make(chan error, 2)
 
 			// Copy from client to tunnel
 			go func() {
-				n, err := io.Copy(tunnelConn.GetConn(), clientConn)
+				n, err := io.Copy(tunnelConn.GetConn(), monitor.reader(clientConn))
 				if s.usageRecorder != nil {
 					if userID, tunnelID, ok := s.connections.GetDomainOwner(domain); ok && n > 0 {
 						s.usageRecorder.Increment(userID, tunnelID, domain, n, 0, 0)
@@ -1190,7 +1419,7 @@ func (s *TunnelServer) proxyWebSocketConnectionInternal(domain string, clientCon
 					clientConn.Write(buffered)
 				}
 
-				n, err := io.Copy(clientConn, tunnelConn.GetConn())
+				n, err := io.Copy(clientConn, monitor.reader(tunnelConn.GetConn()))
 				if s.usageRecorder != nil {
 					if userID, tunnelID, ok := s.connections.GetDomainOwner(domain); ok && n > 0 {
 						s.usageRecorder.Increment(userID, tunnelID, domain, 0, n, 1)
@@ -1260,12 +1489,14 @@ func (s *TunnelServer) proxyWebSocketConnectionInternal(domain string, clientCon
 	// WebSocket data forwarding doesn't need the lock since it's bidirectional copying
 	tunnelConn.Unlock()
 
-	// Start bidirectional copying
+	// Start bidirectional copying, closing the connection if it goes idle
+	monitor, stopMonitor := s.watchWebSocketIdle(domain, clientConn, tunnelConn.GetConn(), isWebSocketUpgrade(response))
+	defer stopMonitor()
 	errChan := make(chan error, 2)
 
 	// Copy from client to tunnel
 	go func() {
-		n, err := io.Copy(tunnelConn.GetConn(), clientConn)
+		n, err := io.Copy(tunnelConn.GetConn(), monitor.reader(clientConn))
 		if s.usageRecorder != nil {
 			if userID, tunnelID, ok := s.connections.GetDomainOwner(domain); ok && n > 0 {
 				s.usageRecorder.Increment(userID, tunnelID, domain, n, 0, 0)
@@ -1276,7 +1507,7 @@ func (s *TunnelServer) proxyWebSocketConnectionInternal(domain string, clientCon
 
 	// Copy from tunnel to client
 	go func() {
-		n, err := io.Copy(clientConn, tunnelConn.GetConn())
+		n, err := io.Copy(clientConn, monitor.reader(tunnelConn.GetConn()))
 		if s.usageRecorder != nil {
 			if userID, tunnelID, ok := s.connections.GetDomainOwner(domain); ok && n > 0 {
 				s.usageRecorder.Increment(userID, tunnelID, domain, 0, n, 1)
@@ -1299,15 +1530,29 @@ func (s *TunnelServer) proxyWebSocketConnectionInternal(domain string, clientCon
 	return nil
 }
 
+// watchWebSocketIdle closes a proxied WebSocket once no bytes have moved through the
+// returned monitor's readers for the configured idle timeout. Call stop when proxying ends.
+func (s *TunnelServer) watchWebSocketIdle(domain string, clientConn, tunnelConn net.Conn, isWebSocket bool) (*wsIdleMonitor, func()) {
+	monitor := newWSIdleMonitor(s.streamConfig.WebSocketIdleTimeout, s.streamConfig.WebSocketPingInterval)
+	done := make(chan struct{})
+	go func() {
+		// The tunnel leads to the local service, the WebSocket server: frames to it are masked
+		if monitor.run(done, wsPeer{conn: clientConn}, wsPeer{conn: tunnelConn, masked: true}, isWebSocket) {
+			s.logger.Info("[WEBSOCKET] Closed WebSocket for domain %s after %v without traffic", domain, monitor.idleTimeout)
+		}
+	}()
+	return monitor, func() { close(done) }
+}
+
 // getConnectionMemoryOverhead estimates memory overhead per connection
 func (s *TunnelServer) getConnectionMemoryOverhead() float64 {
 	// Estimate memory per connection:
-	// - TCP connection buffers (kernel): ~16KB each (read + write)
+	// - TCP connection buffers (kernel): ~16KB (read + write), or the configured sizes
 	// - TLS buffers: ~32KB each //
 	// - Application buffers: MediaBufferSize + RegularBufferSize
 	// - Connection struct + metadata: ~1KB
 
-	tcpBuffers := 16.0 * 1024 // 16KB kernel buffers
+	tcpBuffers := s.socketBufferBytes()
 	tlsBuffers := 32.0 * 1024 // 32KB TLS buffers
 	appBuffers := float64(s.streamConfig.MediaBufferSize + s.streamConfig.RegularBufferSize)
 	metadata := 1.0 * 1024 // 1KB struct overhead
@@ -1316,45 +1561,106 @@ func (s *TunnelServer) getConnectionMemoryOverhead() float64 {
 	return totalBytesPerConn / 1024.0 / 1024.0 // Convert to MB
 }
 
+// socketBufferBytes estimates the kernel buffer memory of one connection. Unset sizes
+// count as half of the 16KB default each; Linux reserves twice a requested size for
+// bookkeeping, so configured sizes count double.
+func (s *TunnelServer) socketBufferBytes() float64 {
+	const defaultPerDirection = 8.0 * 1024
+	total := 0.0
+	for _, size := range []int{s.streamConfig.TCPReadBufferSize, s.streamConfig.TCPWriteBufferSize} {
+		if size > 0 {
+			total += 2 * float64(size)
+		} else {
+			total += defaultPerDirection
+		}
+	}
+	return total
+}
+
 // (removed) ProxyConnectionOnTheFly was deprecated and is no longer used
 
-// createFreshTunnelConnection creates a new tunnel connection on-demand
+// createFreshTunnelConnection creates a new tunnel connection on-demand.
+// Clients sit behind NAT, so instead of dialing the client directly we look up its last
+// known address, ask it over the gRPC control channel to dial back in, and wait for the
+// handshake carrying our request ID. The returned connection is never added to the pool;
+// the caller owns it and must close it.
 func (s *TunnelServer) createFreshTunnelConnection(domain string) (*TunnelConnection, error) {
-	// CURRENT LIMITATION: This is still a fallback to existing pool
-	// TODO: Implement true on-demand connection creation by connecting back to client
+	if s.onRequestFreshTunnel == nil {
+		return nil, fmt.Errorf("no tunnel available for domain: %s (on-demand creation not configured)", domain)
+	}
 
-	// Try to get from pool first
-	tunnelConn := s.connections.GetHTTPConnection(domain)
-	if tunnelConn != nil {
-		s.logger.Debug("[HYBRID] Got existing connection from pool for on-demand request")
-		return tunnelConn, nil
+	// Look up the client address for this domain
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	tunnel, err := s.tunnelRepo.GetByDomain(ctx, domain)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up tunnel for domain %s: %w", domain, err)
+	}
+	if tunnel.ClientIP == "" {
+		return nil, fmt.Errorf("client for domain %s is unreachable: no client address on record", domain)
 	}
 
-	// Pool is empty - wait a brief moment for new connections to be established
-	s.logger.Info("[HYBRID] Pool empty, waiting briefly for new connections...")
-	time.Sleep(100 * time.Millisecond)
+	requestID := fmt.Sprintf("fresh-%d", time.Now().UnixNano())
+	waiter := make(chan *TunnelConnection, 1)
+	s.freshWaitersMu.Lock()
+	s.freshWaiters[requestID] = waiter
+	s.freshWaitersMu.Unlock()
 
-	// Try again after brief wait
-	tunnelConn = s.connections.GetHTTPConnection(domain)
-	if tunnelConn != nil {
-		s.logger.Debug("[HYBRID] Got connection after brief wait")
+	s.logger.Info("[HYBRID] Requesting on-demand connection from client %s for domain: %s (requestID: %s)", tunnel.ClientIP, domain, requestID)
+	if err := s.onRequestFreshTunnel(domain, requestID); err != nil {
+		s.cancelFreshWaiter(requestID)
+		return nil, fmt.Errorf("client %s for domain %s is unreachable: %w", tunnel.ClientIP, domain, err)
+	}
+
+	timeout := s.streamConfig.FreshConnectionTimeout
+	if timeout <= 0 {
+		timeout = DefaultFreshConnectionTimeout
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case tunnelConn := <-waiter:
 		return tunnelConn, nil
+	case <-timer.C:
+		if s.cancelFreshWaiter(requestID) {
+			return nil, fmt.Errorf("client %s for domain %s did not open an on-demand connection within %v", tunnel.ClientIP, domain, timeout)
+		}
+		// Delivered concurrently with the timeout - the connection is already buffered
+		return <-waiter, nil
 	}
+}
 
-	// Still no connections available
-	// In a real on-the-fly implementation, you would:
-	// 1. Look up the client IP/port for this domain from tunnel repository
-	// 2. Establish a new TCP connection to that client
-	// 3. Perform handshake with client to create fresh tunnel
-	// 4. Return the fresh connection (not added to pool)
+// deliverFreshConnection hands an incoming on-demand connection to its waiting request.
+// Returns false if nobody is waiting for requestID (e.g. it already timed out).
+func (s *TunnelServer) deliverFreshConnection(requestID string, tunnelConn *TunnelConnection) bool {
+	s.freshWaitersMu.Lock()
+	defer s.freshWaitersMu.Unlock()
 
-	s.logger.Error("[HYBRID] No connections available and true on-demand not yet implemented")
-	return nil, fmt.Errorf("no tunnel available for domain: %s (pool empty, true on-demand not implemented)", domain)
+	waiter, ok := s.freshWaiters[requestID]
+	if !ok {
+		return false
+	}
+	delete(s.freshWaiters, requestID)
+	waiter <- tunnelConn // Buffered, never blocks
+	return true
+}
+
+// cancelFreshWaiter removes a pending waiter, returning false if it was already delivered
+func (s *TunnelServer) cancelFreshWaiter(requestID string) bool {
+	s.freshWaitersMu.Lock()
+	defer s.freshWaitersMu.Unlock()
+
+	if _, ok := s.freshWaiters[requestID]; !ok {
+		return false
+	}
+	delete(s.freshWaiters, requestID)
+	return true
 }
 
 // shouldKeepInHotPool determines if a connection should stay in the hot pool (less aggressive to maintain stability)
-func (s *TunnelServer) shouldKeepInHotPool(tunnelConn *TunnelConnection, response *http.Response) bool {
-	// Be LESS aggressive to maintain hot pool stability until true on-demand is implemented
+func (s *TunnelServer) shouldKeepInHotPool(domain string, tunnelConn *TunnelConnection, response *http.Response) bool {
+	// Be LESS aggressive to maintain hot pool stability
 
 	// NEVER keep connections with "Connection: close" header
 	if response != nil {
@@ -1364,15 +1670,16 @@ func (s *TunnelServer) shouldKeepInHotPool(tunnelConn *TunnelConnection, respons
 		}
 	}
 
-	// NEVER keep connections that have handled too many requests (increased from 3 to 15)
+	// NEVER keep connections that have handled too many requests, unless sessions are bound to them
 	requestCount := tunnelConn.GetRequestCount()
-	if requestCount > 15 {
+	sticky := s.streamConfig.EnableStickySessions && s.connections.IsStickyHTTPConnection(domain, tunnelConn)
+	if requestCount > int64(s.streamConfig.HotPoolMaxRequests) && !sticky {
 		s.logger.Debug("[HYBRID] Connection handled %d requests, too many for hot pool", requestCount)
 		return false
 	}
 
-	// NEVER keep connections older than 5 minutes (increased from 30 seconds)
-	if time.Since(tunnelConn.GetCreatedAt()) > 5*time.Minute {
+	// NEVER keep connections older than HotPoolMaxAge
+	if time.Since(tunnelConn.GetCreatedAt()) > s.streamConfig.HotPoolMaxAge {
 		s.logger.Debug("[HYBRID] Connection is %v old, too old for hot pool", time.Since(tunnelConn.GetCreatedAt()))
 		return false
 	}
@@ -1432,30 +1739,27 @@ func (s *TunnelServer) proxyWithOnDemandFallback(domain string, conn net.Conn, r
 	}
 
 	// Set timeout and read response
-	timeout := s.getAdaptiveTimeout(false)
+	timeout := s.getRequestTimeout(false)
 	tunnelConn.GetConn().SetReadDeadline(time.Now().Add(timeout))
 	defer tunnelConn.GetConn().SetReadDeadline(time.Time{})
 
-	tunnelReader := bufio.NewReader(tunnelConn.GetConn())
-	response, err := http.ReadResponse(tunnelReader, nil)
+	tunnelReader := newTunnelResponseReader(tunnelConn.GetConn(), s.streamConfig.MaxHeaderBytes)
+	response, err := readTunnelResponse(tunnelReader, nil, s.streamConfig.MaxHeaderBytes)
 	if err != nil {
 		s.logger.Error("[HYBRID] Fallback failed to read response: %v", err)
 		s.writeHTTPError(conn, 502, "Bad Gateway - Fallback response failed")
 		return
 	}
 
+	s.recordSuccess(domain)
+	s.applyOriginTimeout(domain, tunnelConn.GetConn(), response, timeout)
+
 	// Write response to client
-	clientWriter := bufio.NewWriter(conn)
-	if err := response.Write(clientWriter); err != nil {
+	if _, err := s.writeResponse(conn, response); err != nil {
 		s.logger.Debug("[HYBRID] Fallback error writing to client: %v", err)
 		return
 	}
 
-	if err := clientWriter.Flush(); err != nil {
-		s.logger.Debug("[HYBRID] Fallback error flushing: %v", err)
-		return
-	}
-
 	s.logger.Debug("[HYBRID] Fallback completed successfully")
 }
 
@@ -1506,21 +1810,21 @@ func (s *TunnelServer) proxyMediaRequestHybrid(domain string, clientConn net.Con
 		mediaTimeout = 8 * time.Second
 		s.logger.Debug("[HYBRID MEDIA] System stressed (%d concurrent vs %d pool), using moderate 8s timeout", currentConcurrent, poolSize)
 	} else {
-		mediaTimeout = s.getAdaptiveTimeout(true)
+		mediaTimeout = s.getRequestTimeout(true)
 	}
 
 	tunnelConn.GetConn().SetReadDeadline(time.Now().Add(mediaTimeout))
 	defer tunnelConn.GetConn().SetReadDeadline(time.Time{})
 
 	// Read the HTTP response from the tunnel
-	tunnelReader := bufio.NewReader(tunnelConn.GetConn())
-	response, err := http.ReadResponse(tunnelReader, nil)
+	tunnelReader := newTunnelResponseReader(tunnelConn.GetConn(), s.streamConfig.MaxHeaderBytes)
+	response, err := readTunnelResponse(tunnelReader, nil, s.streamConfig.MaxHeaderBytes)
 	if err != nil {
 		s.logger.Error("[HYBRID MEDIA] Error reading response: %v", err)
 
 		// Record timeout for circuit breaker if it's a timeout error
 		if strings.Contains(err.Error(), "timeout") || strings.Contains(err.Error(), "deadline") {
-			s.recordTimeout()
+			s.recordFailure(domain)
 
 			// Under stress, just fail fast
 			if currentConcurrent > poolSize*4 { // Proportional to hot pool size (5*4=20, but scales)
@@ -1542,6 +1846,9 @@ func (s *TunnelServer) proxyMediaRequestHybrid(domain string, clientConn net.Con
 		return
 	}
 
+	s.recordSuccess(domain)
+	s.applyOriginTimeout(domain, tunnelConn.GetConn(), response, mediaTimeout)
+
 	s.logger.Info("[HYBRID MEDIA] Received response: %s", response.Status)
 
 	// Quick client disconnection check
@@ -1556,22 +1863,8 @@ func (s *TunnelServer) proxyMediaRequestHybrid(domain string, clientConn net.Con
 	}
 
 	// Write the response back to the client
-	clientWriter := bufio.NewWriter(clientConn)
-	if err := response.Write(clientWriter); err != nil {
-		if s.isClientDisconnectionError(err) {
-			s.logger.Debug("[HYBRID MEDIA] Client closed connection during streaming: %v", err)
-		} else {
-			s.logger.Debug("[HYBRID MEDIA] Error writing response to client: %v", err)
-		}
-		return
-	}
-
-	if err := clientWriter.Flush(); err != nil {
-		if s.isClientDisconnectionError(err) {
-			s.logger.Debug("[HYBRID MEDIA] Client disconnected during flush: %v", err)
-		} else {
-			s.logger.Debug("[HYBRID MEDIA] Error flushing response: %v", err)
-		}
+	if synced, err := s.writeResponse(clientConn, response); err != nil {
+		s.abandonResponse(domain, tunnelConn, isOnDemand, synced, err)
 		return
 	}
 
@@ -1584,7 +1877,7 @@ func (s *TunnelServer) proxyMediaRequestHybrid(domain string, clientConn net.Con
 		go tunnelConn.Close()
 	} else {
 		// Hot pool connections: Be very aggressive about media connections
-		if !s.shouldKeepInHotPool(tunnelConn, response) {
+		if !s.shouldKeepInHotPool(domain, tunnelConn, response) {
 			s.logger.Debug("[HYBRID MEDIA] Media connection not suitable for hot pool, removing")
 			s.connections.RemoveSpecificHTTPConnection(domain, tunnelConn)
 			go tunnelConn.Close()
@@ -0,0 +1,279 @@
+package tunnel
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EndpointSelection selects the order in which the tunnel servers of a ServerConfig are tried
+type EndpointSelection string
+
+const (
+	// EndpointOrder tries the servers in the order they are configured (default)
+	EndpointOrder EndpointSelection = "order"
+	// EndpointLatency tries the servers that answer a TCP connect fastest first
+	EndpointLatency EndpointSelection = "latency"
+)
+
+// ParseEndpointSelection validates s ("" = EndpointOrder)
+func ParseEndpointSelection(s string) (EndpointSelection, error) {
+	switch selection := EndpointSelection(strings.ToLower(strings.TrimSpace(s))); selection {
+	case "":
+		return EndpointOrder, nil
+	case EndpointOrder, EndpointLatency:
+		return selection, nil
+	default:
+		return "", fmt.Errorf("unsupported endpoint selection %q (use order or latency)", s)
+	}
+}
+
+// EndpointStateFileName is the file in the config directory remembering the tunnel server
+// that last accepted the tunnel
+const EndpointStateFileName = "last_server.json"
+
+// endpointProbeTimeout bounds the TCP connect measuring a server's latency
+const endpointProbeTimeout = 3 * time.Second
+
+// endpointState is the on-disk format of EndpointStateFileName
+type endpointState struct {
+	Host        string    `json:"host"`
+	ConnectedAt time.Time `json:"connected_at"`
+}
+
+// ServerEndpoints is the list of tunnel servers a client fails over between. The server
+// that last accepted the tunnel is tried first, also after a restart.
+type ServerEndpoints struct {
+	hosts     []string
+	byLatency bool
+	statePath string // Where the last good server is remembered ("" = not persisted)
+
+	mu       sync.Mutex
+	lastGood string
+}
+
+// NewServerEndpoints returns the failover list for server's Host and Hosts, or nil when
+// there is only one server to connect to
+func NewServerEndpoints(server ServerConfig) *ServerEndpoints {
+	hosts := server.AllHosts()
+	if len(hosts) < 2 {
+		return nil
+	}
+	selection, _ := ParseEndpointSelection(string(server.EndpointSelection))
+	statePath := ""
+	if dir, err := GetInstanceDir(); err == nil {
+		statePath = filepath.Join(dir, EndpointStateFileName)
+	}
+	return newServerEndpoints(hosts, selection == EndpointLatency, statePath)
+}
+
+func newServerEndpoints(hosts []string, byLatency bool, statePath string) *ServerEndpoints {
+	e := &ServerEndpoints{hosts: hosts, byLatency: byLatency, statePath: statePath}
+	if statePath == "" {
+		return e
+	}
+	data, err := os.ReadFile(statePath)
+	if err != nil {
+		return e
+	}
+	var state endpointState
+	if json.Unmarshal(data, &state) == nil && e.contains(state.Host) {
+		e.lastGood = state.Host
+	}
+	return e
+}
+
+// Hosts returns the configured tunnel servers
+func (e *ServerEndpoints) Hosts() []string {
+	return append([]string(nil), e.hosts...)
+}
+
+// LastGood returns the server that last accepted the tunnel ("" = none yet)
+func (e *ServerEndpoints) LastGood() string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.lastGood
+}
+
+func (e *ServerEndpoints) contains(host string) bool {
+	for _, h := range e.hosts {
+		if strings.EqualFold(h, host) {
+			return true
+		}
+	}
+	return false
+}
+
+// ordered returns the servers in the order to try them: the last good one first, then the
+// rest as configured or, by latency, fastest first with unreachable servers last
+func (e *ServerEndpoints) ordered(measure func(host string) (time.Duration, error)) []string {
+	hosts := e.Hosts()
+	if e.byLatency && measure != nil {
+		latencies := make([]time.Duration, len(hosts))
+		var wg sync.WaitGroup
+		for i, host := range hosts {
+			wg.Add(1)
+			go func(i int, host string) {
+				defer wg.Done()
+				latency, err := measure(host)
+				if err != nil {
+					latency = endpointProbeTimeout + time.Duration(i) // Unreachable: last, in configured order
+				}
+				latencies[i] = latency
+			}(i, host)
+		}
+		wg.Wait()
+		byHost := make(map[string]time.Duration, len(hosts))
+		for i, host := range hosts {
+			byHost[host] = latencies[i]
+		}
+		sort.SliceStable(hosts, func(i, j int) bool { return byHost[hosts[i]] < byHost[hosts[j]] })
+	}
+
+	if last := e.LastGood(); last != "" {
+		for i, host := range hosts {
+			if strings.EqualFold(host, last) {
+				copy(hosts[1:i+1], hosts[:i])
+				hosts[0] = host
+				break
+			}
+		}
+	}
+	return hosts
+}
+
+// after returns the server to fail over to when host is unreachable, in configured order
+func (e *ServerEndpoints) after(host string) string {
+	for i, h := range e.hosts {
+		if strings.EqualFold(h, host) {
+			return e.hosts[(i+1)%len(e.hosts)]
+		}
+	}
+	return e.hosts[0]
+}
+
+// succeeded remembers host as the server to try first next time
+func (e *ServerEndpoints) succeeded(host string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if strings.EqualFold(host, e.lastGood) || !e.contains(host) {
+		return nil
+	}
+	e.lastGood = host
+	if e.statePath == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(endpointState{Host: host, ConnectedAt: time.Now().UTC()}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(e.statePath), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(e.statePath, data, 0644)
+}
+
+// serverCandidates returns the TCP tunnel addresses to try, in order, for a connection to
+// serverAddr: just serverAddr without failover, otherwise every server on its port
+func (t *Tunnel) serverCandidates(serverAddr string) []string {
+	if t.endpoints == nil {
+		return []string{serverAddr}
+	}
+	_, port, err := net.SplitHostPort(serverAddr)
+	if err != nil {
+		return []string{serverAddr}
+	}
+	// A connected gRPC tunnel is reused, so new TCP tunnels must go to its server
+	if t.grpcClient != nil && t.grpcClient.IsConnected() {
+		if host, _, err := net.SplitHostPort(t.grpcClient.GetServerAddr()); err == nil {
+			return []string{net.JoinHostPort(host, port)}
+		}
+	}
+
+	hosts := t.endpoints.ordered(func(host string) (time.Duration, error) {
+		ctx, cancel := context.WithTimeout(t.ctx, endpointProbeTimeout)
+		defer cancel()
+		start := time.Now()
+		conn, err := t.serverDialer.DialContext(ctx, "tcp", net.JoinHostPort(host, port))
+		if err != nil {
+			return 0, err
+		}
+		conn.Close()
+		return time.Since(start), nil
+	})
+	candidates := make([]string, len(hosts))
+	for i, host := range hosts {
+		candidates[i] = net.JoinHostPort(host, port)
+	}
+	return candidates
+}
+
+// attemptServers runs attemptDualConnections against each candidate server until one
+// accepts the tunnel and returns its address. Errors any server would answer the same
+// (authentication, quota) end the round early.
+func (t *Tunnel) attemptServers(serverAddr string, tlsConfig *tls.Config) (string, error) {
+	candidates := t.serverCandidates(serverAddr)
+	var err error
+	for i, addr := range candidates {
+		if i > 0 {
+			t.logger.Warn("Tunnel server %s failed (%v), failing over to %s", candidates[i-1], err, addr)
+		}
+		if err = t.attemptDualConnections(addr, tlsConfig); err == nil {
+			t.serverConnected(addr)
+			return addr, nil
+		}
+		if isAuthenticationError(err) || errors.Is(err, ErrQuotaExceeded) || t.ctx.Err() != nil {
+			break
+		}
+	}
+	return "", err
+}
+
+// serverConnected remembers the server at addr as the one to try first next time
+func (t *Tunnel) serverConnected(addr string) {
+	if t.endpoints == nil {
+		return
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return
+	}
+	if err := t.endpoints.succeeded(host); err != nil {
+		t.logger.Warn("Failed to remember tunnel server %s: %v", host, err)
+	}
+}
+
+// serverReconnected follows the gRPC client onto the server it reconnected to, which
+// also takes the TCP tunnels
+func (t *Tunnel) serverReconnected() {
+	if t.endpoints == nil || t.grpcClient == nil {
+		return
+	}
+	host, _, err := net.SplitHostPort(t.grpcClient.GetServerAddr())
+	if err != nil {
+		return
+	}
+	if _, port, err := net.SplitHostPort(t.tcpServerAddr); err == nil {
+		t.tcpServerAddr = net.JoinHostPort(host, port)
+	}
+	t.serverConnected(t.tcpServerAddr)
+}
+
+// nextGRPCServer is the gRPC client's failover: the next server on the same gRPC port
+func (t *Tunnel) nextGRPCServer(failed string) string {
+	host, port, err := net.SplitHostPort(failed)
+	if err != nil {
+		return ""
+	}
+	return net.JoinHostPort(t.endpoints.after(host), port)
+}
@@ -0,0 +1,53 @@
+package tunnel
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestServerEndpoints(t *testing.T) {
+	server := ServerConfig{Host: "a.example.com", Hosts: []string{"b.example.com", "A.example.com", " ", "c.example.com"}}
+	if hosts := server.AllHosts(); !reflect.DeepEqual(hosts, []string{"a.example.com", "b.example.com", "c.example.com"}) {
+		t.Fatalf("AllHosts = %v", hosts)
+	}
+	if NewServerEndpoints(ServerConfig{Host: "a.example.com"}) != nil {
+		t.Error("failover list for a single server")
+	}
+
+	statePath := filepath.Join(t.TempDir(), EndpointStateFileName)
+	e := newServerEndpoints(server.AllHosts(), false, statePath)
+	if hosts := e.ordered(nil); !reflect.DeepEqual(hosts, []string{"a.example.com", "b.example.com", "c.example.com"}) {
+		t.Errorf("configured order: %v", hosts)
+	}
+	if next := e.after("c.example.com"); next != "a.example.com" {
+		t.Errorf("after the last server: %s", next)
+	}
+
+	// The last good server goes first, also for the next process
+	if err := e.succeeded("b.example.com"); err != nil {
+		t.Fatal(err)
+	}
+	e = newServerEndpoints(server.AllHosts(), true, statePath)
+	if e.LastGood() != "b.example.com" {
+		t.Fatalf("last good server not remembered: %q", e.LastGood())
+	}
+	latencies := map[string]time.Duration{"a.example.com": 50 * time.Millisecond, "c.example.com": 10 * time.Millisecond}
+	hosts := e.ordered(func(host string) (time.Duration, error) {
+		if latency, ok := latencies[host]; ok {
+			return latency, nil
+		}
+		return 0, errors.New("unreachable")
+	})
+	if !reflect.DeepEqual(hosts, []string{"b.example.com", "c.example.com", "a.example.com"}) {
+		t.Errorf("by latency after the last good server: %v", hosts)
+	}
+
+	// A remembered server that is no longer configured is ignored
+	e = newServerEndpoints([]string{"a.example.com", "c.example.com"}, false, statePath)
+	if e.LastGood() != "" {
+		t.Errorf("unconfigured server remembered: %q", e.LastGood())
+	}
+}
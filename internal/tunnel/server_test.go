@@ -0,0 +1,156 @@
+package tunnel
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/osa911/giraffecloud/internal/db/ent"
+	"github.com/osa911/giraffecloud/internal/logging"
+	"github.com/osa911/giraffecloud/internal/repository"
+)
+
+// fakeTunnelRepo answers GetByDomain with a fixed tunnel
+type fakeTunnelRepo struct {
+	repository.TunnelRepository
+	tunnel *ent.Tunnel
+}
+
+func (r *fakeTunnelRepo) GetByDomain(ctx context.Context, domain string) (*ent.Tunnel, error) {
+	return r.tunnel, nil
+}
+
+func TestTunnelServer_Drain(t *testing.T) {
+	initTestLogger(t)
+
+	// In-flight request finishes before the deadline
+	s := &TunnelServer{logger: logging.GetGlobalLogger(), connections: NewConnectionManager()}
+	atomic.StoreInt64(&s.concurrentReqs, 1)
+	go func() {
+		time.Sleep(150 * time.Millisecond)
+		atomic.AddInt64(&s.concurrentReqs, -1)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := s.Drain(ctx); err != nil {
+		t.Errorf("Expected drain to complete, got %v", err)
+	}
+	if atomic.LoadInt32(&s.draining) != 1 {
+		t.Error("Expected server to be marked as draining")
+	}
+
+	// In-flight request outlives the deadline
+	s = &TunnelServer{logger: logging.GetGlobalLogger(), connections: NewConnectionManager()}
+	atomic.StoreInt64(&s.concurrentReqs, 1)
+
+	ctx, cancel = context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if err := s.Drain(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestTunnelServer_SocketBuffers(t *testing.T) {
+	s := &TunnelServer{logger: logging.GetGlobalLogger(), streamConfig: DefaultStreamingConfig()}
+	defaultOverhead := s.getConnectionMemoryOverhead()
+	if got := s.socketBufferBytes(); got != 16*1024 {
+		t.Errorf("default socket buffers = %v bytes, want 16KB", got)
+	}
+
+	s.streamConfig.TCPReadBufferSize = 1 << 20
+	s.streamConfig.TCPWriteBufferSize = 1 << 20
+	if got := s.socketBufferBytes(); got != 4<<20 {
+		t.Errorf("configured socket buffers = %v bytes, want 4MB", got)
+	}
+	if s.getConnectionMemoryOverhead() <= defaultOverhead {
+		t.Error("memory overhead estimate ignores the configured buffer sizes")
+	}
+
+	// The listener returns TLS connections; the buffers go on the TCP socket beneath
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	client, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	s.applySocketBuffers(tls.Server(conn, &tls.Config{}))
+}
+
+func TestTunnelServer_FreshConnection(t *testing.T) {
+	initTestLogger(t)
+	const timeout = 100 * time.Millisecond
+
+	s := &TunnelServer{
+		logger:       logging.GetGlobalLogger(),
+		streamConfig: &StreamingConfig{FreshConnectionTimeout: timeout},
+		tunnelRepo:   &fakeTunnelRepo{tunnel: &ent.Tunnel{Domain: "app.example.com", ClientIP: "203.0.113.7"}},
+		freshWaiters: make(map[string]chan *TunnelConnection),
+	}
+	waiting := func() int {
+		s.freshWaitersMu.Lock()
+		defer s.freshWaitersMu.Unlock()
+		return len(s.freshWaiters)
+	}
+
+	// The client dials in with the request ID: the connection goes to the waiting request
+	serverSide, clientSide := net.Pipe()
+	defer clientSide.Close()
+	s.SetRequestFreshTunnelCallback(func(domain, requestID string) error {
+		go s.deliverFreshConnection(requestID, NewTunnelConnection(domain, serverSide, 3000))
+		return nil
+	})
+	conn, err := s.createFreshTunnelConnection("app.example.com")
+	if err != nil || conn.GetConn() != serverSide {
+		t.Fatalf("expected the dialed-in connection, got %v (%v)", conn, err)
+	}
+	if n := waiting(); n != 0 {
+		t.Errorf("%d waiters left after delivery", n)
+	}
+
+	// The client can't be asked: the waiter is cancelled at once
+	s.SetRequestFreshTunnelCallback(func(domain, requestID string) error {
+		return errors.New("no active gRPC tunnel")
+	})
+	if _, err := s.createFreshTunnelConnection("app.example.com"); err == nil {
+		t.Error("expected an error when the establish request can't be sent")
+	}
+	if n := waiting(); n != 0 {
+		t.Errorf("%d waiters left after a failed request", n)
+	}
+
+	// The client never dials in: timeout, and a late connection finds nobody waiting
+	var lateID string
+	s.SetRequestFreshTunnelCallback(func(domain, requestID string) error {
+		lateID = requestID
+		return nil
+	})
+	start := time.Now()
+	if _, err := s.createFreshTunnelConnection("app.example.com"); err == nil {
+		t.Error("expected a timeout when the client never dials in")
+	}
+	if elapsed := time.Since(start); elapsed < timeout || elapsed > 2*time.Second {
+		t.Errorf("timed out after %v, want about %v", elapsed, timeout)
+	}
+	if n := waiting(); n != 0 {
+		t.Errorf("%d waiters left after a timeout", n)
+	}
+	late, _ := net.Pipe()
+	defer late.Close()
+	if s.deliverFreshConnection(lateID, NewTunnelConnection("app.example.com", late, 3000)) {
+		t.Error("late connection delivered to a request that already timed out")
+	}
+}
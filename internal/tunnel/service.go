@@ -31,16 +31,18 @@ func NewServiceManager() (*ServiceManager, error) {
 	}, nil
 }
 
-func (sm *ServiceManager) Install() error {
+// Install installs the service for the current OS. With dryRun set, it only prints the
+// unit file contents and the commands it would run, without touching the system.
+func (sm *ServiceManager) Install(dryRun bool) error {
 	// Install service based on OS
 	var err error
 	switch runtime.GOOS {
 	case "darwin":
-		err = sm.installDarwin()
+		err = sm.installDarwin(dryRun)
 	case "linux":
-		err = sm.installLinux()
+		err = sm.installLinux(dryRun)
 	case "windows":
-		err = sm.installWindows()
+		err = sm.installWindows(dryRun)
 	default:
 		return fmt.Errorf("unsupported operating system: %s", runtime.GOOS)
 	}
@@ -49,6 +51,11 @@ func (sm *ServiceManager) Install() error {
 		return err
 	}
 
+	if dryRun {
+		fmt.Printf("# Would make %s available on PATH (skipped in dry run)\n", sm.executablePath)
+		return nil
+	}
+
 	// Add to PATH after successful service installation
 	// For user-level installs or when not running as root, skip system PATH updates to avoid sudo requirement
 	if runtime.GOOS == "linux" || runtime.GOOS == "darwin" {
@@ -88,7 +95,7 @@ func (sm *ServiceManager) Uninstall() error {
 	}
 }
 
-func (sm *ServiceManager) installDarwin() error {
+func (sm *ServiceManager) installDarwin(dryRun bool) error {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
 		return err
@@ -124,6 +131,12 @@ func (sm *ServiceManager) installDarwin() error {
 
 	plistPath := filepath.Join(homeDir, "Library/LaunchAgents/com.giraffecloud.tunnel.plist")
 
+	if dryRun {
+		printDryRunFile(plistPath, plistContent)
+		printDryRunCommand("launchctl", "load", plistPath)
+		return nil
+	}
+
 	if err := os.MkdirAll(filepath.Dir(plistPath), 0755); err != nil {
 		return err
 	}
@@ -165,7 +178,7 @@ func (sm *ServiceManager) uninstallDarwin() error {
 	return nil
 }
 
-func (sm *ServiceManager) installLinux() error {
+func (sm *ServiceManager) installLinux(dryRun bool) error {
 	if sm.useUserUnit {
 		// User-level systemd unit (~/.config/systemd/user)
 		// Expand explicit home for reliability
@@ -184,13 +197,22 @@ RestartSec=10
 [Install]
 WantedBy=default.target`, sm.executablePath, userHome)
 
-		// Ensure user unit directory exists
 		userDir := filepath.Join(os.Getenv("HOME"), ".config/systemd/user")
+		servicePath := filepath.Join(userDir, "giraffecloud.service")
+
+		if dryRun {
+			printDryRunFile(servicePath, serviceContent)
+			printDryRunCommand("systemctl", "--user", "daemon-reload")
+			printDryRunCommand("systemctl", "--user", "enable", "giraffecloud")
+			printDryRunCommand("systemctl", "--user", "start", "giraffecloud")
+			return nil
+		}
+
+		// Ensure user unit directory exists
 		if err := os.MkdirAll(userDir, 0755); err != nil {
 			return fmt.Errorf("failed to create user systemd directory: %w", err)
 		}
 
-		servicePath := filepath.Join(userDir, "giraffecloud.service")
 		if err := os.WriteFile(servicePath, []byte(serviceContent), 0644); err != nil {
 			return fmt.Errorf("failed to write user service file: %w", err)
 		}
@@ -212,7 +234,10 @@ WantedBy=default.target`, sm.executablePath, userHome)
 
 	// System-level unit: write config to the invoking user's home, not /root
 	if !isSystemdAvailable() {
-		return fmt.Errorf("systemd is not available on this host (no systemctl or /run/systemd/system). Service install cannot proceed")
+		if !dryRun {
+			return fmt.Errorf("systemd is not available on this host (no systemctl or /run/systemd/system). Service install cannot proceed")
+		}
+		fmt.Println("# Warning: systemd is not available on this host; a real install would fail")
 	}
 	// Determine the intended user and home directory for config
 	svcUser := os.Getenv("SUDO_USER")
@@ -253,6 +278,17 @@ StandardError=append:%s/tunnel.log
 [Install]
 WantedBy=multi-user.target`, sm.executablePath, userHome, logDir, logDir)
 
+	servicePath := filepath.Join(unitDir, "giraffecloud.service")
+
+	if dryRun {
+		printDryRunCommand("sudo", "mkdir", "-p", logDir)
+		printDryRunFile(servicePath, serviceContent)
+		printDryRunCommand("sudo", "systemctl", "daemon-reload")
+		printDryRunCommand("sudo", "systemctl", "enable", "giraffecloud")
+		printDryRunCommand("sudo", "systemctl", "start", "giraffecloud")
+		return nil
+	}
+
 	// Ensure log dir exists
 	if err := os.MkdirAll(logDir, 0755); err != nil {
 		if isInteractive() {
@@ -264,30 +300,8 @@ WantedBy=multi-user.target`, sm.executablePath, userHome, logDir, logDir)
 		}
 	}
 
-	servicePath := filepath.Join(unitDir, "giraffecloud.service")
-	if err := os.WriteFile(servicePath, []byte(serviceContent), 0644); err != nil {
-		// Permission denied – write via sudo using a temp file + install
-		tmpFile, terr := os.CreateTemp("", "giraffecloud.service.*.tmp")
-		if terr != nil {
-			return fmt.Errorf("failed to create temp service file: %w", terr)
-		}
-		tmpPath := tmpFile.Name()
-		if _, werr := tmpFile.WriteString(serviceContent); werr != nil {
-			tmpFile.Close()
-			_ = os.Remove(tmpPath)
-			return fmt.Errorf("failed to write temp service file: %w", werr)
-		}
-		tmpFile.Close()
-		if isInteractive() {
-			if ierr := exec.Command("sudo", "install", "-m", "0644", tmpPath, servicePath).Run(); ierr != nil {
-				_ = os.Remove(tmpPath)
-				return fmt.Errorf("failed to write service file with sudo: %w", ierr)
-			}
-			_ = os.Remove(tmpPath)
-		} else {
-			_ = os.Remove(tmpPath)
-			return fmt.Errorf("insufficient permissions to write '%s'. Run: sudo install -m 0644 <file> %s", servicePath, servicePath)
-		}
+	if err := writeUnitFile(servicePath, serviceContent); err != nil {
+		return err
 	}
 	// Reload/enable/start
 	if isInteractive() {
@@ -345,18 +359,34 @@ func (sm *ServiceManager) uninstallLinux() error {
 	return nil
 }
 
-func (sm *ServiceManager) installWindows() error {
+func (sm *ServiceManager) installWindows(dryRun bool) error {
 	// Create Windows service using sc.exe
 	serviceName := "GiraffeCloudTunnel"
 	displayName := "GiraffeCloud Tunnel Service"
 	description := "GiraffeCloud secure tunnel service for exposing local applications"
-
-	// Create the service
-	cmd := exec.Command("sc", "create", serviceName,
+	createArgs := []string{"create", serviceName,
 		"binPath=", fmt.Sprintf("\"%s\" connect", sm.executablePath),
 		"DisplayName=", displayName,
 		"start=", "auto",
-		"depend=", "Tcpip")
+		"depend=", "Tcpip"}
+	failureArgs := []string{"failure", serviceName,
+		"reset=", "86400", // Reset failure count after 24 hours
+		"actions=", "restart/5000/restart/10000/restart/30000"} // Restart after 5s, 10s, 30s
+
+	if dryRun {
+		printDryRunCommand("sc", createArgs...)
+		printDryRunCommand("sc", "description", serviceName, description)
+		printDryRunCommand("sc", failureArgs...)
+		if userHome, err := os.UserHomeDir(); err == nil && userHome != "" {
+			printDryRunCommand("reg", "add", `HKLM\\SYSTEM\\CurrentControlSet\\Services\\`+serviceName,
+				"/v", "Environment", "/t", "REG_MULTI_SZ", "/d", fmt.Sprintf("GIRAFFECLOUD_HOME=%s\\.giraffecloud", userHome), "/f")
+		}
+		printDryRunCommand("sc", "start", serviceName)
+		return nil
+	}
+
+	// Create the service
+	cmd := exec.Command("sc", createArgs...)
 
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("failed to create Windows service: %w", err)
@@ -370,9 +400,7 @@ func (sm *ServiceManager) installWindows() error {
 	}
 
 	// Configure service to restart on failure
-	cmd = exec.Command("sc", "failure", serviceName,
-		"reset=", "86400", // Reset failure count after 24 hours
-		"actions=", "restart/5000/restart/10000/restart/30000") // Restart after 5s, 10s, 30s
+	cmd = exec.Command("sc", failureArgs...)
 	if err := cmd.Run(); err != nil {
 		// Don't fail if failure action setting fails
 		sm.logger.Info("Warning: Failed to set service failure actions")
@@ -651,6 +679,54 @@ func isSystemdAvailable() bool {
 	return false
 }
 
+// printDryRunFile prints a file that an install would write
+func printDryRunFile(path, content string) {
+	fmt.Printf("# Would write %s:\n%s\n\n", path, content)
+}
+
+// printDryRunCommand prints a command that an install would run
+func printDryRunCommand(name string, args ...string) {
+	quoted := make([]string, 0, len(args)+1)
+	quoted = append(quoted, name)
+	for _, arg := range args {
+		if strings.ContainsAny(arg, " \t\"") {
+			arg = fmt.Sprintf("%q", arg)
+		}
+		quoted = append(quoted, arg)
+	}
+	fmt.Printf("# Would run:\n%s\n\n", strings.Join(quoted, " "))
+}
+
+// writeUnitFile writes a system unit file, falling back to sudo install when the
+// current user can't write it
+func writeUnitFile(path, content string) error {
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		// Permission denied – write via sudo using a temp file + install
+		tmpFile, terr := os.CreateTemp("", "giraffecloud.service.*.tmp")
+		if terr != nil {
+			return fmt.Errorf("failed to create temp service file: %w", terr)
+		}
+		tmpPath := tmpFile.Name()
+		if _, werr := tmpFile.WriteString(content); werr != nil {
+			tmpFile.Close()
+			_ = os.Remove(tmpPath)
+			return fmt.Errorf("failed to write temp service file: %w", werr)
+		}
+		tmpFile.Close()
+		if isInteractive() {
+			if ierr := exec.Command("sudo", "install", "-m", "0644", tmpPath, path).Run(); ierr != nil {
+				_ = os.Remove(tmpPath)
+				return fmt.Errorf("failed to write service file with sudo: %w", ierr)
+			}
+			_ = os.Remove(tmpPath)
+		} else {
+			_ = os.Remove(tmpPath)
+			return fmt.Errorf("insufficient permissions to write '%s'. Run: sudo install -m 0644 <file> %s", path, path)
+		}
+	}
+	return nil
+}
+
 func isInteractive() bool {
 	fi, err := os.Stdin.Stat()
 	if err != nil {
@@ -0,0 +1,407 @@
+package tunnel
+
+import (
+	"encoding/xml"
+	"fmt"
+	"html"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// windowsServiceKey is the registry key of the Windows service; its Environment value
+// (REG_MULTI_SZ) holds the service's environment variables
+const windowsServiceKey = `HKLM\SYSTEM\CurrentControlSet\Services\GiraffeCloudTunnel`
+
+var envKeyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ServiceEnv returns the environment variables set in the installed service definition
+func (sm *ServiceManager) ServiceEnv() (map[string]string, error) {
+	switch runtime.GOOS {
+	case "darwin":
+		plist, err := os.ReadFile(sm.darwinPlistPath())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read service definition: %w", err)
+		}
+		return launchdEnv(string(plist)), nil
+	case "linux":
+		unit, err := os.ReadFile(sm.linuxUnitPath())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read service definition: %w", err)
+		}
+		return systemdEnv(string(unit)), nil
+	case "windows":
+		entries, err := readWindowsServiceEnv()
+		if err != nil {
+			return nil, err
+		}
+		env := make(map[string]string)
+		for _, entry := range entries {
+			if key, value, ok := strings.Cut(entry, "="); ok {
+				env[key] = value
+			}
+		}
+		return env, nil
+	default:
+		return nil, fmt.Errorf("unsupported operating system: %s", runtime.GOOS)
+	}
+}
+
+// EditEnv sets and unsets environment variables in the installed service definition
+// (Environment= lines for systemd, EnvironmentVariables for launchd, the registry
+// Environment value on Windows) and reloads it. With restart set, a running service is
+// restarted so it picks them up.
+func (sm *ServiceManager) EditEnv(set map[string]string, unset []string, restart bool) error {
+	for key, value := range set {
+		if !envKeyPattern.MatchString(key) {
+			return fmt.Errorf("invalid environment variable name: %q", key)
+		}
+		if strings.ContainsAny(value, "\r\n\x00") {
+			return fmt.Errorf("value of %s must be a single line", key)
+		}
+	}
+	for _, key := range unset {
+		if !envKeyPattern.MatchString(key) {
+			return fmt.Errorf("invalid environment variable name: %q", key)
+		}
+	}
+
+	var err error
+	switch runtime.GOOS {
+	case "darwin":
+		err = sm.editEnvDarwin(set, unset)
+	case "linux":
+		err = sm.editEnvLinux(set, unset)
+	case "windows":
+		err = editEnvWindows(set, unset)
+	default:
+		return fmt.Errorf("unsupported operating system: %s", runtime.GOOS)
+	}
+	if err != nil || !restart {
+		return err
+	}
+
+	running, _ := sm.IsRunning()
+	if !running {
+		sm.logger.Info("Service is not running; the new environment applies on next start")
+		return nil
+	}
+	return sm.Restart()
+}
+
+func (sm *ServiceManager) darwinPlistPath() string {
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, "Library/LaunchAgents/com.giraffecloud.tunnel.plist")
+}
+
+func (sm *ServiceManager) linuxUnitPath() string {
+	if sm.useUserUnit {
+		return filepath.Join(os.Getenv("HOME"), ".config/systemd/user/giraffecloud.service")
+	}
+	unitDir := os.Getenv("GIRAFFECLOUD_UNIT_DIR")
+	if unitDir == "" {
+		unitDir = "/etc/systemd/system"
+	}
+	return filepath.Join(unitDir, "giraffecloud.service")
+}
+
+func (sm *ServiceManager) editEnvDarwin(set map[string]string, unset []string) error {
+	plistPath := sm.darwinPlistPath()
+	plist, err := os.ReadFile(plistPath)
+	if err != nil {
+		return fmt.Errorf("failed to read service definition (is the service installed?): %w", err)
+	}
+	edited, err := editLaunchdEnv(string(plist), set, unset)
+	if err != nil {
+		return err
+	}
+	// launchd reads the plist when the job is loaded, so a restart (unload + load) applies it
+	if err := os.WriteFile(plistPath, []byte(edited), 0644); err != nil {
+		return fmt.Errorf("failed to write service definition: %w", err)
+	}
+	return nil
+}
+
+func (sm *ServiceManager) editEnvLinux(set map[string]string, unset []string) error {
+	unitPath := sm.linuxUnitPath()
+	unit, err := os.ReadFile(unitPath)
+	if err != nil {
+		return fmt.Errorf("failed to read service definition (is the service installed?): %w", err)
+	}
+	edited, err := editSystemdEnv(string(unit), set, unset)
+	if err != nil {
+		return err
+	}
+
+	if sm.useUserUnit {
+		if err := os.WriteFile(unitPath, []byte(edited), 0644); err != nil {
+			return fmt.Errorf("failed to write user service file: %w", err)
+		}
+		if err := exec.Command("systemctl", "--user", "daemon-reload").Run(); err != nil {
+			return fmt.Errorf("failed to reload user systemd daemon: %w", err)
+		}
+		return nil
+	}
+
+	if err := writeUnitFile(unitPath, edited); err != nil {
+		return err
+	}
+	if !isInteractive() {
+		return fmt.Errorf("service file updated at %s. Now run: sudo systemctl daemon-reload && sudo systemctl restart giraffecloud", unitPath)
+	}
+	if err := exec.Command("sudo", "systemctl", "daemon-reload").Run(); err != nil {
+		return fmt.Errorf("failed to reload systemd daemon: %w", err)
+	}
+	return nil
+}
+
+func editEnvWindows(set map[string]string, unset []string) error {
+	entries, err := readWindowsServiceEnv()
+	if err != nil {
+		return err
+	}
+	entries = editEnvEntries(entries, set, unset)
+
+	// The service control manager reads Environment when it starts the service
+	var cmd *exec.Cmd
+	if len(entries) == 0 {
+		cmd = exec.Command("reg", "delete", windowsServiceKey, "/v", "Environment", "/f")
+	} else {
+		cmd = exec.Command("reg", "add", windowsServiceKey, "/v", "Environment", "/t", "REG_MULTI_SZ",
+			"/d", strings.Join(entries, `\0`), "/f")
+	}
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to update Windows service environment: %w", err)
+	}
+	return nil
+}
+
+// readWindowsServiceEnv returns the KEY=VALUE entries of the service's Environment value
+func readWindowsServiceEnv() ([]string, error) {
+	if err := exec.Command("sc", "query", "GiraffeCloudTunnel").Run(); err != nil {
+		return nil, fmt.Errorf("service is not installed")
+	}
+	output, err := exec.Command("reg", "query", windowsServiceKey, "/v", "Environment").Output()
+	if err != nil {
+		return nil, nil // No Environment value yet
+	}
+	return parseRegMultiSz(string(output), "Environment"), nil
+}
+
+// parseRegMultiSz extracts a REG_MULTI_SZ value from 'reg query' output, which prints
+// the strings joined by \0
+func parseRegMultiSz(output, name string) []string {
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] != name || fields[1] != "REG_MULTI_SZ" {
+			continue
+		}
+		_, data, _ := strings.Cut(line, "REG_MULTI_SZ")
+		data = strings.TrimSpace(data)
+		if data == "" {
+			return nil
+		}
+		return strings.Split(data, `\0`)
+	}
+	return nil
+}
+
+// editEnvEntries applies set and unset to KEY=VALUE entries, keeping untouched entries in
+// place and appending new keys in sorted order
+func editEnvEntries(entries []string, set map[string]string, unset []string) []string {
+	edited := make([]string, 0, len(entries)+len(set))
+	for _, entry := range entries {
+		key, _, _ := strings.Cut(entry, "=")
+		if _, ok := set[key]; ok || slices.Contains(unset, key) {
+			continue
+		}
+		edited = append(edited, entry)
+	}
+	for _, key := range slices.Sorted(maps.Keys(set)) {
+		edited = append(edited, key+"="+set[key])
+	}
+	return edited
+}
+
+// systemdEnv returns the variables assigned by Environment= lines of a systemd unit
+func systemdEnv(unit string) map[string]string {
+	env := make(map[string]string)
+	for _, line := range strings.Split(unit, "\n") {
+		value, ok := strings.CutPrefix(strings.TrimSpace(line), "Environment=")
+		if !ok {
+			continue
+		}
+		for _, word := range splitSystemdWords(value) {
+			if key, val, ok := strings.Cut(word, "="); ok {
+				env[key] = val
+			}
+		}
+	}
+	return env
+}
+
+// editSystemdEnv rewrites the Environment= lines of a systemd unit: assignments of edited
+// keys are dropped wherever they are, and the set ones are added after the last remaining
+// Environment= line (or ExecStart=) of the [Service] section
+func editSystemdEnv(unit string, set map[string]string, unset []string) (string, error) {
+	lines := strings.Split(unit, "\n")
+	out := make([]string, 0, len(lines)+len(set))
+	section := ""
+	insertAt := -1
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "[") {
+			section = trimmed
+			out = append(out, line)
+			if section == "[Service]" {
+				insertAt = len(out)
+			}
+			continue
+		}
+		if section != "[Service]" {
+			out = append(out, line)
+			continue
+		}
+
+		value, isEnv := strings.CutPrefix(trimmed, "Environment=")
+		if !isEnv {
+			out = append(out, line)
+			if strings.HasPrefix(trimmed, "ExecStart=") {
+				insertAt = len(out)
+			}
+			continue
+		}
+
+		words := splitSystemdWords(value)
+		kept := make([]string, 0, len(words))
+		for _, word := range words {
+			key, _, _ := strings.Cut(word, "=")
+			if _, ok := set[key]; ok || slices.Contains(unset, key) {
+				continue
+			}
+			kept = append(kept, word)
+		}
+		switch {
+		case len(kept) == len(words):
+			out = append(out, line)
+		case len(kept) > 0:
+			quoted := make([]string, len(kept))
+			for i, word := range kept {
+				quoted[i] = quoteSystemdWord(word)
+			}
+			out = append(out, "Environment="+strings.Join(quoted, " "))
+		default:
+			continue // Every assignment on the line was edited
+		}
+		insertAt = len(out)
+	}
+	if insertAt < 0 {
+		return "", fmt.Errorf("service definition has no [Service] section")
+	}
+
+	added := make([]string, 0, len(set))
+	for _, key := range slices.Sorted(maps.Keys(set)) {
+		added = append(added, "Environment="+quoteSystemdWord(key+"="+set[key]))
+	}
+	out = append(out[:insertAt], append(added, out[insertAt:]...)...)
+	return strings.Join(out, "\n"), nil
+}
+
+// splitSystemdWords splits an Environment= value into its assignments, honouring double
+// quotes, backslash escapes and %% (a literal % in unit files)
+func splitSystemdWords(value string) []string {
+	var words []string
+	var word strings.Builder
+	inWord, quoted, escaped := false, false, false
+	for _, r := range strings.ReplaceAll(value, "%%", "%") {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped, inWord = true, true
+		case r == '"':
+			quoted, inWord = !quoted, true
+		case !quoted && (r == ' ' || r == '\t'):
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words
+}
+
+// quoteSystemdWord renders one KEY=VALUE assignment for an Environment= line
+func quoteSystemdWord(word string) string {
+	word = strings.ReplaceAll(word, "%", "%%")
+	if !strings.ContainsAny(word, " \t\"\\") {
+		return word
+	}
+	word = strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(word)
+	return `"` + word + `"`
+}
+
+var (
+	launchdEnvPattern  = regexp.MustCompile(`(?s)<key>EnvironmentVariables</key>\s*<dict>(.*?)</dict>`)
+	launchdPairPattern = regexp.MustCompile(`(?s)<key>(.*?)</key>\s*<string>(.*?)</string>`)
+)
+
+// launchdEnv returns the EnvironmentVariables dict of a launchd plist
+func launchdEnv(plist string) map[string]string {
+	env := make(map[string]string)
+	match := launchdEnvPattern.FindStringSubmatch(plist)
+	if match == nil {
+		return env
+	}
+	for _, pair := range launchdPairPattern.FindAllStringSubmatch(match[1], -1) {
+		env[html.UnescapeString(pair[1])] = html.UnescapeString(pair[2])
+	}
+	return env
+}
+
+// editLaunchdEnv rewrites the EnvironmentVariables dict of a launchd plist, adding the dict
+// to the top-level one when the plist has none
+func editLaunchdEnv(plist string, set map[string]string, unset []string) (string, error) {
+	env := launchdEnv(plist)
+	for _, key := range unset {
+		delete(env, key)
+	}
+	for key, value := range set {
+		env[key] = value
+	}
+
+	var block strings.Builder
+	block.WriteString("<key>EnvironmentVariables</key>\n    <dict>\n")
+	for _, key := range slices.Sorted(maps.Keys(env)) {
+		fmt.Fprintf(&block, "        <key>%s</key>\n        <string>%s</string>\n", escapeXML(key), escapeXML(env[key]))
+	}
+	block.WriteString("    </dict>")
+
+	if loc := launchdEnvPattern.FindStringIndex(plist); loc != nil {
+		return plist[:loc[0]] + block.String() + plist[loc[1]:], nil
+	}
+	end := strings.LastIndex(plist, "</dict>")
+	if end < 0 {
+		return "", fmt.Errorf("service definition is not a launchd property list")
+	}
+	return plist[:end] + "    " + block.String() + "\n" + plist[end:], nil
+}
+
+func escapeXML(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
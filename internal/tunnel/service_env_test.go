@@ -0,0 +1,107 @@
+package tunnel
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEditSystemdEnv(t *testing.T) {
+	unit := `[Unit]
+Description=GiraffeCloud Tunnel Service
+
+[Service]
+Type=simple
+ExecStart=/usr/local/bin/giraffecloud connect
+Environment=GIRAFFECLOUD_HOME=/home/me/.giraffecloud
+Environment=GIRAFFECLOUD_IS_SERVICE=1 LOG_LEVEL=info
+Restart=always
+
+[Install]
+WantedBy=multi-user.target`
+
+	edited, err := editSystemdEnv(unit, map[string]string{
+		"LOG_LEVEL":   "debug",
+		"HTTPS_PROXY": "http://proxy.local:3128",
+		"MOTD":        `say "hi" 100%`,
+	}, []string{"GIRAFFECLOUD_IS_SERVICE"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `[Service]
+Type=simple
+ExecStart=/usr/local/bin/giraffecloud connect
+Environment=GIRAFFECLOUD_HOME=/home/me/.giraffecloud
+Environment=HTTPS_PROXY=http://proxy.local:3128
+Environment=LOG_LEVEL=debug
+Environment="MOTD=say \"hi\" 100%%"
+Restart=always`
+	if !strings.Contains(edited, want) {
+		t.Errorf("edited unit:\n%s\nwant it to contain:\n%s", edited, want)
+	}
+
+	env := systemdEnv(edited)
+	if env["MOTD"] != `say "hi" 100%` || env["LOG_LEVEL"] != "debug" {
+		t.Errorf("values don't survive a round trip: %v", env)
+	}
+	if _, ok := env["GIRAFFECLOUD_IS_SERVICE"]; ok {
+		t.Error("GIRAFFECLOUD_IS_SERVICE was not unset")
+	}
+
+	if _, err := editSystemdEnv("[Unit]\nDescription=x\n", map[string]string{"A": "1"}, nil); err == nil {
+		t.Error("expected an error for a unit without a [Service] section")
+	}
+}
+
+func TestEditLaunchdEnv(t *testing.T) {
+	plist := `<plist version="1.0">
+<dict>
+    <key>Label</key>
+    <string>com.giraffecloud.tunnel</string>
+    <key>EnvironmentVariables</key>
+    <dict>
+        <key>GIRAFFECLOUD_HOME</key>
+        <string>/Users/me/.giraffecloud</string>
+    </dict>
+    <key>RunAtLoad</key>
+    <true/>
+</dict>
+</plist>`
+
+	edited, err := editLaunchdEnv(plist, map[string]string{"SUBDOMAIN_SECRET": "a<b&c"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := launchdEnv(edited)
+	if env["SUBDOMAIN_SECRET"] != "a<b&c" || env["GIRAFFECLOUD_HOME"] != "/Users/me/.giraffecloud" {
+		t.Errorf("unexpected environment: %v", env)
+	}
+	if !strings.Contains(edited, "<string>a&lt;b&amp;c</string>") || !strings.Contains(edited, "<key>RunAtLoad</key>") {
+		t.Errorf("unexpected plist:\n%s", edited)
+	}
+
+	edited, _ = editLaunchdEnv(edited, nil, []string{"SUBDOMAIN_SECRET"})
+	if _, ok := launchdEnv(edited)["SUBDOMAIN_SECRET"]; ok {
+		t.Error("SUBDOMAIN_SECRET was not unset")
+	}
+
+	// A plist without the dict gets one
+	edited, _ = editLaunchdEnv("<plist>\n<dict>\n    <key>Label</key>\n    <string>x</string>\n</dict>\n</plist>", map[string]string{"A": "1"}, nil)
+	if launchdEnv(edited)["A"] != "1" {
+		t.Errorf("EnvironmentVariables not added:\n%s", edited)
+	}
+}
+
+func TestWindowsServiceEnv(t *testing.T) {
+	output := "\r\nHKEY_LOCAL_MACHINE\\SYSTEM\\CurrentControlSet\\Services\\GiraffeCloudTunnel\r\n" +
+		"    Environment    REG_MULTI_SZ    GIRAFFECLOUD_HOME=C:\\Users\\me\\.giraffecloud\\0LOG_LEVEL=info\r\n\r\n"
+	entries := parseRegMultiSz(output, "Environment")
+	if len(entries) != 2 || entries[0] != `GIRAFFECLOUD_HOME=C:\Users\me\.giraffecloud` || entries[1] != "LOG_LEVEL=info" {
+		t.Fatalf("unexpected entries: %q", entries)
+	}
+
+	edited := editEnvEntries(entries, map[string]string{"LOG_LEVEL": "debug", "A": "1"}, []string{"GIRAFFECLOUD_HOME"})
+	if strings.Join(edited, ",") != "A=1,LOG_LEVEL=debug" {
+		t.Errorf("unexpected entries after edit: %q", edited)
+	}
+}
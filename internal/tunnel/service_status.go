@@ -0,0 +1,182 @@
+package tunnel
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ServiceStatus is a machine-readable snapshot of the installed tunnel service
+type ServiceStatus struct {
+	Installed bool   `json:"installed"`
+	Running   bool   `json:"running"`
+	UnitPath  string `json:"unitPath"`
+	PID       int    `json:"pid"`    // 0 when not running
+	Uptime    int64  `json:"uptime"` // Seconds since the service started (0 when unknown or not running)
+}
+
+// Status returns the installed/running state, unit location, PID and uptime of the service
+func (sm *ServiceManager) Status() (*ServiceStatus, error) {
+	switch runtime.GOOS {
+	case "darwin":
+		return sm.statusDarwin()
+	case "linux":
+		return sm.statusLinux()
+	case "windows":
+		return sm.statusWindows()
+	default:
+		return nil, fmt.Errorf("unsupported operating system: %s", runtime.GOOS)
+	}
+}
+
+func (sm *ServiceManager) statusDarwin() (*ServiceStatus, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	status := &ServiceStatus{
+		UnitPath: filepath.Join(homeDir, "Library/LaunchAgents/com.giraffecloud.tunnel.plist"),
+	}
+	if _, err := os.Stat(status.UnitPath); err == nil {
+		status.Installed = true
+	}
+
+	// A loaded job without a PID is installed but not running
+	output, err := exec.Command("launchctl", "list", "com.giraffecloud.tunnel").Output()
+	if err != nil {
+		return status, nil
+	}
+	status.PID = parseLaunchctlPID(string(output))
+	status.Running = status.PID > 0
+	if status.Running {
+		status.Uptime = processUptime(status.PID)
+	}
+	return status, nil
+}
+
+func (sm *ServiceManager) statusLinux() (*ServiceStatus, error) {
+	status := &ServiceStatus{UnitPath: "/etc/systemd/system/giraffecloud.service"}
+	if sm.useUserUnit {
+		status.UnitPath = filepath.Join(os.Getenv("HOME"), ".config/systemd/user/giraffecloud.service")
+	}
+
+	args := []string{"show", "giraffecloud", "--property=LoadState,ActiveState,MainPID,FragmentPath,ActiveEnterTimestamp"}
+	if sm.useUserUnit {
+		args = append([]string{"--user"}, args...)
+	}
+	output, err := exec.Command("systemctl", args...).Output()
+	if err != nil {
+		// No systemd: fall back to checking for the unit file
+		installed, _ := sm.isInstalledLinux()
+		status.Installed = installed
+		return status, nil
+	}
+
+	props := parseSystemctlShow(string(output))
+	if path := props["FragmentPath"]; path != "" {
+		status.UnitPath = path
+	}
+	status.Installed = props["LoadState"] == "loaded"
+	status.Running = props["ActiveState"] == "active"
+	if status.Running {
+		status.PID, _ = strconv.Atoi(props["MainPID"])
+		if started, err := time.Parse("Mon 2006-01-02 15:04:05 MST", props["ActiveEnterTimestamp"]); err == nil {
+			status.Uptime = int64(time.Since(started).Seconds())
+		}
+	}
+	return status, nil
+}
+
+func (sm *ServiceManager) statusWindows() (*ServiceStatus, error) {
+	status := &ServiceStatus{
+		UnitPath: `HKLM\SYSTEM\CurrentControlSet\Services\GiraffeCloudTunnel`,
+	}
+
+	// queryex is sc query plus the PID
+	output, err := exec.Command("sc", "queryex", "GiraffeCloudTunnel").Output()
+	if err != nil {
+		return status, nil
+	}
+	status.Installed = true
+	status.Running, status.PID = parseScQuery(string(output))
+	return status, nil
+}
+
+// parseSystemctlShow parses the Key=Value lines printed by 'systemctl show'
+func parseSystemctlShow(output string) map[string]string {
+	props := make(map[string]string)
+	for _, line := range strings.Split(output, "\n") {
+		if key, value, ok := strings.Cut(strings.TrimSpace(line), "="); ok {
+			props[key] = value
+		}
+	}
+	return props
+}
+
+var launchctlPIDPattern = regexp.MustCompile(`"PID"\s*=\s*(\d+);`)
+
+// parseLaunchctlPID extracts the PID from 'launchctl list <label>' output (0 if not running)
+func parseLaunchctlPID(output string) int {
+	match := launchctlPIDPattern.FindStringSubmatch(output)
+	if match == nil {
+		return 0
+	}
+	pid, _ := strconv.Atoi(match[1])
+	return pid
+}
+
+// parseScQuery extracts the running state and PID from 'sc queryex' output
+func parseScQuery(output string) (bool, int) {
+	running := false
+	pid := 0
+	for _, line := range strings.Split(output, "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		switch strings.TrimSpace(key) {
+		case "STATE":
+			running = strings.Contains(value, "RUNNING")
+		case "PID":
+			pid, _ = strconv.Atoi(strings.TrimSpace(value))
+		}
+	}
+	if !running {
+		pid = 0
+	}
+	return running, pid
+}
+
+// processUptime returns how long pid has been running in seconds, using ps (0 if unknown)
+func processUptime(pid int) int64 {
+	output, err := exec.Command("ps", "-o", "etime=", "-p", strconv.Itoa(pid)).Output()
+	if err != nil {
+		return 0
+	}
+	return parseEtime(strings.TrimSpace(string(output)))
+}
+
+// parseEtime parses the ps elapsed-time format [[dd-]hh:]mm:ss into seconds
+func parseEtime(etime string) int64 {
+	var days int64
+	if d, rest, ok := strings.Cut(etime, "-"); ok {
+		days, _ = strconv.ParseInt(d, 10, 64)
+		etime = rest
+	}
+
+	var seconds int64
+	for _, part := range strings.Split(etime, ":") {
+		n, err := strconv.ParseInt(part, 10, 64)
+		if err != nil {
+			return 0
+		}
+		seconds = seconds*60 + n
+	}
+	return days*86400 + seconds
+}
@@ -0,0 +1,53 @@
+package tunnel
+
+import "testing"
+
+func TestParseSystemctlShow(t *testing.T) {
+	props := parseSystemctlShow("LoadState=loaded\nActiveState=active\nMainPID=4242\nFragmentPath=/etc/systemd/system/giraffecloud.service\n")
+	if props["LoadState"] != "loaded" || props["ActiveState"] != "active" || props["MainPID"] != "4242" {
+		t.Errorf("Unexpected properties: %v", props)
+	}
+	if props["FragmentPath"] != "/etc/systemd/system/giraffecloud.service" {
+		t.Errorf("Unexpected FragmentPath: %q", props["FragmentPath"])
+	}
+}
+
+func TestParseLaunchctlPID(t *testing.T) {
+	output := "{\n\t\"LimitLoadToSessionType\" = \"Aqua\";\n\t\"Label\" = \"com.giraffecloud.tunnel\";\n\t\"PID\" = 812;\n};\n"
+	if pid := parseLaunchctlPID(output); pid != 812 {
+		t.Errorf("Expected PID 812, got %d", pid)
+	}
+	if pid := parseLaunchctlPID("{\n\t\"Label\" = \"com.giraffecloud.tunnel\";\n};\n"); pid != 0 {
+		t.Errorf("Expected PID 0 for stopped job, got %d", pid)
+	}
+}
+
+func TestParseScQuery(t *testing.T) {
+	output := `
+SERVICE_NAME: GiraffeCloudTunnel
+        TYPE               : 10  WIN32_OWN_PROCESS
+        STATE              : 4  RUNNING
+                                (STOPPABLE, NOT_PAUSABLE, ACCEPTS_SHUTDOWN)
+        WIN32_EXIT_CODE    : 0  (0x0)
+        PID                : 5120
+        FLAGS              :
+`
+	running, pid := parseScQuery(output)
+	if !running || pid != 5120 {
+		t.Errorf("Expected running with PID 5120, got running=%v pid=%d", running, pid)
+	}
+}
+
+func TestParseEtime(t *testing.T) {
+	tests := map[string]int64{
+		"05:07":      307,
+		"01:02:03":   3723,
+		"2-01:02:03": 2*86400 + 3723,
+		"not-a-time": 0,
+	}
+	for in, want := range tests {
+		if got := parseEtime(in); got != want {
+			t.Errorf("parseEtime(%q) = %d, want %d", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,324 @@
+package tunnel
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Query parameters carrying a signed link's signature and expiry (Unix seconds)
+const (
+	SignedURLSigParam = "sig"
+	SignedURLExpParam = "exp"
+)
+
+// SignedURLCookie is set on the response to a valid signed link so the page's assets and
+// follow-up requests load too. It is scoped to the tunnel's host, expires with the link
+// and carries an HMAC over the host and expiry.
+const SignedURLCookie = "giraffecloud_signed"
+
+var (
+	errSignatureMissing = errors.New("missing signature")
+	errSignatureInvalid = errors.New("invalid signature")
+	errSignatureExpired = errors.New("signature expired")
+)
+
+// MinSignedURLSecretLength is the shortest secret accepted for signing links
+const MinSignedURLSecretLength = 16
+
+// ValidateSignedURLSecret checks that secret is set and long enough to sign links with
+func ValidateSignedURLSecret(secret string) error {
+	if secret == "" {
+		return fmt.Errorf("not set - required by require_signed_url")
+	}
+	if len(secret) < MinSignedURLSecretLength {
+		return fmt.Errorf("too short - use at least %d characters", MinSignedURLSecretLength)
+	}
+	return nil
+}
+
+// SignURL returns rawURL with sig and exp parameters that let it through a tunnel with
+// RequireSignedURL until ttl from now. The signature covers the host, the path and the
+// expiry, so a link can't be reused for another page or extended; other query
+// parameters are left unsigned. Opening the link sets SignedURLCookie, so the page's
+// assets and the pages it links to on the same host load until the same expiry.
+func SignURL(rawURL, secret string, ttl time.Duration) (string, error) {
+	if secret == "" {
+		return "", fmt.Errorf("no signing secret configured")
+	}
+	if ttl <= 0 {
+		return "", fmt.Errorf("ttl must be positive")
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL: %w", err)
+	}
+	if u.Host == "" {
+		return "", fmt.Errorf("URL %q has no host (include the scheme, e.g. https://)", rawURL)
+	}
+
+	exp := time.Now().Add(ttl).Unix()
+	query := stripQueryParams(u.RawQuery, SignedURLSigParam, SignedURLExpParam)
+	if query != "" {
+		query += "&"
+	}
+	query += SignedURLExpParam + "=" + strconv.FormatInt(exp, 10) +
+		"&" + SignedURLSigParam + "=" + signURLPath(secret, u.Hostname(), u.Path, exp)
+	u.RawQuery = query
+	return u.String(), nil
+}
+
+// verifySignedURL checks the sig and exp parameters of u, a request for host, and
+// returns the link's expiry
+func verifySignedURL(secret, host string, u *url.URL, now time.Time) (int64, error) {
+	query := u.Query()
+	sig, expParam := query.Get(SignedURLSigParam), query.Get(SignedURLExpParam)
+	if sig == "" || expParam == "" {
+		return 0, errSignatureMissing
+	}
+	exp, err := strconv.ParseInt(expParam, 10, 64)
+	if err != nil {
+		return 0, errSignatureInvalid
+	}
+	if !hmac.Equal([]byte(sig), []byte(signURLPath(secret, host, u.Path, exp))) {
+		return 0, errSignatureInvalid
+	}
+	if now.Unix() > exp {
+		return 0, errSignatureExpired
+	}
+	return exp, nil
+}
+
+// signedCookie returns the SignedURLCookie granting access to host until exp
+func signedCookie(secret, host string, exp int64) *http.Cookie {
+	return &http.Cookie{
+		Name:     SignedURLCookie,
+		Value:    strconv.FormatInt(exp, 10) + "." + signCookie(secret, host, exp),
+		Path:     "/",
+		Expires:  time.Unix(exp, 0),
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// verifySignedCookie checks a SignedURLCookie value presented for host
+func verifySignedCookie(secret, host, value string, now time.Time) error {
+	expParam, sig, ok := strings.Cut(value, ".")
+	if !ok {
+		return errSignatureInvalid
+	}
+	exp, err := strconv.ParseInt(expParam, 10, 64)
+	if err != nil {
+		return errSignatureInvalid
+	}
+	if !hmac.Equal([]byte(sig), []byte(signCookie(secret, host, exp))) {
+		return errSignatureInvalid
+	}
+	if now.Unix() > exp {
+		return errSignatureExpired
+	}
+	return nil
+}
+
+// signCookie computes the signature of a cookie for host expiring at exp. It is kept
+// apart from link signatures so a cookie value can't be replayed as a link.
+func signCookie(secret, host string, exp int64) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "cookie\n%s\n%d", strings.ToLower(host), exp)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// signURLPath computes the signature of a link to path on host expiring at exp
+func signURLPath(secret, host, path string, exp int64) string {
+	if path == "" {
+		path = "/"
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%s\n%s\n%d", strings.ToLower(host), path, exp)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// stripQueryParams removes names from rawQuery, keeping the other parameters as they were
+func stripQueryParams(rawQuery string, names ...string) string {
+	if rawQuery == "" {
+		return ""
+	}
+	kept := make([]string, 0, strings.Count(rawQuery, "&")+1)
+	for _, param := range strings.Split(rawQuery, "&") {
+		key, _, _ := strings.Cut(param, "=")
+		if key, err := url.QueryUnescape(key); err == nil {
+			drop := false
+			for _, name := range names {
+				drop = drop || key == name
+			}
+			if drop {
+				continue
+			}
+		}
+		kept = append(kept, param)
+	}
+	return strings.Join(kept, "&")
+}
+
+// SignedURLSecret returns the link-signing secret of domain's tunnel, and whether the
+// tunnel requires signed links at all
+func (s *GRPCTunnelServer) SignedURLSecret(domain string) (secret string, required bool) {
+	s.tunnelStreamsMux.RLock()
+	tunnelStream, exists := s.tunnelStreams[domain]
+	s.tunnelStreamsMux.RUnlock()
+	if !exists || tunnelStream.signedURLSecret == "" {
+		return "", false
+	}
+	return tunnelStream.signedURLSecret, true
+}
+
+// checkSignedURL enforces RequireSignedURL for domain. A request passes with a valid,
+// unexpired signed link, or with the SignedURLCookie an earlier one set; a signed link
+// returns the cookie to set on the response. The returned requestData has the sig and exp
+// parameters and the cookie removed, so the local service never sees them.
+func (r *HybridTunnelRouter) checkSignedURL(domain string, requestData []byte) ([]byte, *http.Cookie, error) {
+	secret, required := r.grpcTunnel.SignedURLSecret(domain)
+	if !required {
+		return requestData, nil, nil
+	}
+
+	lineEnd := bytes.Index(requestData, []byte("\r\n"))
+	if lineEnd < 0 {
+		return nil, nil, errSignatureMissing
+	}
+	parts := strings.Split(string(requestData[:lineEnd]), " ")
+	if len(parts) != 3 {
+		return nil, nil, errSignatureMissing
+	}
+	target, err := url.ParseRequestURI(parts[1])
+	if err != nil {
+		return nil, nil, errSignatureMissing
+	}
+	cookie, head := takeRequestCookie(requestData[lineEnd:], SignedURLCookie)
+
+	now := time.Now()
+	var grant *http.Cookie
+	exp, err := verifySignedURL(secret, domain, target, now)
+	if err == nil {
+		grant = signedCookie(secret, domain, exp)
+	} else if cookie == "" || verifySignedCookie(secret, domain, cookie, now) != nil {
+		return nil, nil, err
+	}
+
+	target.RawQuery = stripQueryParams(target.RawQuery, SignedURLSigParam, SignedURLExpParam)
+	line := parts[0] + " " + target.RequestURI() + " " + parts[2]
+	rewritten := make([]byte, 0, len(line)+len(head))
+	rewritten = append(rewritten, line...)
+	return append(rewritten, head...), grant, nil
+}
+
+// loggedRequest returns requestData as the access log records it: on tunnels requiring
+// signed links the sig and exp parameters are removed from the request line whether or not
+// they were valid, so the log can't be used to replay a link
+func (r *HybridTunnelRouter) loggedRequest(domain string, requestData []byte) []byte {
+	if _, required := r.grpcTunnel.SignedURLSecret(domain); !required {
+		return requestData
+	}
+	lineEnd := bytes.Index(requestData, []byte("\r\n"))
+	if lineEnd < 0 {
+		lineEnd = len(requestData)
+	}
+	parts := strings.Split(string(requestData[:lineEnd]), " ")
+	if len(parts) != 3 {
+		return requestData
+	}
+	target, err := url.ParseRequestURI(parts[1])
+	if err != nil {
+		return requestData
+	}
+	target.RawQuery = stripQueryParams(target.RawQuery, SignedURLSigParam, SignedURLExpParam)
+	line := parts[0] + " " + target.RequestURI() + " " + parts[2]
+	return append([]byte(line), requestData[lineEnd:]...)
+}
+
+// takeRequestCookie returns the value of the name cookie in a raw request head (from the
+// CRLF ending the request line) and the head with that cookie removed
+func takeRequestCookie(head []byte, name string) (string, []byte) {
+	if !bytes.Contains(head, []byte(name+"=")) {
+		return "", head
+	}
+	var value string
+	lines := strings.Split(string(head), "\r\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		key, cookies, ok := strings.Cut(line, ":")
+		if !ok || !strings.EqualFold(strings.TrimSpace(key), "Cookie") {
+			kept = append(kept, line)
+			continue
+		}
+		var others []string
+		for _, pair := range strings.Split(cookies, ";") {
+			pair = strings.TrimSpace(pair)
+			if v, found := strings.CutPrefix(pair, name+"="); found {
+				value = v
+			} else if pair != "" {
+				others = append(others, pair)
+			}
+		}
+		if len(others) > 0 {
+			kept = append(kept, key+": "+strings.Join(others, "; "))
+		}
+	}
+	return value, []byte(strings.Join(kept, "\r\n"))
+}
+
+// setCookieConn adds a Set-Cookie header to the final response written to the visitor,
+// passing interim responses such as 100 Continue through untouched
+type setCookieConn struct {
+	net.Conn
+	header  []byte // Set-Cookie line still to be added, nil once written
+	pending []byte // Start of a response whose status line isn't complete yet
+}
+
+func newSetCookieConn(conn net.Conn, cookie *http.Cookie) *setCookieConn {
+	return &setCookieConn{Conn: conn, header: []byte("Set-Cookie: " + cookie.String() + "\r\n")}
+}
+
+func (c *setCookieConn) Write(p []byte) (int, error) {
+	if c.header == nil {
+		return c.Conn.Write(p)
+	}
+	c.pending = append(c.pending, p...)
+	for c.header != nil {
+		lineEnd := bytes.Index(c.pending, []byte("\r\n"))
+		if lineEnd < 0 {
+			return len(p), nil
+		}
+		if status := parseStatusLine(c.pending); status >= 100 && status < 200 && status != http.StatusSwitchingProtocols {
+			end := bytes.Index(c.pending, []byte("\r\n\r\n"))
+			if end < 0 {
+				return len(p), nil
+			}
+			if _, err := c.Conn.Write(c.pending[:end+4]); err != nil {
+				return 0, err
+			}
+			c.pending = c.pending[end+4:]
+			continue
+		}
+
+		out := make([]byte, 0, len(c.pending)+len(c.header))
+		out = append(out, c.pending[:lineEnd+2]...)
+		out = append(out, c.header...)
+		out = append(out, c.pending[lineEnd+2:]...)
+		c.header, c.pending = nil, nil
+		if _, err := c.Conn.Write(out); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
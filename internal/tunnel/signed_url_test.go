@@ -0,0 +1,173 @@
+package tunnel
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignURL(t *testing.T) {
+	const secret = "0123456789abcdef"
+	signed, err := SignURL("https://App.example.com/preview/42?tab=files", secret, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse(signed)
+	if u.Query().Get("tab") != "files" {
+		t.Errorf("existing query parameters were lost: %s", signed)
+	}
+
+	now := time.Now()
+	if _, err := verifySignedURL(secret, "app.example.com", u, now); err != nil {
+		t.Errorf("valid link rejected: %v", err)
+	}
+	if _, err := verifySignedURL(secret, "app.example.com", u, now.Add(2*time.Hour)); !errors.Is(err, errSignatureExpired) {
+		t.Errorf("expired link: got %v, want %v", err, errSignatureExpired)
+	}
+	if _, err := verifySignedURL("another-secret-value", "app.example.com", u, now); !errors.Is(err, errSignatureInvalid) {
+		t.Errorf("wrong secret: got %v, want %v", err, errSignatureInvalid)
+	}
+	if _, err := verifySignedURL(secret, "other.example.com", u, now); !errors.Is(err, errSignatureInvalid) {
+		t.Errorf("other host: got %v, want %v", err, errSignatureInvalid)
+	}
+
+	other := *u
+	other.Path = "/admin"
+	if _, err := verifySignedURL(secret, "app.example.com", &other, now); !errors.Is(err, errSignatureInvalid) {
+		t.Errorf("other path: got %v, want %v", err, errSignatureInvalid)
+	}
+
+	extended := *u
+	query := u.Query()
+	query.Set(SignedURLExpParam, "99999999999")
+	extended.RawQuery = query.Encode()
+	if _, err := verifySignedURL(secret, "app.example.com", &extended, now); !errors.Is(err, errSignatureInvalid) {
+		t.Errorf("extended expiry: got %v, want %v", err, errSignatureInvalid)
+	}
+
+	unsigned, _ := url.Parse("https://app.example.com/preview/42")
+	if _, err := verifySignedURL(secret, "app.example.com", unsigned, now); !errors.Is(err, errSignatureMissing) {
+		t.Errorf("unsigned link: got %v, want %v", err, errSignatureMissing)
+	}
+}
+
+func TestCheckSignedURL(t *testing.T) {
+	const secret = "0123456789abcdef"
+	server := &GRPCTunnelServer{tunnelStreams: map[string]*TunnelStream{
+		"app.example.com": {signedURLSecret: secret},
+	}}
+	r := &HybridTunnelRouter{grpcTunnel: server}
+
+	signed, _ := SignURL("https://app.example.com/preview?tab=files", secret, time.Hour)
+	u, _ := url.Parse(signed)
+	request := "GET " + u.RequestURI() + " HTTP/1.1\r\nHost: app.example.com\r\n\r\n"
+
+	forwarded, _, err := r.checkSignedURL("app.example.com", []byte(request))
+	if err != nil {
+		t.Fatalf("signed request rejected: %v", err)
+	}
+	if want := "GET /preview?tab=files HTTP/1.1\r\nHost: app.example.com\r\n\r\n"; string(forwarded) != want {
+		t.Errorf("forwarded request = %q, want %q", forwarded, want)
+	}
+
+	if _, _, err := r.checkSignedURL("app.example.com", []byte("GET /preview HTTP/1.1\r\n\r\n")); err == nil {
+		t.Error("unsigned request was accepted")
+	}
+
+	// Tunnels without RequireSignedURL pass requests through untouched
+	plain := "GET /?sig=x&exp=1 HTTP/1.1\r\n\r\n"
+	if forwarded, _, err := r.checkSignedURL("other.example.com", []byte(plain)); err != nil || string(forwarded) != plain {
+		t.Errorf("unprotected tunnel: got %q, %v", forwarded, err)
+	}
+
+	// The access log never records a signature, valid or not
+	if logged := r.loggedRequest("app.example.com", []byte(request)); string(logged) != "GET /preview?tab=files HTTP/1.1\r\nHost: app.example.com\r\n\r\n" {
+		t.Errorf("logged request = %q", logged)
+	}
+	if logged := r.loggedRequest("app.example.com", []byte("GET /?exp=1&sig=bad HTTP/1.1\r\n\r\n")); string(logged) != "GET / HTTP/1.1\r\n\r\n" {
+		t.Errorf("logged rejected request = %q", logged)
+	}
+	if logged := r.loggedRequest("other.example.com", []byte(plain)); string(logged) != plain {
+		t.Errorf("logged request of an unprotected tunnel = %q", logged)
+	}
+}
+
+func TestSignedURLCookieLoadsPageAndAssets(t *testing.T) {
+	const secret = "0123456789abcdef"
+	server := &GRPCTunnelServer{tunnelStreams: map[string]*TunnelStream{
+		"app.example.com": {signedURLSecret: secret},
+	}}
+	r := &HybridTunnelRouter{grpcTunnel: server}
+
+	// The signed page is let through and answered with the access cookie
+	signed, _ := SignURL("https://app.example.com/preview/42", secret, time.Hour)
+	u, _ := url.Parse(signed)
+	page := "GET " + u.RequestURI() + " HTTP/1.1\r\nHost: app.example.com\r\n\r\n"
+	_, grant, err := r.checkSignedURL("app.example.com", []byte(page))
+	if err != nil || grant == nil {
+		t.Fatalf("signed page: grant %v, err %v", grant, err)
+	}
+	cookie := writeThroughSetCookieConn(t, grant)
+	if cookie.Name != SignedURLCookie || !cookie.Secure || !cookie.HttpOnly || cookie.Path != "/" {
+		t.Fatalf("unexpected cookie: %v", cookie)
+	}
+	if until := time.Until(cookie.Expires); until <= 0 || until > time.Hour {
+		t.Errorf("cookie expires in %v, want the link's expiry", until)
+	}
+
+	// Assets the page loads carry the cookie instead of a signature
+	asset := "GET /static/app.js HTTP/1.1\r\nHost: app.example.com\r\nCookie: theme=dark; " +
+		SignedURLCookie + "=" + cookie.Value + "\r\n\r\n"
+	forwarded, grant, err := r.checkSignedURL("app.example.com", []byte(asset))
+	if err != nil || grant != nil {
+		t.Fatalf("asset: grant %v, err %v", grant, err)
+	}
+	if want := "GET /static/app.js HTTP/1.1\r\nHost: app.example.com\r\nCookie: theme=dark\r\n\r\n"; string(forwarded) != want {
+		t.Errorf("forwarded asset request = %q, want %q", forwarded, want)
+	}
+
+	// The cookie is bound to its host and can't be extended
+	if _, _, err := r.checkSignedURL("app.example.com", []byte("GET / HTTP/1.1\r\nCookie: "+SignedURLCookie+"=99999999999."+strings.SplitN(cookie.Value, ".", 2)[1]+"\r\n\r\n")); err == nil {
+		t.Error("cookie with a changed expiry was accepted")
+	}
+	if err := verifySignedCookie(secret, "other.example.com", cookie.Value, time.Now()); !errors.Is(err, errSignatureInvalid) {
+		t.Errorf("other host: got %v, want %v", err, errSignatureInvalid)
+	}
+	if err := verifySignedCookie(secret, "app.example.com", cookie.Value, time.Now().Add(2*time.Hour)); !errors.Is(err, errSignatureExpired) {
+		t.Errorf("expired cookie: got %v, want %v", err, errSignatureExpired)
+	}
+}
+
+// writeThroughSetCookieConn writes a 100 Continue and a response through a setCookieConn
+// and returns the cookie the visitor receives
+func writeThroughSetCookieConn(t *testing.T, grant *http.Cookie) *http.Cookie {
+	t.Helper()
+	server, client := net.Pipe()
+	go func() {
+		conn := newSetCookieConn(server, grant)
+		conn.Write([]byte("HTTP/1.1 100 Continue\r\n\r\nHTTP/1.1 200"))
+		conn.Write([]byte(" OK\r\nContent-Length: 2\r\n\r\nok"))
+		server.Close()
+	}()
+
+	reader := bufio.NewReader(client)
+	interim, err := http.ReadResponse(reader, nil)
+	if err != nil || interim.StatusCode != http.StatusContinue || len(interim.Cookies()) != 0 {
+		t.Fatalf("interim response = %v, %v", interim, err)
+	}
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || string(body) != "ok" || len(resp.Cookies()) != 1 {
+		t.Fatalf("response = %d %q with cookies %v", resp.StatusCode, body, resp.Cookies())
+	}
+	return resp.Cookies()[0]
+}
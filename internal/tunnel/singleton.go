@@ -23,7 +23,7 @@ func NewSingletonManager() (*SingletonManager, error) {
 	// Normalize config home and prefer same directory as other client configs
 	EnsureConsistentConfigHome()
 
-	configDir, err := GetConfigDir()
+	configDir, err := GetInstanceDir()
 	if err != nil {
 		// Graceful fallback when HOME is missing: use temp dir
 		fallbackDir := filepath.Join(os.TempDir(), "giraffecloud")
@@ -53,36 +53,34 @@ func (sm *SingletonManager) AcquireLock() error {
 		return fmt.Errorf("failed to create pid directory: %w", err)
 	}
 
-	// Open or create the PID file
 	file, err := os.OpenFile(sm.PidFile, os.O_RDWR|os.O_CREATE, 0644)
 	if err != nil {
 		return fmt.Errorf("failed to open pid file: %w", err)
 	}
 
-	// Attempt to acquire an exclusive lock without blocking
-	err = lockFile(file)
-	if err != nil {
+	// Attempt to acquire an exclusive lock without blocking. The kernel drops the lock
+	// when its holder exits, so a contended lock always belongs to a live process.
+	if err := lockFile(file); err != nil {
 		file.Close()
-		if isLockContended(err) {
-			// Lock is held by another process
-			pid := sm.getPIDFromFile()
-			return fmt.Errorf("tunnel is already running (PID: %d). Use 'giraffecloud service status' to check service status", pid)
+		if !isLockContended(err) {
+			return fmt.Errorf("failed to acquire advisory lock: %w", err)
 		}
-		return fmt.Errorf("failed to acquire advisory lock: %w", err)
+		return fmt.Errorf("tunnel is already running (PID: %d). Use 'giraffecloud service status' to check service status", sm.readLockOwner().PID)
 	}
 
 	// Lock acquired! Keep the file handle open to maintain the lock
 	sm.lockFile = file
 
-	// Write current PID to file for informational purposes
-	pid := os.Getpid()
+	// Record who holds the lock, so a later instance can tell a live tunnel from a reused PID
+	owner := currentLockOwner()
+	pid := owner.PID
 	if err := file.Truncate(0); err != nil {
 		sm.logger.Warn("Failed to truncate pid file: %v", err)
 	}
 	if _, err := file.Seek(0, 0); err != nil {
 		sm.logger.Warn("Failed to seek pid file: %v", err)
 	}
-	if _, err := fmt.Fprintf(file, "%d\n", pid); err != nil {
+	if _, err := file.WriteString(owner.String()); err != nil {
 		sm.logger.Warn("Failed to write to pid file: %v", err)
 	}
 
@@ -143,23 +141,16 @@ func (sm *SingletonManager) GetRunningPID() int {
 	if !sm.IsRunning() {
 		return 0
 	}
-	return sm.getPIDFromFile()
+	return sm.readLockOwner().PID
 }
 
-// getPIDFromFile reads the PID from the PID file (helper)
-func (sm *SingletonManager) getPIDFromFile() int {
+// readLockOwner reads the lock holder recorded in the PID file (zero if unreadable)
+func (sm *SingletonManager) readLockOwner() lockOwner {
 	data, err := os.ReadFile(sm.PidFile)
 	if err != nil {
-		return 0
-	}
-
-	pidStr := strings.TrimSpace(string(data))
-	pid, err := strconv.Atoi(pidStr)
-	if err != nil {
-		return 0
+		return lockOwner{}
 	}
-
-	return pid
+	return parseLockOwner(data)
 }
 
 // CheckServiceConflict checks if there's a conflict with the system service
@@ -211,14 +202,107 @@ func (sm *SingletonManager) CleanupStaleLock() error {
 	if err == nil {
 		_ = unlockFile(file)
 		file.Close()
+		owner := sm.readLockOwner()
 		_ = os.Remove(sm.PidFile)
 		if sm.logger != nil {
-			sm.logger.Debug("Cleaned up stale lock file")
+			if reason := owner.staleReason(); reason != "" {
+				sm.logger.Debug("Cleaned up stale lock file: %s", reason)
+			} else {
+				sm.logger.Debug("Cleaned up stale lock file")
+			}
 		}
 	}
 	return nil
 }
 
+// lockOwner identifies the process holding the singleton lock, as recorded in the PID
+// file: the PID on the first line (all older versions wrote), then key=value lines
+type lockOwner struct {
+	PID        int
+	StartTime  string // Process start time, which changes when the PID is reused
+	BootID     string // Changes on every boot (empty where unknown)
+	Executable string
+}
+
+// currentLockOwner describes this process
+func currentLockOwner() lockOwner {
+	pid := os.Getpid()
+	owner := lockOwner{PID: pid, BootID: bootID()}
+	owner.StartTime, _ = processStartTime(pid)
+	owner.Executable, _ = os.Executable()
+	return owner
+}
+
+// String formats the owner as PID file contents
+func (o lockOwner) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d\n", o.PID)
+	for _, field := range [][2]string{{"start_time", o.StartTime}, {"boot_id", o.BootID}, {"executable", o.Executable}} {
+		if field[1] != "" {
+			fmt.Fprintf(&b, "%s=%s\n", field[0], field[1])
+		}
+	}
+	return b.String()
+}
+
+// parseLockOwner parses PID file contents, including the PID-only files of older versions
+func parseLockOwner(data []byte) lockOwner {
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	pid, err := strconv.Atoi(strings.TrimSpace(lines[0]))
+	if err != nil {
+		return lockOwner{}
+	}
+	owner := lockOwner{PID: pid}
+	for _, line := range lines[1:] {
+		key, value, _ := strings.Cut(strings.TrimSpace(line), "=")
+		switch key {
+		case "start_time":
+			owner.StartTime = value
+		case "boot_id":
+			owner.BootID = value
+		case "executable":
+			owner.Executable = value
+		}
+	}
+	return owner
+}
+
+// staleReason explains why the recorded owner of a lock nobody holds is gone, or returns ""
+// if nothing can tell. It only describes the previous owner: a contended lock is never stale.
+func (o lockOwner) staleReason() string {
+	if o.PID <= 0 {
+		return ""
+	}
+	if !processExists(o.PID) {
+		return fmt.Sprintf("process %d no longer exists", o.PID)
+	}
+	if o.BootID != "" {
+		if current := bootID(); current != "" && current != o.BootID {
+			return fmt.Sprintf("process %d was started before the last reboot", o.PID)
+		}
+	}
+	if o.StartTime != "" {
+		if started, err := processStartTime(o.PID); err == nil && started != o.StartTime {
+			return fmt.Sprintf("PID %d was reused by another process", o.PID)
+		}
+	}
+	if o.Executable != "" {
+		if exe, err := processExecutable(o.PID); err == nil && !sameExecutable(exe, o.Executable) {
+			return fmt.Sprintf("PID %d now runs %s, not %s", o.PID, exe, o.Executable)
+		}
+	}
+	return ""
+}
+
+// sameExecutable compares executables by file name: an update may have replaced or moved
+// the binary the tunnel was started from
+func sameExecutable(a, b string) bool {
+	clean := func(path string) string {
+		return filepath.Base(strings.TrimSuffix(path, " (deleted)"))
+	}
+	return strings.EqualFold(clean(a), clean(b))
+}
+
 // WaitForLock waits for the lock to become available
 func (sm *SingletonManager) WaitForLock(timeout time.Duration) error {
 	deadline := time.Now().Add(timeout)
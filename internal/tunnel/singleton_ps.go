@@ -0,0 +1,37 @@
+//go:build !linux && !windows
+
+package tunnel
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// processStartTime returns when pid started, as reported by ps
+func processStartTime(pid int) (string, error) {
+	return psField(pid, "lstart=")
+}
+
+// processExecutable returns the program pid runs, as reported by ps
+func processExecutable(pid int) (string, error) {
+	return psField(pid, "comm=")
+}
+
+// bootID is unknown here; the process start time already changes across reboots
+func bootID() string {
+	return ""
+}
+
+func psField(pid int, field string) (string, error) {
+	out, err := exec.Command("ps", "-o", field, "-p", strconv.Itoa(pid)).Output()
+	if err != nil {
+		return "", err
+	}
+	value := strings.TrimSpace(string(out))
+	if value == "" {
+		return "", fmt.Errorf("no process %d", pid)
+	}
+	return value, nil
+}
@@ -144,3 +144,115 @@ func TestSingletonManager_WaitForLock(t *testing.T) {
 	// Clean up
 	sm.ReleaseLock()
 }
+
+// writeLockOwner writes owner to the PID file and, if held, locks it through a separate
+// handle the way another running instance would
+func writeLockOwner(t *testing.T, sm *SingletonManager, owner lockOwner, held bool) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(sm.PidFile), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(sm.PidFile, []byte(owner.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if !held {
+		return
+	}
+	file, err := os.Open(sm.PidFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { file.Close() })
+	if err := lockFile(file); err != nil {
+		t.Fatalf("Failed to hold lock: %v", err)
+	}
+}
+
+func TestSingletonManager_StaleOwner(t *testing.T) {
+	self := currentLockOwner()
+	if self.StartTime == "" {
+		t.Skip("process start time not available")
+	}
+
+	tests := []struct {
+		name  string
+		owner lockOwner
+		stale bool
+	}{
+		{"live tunnel", self, false},
+		{"dead process", lockOwner{PID: 999999999, StartTime: "1"}, true},
+		{"reused PID", lockOwner{PID: self.PID, StartTime: self.StartTime + "0", Executable: self.Executable}, true},
+		{"other program", lockOwner{PID: self.PID, StartTime: self.StartTime, Executable: "/usr/bin/unrelated-daemon"}, true},
+		{"replaced binary", lockOwner{PID: self.PID, StartTime: self.StartTime, Executable: self.Executable + " (deleted)"}, false},
+		{"old PID-only file", lockOwner{PID: self.PID}, false},
+		{"unwritten file", lockOwner{}, false},
+	}
+	if self.BootID != "" {
+		tests = append(tests, struct {
+			name  string
+			owner lockOwner
+			stale bool
+		}{"previous boot", lockOwner{PID: self.PID, StartTime: self.StartTime, BootID: "previous-boot"}, true})
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if stale := tt.owner.staleReason() != ""; stale != tt.stale {
+				t.Errorf("staleReason() stale = %v, want %v", stale, tt.stale)
+			}
+
+			// Whatever the PID file says, a held lock belongs to a live instance
+			t.Run("held", func(t *testing.T) {
+				t.Setenv("HOME", t.TempDir())
+				sm, err := NewSingletonManager()
+				if err != nil {
+					t.Fatal(err)
+				}
+				writeLockOwner(t, sm, tt.owner, true)
+
+				if !sm.IsRunning() {
+					t.Error("IsRunning() = false while the lock is held")
+				}
+				if err := sm.AcquireLock(); err == nil {
+					sm.ReleaseLock()
+					t.Fatal("AcquireLock succeeded while another instance holds the lock")
+				}
+			})
+
+			// Left behind by a crash: nobody holds the lock, so it is taken over
+			t.Run("released", func(t *testing.T) {
+				t.Setenv("HOME", t.TempDir())
+				sm, err := NewSingletonManager()
+				if err != nil {
+					t.Fatal(err)
+				}
+				writeLockOwner(t, sm, tt.owner, false)
+
+				if sm.IsRunning() {
+					t.Error("IsRunning() = true for a lock nobody holds")
+				}
+				writeLockOwner(t, sm, tt.owner, false)
+				if err := sm.AcquireLock(); err != nil {
+					t.Fatalf("AcquireLock over a released lock: %v", err)
+				}
+				defer sm.ReleaseLock()
+				if owner := sm.readLockOwner(); owner.PID != self.PID || owner.StartTime != self.StartTime {
+					t.Errorf("PID file records %+v, want this process", owner)
+				}
+			})
+		})
+	}
+}
+
+func TestLockOwnerFormat(t *testing.T) {
+	owner := lockOwner{PID: 42, StartTime: "123", BootID: "b", Executable: "/usr/local/bin/giraffecloud"}
+	if got := parseLockOwner([]byte(owner.String())); got != owner {
+		t.Errorf("round trip = %+v, want %+v", got, owner)
+	}
+	if got := parseLockOwner([]byte("4242\n")); got != (lockOwner{PID: 4242}) {
+		t.Errorf("PID-only file = %+v", got)
+	}
+	if got := parseLockOwner([]byte("")); got.PID != 0 {
+		t.Errorf("empty file = %+v", got)
+	}
+}
@@ -21,3 +21,9 @@ func unlockFile(file *os.File) error {
 func isLockContended(err error) bool {
 	return err == syscall.EWOULDBLOCK || err == syscall.EAGAIN
 }
+
+// processExists reports whether a process with the PID is running (a zombie counts until reaped)
+func processExists(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
@@ -0,0 +1,78 @@
+package tunnel
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"strings"
+	"sync"
+)
+
+// NewSNIListener wraps a listener so each connection keeps the TLS server name (SNI) its
+// client asked for. Put it under tls.NewListener with a config from
+// CreateSecureServerTLSConfig, whose GetCertificate records the name during the
+// handshake; ServerName and ServerNameFromContext read it back.
+func NewSNIListener(inner net.Listener) net.Listener {
+	return &sniListener{Listener: inner}
+}
+
+type sniListener struct {
+	net.Listener
+}
+
+func (l *sniListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &sniConn{Conn: conn}, nil
+}
+
+// sniConn is a connection from NewSNIListener, carrying the server name of its handshake
+type sniConn struct {
+	net.Conn
+
+	mu         sync.RWMutex
+	serverName string
+}
+
+// recordServerName keeps the SNI of a handshake on connections from NewSNIListener
+func recordServerName(info *tls.ClientHelloInfo) {
+	if c, ok := info.Conn.(*sniConn); ok {
+		c.mu.Lock()
+		c.serverName = strings.ToLower(info.ServerName)
+		c.mu.Unlock()
+	}
+}
+
+// ServerName returns the SNI recorded for conn (a *tls.Conn over a NewSNIListener
+// connection, or that connection itself), or "" when there is none
+func ServerName(conn net.Conn) string {
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		conn = tlsConn.NetConn()
+	}
+	c, ok := conn.(*sniConn)
+	if !ok {
+		return ""
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.serverName
+}
+
+type connContextKey struct{}
+
+// ConnContext stores the connection in the request context; set it as
+// http.Server.ConnContext so handlers can call ServerNameFromContext
+func ConnContext(ctx context.Context, conn net.Conn) context.Context {
+	return context.WithValue(ctx, connContextKey{}, conn)
+}
+
+// ServerNameFromContext returns the SNI of the connection a request arrived on (see ConnContext)
+func ServerNameFromContext(ctx context.Context) string {
+	conn, _ := ctx.Value(connContextKey{}).(net.Conn)
+	if conn == nil {
+		return ""
+	}
+	return ServerName(conn)
+}
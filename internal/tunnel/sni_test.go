@@ -0,0 +1,80 @@
+package tunnel
+
+import (
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/osa911/giraffecloud/internal/logging"
+)
+
+func TestSNIRouting(t *testing.T) {
+	initTestLogger(t)
+	certs, err := writeTestCertificates(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tlsConfig, err := CreateSecureServerTLSConfig(certs.serverCert, certs.serverKey, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, ServerNameFromContext(r.Context()))
+		}),
+		ConnContext: ConnContext,
+	}
+	go server.Serve(tls.NewListener(NewSNIListener(inner), tlsConfig))
+	defer server.Close()
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+		ServerName:         "App.Example.com",
+		InsecureSkipVerify: true,
+	}}}
+	resp, err := client.Get("https://" + inner.Addr().String() + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "app.example.com" {
+		t.Errorf("server name = %q, want app.example.com", body)
+	}
+
+	// Without NewSNIListener there's nothing to report
+	plain, _ := net.Pipe()
+	if name := ServerName(plain); name != "" {
+		t.Errorf("server name of a plain connection = %q, want none", name)
+	}
+
+	// The router falls back to the SNI only when the Host isn't an active tunnel
+	r := &HybridTunnelRouter{
+		logger: logging.GetGlobalLogger(),
+		grpcTunnel: &GRPCTunnelServer{tunnelStreams: map[string]*TunnelStream{
+			"app.example.com": {connected: true},
+			"www.example.com": {connected: true},
+		}},
+		tcpTunnel: &TunnelServer{connections: NewConnectionManager()},
+	}
+	tests := []struct {
+		host, serverName, want string
+	}{
+		{"www.example.com", "app.example.com", "www.example.com"},
+		{"", "app.example.com", "app.example.com"},
+		{"10.0.0.1:8081", "app.example.com", "app.example.com"},
+		{"other.example.com", "unknown.example.com", "other.example.com"},
+		{"other.example.com", "", "other.example.com"},
+	}
+	for _, tt := range tests {
+		if got := r.RoutingDomain(tt.host, tt.serverName); got != tt.want {
+			t.Errorf("RoutingDomain(%q, %q) = %q, want %q", tt.host, tt.serverName, got, tt.want)
+		}
+	}
+}
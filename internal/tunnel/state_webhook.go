@@ -0,0 +1,126 @@
+package tunnel
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/osa911/giraffecloud/internal/logging"
+)
+
+// State change webhook delivery: each event is tried stateWebhookAttempts times, waiting
+// stateWebhookTimeout for each and stateWebhookRetryDelay (doubling) in between
+const (
+	stateWebhookAttempts = 3
+	stateWebhookTimeout  = 5 * time.Second
+	stateWebhookQueue    = 32 // Events waiting for delivery; newer ones are dropped beyond this
+)
+
+var stateWebhookRetryDelay = time.Second
+
+// StateChangeEvent is the JSON body POSTed to Config.StateChangeWebhook
+type StateChangeEvent struct {
+	Domain    string    `json:"domain"`
+	OldState  string    `json:"oldState"`
+	NewState  string    `json:"newState"`
+	Timestamp time.Time `json:"timestamp"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// stateWebhook delivers state changes in order from a single goroutine, so a slow or
+// failing endpoint never holds up the tunnel
+type stateWebhook struct {
+	url    string
+	client *http.Client
+	logger *logging.Logger
+
+	// Domain of the events, looked up on delivery: the state can change while the gRPC
+	// client holds the lock guarding the domain the server assigned
+	domain func() string
+
+	events    chan StateChangeEvent
+	startOnce sync.Once
+}
+
+// newStateWebhook validates the webhook URL (http or https)
+func newStateWebhook(rawURL string, domain func() string) (*stateWebhook, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid state change webhook %q: expected an http(s) URL", rawURL)
+	}
+	return &stateWebhook{
+		url:    rawURL,
+		client: &http.Client{Timeout: stateWebhookTimeout},
+		logger: logging.GetGlobalLogger(),
+		domain: domain,
+		events: make(chan StateChangeEvent, stateWebhookQueue),
+	}, nil
+}
+
+// notify queues an event without blocking
+func (w *stateWebhook) notify(event StateChangeEvent) {
+	w.startOnce.Do(func() { go w.run() })
+	select {
+	case w.events <- event:
+	default:
+		w.logger.Warn("State change webhook is falling behind, dropping %s -> %s event", event.OldState, event.NewState)
+	}
+}
+
+func (w *stateWebhook) run() {
+	for event := range w.events {
+		event.Domain = w.domain()
+		body, err := json.Marshal(event)
+		if err != nil {
+			continue
+		}
+		delay := stateWebhookRetryDelay
+		for attempt := 1; ; attempt++ {
+			err = w.post(body)
+			if err == nil {
+				break
+			}
+			if attempt == stateWebhookAttempts {
+				w.logger.Warn("State change webhook failed after %d attempts: %v", attempt, err)
+				break
+			}
+			time.Sleep(delay)
+			delay *= 2
+		}
+	}
+}
+
+func (w *stateWebhook) post(body []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), stateWebhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "giraffecloud-tunnel")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// redactToken removes the API token from text sent off the machine
+func redactToken(text, token string) string {
+	if token == "" {
+		return text
+	}
+	return strings.ReplaceAll(text, token, "[REDACTED]")
+}
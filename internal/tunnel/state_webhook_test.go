@@ -0,0 +1,81 @@
+package tunnel
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStateChangeWebhook(t *testing.T) {
+	initTestLogger(t)
+	stateWebhookRetryDelay = 10 * time.Millisecond
+	defer func() { stateWebhookRetryDelay = time.Second }()
+
+	var mu sync.Mutex
+	var events []StateChangeEvent
+	calls := 0
+	received := make(chan struct{}, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if calls == 1 {
+			// The first delivery fails and is retried
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var event StateChangeEvent
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Errorf("decoding event: %v", err)
+		}
+		events = append(events, event)
+		received <- struct{}{}
+	}))
+	defer srv.Close()
+
+	tun := NewTunnel()
+	tun.domain = "app.example.com"
+	tun.token = "secret-token"
+	if err := tun.SetStateChangeWebhook("ftp://example.com"); err == nil {
+		t.Error("non-http webhook URL accepted")
+	}
+	if err := tun.SetStateChangeWebhook(srv.URL); err != nil {
+		t.Fatal(err)
+	}
+
+	tun.setState(StateConnected)
+	tun.setReconnecting(true, errors.New("stream closed for token secret-token"))
+	tun.setState(StateReconnecting) // No transition, no event
+	tun.setReconnecting(false, nil)
+
+	for i := 0; i < 3; i++ {
+		select {
+		case <-received:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("only %d of 3 events delivered", i)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := [][2]string{{"Disconnected", "Connected"}, {"Connected", "Reconnecting"}, {"Reconnecting", "Connected"}}
+	if len(events) != len(want) {
+		t.Fatalf("got %d events, want %d", len(events), len(want))
+	}
+	for i, event := range events {
+		if event.OldState != want[i][0] || event.NewState != want[i][1] || event.Domain != "app.example.com" || event.Timestamp.IsZero() {
+			t.Errorf("event %d = %+v, want %s -> %s", i, event, want[i][0], want[i][1])
+		}
+	}
+	if events[0].Error != "" {
+		t.Errorf("connected event has error %q", events[0].Error)
+	}
+	if got := events[1].Error; got != "stream closed for token [REDACTED]" || strings.Contains(got, "secret-token") {
+		t.Errorf("reconnecting event error = %q, want the token redacted", got)
+	}
+}
@@ -0,0 +1,144 @@
+package tunnel
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// stickySessionIdleTimeout drops a session's binding once it has sent nothing for this long
+const stickySessionIdleTimeout = 30 * time.Minute
+
+// stickyBinding ties a session to the pool connection serving it
+type stickyBinding struct {
+	conn     *TunnelConnection
+	lastUsed time.Time
+}
+
+// GetStickyConnection returns the connection bound to the session key, binding the session
+// to the next round-robin connection when it has none yet or its connection left the pool
+func (p *TunnelConnectionPool) GetStickyConnection(key string) *TunnelConnection {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.connections) == 0 {
+		return nil
+	}
+
+	if binding, ok := p.sticky[key]; ok {
+		for _, conn := range p.connections {
+			if conn == binding.conn && conn.GetConn() != nil {
+				binding.lastUsed = time.Now()
+				return conn
+			}
+		}
+		// The session's connection died; fall through and rebind it
+		delete(p.sticky, key)
+	}
+
+	index := atomic.AddUint64(&p.roundRobin, 1) % uint64(len(p.connections))
+	conn := p.connections[index]
+	if conn.GetConn() == nil {
+		go p.CleanupDeadConnections()
+		return nil
+	}
+
+	if p.sticky == nil {
+		p.sticky = make(map[string]*stickyBinding)
+	}
+	p.sticky[key] = &stickyBinding{conn: conn, lastUsed: time.Now()}
+	return conn
+}
+
+// IsSticky reports whether any session is bound to conn
+func (p *TunnelConnectionPool) IsSticky(conn *TunnelConnection) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	for _, binding := range p.sticky {
+		if binding.conn == conn {
+			return true
+		}
+	}
+	return false
+}
+
+// unbindSessionsLocked forgets the sessions bound to conn, so they move to another
+// connection on their next request. Callers hold p.mu.
+func (p *TunnelConnectionPool) unbindSessionsLocked(conn *TunnelConnection) {
+	for key, binding := range p.sticky {
+		if binding.conn == conn {
+			delete(p.sticky, key)
+		}
+	}
+}
+
+// pruneSessionsLocked drops bindings of sessions idle for stickySessionIdleTimeout. Callers hold p.mu.
+func (p *TunnelConnectionPool) pruneSessionsLocked() {
+	for key, binding := range p.sticky {
+		if time.Since(binding.lastUsed) > stickySessionIdleTimeout {
+			delete(p.sticky, key)
+		}
+	}
+}
+
+// GetStickyHTTPConnection returns the HTTP tunnel connection a session is bound to (see
+// TunnelConnectionPool.GetStickyConnection)
+func (m *ConnectionManager) GetStickyHTTPConnection(domain, sessionKey string) *TunnelConnection {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	domainConns := m.connections[domain]
+	if domainConns == nil {
+		return nil
+	}
+
+	domainConns.mu.RLock()
+	defer domainConns.mu.RUnlock()
+
+	return domainConns.httpPool.GetStickyConnection(sessionKey)
+}
+
+// IsStickyHTTPConnection reports whether a session is bound to the domain's HTTP connection
+func (m *ConnectionManager) IsStickyHTTPConnection(domain string, conn *TunnelConnection) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	domainConns := m.connections[domain]
+	if domainConns == nil {
+		return false
+	}
+
+	domainConns.mu.RLock()
+	defer domainConns.mu.RUnlock()
+
+	return domainConns.httpPool.IsSticky(conn)
+}
+
+// stickySessionKey identifies the session a request belongs to: the StickySessionCookie
+// value when configured and present, otherwise the visitor's IP. This only picks a pool
+// connection, so trusting X-Forwarded-For is fine here.
+func (s *TunnelServer) stickySessionKey(conn net.Conn, requestData []byte) string {
+	req, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(requestData)))
+	if err == nil {
+		if name := s.streamConfig.StickySessionCookie; name != "" {
+			if cookie, err := req.Cookie(name); err == nil && cookie.Value != "" {
+				return "cookie:" + cookie.Value
+			}
+		}
+		if forwarded := req.Header.Get("X-Forwarded-For"); forwarded != "" {
+			first, _, _ := strings.Cut(forwarded, ",")
+			return "ip:" + strings.TrimSpace(first)
+		}
+	}
+
+	addr := conn.RemoteAddr().String()
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+	return "ip:" + addr
+}
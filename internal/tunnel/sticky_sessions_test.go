@@ -0,0 +1,60 @@
+package tunnel
+
+import (
+	"net"
+	"testing"
+)
+
+func TestStickySessions(t *testing.T) {
+	pool := NewTunnelConnectionPool("app.example.com", 3000)
+	for i := 0; i < 3; i++ {
+		server, client := net.Pipe()
+		defer client.Close()
+		pool.AddConnection(server)
+	}
+
+	alice := pool.GetStickyConnection("cookie:alice")
+	bob := pool.GetStickyConnection("cookie:bob")
+	if alice == nil || bob == nil || alice == bob {
+		t.Fatalf("sessions should be spread across the pool, got %p and %p", alice, bob)
+	}
+	for i := 0; i < 5; i++ {
+		if got := pool.GetStickyConnection("cookie:alice"); got != alice {
+			t.Fatalf("request %d of alice's session went to another connection", i)
+		}
+	}
+	if !pool.IsSticky(alice) {
+		t.Error("alice's connection should be marked sticky")
+	}
+
+	// When the session's connection leaves the pool, the session moves on and stays there
+	pool.RemoveConnection(alice)
+	moved := pool.GetStickyConnection("cookie:alice")
+	if moved == nil || moved == alice {
+		t.Fatalf("alice's session wasn't rebound after its connection died: %p", moved)
+	}
+	if got := pool.GetStickyConnection("cookie:alice"); got != moved {
+		t.Error("alice's session should stick to its new connection")
+	}
+	if got := pool.GetStickyConnection("cookie:bob"); got != bob {
+		t.Error("bob's session shouldn't move when another connection dies")
+	}
+}
+
+func TestStickySessionKey(t *testing.T) {
+	s := &TunnelServer{streamConfig: &StreamingConfig{StickySessionCookie: "SESSIONID"}}
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+
+	tests := []struct{ request, want string }{
+		{"GET / HTTP/1.1\r\nHost: app.example.com\r\nCookie: theme=dark; SESSIONID=abc123\r\nX-Forwarded-For: 203.0.113.7\r\n\r\n", "cookie:abc123"},
+		{"GET / HTTP/1.1\r\nHost: app.example.com\r\nX-Forwarded-For: 203.0.113.7, 10.0.0.1\r\n\r\n", "ip:203.0.113.7"},
+		{"GET / HTTP/1.1\r\nHost: app.example.com\r\n\r\n", "ip:pipe"},
+	}
+	for _, tt := range tests {
+		if got := s.stickySessionKey(server, []byte(tt.request)); got != tt.want {
+			t.Errorf("stickySessionKey(%q) = %q, want %q", tt.request, got, tt.want)
+		}
+	}
+}
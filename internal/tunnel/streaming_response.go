@@ -0,0 +1,150 @@
+package tunnel
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httputil"
+	"strconv"
+	"strings"
+)
+
+// isStreamingResponse reports whether a local response has to be forwarded as it
+// arrives (chunked bodies, server-sent events) rather than re-written by net/http
+func isStreamingResponse(req *http.Request, resp *http.Response) bool {
+	if req.Method == http.MethodHead || resp.StatusCode < 200 ||
+		resp.StatusCode == http.StatusNoContent || resp.StatusCode == http.StatusNotModified {
+		return false
+	}
+	return isChunkedTransfer(resp.TransferEncoding) || isEventStream(resp.Header)
+}
+
+// isEventStream reports whether the headers describe a server-sent events stream
+func isEventStream(header http.Header) bool {
+	mediaType, _, _ := mime.ParseMediaType(header.Get("Content-Type"))
+	return mediaType == "text/event-stream"
+}
+
+func isChunkedTransfer(te []string) bool {
+	return len(te) > 0 && strings.EqualFold(te[len(te)-1], "chunked")
+}
+
+// flushWriter flushes after every write so a streamed body reaches the client as it arrives
+type flushWriter struct {
+	w *bufio.Writer
+}
+
+func (f flushWriter) Write(p []byte) (int, error) {
+	n, err := f.w.Write(p)
+	if err != nil {
+		return n, err
+	}
+	return n, f.w.Flush()
+}
+
+// writeStreamingResponse writes resp to dst, copying the body from src (the reader
+// http.ReadResponse parsed resp from) as it arrives. Chunked bodies are passed through
+// with their original framing; close-delimited bodies are chunked so the tunnel
+// connection can carry further requests.
+func writeStreamingResponse(dst io.Writer, resp *http.Response, src *bufio.Reader) error {
+	chunked := isChunkedTransfer(resp.TransferEncoding)
+	closeDelimited := !chunked && resp.ContentLength < 0
+
+	header := resp.Header.Clone()
+	header.Del("Content-Length")
+	if closeDelimited {
+		header.Del("Connection") // The end of the body is marked by the last chunk instead
+	}
+
+	var head bytes.Buffer
+	fmt.Fprintf(&head, "HTTP/1.1 %s\r\n", resp.Status)
+	header.Write(&head)
+	if chunked || closeDelimited {
+		head.WriteString("Transfer-Encoding: chunked\r\n")
+	} else {
+		fmt.Fprintf(&head, "Content-Length: %d\r\n", resp.ContentLength)
+	}
+	head.WriteString("\r\n")
+	if _, err := dst.Write(head.Bytes()); err != nil {
+		return err
+	}
+
+	switch {
+	case chunked:
+		return copyChunkedBody(dst, src)
+	case closeDelimited:
+		return copyAsChunks(dst, src)
+	default:
+		_, err := io.CopyN(dst, src, resp.ContentLength)
+		return err
+	}
+}
+
+// copyChunkedBody copies one chunked body from src to dst verbatim (sizes, extensions
+// and trailers included), writing each chunk as soon as it has been read
+func copyChunkedBody(dst io.Writer, src *bufio.Reader) error {
+	for {
+		line, err := src.ReadBytes('\n')
+		if err != nil {
+			return fmt.Errorf("reading chunk size: %w", err)
+		}
+		if _, err := dst.Write(line); err != nil {
+			return err
+		}
+
+		sizeField, _, _ := strings.Cut(strings.TrimSpace(string(line)), ";")
+		size, err := strconv.ParseInt(strings.TrimSpace(sizeField), 16, 64)
+		if err != nil || size < 0 {
+			return fmt.Errorf("invalid chunk size %q", sizeField)
+		}
+
+		if size == 0 {
+			// Trailers, ended by an empty line
+			for {
+				line, err := src.ReadBytes('\n')
+				if err != nil {
+					return fmt.Errorf("reading trailers: %w", err)
+				}
+				if _, err := dst.Write(line); err != nil {
+					return err
+				}
+				if len(bytes.TrimRight(line, "\r\n")) == 0 {
+					return nil
+				}
+			}
+		}
+
+		// Chunk data plus its trailing CRLF
+		if _, err := io.CopyN(dst, src, size+2); err != nil {
+			return fmt.Errorf("copying chunk: %w", err)
+		}
+	}
+}
+
+// copyAsChunks copies src to dst until EOF, sending every read as its own chunk
+func copyAsChunks(dst io.Writer, src io.Reader) error {
+	cw := httputil.NewChunkedWriter(dst)
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := src.Read(buf)
+		if n > 0 {
+			if _, werr := cw.Write(buf[:n]); werr != nil {
+				return werr
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if err := cw.Close(); err != nil {
+		return err
+	}
+	_, err := io.WriteString(dst, "\r\n") // No trailers
+	return err
+}
@@ -0,0 +1,143 @@
+package tunnel
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func readTestResponse(t *testing.T, raw io.Reader, method string) (*http.Response, *bufio.Reader) {
+	t.Helper()
+	req, _ := http.NewRequest(method, "http://localhost/events", nil)
+	br := bufio.NewReader(raw)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		t.Fatalf("ReadResponse: %v", err)
+	}
+	return resp, br
+}
+
+func TestWriteStreamingResponsePreservesChunks(t *testing.T) {
+	body := "5;ext=1\r\nhello\r\n7\r\n, world\r\n0\r\nX-Checksum: abc\r\n\r\n"
+	raw := "HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\nTrailer: X-Checksum\r\n\r\n" + body
+	resp, br := readTestResponse(t, strings.NewReader(raw+"NEXT"), http.MethodGet)
+
+	req, _ := http.NewRequest(http.MethodGet, "/", nil)
+	if !isStreamingResponse(req, resp) {
+		t.Fatal("chunked response not detected as streaming")
+	}
+
+	var out bytes.Buffer
+	if err := writeStreamingResponse(&out, resp, br); err != nil {
+		t.Fatalf("writeStreamingResponse: %v", err)
+	}
+	if !strings.HasSuffix(out.String(), "\r\n\r\n"+body) {
+		t.Fatalf("chunk framing not preserved:\n%q", out.String())
+	}
+	// Bytes after the body belong to the next response and must not be consumed
+	if rest, _ := io.ReadAll(br); string(rest) != "NEXT" {
+		t.Fatalf("remaining input = %q, want %q", rest, "NEXT")
+	}
+}
+
+func TestWriteStreamingResponseSSEIsIncremental(t *testing.T) {
+	pr, pw := io.Pipe()
+	go io.WriteString(pw, "HTTP/1.0 200 OK\r\nContent-Type: text/event-stream\r\nConnection: close\r\n\r\ndata: one\n\n")
+	resp, br := readTestResponse(t, pr, http.MethodGet)
+
+	req, _ := http.NewRequest(http.MethodGet, "/", nil)
+	if !isStreamingResponse(req, resp) {
+		t.Fatal("SSE response not detected as streaming")
+	}
+
+	outR, outW := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		done <- writeStreamingResponse(outW, resp, br)
+		outW.Close()
+	}()
+
+	// The client must see the first event before the local service closes the stream
+	client, _ := readTestResponse(t, outR, http.MethodGet)
+	if client.Header.Get("Connection") == "close" {
+		t.Fatal("Connection: close must be dropped when re-chunking")
+	}
+	events := make(chan string, 1)
+	go func() {
+		buf := make([]byte, 64)
+		n, _ := client.Body.Read(buf)
+		events <- string(buf[:n])
+	}()
+	select {
+	case got := <-events:
+		if got != "data: one\n\n" {
+			t.Fatalf("first event = %q", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("first event was buffered")
+	}
+
+	go func() {
+		io.WriteString(pw, "data: two\n\n")
+		pw.Close()
+	}()
+	rest, err := io.ReadAll(client.Body)
+	if err != nil || string(rest) != "data: two\n\n" {
+		t.Fatalf("rest = %q, %v", rest, err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("writeStreamingResponse: %v", err)
+	}
+}
+
+func TestIsStreamingResponseSkipsBodylessResponses(t *testing.T) {
+	raw := "HTTP/1.1 200 OK\r\nContent-Type: text/event-stream\r\n\r\n"
+	resp, _ := readTestResponse(t, strings.NewReader(raw), http.MethodHead)
+	req, _ := http.NewRequest(http.MethodHead, "/", nil)
+	if isStreamingResponse(req, resp) {
+		t.Fatal("HEAD response treated as streaming")
+	}
+
+	raw = "HTTP/1.1 200 OK\r\nContent-Type: application/json\r\nContent-Length: 2\r\n\r\n{}"
+	resp, _ = readTestResponse(t, strings.NewReader(raw), http.MethodGet)
+	req, _ = http.NewRequest(http.MethodGet, "/", nil)
+	if isStreamingResponse(req, resp) {
+		t.Fatal("fixed-length JSON treated as streaming")
+	}
+}
+
+func TestUsageBodyCountsStreamedBytes(t *testing.T) {
+	pr, pw := io.Pipe()
+	recorded := make(chan int64, 2)
+	body := &usageBody{ReadCloser: pr, record: func(n int64) { recorded <- n }}
+
+	// Events are readable as they arrive; usage waits for the end of the response
+	go pw.Write([]byte("data: one\n\n"))
+	buf := make([]byte, 64)
+	if n, err := body.Read(buf); err != nil || string(buf[:n]) != "data: one\n\n" {
+		t.Fatalf("Read = %q, %v", buf[:n], err)
+	}
+	select {
+	case n := <-recorded:
+		t.Fatalf("usage recorded mid-stream: %d", n)
+	default:
+	}
+
+	go func() {
+		pw.Write([]byte("data: two\n\n"))
+		pw.Close()
+	}()
+	io.Copy(io.Discard, body)
+	body.Close()
+	body.Close()
+	if n := <-recorded; n != 22 {
+		t.Errorf("recorded %d bytes, want 22", n)
+	}
+	if len(recorded) != 0 {
+		t.Error("usage recorded more than once")
+	}
+}
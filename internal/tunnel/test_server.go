@@ -0,0 +1,445 @@
+package tunnel
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/osa911/giraffecloud/internal/api/mapper"
+	"github.com/osa911/giraffecloud/internal/db/ent"
+	"github.com/osa911/giraffecloud/internal/repository"
+)
+
+// Account served by a TestServer
+const (
+	TestServerDomain = "test.giraffecloud.local"
+	TestServerToken  = "test-server-token"
+)
+
+// TestServer is an in-process tunnel server for exercising the client end to end without
+// real infrastructure. It runs the real gRPC tunnel service on loopback with throwaway
+// certificates and an in-memory account holding one tunnel, a local service for that
+// tunnel (EchoHandler unless SetHandler replaces it), and a public HTTP endpoint at URL
+// whose requests and WebSocket upgrades go through the tunnel like visitor traffic.
+//
+// The client reads its certificates and config from ConfigHome, so point
+// GIRAFFECLOUD_HOME there (t.Setenv) before connecting:
+//
+//	ts, err := tunnel.NewTestServer()
+//	defer ts.Close()
+//	t.Setenv("GIRAFFECLOUD_HOME", ts.ConfigHome)
+//	tun, err := ts.Connect(ctx)
+//	defer tun.Disconnect()
+//	resp, err := http.Get(ts.URL + "/hello")
+type TestServer struct {
+	Domain     string // Domain of the test tunnel
+	Token      string // API token accepted in handshakes
+	ConfigHome string // Client config directory: config.json and certificates
+	URL        string // Public endpoint, e.g. http://127.0.0.1:54321
+
+	grpc      *GRPCTunnelServer
+	grpcAddr  string
+	tlsConfig *tls.Config
+	public    *http.Server
+	local     *http.Server
+	localPort int
+
+	handlerMu sync.RWMutex
+	handler   http.Handler
+}
+
+// NewTestServer starts a TestServer on loopback ports; Close stops it and removes ConfigHome
+func NewTestServer() (ts *TestServer, err error) {
+	home, err := os.MkdirTemp("", "giraffecloud-test-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create config home: %w", err)
+	}
+	ts = &TestServer{Domain: TestServerDomain, Token: TestServerToken, ConfigHome: home, handler: EchoHandler()}
+	defer func() {
+		if err != nil {
+			ts.Close()
+		}
+	}()
+
+	certs, err := writeTestCertificates(filepath.Join(home, "certs"))
+	if err != nil {
+		return nil, err
+	}
+	ts.tlsConfig, err = CreateSecureServerTLSConfig(certs.serverCert, certs.serverKey, certs.caCert)
+	if err != nil {
+		return nil, err
+	}
+
+	// Local service the client forwards to
+	localListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for the local service: %w", err)
+	}
+	ts.localPort = localListener.Addr().(*net.TCPAddr).Port
+	ts.local = &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ts.handlerMu.RLock()
+		handler := ts.handler
+		ts.handlerMu.RUnlock()
+		handler.ServeHTTP(w, r)
+	})}
+	go ts.local.Serve(localListener)
+
+	// gRPC tunnel service with an in-memory account; the status cache would need the database
+	tunnel := &ent.Tunnel{ID: 1, Domain: ts.Domain, IsEnabled: true, TargetPort: ts.localPort, UserID: 1}
+	ts.grpc = NewGRPCTunnelServer(
+		&testTokenRepository{token: ts.Token, userID: tunnel.UserID},
+		&testTunnelRepository{tunnel: tunnel},
+		nil,
+		DefaultGRPCTunnelConfig(),
+	)
+	ts.grpc.statusCache = nil
+	grpcListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for gRPC: %w", err)
+	}
+	ts.grpcAddr = grpcListener.Addr().String()
+	if err := ts.grpc.serve(grpcListener, ts.tlsConfig); err != nil {
+		return nil, err
+	}
+
+	// Public side, standing in for Caddy and the hijack server
+	publicListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for visitors: %w", err)
+	}
+	ts.URL = "http://" + publicListener.Addr().String()
+	ts.public = &http.Server{Handler: http.HandlerFunc(ts.proxy)}
+	go ts.public.Serve(publicListener)
+
+	cfg := DefaultConfig
+	cfg.Token = ts.Token
+	cfg.Domain = ts.Domain
+	cfg.LocalPort = ts.localPort
+	cfg.LocalHost = "127.0.0.1"
+	cfg.Server = ServerConfig{Host: "127.0.0.1", GRPCPort: ts.GRPCPort()}
+	cfg.Security.CACert = certs.caCert
+	cfg.Security.ClientCert = certs.clientCert
+	cfg.Security.ClientKey = certs.clientKey
+	data, err := json.MarshalIndent(&cfg, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal client config: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(home, "config.json"), data, 0600); err != nil {
+		return nil, fmt.Errorf("failed to write client config: %w", err)
+	}
+	return ts, nil
+}
+
+// SetHandler replaces the local service's handler (nil restores EchoHandler)
+func (ts *TestServer) SetHandler(handler http.Handler) {
+	if handler == nil {
+		handler = EchoHandler()
+	}
+	ts.handlerMu.Lock()
+	ts.handler = handler
+	ts.handlerMu.Unlock()
+}
+
+// ServerAddr is the server address to give Tunnel.Connect (with SetGRPCPort(GRPCPort()))
+func (ts *TestServer) ServerAddr() string {
+	return ts.grpcAddr
+}
+
+// GRPCPort is the port of the gRPC tunnel listener
+func (ts *TestServer) GRPCPort() int {
+	_, port, _ := net.SplitHostPort(ts.grpcAddr)
+	n, _ := strconv.Atoi(port)
+	return n
+}
+
+// LocalPort is the port of the local service the tunnel forwards to
+func (ts *TestServer) LocalPort() int {
+	return ts.localPort
+}
+
+// Connect starts a Tunnel to the server, living until ctx is done or Disconnect, and waits
+// until it is registered. GIRAFFECLOUD_HOME must point at ConfigHome, so the client never
+// touches the real configuration.
+func (ts *TestServer) Connect(ctx context.Context) (*Tunnel, error) {
+	if os.Getenv("GIRAFFECLOUD_HOME") != ts.ConfigHome {
+		return nil, fmt.Errorf("set GIRAFFECLOUD_HOME to %s before connecting", ts.ConfigHome)
+	}
+	tun := NewTunnel()
+	tun.SetGRPCPort(ts.GRPCPort())
+	tun.SetLocalHost("127.0.0.1")
+	if err := tun.Connect(ctx, ts.ServerAddr(), ts.Token, ts.Domain, ts.localPort, nil); err != nil {
+		tun.Disconnect()
+		return nil, err
+	}
+	if err := ts.WaitForTunnel(ctx); err != nil {
+		tun.Disconnect()
+		return nil, err
+	}
+	return tun, nil
+}
+
+// WaitForTunnel waits until a client holds the test tunnel, e.g. after DropConnections
+func (ts *TestServer) WaitForTunnel(ctx context.Context) error {
+	ticker := time.NewTicker(20 * time.Millisecond)
+	defer ticker.Stop()
+	for !ts.grpc.IsTunnelActive(ts.Domain) {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("tunnel %s did not connect: %w", ts.Domain, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+// DropConnections closes every client connection, as a server restart would, and keeps
+// listening on the same address so clients can reconnect
+func (ts *TestServer) DropConnections() error {
+	ts.grpc.grpcServer.Stop()
+
+	// Let the old stream unregister before a reconnect can register its replacement
+	deadline := time.Now().Add(5 * time.Second)
+	for ts.grpc.IsTunnelActive(ts.Domain) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	listener, err := net.Listen("tcp", ts.grpcAddr)
+	if err != nil {
+		return fmt.Errorf("failed to listen for gRPC again: %w", err)
+	}
+	return ts.grpc.serve(listener, ts.tlsConfig)
+}
+
+// Close stops the server and removes ConfigHome
+func (ts *TestServer) Close() {
+	if ts.public != nil {
+		ts.public.Close()
+	}
+	if ts.grpc != nil && ts.grpc.grpcServer != nil {
+		ts.grpc.grpcServer.Stop()
+	}
+	if ts.local != nil {
+		ts.local.Close()
+	}
+	os.RemoveAll(ts.ConfigHome)
+}
+
+// proxy forwards a visitor request through the tunnel, like the hybrid router does
+func (ts *TestServer) proxy(w http.ResponseWriter, r *http.Request) {
+	clientIP, _, _ := net.SplitHostPort(r.RemoteAddr)
+	r.Host = ts.Domain
+
+	if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		hj, ok := w.(http.Hijacker)
+		if !ok {
+			http.Error(w, "hijacking not supported", http.StatusInternalServerError)
+			return
+		}
+		conn, _, err := hj.Hijack()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer conn.Close()
+		if err := ts.grpc.ProxyWebSocket(ts.Domain, conn, r, clientIP); err != nil {
+			io.WriteString(conn, "HTTP/1.1 502 Bad Gateway\r\nContent-Length: 0\r\nConnection: close\r\n\r\n")
+		}
+		return
+	}
+
+	resp, err := ts.grpc.ProxyHTTPRequestWithChunking(ts.Domain, r, clientIP)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+
+	for key, values := range resp.Header {
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+
+	// Flush as the body arrives so streamed responses reach the visitor unbuffered
+	rc := http.NewResponseController(w)
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := resp.Body.Read(buf)
+		if n > 0 {
+			if _, writeErr := w.Write(buf[:n]); writeErr != nil {
+				return
+			}
+			rc.Flush()
+		}
+		if err != nil {
+			break
+		}
+	}
+	for key, values := range resp.Trailer {
+		for _, value := range values {
+			w.Header().Add(http.TrailerPrefix+key, value)
+		}
+	}
+}
+
+// EchoHandler answers every request with its body, and the method and path it arrived
+// with in X-Echo-Method and X-Echo-Path
+func EchoHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Echo-Method", r.Method)
+		w.Header().Set("X-Echo-Path", r.URL.RequestURI())
+		if contentType := r.Header.Get("Content-Type"); contentType != "" {
+			w.Header().Set("Content-Type", contentType)
+		}
+		io.Copy(w, r.Body)
+	})
+}
+
+// testTokenRepository accepts a single API token. The embedded interface is nil: the
+// tunnel server only looks tokens up.
+type testTokenRepository struct {
+	repository.TokenRepository
+	token  string
+	userID uint32
+}
+
+func (r *testTokenRepository) GetByToken(ctx context.Context, token string) (*mapper.Token, error) {
+	if token != r.token {
+		return nil, fmt.Errorf("%w: token not found", repository.ErrNotFound)
+	}
+	return &mapper.Token{UserID: r.userID, Name: "test"}, nil
+}
+
+// testTunnelRepository holds a single tunnel
+type testTunnelRepository struct {
+	repository.TunnelRepository
+	tunnel *ent.Tunnel
+}
+
+func (r *testTunnelRepository) GetByUserID(ctx context.Context, userID uint32) ([]*ent.Tunnel, error) {
+	if userID != r.tunnel.UserID {
+		return nil, nil
+	}
+	return []*ent.Tunnel{r.tunnel}, nil
+}
+
+func (r *testTunnelRepository) GetByDomain(ctx context.Context, domain string) (*ent.Tunnel, error) {
+	if domain != r.tunnel.Domain {
+		return nil, fmt.Errorf("tunnel not found: %s", domain)
+	}
+	return r.tunnel, nil
+}
+
+// testCertificatePaths are the files written by writeTestCertificates
+type testCertificatePaths struct {
+	caCert, serverCert, serverKey, clientCert, clientKey string
+}
+
+// writeTestCertificates creates a throwaway CA, a server certificate for 127.0.0.1 and
+// localhost, and a client certificate, all valid for a day
+func writeTestCertificates(dir string) (*testCertificatePaths, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create certificate directory: %w", err)
+	}
+	paths := &testCertificatePaths{
+		caCert:     filepath.Join(dir, "ca.crt"),
+		serverCert: filepath.Join(dir, "tunnel.crt"),
+		serverKey:  filepath.Join(dir, "tunnel.key"),
+		clientCert: filepath.Join(dir, "client.crt"),
+		clientKey:  filepath.Join(dir, "client.key"),
+	}
+
+	notBefore := time.Now().Add(-time.Hour)
+	notAfter := time.Now().Add(24 * time.Hour)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "GiraffeCloud Test CA"},
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caCert, caKey, err := issueTestCertificate(caTemplate, nil, nil, paths.caCert, "")
+	if err != nil {
+		return nil, err
+	}
+
+	serverTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	if _, _, err := issueTestCertificate(serverTemplate, caCert, caKey, paths.serverCert, paths.serverKey); err != nil {
+		return nil, err
+	}
+
+	clientTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(3),
+		Subject:      pkix.Name{CommonName: "test-client"},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	if _, _, err := issueTestCertificate(clientTemplate, caCert, caKey, paths.clientCert, paths.clientKey); err != nil {
+		return nil, err
+	}
+	return paths, nil
+}
+
+// issueTestCertificate signs template with parent (self-signed when nil) and writes the
+// PEM certificate, and the key unless keyPath is empty
+func issueTestCertificate(template, parent *x509.Certificate, parentKey *ecdsa.PrivateKey, certPath, keyPath string) (*x509.Certificate, *ecdsa.PrivateKey, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create certificate %s: %w", template.Subject.CommonName, err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		return nil, nil, fmt.Errorf("failed to write %s: %w", certPath, err)
+	}
+	if keyPath != "" {
+		keyDER, err := x509.MarshalECPrivateKey(key)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+			return nil, nil, fmt.Errorf("failed to write %s: %w", keyPath, err)
+		}
+	}
+	return cert, key, nil
+}
@@ -0,0 +1,133 @@
+package tunnel
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTestServer(t *testing.T) {
+	if testing.Short() {
+		t.Skip("end-to-end tunnel test")
+	}
+	initTestLogger(t)
+	ts, err := NewTestServer()
+	if err != nil {
+		t.Fatalf("NewTestServer: %v", err)
+	}
+	defer ts.Close()
+	t.Setenv("GIRAFFECLOUD_HOME", ts.ConfigHome)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	tun, err := ts.Connect(ctx)
+	if err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	defer tun.Disconnect()
+
+	post := func(path string) (*http.Response, []byte) {
+		t.Helper()
+		resp, err := http.Post(ts.URL+path, "text/plain", strings.NewReader("hello"))
+		if err != nil {
+			t.Fatalf("request through the tunnel: %v", err)
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("reading %s: %v", path, err)
+		}
+		return resp, body
+	}
+
+	t.Run("echo", func(t *testing.T) {
+		resp, body := post("/echo?x=1")
+		if resp.StatusCode != http.StatusOK || string(body) != "hello" || resp.Header.Get("X-Echo-Path") != "/echo?x=1" {
+			t.Errorf("got %d %q (path %q)", resp.StatusCode, body, resp.Header.Get("X-Echo-Path"))
+		}
+	})
+
+	t.Run("chunked streaming", func(t *testing.T) {
+		// No Content-Length and flushed writes: the client streams the response in chunks
+		block := bytes.Repeat([]byte("0123456789abcdef"), 64*1024) // 1MB
+		ts.SetHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for i := 0; i < 3; i++ {
+				w.Write(block)
+				http.NewResponseController(w).Flush()
+			}
+		}))
+		defer ts.SetHandler(nil)
+
+		resp, body := post("/stream")
+		if resp.StatusCode != http.StatusOK || len(body) != 3*len(block) {
+			t.Errorf("got %d with %d bytes, want %d", resp.StatusCode, len(body), 3*len(block))
+		}
+	})
+
+	t.Run("trailers", func(t *testing.T) {
+		ts.SetHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/grpc-web")
+			w.Header().Set("Trailer", "Grpc-Status")
+			w.Write([]byte("message"))
+			http.NewResponseController(w).Flush()
+			w.Header().Set("Grpc-Status", "0")
+			w.Header().Set(http.TrailerPrefix+"Grpc-Message", "ok")
+		}))
+		defer ts.SetHandler(nil)
+
+		resp, body := post("/grpc.Service/Method")
+		if string(body) != "message" || resp.Trailer.Get("Grpc-Status") != "0" || resp.Trailer.Get("Grpc-Message") != "ok" {
+			t.Errorf("got %q with trailers %v", body, resp.Trailer)
+		}
+	})
+
+	t.Run("websocket", func(t *testing.T) {
+		ts.SetHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			conn, rw, err := w.(http.Hijacker).Hijack()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			io.WriteString(conn, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n")
+			io.Copy(conn, rw)
+		}))
+		defer ts.SetHandler(nil)
+
+		conn, err := net.Dial("tcp", strings.TrimPrefix(ts.URL, "http://"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		conn.SetDeadline(time.Now().Add(30 * time.Second))
+		io.WriteString(conn, "GET /socket HTTP/1.1\r\nHost: "+ts.Domain+"\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n"+
+			"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n")
+		reader := bufio.NewReader(conn)
+		resp, err := http.ReadResponse(reader, nil)
+		if err != nil || resp.StatusCode != http.StatusSwitchingProtocols {
+			t.Fatalf("upgrade: %v %v", resp, err)
+		}
+		io.WriteString(conn, "ping")
+		echo := make([]byte, 4)
+		if _, err := io.ReadFull(reader, echo); err != nil || string(echo) != "ping" {
+			t.Errorf("echo = %q (%v)", echo, err)
+		}
+	})
+
+	t.Run("reconnect", func(t *testing.T) {
+		if err := ts.DropConnections(); err != nil {
+			t.Fatalf("DropConnections: %v", err)
+		}
+		if err := ts.WaitForTunnel(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if resp, body := post("/after"); resp.StatusCode != http.StatusOK || string(body) != "hello" {
+			t.Errorf("after reconnecting got %d %q", resp.StatusCode, body)
+		}
+	})
+}
@@ -1,12 +1,17 @@
 package tunnel
 
 import (
+	"crypto/sha256"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+
+	"github.com/osa911/giraffecloud/internal/logging"
 )
 
 // expandTildePath expands tilde (~) to the user's home directory
@@ -123,6 +128,77 @@ func ValidateCertificateFiles(caCertPath, clientCertPath, clientKeyPath string)
 	return result
 }
 
+// insecureTLSConfig returns a copy of config that skips server certificate verification
+func insecureTLSConfig(config *tls.Config) *tls.Config {
+	if config == nil {
+		config = &tls.Config{}
+	}
+	config = config.Clone()
+	config.InsecureSkipVerify = true
+	return config
+}
+
+// warnInsecureSkipVerify logs the warning shown for every connection made with --insecure
+func warnInsecureSkipVerify(logger *logging.Logger, serverAddr string) {
+	logger.Warn("⚠️  INSECURE: TLS certificate verification is DISABLED for %s (--insecure). Never use this in production.", serverAddr)
+}
+
+// errServerCertMismatch is returned when the server certificate doesn't match the pinned fingerprint
+var errServerCertMismatch = errors.New("server certificate does not match the pinned fingerprint")
+
+// ParseCertFingerprint normalizes a SHA-256 certificate fingerprint given as hex, with or
+// without colons (as printed by 'openssl x509 -noout -fingerprint -sha256'), to lower-case hex
+func ParseCertFingerprint(fingerprint string) (string, error) {
+	fingerprint = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(fingerprint)), "sha256:")
+	fingerprint = strings.ReplaceAll(fingerprint, ":", "")
+	raw, err := hex.DecodeString(fingerprint)
+	if err != nil || len(raw) != sha256.Size {
+		return "", fmt.Errorf("invalid SHA-256 fingerprint %q: want 64 hex digits, colons optional", fingerprint)
+	}
+	return fingerprint, nil
+}
+
+// certFingerprint formats the SHA-256 fingerprint of a DER certificate like openssl does
+func certFingerprint(der []byte) string {
+	sum := sha256.Sum256(der)
+	hexSum := strings.ToUpper(hex.EncodeToString(sum[:]))
+	pairs := make([]string, 0, sha256.Size)
+	for i := 0; i < len(hexSum); i += 2 {
+		pairs = append(pairs, hexSum[i:i+2])
+	}
+	return strings.Join(pairs, ":")
+}
+
+// pinServerCertificate returns a copy of config that, after the usual verification, also
+// requires the server's leaf certificate to have the given SHA-256 fingerprint. An empty
+// fingerprint leaves config unchanged. The check still applies with --insecure.
+func pinServerCertificate(config *tls.Config, fingerprint string, logger *logging.Logger) (*tls.Config, error) {
+	if fingerprint == "" {
+		return config, nil
+	}
+	want, err := ParseCertFingerprint(fingerprint)
+	if err != nil {
+		return nil, fmt.Errorf("security.server_cert_fingerprint: %w", err)
+	}
+	if config == nil {
+		config = &tls.Config{}
+	}
+	config = config.Clone()
+	config.VerifyConnection = func(state tls.ConnectionState) error {
+		if len(state.PeerCertificates) == 0 {
+			return errServerCertMismatch
+		}
+		got := certFingerprint(state.PeerCertificates[0].Raw)
+		if strings.ReplaceAll(strings.ToLower(got), ":", "") != want {
+			logger.Error("🚨 Server certificate fingerprint mismatch for %s: got %s. If the server certificate was rotated on purpose, update security.server_cert_fingerprint to this value; otherwise the connection may be intercepted.",
+				state.ServerName, got)
+			return errServerCertMismatch
+		}
+		return nil
+	}
+	return config, nil
+}
+
 // CreateSecureTLSConfig creates a production-ready TLS configuration with proper certificate validation
 func CreateSecureTLSConfig(caCertPath, clientCertPath, clientKeyPath string) (*tls.Config, error) {
 	config := &tls.Config{
@@ -184,6 +260,8 @@ func CreateSecureServerTLSConfig(serverCertPath, serverKeyPath, caCertPath strin
 			tls.TLS_RSA_WITH_AES_256_GCM_SHA384,
 		},
 		GetCertificate: func(info *tls.ClientHelloInfo) (*tls.Certificate, error) {
+			// Keep the requested server name as a routing key (see NewSNIListener)
+			recordServerName(info)
 			cert, err := tls.LoadX509KeyPair(serverCertPath, serverKeyPath)
 			if err != nil {
 				return nil, fmt.Errorf("failed to load server certificate: %w", err)
@@ -206,6 +284,7 @@ func CreateSecureServerTLSConfig(serverCertPath, serverKeyPath, caCertPath strin
 
 		config.ClientCAs = caCertPool
 		config.ClientAuth = tls.RequireAndVerifyClientCert
+		config.VerifyConnection = verifyClientNotRevoked // See SetCertRevocationCheck
 	}
 
 	return config, nil
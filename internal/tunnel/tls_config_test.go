@@ -0,0 +1,71 @@
+package tunnel
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseCertFingerprint(t *testing.T) {
+	want := strings.Repeat("ab", 32)
+	for _, input := range []string{
+		want,
+		strings.ToUpper(want),
+		strings.TrimSuffix(strings.Repeat("AB:", 32), ":"),
+		"sha256:" + want,
+		" " + want + "\n",
+	} {
+		if got, err := ParseCertFingerprint(input); err != nil || got != want {
+			t.Errorf("ParseCertFingerprint(%q) = %q, %v", input, got, err)
+		}
+	}
+	for _, input := range []string{"", "abcd", strings.Repeat("zz", 32), strings.Repeat("ab", 20)} {
+		if _, err := ParseCertFingerprint(input); err == nil {
+			t.Errorf("ParseCertFingerprint(%q) succeeded, want an error", input)
+		}
+	}
+}
+
+func TestServerCertificatePinning(t *testing.T) {
+	initTestLogger(t)
+	ts, err := NewTestServer()
+	if err != nil {
+		t.Fatalf("NewTestServer: %v", err)
+	}
+	defer ts.Close()
+	t.Setenv("GIRAFFECLOUD_HOME", ts.ConfigHome)
+
+	certPEM, err := os.ReadFile(filepath.Join(ts.ConfigHome, "certs", "tunnel.crt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, _ := pem.Decode(certPEM)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := func(fingerprint string) error {
+		config := DefaultGRPCClientConfig()
+		config.ServerCertFingerprint = fingerprint
+		client := NewGRPCTunnelClient(ts.ServerAddr(), ts.Domain, ts.Token, int32(ts.LocalPort()), config)
+		defer client.Stop()
+		return client.Start()
+	}
+
+	// A CA-valid certificate with another fingerprint is refused
+	if err := start(strings.Repeat("00", 32)); err == nil || !isAuthenticationError(err) {
+		t.Fatalf("Start() with a wrong pin = %v, want a pinning error", err)
+	}
+	if ts.grpc.IsTunnelActive(ts.Domain) {
+		t.Fatal("tunnel registered despite the pin mismatch")
+	}
+
+	// The server's own fingerprint, as openssl prints it, is accepted
+	if err := start(certFingerprint(cert.Raw)); err != nil {
+		t.Fatalf("Start() with the right pin: %v", err)
+	}
+}
@@ -6,13 +6,14 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"math/rand"
 	"net"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -32,6 +33,7 @@ const (
 	StateReconnecting
 	StateMaintenance
 	StateFailed
+	StateQuotaExceeded // The server refuses the tunnel until the monthly bandwidth quota frees up
 )
 
 func (s ConnectionState) String() string {
@@ -48,6 +50,8 @@ func (s ConnectionState) String() string {
 		return "Maintenance"
 	case StateFailed:
 		return "Failed"
+	case StateQuotaExceeded:
+		return "Quota Exceeded"
 	default:
 		return "Unknown"
 	}
@@ -61,6 +65,19 @@ type RetryConfig struct {
 	BackoffFactor       float64       `json:"backoff_factor"`
 	JitterEnabled       bool          `json:"jitter_enabled"`
 	HealthCheckInterval time.Duration `json:"health_check_interval"`
+
+	// How the delay grows between attempts: exponential (default, by BackoffFactor),
+	// linear (by InitialDelay) or constant (always InitialDelay)
+	BackoffStrategy BackoffStrategy `json:"backoff_strategy,omitempty"`
+
+	// Poll the local service port this often; when it comes back after being down,
+	// stale local connections are dropped and a pending reconnect retries at once (0 = disabled)
+	LocalHealthInterval time.Duration `json:"local_health_interval,omitempty"`
+
+	// Bounds of the gRPC keepalive interval, which shortens while the tunnel keeps
+	// reconnecting and relaxes once it is stable (0 = DefaultKeepAliveMin/DefaultKeepAliveMax)
+	KeepAliveMin time.Duration `json:"keepalive_min,omitempty"`
+	KeepAliveMax time.Duration `json:"keepalive_max,omitempty"`
 }
 
 // DefaultRetryConfig returns sensible defaults for retry configuration
@@ -75,6 +92,40 @@ func DefaultRetryConfig() *RetryConfig {
 	}
 }
 
+// UnmarshalJSON decodes onto DefaultRetryConfig, so a partial "retry" section keeps the
+// defaults for every field it leaves out instead of zero delays and intervals
+func (r *RetryConfig) UnmarshalJSON(data []byte) error {
+	type plain RetryConfig // Drops the method so decoding doesn't recurse
+	decoded := plain(*DefaultRetryConfig())
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*r = RetryConfig(decoded)
+	return nil
+}
+
+// Validate rejects settings the retry and health loops can't run with
+func (r *RetryConfig) Validate() error {
+	if r.InitialDelay <= 0 {
+		return fmt.Errorf("initial_delay must be positive, got %v", r.InitialDelay)
+	}
+	if r.MaxDelay < r.InitialDelay {
+		return fmt.Errorf("max_delay (%v) must be at least initial_delay (%v)", r.MaxDelay, r.InitialDelay)
+	}
+	if r.HealthCheckInterval <= 0 {
+		return fmt.Errorf("health_check_interval must be positive, got %v", r.HealthCheckInterval)
+	}
+	if r.LocalHealthInterval < 0 {
+		return fmt.Errorf("local_health_interval can't be negative, got %v", r.LocalHealthInterval)
+	}
+	if r.BackoffStrategy != "" {
+		if _, err := ParseBackoffStrategy(string(r.BackoffStrategy)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Tunnel represents a secure tunnel connection with enhanced reliability
 type Tunnel struct {
 	conn      net.Conn
@@ -82,22 +133,86 @@ type Tunnel struct {
 	token     string
 	domain    string
 	localPort int
+	localHost string
+	tunnelID  uint32
+	grpcPort  int
 	logger    *logging.Logger
 
+	// Local port used when nothing accepts on localPort (see Config.FallbackLocalPort)
+	fallbackLocalPort int
+	localFailovers    int64
+
+	// Protocol of the local service for the gRPC tunnel (see Config.LocalScheme)
+	localScheme   string
+	localUseHTTP2 bool
+
+	// Request path rewriting before the local service (see Config.LocalPathPrefix)
+	paths pathRewrite
+
+	// Only serve signed links (see Config.RequireSignedURL)
+	requireSignedURL bool
+	signedURLSecret  string
+
+	// UDP forwarding (see SetUDPForward); udpPublicPort is updated to the port the server assigned
+	udpLocalPort   int
+	udpPublicPort  int
+	udpMaxSessions int
+	udpStarted     int32
+
+	// Skip server certificate verification (only set by the --insecure flag)
+	insecureSkipVerify bool
+
+	// Pinned SHA-256 fingerprint of the server certificate (see SecurityConfig.ServerCertFingerprint)
+	serverCertFingerprint string
+
+	// Timeout for regular requests to the local service (0 = GRPCClientConfig default)
+	localRequestTimeout time.Duration
+
+	// Local usage accounting (nil = disabled)
+	usage UsageRecorder
+
+	// Expiry of the configured certificates, reported in GetStats
+	certExpiries []CertExpiry
+
+	// Set by ReloadCertificates: the TLS config for new TCP tunnel connections, and a channel
+	// closed at the next reload
+	certMu       sync.Mutex
+	reloadedTLS  *tls.Config
+	certsRotated chan struct{}
+
+	// Dials the tunnel server with custom DNS resolution (nil = system resolver)
+	serverDialer *ServerDialer
+
+	// TCP tunnel server address of the current connection, used for on-demand and
+	// reconnected WebSocket tunnels
+	tcpServerAddr string
+
+	// Tunnel servers to fail over between (nil = only the address passed to Connect)
+	endpoints *ServerEndpoints
+
 	// Singleton management
 	singletonManager *SingletonManager
 
 	// Enhanced connection management
 	state       ConnectionState
 	stateMutex  sync.RWMutex
-	retryConfig *RetryConfig
+	retryConfig atomic.Pointer[RetryConfig] // Swapped whole by SetRetryConfig (reload) while the retry loops read it
 	retryCount  int
 	lastError   error
 
+	// Notified of every state change (nil = no webhook)
+	stateWebhook *stateWebhook
+
 	// Health monitoring
 	healthTicker *time.Ticker
 	lastPing     time.Time
 
+	// Local service polling (RetryConfig.LocalHealthInterval)
+	localHealthPolling int32         // 1 while the poll goroutine runs
+	waitForLocal       time.Duration // How long the first connection waits for the local service (0 = fail at once)
+	localServiceState  int32         // localServiceUnknown/Up/Down
+	localRecovered     chan struct{} // Signalled when the local service comes back
+
 	// Graceful shutdown
 	ctx    context.Context
 	cancel context.CancelFunc
@@ -115,12 +230,24 @@ type Tunnel struct {
 	isReconnecting         bool
 	isIntentionalReconnect bool // Flag to prevent race conditions during WebSocket recycling
 
+	// Give up instead of reconnecting once an established tunnel drops (see SetOnce)
+	once     bool
+	lost     chan error
+	lostOnce sync.Once
+
 	// WebSocket re-establishment loop control (hybrid mode)
 	wsReconnectMu         sync.Mutex
 	wsReconnectInProgress bool
 
 	// Streaming configuration
-	streamConfig *StreamingConfig
+	streamConfig atomic.Pointer[StreamingConfig] // Swapped whole by UpdateStreamingConfig (reload)
+
+	// Skip media detection whatever streamConfig says (Config.DisableMediaOptimization)
+	disableMediaOptimization bool
+
+	// Bandwidth caps shared by all requests on this tunnel (StreamingConfig.MaxBytesPerSec)
+	uploadLimiter   *bandwidthLimiter // Tunnel -> local service
+	downloadLimiter *bandwidthLimiter // Local service -> tunnel
 
 	// PRODUCTION-GRADE: gRPC Tunnel Client for unlimited HTTP concurrency
 	grpcClient  *GRPCTunnelClient
@@ -140,6 +267,8 @@ type TunnelState struct {
 	Token      string          `json:"token"`
 	Domain     string          `json:"domain"`
 	LocalPort  int             `json:"local_port"`
+	LocalHost  string          `json:"local_host,omitempty"`
+	TunnelID   uint32          `json:"tunnel_id,omitempty"`
 	ServerAddr string          `json:"server_addr"`
 	TLSConfig  *tls.Config     `json:"-"` // Can't serialize, will need to recreate
 	State      ConnectionState `json:"state"`
@@ -161,14 +290,19 @@ func NewTunnel() *Tunnel {
 		logging.GetGlobalLogger().Warn("Failed to create singleton manager: %v", err)
 	}
 
-	return &Tunnel{
+	t := &Tunnel{
 		stopChan:         make(chan struct{}),
 		logger:           logging.GetGlobalLogger(),
 		singletonManager: singletonManager,
 		state:            StateDisconnected,
-		retryConfig:      DefaultRetryConfig(),
-		streamConfig:     DefaultStreamingConfig(), // Use default streaming config
-	}
+		uploadLimiter:    newBandwidthLimiter(0),
+		downloadLimiter:  newBandwidthLimiter(0),
+		localRecovered:   make(chan struct{}, 1),
+		lost:             make(chan error, 1),
+	}
+	t.retryConfig.Store(DefaultRetryConfig())
+	t.streamConfig.Store(DefaultStreamingConfig()) // Use default streaming config
+	return t
 }
 
 // SetOnConnectHook registers a hook to be called after each successful connection establishment
@@ -176,9 +310,145 @@ func (t *Tunnel) SetOnConnectHook(hook func()) {
 	t.onConnectHook = hook
 }
 
+// SetLocalHost sets the host of the local service to forward traffic to (default: localhost)
+func (t *Tunnel) SetLocalHost(host string) {
+	t.localHost = host
+}
+
+// SetLocalProtocol sets the scheme (http or https) of the local service and whether to
+// speak HTTP/2 to it. It applies to requests over the gRPC tunnel.
+func (t *Tunnel) SetLocalProtocol(scheme string, useHTTP2 bool) {
+	t.localScheme = scheme
+	t.localUseHTTP2 = useHTTP2
+}
+
+// SetPathRewrite strips publicStrip from request paths under it and prepends localPrefix
+// before forwarding them to the local service ("" = none). It applies to new connections.
+func (t *Tunnel) SetPathRewrite(publicStrip, localPrefix string) {
+	t.paths = newPathRewrite(publicStrip, localPrefix)
+}
+
+// SetSignedURL makes the server reject requests that aren't for a link signed with
+// secret. It applies from the next connection.
+func (t *Tunnel) SetSignedURL(require bool, secret string) {
+	t.requireSignedURL = require
+	t.signedURLSecret = secret
+}
+
+// SetGRPCPort sets the port of the server's gRPC tunnel listener (0 = DefaultGRPCTunnelPort)
+func (t *Tunnel) SetGRPCPort(port int) {
+	t.grpcPort = port
+}
+
+// SetInsecureSkipVerify disables server certificate verification for every tunnel
+// connection. Only the --insecure flag should enable it; each connection logs a warning.
+func (t *Tunnel) SetInsecureSkipVerify(insecure bool) {
+	t.insecureSkipVerify = insecure
+}
+
+// SetServerCertFingerprint pins the server certificate: connections whose certificate has
+// a different SHA-256 fingerprint are refused even when the CA accepts it (empty = no pinning)
+func (t *Tunnel) SetServerCertFingerprint(fingerprint string) {
+	t.serverCertFingerprint = fingerprint
+}
+
+// SetLocalRequestTimeout sets how long a regular request to the local service may take
+// (0 = default). Large-file and streamed responses have their own limits.
+func (t *Tunnel) SetLocalRequestTimeout(timeout time.Duration) {
+	t.localRequestTimeout = timeout
+}
+
+// SetUsageRecorder records the tunnel's traffic (gRPC requests and WebSockets) to rec
+func (t *Tunnel) SetUsageRecorder(rec UsageRecorder) {
+	t.usage = rec
+	if t.grpcClient != nil {
+		t.grpcClient.SetUsageRecorder(rec)
+	}
+}
+
+// SetServerDialer resolves the tunnel server through d's overrides or DNS server
+// for both the TCP and the gRPC tunnel (nil = system resolver)
+func (t *Tunnel) SetServerDialer(d *ServerDialer) {
+	t.serverDialer = d
+}
+
+// SetServerEndpoints makes the tunnel fail over between several tunnel servers on connect
+// and reconnect, keeping the port of the address passed to Connect (nil = just that one)
+func (t *Tunnel) SetServerEndpoints(e *ServerEndpoints) {
+	t.endpoints = e
+}
+
+// SetCertExpiries records when the certificates used by the tunnel expire, for GetStats
+func (t *Tunnel) SetCertExpiries(expiries []CertExpiry) {
+	t.stateMutex.Lock()
+	defer t.stateMutex.Unlock()
+	t.certExpiries = expiries
+}
+
+// grpcServerAddr returns the gRPC tunnel address on the same host as the TCP tunnel address
+func (t *Tunnel) grpcServerAddr(serverAddr string) (string, error) {
+	host, _, err := net.SplitHostPort(serverAddr)
+	if err != nil {
+		return "", fmt.Errorf("invalid server address %q: %w", serverAddr, err)
+	}
+	port := t.grpcPort
+	if port == 0 {
+		port = DefaultGRPCTunnelPort
+	}
+	return net.JoinHostPort(host, strconv.Itoa(port)), nil
+}
+
+// SetTunnelID selects which of the account's tunnels to connect to by ID (0 = match by domain)
+func (t *Tunnel) SetTunnelID(id uint32) {
+	t.tunnelID = id
+}
+
+// SetDisableMediaOptimization handles all requests through the regular path, without media detection
+func (t *Tunnel) SetDisableMediaOptimization(disable bool) {
+	t.disableMediaOptimization = disable
+}
+
+// SetOnce makes the tunnel give up instead of reconnecting: when an established tunnel
+// drops, the reason is sent on Lost. Pair it with a small RetryConfig.MaxRetries so the
+// first connection gives up too.
+func (t *Tunnel) SetOnce(once bool) {
+	t.once = once
+}
+
+// Lost receives why the tunnel dropped when running with SetOnce
+func (t *Tunnel) Lost() <-chan error {
+	return t.lost
+}
+
+// markLost reports the first loss of a tunnel running with SetOnce
+func (t *Tunnel) markLost(err error) {
+	t.lostOnce.Do(func() {
+		t.lastError = err
+		t.setState(StateFailed)
+		t.logger.Error("Tunnel lost, not reconnecting (--once): %v", err)
+		t.lost <- err
+	})
+}
+
 // SetRetryConfig allows customization of retry behavior
 func (t *Tunnel) SetRetryConfig(config *RetryConfig) {
-	t.retryConfig = config
+	t.retryConfig.Store(config)
+	if t.grpcClient != nil {
+		t.grpcClient.SetBackoffStrategy(config.BackoffStrategy)
+		t.grpcClient.SetKeepAliveBounds(config.KeepAliveMin, config.KeepAliveMax)
+	}
+}
+
+// GetDomain returns the domain the tunnel serves: the one asked for, or the one the server
+// assigned in the handshake ("" before the first connection)
+func (t *Tunnel) GetDomain() string {
+	if t.domain != "" {
+		return t.domain
+	}
+	if t.grpcClient != nil {
+		return t.grpcClient.ServedDomain()
+	}
+	return ""
 }
 
 // GetState returns the current connection state
@@ -194,10 +464,52 @@ func (t *Tunnel) setState(state ConnectionState) {
 	defer t.stateMutex.Unlock()
 	if t.state != state {
 		t.logger.Info("Connection state changed: %s -> %s", t.state, state)
+		if t.stateWebhook != nil {
+			event := StateChangeEvent{
+				OldState:  t.state.String(),
+				NewState:  state.String(),
+				Timestamp: time.Now().UTC(),
+			}
+			if state != StateConnected && t.lastError != nil {
+				event.Error = redactToken(t.lastError.Error(), t.token)
+			}
+			t.stateWebhook.notify(event)
+		}
 		t.state = state
 	}
 }
 
+// SetStateChangeWebhook POSTs a StateChangeEvent to url on every state change, e.g. to
+// alert when the tunnel goes down or reconnects (empty = off)
+func (t *Tunnel) SetStateChangeWebhook(url string) error {
+	if url == "" {
+		t.stateWebhook = nil
+		return nil
+	}
+	webhook, err := newStateWebhook(url, t.GetDomain)
+	if err != nil {
+		return err
+	}
+	t.stateWebhook = webhook
+	return nil
+}
+
+// setReconnecting reflects the gRPC client losing its stream and getting it back in the
+// tunnel state
+func (t *Tunnel) setReconnecting(reconnecting bool, err error) {
+	if reconnecting {
+		if err != nil {
+			t.lastError = err
+		}
+		t.setState(StateReconnecting)
+		return
+	}
+	t.serverReconnected()
+	if t.GetState() == StateReconnecting {
+		t.setState(StateConnected)
+	}
+}
+
 // Connect establishes tunnel connections with retry logic
 func (t *Tunnel) Connect(ctx context.Context, serverAddr, token, domain string, localPort int, tlsConfig *tls.Config) error {
 	// Check singleton lock before connecting
@@ -224,6 +536,14 @@ func (t *Tunnel) Connect(ctx context.Context, serverAddr, token, domain string,
 	t.token = token
 	t.domain = domain
 	t.localPort = localPort
+	t.startLocalHealthPolling()
+
+	// Start the tunnel and the local service together without a fixed sleep
+	if t.waitForLocal > 0 && t.localPort > 0 {
+		if err := t.waitForLocalService(t.ctx, t.waitForLocal); err != nil {
+			return err
+		}
+	}
 
 	// Start the connections with retry logic
 	return t.connectWithRetry(serverAddr, tlsConfig)
@@ -232,7 +552,7 @@ func (t *Tunnel) Connect(ctx context.Context, serverAddr, token, domain string,
 // connectWithRetry implements exponential backoff retry logic for both connection types
 func (t *Tunnel) connectWithRetry(serverAddr string, tlsConfig *tls.Config) error {
 	t.retryCount = 0
-	delay := t.retryConfig.InitialDelay
+	delay := t.retryConfig.Load().InitialDelay
 
 	for {
 		select {
@@ -242,9 +562,9 @@ func (t *Tunnel) connectWithRetry(serverAddr string, tlsConfig *tls.Config) erro
 		}
 
 		// Check if we've exceeded max retries (if set)
-		if t.retryConfig.MaxRetries > 0 && t.retryCount >= t.retryConfig.MaxRetries {
+		if t.retryConfig.Load().MaxRetries > 0 && t.retryCount >= t.retryConfig.Load().MaxRetries {
 			t.setState(StateFailed)
-			return fmt.Errorf("max retries (%d) exceeded, last error: %w", t.retryConfig.MaxRetries, t.lastError)
+			return fmt.Errorf("max retries (%d) exceeded, last error: %w", t.retryConfig.Load().MaxRetries, t.lastError)
 		}
 
 		// Set appropriate state
@@ -254,21 +574,25 @@ func (t *Tunnel) connectWithRetry(serverAddr string, tlsConfig *tls.Config) erro
 			t.setState(StateReconnecting)
 			t.logger.Info("Retrying connection (attempt %d) in %v...", t.retryCount+1, delay)
 
-			// Wait with context cancellation support
+			// Wait with context cancellation support; a recovered local service retries at once
 			select {
 			case <-time.After(delay):
+			case <-t.localRecovered:
+				t.logger.Info("Local service is back, retrying now")
+				delay = t.retryConfig.Load().InitialDelay
 			case <-t.ctx.Done():
 				return fmt.Errorf("connection cancelled during retry")
 			}
 		}
 
-		// Attempt to establish both HTTP and WebSocket connections
-		err := t.attemptDualConnections(serverAddr, tlsConfig)
+		// Attempt to establish both HTTP and WebSocket connections, on each server in turn
+		connectedAddr, err := t.attemptServers(serverAddr, tlsConfig)
 		if err == nil {
 			// Success! Reset retry count and start health monitoring
 			t.retryCount = 0
 			t.setState(StateConnected)
 			t.startHealthMonitoring()
+			t.startUDPForwarding(connectedAddr, tlsConfig)
 			if t.onConnectHook != nil {
 				// Invoke hook safely in a separate goroutine
 				go func() {
@@ -289,13 +613,21 @@ func (t *Tunnel) connectWithRetry(serverAddr string, tlsConfig *tls.Config) erro
 			return err // Return immediately without retrying
 		}
 
+		// Over quota: retrying at the usual pace can't help, the quota has to free up first
+		if errors.Is(err, ErrQuotaExceeded) {
+			t.setState(StateQuotaExceeded)
+			t.logger.Error("Monthly bandwidth quota reached - the tunnel is paused until the quota resets or the plan is upgraded; checking again in %v", QuotaRetryInterval)
+			delay = QuotaRetryInterval
+			continue
+		}
+
 		// Check if this is a maintenance mode error
 		if isMaintenanceError(err) {
 			t.setState(StateMaintenance)
 			t.logger.Info("Server is in maintenance mode, will retry when available")
-			delay = t.retryConfig.HealthCheckInterval // Use health check interval for maintenance
+			delay = t.retryConfig.Load().HealthCheckInterval // Use health check interval for maintenance
 		} else {
-			// Calculate next delay with exponential backoff
+			// Calculate next delay with the configured backoff
 			delay = t.calculateNextDelay(delay)
 			t.logger.Error("Connection failed (attempt %d): %v", t.retryCount, err)
 		}
@@ -360,20 +692,55 @@ func (t *Tunnel) attemptDualConnections(serverAddr string, tlsConfig *tls.Config
 	// Step 1: Establish or reuse gRPC tunnel for HTTP traffic (unlimited concurrency)
 	t.logger.Info("📡 Establishing gRPC tunnel for HTTP traffic...")
 
-	// Parse server address for gRPC port
-	grpcServerAddr := strings.Replace(serverAddr, ":4443", ":4444", 1) // Use gRPC port
+	// The gRPC tunnel listens on its own port on the same host
+	grpcServerAddr, err := t.grpcServerAddr(serverAddr)
+	if err != nil {
+		return err
+	}
+	t.tcpServerAddr = serverAddr
 
 	// Reuse existing client if available, otherwise create
 	if t.grpcClient == nil {
 		grpcConfig := DefaultGRPCClientConfig()
+		grpcConfig.InsecureSkipVerify = t.insecureSkipVerify
+		grpcConfig.ServerCertFingerprint = t.serverCertFingerprint
+		grpcConfig.Dialer = t.serverDialer
+		grpcConfig.LocalScheme = t.localScheme
+		grpcConfig.LocalUseHTTP2 = t.localUseHTTP2
+		grpcConfig.LocalPathPrefix = t.paths.prefix
+		grpcConfig.PublicPathStrip = t.paths.strip
+		grpcConfig.RequireSignedURL = t.requireSignedURL
+		grpcConfig.SignedURLSecret = t.signedURLSecret
+		grpcConfig.BackoffStrategy = t.retryConfig.Load().BackoffStrategy
+		grpcConfig.KeepAliveMin = t.retryConfig.Load().KeepAliveMin
+		grpcConfig.KeepAliveMax = t.retryConfig.Load().KeepAliveMax
+		grpcConfig.DisableReconnect = t.once
+		if t.localRequestTimeout > 0 {
+			grpcConfig.LocalRequestTimeout = t.localRequestTimeout
+		}
 		t.grpcClient = NewGRPCTunnelClient(grpcServerAddr, t.domain, t.token, int32(t.localPort), grpcConfig)
+		t.grpcClient.SetLocalHost(t.localHost)
+		t.grpcClient.SetFallbackLocalPort(t.fallbackLocalPort)
+		t.grpcClient.SetTunnelID(t.tunnelID)
+		t.grpcClient.SetBandwidthLimiters(t.uploadLimiter, t.downloadLimiter)
+		t.grpcClient.SetReissueOnStreamFailure(t.streamConfig.Load().ReissueOnStreamFailure)
+		t.grpcClient.SetChunkWindowSize(t.streamConfig.Load().ChunkWindowSize)
+		t.grpcClient.SetUsageRecorder(t.usage)
 
 		// Set up tunnel establishment handler for demand-based tunnel creation
 		t.grpcClient.SetTunnelEstablishHandler(t.handleTunnelEstablishRequest)
+		t.grpcClient.SetQuotaHandler(t.setQuotaExceeded)
+		t.grpcClient.SetReconnectHandler(t.setReconnecting)
+		if t.endpoints != nil {
+			t.grpcClient.SetServerFailover(t.nextGRPCServer)
+		}
+		if t.once {
+			t.grpcClient.SetDisconnectHandler(t.markLost)
+		}
 
 		if err := t.grpcClient.Start(); err != nil {
-			// CRITICAL: Propagate authentication errors immediately to stop retry loop
-			if isAuthenticationError(err) {
+			// CRITICAL: Propagate authentication, quota and maintenance errors to the retry loop
+			if isAuthenticationError(err) || errors.Is(err, ErrQuotaExceeded) || isMaintenanceError(err) {
 				return err
 			}
 			t.logger.Error("Failed to establish gRPC tunnel: %v", err)
@@ -390,9 +757,10 @@ func (t *Tunnel) attemptDualConnections(serverAddr string, tlsConfig *tls.Config
 			t.grpcEnabled = true
 		} else {
 			t.logger.Info("Existing gRPC client not connected; attempting to start (Client ID: %s)", t.grpcClient.GetClientID())
+			t.grpcClient.SetServerAddr(grpcServerAddr)
 			if err := t.grpcClient.Start(); err != nil {
-				// CRITICAL: Propagate authentication errors immediately to stop retry loop
-				if isAuthenticationError(err) {
+				// CRITICAL: Propagate authentication, quota and maintenance errors to the retry loop
+				if isAuthenticationError(err) || errors.Is(err, ErrQuotaExceeded) || isMaintenanceError(err) {
 					return err
 				}
 				t.logger.Error("Failed to (re)start existing gRPC client: %v", err)
@@ -415,31 +783,117 @@ func (t *Tunnel) attemptDualConnections(serverAddr string, tlsConfig *tls.Config
 	return fmt.Errorf("gRPC tunnel is required for demand-based tunnel establishment - cannot proceed without it")
 }
 
-// startWebSocketReconnectLoop is obsolete in on-demand establishment model
-// The server now requests new WebSocket tunnels via gRPC as needed
-// This function is kept for backward compatibility but does nothing
+// defaultWSReconnectMaxAttempts bounds the WebSocket reconnect loop when RetryConfig.MaxRetries is unlimited.
+// The gRPC tunnel keeps serving HTTP and the server can still request WebSocket tunnels on demand,
+// so there is no reason to retry a dead local/remote WebSocket path forever.
+const defaultWSReconnectMaxAttempts = 10
+
+// startWebSocketReconnectLoop re-establishes a WebSocket tunnel after a TCP-only reconnect.
+// Only one loop runs at a time; a nil tlsConfig is rebuilt from the config file.
 func (t *Tunnel) startWebSocketReconnectLoop(serverAddr string, tlsConfig *tls.Config) {
-	t.logger.Debug("🔄 WebSocket reconnect loop disabled - using on-demand establishment model")
-	// In hybrid mode with on-demand establishment, WebSocket tunnels are
-	// created on-demand by the server via establishTCPTunnelOnDemand()
-	// No need for automatic reconnection loops
+	if t.once {
+		// The server still requests WebSocket tunnels on demand
+		t.logger.Debug("🔄 Not re-establishing WebSocket tunnel (--once)")
+		return
+	}
+
+	t.wsReconnectMu.Lock()
+	if t.wsReconnectInProgress {
+		t.wsReconnectMu.Unlock()
+		t.logger.Debug("🔄 WebSocket reconnect loop already running")
+		return
+	}
+	t.wsReconnectInProgress = true
+	t.wsReconnectMu.Unlock()
+
+	defer func() {
+		t.wsReconnectMu.Lock()
+		t.wsReconnectInProgress = false
+		t.wsReconnectMu.Unlock()
+	}()
+
+	t.runWebSocketReconnectLoop(func() error {
+		return t.reestablishWebSocketTunnel(serverAddr, tlsConfig)
+	})
+}
+
+// runWebSocketReconnectLoop calls dial with exponential backoff until it succeeds, the tunnel
+// shuts down or the attempt limit is reached. It returns the last dial error when giving up.
+func (t *Tunnel) runWebSocketReconnectLoop(dial func() error) error {
+	maxAttempts := t.retryConfig.Load().MaxRetries
+	if maxAttempts <= 0 {
+		maxAttempts = defaultWSReconnectMaxAttempts
+	}
+
+	delay := t.retryConfig.Load().InitialDelay
+	for attempt := 1; ; attempt++ {
+		err := dial()
+		if err == nil {
+			if attempt > 1 {
+				t.logger.Info("✅ WebSocket tunnel re-established after %d attempts", attempt)
+			}
+			return nil
+		}
+
+		if attempt >= maxAttempts {
+			t.logger.Error("❌ Giving up on WebSocket tunnel after %d attempts: %v (gRPC tunnel unaffected, WebSocket tunnels will be requested on demand)", attempt, err)
+			return err
+		}
+
+		t.logger.Debug("WebSocket reconnect attempt %d/%d failed: %v, retrying in %v", attempt, maxAttempts, err, delay)
+		select {
+		case <-time.After(delay):
+		case <-t.ctx.Done():
+			return t.ctx.Err()
+		}
+		delay = t.calculateNextDelay(delay)
+	}
+}
+
+// reestablishWebSocketTunnel dials a single WebSocket tunnel connection and starts its handler
+func (t *Tunnel) reestablishWebSocketTunnel(serverAddr string, tlsConfig *tls.Config) error {
+	if tlsConfig == nil {
+		var err error
+		if tlsConfig, err = loadTCPTunnelTLSConfig(); err != nil {
+			return err
+		}
+	}
+
+	wsConn, err := t.establishConnection(serverAddr, tlsConfig, "websocket", "")
+	if err != nil {
+		return err
+	}
+
+	t.wsConnsMu.Lock()
+	t.wsConns = append(t.wsConns, wsConn)
+	t.wsConnsMu.Unlock()
+
+	t.wg.Add(1)
+	go t.handleWebSocketConnection(wsConn)
+	return nil
 }
 
 // establishConnection establishes a single tunnel connection of specified type
-func (t *Tunnel) establishConnection(serverAddr string, tlsConfig *tls.Config, connType string) (net.Conn, error) {
-	// Connect to server with TLS and timeout
-	dialer := &net.Dialer{
-		Timeout: 10 * time.Second,
+// requestID is echoed back to the server when answering a TunnelEstablishRequest (empty otherwise)
+func (t *Tunnel) establishConnection(serverAddr string, tlsConfig *tls.Config, connType, requestID string) (net.Conn, error) {
+	if t.insecureSkipVerify {
+		tlsConfig = insecureTLSConfig(tlsConfig)
+		warnInsecureSkipVerify(t.logger, serverAddr)
+	}
+	tlsConfig, err := pinServerCertificate(tlsConfig, t.serverCertFingerprint, t.logger)
+	if err != nil {
+		return nil, fmt.Errorf("CONFIGURATION ERROR: %w", err)
 	}
 
-	conn, err := tls.DialWithDialer(dialer, "tcp", serverAddr, tlsConfig)
+	// Connect to server with TLS and timeout, resolving it through any DNS overrides
+	conn, err := t.serverDialer.DialTLS("tcp", serverAddr, tlsConfig)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to server: %w", err)
 	}
 
 	// Perform handshake with timeout and connection type
 	conn.SetDeadline(time.Now().Add(15 * time.Second))
-	resp, err := t.performHandshake(conn, t.token, connType)
+	resp, err := t.performHandshake(conn, t.token, connType, requestID)
 	if err != nil {
 		conn.Close()
 		return nil, fmt.Errorf("handshake failed: %w", err)
@@ -453,15 +907,26 @@ func (t *Tunnel) establishConnection(serverAddr string, tlsConfig *tls.Config, c
 	if t.localPort <= 0 {
 		t.localPort = resp.TargetPort
 	}
+	if resp.UDPPort != 0 {
+		t.udpPublicPort = resp.UDPPort // Ask for the same port when reconnecting
+	}
 
 	// Check if the local port is actually listening (only once)
 	if connType == "http" {
-		localConn, err := net.DialTimeout("tcp", fmt.Sprintf("localhost:%d", t.localPort), 5*time.Second)
-		if err != nil {
-			conn.Close()
-			return nil, fmt.Errorf("no service found listening on port %d - make sure your service is running first", t.localPort)
+		if t.waitForLocal > 0 {
+			// The port may only be known now that the server sent it
+			if err := t.waitForLocalService(t.ctx, t.waitForLocal); err != nil {
+				conn.Close()
+				return nil, err
+			}
+		} else {
+			localConn, err := t.dialLocalService()
+			if err != nil {
+				conn.Close()
+				return nil, fmt.Errorf("no service found listening on %s - make sure your service is running first", localServiceAddr(t.localHost, t.localPort))
+			}
+			localConn.Close()
 		}
-		localConn.Close()
 	}
 
 	t.logger.Info("%s tunnel connection established successfully", strings.Title(connType))
@@ -469,7 +934,7 @@ func (t *Tunnel) establishConnection(serverAddr string, tlsConfig *tls.Config, c
 }
 
 // performHandshake performs the handshake for a specific connection type
-func (t *Tunnel) performHandshake(conn net.Conn, token, connType string) (*TunnelHandshakeResponse, error) {
+func (t *Tunnel) performHandshake(conn net.Conn, token, connType, requestID string) (*TunnelHandshakeResponse, error) {
 	// Create JSON encoder/decoder
 	encoder := json.NewEncoder(conn)
 	decoder := json.NewDecoder(conn)
@@ -478,7 +943,12 @@ func (t *Tunnel) performHandshake(conn net.Conn, token, connType string) (*Tunne
 	req := TunnelHandshakeRequest{
 		Token:          token,
 		Domain:         t.domain, // Include domain so server knows which tunnel to match
+		TunnelID:       t.tunnelID,
 		ConnectionType: connType,
+		RequestID:      requestID,
+	}
+	if connType == string(ConnectionTypeUDP) {
+		req.UDPPort = t.udpPublicPort
 	}
 
 	if err := encoder.Encode(req); err != nil {
@@ -492,6 +962,9 @@ func (t *Tunnel) performHandshake(conn net.Conn, token, connType string) (*Tunne
 	}
 
 	if resp.Status != "success" {
+		if resp.Code != "" {
+			return nil, fmt.Errorf("handshake failed: %w", &HandshakeError{Code: resp.Code, Message: resp.Message})
+		}
 		return nil, fmt.Errorf("handshake failed: %s", resp.Message)
 	}
 
@@ -551,6 +1024,7 @@ func (t *Tunnel) handleHTTPConnection(conn net.Conn, connIndex ...int) {
 			conn.SetReadDeadline(time.Time{})
 
 			t.logger.Info("Received HTTP request: %s %s", request.Method, request.URL.Path)
+			t.paths.applyToRequest(request)
 
 			// Handle regular HTTP request
 			t.handleHTTPRequest(request, conn)
@@ -619,6 +1093,7 @@ func (t *Tunnel) handleWebSocketConnection(conn net.Conn) {
 			conn.SetReadDeadline(time.Time{})
 
 			t.logger.Info("Received WebSocket upgrade request: %s %s", request.Method, request.URL.Path)
+			t.paths.applyToRequest(request)
 
 			// Handle WebSocket upgrade - this will consume the entire connection
 			t.handleWebSocketUpgradeOnDedicatedConnection(request, conn)
@@ -637,7 +1112,7 @@ func (t *Tunnel) handleWebSocketUpgradeOnDedicatedConnection(request *http.Reque
 	t.logger.Info("[WEBSOCKET DEBUG] Handling WebSocket upgrade to local service on port %d", t.localPort)
 
 	// Connect to local service for WebSocket upgrade
-	localConn, err := net.DialTimeout("tcp", fmt.Sprintf("localhost:%d", t.localPort), 5*time.Second)
+	localConn, err := t.dialLocalService()
 	if err != nil {
 		t.logger.Error("[WEBSOCKET DEBUG] Failed to connect to local service: %v", err)
 		// Send error response back through tunnel
@@ -683,19 +1158,36 @@ func (t *Tunnel) handleWebSocketUpgradeOnDedicatedConnection(request *http.Reque
 
 	t.logger.Info("[WEBSOCKET DEBUG] WebSocket upgrade successful, starting bidirectional forwarding")
 
+	// Close the connection if it goes idle so a silent WebSocket can't hold the tunnel
+	// connection forever. The local service is the WebSocket server: frames to it are masked.
+	monitor := newWSIdleMonitor(t.streamConfig.Load().WebSocketIdleTimeout, t.streamConfig.Load().WebSocketPingInterval)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		if monitor.run(done, wsPeer{conn: tunnelConn}, wsPeer{conn: localConn, masked: true}, isWebSocketUpgrade(response)) {
+			t.logger.Info("[WEBSOCKET] Closed WebSocket after %v without traffic", monitor.idleTimeout)
+		}
+	}()
+
 	// Start bidirectional copying between tunnel and local service
 	errChan := make(chan error, 2)
 
 	// Copy from tunnel to local service
 	go func() {
-		_, err := io.Copy(localConn, tunnelConn)
+		n, err := io.Copy(localConn, monitor.reader(tunnelConn))
+		if t.usage != nil {
+			t.usage.Increment(0, t.tunnelID, t.domain, n, 0, 1)
+		}
 		t.logger.Info("[WEBSOCKET DEBUG] Tunnel->Local copy finished: %v", err)
 		errChan <- err
 	}()
 
 	// Copy from local service to tunnel
 	go func() {
-		_, err := io.Copy(tunnelConn, localConn)
+		n, err := io.Copy(tunnelConn, monitor.reader(localConn))
+		if t.usage != nil {
+			t.usage.Increment(0, t.tunnelID, t.domain, 0, n, 0)
+		}
 		t.logger.Info("[WEBSOCKET DEBUG] Local->Tunnel copy finished: %v", err)
 		errChan <- err
 	}()
@@ -717,7 +1209,7 @@ func (t *Tunnel) handleHTTPRequest(request *http.Request, tunnelConn net.Conn) {
 	isMediaRequest := t.isMediaRequest(request)
 
 	// Connect to local service for this request
-	localConn, err := net.DialTimeout("tcp", fmt.Sprintf("localhost:%d", t.localPort), 5*time.Second)
+	localConn, err := t.dialLocalService()
 	if err != nil {
 		t.logger.Error("Failed to connect to local service: %v", err)
 		// Send error response back through tunnel
@@ -744,14 +1236,14 @@ func (t *Tunnel) handleHTTPRequest(request *http.Request, tunnelConn net.Conn) {
 
 // isMediaRequest checks if this is a media/video request
 func (t *Tunnel) isMediaRequest(request *http.Request) bool {
-	if !t.streamConfig.EnableMediaOptimization {
+	if t.disableMediaOptimization || !t.streamConfig.Load().EnableMediaOptimization {
 		return false
 	}
 
 	path := request.URL.Path
 
 	// Check for media file extensions from config
-	for _, ext := range t.streamConfig.MediaExtensions {
+	for _, ext := range t.streamConfig.Load().MediaExtensions {
 		if strings.HasSuffix(path, ext) {
 			return true
 		}
@@ -763,7 +1255,7 @@ func (t *Tunnel) isMediaRequest(request *http.Request) bool {
 	}
 
 	// Check for media paths from config
-	for _, mediaPath := range t.streamConfig.MediaPaths {
+	for _, mediaPath := range t.streamConfig.Load().MediaPaths {
 		if strings.Contains(path, mediaPath) {
 			return true
 		}
@@ -777,21 +1269,23 @@ func (t *Tunnel) handleMediaResponse(localConn, tunnelConn net.Conn) {
 	t.logger.Info("Starting optimized media streaming")
 
 	// Use larger buffers for media streaming
-	buffer := make([]byte, t.streamConfig.MediaBufferSize)
+	buffer := make([]byte, t.streamConfig.Load().MediaBufferSize)
 
-	// Start bidirectional copying with optimized buffers
+	// Start bidirectional copying with optimized buffers, paced by the tunnel's bandwidth caps
 	errChan := make(chan error, 2)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
 	// Copy from local service to tunnel (response)
 	go func() {
-		_, err := io.CopyBuffer(tunnelConn, localConn, buffer)
+		_, err := io.CopyBuffer(newThrottledWriter(ctx, tunnelConn, t.downloadLimiter), localConn, buffer)
 		errChan <- err
 	}()
 
 	// Copy from tunnel to local service (for any additional data)
 	go func() {
-		buffer2 := make([]byte, t.streamConfig.MediaBufferSize)
-		_, err := io.CopyBuffer(localConn, tunnelConn, buffer2)
+		buffer2 := make([]byte, t.streamConfig.Load().MediaBufferSize)
+		_, err := io.CopyBuffer(newThrottledWriter(ctx, localConn, t.uploadLimiter), tunnelConn, buffer2)
 		errChan <- err
 	}()
 
@@ -814,6 +1308,18 @@ func (t *Tunnel) handleRegularResponse(request *http.Request, localConn, tunnelC
 		return
 	}
 
+	// Chunked and SSE bodies are streamed through as they arrive so events aren't held back
+	if isStreamingResponse(request, response) {
+		t.logger.Info("Streaming %s response from local service (Transfer-Encoding: %v)",
+			response.Header.Get("Content-Type"), response.TransferEncoding)
+		if err := writeStreamingResponse(tunnelConn, response, localReader); err != nil {
+			t.logger.Error("Error streaming response to tunnel: %v", err)
+			return
+		}
+		t.logger.Info("Streaming response completed")
+		return
+	}
+
 	// Write response back to tunnel
 	if err := response.Write(tunnelConn); err != nil {
 		t.logger.Error("Error writing response to tunnel: %v", err)
@@ -823,18 +1329,29 @@ func (t *Tunnel) handleRegularResponse(request *http.Request, localConn, tunnelC
 	t.logger.Info("HTTP request/response cycle completed")
 }
 
+// healthPingTimeout is how long a gRPC health ping may take before the tunnel is considered dead
+const healthPingTimeout = 10 * time.Second
+
 // startHealthMonitoring starts the health monitoring goroutine
 func (t *Tunnel) startHealthMonitoring() {
-	t.logger.Info("Starting health monitoring with %v interval", t.retryConfig.HealthCheckInterval)
+	t.logger.Info("Starting health monitoring with %v interval", t.retryConfig.Load().HealthCheckInterval)
 
-	t.healthTicker = time.NewTicker(t.retryConfig.HealthCheckInterval)
+	// Capture the ticker before starting the goroutine: Disconnect may clear t.healthTicker first
+	ticker := time.NewTicker(t.retryConfig.Load().HealthCheckInterval)
+	t.healthTicker = ticker
 	go func() {
-		ticker := t.healthTicker // Capture ticker locally to avoid nil pointer during cleanup
 		defer ticker.Stop()
 		for {
 			select {
 			case <-ticker.C:
-				// CRITICAL: Check both HTTP connection (legacy) and WebSocket connection
+				// Check the gRPC tunnel with a real round-trip (carries all HTTP traffic in hybrid mode)
+				if t.grpcEnabled && t.grpcClient != nil && t.grpcClient.IsConnected() {
+					if err := t.grpcClient.Ping(healthPingTimeout); err != nil {
+						t.logger.Warn("gRPC health ping failed, triggering reconnection: %v", err)
+						t.reconnect()
+						return
+					}
+				}
 
 				// Check HTTP connection (legacy, mostly unused now)
 				if t.conn != nil {
@@ -899,10 +1416,7 @@ func (t *Tunnel) reconnectTCPTunnelOnly() {
 
 	// Start WebSocket reconnection loop in background (non-blocking)
 	if t.grpcEnabled && t.grpcClient != nil && t.grpcClient.IsConnected() {
-		// Get server address from gRPC client
-		serverAddr := t.grpcClient.GetServerAddr()
-		// Convert gRPC port (4444) back to TCP port (4443)
-		serverAddr = strings.Replace(serverAddr, ":4444", ":4443", 1)
+		serverAddr := t.tcpServerAddr
 
 		// TLS config will be recreated in startWebSocketReconnectLoop if needed
 		go t.startWebSocketReconnectLoop(serverAddr, nil)
@@ -915,6 +1429,11 @@ func (t *Tunnel) reconnectTCPTunnelOnly() {
 
 // coordinatedReconnectWithContext handles reconnection with context about whether it's intentional
 func (t *Tunnel) coordinatedReconnectWithContext(isIntentional bool) {
+	if t.once {
+		t.markLost(errors.New("tunnel connection lost"))
+		return
+	}
+
 	// Use mutex to prevent multiple reconnection attempts
 	t.reconnectMutex.Lock()
 	defer t.reconnectMutex.Unlock()
@@ -992,8 +1511,7 @@ func (t *Tunnel) coordinatedReconnectWithContext(isIntentional bool) {
 		if err != nil {
 			cfg = &DefaultConfig // fallback to default
 		}
-		serverAddr := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port)
-		t.connectWithRetry(serverAddr, nil)
+		t.connectWithRetry(cfg.Server.TCPAddr(), nil)
 	}()
 }
 
@@ -1129,9 +1647,10 @@ func (t *Tunnel) GetStats() map[string]interface{} {
 		"retry_count":        t.retryCount,
 		"domain":             t.domain,
 		"local_port":         t.localPort,
+		"local_failovers":    atomic.LoadInt64(&t.localFailovers),
 		"last_ping":          t.lastPing,
-		"media_optimization": t.streamConfig.EnableMediaOptimization,
-		"media_buffer_size":  t.streamConfig.MediaBufferSize,
+		"media_optimization": t.streamConfig.Load().EnableMediaOptimization,
+		"media_buffer_size":  t.streamConfig.Load().MediaBufferSize,
 		"grpc_enabled":       t.grpcEnabled,
 		"tunnel_mode":        "hybrid", // New production-grade hybrid mode
 	}
@@ -1139,6 +1658,18 @@ func (t *Tunnel) GetStats() map[string]interface{} {
 	if t.lastError != nil {
 		stats["last_error"] = t.lastError.Error()
 	}
+	if t.retryConfig.Load().LocalHealthInterval > 0 {
+		stats["local_service"] = localServiceStateNames[atomic.LoadInt32(&t.localServiceState)]
+	}
+
+	for _, expiry := range t.certExpiries {
+		switch expiry.Name {
+		case "client":
+			stats["client_cert_expires"] = expiry.NotAfter
+		case "CA":
+			stats["ca_cert_expires"] = expiry.NotAfter
+		}
+	}
 
 	// Add gRPC client metrics if available
 	if t.grpcEnabled && t.grpcClient != nil {
@@ -1151,6 +1682,11 @@ func (t *Tunnel) GetStats() map[string]interface{} {
 		stats["grpc_timeout_errors"] = grpcMetrics["timeout_errors"]
 		stats["grpc_reconnects"] = grpcMetrics["reconnect_count"]
 		stats["grpc_timeout_reconnects"] = grpcMetrics["timeout_reconnects"]
+		stats["bytes_in"] = grpcMetrics["bytes_in"]
+		stats["bytes_out"] = grpcMetrics["bytes_out"]
+		if n, ok := grpcMetrics["local_failovers"].(int64); ok {
+			stats["local_failovers"] = atomic.LoadInt64(&t.localFailovers) + n
+		}
 	}
 
 	return stats
@@ -1158,41 +1694,62 @@ func (t *Tunnel) GetStats() map[string]interface{} {
 
 // UpdateStreamingConfig updates the streaming configuration
 func (t *Tunnel) UpdateStreamingConfig(config *StreamingConfig) {
-	t.streamConfig = config
-	t.logger.Info("Updated tunnel streaming configuration: MediaOptimization=%v, MediaBufferSize=%d",
-		config.EnableMediaOptimization, config.MediaBufferSize)
+	t.streamConfig.Store(config)
+	t.uploadLimiter.SetRate(config.MaxBytesPerSec)
+	t.downloadLimiter.SetRate(config.MaxBytesPerSec)
+	if t.grpcClient != nil {
+		t.grpcClient.SetReissueOnStreamFailure(config.ReissueOnStreamFailure)
+		t.grpcClient.SetChunkWindowSize(config.ChunkWindowSize)
+	}
+	t.logger.Info("Updated tunnel streaming configuration: MediaOptimization=%v, MediaBufferSize=%d, MaxBytesPerSec=%d",
+		config.EnableMediaOptimization, config.MediaBufferSize, config.MaxBytesPerSec)
+}
+
+// ApplyReloadableConfig applies the hot-reloadable parts of cfg to a running tunnel.
+// Only streaming and retry settings are hot-reloadable; token, domain, local host/port,
+// server and security settings require a reconnect ('giraffecloud service restart').
+// Returns the names of the sections that were applied.
+func (t *Tunnel) ApplyReloadableConfig(cfg *Config) []string {
+	var applied []string
+	if cfg.Streaming != nil {
+		t.UpdateStreamingConfig(cfg.Streaming)
+		applied = append(applied, "streaming")
+	}
+	if cfg.Retry != nil {
+		if err := cfg.Retry.Validate(); err != nil {
+			t.logger.Warn("Ignoring invalid retry configuration: %v", err)
+			return applied
+		}
+		t.SetRetryConfig(cfg.Retry)
+		t.logger.Info("Updated tunnel retry configuration: MaxRetries=%d, MaxDelay=%v",
+			cfg.Retry.MaxRetries, cfg.Retry.MaxDelay)
+		t.startLocalHealthPolling()
+		applied = append(applied, "retry")
+	}
+	return applied
 }
 
 // GetStreamingConfig returns the current streaming configuration
 func (t *Tunnel) GetStreamingConfig() *StreamingConfig {
-	return t.streamConfig
+	return t.streamConfig.Load()
 }
 
-// calculateNextDelay implements exponential backoff with jitter
+// calculateNextDelay applies the configured backoff strategy (exponential by default) with jitter
 func (t *Tunnel) calculateNextDelay(currentDelay time.Duration) time.Duration {
-	// Exponential backoff
-	nextDelay := time.Duration(float64(currentDelay) * t.retryConfig.BackoffFactor)
-
-	// Cap at max delay
-	if nextDelay > t.retryConfig.MaxDelay {
-		nextDelay = t.retryConfig.MaxDelay
-	}
-
-	// Add jitter to prevent thundering herd
-	if t.retryConfig.JitterEnabled {
-		jitter := time.Duration(float64(nextDelay) * 0.1 * (2*rand.Float64() - 1))
-		nextDelay += jitter
-	}
-
-	return nextDelay
+	retry := t.retryConfig.Load()
+	return nextBackoffDelay(retry.BackoffStrategy, currentDelay, retry.InitialDelay,
+		retry.MaxDelay, retry.BackoffFactor, retry.JitterEnabled)
 }
 
-// isMaintenanceError checks if the error indicates server maintenance
-// isAuthenticationError checks if an error is authentication-related and should not be retried
+// isAuthenticationError checks if an error is authentication-related and should not be retried:
+// a handshake refused with a permanent code, or a known message from servers that send no code
 func isAuthenticationError(err error) bool {
 	if err == nil {
 		return false
 	}
+	if code, ok := handshakeErrorCode(err); ok {
+		return code.Permanent()
+	}
 
 	errStr := strings.ToLower(err.Error())
 	authErrorKeywords := []string{
@@ -1204,6 +1761,7 @@ func isAuthenticationError(err error) bool {
 		"unauthenticated",
 		"invalid token",
 		"no tunnel found for domain",
+		"does not match the pinned fingerprint",
 	}
 
 	for _, keyword := range authErrorKeywords {
@@ -1214,7 +1772,13 @@ func isAuthenticationError(err error) bool {
 	return false
 }
 
+// isMaintenanceError checks if the error indicates server maintenance: a MAINTENANCE
+// handshake refusal, or a maintenance message from servers that send no code
 func isMaintenanceError(err error) bool {
+	if code, ok := handshakeErrorCode(err); ok {
+		return code == HandshakeMaintenance
+	}
+
 	// Check for specific maintenance-related error messages
 	errStr := err.Error()
 	maintenanceKeywords := []string{
@@ -1309,11 +1873,13 @@ func (t *Tunnel) PreserveState() (interface{}, error) {
 		Token:        t.token,
 		Domain:       t.domain,
 		LocalPort:    t.localPort,
+		LocalHost:    t.localHost,
+		TunnelID:     t.tunnelID,
 		State:        t.state,
 		Timestamp:    time.Now(),
 		GRPCEnabled:  t.grpcEnabled,
-		RetryConfig:  t.retryConfig,
-		StreamConfig: t.streamConfig,
+		RetryConfig:  t.retryConfig.Load(),
+		StreamConfig: t.streamConfig.Load(),
 	}
 
 	t.logger.Info("Preserved tunnel state for domain: %s, local port: %d", state.Domain, state.LocalPort)
@@ -1344,9 +1910,11 @@ func (t *Tunnel) RestoreState(stateInterface interface{}) error {
 	t.token = state.Token
 	t.domain = state.Domain
 	t.localPort = state.LocalPort
+	t.localHost = state.LocalHost
+	t.tunnelID = state.TunnelID
 	t.grpcEnabled = state.GRPCEnabled
-	t.retryConfig = state.RetryConfig
-	t.streamConfig = state.StreamConfig
+	t.retryConfig.Store(state.RetryConfig)
+	t.streamConfig.Store(state.StreamConfig)
 
 	// Load current config to get server details and TLS config
 	cfg, err := LoadConfig()
@@ -1358,9 +1926,7 @@ func (t *Tunnel) RestoreState(stateInterface interface{}) error {
 	t.ctx, t.cancel = context.WithCancel(context.Background())
 
 	// Reconstruct TLS config (since it's not serializable)
-	tlsConfig := &tls.Config{
-		InsecureSkipVerify: cfg.Security.InsecureSkipVerify,
-	}
+	tlsConfig := &tls.Config{}
 
 	// Load certificates if available
 	if cfg.Security.CACert != "" {
@@ -1379,7 +1945,7 @@ func (t *Tunnel) RestoreState(stateInterface interface{}) error {
 	}
 
 	// Attempt to reconnect
-	serverAddr := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port)
+	serverAddr := cfg.Server.TCPAddr()
 
 	t.logger.Info("Reconnecting tunnel with preserved state...")
 	err = t.Connect(t.ctx, serverAddr, t.token, t.domain, t.localPort, tlsConfig)
@@ -1391,6 +1957,16 @@ func (t *Tunnel) RestoreState(stateInterface interface{}) error {
 	return nil
 }
 
+// setQuotaExceeded reflects the gRPC client's reconnects being refused over quota, and
+// accepted again, in the tunnel state
+func (t *Tunnel) setQuotaExceeded(exceeded bool) {
+	if exceeded {
+		t.setState(StateQuotaExceeded)
+	} else if t.GetState() == StateQuotaExceeded {
+		t.setState(StateConnected)
+	}
+}
+
 // SaveStateToFile saves tunnel state to a file for persistence across restarts
 func (t *Tunnel) SaveStateToFile() error {
 	state, err := t.PreserveState()
@@ -1470,6 +2046,9 @@ func (t *Tunnel) handleTunnelEstablishRequest(establishReq *proto.TunnelEstablis
 
 	switch establishReq.TunnelType {
 	case proto.TunnelType_TUNNEL_TYPE_TCP:
+		if establishReq.ConnectionType == string(ConnectionTypeHTTPOnDemand) {
+			return t.establishHTTPConnectionOnDemand(establishReq)
+		}
 		return t.establishTCPTunnelOnDemand(establishReq)
 	case proto.TunnelType_TUNNEL_TYPE_GRPC:
 		return fmt.Errorf("gRPC tunnel already established")
@@ -1501,30 +2080,16 @@ func (t *Tunnel) establishTCPTunnelOnDemand(establishReq *proto.TunnelEstablishR
 	t.logger.Info("📊 Current WebSocket tunnel pool: %d/%d", currentSize, maxWebSocketTunnels)
 
 	// Create TLS config for TCP connection
-	cfg, err := LoadConfig()
-	if err != nil {
-		return fmt.Errorf("CONFIGURATION ERROR: Failed to load config: %w", err)
-	}
-
-	// Validate certificates before attempting to use them
-	validation := ValidateCertificateFiles(cfg.Security.CACert, cfg.Security.ClientCert, cfg.Security.ClientKey)
-	if !validation.Valid {
-		t.logger.Error("Certificate validation failed for on-demand TCP tunnel:")
-		t.logger.Error("  Error: %s", validation.ErrorMessage)
-		t.logger.Error("  Action: %s", validation.SuggestedAction)
-		return fmt.Errorf("CERTIFICATE ERROR: %s. %s", validation.ErrorMessage, validation.SuggestedAction)
-	}
-
-	tlsConfig, err := CreateSecureTLSConfig(cfg.Security.CACert, cfg.Security.ClientCert, cfg.Security.ClientKey)
+	tlsConfig, err := loadTCPTunnelTLSConfig()
 	if err != nil {
-		return fmt.Errorf("TLS CONFIGURATION ERROR: %w", err)
+		t.logger.Error("Failed to prepare TLS for on-demand TCP tunnel: %v", err)
+		return err
 	}
 
-	// Determine server address for TCP tunnel (port 4443)
-	serverAddr := strings.Replace(t.grpcClient.serverAddr, ":4444", ":4443", 1)
+	serverAddr := t.tcpServerAddr
 
 	// Establish WebSocket tunnel connection
-	wsConn, err := t.establishConnection(serverAddr, tlsConfig, "websocket")
+	wsConn, err := t.establishConnection(serverAddr, tlsConfig, "websocket", establishReq.RequestId)
 	if err != nil {
 		return fmt.Errorf("failed to establish WebSocket tunnel: %w", err)
 	}
@@ -1547,6 +2112,73 @@ func (t *Tunnel) establishTCPTunnelOnDemand(establishReq *proto.TunnelEstablishR
 	return nil
 }
 
+// establishHTTPConnectionOnDemand dials in a dedicated connection for one HTTP request the
+// server couldn't fit in the pool. It serves that request and closes; it isn't counted
+// against the WebSocket tunnel limit.
+func (t *Tunnel) establishHTTPConnectionOnDemand(establishReq *proto.TunnelEstablishRequest) error {
+	tlsConfig, err := loadTCPTunnelTLSConfig()
+	if err != nil {
+		t.logger.Error("Failed to prepare TLS for on-demand HTTP connection: %v", err)
+		return err
+	}
+
+	conn, err := t.establishConnection(t.tcpServerAddr, tlsConfig, string(ConnectionTypeHTTPOnDemand), establishReq.RequestId)
+	if err != nil {
+		return fmt.Errorf("failed to establish on-demand HTTP connection: %w", err)
+	}
+
+	t.wg.Add(1)
+	go t.serveOnDemandHTTPRequest(conn)
+	return nil
+}
+
+// serveOnDemandHTTPRequest answers the single request sent over an on-demand connection
+func (t *Tunnel) serveOnDemandHTTPRequest(conn net.Conn) {
+	defer t.wg.Done()
+	defer conn.Close()
+
+	// The server writes the request as soon as it has the connection
+	timeout := t.streamConfig.Load().FreshConnectionTimeout
+	if timeout <= 0 {
+		timeout = DefaultFreshConnectionTimeout
+	}
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	request, err := http.ReadRequest(bufio.NewReader(conn))
+	if err != nil {
+		t.logger.Warn("On-demand HTTP connection closed before a request arrived: %v", err)
+		return
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	t.logger.Info("Received on-demand HTTP request: %s %s", request.Method, request.URL.Path)
+	t.paths.applyToRequest(request)
+	t.handleHTTPRequest(request, conn)
+}
+
+// loadTCPTunnelTLSConfig builds the mutual-TLS config for TCP tunnel connections from the config file
+func loadTCPTunnelTLSConfig() (*tls.Config, error) {
+	cfg, err := LoadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("CONFIGURATION ERROR: Failed to load config: %w", err)
+	}
+	return tcpTunnelTLSConfig(cfg)
+}
+
+// tcpTunnelTLSConfig builds the mutual-TLS config for TCP tunnel connections from cfg's certificates
+func tcpTunnelTLSConfig(cfg *Config) (*tls.Config, error) {
+	// Validate certificates before attempting to use them
+	validation := ValidateCertificateFiles(cfg.Security.CACert, cfg.Security.ClientCert, cfg.Security.ClientKey)
+	if !validation.Valid {
+		return nil, fmt.Errorf("CERTIFICATE ERROR: %s. %s", validation.ErrorMessage, validation.SuggestedAction)
+	}
+
+	tlsConfig, err := CreateSecureTLSConfig(cfg.Security.CACert, cfg.Security.ClientCert, cfg.Security.ClientKey)
+	if err != nil {
+		return nil, fmt.Errorf("TLS CONFIGURATION ERROR: %w", err)
+	}
+	return tlsConfig, nil
+}
+
 // notifyTCPTunnelEstablished notifies that TCP tunnel is ready (this would be wired to hybrid router)
 func (t *Tunnel) notifyTCPTunnelEstablished() {
 	// This will be wired to the hybrid router's OnTCPTunnelEstablished method
@@ -0,0 +1,199 @@
+package tunnel
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/osa911/giraffecloud/internal/logging"
+)
+
+func TestTunnel_WebSocketReconnectLoopGivesUp(t *testing.T) {
+	initTestLogger(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	tun := &Tunnel{
+		logger: logging.GetGlobalLogger(),
+		ctx:    ctx,
+	}
+	retry := &RetryConfig{
+		MaxRetries:    3,
+		InitialDelay:  time.Millisecond,
+		MaxDelay:      5 * time.Millisecond,
+		BackoffFactor: 2,
+	}
+	tun.retryConfig.Store(retry)
+
+	// Permanent dial failure stops after MaxRetries attempts
+	dialErr := errors.New("connection refused")
+	attempts := 0
+	done := make(chan error, 1)
+	go func() {
+		done <- tun.runWebSocketReconnectLoop(func() error {
+			attempts++
+			return dialErr
+		})
+	}()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, dialErr) {
+			t.Errorf("Expected last dial error, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Reconnect loop did not terminate")
+	}
+	if attempts != 3 {
+		t.Errorf("Expected 3 attempts, got %d", attempts)
+	}
+
+	// Success after a failure ends the loop early
+	attempts = 0
+	err := tun.runWebSocketReconnectLoop(func() error {
+		attempts++
+		if attempts < 2 {
+			return dialErr
+		}
+		return nil
+	})
+	if err != nil || attempts != 2 {
+		t.Errorf("Expected success on attempt 2, got err=%v attempts=%d", err, attempts)
+	}
+
+	// Unlimited retries fall back to the default bound
+	retry.MaxRetries = -1
+	attempts = 0
+	tun.runWebSocketReconnectLoop(func() error {
+		attempts++
+		return dialErr
+	})
+	if attempts != defaultWSReconnectMaxAttempts {
+		t.Errorf("Expected %d attempts with unlimited retries, got %d", defaultWSReconnectMaxAttempts, attempts)
+	}
+}
+
+func TestTunnel_OnceExitsWhenConnectionDrops(t *testing.T) {
+	if testing.Short() {
+		t.Skip("end-to-end tunnel test")
+	}
+	initTestLogger(t)
+	ts, err := NewTestServer()
+	if err != nil {
+		t.Fatalf("NewTestServer: %v", err)
+	}
+	defer ts.Close()
+	t.Setenv("GIRAFFECLOUD_HOME", ts.ConfigHome)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	tun := NewTunnel()
+	tun.SetOnce(true)
+	tun.SetGRPCPort(ts.GRPCPort())
+	tun.SetLocalHost("127.0.0.1")
+	if err := tun.Connect(ctx, ts.ServerAddr(), ts.Token, ts.Domain, ts.LocalPort(), nil); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	defer tun.Disconnect()
+	if err := ts.WaitForTunnel(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ts.DropConnections(); err != nil {
+		t.Fatalf("DropConnections: %v", err)
+	}
+	select {
+	case err := <-tun.Lost():
+		if err == nil {
+			t.Error("Lost() sent a nil error")
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("tunnel did not report the dropped connection")
+	}
+	if state := tun.GetState(); state != StateFailed {
+		t.Errorf("state = %s, want %s", state, StateFailed)
+	}
+
+	// Nothing reconnects behind our back
+	time.Sleep(500 * time.Millisecond)
+	if ts.grpc.IsTunnelActive(ts.Domain) {
+		t.Error("tunnel reconnected despite SetOnce")
+	}
+}
+
+func TestTunnel_GetDomainFromHandshake(t *testing.T) {
+	if testing.Short() {
+		t.Skip("end-to-end tunnel test")
+	}
+	initTestLogger(t)
+	ts, err := NewTestServer()
+	if err != nil {
+		t.Fatalf("NewTestServer: %v", err)
+	}
+	defer ts.Close()
+	t.Setenv("GIRAFFECLOUD_HOME", ts.ConfigHome)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	tun := NewTunnel()
+	tun.SetGRPCPort(ts.GRPCPort())
+	tun.SetLocalHost("127.0.0.1")
+	if got := tun.GetDomain(); got != "" {
+		t.Errorf("GetDomain() before connecting = %q", got)
+	}
+	// No domain asked for: connect --print-url reports the one the server picked
+	if err := tun.Connect(ctx, ts.ServerAddr(), ts.Token, "", ts.LocalPort(), nil); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	defer tun.Disconnect()
+	if got := tun.GetDomain(); got != ts.Domain {
+		t.Errorf("GetDomain() = %q, want %q", got, ts.Domain)
+	}
+}
+
+func TestTunnel_OnDemandHTTPConnectionServesOneRequest(t *testing.T) {
+	initTestLogger(t)
+	local := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello " + r.URL.Path))
+	}))
+	defer local.Close()
+	port, _ := strconv.Atoi(local.URL[strings.LastIndex(local.URL, ":")+1:])
+
+	t.Setenv("GIRAFFECLOUD_HOME", t.TempDir())
+	tun := NewTunnel()
+	tun.ctx = context.Background()
+	tun.SetLocalHost("127.0.0.1")
+	tun.localPort = port
+
+	serverSide, clientSide := net.Pipe()
+	defer serverSide.Close()
+	tun.wg.Add(1)
+	go tun.serveOnDemandHTTPRequest(clientSide)
+
+	go serverSide.Write([]byte("GET /page HTTP/1.1\r\nHost: app.example.com\r\n\r\n"))
+	reader := bufio.NewReader(serverSide)
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "hello /page" {
+		t.Errorf("body = %q", body)
+	}
+
+	// The connection is closed after the one response, not kept for more requests
+	serverSide.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err := reader.ReadByte(); err != io.EOF {
+		t.Errorf("expected the on-demand connection to close, got %v", err)
+	}
+	tun.wg.Wait()
+}
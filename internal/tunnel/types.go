@@ -10,16 +10,21 @@ import (
 type TunnelHandshakeRequest struct {
 	Token          string `json:"token"`
 	Domain         string `json:"domain,omitempty"`          // For multi-tunnel support
-	ConnectionType string `json:"connection_type,omitempty"` // "http" or "websocket"
+	TunnelID       uint32 `json:"tunnel_id,omitempty"`       // Alternative to Domain for selecting a tunnel
+	ConnectionType string `json:"connection_type,omitempty"` // "http", "websocket", "udp" or "http-on-demand"
+	RequestID      string `json:"request_id,omitempty"`      // Set when answering a server establishment request
+	UDPPort        int    `json:"udp_port,omitempty"`        // Public UDP port wanted by a "udp" connection (0 = any)
 }
 
 // TunnelHandshakeResponse represents the server's response to a handshake
 type TunnelHandshakeResponse struct {
-	Status         string `json:"status"`
-	Message        string `json:"message"`
-	Domain         string `json:"domain,omitempty"`
-	TargetPort     int    `json:"target_port,omitempty"`
-	ConnectionType string `json:"connection_type,omitempty"` // "http" or "websocket"
+	Status         string             `json:"status"`
+	Message        string             `json:"message"`
+	Code           HandshakeErrorCode `json:"code,omitempty"` // Why the handshake was refused (Status "error")
+	Domain         string             `json:"domain,omitempty"`
+	TargetPort     int                `json:"target_port,omitempty"`
+	ConnectionType string             `json:"connection_type,omitempty"` // "http", "websocket", "udp" or "http-on-demand"
+	UDPPort        int                `json:"udp_port,omitempty"`        // Public UDP port serving a "udp" connection
 }
 
 // UsageRecorder is a lightweight interface for recording usage stats.
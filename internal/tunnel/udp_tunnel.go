@@ -0,0 +1,509 @@
+package tunnel
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/osa911/giraffecloud/internal/db/ent"
+)
+
+/*
+UDP tunnels expose a local UDP service (game servers, DNS) on a public UDP port of the
+server. The client dials a dedicated TCP tunnel connection with connection_type "udp";
+the server answers with the public port it listens on and tags every visitor address
+with a session ID. Datagrams travel both ways as frames of
+  session ID (4 bytes) | payload length (2 bytes) | payload
+and the client gives each session its own local UDP socket, so replies find their way
+back to the right visitor.
+*/
+
+// DefaultUDPSessionTimeout is how long a visitor's UDP session lives without traffic
+const DefaultUDPSessionTimeout = 2 * time.Minute
+
+// DefaultUDPMaxSessions caps the open sessions of one UDP tunnel, on the server and on the
+// client (StreamingConfig.UDPMaxSessions, Config.UDPMaxSessions)
+const DefaultUDPMaxSessions = 1024
+
+// udpEvictableIdle is how long a session must have been quiet before a new visitor may
+// take its place once the session cap is reached; until then new visitors are dropped
+const udpEvictableIdle = 10 * time.Second
+
+const (
+	udpFrameHeaderSize = 6
+	maxUDPPayload      = 65535
+)
+
+var errUDPDisabled = errors.New("UDP tunnels are not enabled on this server")
+
+// writeUDPFrame writes one datagram for session to w in a single Write
+func writeUDPFrame(w io.Writer, session uint32, payload []byte) error {
+	if len(payload) > maxUDPPayload {
+		return fmt.Errorf("datagram of %d bytes is too large", len(payload))
+	}
+	frame := make([]byte, udpFrameHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(frame[0:4], session)
+	binary.BigEndian.PutUint16(frame[4:6], uint16(len(payload)))
+	copy(frame[udpFrameHeaderSize:], payload)
+	_, err := w.Write(frame)
+	return err
+}
+
+// readUDPFrame reads one datagram from r into buf, which must hold
+// udpFrameHeaderSize+maxUDPPayload bytes; the payload is only valid until the next call
+func readUDPFrame(r io.Reader, buf []byte) (uint32, []byte, error) {
+	if _, err := io.ReadFull(r, buf[:udpFrameHeaderSize]); err != nil {
+		return 0, nil, err
+	}
+	session := binary.BigEndian.Uint32(buf[0:4])
+	payload := buf[udpFrameHeaderSize : udpFrameHeaderSize+int(binary.BigEndian.Uint16(buf[4:6]))]
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+	return session, payload, nil
+}
+
+// udpTunnel is a public UDP socket served through one client connection
+type udpTunnel struct {
+	conn    net.Conn
+	packets net.PacketConn
+
+	mu          sync.Mutex
+	nextID      uint32
+	sessions    map[string]uint32 // Visitor address → session ID
+	peers       map[uint32]*udpPeer
+	maxSessions int
+}
+
+type udpPeer struct {
+	addr     net.Addr
+	lastSeen time.Time
+}
+
+func (u *udpTunnel) close() {
+	u.packets.Close()
+	u.conn.Close()
+}
+
+// session returns the session ID of a visitor, opening one on its first datagram. With
+// maxSessions open, the least recently active one is closed to make room if it has been
+// idle for udpEvictableIdle; otherwise ok is false and the datagram is dropped.
+func (u *udpTunnel) session(addr net.Addr) (id uint32, ok bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	key := addr.String()
+	id, ok = u.sessions[key]
+	if !ok {
+		if len(u.peers) >= u.maxSessions && !u.evictIdleLocked() {
+			return 0, false
+		}
+		u.nextID++
+		id = u.nextID
+		u.sessions[key] = id
+		u.peers[id] = &udpPeer{addr: addr}
+	}
+	u.peers[id].lastSeen = time.Now()
+	return id, true
+}
+
+// evictIdleLocked closes the least recently active session if it has been idle for
+// udpEvictableIdle. Callers hold u.mu.
+func (u *udpTunnel) evictIdleLocked() bool {
+	var oldestID uint32
+	var oldest *udpPeer
+	for id, p := range u.peers {
+		if oldest == nil || p.lastSeen.Before(oldest.lastSeen) {
+			oldestID, oldest = id, p
+		}
+	}
+	if oldest == nil || time.Since(oldest.lastSeen) < udpEvictableIdle {
+		return false
+	}
+	delete(u.peers, oldestID)
+	delete(u.sessions, oldest.addr.String())
+	return true
+}
+
+// peer returns the visitor address of a session, if it is still open
+func (u *udpTunnel) peer(id uint32) net.Addr {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	p, ok := u.peers[id]
+	if !ok {
+		return nil
+	}
+	p.lastSeen = time.Now()
+	return p.addr
+}
+
+// expire closes sessions idle for longer than timeout
+func (u *udpTunnel) expire(timeout time.Duration) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	for id, p := range u.peers {
+		if time.Since(p.lastSeen) > timeout {
+			delete(u.peers, id)
+			delete(u.sessions, p.addr.String())
+		}
+	}
+}
+
+// listenUDP opens the public UDP port of a tunnel: requested when it is in the configured
+// range and free, so a reconnecting client keeps its port, otherwise the first free one
+func (s *TunnelServer) listenUDP(requested int) (net.PacketConn, error) {
+	min, max := s.streamConfig.UDPPortMin, s.streamConfig.UDPPortMax
+	if min <= 0 || max < min {
+		return nil, errUDPDisabled
+	}
+	if requested >= min && requested <= max {
+		if packets, err := net.ListenPacket("udp", ":"+strconv.Itoa(requested)); err == nil {
+			return packets, nil
+		}
+	}
+	for port := min; port <= max; port++ {
+		if packets, err := net.ListenPacket("udp", ":"+strconv.Itoa(port)); err == nil {
+			return packets, nil
+		}
+	}
+	return nil, fmt.Errorf("no free UDP port in %d-%d", min, max)
+}
+
+// serveUDPTunnel answers a "udp" handshake and relays datagrams until the client goes away
+func (s *TunnelServer) serveUDPTunnel(conn net.Conn, encoder *json.Encoder, tunnel *ent.Tunnel, requestedPort int) {
+	fail := func(code HandshakeErrorCode, message string) {
+		encoder.Encode(TunnelHandshakeResponse{Status: "error", Message: message, Code: code})
+	}
+	if s.quotaChecker != nil {
+		if res, _ := s.quotaChecker.CheckUser(context.Background(), tunnel.UserID); res.Decision == QuotaBlock {
+			fail(HandshakeQuotaExceeded, ErrQuotaExceeded.Error())
+			return
+		}
+	}
+
+	// A reconnecting client replaces its previous UDP tunnel and takes over its port
+	s.udpMu.Lock()
+	if old := s.udpTunnels[tunnel.Domain]; old != nil {
+		old.close()
+	}
+	packets, err := s.listenUDP(requestedPort)
+	if err != nil {
+		s.udpMu.Unlock()
+		s.logger.Error("Failed to open UDP tunnel for domain %s: %v", tunnel.Domain, err)
+		code := HandshakeInternal
+		if errors.Is(err, errUDPDisabled) {
+			code = HandshakeInvalidRequest
+		}
+		fail(code, err.Error())
+		return
+	}
+	ut := &udpTunnel{
+		conn:        conn,
+		packets:     packets,
+		sessions:    make(map[string]uint32),
+		peers:       make(map[uint32]*udpPeer),
+		maxSessions: udpSessionLimit(s.streamConfig.UDPMaxSessions),
+	}
+	if s.udpTunnels == nil {
+		s.udpTunnels = make(map[string]*udpTunnel)
+	}
+	s.udpTunnels[tunnel.Domain] = ut
+	s.udpMu.Unlock()
+
+	defer func() {
+		s.udpMu.Lock()
+		if s.udpTunnels[tunnel.Domain] == ut {
+			delete(s.udpTunnels, tunnel.Domain)
+		}
+		s.udpMu.Unlock()
+		ut.close()
+	}()
+
+	port := packets.LocalAddr().(*net.UDPAddr).Port
+	if err := encoder.Encode(TunnelHandshakeResponse{
+		Status:         "success",
+		Message:        "UDP tunnel ready",
+		Domain:         tunnel.Domain,
+		TargetPort:     tunnel.TargetPort,
+		ConnectionType: string(ConnectionTypeUDP),
+		UDPPort:        port,
+	}); err != nil {
+		s.logger.Error("Failed to send response: %v", err)
+		return
+	}
+	s.logger.Info("UDP tunnel for domain %s listening on port %d", tunnel.Domain, port)
+
+	record := func(bytesIn, bytesOut int64) {
+		if s.usageRecorder != nil {
+			s.usageRecorder.Increment(tunnel.UserID, uint32(tunnel.ID), tunnel.Domain, bytesIn, bytesOut, 0)
+		}
+	}
+
+	// Visitors → client
+	go func() {
+		defer ut.close()
+		buf := make([]byte, maxUDPPayload)
+		for {
+			n, addr, err := packets.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			id, ok := ut.session(addr)
+			if !ok {
+				continue // Session cap reached and every session is active
+			}
+			if err := writeUDPFrame(conn, id, buf[:n]); err != nil {
+				return
+			}
+			record(int64(n), 0)
+		}
+	}()
+
+	// Idle sessions
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(DefaultUDPSessionTimeout / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				ut.expire(DefaultUDPSessionTimeout)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	// Client → visitors
+	reader := bufio.NewReader(conn)
+	buf := make([]byte, udpFrameHeaderSize+maxUDPPayload)
+	for {
+		id, payload, err := readUDPFrame(reader, buf)
+		if err != nil {
+			s.logger.Info("UDP tunnel for domain %s closed: %v", tunnel.Domain, err)
+			return
+		}
+		addr := ut.peer(id)
+		if addr == nil {
+			continue // Session expired; the reply has nowhere to go
+		}
+		if _, err := packets.WriteTo(payload, addr); err == nil {
+			record(0, int64(len(payload)))
+		}
+	}
+}
+
+// udpSessionLimit returns a configured session cap, or DefaultUDPMaxSessions if unset
+func udpSessionLimit(configured int) int {
+	if configured <= 0 {
+		return DefaultUDPMaxSessions
+	}
+	return configured
+}
+
+// SetUDPForward forwards datagrams sent to the tunnel's public UDP port to localPort on
+// the local host (0 = no UDP forwarding). publicPort asks the server for a specific port
+// of its UDP range (0 = any); either way the port is kept across reconnects when free.
+// maxSessions caps the local sockets opened for visitors (0 = DefaultUDPMaxSessions).
+func (t *Tunnel) SetUDPForward(localPort, publicPort, maxSessions int) {
+	t.udpLocalPort = localPort
+	t.udpPublicPort = publicPort
+	t.udpMaxSessions = maxSessions
+}
+
+// startUDPForwarding keeps a UDP tunnel connection up for as long as the tunnel runs
+func (t *Tunnel) startUDPForwarding(serverAddr string, tlsConfig *tls.Config) {
+	if t.udpLocalPort <= 0 || !atomic.CompareAndSwapInt32(&t.udpStarted, 0, 1) {
+		return
+	}
+	ctx := t.ctx
+	go func() {
+		defer atomic.StoreInt32(&t.udpStarted, 0)
+		delay := t.retryConfig.Load().InitialDelay
+		for {
+			established, err := t.serveUDPForwarding(ctx, serverAddr, tlsConfig)
+			if ctx.Err() != nil {
+				return
+			}
+			if t.once {
+				t.markLost(fmt.Errorf("UDP tunnel lost: %w", err))
+				return
+			}
+			if established {
+				delay = t.retryConfig.Load().InitialDelay
+			}
+			t.logger.Warn("UDP tunnel lost: %v (reconnecting in %v)", err, delay)
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return
+			}
+			delay = t.calculateNextDelay(delay)
+		}
+	}()
+}
+
+// serveUDPForwarding dials a UDP tunnel connection and relays its datagrams to the local
+// service until the connection or ctx ends. established reports whether the server
+// accepted it.
+func (t *Tunnel) serveUDPForwarding(ctx context.Context, serverAddr string, tlsConfig *tls.Config) (established bool, err error) {
+	reloaded, rotated := t.certificateReloads()
+	if reloaded != nil {
+		tlsConfig = reloaded
+	}
+	if tlsConfig == nil {
+		if tlsConfig, err = loadTCPTunnelTLSConfig(); err != nil {
+			return false, err
+		}
+	}
+	conn, err := t.establishConnection(serverAddr, tlsConfig, string(ConnectionTypeUDP), "")
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	// Redial with the new certificates once they are reloaded
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-rotated:
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	localAddr := localServiceAddr(t.localHost, t.udpLocalPort)
+	t.logger.Info("UDP forwarding: %s:%d -> %s", t.domain, t.udpPublicPort, localAddr)
+	return true, t.relayUDP(conn, localAddr)
+}
+
+// relayUDP forwards the datagrams framed on conn to localAddr, one local socket per
+// visitor session, and frames the replies back until conn fails. Past the session cap a
+// new session takes the place of the least recently active one if it is idle (see
+// udpEvictableIdle), and is dropped otherwise.
+func (t *Tunnel) relayUDP(conn net.Conn, localAddr string) error {
+	maxSessions := udpSessionLimit(t.udpMaxSessions)
+	var writeMu sync.Mutex
+	var sessionsMu sync.Mutex
+	sessions := make(map[uint32]*udpLocalSession)
+	defer func() {
+		sessionsMu.Lock()
+		for _, session := range sessions {
+			session.conn.Close()
+		}
+		sessionsMu.Unlock()
+	}()
+
+	reader := bufio.NewReader(conn)
+	buf := make([]byte, udpFrameHeaderSize+maxUDPPayload)
+	for {
+		id, payload, err := readUDPFrame(reader, buf)
+		if err != nil {
+			return err
+		}
+
+		sessionsMu.Lock()
+		session := sessions[id]
+		if session == nil && len(sessions) >= maxSessions && !evictIdleUDPSession(sessions) {
+			sessionsMu.Unlock()
+			t.logger.Debug("UDP forwarding: %d sessions open, dropping datagram of new session %d", maxSessions, id)
+			continue
+		}
+		if session == nil {
+			local, dialErr := net.Dial("udp", localAddr)
+			if dialErr != nil {
+				sessionsMu.Unlock()
+				t.logger.Warn("UDP forwarding: failed to reach %s: %v", localAddr, dialErr)
+				continue
+			}
+			session = &udpLocalSession{conn: local}
+			sessions[id] = session
+			go func() {
+				defer func() {
+					sessionsMu.Lock()
+					if sessions[id] == session {
+						delete(sessions, id)
+					}
+					sessionsMu.Unlock()
+					local.Close()
+				}()
+				session.relayReplies(conn, &writeMu, id, DefaultUDPSessionTimeout)
+			}()
+		}
+		sessionsMu.Unlock()
+
+		session.touch()
+		session.conn.Write(payload)
+	}
+}
+
+// udpLocalSession is the local UDP socket of one visitor session
+type udpLocalSession struct {
+	conn       net.Conn
+	lastActive atomic.Int64 // Unix nanoseconds
+}
+
+func (s *udpLocalSession) touch() {
+	s.lastActive.Store(time.Now().UnixNano())
+}
+
+// evictIdleUDPSession closes the least recently active session if it has been idle for
+// udpEvictableIdle. Callers hold the lock guarding sessions.
+func evictIdleUDPSession(sessions map[uint32]*udpLocalSession) bool {
+	var oldestID uint32
+	var oldest *udpLocalSession
+	for id, session := range sessions {
+		if oldest == nil || session.lastActive.Load() < oldest.lastActive.Load() {
+			oldestID, oldest = id, session
+		}
+	}
+	if oldest == nil || time.Since(time.Unix(0, oldest.lastActive.Load())) < udpEvictableIdle {
+		return false
+	}
+	delete(sessions, oldestID)
+	oldest.conn.Close() // Ends its relayReplies
+	return true
+}
+
+// relayReplies frames the local service's replies back through the tunnel until the
+// session has been idle for timeout or a socket fails
+func (s *udpLocalSession) relayReplies(tunnel net.Conn, writeMu *sync.Mutex, id uint32, timeout time.Duration) {
+	buf := make([]byte, maxUDPPayload)
+	for {
+		s.conn.SetReadDeadline(time.Now().Add(timeout))
+		n, err := s.conn.Read(buf)
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				if time.Since(time.Unix(0, s.lastActive.Load())) < timeout {
+					continue
+				}
+				return
+			}
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue // e.g. ICMP port unreachable while the local service restarts
+		}
+		s.touch()
+		writeMu.Lock()
+		err = writeUDPFrame(tunnel, id, buf[:n])
+		writeMu.Unlock()
+		if err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package tunnel
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/osa911/giraffecloud/internal/db/ent"
+	"github.com/osa911/giraffecloud/internal/logging"
+)
+
+func TestUDPFrameRoundTrip(t *testing.T) {
+	var wire bytes.Buffer
+	if err := writeUDPFrame(&wire, 7, []byte("query")); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeUDPFrame(&wire, 8, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeUDPFrame(&wire, 9, make([]byte, maxUDPPayload+1)); err == nil {
+		t.Error("expected an error for an oversized datagram")
+	}
+
+	buf := make([]byte, udpFrameHeaderSize+maxUDPPayload)
+	if id, payload, err := readUDPFrame(&wire, buf); err != nil || id != 7 || string(payload) != "query" {
+		t.Errorf("first frame = %d %q, %v", id, payload, err)
+	}
+	if id, payload, err := readUDPFrame(&wire, buf); err != nil || id != 8 || len(payload) != 0 {
+		t.Errorf("second frame = %d %q, %v", id, payload, err)
+	}
+}
+
+func TestUDPTunnel(t *testing.T) {
+	initTestLogger(t)
+
+	// Local UDP service that echoes in upper case
+	local, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer local.Close()
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			n, addr, err := local.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			local.WriteTo(bytes.ToUpper(buf[:n]), addr)
+		}
+	}()
+
+	// A free port for the server's UDP range
+	probe, err := net.ListenPacket("udp", ":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := probe.LocalAddr().(*net.UDPAddr).Port
+	probe.Close()
+
+	config := DefaultStreamingConfig()
+	config.UDPPortMin, config.UDPPortMax = port, port
+	s := &TunnelServer{logger: logging.GetGlobalLogger(), streamConfig: config}
+	serverConn, clientConn := net.Pipe()
+	defer clientConn.Close()
+	go s.serveUDPTunnel(serverConn, json.NewEncoder(serverConn), &ent.Tunnel{ID: 1, Domain: "game.example.com", UserID: 1}, 0)
+
+	var resp TunnelHandshakeResponse
+	if err := json.NewDecoder(clientConn).Decode(&resp); err != nil || resp.Status != "success" || resp.UDPPort != port {
+		t.Fatalf("handshake response = %+v, %v; want success on port %d", resp, err, port)
+	}
+
+	tun := &Tunnel{logger: logging.GetGlobalLogger()}
+	go tun.relayUDP(clientConn, local.LocalAddr().String())
+
+	// Two visitors each get their own replies
+	for _, message := range []string{"ping", "pong"} {
+		visitor, err := net.Dial("udp", net.JoinHostPort("127.0.0.1", strconv.Itoa(resp.UDPPort)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer visitor.Close()
+		visitor.SetDeadline(time.Now().Add(2 * time.Second))
+		if _, err := visitor.Write([]byte(message)); err != nil {
+			t.Fatal(err)
+		}
+		buf := make([]byte, 1500)
+		n, err := visitor.Read(buf)
+		if err != nil {
+			t.Fatalf("no reply to %q: %v", message, err)
+		}
+		if want := string(bytes.ToUpper([]byte(message))); string(buf[:n]) != want {
+			t.Errorf("reply = %q, want %q", buf[:n], want)
+		}
+	}
+
+	// UDP tunnels are refused when the server has no port range
+	s.streamConfig = DefaultStreamingConfig()
+	serverConn, clientConn = net.Pipe()
+	defer clientConn.Close()
+	go s.serveUDPTunnel(serverConn, json.NewEncoder(serverConn), &ent.Tunnel{ID: 2, Domain: "dns.example.com", UserID: 1}, 0)
+	if err := json.NewDecoder(clientConn).Decode(&resp); err != nil || resp.Status != "error" {
+		t.Errorf("handshake response without a port range = %+v, %v; want an error", resp, err)
+	}
+}
+
+func TestUDPTunnelSessionCap(t *testing.T) {
+	u := &udpTunnel{sessions: make(map[string]uint32), peers: make(map[uint32]*udpPeer), maxSessions: 2}
+	visitor := func(port int) net.Addr { return &net.UDPAddr{IP: net.IPv4(203, 0, 113, 7), Port: port} }
+
+	first, _ := u.session(visitor(1))
+	u.session(visitor(2))
+	if _, ok := u.session(visitor(3)); ok {
+		t.Fatal("session opened past the cap while every session is active")
+	}
+	if id, ok := u.session(visitor(1)); !ok || id != first {
+		t.Errorf("existing visitor = %d, %v; want its session %d", id, ok, first)
+	}
+
+	// The least recently active session makes room once it has been idle long enough
+	u.peers[first].lastSeen = time.Now().Add(-udpEvictableIdle)
+	if _, ok := u.session(visitor(3)); !ok {
+		t.Fatal("idle session was not replaced")
+	}
+	if u.peer(first) != nil || len(u.peers) != 2 {
+		t.Errorf("after eviction: %d sessions, evicted session still open: %v", len(u.peers), u.peer(first) != nil)
+	}
+}
+
+func TestRelayUDPSessionCap(t *testing.T) {
+	initTestLogger(t)
+
+	local, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer local.Close()
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			n, addr, err := local.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			local.WriteTo(buf[:n], addr)
+		}
+	}()
+
+	serverConn, clientConn := net.Pipe()
+	defer serverConn.Close()
+	tun := &Tunnel{logger: logging.GetGlobalLogger(), udpMaxSessions: 1}
+	go tun.relayUDP(clientConn, local.LocalAddr().String())
+
+	frames := make(chan uint32, 4)
+	go func() {
+		buf := make([]byte, udpFrameHeaderSize+maxUDPPayload)
+		for {
+			id, _, err := readUDPFrame(serverConn, buf)
+			if err != nil {
+				return
+			}
+			frames <- id
+		}
+	}()
+
+	// Session 2 is dropped while session 1 is active, so only session 1 gets replies
+	for _, id := range []uint32{1, 2, 1} {
+		if err := writeUDPFrame(serverConn, id, []byte("ping")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case id := <-frames:
+			if id != 1 {
+				t.Fatalf("reply for session %d past the cap", id)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("no reply for session 1")
+		}
+	}
+	select {
+	case id := <-frames:
+		t.Errorf("unexpected reply for session %d", id)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,87 @@
+package tunnel
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/osa911/giraffecloud/internal/logging"
+	"github.com/osa911/giraffecloud/internal/tunnel/proto"
+)
+
+func TestUploadLimitPayloadTooLarge(t *testing.T) {
+	initTestLogger(t)
+	const domain = "app.example.com"
+	const limit = 1024
+
+	tests := []struct {
+		name      string
+		head      string
+		bodySize  int
+		chunked   bool // Route through routeToGRPCChunkedStreaming instead of routeToGRPCTunnel
+		wantAbort bool // Limit is hit mid-stream, so the client must be told to drop the upload
+	}{
+		{"declared length over limit", "POST /upload HTTP/1.1\r\nHost: " + domain + "\r\nContent-Length: 2048\r\n\r\n", 2048, false, false},
+		{"declared length over limit, chunked route", "PUT /upload HTTP/1.1\r\nHost: " + domain + "\r\nContent-Length: 2048\r\n\r\n", 2048, true, false},
+		{"streamed body over limit", "POST /upload HTTP/1.1\r\nHost: " + domain + "\r\nTransfer-Encoding: chunked\r\n\r\n", 3000, false, true},
+		{"streamed body over limit, chunked route", "PATCH /upload HTTP/1.1\r\nHost: " + domain + "\r\nTransfer-Encoding: chunked\r\n\r\n", 3000, true, true},
+		{"declared length understated", "POST /upload HTTP/1.1\r\nHost: " + domain + "\r\nContent-Length: 10\r\n\r\n", 3000, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream := &fakeServerStream{sent: make(chan *proto.TunnelMessage, 8)}
+			grpcTunnel := &GRPCTunnelServer{
+				logger:         logging.GetGlobalLogger(),
+				config:         DefaultGRPCTunnelConfig(),
+				maxUploadBytes: limit,
+				tunnelStreams: map[string]*TunnelStream{domain: {
+					Domain:          domain,
+					Stream:          stream,
+					Context:         context.Background(),
+					connected:       true,
+					pendingRequests: make(map[string]chan *proto.TunnelMessage),
+				}},
+			}
+			r := &HybridTunnelRouter{config: &HybridRouterConfig{}, logger: logging.GetGlobalLogger(), grpcTunnel: grpcTunnel}
+
+			server, client := net.Pipe()
+			defer client.Close()
+			go func() {
+				defer server.Close()
+				body := strings.NewReader(strings.Repeat("x", tt.bodySize))
+				method := strings.Fields(tt.head)[0]
+				if tt.chunked {
+					r.routeToGRPCChunkedStreaming(domain, server, []byte(tt.head), body, "203.0.113.7", method, "/upload")
+				} else {
+					r.routeToGRPCTunnel(domain, server, []byte(tt.head), body, "203.0.113.7", method, "/upload")
+				}
+			}()
+
+			client.SetDeadline(time.Now().Add(5 * time.Second))
+			resp, err := io.ReadAll(client)
+			if err != nil {
+				t.Fatalf("read response: %v", err)
+			}
+			if !strings.HasPrefix(string(resp), "HTTP/1.1 413 ") {
+				t.Fatalf("response = %q, want 413", resp)
+			}
+
+			var abort *proto.ErrorMessage
+			for len(stream.sent) > 0 {
+				if e := (<-stream.sent).GetError(); e != nil {
+					abort = e
+				}
+			}
+			if tt.wantAbort && (abort == nil || abort.Code != http.StatusRequestEntityTooLarge) {
+				t.Errorf("client abort = %v, want error with code 413", abort)
+			}
+			if !tt.wantAbort && abort != nil {
+				t.Errorf("unexpected client abort %v for an upload rejected before it started", abort)
+			}
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package tunnel
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// UsageFileName is the default usage file in the config directory
+const UsageFileName = "usage.json"
+
+// DefaultUsageFlushInterval is how often a FileUsageRecorder writes its totals
+const DefaultUsageFlushInterval = 30 * time.Second
+
+// DomainUsage holds the accumulated traffic for one domain
+type DomainUsage struct {
+	BytesIn  int64 `json:"bytes_in"`  // Request bytes from visitors
+	BytesOut int64 `json:"bytes_out"` // Response bytes to visitors
+	Requests int64 `json:"requests"`
+}
+
+// UsageFile is the on-disk format written by FileUsageRecorder
+type UsageFile struct {
+	Since     time.Time               `json:"since"`
+	UpdatedAt time.Time               `json:"updated_at"`
+	Domains   map[string]*DomainUsage `json:"domains"`
+}
+
+// FileUsageRecorder is a UsageRecorder that accumulates traffic per domain and flushes
+// the totals to a JSON file periodically. Totals already in the file are carried over.
+type FileUsageRecorder struct {
+	path          string
+	flushInterval time.Duration
+
+	mu      sync.Mutex
+	usage   UsageFile
+	dirty   bool
+	writeMu sync.Mutex // Serializes file writes
+
+	stopOnce sync.Once
+	stopChan chan struct{}
+}
+
+// DefaultUsageFilePath returns the usage file in the config directory
+func DefaultUsageFilePath() (string, error) {
+	dir, err := GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, UsageFileName), nil
+}
+
+// ReadUsageFile reads the totals written by a FileUsageRecorder
+func ReadUsageFile(path string) (*UsageFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var usage UsageFile
+	if err := json.Unmarshal(data, &usage); err != nil {
+		return nil, fmt.Errorf("invalid usage file %s: %w", path, err)
+	}
+	if usage.Domains == nil {
+		usage.Domains = make(map[string]*DomainUsage)
+	}
+	return &usage, nil
+}
+
+// NewFileUsageRecorder creates a recorder writing to path, continuing from the totals
+// already in it. A flushInterval of 0 uses DefaultUsageFlushInterval.
+func NewFileUsageRecorder(path string, flushInterval time.Duration) (*FileUsageRecorder, error) {
+	if flushInterval <= 0 {
+		flushInterval = DefaultUsageFlushInterval
+	}
+
+	usage, err := ReadUsageFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		usage = &UsageFile{Since: time.Now(), Domains: make(map[string]*DomainUsage)}
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create usage directory: %w", err)
+	}
+
+	return &FileUsageRecorder{
+		path:          path,
+		flushInterval: flushInterval,
+		usage:         *usage,
+		stopChan:      make(chan struct{}),
+	}, nil
+}
+
+// Increment implements UsageRecorder
+func (r *FileUsageRecorder) Increment(userID uint32, tunnelID uint32, domain string, bytesIn int64, bytesOut int64, requests int64) {
+	if domain == "" {
+		domain = "unknown"
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	d := r.usage.Domains[domain]
+	if d == nil {
+		d = &DomainUsage{}
+		r.usage.Domains[domain] = d
+	}
+	d.BytesIn += bytesIn
+	d.BytesOut += bytesOut
+	d.Requests += requests
+	r.dirty = true
+}
+
+// Start flushes the totals every flush interval until ctx ends or Close is called
+func (r *FileUsageRecorder) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(r.flushInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				r.Flush()
+				return
+			case <-r.stopChan:
+				return
+			case <-ticker.C:
+				r.Flush()
+			}
+		}
+	}()
+}
+
+// Flush writes the totals to the file if anything changed since the last flush
+func (r *FileUsageRecorder) Flush() error {
+	r.writeMu.Lock()
+	defer r.writeMu.Unlock()
+
+	r.mu.Lock()
+	if !r.dirty {
+		r.mu.Unlock()
+		return nil
+	}
+	r.usage.UpdatedAt = time.Now()
+	data, err := json.MarshalIndent(r.usage, "", "  ")
+	r.dirty = false
+	r.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	// Write to a temp file first so readers never see a partial file
+	tmp := r.path + ".tmp"
+	err = os.WriteFile(tmp, data, 0644)
+	if err == nil {
+		err = os.Rename(tmp, r.path)
+	}
+	if err != nil {
+		r.mu.Lock()
+		r.dirty = true // Retry on the next flush
+		r.mu.Unlock()
+		return fmt.Errorf("failed to write usage file: %w", err)
+	}
+	return nil
+}
+
+// Close stops periodic flushing (if started) and writes the final totals
+func (r *FileUsageRecorder) Close() error {
+	r.stopOnce.Do(func() { close(r.stopChan) })
+	return r.Flush()
+}
+
+// multiUsageRecorder forwards usage to several recorders
+type multiUsageRecorder []UsageRecorder
+
+// MultiUsageRecorder returns a UsageRecorder that records to all of recs, skipping nils
+func MultiUsageRecorder(recs ...UsageRecorder) UsageRecorder {
+	var multi multiUsageRecorder
+	for _, rec := range recs {
+		if rec != nil {
+			multi = append(multi, rec)
+		}
+	}
+	return multi
+}
+
+func (m multiUsageRecorder) Increment(userID uint32, tunnelID uint32, domain string, bytesIn int64, bytesOut int64, requests int64) {
+	for _, rec := range m {
+		rec.Increment(userID, tunnelID, domain, bytesIn, bytesOut, requests)
+	}
+}
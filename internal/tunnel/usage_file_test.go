@@ -0,0 +1,52 @@
+package tunnel
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestFileUsageRecorderAccumulatesAcrossRuns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage.json")
+
+	rec, err := NewFileUsageRecorder(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec.Increment(0, 0, "a.example.com", 100, 0, 1)
+	rec.Increment(0, 0, "a.example.com", 0, 2000, 0)
+	rec.Increment(0, 0, "", 5, 5, 1)
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	usage, err := ReadUsageFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := *usage.Domains["a.example.com"]; got != (DomainUsage{BytesIn: 100, BytesOut: 2000, Requests: 1}) {
+		t.Errorf("a.example.com = %+v", got)
+	}
+	if usage.Domains["unknown"] == nil {
+		t.Error("usage without a domain was not recorded under \"unknown\"")
+	}
+
+	// A new recorder continues from the totals in the file
+	rec, err = NewFileUsageRecorder(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec.Increment(0, 0, "a.example.com", 1, 1, 1)
+	if err := rec.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	reloaded, err := ReadUsageFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := *reloaded.Domains["a.example.com"]; got != (DomainUsage{BytesIn: 101, BytesOut: 2001, Requests: 2}) {
+		t.Errorf("after reload a.example.com = %+v", got)
+	}
+	if !reloaded.Since.Equal(usage.Since) {
+		t.Errorf("Since changed from %v to %v", usage.Since, reloaded.Since)
+	}
+}
@@ -0,0 +1,68 @@
+package tunnel
+
+// UserConnectionCheck refuses a handshake from userID when the user holds too many tunnel
+// connections already. takeover is set when the new connection replaces one of the user's
+// that is about to close, so it doesn't add to the total.
+type UserConnectionCheck func(userID uint32, takeover bool) error
+
+// SetUserConnectionCheck installs the per-user connection limit for gRPC handshakes (nil = none)
+func (s *GRPCTunnelServer) SetUserConnectionCheck(check UserConnectionCheck) {
+	s.userConnectionCheck = check
+}
+
+// SetUserConnectionCheck installs the per-user connection limit for TCP handshakes (nil = none)
+func (s *TunnelServer) SetUserConnectionCheck(check UserConnectionCheck) {
+	s.userConnectionCheck = check
+}
+
+// userStreamCounts returns the connected gRPC streams per user
+func (s *GRPCTunnelServer) userStreamCounts() map[uint32]int {
+	s.tunnelStreamsMux.RLock()
+	defer s.tunnelStreamsMux.RUnlock()
+	counts := make(map[uint32]int)
+	for _, tunnelStream := range s.tunnelStreams {
+		if tunnelStream.connected {
+			counts[tunnelStream.UserID]++
+		}
+	}
+	return counts
+}
+
+// UserConnectionCounts returns the pooled HTTP and WebSocket connections per domain owner
+func (m *ConnectionManager) UserConnectionCounts() map[uint32]int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	counts := make(map[uint32]int)
+	for _, domainConns := range m.connections {
+		domainConns.mu.RLock()
+		if n := domainConns.httpPool.Size() + domainConns.wsPool.Size(); n > 0 {
+			counts[domainConns.userID] += n
+		}
+		domainConns.mu.RUnlock()
+	}
+	return counts
+}
+
+// UserConnectionCounts returns the tunnel connections each user holds: gRPC streams plus
+// pooled TCP connections (on-demand connections answering one request aren't counted)
+func (r *HybridTunnelRouter) UserConnectionCounts() map[uint32]int {
+	counts := r.grpcTunnel.userStreamCounts()
+	for userID, n := range r.tcpTunnel.connections.UserConnectionCounts() {
+		counts[userID] += n
+	}
+	return counts
+}
+
+// checkUserConnections is the UserConnectionCheck enforcing MaxConnectionsPerUser.
+// Concurrent handshakes may overshoot the limit by a connection or two.
+func (r *HybridTunnelRouter) checkUserConnections(userID uint32, takeover bool) error {
+	limit := r.config.MaxConnectionsPerUser
+	if limit <= 0 || takeover {
+		return nil
+	}
+	if held := r.UserConnectionCounts()[userID]; held >= limit {
+		r.logger.Warn("Refusing tunnel connection for user %d: %d connections open (limit %d)", userID, held, limit)
+		return handshakeErrorf(HandshakeTooManyConnections, "too many tunnel connections for this account (%d, limit %d): stop other clients or try again later", held, limit)
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package tunnel
+
+import (
+	"net"
+	"testing"
+
+	"github.com/osa911/giraffecloud/internal/logging"
+)
+
+func TestCheckUserConnections(t *testing.T) {
+	initTestLogger(t)
+	r := &HybridTunnelRouter{
+		config:     &HybridRouterConfig{MaxConnectionsPerUser: 3},
+		logger:     logging.GetGlobalLogger(),
+		grpcTunnel: &GRPCTunnelServer{tunnelStreams: make(map[string]*TunnelStream)},
+		tcpTunnel:  &TunnelServer{connections: NewConnectionManager()},
+	}
+	r.grpcTunnel.tunnelStreams["a.example.com"] = &TunnelStream{Domain: "a.example.com", UserID: 7, connected: true}
+	r.grpcTunnel.tunnelStreams["b.example.com"] = &TunnelStream{Domain: "b.example.com", UserID: 8, connected: true}
+	conn, peer := net.Pipe()
+	defer conn.Close()
+	defer peer.Close()
+	r.tcpTunnel.connections.AddConnection("a.example.com", conn, 80, ConnectionTypeHTTP, 7, 1)
+
+	if err := r.checkUserConnections(7, false); err != nil {
+		t.Fatalf("user under the limit refused: %v", err)
+	}
+	r.tcpTunnel.connections.AddConnection("c.example.com", conn, 80, ConnectionTypeWebSocket, 7, 2)
+	if got := r.UserConnectionCounts(); got[7] != 3 || got[8] != 1 {
+		t.Fatalf("counts = %v, want 3 for user 7 and 1 for user 8", got)
+	}
+
+	err := r.checkUserConnections(7, false)
+	if handshakeCodeOf(err) != HandshakeTooManyConnections {
+		t.Errorf("user at the limit: err = %v", err)
+	}
+	if err := r.checkUserConnections(7, true); err != nil {
+		t.Errorf("takeover of the user's own stream refused: %v", err)
+	}
+	if err := r.checkUserConnections(8, false); err != nil {
+		t.Errorf("other user refused: %v", err)
+	}
+}
@@ -0,0 +1,134 @@
+package tunnel
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// WebSocket opcodes and close status used by injected control frames (RFC 6455)
+const (
+	wsOpClose = 0x8
+	wsOpPing  = 0x9
+
+	wsCloseGoingAway = 1001
+)
+
+// isWebSocketUpgrade reports whether an upgrade response switched the connection to the
+// WebSocket protocol, so WebSocket control frames may be injected into it
+func isWebSocketUpgrade(resp *http.Response) bool {
+	return resp.StatusCode == http.StatusSwitchingProtocols &&
+		strings.EqualFold(resp.Header.Get("Upgrade"), "websocket")
+}
+
+// writeWSControlFrame writes a single control frame (payload of at most 125 bytes).
+// Frames sent towards a WebSocket server must be masked, frames sent to a client must not.
+func writeWSControlFrame(w io.Writer, opcode byte, payload []byte, masked bool) error {
+	frame := []byte{0x80 | opcode, byte(len(payload))} // FIN set, no fragmentation
+	if !masked {
+		_, err := w.Write(append(frame, payload...))
+		return err
+	}
+
+	frame[1] |= 0x80
+	var key [4]byte
+	if _, err := rand.Read(key[:]); err != nil {
+		return err
+	}
+	frame = append(frame, key[:]...)
+	for i, b := range payload {
+		frame = append(frame, b^key[i%4])
+	}
+	_, err := w.Write(frame)
+	return err
+}
+
+// wsPeer is one side of a proxied WebSocket
+type wsPeer struct {
+	conn   io.WriteCloser
+	masked bool // The peer is the WebSocket server, so frames to it are masked
+}
+
+// wsIdleMonitor closes a proxied WebSocket after no bytes have moved in either direction
+// for the idle timeout. With a ping interval set, a ping is sent to the client after that
+// much silence; the pong counts as traffic, so only unresponsive peers are closed.
+type wsIdleMonitor struct {
+	idleTimeout  time.Duration
+	pingInterval time.Duration
+	lastActivity atomic.Int64
+}
+
+func newWSIdleMonitor(idleTimeout, pingInterval time.Duration) *wsIdleMonitor {
+	m := &wsIdleMonitor{idleTimeout: idleTimeout, pingInterval: pingInterval}
+	m.lastActivity.Store(time.Now().UnixNano())
+	return m
+}
+
+// reader wraps r so that every read counts as activity
+func (m *wsIdleMonitor) reader(r io.Reader) io.Reader {
+	return wsActivityReader{r: r, m: m}
+}
+
+type wsActivityReader struct {
+	r io.Reader
+	m *wsIdleMonitor
+}
+
+func (a wsActivityReader) Read(p []byte) (int, error) {
+	n, err := a.r.Read(p)
+	if n > 0 {
+		a.m.lastActivity.Store(time.Now().UnixNano())
+	}
+	return n, err
+}
+
+// run watches the connection until done is closed. When it goes idle, both peers get a
+// close frame (if isWebSocket) and are closed, which ends the copies; run then returns
+// true. Pings are only sent to client when isWebSocket. An idle timeout of 0 disables
+// the monitor.
+func (m *wsIdleMonitor) run(done <-chan struct{}, client, server wsPeer, isWebSocket bool) bool {
+	if m.idleTimeout <= 0 {
+		return false
+	}
+
+	check := m.idleTimeout / 4
+	if m.pingInterval > 0 && isWebSocket && m.pingInterval/2 < check {
+		check = m.pingInterval / 2
+	}
+	if check < 100*time.Millisecond {
+		check = 100 * time.Millisecond
+	}
+	ticker := time.NewTicker(check)
+	defer ticker.Stop()
+
+	var pingedAt int64 // lastActivity when the last ping went out
+	for {
+		select {
+		case <-done:
+			return false
+		case <-ticker.C:
+		}
+
+		last := m.lastActivity.Load()
+		idle := time.Since(time.Unix(0, last))
+		if idle >= m.idleTimeout {
+			if isWebSocket {
+				payload := binary.BigEndian.AppendUint16(nil, wsCloseGoingAway)
+				payload = append(payload, "idle timeout"...)
+				writeWSControlFrame(client.conn, wsOpClose, payload, client.masked)
+				writeWSControlFrame(server.conn, wsOpClose, payload, server.masked)
+			}
+			client.conn.Close()
+			server.conn.Close()
+			return true
+		}
+		if isWebSocket && m.pingInterval > 0 && idle >= m.pingInterval && pingedAt != last {
+			pingedAt = last
+			writeWSControlFrame(client.conn, wsOpPing, nil, client.masked)
+		}
+	}
+}
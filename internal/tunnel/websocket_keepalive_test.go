@@ -0,0 +1,95 @@
+package tunnel
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestWriteWSControlFrameMasking(t *testing.T) {
+	var unmasked bytes.Buffer
+	if err := writeWSControlFrame(&unmasked, wsOpPing, []byte("hi"), false); err != nil {
+		t.Fatal(err)
+	}
+	if got := unmasked.Bytes(); !bytes.Equal(got, []byte{0x89, 0x02, 'h', 'i'}) {
+		t.Fatalf("unmasked frame = %x", got)
+	}
+
+	var masked bytes.Buffer
+	if err := writeWSControlFrame(&masked, wsOpPing, []byte("hi"), true); err != nil {
+		t.Fatal(err)
+	}
+	frame := masked.Bytes()
+	if len(frame) != 8 || frame[0] != 0x89 || frame[1] != 0x82 {
+		t.Fatalf("masked frame header = %x", frame)
+	}
+	key := frame[2:6]
+	if payload := []byte{frame[6] ^ key[0], frame[7] ^ key[1]}; string(payload) != "hi" {
+		t.Fatalf("unmasked payload = %q", payload)
+	}
+}
+
+func TestWSIdleMonitorClosesIdleConnection(t *testing.T) {
+	clientConn, clientPeer := net.Pipe()
+	serverConn, serverPeer := net.Pipe()
+
+	monitor := newWSIdleMonitor(300*time.Millisecond, 0)
+	done := make(chan struct{})
+	defer close(done)
+	result := make(chan bool, 1)
+	go func() {
+		result <- monitor.run(done, wsPeer{conn: clientConn}, wsPeer{conn: serverConn, masked: true}, true)
+	}()
+
+	// Traffic through the monitor's reader keeps the connection open
+	go clientPeer.Write([]byte("x"))
+	buf := make([]byte, 1)
+	if _, err := monitor.reader(clientConn).Read(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	// Both peers get a close frame, then the connection is closed
+	serverFrames := make(chan []byte, 1)
+	go func() {
+		frame, _ := io.ReadAll(serverPeer)
+		serverFrames <- frame
+	}()
+	clientFrame, _ := io.ReadAll(clientPeer)
+	serverFrame := <-serverFrames
+	if len(clientFrame) < 4 || clientFrame[0] != 0x88 || clientFrame[1]&0x80 != 0 {
+		t.Errorf("client close frame = %x, want an unmasked close", clientFrame)
+	}
+	if len(serverFrame) < 8 || serverFrame[0] != 0x88 || serverFrame[1]&0x80 == 0 {
+		t.Errorf("server close frame = %x, want a masked close", serverFrame)
+	}
+
+	select {
+	case closed := <-result:
+		if !closed {
+			t.Fatal("monitor returned without closing")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("monitor did not return after closing")
+	}
+}
+
+func TestWSIdleMonitorPingsClient(t *testing.T) {
+	clientConn, clientPeer := net.Pipe()
+	serverConn, _ := net.Pipe()
+
+	monitor := newWSIdleMonitor(time.Minute, 200*time.Millisecond)
+	done := make(chan struct{})
+	defer close(done)
+	go monitor.run(done, wsPeer{conn: clientConn}, wsPeer{conn: serverConn, masked: true}, true)
+
+	clientPeer.SetReadDeadline(time.Now().Add(2 * time.Second))
+	frame := make([]byte, 2)
+	if _, err := io.ReadFull(clientPeer, frame); err != nil {
+		t.Fatalf("no ping received: %v", err)
+	}
+	if frame[0] != 0x89 || frame[1] != 0 {
+		t.Fatalf("frame = %x, want an empty unmasked ping", frame)
+	}
+}
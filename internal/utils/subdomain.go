@@ -4,6 +4,7 @@ import (
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"math/big"
 	"os"
@@ -57,6 +58,64 @@ var nouns = []string{
 	"phoenix", "dragon", "griffin", "pegasus", "unicorn", "sphinx", "hydra", "kraken", "titan", "giant",
 }
 
+// DefaultSubdomainFormat is the format template used when SubdomainConfig.Format is empty
+const DefaultSubdomainFormat = "{adjective}-{noun}-{hash}"
+
+// SubdomainConfig customizes generated subdomains. A nil config, or any empty field,
+// falls back to the built-in word lists, DefaultSubdomainFormat and GetBaseDomain.
+type SubdomainConfig struct {
+	Adjectives []string
+	Nouns      []string
+	// Format is a template with the placeholders {adjective}, {noun} and {hash}.
+	// It must contain {hash} so different users get different subdomains.
+	Format     string
+	BaseDomain string
+}
+
+// Validate checks that the format template can produce unique subdomains
+func (c *SubdomainConfig) Validate() error {
+	if c == nil {
+		return nil
+	}
+	format := c.format()
+	if !strings.Contains(format, "{hash}") {
+		return errors.New("subdomain format must contain {hash}")
+	}
+	rest := strings.NewReplacer("{adjective}", "", "{noun}", "", "{hash}", "").Replace(format)
+	if strings.ContainsAny(rest, "{}") {
+		return fmt.Errorf("subdomain format %q has an unknown placeholder", format)
+	}
+	return nil
+}
+
+func (c *SubdomainConfig) adjectives() []string {
+	if c == nil || len(c.Adjectives) == 0 {
+		return adjectives
+	}
+	return c.Adjectives
+}
+
+func (c *SubdomainConfig) nouns() []string {
+	if c == nil || len(c.Nouns) == 0 {
+		return nouns
+	}
+	return c.Nouns
+}
+
+func (c *SubdomainConfig) format() string {
+	if c == nil || c.Format == "" {
+		return DefaultSubdomainFormat
+	}
+	return c.Format
+}
+
+func (c *SubdomainConfig) baseDomain() string {
+	if c == nil || c.BaseDomain == "" {
+		return GetBaseDomain()
+	}
+	return c.BaseDomain
+}
+
 // getSubdomainSecret returns the secret for subdomain generation from environment
 func getSubdomainSecret() string {
 	secret := os.Getenv("SUBDOMAIN_SECRET")
@@ -91,17 +150,46 @@ func GetBaseDomain() string {
 }
 
 // GenerateSubdomainForUser generates a deterministic subdomain for a given user ID
-// Format: {adjective}-{noun}-{encoded-hash}.{base-domain}
+// Format: {adjective}-{noun}-{encoded-hash}.{base-domain} unless cfg says otherwise
 // Example: happy-giraffe-9ix2a.giraffecloud.xyz
-func GenerateSubdomainForUser(userID uint32) string {
+func GenerateSubdomainForUser(userID uint32, cfg *SubdomainConfig) string {
+	return generateSubdomain(userID, 0, cfg)
+}
+
+// maxSubdomainAttempts bounds how many candidates GenerateUniqueSubdomainForUser tries
+const maxSubdomainAttempts = 100
+
+// GenerateUniqueSubdomainForUser generates a subdomain for a user that exists reports as
+// not taken. The first candidate is GenerateSubdomainForUser(userID, cfg); on a collision the
+// subdomain is regenerated with an increasing salt, so the result stays the same for a
+// given set of taken domains.
+func GenerateUniqueSubdomainForUser(userID uint32, cfg *SubdomainConfig, exists func(domain string) bool) (string, error) {
+	for salt := uint32(0); salt < maxSubdomainAttempts; salt++ {
+		candidate := generateSubdomain(userID, salt, cfg)
+		if !exists(candidate) {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("no free subdomain for user %d after %d attempts", userID, maxSubdomainAttempts)
+}
+
+// generateSubdomain builds the subdomain for userID; salt 0 gives the original deterministic one
+func generateSubdomain(userID, salt uint32, cfg *SubdomainConfig) string {
 	secret := getSubdomainSecret()
-	baseDomain := GetBaseDomain()
+	baseDomain := cfg.baseDomain()
+	adjectives := cfg.adjectives()
+	nouns := cfg.nouns()
 
-	// Create HMAC hash of userID with secret
+	// Create HMAC hash of userID (and the salt, once there was a collision) with secret
 	h := hmac.New(sha256.New, []byte(secret))
 	userIDBytes := make([]byte, 4)
 	binary.BigEndian.PutUint32(userIDBytes, userID)
 	h.Write(userIDBytes)
+	if salt > 0 {
+		saltBytes := make([]byte, 4)
+		binary.BigEndian.PutUint32(saltBytes, salt)
+		h.Write(saltBytes)
+	}
 	hashBytes := h.Sum(nil)
 
 	// Use first bytes of hash to select words (deterministic)
@@ -122,7 +210,11 @@ func GenerateSubdomainForUser(userID uint32) string {
 	}
 
 	// Build subdomain
-	subdomain := fmt.Sprintf("%s-%s-%s", adjectives[adjectiveIndex], nouns[nounIndex], encodedHash)
+	subdomain := strings.NewReplacer(
+		"{adjective}", adjectives[adjectiveIndex],
+		"{noun}", nouns[nounIndex],
+		"{hash}", encodedHash,
+	).Replace(cfg.format())
 	fullDomain := fmt.Sprintf("%s.%s", subdomain, baseDomain)
 
 	return fullDomain
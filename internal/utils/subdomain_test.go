@@ -28,7 +28,7 @@ func TestGenerateSubdomainForUser(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// Generate subdomain
-			subdomain1 := GenerateSubdomainForUser(tt.userID)
+			subdomain1 := GenerateSubdomainForUser(tt.userID, nil)
 
 			// Verify format
 			if !strings.HasSuffix(subdomain1, ".test.example.com") {
@@ -42,7 +42,7 @@ func TestGenerateSubdomainForUser(t *testing.T) {
 			}
 
 			// Verify determinism - same user ID should always generate same subdomain
-			subdomain2 := GenerateSubdomainForUser(tt.userID)
+			subdomain2 := GenerateSubdomainForUser(tt.userID, nil)
 			if subdomain1 != subdomain2 {
 				t.Errorf("Non-deterministic generation: %s != %s", subdomain1, subdomain2)
 			}
@@ -65,7 +65,7 @@ func TestGenerateSubdomainForUser_Uniqueness(t *testing.T) {
 	collisions := 0
 
 	for i := uint32(1); i <= 1000; i++ {
-		subdomain := GenerateSubdomainForUser(i)
+		subdomain := GenerateSubdomainForUser(i, nil)
 		if existingUserID, exists := seen[subdomain]; exists {
 			collisions++
 			t.Logf("Collision detected: User %d and User %d both got %s", i, existingUserID, subdomain)
@@ -138,11 +138,11 @@ func TestGenerateSubdomainForUser_DifferentSecrets(t *testing.T) {
 
 	// Generate with first secret
 	os.Setenv("SUBDOMAIN_SECRET", "secret1")
-	subdomain1 := GenerateSubdomainForUser(userID)
+	subdomain1 := GenerateSubdomainForUser(userID, nil)
 
 	// Generate with second secret
 	os.Setenv("SUBDOMAIN_SECRET", "secret2")
-	subdomain2 := GenerateSubdomainForUser(userID)
+	subdomain2 := GenerateSubdomainForUser(userID, nil)
 
 	os.Unsetenv("SUBDOMAIN_SECRET")
 
@@ -154,3 +154,77 @@ func TestGenerateSubdomainForUser_DifferentSecrets(t *testing.T) {
 	t.Logf("Secret1: %s", subdomain1)
 	t.Logf("Secret2: %s", subdomain2)
 }
+
+func TestGenerateUniqueSubdomainForUser(t *testing.T) {
+	os.Setenv("SUBDOMAIN_SECRET", "test-secret-key")
+	os.Setenv("CLIENT_URL", "https://test.example.com")
+	defer func() {
+		os.Unsetenv("SUBDOMAIN_SECRET")
+		os.Unsetenv("CLIENT_URL")
+	}()
+
+	// No collision: same as the deterministic subdomain
+	free, err := GenerateUniqueSubdomainForUser(42, nil, func(string) bool { return false })
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if free != GenerateSubdomainForUser(42, nil) {
+		t.Errorf("Expected deterministic subdomain %s, got %s", GenerateSubdomainForUser(42, nil), free)
+	}
+
+	// The first two candidates are taken
+	taken := map[string]bool{free: true}
+	second, err := GenerateUniqueSubdomainForUser(42, nil, func(domain string) bool { return taken[domain] })
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	taken[second] = true
+	third, err := GenerateUniqueSubdomainForUser(42, nil, func(domain string) bool { return taken[domain] })
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if second == free || third == free || third == second {
+		t.Errorf("Expected distinct candidates, got %s, %s, %s", free, second, third)
+	}
+	if !strings.HasSuffix(third, ".test.example.com") {
+		t.Errorf("Subdomain doesn't end with base domain: %s", third)
+	}
+
+	// Stable for the same set of taken domains
+	again, _ := GenerateUniqueSubdomainForUser(42, nil, func(domain string) bool { return taken[domain] })
+	if again != third {
+		t.Errorf("Non-deterministic generation: %s != %s", third, again)
+	}
+
+	if _, err := GenerateUniqueSubdomainForUser(42, nil, func(string) bool { return true }); err == nil {
+		t.Error("Expected an error when every candidate is taken")
+	}
+}
+
+func TestGenerateSubdomainForUser_CustomConfig(t *testing.T) {
+	os.Setenv("SUBDOMAIN_SECRET", "test-secret-key")
+	defer os.Unsetenv("SUBDOMAIN_SECRET")
+
+	cfg := &SubdomainConfig{
+		Nouns:      []string{"widget"},
+		Format:     "{noun}{hash}",
+		BaseDomain: "tunnels.example.org",
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Unexpected validation error: %v", err)
+	}
+
+	subdomain := GenerateSubdomainForUser(7, cfg)
+	if !strings.HasPrefix(subdomain, "widget") || !strings.HasSuffix(subdomain, ".tunnels.example.org") {
+		t.Errorf("Subdomain doesn't follow the custom config: %s", subdomain)
+	}
+	if strings.Contains(subdomain, "-") {
+		t.Errorf("Expected no separators, got: %s", subdomain)
+	}
+
+	for _, format := range []string{"{adjective}-{noun}", "{noun}-{hash}-{color}"} {
+		if err := (&SubdomainConfig{Format: format}).Validate(); err == nil {
+			t.Errorf("Expected format %q to be rejected", format)
+		}
+	}
+}